@@ -0,0 +1,58 @@
+// analyze.go implements the 'racedetector analyze' command.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kolkov/racedetector/internal/race/detector"
+)
+
+// analyzeCommand implements the 'racedetector analyze' command.
+//
+// Unlike build/run/test, which instrument and execute a program live,
+// analyze runs FastTrack over a trace previously recorded with
+// RACEDETECTOR_RECORD_SCHEDULE (see detector.ScheduleRecorder). This lets a
+// race be investigated offline, after the fact, with no instrumented
+// program to re-run and no live timing to reproduce - see
+// detector.AnalyzeTrace.
+//
+// Example:
+//
+//	RACEDETECTOR_RECORD_SCHEDULE=trace.jsonl racedetector test ./...
+//	racedetector analyze trace.jsonl
+func analyzeCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: racedetector analyze requires a trace file")
+		fmt.Fprintln(os.Stderr, "Usage: racedetector analyze <trace-file>")
+		os.Exit(1)
+	}
+	tracePath := args[0]
+
+	f, err := os.Open(tracePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not open trace file %s: %v\n", tracePath, err)
+		os.Exit(1)
+	}
+	events, err := detector.LoadSchedule(f)
+	_ = f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not load trace %s: %v\n", tracePath, err)
+		os.Exit(1)
+	}
+
+	d := detector.AnalyzeTrace(events, detector.DetectorOptions{})
+
+	racesDetected := d.RacesDetected()
+	if racesDetected == 0 {
+		fmt.Printf("racedetector analyze: no data races found in %d recorded event(s)\n", len(events))
+		return
+	}
+
+	for _, report := range d.RecentReports() {
+		report.Format(os.Stdout)
+	}
+	fmt.Fprintf(os.Stderr, "racedetector analyze: %d data race(s) detected in %d recorded event(s)\n",
+		racesDetected, len(events))
+	os.Exit(1)
+}
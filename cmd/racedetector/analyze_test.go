@@ -0,0 +1,56 @@
+// analyze_test.go implements tests for the 'racedetector analyze' command.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/detector"
+)
+
+// writeTrace writes events as a newline-delimited JSON trace file, matching
+// the format detector.ScheduleRecorder produces.
+func writeTrace(t *testing.T, path string, events []detector.ScheduleEvent) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create trace file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	enc := json.NewEncoder(f)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			t.Fatalf("failed to encode event: %v", err)
+		}
+	}
+}
+
+// TestAnalyzeCommand_NoRaces verifies analyzeCommand runs to completion
+// (without calling os.Exit) and prints a "no races" summary for a trace
+// that doesn't contain one.
+func TestAnalyzeCommand_NoRaces(t *testing.T) {
+	tracePath := filepath.Join(t.TempDir(), "trace.jsonl")
+	writeTrace(t, tracePath, []detector.ScheduleEvent{
+		{Seq: 1, Goroutine: 1, Step: 1, Kind: detector.ScheduleEventWrite, Addr: 0x1000},
+	})
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	analyzeCommand([]string{tracePath})
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+
+	if got := buf.String(); got == "" {
+		t.Error("analyzeCommand() printed nothing to stdout")
+	}
+}
@@ -0,0 +1,317 @@
+// bench_overhead.go implements the 'racedetector bench-overhead' command (synth-3584).
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kolkov/racedetector/cmd/racedetector/runtime"
+)
+
+// benchOverheadConfig holds configuration for the bench-overhead command.
+type benchOverheadConfig struct {
+	// Package patterns to benchmark (e.g., "./...", "./internal/...")
+	packages []string
+
+	// Benchmark name pattern, forwarded to `go test -bench=`. Defaults to
+	// "." (run every benchmark), matching `go test`'s own default when
+	// -bench is given with no argument.
+	benchPattern string
+
+	// Working directory
+	workDir string
+}
+
+// benchResult is one benchmark's parsed `go test -bench -benchmem` line.
+type benchResult struct {
+	nsPerOp     float64
+	bytesPerOp  float64
+	allocsPerOp float64
+}
+
+// benchOverheadCommand implements 'racedetector bench-overhead': it runs a
+// package's benchmarks twice, once as plain `go test -bench` and once
+// through the same instrument/build/test cycle 'racedetector test' uses,
+// and reports how much the instrumentation actually costs on the user's
+// own code.
+//
+// The published "5-15x" overhead figure is measured against the FastTrack
+// microbenchmarks in this repo; a user's real workload can differ wildly
+// depending on how access-heavy it is, so this command exists to let them
+// measure their own slowdown factor and allocation delta instead of taking
+// that figure on faith.
+//
+// Flow:
+//  1. Run `go test -run=^$ -bench=<pattern> -benchmem` on config.packages,
+//     uninstrumented, and parse each benchmark's ns/op, B/op, allocs/op.
+//  2. Run the same benchmarks through the instrument/build/test cycle (see
+//     runTestCycle), with RACEDETECTOR_PROFILE and RACEDETECTOR_PROFILE_OUT
+//     set so the instrumented run also produces a per-call-site overhead
+//     profile (see detector.Profiler), and parse its ns/op, B/op,
+//     allocs/op the same way.
+//  3. For each benchmark present on both sides, report the slowdown factor
+//     and the allocation delta.
+//  4. If `go tool pprof` is available, print its -top view of the overhead
+//     profile as the per-hook cost breakdown - see runOverheadProfileTop
+//     for why this delegates to pprof's own report rather than
+//     reimplementing one.
+//
+// Example:
+//
+//	racedetector bench-overhead ./internal/race/detector/...
+//	racedetector bench-overhead -bench=BenchmarkOnWrite ./internal/race/detector/...
+func benchOverheadCommand(args []string) {
+	config, err := parseBenchOverheadArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(runBenchOverheadCycle(config))
+}
+
+// parseBenchOverheadArgs parses command-line arguments for
+// 'racedetector bench-overhead'.
+//
+// Like compare (see parseCompareArgs), bench-overhead doesn't forward
+// arbitrary flags to either `go test` invocation - the baseline and
+// instrumented runs need the exact same benchmark selection for the
+// comparison to mean anything. The one flag it does understand,
+// -bench=PATTERN, is applied identically to both sides.
+func parseBenchOverheadArgs(args []string) (*benchOverheadConfig, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	config := &benchOverheadConfig{workDir: cwd, benchPattern: "."}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-bench=") {
+			config.benchPattern = strings.TrimPrefix(arg, "-bench=")
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		config.packages = append(config.packages, arg)
+	}
+
+	if len(config.packages) == 0 {
+		config.packages = []string{"./..."}
+	}
+
+	return config, nil
+}
+
+// runBenchOverheadCycle runs both the baseline and instrumented benchmarks
+// over config.packages, prints the comparison, and returns the process
+// exit code to use: 0 on success, 1 if either run couldn't be completed.
+func runBenchOverheadCycle(config *benchOverheadConfig) int {
+	baseline, err := runBaselineBenchmarks(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running baseline benchmarks: %v\n", err)
+		return 1
+	}
+
+	instrumented, profilePath, err := runInstrumentedBenchmarks(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running instrumented benchmarks: %v\n", err)
+		return 1
+	}
+	if profilePath != "" {
+		defer func() { _ = os.Remove(profilePath) }()
+	}
+
+	names := make([]string, 0, len(baseline))
+	for name := range baseline {
+		if _, ok := instrumented[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		fmt.Fprintln(os.Stderr, "racedetector bench-overhead: no benchmark ran on both sides - nothing to compare")
+		return 1
+	}
+
+	fmt.Printf("%-40s %10s %14s %14s\n", "BENCHMARK", "SLOWDOWN", "ALLOC DELTA", "ALLOCS DELTA")
+	for _, name := range names {
+		b, i := baseline[name], instrumented[name]
+		slowdown := i.nsPerOp / b.nsPerOp
+		allocDelta := i.bytesPerOp - b.bytesPerOp
+		allocsDelta := i.allocsPerOp - b.allocsPerOp
+		fmt.Printf("%-40s %9.2fx %+13.0fB %+14.0f\n", name, slowdown, allocDelta, allocsDelta)
+	}
+
+	if profilePath != "" {
+		if top, err := runOverheadProfileTop(profilePath); err == nil {
+			fmt.Printf("\nPer-hook cost breakdown (go tool pprof -top):\n\n%s\n", top)
+		} else {
+			fmt.Fprintf(os.Stderr, "\nNote: could not run `go tool pprof` for a per-hook breakdown: %v\n", err)
+		}
+	}
+
+	return 0
+}
+
+// runBaselineBenchmarks runs config's benchmarks uninstrumented via plain
+// `go test`, and returns each benchmark's parsed result.
+func runBaselineBenchmarks(config *benchOverheadConfig) (map[string]benchResult, error) {
+	args := []string{"test", "-run=^$", "-bench=" + config.benchPattern, "-benchmem"}
+	args = append(args, config.packages...)
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = config.workDir
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	_ = cmd.Run()
+
+	return parseBenchOutput(out.String()), nil
+}
+
+// runInstrumentedBenchmarks runs config's benchmarks through the same
+// instrument/build/test cycle 'racedetector test' uses (see
+// runTestCycle), with per-call-site overhead profiling enabled, and
+// returns each benchmark's parsed result along with the path profiling was
+// written to (empty if profiling didn't produce a file).
+func runInstrumentedBenchmarks(config *benchOverheadConfig) (map[string]benchResult, string, error) {
+	tConfig := &testConfig{
+		packages: config.packages,
+		testFlags: []string{
+			"-run=^$",
+			"-bench=" + config.benchPattern,
+			"-benchmem",
+		},
+		workDir: config.workDir,
+	}
+
+	workspace, err := createWorkspace()
+	if err != nil {
+		return nil, "", fmt.Errorf("creating workspace: %w", err)
+	}
+	defer workspace.cleanup()
+
+	if err := instrumentTestSources(tConfig, workspace); err != nil {
+		return nil, "", fmt.Errorf("instrumenting sources: %w", err)
+	}
+	if err := workspace.setupRuntimeLinking(); err != nil {
+		return nil, "", fmt.Errorf("setting up runtime: %w", err)
+	}
+
+	profileFile, err := os.CreateTemp("", "racedetector-overhead-*.pprof")
+	if err != nil {
+		return nil, "", fmt.Errorf("creating profile file: %w", err)
+	}
+	profilePath := profileFile.Name()
+	_ = profileFile.Close()
+
+	out, err := runInstrumentedTestsCaptured(workspace, tConfig, []string{
+		"RACEDETECTOR_PROFILE=1",
+		"RACEDETECTOR_PROFILE_OUT=" + profilePath,
+	})
+	if err != nil {
+		_ = os.Remove(profilePath)
+		return nil, "", err
+	}
+
+	if info, statErr := os.Stat(profilePath); statErr != nil || info.Size() == 0 {
+		_ = os.Remove(profilePath)
+		profilePath = ""
+	}
+
+	return parseBenchOutput(out), profilePath, nil
+}
+
+// runInstrumentedTestsCaptured runs `go test` in workspace with config's
+// flags and extraEnv, capturing combined stdout+stderr instead of
+// streaming it live like runTests does - bench-overhead needs the
+// benchmark table to parse, not just a race-count side channel.
+func runInstrumentedTestsCaptured(workspace *workspace, config *testConfig, extraEnv []string) (string, error) {
+	args := []string{"test"}
+	args = append(args, config.testFlags...)
+	args = runtime.BuildFlags(args, runtime.BuildMetadata{
+		ToolVersion: version,
+		ToolCommit:  commit,
+		Scope:       strings.Join(config.packages, " "),
+	})
+	args = append(args, "./...")
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = workspace.srcDir
+	cmd.Env = append(os.Environ(), extraEnv...)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	_ = cmd.Run()
+
+	return out.String(), nil
+}
+
+// benchLineRe matches one `go test -bench -benchmem` result line, e.g.
+//
+//	BenchmarkOnWrite-8   	 5000000	   234.5 ns/op	  16 B/op	   1 allocs/op
+var benchLineRe = regexp.MustCompile(`^(Benchmark\S+)\s+\d+\s+([\d.]+) ns/op(?:\s+([\d.]+) B/op)?(?:\s+([\d.]+) allocs/op)?`)
+
+// parseBenchOutput extracts each benchmark's ns/op, B/op, and allocs/op
+// from `go test -bench -benchmem` output. Benchmark names include the
+// trailing "-N" GOMAXPROCS suffix (see testing.B), which is stripped so the
+// same benchmark's baseline and instrumented names match even if the two
+// runs used different GOMAXPROCS.
+func parseBenchOutput(output string) map[string]benchResult {
+	results := make(map[string]benchResult)
+	for _, line := range strings.Split(output, "\n") {
+		m := benchLineRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		name := m[1]
+		if idx := strings.LastIndex(name, "-"); idx != -1 {
+			if _, err := strconv.Atoi(name[idx+1:]); err == nil {
+				name = name[:idx]
+			}
+		}
+
+		var r benchResult
+		r.nsPerOp, _ = strconv.ParseFloat(m[2], 64)
+		if m[3] != "" {
+			r.bytesPerOp, _ = strconv.ParseFloat(m[3], 64)
+		}
+		if m[4] != "" {
+			r.allocsPerOp, _ = strconv.ParseFloat(m[4], 64)
+		}
+		results[name] = r
+	}
+	return results
+}
+
+// runOverheadProfileTop runs `go tool pprof -top` against the profile at
+// path and returns its output.
+//
+// The profile is symbolized when detector.Profiler.Dump writes it (pprof
+// resolves function names from the running process at that point), so
+// `go tool pprof` can render a useful call-site breakdown without needing
+// the instrumented test binary, which is already gone by the time
+// bench-overhead gets to look at the profile - see workspace.cleanup.
+// Reporting pprof's own -top view rather than re-aggregating the profile's
+// samples ourselves keeps this command from silently drifting out of sync
+// with whatever detector.Profiler.RecordSample decides to attribute
+// overhead to.
+func runOverheadProfileTop(path string) (string, error) {
+	cmd := exec.Command("go", "tool", "pprof", "-top", "-nodecount=15", path)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
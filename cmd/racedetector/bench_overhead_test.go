@@ -0,0 +1,102 @@
+// bench_overhead_test.go implements tests for the 'racedetector bench-overhead' command.
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseBenchOverheadArgs tests package-pattern and -bench= parsing,
+// including the default package list and default bench pattern.
+func TestParseBenchOverheadArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantPackages []string
+		wantPattern  string
+	}{
+		{
+			name:         "no args defaults to ./... and .",
+			args:         nil,
+			wantPackages: []string{"./..."},
+			wantPattern:  ".",
+		},
+		{
+			name:         "single package",
+			args:         []string{"./internal/..."},
+			wantPackages: []string{"./internal/..."},
+			wantPattern:  ".",
+		},
+		{
+			name:         "bench pattern",
+			args:         []string{"-bench=BenchmarkOnWrite", "./internal/..."},
+			wantPackages: []string{"./internal/..."},
+			wantPattern:  "BenchmarkOnWrite",
+		},
+		{
+			name:         "other flags are ignored, not forwarded",
+			args:         []string{"-v", "./foo"},
+			wantPackages: []string{"./foo"},
+			wantPattern:  ".",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseBenchOverheadArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseBenchOverheadArgs() error: %v", err)
+			}
+			if !reflect.DeepEqual(config.packages, tt.wantPackages) {
+				t.Errorf("packages = %v, want %v", config.packages, tt.wantPackages)
+			}
+			if config.benchPattern != tt.wantPattern {
+				t.Errorf("benchPattern = %q, want %q", config.benchPattern, tt.wantPattern)
+			}
+		})
+	}
+}
+
+// TestParseBenchOutput verifies ns/op, B/op, and allocs/op are recovered
+// from a `go test -bench -benchmem` table, and that the trailing GOMAXPROCS
+// suffix is stripped from benchmark names.
+func TestParseBenchOutput(t *testing.T) {
+	output := `goos: linux
+goarch: amd64
+pkg: github.com/kolkov/racedetector/internal/race/detector
+BenchmarkOnWrite-8      5000000       234.5 ns/op        16 B/op         1 allocs/op
+BenchmarkOnRead-8      10000000       102.0 ns/op         0 B/op         0 allocs/op
+PASS
+ok      github.com/kolkov/racedetector/internal/race/detector 3.412s
+`
+
+	got := parseBenchOutput(output)
+	want := map[string]benchResult{
+		"BenchmarkOnWrite": {nsPerOp: 234.5, bytesPerOp: 16, allocsPerOp: 1},
+		"BenchmarkOnRead":  {nsPerOp: 102.0, bytesPerOp: 0, allocsPerOp: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBenchOutput() = %+v, want %+v", got, want)
+	}
+}
+
+// TestParseBenchOutput_NoBenchmemStillParsesNsPerOp verifies a table
+// without -benchmem (no B/op or allocs/op columns) still yields ns/op.
+func TestParseBenchOutput_NoBenchmemStillParsesNsPerOp(t *testing.T) {
+	output := "BenchmarkOnWrite-8   5000000   234.5 ns/op\n"
+
+	got := parseBenchOutput(output)
+	want := map[string]benchResult{"BenchmarkOnWrite": {nsPerOp: 234.5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBenchOutput() = %+v, want %+v", got, want)
+	}
+}
+
+// TestParseBenchOutput_NoMatches verifies non-benchmark output yields an
+// empty, non-nil set.
+func TestParseBenchOutput_NoMatches(t *testing.T) {
+	got := parseBenchOutput("PASS\nok  \tsome/pkg\t0.005s\n")
+	if len(got) != 0 {
+		t.Errorf("parseBenchOutput() = %v, want empty", got)
+	}
+}
@@ -2,10 +2,14 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/kolkov/racedetector/cmd/racedetector/instrument"
@@ -15,7 +19,9 @@ import (
 // buildCommand implements the 'racedetector build' command.
 //
 // This command instruments Go source files and builds them with race detection.
-// It acts as a drop-in replacement for 'go build', supporting all standard flags.
+// It acts as a drop-in replacement for 'go build', supporting all standard
+// flags and package patterns - literal files/directories, "..." wildcards,
+// and bare import paths (synth-3589; see collectGoFiles/resolvePackageDirs).
 //
 // Flow:
 //  1. Parse arguments (source files + go build flags)
@@ -30,6 +36,8 @@ import (
 //	racedetector build main.go
 //	racedetector build -o myapp main.go helper.go
 //	racedetector build -ldflags="-s -w" .
+//	racedetector build ./cmd/...
+//	GOOS=linux GOARCH=arm64 racedetector build -trimpath ./cmd/foo
 func buildCommand(args []string) {
 	// Parse arguments
 	config, err := parseBuildArgs(args)
@@ -95,6 +103,23 @@ type buildConfig struct {
 
 	// Verbose output flag (-v)
 	verbose bool
+
+	// Path to write a JSON instrumentation coverage report
+	// (-coverage-report flag). Empty means no report is written.
+	coverageReport string
+
+	// includeVendor opts vendor/ code into instrumentation (-include-vendor
+	// flag, synth-3626). Off by default - vendored dependencies are usually
+	// large and not the code someone invoking racedetector is trying to
+	// debug.
+	includeVendor bool
+
+	// vendorAllowlist restricts instrumentation to these import path
+	// prefixes under vendor/ (the comma-separated value of -include-vendor,
+	// e.g. "-include-vendor=github.com/foo/bar"). Ignored unless
+	// includeVendor is set; empty means every vendored package is
+	// instrumented once -include-vendor is set.
+	vendorAllowlist []string
 }
 
 // parseBuildArgs parses command-line arguments for 'racedetector build'.
@@ -147,12 +172,41 @@ func parseBuildArgs(args []string) (*buildConfig, error) {
 			continue
 		}
 
+		// Handle -coverage-report flag (instrumentation coverage report)
+		if arg == "-coverage-report" {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("-coverage-report flag requires an argument")
+			}
+			i++
+			config.coverageReport = args[i]
+			continue
+		}
+
+		// Handle -coverage-report=file format
+		if strings.HasPrefix(arg, "-coverage-report=") {
+			config.coverageReport = strings.TrimPrefix(arg, "-coverage-report=")
+			continue
+		}
+
 		// Handle -v flag (verbose output)
 		if arg == "-v" {
 			config.verbose = true
 			continue
 		}
 
+		// Handle -include-vendor flag (opt vendor/ into instrumentation,
+		// synth-3626): bare form instruments all of vendor/, "=" form
+		// restricts it to the given comma-separated import path prefixes.
+		if arg == "-include-vendor" {
+			config.includeVendor = true
+			continue
+		}
+		if strings.HasPrefix(arg, "-include-vendor=") {
+			config.includeVendor = true
+			config.vendorAllowlist = strings.Split(strings.TrimPrefix(arg, "-include-vendor="), ",")
+			continue
+		}
+
 		// Handle flags (starts with -)
 		if strings.HasPrefix(arg, "-") {
 			// It's a build flag - pass through to go build
@@ -209,12 +263,24 @@ type workspace struct {
 	// Root directory of workspace
 	dir string
 
-	// Source directory (where instrumented .go files go)
+	// Source directory (where instrumented .go files go). Used by the
+	// test/bench-overhead commands' own mirrored-tree instrumentation
+	// pipeline; build/run/compare's own pipeline uses overlay instead (see
+	// overlay field) and never writes here.
 	srcDir string
 
 	// Original source directory (where original .go files come from)
 	// Used to find original go.mod for replace directives
 	originalSourceDir string
+
+	// overlay maps each real absolute path build/run/compare's pipeline
+	// wants replaced - an instrumented source file, or the project's own
+	// go.mod - to the temp file holding what should be read instead
+	// (synth-3590). Fed to `go build -overlay` so building runs against the
+	// real source tree in place rather than a full mirrored copy of it; see
+	// instrumentSources and stageGoModOverlay for what populates it, and
+	// build for how it's consumed.
+	overlay map[string]string
 }
 
 // createWorkspace creates a temporary workspace for building instrumented code.
@@ -271,14 +337,165 @@ func (w *workspace) setupRuntimeLinking() error {
 		}
 	}
 
+	// Also stage a go.mod -overlay entry (synth-3590) for build/run/compare's
+	// own pipeline (see workspace.build): it builds the real source tree in
+	// place rather than the mirrored ./src above, so it needs the runtime
+	// dependency added directly onto the real go.mod's content, preserving
+	// the real module's identity, instead of the throwaway "instrumented"
+	// module ModFileOverlay wrote above for the mirrored-tree path.
+	if err := w.stageGoModOverlay(); err != nil {
+		return fmt.Errorf("failed to stage go.mod overlay: %w", err)
+	}
+
+	return nil
+}
+
+// stageGoModOverlay stages an augmented go.mod overlay (synth-3590) for the
+// primary module at w.originalSourceDir, then does the same for every other
+// member module of the go.work workspace governing it, if any (synth-3625).
+//
+// A workspace can spread instrumented packages across multiple member
+// modules, each with its own go.mod - the primary module's overlay alone
+// isn't enough, since a member module other than the primary one also needs
+// the runtime require/replace added before its own instrumented files (now
+// importing the race package) can resolve it.
+func (w *workspace) stageGoModOverlay() error {
+	goModPath := runtime.FindOriginalGoMod(w.originalSourceDir)
+	if goModPath == "" {
+		goModPath = filepath.Join(w.originalSourceDir, "go.mod")
+	}
+
+	if err := w.stageGoModOverlayFor(goModPath); err != nil {
+		return err
+	}
+
+	workspaceDirs, err := runtime.WorkspaceModuleDirs(w.originalSourceDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace modules: %w", err)
+	}
+	for _, dir := range workspaceDirs {
+		memberGoMod := filepath.Join(dir, "go.mod")
+		if memberGoMod == goModPath {
+			continue // primary module, already staged above
+		}
+		if err := w.stageGoModOverlayFor(memberGoMod); err != nil {
+			return fmt.Errorf("failed to stage go.mod overlay for workspace module %s: %w", memberGoMod, err)
+		}
+	}
+
+	return nil
+}
+
+// stageGoModOverlayFor adds a {goModPath: augmentedTempGoMod} entry to
+// w.overlay for a single module (synth-3590; split out from
+// stageGoModOverlay for the multi-module workspace case in synth-3625).
+// goModPath may not exist on disk yet - see runtime.AugmentedGoMod - in
+// which case the entry introduces a virtual go.mod at its directory instead
+// of overriding a real one. Never writes to goModPath itself.
+func (w *workspace) stageGoModOverlayFor(goModPath string) error {
+	content, err := runtime.AugmentedGoMod(goModPath)
+	if err != nil {
+		return err
+	}
+
+	// Temp file names are derived from goModPath so staging several member
+	// modules' go.mod overlays side by side in the same w.dir doesn't have
+	// one overwrite another's.
+	slug := overlaySlug(goModPath)
+
+	tempPath := filepath.Join(w.dir, "go.mod.build-overlay."+slug)
+	if err := os.WriteFile(tempPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write go.mod overlay: %w", err)
+	}
+
+	if w.overlay == nil {
+		w.overlay = map[string]string{}
+	}
+	w.overlay[goModPath] = tempPath
+
+	// The augmented require may need go.sum entries of its own (published
+	// mode - see runtime.GoSumForOverlay); stage those the same way, rather
+	// than letting `go build` fall back to writing them straight into the
+	// real go.sum next to goModPath.
+	goSumContent, err := runtime.GoSumForOverlay(content)
+	if err != nil {
+		return fmt.Errorf("failed to compute go.sum: %w", err)
+	}
+	if goSumContent != nil {
+		goSumPath := filepath.Join(filepath.Dir(goModPath), "go.sum")
+
+		// Merge onto the real go.sum's existing entries (if any) rather
+		// than overlaying a go.sum that only knows about the runtime
+		// dependency - the real file may carry checksums for the project's
+		// own other dependencies that a full replacement would drop.
+		merged := goSumContent
+		if existing, err := os.ReadFile(goSumPath); err == nil {
+			merged = mergeGoSum(existing, goSumContent)
+		}
+
+		goSumTempPath := filepath.Join(w.dir, "go.sum.build-overlay."+slug)
+		if err := os.WriteFile(goSumTempPath, merged, 0644); err != nil {
+			return fmt.Errorf("failed to write go.sum overlay: %w", err)
+		}
+		w.overlay[goSumPath] = goSumTempPath
+	}
+
 	return nil
 }
 
-// build runs 'go build' on the instrumented code in the workspace.
+// overlaySlug turns a go.mod path into a short filesystem-safe identifier
+// for naming that module's staged overlay files (synth-3625), so multiple
+// member modules' overlays can coexist in the same workspace directory.
+func overlaySlug(goModPath string) string {
+	sum := sha256.Sum256([]byte(filepath.Dir(goModPath)))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// mergeGoSum combines an existing go.sum's lines with additional ones,
+// deduplicating by exact line so a re-added checksum doesn't appear twice,
+// and sorting the result the way `go mod tidy` normally leaves a go.sum.
+func mergeGoSum(existing, additional []byte) []byte {
+	lines := map[string]struct{}{}
+	var ordered []string
+	for _, raw := range append(strings.Split(string(existing), "\n"), strings.Split(string(additional), "\n")...) {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if _, ok := lines[line]; ok {
+			continue
+		}
+		lines[line] = struct{}{}
+		ordered = append(ordered, line)
+	}
+	sort.Strings(ordered)
+	return []byte(strings.Join(ordered, "\n") + "\n")
+}
+
+// build runs 'go build' on the instrumented code, in place, via an -overlay
+// JSON file (synth-3590) rather than from a mirrored copy of the source
+// tree: w.overlay already maps every instrumented file's real path to its
+// staged content, plus the real go.mod's path to its runtime-augmented
+// content (see instrumentSources and stageGoModOverlay), so `go build`
+// itself can read the rest of the tree straight from config.workDir exactly
+// as it would for an uninstrumented `go build`.
+//
+// GOOS/GOARCH cross-compilation (synth-3589) needs no special handling here:
+// like any go build flag they're environment variables, and cmd.Env is left
+// nil so the child inherits the caller's environment, including whatever
+// GOOS/GOARCH the caller already exported.
 func (w *workspace) build(config *buildConfig) error {
 	// Prepare go build command
 	args := []string{"build"}
 
+	if len(w.overlay) > 0 {
+		overlayPath, err := w.writeOverlayFile()
+		if err != nil {
+			return fmt.Errorf("failed to write overlay file: %w", err)
+		}
+		args = append(args, "-overlay", overlayPath)
+	}
+
 	// Add output file if specified
 	if config.outputFile != "" {
 		// Make output path absolute
@@ -292,58 +509,157 @@ func (w *workspace) build(config *buildConfig) error {
 	// Add user-specified build flags
 	args = append(args, config.buildFlags...)
 
-	// Add runtime build flags
-	runtimeFlags := runtime.BuildFlags()
-	args = append(args, runtimeFlags...)
-
-	// Build from workspace src directory
-	args = append(args, ".")
+	// Fold build metadata (tool version/commit, instrumentation scope) into
+	// an -ldflags -X assignment, merging with any -ldflags already added
+	// above (synth-3624).
+	args = runtime.BuildFlags(args, runtime.BuildMetadata{
+		ToolVersion: version,
+		ToolCommit:  commit,
+		Scope:       strings.Join(config.sourceFiles, " "),
+	})
+
+	// Build exactly the patterns the caller asked for - same files,
+	// directories, "..." wildcards, or import paths as config.sourceFiles
+	// (see collectGoFiles) - since we're now building config.workDir itself
+	// rather than a synthetic mirrored copy of it.
+	args = append(args, config.sourceFiles...)
 
 	// Run go build
 	cmd := exec.Command("go", args...)
-	cmd.Dir = w.srcDir
+	cmd.Dir = config.workDir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	return cmd.Run()
 }
 
+// overlayFile is the JSON structure `go build -overlay` expects: a single
+// "Replace" map from a real disk path to the path whose contents should be
+// used instead (synth-3590).
+type overlayFile struct {
+	Replace map[string]string `json:"Replace"`
+}
+
+// writeOverlayFile serializes w.overlay to a JSON file in the workspace and
+// returns its path.
+func (w *workspace) writeOverlayFile() (string, error) {
+	data, err := json.MarshalIndent(overlayFile{Replace: w.overlay}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal overlay: %w", err)
+	}
+
+	path := filepath.Join(w.dir, "overlay.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write overlay: %w", err)
+	}
+	return path, nil
+}
+
 // instrumentSources instruments all source files and writes them to workspace.
+//
+// If config.coverageReport is set, it also runs instrument.AnalyzeCoverage
+// on each file and writes the combined per-file, per-function results as
+// JSON to that path once all files are processed.
 func instrumentSources(config *buildConfig, workspace *workspace) error {
 	// Collect all .go files to instrument
-	goFiles, err := collectGoFiles(config.sourceFiles, config.workDir)
+	goFiles, err := collectGoFiles(config.sourceFiles, config.workDir, config.includeVendor, config.vendorAllowlist)
 	if err != nil {
 		return fmt.Errorf("failed to collect source files: %w", err)
 	}
 
+	// In a go.work workspace, config.workDir is only ever one member module -
+	// its own local replace directives already make cross-module code build,
+	// but a cross-module race (e.g. a shared package another member module
+	// exports) would go completely uninstrumented if only workDir's own
+	// files were covered (synth-3625). Instrument every other member
+	// module's sources too, so happens-before tracking is consistent across
+	// the whole workspace, not just the module racedetector was invoked
+	// from.
+	workspaceDirs, err := runtime.WorkspaceModuleDirs(config.workDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace modules: %w", err)
+	}
+	for _, dir := range workspaceDirs {
+		if dir == config.workDir {
+			continue // primary module, already collected above
+		}
+		files, err := collectGoFiles([]string{"."}, dir, config.includeVendor, config.vendorAllowlist)
+		if err != nil {
+			return fmt.Errorf("failed to collect source files for workspace module %s: %w", dir, err)
+		}
+		goFiles = append(goFiles, files...)
+	}
+
 	if len(goFiles) == 0 {
 		return fmt.Errorf("no Go source files found")
 	}
 
-	// Store original source directory for go.mod replace directive handling
-	// Use the first source file's directory or workDir
-	if len(goFiles) > 0 {
-		workspace.originalSourceDir = filepath.Dir(goFiles[0])
-	} else {
-		workspace.originalSourceDir = config.workDir
+	// Original source directory for go.mod replace directive handling is
+	// simply config.workDir (synth-3590): unlike the mirrored-tree path
+	// below, build/run/compare's overlay never moves files anywhere, so
+	// there's no need to infer it from where a source file happened to
+	// land.
+	workspace.originalSourceDir = config.workDir
+
+	if workspace.overlay == nil {
+		workspace.overlay = map[string]string{}
 	}
 
+	var coverage []instrument.FileCoverage
+	var cgoSkipped []string
+	var asmFiles []string
+	scannedDirs := map[string]bool{}
+
 	// Instrument each file
-	for _, srcPath := range goFiles {
+	for i, srcPath := range goFiles {
+		// A package mixing cgo/assembly with pure-Go files is common (the
+		// standard library does it constantly) - report both once per
+		// directory rather than skip silently (synth-3593).
+		dir := filepath.Dir(srcPath)
+		if !scannedDirs[dir] {
+			scannedDirs[dir] = true
+			files, err := assemblyFilesIn(dir)
+			if err != nil {
+				return fmt.Errorf("failed to scan %s for assembly files: %w", dir, err)
+			}
+			asmFiles = append(asmFiles, files...)
+		}
+
+		src, err := os.ReadFile(srcPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", srcPath, err)
+		}
+
+		// Cgo files are left on disk untouched and built as-is (synth-3593)
+		// - see isCgoFile for why instrumenting them is unsafe. Since they
+		// aren't added to workspace.overlay, `go build` reads them straight
+		// from srcPath exactly like every other file build() doesn't
+		// override.
+		if isCgoFile(srcPath, src) {
+			cgoSkipped = append(cgoSkipped, srcPath)
+			fmt.Printf("Skipped (cgo, left unmodified): %s\n", srcPath)
+			continue
+		}
+
 		// Instrument the file
-		result, err := instrument.InstrumentFile(srcPath, nil)
+		result, err := instrument.InstrumentFile(srcPath, src)
 		if err != nil {
 			return fmt.Errorf("failed to instrument %s: %w", srcPath, err)
 		}
 
-		// Determine output path in workspace
-		// Use just the filename (flatten directory structure for simplicity)
-		outPath := filepath.Join(workspace.srcDir, filepath.Base(srcPath))
+		// Stage the instrumented content as a flat temp file and point
+		// workspace.overlay's real-path entry at it (synth-3590), rather
+		// than mirroring srcPath's directory structure under a workspace
+		// srcDir: `go build -overlay` replaces srcPath in place, so nothing
+		// needs to reproduce the original tree layout - only srcPath itself
+		// (used as the map key) matters to the build.
+		outPath := filepath.Join(workspace.dir, fmt.Sprintf("overlay_%d_%s", i, filepath.Base(srcPath)))
 
-		// Write instrumented code to workspace
+		// Write instrumented code to the workspace
 		if err := os.WriteFile(outPath, []byte(result.Code), 0644); err != nil {
 			return fmt.Errorf("failed to write instrumented file %s: %w", outPath, err)
 		}
+		workspace.overlay[srcPath] = outPath
 
 		// Print instrumentation info
 		fmt.Printf("Instrumented: %s -> %s\n", srcPath, outPath)
@@ -364,21 +680,112 @@ func instrumentSources(config *buildConfig, workspace *workspace) error {
 			}
 			fmt.Printf("  Total: %d race detection calls inserted\n", stats.Total())
 		}
+
+		if config.coverageReport != "" {
+			fileCoverage, err := instrument.AnalyzeCoverage(srcPath, nil)
+			if err != nil {
+				return fmt.Errorf("failed to analyze coverage for %s: %w", srcPath, err)
+			}
+			coverage = append(coverage, *fileCoverage)
+		}
+	}
+
+	if len(asmFiles) > 0 {
+		fmt.Printf("Skipped (assembly, left unmodified): %s\n", strings.Join(asmFiles, ", "))
+	}
+	if len(cgoSkipped) > 0 || len(asmFiles) > 0 {
+		fmt.Printf("Total: %d cgo file(s) and %d assembly file(s) left unmodified\n", len(cgoSkipped), len(asmFiles))
+	}
+
+	if config.coverageReport != "" {
+		if err := writeCoverageReport(config.coverageReport, coverage); err != nil {
+			return fmt.Errorf("failed to write coverage report: %w", err)
+		}
+		fmt.Printf("Coverage report written: %s\n", config.coverageReport)
 	}
 
 	return nil
 }
 
+// writeCoverageReport writes a slice of per-file coverage summaries to path
+// as indented JSON.
+func writeCoverageReport(path string, coverage []instrument.FileCoverage) error {
+	data, err := json.MarshalIndent(coverage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal coverage report: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// skipDirNames lists directory names that collectGoFiles never descends
+// into when scanning a directory tree (v0.5.0): testdata is Go-tooling-
+// reserved fixture data, and dot-prefixed directories (.git, .idea, ...)
+// aren't package source. vendor is handled separately by vendorPathAllowed
+// (synth-3626), since whether to descend into it depends on -include-vendor.
+func skipDirName(name string) bool {
+	return name == "testdata" || strings.HasPrefix(name, ".")
+}
+
+// vendorPathAllowed reports whether relPath, a slash-separated path relative
+// to a vendor/ directory (e.g. "github.com/foo/bar" or an intermediate
+// prefix of it such as "github.com/foo" while still descending toward it),
+// should be walked into or instrumented, given -include-vendor's allowlist
+// (synth-3626). An empty allowlist means -include-vendor was given with no
+// restriction, so every vendored package is allowed.
+func vendorPathAllowed(relPath string, allowlist []string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	for _, entry := range allowlist {
+		entry = strings.Trim(entry, "/")
+		if entry == relPath || strings.HasPrefix(entry, relPath+"/") || strings.HasPrefix(relPath, entry+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // collectGoFiles finds all .go files from the given sources.
 //
 // Sources can be:
 //   - .go files directly
-//   - directories (scans for .go files)
+//   - directories (scanned recursively for .go files, so a helper package
+//     one level or more below the given root - e.g. one holding a type with
+//     pointer-receiver methods called from main - is instrumented too
+//     instead of silently escaping race coverage; see skipDirName for the
+//     directories this excludes)
 //   - "." for current directory
-func collectGoFiles(sources []string, workDir string) ([]string, error) {
+//   - a "..." wildcard pattern (e.g. "./cmd/...") or a bare import path with
+//     no corresponding entry on disk relative to workDir (synth-3589),
+//     resolved via resolvePackageDirs the same way `go build` itself would
+//     resolve them, then scanned one directory at a time (not recursively -
+//     "..." has already expanded every matching package individually, so
+//     recursing into each would revisit ones already listed)
+//
+// vendor/ directories are skipped entirely unless includeVendor is set
+// (-include-vendor, synth-3626); when it is, vendorAllowlist further
+// restricts instrumentation to the named import path prefixes under
+// vendor/, if non-empty, so instrumenting one suspect dependency doesn't
+// silently balloon into the whole vendor tree.
+func collectGoFiles(sources []string, workDir string, includeVendor bool, vendorAllowlist []string) ([]string, error) {
 	var goFiles []string
 
 	for _, src := range sources {
+		if strings.Contains(src, "...") {
+			dirs, err := resolvePackageDirs(src, workDir)
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve package pattern %s: %w", src, err)
+			}
+			for _, dir := range dirs {
+				files, err := scanGoDir(dir)
+				if err != nil {
+					return nil, fmt.Errorf("cannot read directory %s: %w", dir, err)
+				}
+				goFiles = append(goFiles, files...)
+			}
+			continue
+		}
+
 		// Make path absolute
 		srcPath := src
 		if !filepath.IsAbs(srcPath) {
@@ -388,27 +795,65 @@ func collectGoFiles(sources []string, workDir string) ([]string, error) {
 		// Check if it's a file or directory
 		info, err := os.Stat(srcPath)
 		if err != nil {
-			return nil, fmt.Errorf("cannot access %s: %w", src, err)
+			// Not a filesystem path relative to workDir - could still be a
+			// valid import path (e.g. "github.com/kolkov/racedetector/cmd/foo")
+			// naming a package elsewhere in the module or GOPATH/module cache.
+			dirs, listErr := resolvePackageDirs(src, workDir)
+			if listErr != nil {
+				return nil, fmt.Errorf("cannot access %s: %w", src, err)
+			}
+			for _, dir := range dirs {
+				files, err := scanGoDir(dir)
+				if err != nil {
+					return nil, fmt.Errorf("cannot read directory %s: %w", dir, err)
+				}
+				goFiles = append(goFiles, files...)
+			}
+			continue
 		}
 
 		if info.IsDir() {
-			// Scan directory for .go files
-			entries, err := os.ReadDir(srcPath)
-			if err != nil {
-				return nil, fmt.Errorf("cannot read directory %s: %w", srcPath, err)
-			}
-
-			for _, entry := range entries {
+			// Recursively scan the directory tree for .go files, so
+			// packages this one imports from elsewhere in the module are
+			// discovered and instrumented too, not just the top-level
+			// directory.
+			var vendorRoot string
+			err := filepath.WalkDir(srcPath, func(path string, entry os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
 				if entry.IsDir() {
-					continue
+					if path == srcPath {
+						return nil
+					}
+					if skipDirName(entry.Name()) {
+						return filepath.SkipDir
+					}
+					if entry.Name() == "vendor" {
+						if !includeVendor {
+							return filepath.SkipDir
+						}
+						vendorRoot = path
+						return nil
+					}
+					if vendorRoot != "" && strings.HasPrefix(path, vendorRoot+string(filepath.Separator)) {
+						rel := filepath.ToSlash(strings.TrimPrefix(path, vendorRoot+string(filepath.Separator)))
+						if !vendorPathAllowed(rel, vendorAllowlist) {
+							return filepath.SkipDir
+						}
+					}
+					return nil
 				}
 
 				name := entry.Name()
 				// Include only .go files (exclude _test.go for build)
 				if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
-					fullPath := filepath.Join(srcPath, name)
-					goFiles = append(goFiles, fullPath)
+					goFiles = append(goFiles, path)
 				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("cannot read directory %s: %w", srcPath, err)
 			}
 		} else {
 			// It's a file - check if it's a .go file
@@ -420,3 +865,54 @@ func collectGoFiles(sources []string, workDir string) ([]string, error) {
 
 	return goFiles, nil
 }
+
+// scanGoDir lists the non-test .go files directly inside dir, without
+// descending into subdirectories (synth-3589). Used for directories that
+// resolvePackageDirs already produced one-per-package - collectGoFiles'
+// own recursive walk handles the "instrument a whole subtree" case for a
+// literal directory source instead.
+func scanGoDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	return files, nil
+}
+
+// resolvePackageDirs expands a Go package pattern - a "..." wildcard like
+// "./cmd/..." or a bare import path - into the absolute directories `go
+// list` would build it from (synth-3589). Shelling out to `go list` reuses
+// the toolchain's own package pattern resolution (module-aware, respects
+// build tags and go.mod) instead of reimplementing a subset of it.
+func resolvePackageDirs(pattern string, workDir string) ([]string, error) {
+	cmd := exec.Command("go", "list", "-f", "{{.Dir}}", pattern)
+	cmd.Dir = workDir
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("go list %s: %w: %s", pattern, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("go list %s: %w", pattern, err)
+	}
+
+	var dirs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("pattern %s matched no packages", pattern)
+	}
+	return dirs, nil
+}
@@ -2,10 +2,13 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/kolkov/racedetector/cmd/racedetector/instrument"
 )
 
 // TestParseBuildArgs_SimpleFile tests parsing a single source file.
@@ -234,7 +237,7 @@ func TestCollectGoFiles(t *testing.T) {
 	}
 
 	// Test collecting from directory
-	files, err := collectGoFiles([]string{tempDir}, "")
+	files, err := collectGoFiles([]string{tempDir}, "", false, nil)
 	if err != nil {
 		t.Fatalf("collectGoFiles() error: %v", err)
 	}
@@ -270,7 +273,7 @@ func TestCollectGoFiles_SingleFile(t *testing.T) {
 	}
 
 	// Collect single file
-	files, err := collectGoFiles([]string{testFile}, "")
+	files, err := collectGoFiles([]string{testFile}, "", false, nil)
 	if err != nil {
 		t.Fatalf("collectGoFiles() error: %v", err)
 	}
@@ -294,7 +297,7 @@ func TestCollectGoFiles_EmptyDirectory(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	// Should return empty list, not error
-	files, err := collectGoFiles([]string{tempDir}, "")
+	files, err := collectGoFiles([]string{tempDir}, "", false, nil)
 	if err != nil {
 		t.Fatalf("collectGoFiles() error: %v", err)
 	}
@@ -306,12 +309,179 @@ func TestCollectGoFiles_EmptyDirectory(t *testing.T) {
 
 // TestCollectGoFiles_NonExistent tests non-existent path handling.
 func TestCollectGoFiles_NonExistent(t *testing.T) {
-	_, err := collectGoFiles([]string{"/nonexistent/path/file.go"}, "")
+	_, err := collectGoFiles([]string{"/nonexistent/path/file.go"}, "", false, nil)
 	if err == nil {
 		t.Error("Expected error for non-existent path, got nil")
 	}
 }
 
+// TestCollectGoFiles_Recursive tests that a subpackage one level below the
+// given directory is discovered too - e.g. one holding a type with
+// pointer-receiver methods called from main, which would otherwise escape
+// instrumentation entirely.
+func TestCollectGoFiles_Recursive(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-recursive-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	subDir := filepath.Join(tempDir, "helper")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("package main"), 0644); err != nil {
+		t.Fatalf("Failed to create main.go: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(subDir, "helper.go"), []byte("package helper"), 0644); err != nil {
+		t.Fatalf("Failed to create helper.go: %v", err)
+	}
+
+	files, err := collectGoFiles([]string{tempDir}, "", false, nil)
+	if err != nil {
+		t.Fatalf("collectGoFiles() error: %v", err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 .go files, got %d: %v", len(files), files)
+	}
+
+	found := map[string]bool{}
+	for _, f := range files {
+		found[f] = true
+	}
+	if !found[filepath.Join(tempDir, "main.go")] {
+		t.Errorf("Missing top-level main.go in %v", files)
+	}
+	if !found[filepath.Join(subDir, "helper.go")] {
+		t.Errorf("Missing subpackage helper.go in %v", files)
+	}
+}
+
+// TestCollectGoFiles_SkipsVendorAndDotDirs tests that vendor, testdata, and
+// dot-prefixed directories are not descended into - see skipDirName.
+func TestCollectGoFiles_SkipsVendorAndDotDirs(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-skipdirs-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"vendor", "testdata", ".git"} {
+		full := filepath.Join(tempDir, dir)
+		if err := os.MkdirAll(full, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		if err := os.WriteFile(filepath.Join(full, "skipme.go"), []byte("package skipme"), 0644); err != nil {
+			t.Fatalf("Failed to create skipme.go in %s: %v", dir, err)
+		}
+	}
+
+	files, err := collectGoFiles([]string{tempDir}, "", false, nil)
+	if err != nil {
+		t.Fatalf("collectGoFiles() error: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected 0 .go files (all under skipped dirs), got %d: %v", len(files), files)
+	}
+}
+
+// TestCollectGoFiles_IncludeVendor verifies -include-vendor's two forms
+// (synth-3626): with includeVendor set and no allowlist, every vendored
+// package is collected; with an allowlist, only the named import path
+// prefixes are.
+func TestCollectGoFiles_IncludeVendor(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-includevendor-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	wanted := filepath.Join(tempDir, "vendor", "github.com", "foo", "bar")
+	unwanted := filepath.Join(tempDir, "vendor", "github.com", "baz", "qux")
+	for _, dir := range []string{wanted, unwanted} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "lib.go"), []byte("package lib"), 0644); err != nil {
+			t.Fatalf("Failed to create lib.go in %s: %v", dir, err)
+		}
+	}
+
+	if files, err := collectGoFiles([]string{tempDir}, "", false, nil); err != nil {
+		t.Fatalf("collectGoFiles() error: %v", err)
+	} else if len(files) != 0 {
+		t.Errorf("includeVendor=false: expected 0 .go files, got %d: %v", len(files), files)
+	}
+
+	if files, err := collectGoFiles([]string{tempDir}, "", true, nil); err != nil {
+		t.Fatalf("collectGoFiles() error: %v", err)
+	} else if len(files) != 2 {
+		t.Errorf("includeVendor=true, no allowlist: expected 2 .go files, got %d: %v", len(files), files)
+	}
+
+	files, err := collectGoFiles([]string{tempDir}, "", true, []string{"github.com/foo/bar"})
+	if err != nil {
+		t.Fatalf("collectGoFiles() error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("includeVendor=true, allowlisted: expected 1 .go file, got %d: %v", len(files), files)
+	}
+	if files[0] != filepath.Join(wanted, "lib.go") {
+		t.Errorf("expected allowlisted file %s, got %s", filepath.Join(wanted, "lib.go"), files[0])
+	}
+}
+
+// TestCollectGoFiles_WildcardPattern tests that a "..." package pattern
+// (synth-3589) resolves to the .go files of every package it matches,
+// via resolvePackageDirs, rather than being treated as a literal path.
+func TestCollectGoFiles_WildcardPattern(t *testing.T) {
+	files, err := collectGoFiles([]string{"./runtime/..."}, "", false, nil)
+	if err != nil {
+		t.Fatalf("collectGoFiles() error: %v", err)
+	}
+
+	found := map[string]bool{}
+	for _, f := range files {
+		found[filepath.Base(f)] = true
+		if strings.HasSuffix(f, "_test.go") {
+			t.Errorf("Test file should be excluded: %s", f)
+		}
+	}
+	if !found["link.go"] {
+		t.Errorf("Expected link.go from ./runtime/..., got %v", files)
+	}
+}
+
+// TestCollectGoFiles_ImportPath tests that a bare import path with no
+// corresponding entry on disk relative to workDir (synth-3589) falls back
+// to resolving it as a Go package pattern instead of erroring out.
+func TestCollectGoFiles_ImportPath(t *testing.T) {
+	files, err := collectGoFiles([]string{"github.com/kolkov/racedetector/cmd/racedetector/staticvet"}, "", false, nil)
+	if err != nil {
+		t.Fatalf("collectGoFiles() error: %v", err)
+	}
+
+	found := map[string]bool{}
+	for _, f := range files {
+		found[filepath.Base(f)] = true
+	}
+	if !found["staticvet.go"] {
+		t.Errorf("Expected staticvet.go from import path, got %v", files)
+	}
+}
+
+// TestResolvePackageDirs_NoMatch tests that a pattern matching no packages
+// is reported as an error instead of silently returning no files.
+func TestResolvePackageDirs_NoMatch(t *testing.T) {
+	_, err := resolvePackageDirs("./nonexistent-package-dir/...", ".")
+	if err == nil {
+		t.Error("Expected error for a pattern matching no packages, got nil")
+	}
+}
+
 // TestNeedsValue tests flag value detection.
 func TestNeedsValue(t *testing.T) {
 	tests := []struct {
@@ -378,8 +548,12 @@ func main() {
 		t.Fatalf("instrumentSources() error: %v", err)
 	}
 
-	// Check instrumented file was created
-	instrumentedPath := filepath.Join(ws.srcDir, "main.go")
+	// Check instrumented file was staged in the overlay (synth-3590),
+	// keyed by the real source path rather than mirrored into ws.srcDir.
+	instrumentedPath, ok := ws.overlay[testFile]
+	if !ok {
+		t.Fatalf("ws.overlay missing entry for %s", testFile)
+	}
 	content, err := os.ReadFile(instrumentedPath)
 	if err != nil {
 		t.Fatalf("Failed to read instrumented file: %v", err)
@@ -398,6 +572,137 @@ func main() {
 	}
 }
 
+// TestInstrumentSources_WorkspaceMembers verifies that building inside a
+// go.work workspace instruments every member module's sources, not just
+// the one config.workDir points at (synth-3625) - otherwise a race in code
+// another member module exports would go completely uninstrumented.
+func TestInstrumentSources_WorkspaceMembers(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-instrument-workspace-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	appDir := filepath.Join(tempDir, "app")
+	libDir := filepath.Join(tempDir, "lib")
+	for _, dir := range []string{appDir, libDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+
+	appMain := filepath.Join(appDir, "main.go")
+	if err := os.WriteFile(appMain, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write app main.go: %v", err)
+	}
+	libFile := filepath.Join(libDir, "lib.go")
+	if err := os.WriteFile(libFile, []byte("package lib\n\nvar Counter int\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lib.go: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "go.mod"), []byte("module example.com/app\n\ngo 1.24.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write app go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(libDir, "go.mod"), []byte("module example.com/lib\n\ngo 1.24.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lib go.mod: %v", err)
+	}
+	goWork := "go 1.24.0\n\nuse ./app\nuse ./lib\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "go.work"), []byte(goWork), 0644); err != nil {
+		t.Fatalf("Failed to write go.work: %v", err)
+	}
+
+	ws, err := createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace() error: %v", err)
+	}
+	defer ws.cleanup()
+
+	config := &buildConfig{
+		sourceFiles: []string{"."},
+		workDir:     appDir,
+	}
+
+	if err := instrumentSources(config, ws); err != nil {
+		t.Fatalf("instrumentSources() error: %v", err)
+	}
+
+	if _, ok := ws.overlay[appMain]; !ok {
+		t.Errorf("ws.overlay missing entry for the primary module's %s", appMain)
+	}
+	if _, ok := ws.overlay[libFile]; !ok {
+		t.Errorf("ws.overlay missing entry for workspace member's %s - workspace members should be instrumented too", libFile)
+	}
+}
+
+// TestInstrumentSources_PreservesSubpackageStructure tests that a
+// subpackage's instrumented file is staged in the overlay under its own
+// real path (synth-3590), so `go build -overlay` replaces it in place
+// without disturbing the rest of the package's directory layout.
+func TestInstrumentSources_PreservesSubpackageStructure(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-instrument-sub-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	subDir := filepath.Join(tempDir, "helper")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+
+	mainSource := `package main
+
+func main() {
+	x := 42
+	println(x)
+}
+`
+	helperSource := `package helper
+
+type Counter struct {
+	n int
+}
+
+func (c *Counter) Inc() {
+	c.n = c.n + 1
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte(mainSource), 0644); err != nil {
+		t.Fatalf("Failed to create main.go: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(subDir, "helper.go"), []byte(helperSource), 0644); err != nil {
+		t.Fatalf("Failed to create helper.go: %v", err)
+	}
+
+	ws, err := createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace() error: %v", err)
+	}
+	defer ws.cleanup()
+
+	config := &buildConfig{
+		sourceFiles: []string{tempDir},
+		workDir:     tempDir,
+	}
+
+	if err := instrumentSources(config, ws); err != nil {
+		t.Fatalf("instrumentSources() error: %v", err)
+	}
+
+	mainPath := filepath.Join(tempDir, "main.go")
+	helperPath := filepath.Join(subDir, "helper.go")
+
+	if staged, ok := ws.overlay[mainPath]; !ok {
+		t.Errorf("ws.overlay missing entry for %s", mainPath)
+	} else if _, err := os.Stat(staged); err != nil {
+		t.Errorf("Missing instrumented main.go: %v", err)
+	}
+	if staged, ok := ws.overlay[helperPath]; !ok {
+		t.Errorf("ws.overlay missing entry for %s", helperPath)
+	} else if _, err := os.Stat(staged); err != nil {
+		t.Errorf("Missing instrumented helper/helper.go: %v", err)
+	}
+}
+
 // TestInstrumentSources_NoFiles tests error handling for no source files.
 func TestInstrumentSources_NoFiles(t *testing.T) {
 	ws, err := createWorkspace()
@@ -421,6 +726,216 @@ func TestInstrumentSources_NoFiles(t *testing.T) {
 	}
 }
 
+// TestInstrumentSources_SkipsCgoFile verifies a cgo file (import "C") is
+// left out of the overlay entirely - so `go build` reads it straight from
+// disk, unmodified - while a plain Go file in the same package is still
+// instrumented normally (synth-3593).
+func TestInstrumentSources_SkipsCgoFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-instrument-cgo-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cgoSource := `package main
+
+// #include <stdlib.h>
+import "C"
+
+func callC() {
+	C.free(nil)
+}
+`
+	pureSource := `package main
+
+func main() {
+	x := 42
+	println(x)
+}
+`
+	cgoFile := filepath.Join(tempDir, "cgo.go")
+	pureFile := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(cgoFile, []byte(cgoSource), 0644); err != nil {
+		t.Fatalf("Failed to create cgo.go: %v", err)
+	}
+	if err := os.WriteFile(pureFile, []byte(pureSource), 0644); err != nil {
+		t.Fatalf("Failed to create main.go: %v", err)
+	}
+
+	ws, err := createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace() error: %v", err)
+	}
+	defer ws.cleanup()
+
+	config := &buildConfig{
+		sourceFiles: []string{tempDir},
+		workDir:     tempDir,
+	}
+
+	if err := instrumentSources(config, ws); err != nil {
+		t.Fatalf("instrumentSources() error: %v", err)
+	}
+
+	if _, ok := ws.overlay[cgoFile]; ok {
+		t.Error("ws.overlay has an entry for the cgo file - it should be left unmodified")
+	}
+	if _, ok := ws.overlay[pureFile]; !ok {
+		t.Error("ws.overlay is missing the entry for the plain Go file in the same package")
+	}
+}
+
+// TestInstrumentSources_ReportsAssemblyFiles verifies that a .s file
+// alongside instrumented sources is neither collected as a Go source nor
+// silently invisible - assemblyFilesIn must surface it (synth-3593).
+func TestInstrumentSources_ReportsAssemblyFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-instrument-asm-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	pureSource := `package main
+
+func main() {
+	x := 42
+	println(x)
+}
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte(pureSource), 0644); err != nil {
+		t.Fatalf("Failed to create main.go: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "asm_amd64.s"), []byte("// empty stub\n"), 0644); err != nil {
+		t.Fatalf("Failed to create asm_amd64.s: %v", err)
+	}
+
+	files, err := assemblyFilesIn(tempDir)
+	if err != nil {
+		t.Fatalf("assemblyFilesIn() error: %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "asm_amd64.s" {
+		t.Errorf("assemblyFilesIn() = %v, want [asm_amd64.s]", files)
+	}
+
+	goFiles, err := collectGoFiles([]string{tempDir}, tempDir, false, nil)
+	if err != nil {
+		t.Fatalf("collectGoFiles() error: %v", err)
+	}
+	for _, f := range goFiles {
+		if strings.HasSuffix(f, ".s") {
+			t.Errorf("collectGoFiles() collected an assembly file: %s", f)
+		}
+	}
+}
+
+// TestParseBuildArgs_CoverageReportFlag tests -coverage-report flag parsing.
+func TestParseBuildArgs_CoverageReportFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "dash flag space",
+			args: []string{"-coverage-report", "out.json", "main.go"},
+			want: "out.json",
+		},
+		{
+			name: "dash flag equals",
+			args: []string{"-coverage-report=out.json", "main.go"},
+			want: "out.json",
+		},
+		{
+			name: "not specified",
+			args: []string{"main.go"},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseBuildArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseBuildArgs() error: %v", err)
+			}
+			if config.coverageReport != tt.want {
+				t.Errorf("coverageReport = %q, want %q", config.coverageReport, tt.want)
+			}
+		})
+	}
+}
+
+// TestInstrumentSources_CoverageReport tests that -coverage-report writes a
+// JSON summary covering every instrumented file.
+func TestInstrumentSources_CoverageReport(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-coverage-report-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	testSource := `package main
+
+type Point struct {
+	X int
+}
+
+func main() {
+	p := Point{}
+	p.X = 1
+	println(p.X)
+}
+`
+	testFile := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(testFile, []byte(testSource), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	ws, err := createWorkspace()
+	if err != nil {
+		t.Fatalf("createWorkspace() error: %v", err)
+	}
+	defer ws.cleanup()
+
+	reportPath := filepath.Join(tempDir, "coverage.json")
+	config := &buildConfig{
+		sourceFiles:    []string{testFile},
+		workDir:        tempDir,
+		coverageReport: reportPath,
+	}
+
+	if err := instrumentSources(config, ws); err != nil {
+		t.Fatalf("instrumentSources() error: %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Failed to read coverage report: %v", err)
+	}
+
+	var report []instrument.FileCoverage
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse coverage report: %v", err)
+	}
+
+	if len(report) != 1 {
+		t.Fatalf("Expected 1 file in coverage report, got %d", len(report))
+	}
+	if report[0].File != testFile {
+		t.Errorf("report[0].File = %q, want %q", report[0].File, testFile)
+	}
+
+	found := false
+	for _, fn := range report[0].Functions {
+		if fn.Name == "main" && fn.Skipped[instrument.SkipSelectorExpr] > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected main() to report skipped selector-expr accesses, got: %+v", report[0].Functions)
+	}
+}
+
 // BenchmarkParseBuildArgs benchmarks argument parsing.
 func BenchmarkParseBuildArgs(b *testing.B) {
 	args := []string{"-o", "myapp", "-ldflags", "-s -w", "main.go", "server.go"}
@@ -0,0 +1,87 @@
+// cache.go implements a content-hash keyed on-disk cache of instrumented
+// sources, shared across 'racedetector test' invocations.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/kolkov/racedetector/cmd/racedetector/instrument"
+	"github.com/kolkov/racedetector/cmd/racedetector/runtime"
+)
+
+// instrumentCacheDir returns the root directory for the instrumentation
+// cache, creating it if necessary.
+//
+// The cache lives under the user's cache directory (like the Go build
+// cache) so it survives across invocations and is shared by every project
+// instrumented on the machine. Entries are namespaced by tool version so
+// that upgrading racedetector invalidates stale entries automatically.
+func instrumentCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		// Fall back to a temp directory if the platform has no cache dir
+		// (e.g. restricted sandboxes). Best effort - still correct, just
+		// not persistent across reboots.
+		base = os.TempDir()
+	}
+	dir := filepath.Join(base, "racedetector", runtime.Version, "instrument")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cacheKey computes the content-hash cache key for a source file.
+//
+// The key covers the file's content only - instrumentation is a pure
+// function of the source bytes for a given tool version (already encoded in
+// the cache directory path), so identical content always instruments to
+// identical output.
+func cacheKey(src []byte) string {
+	sum := sha256.Sum256(src)
+	return hex.EncodeToString(sum[:])
+}
+
+// instrumentCached instruments a single file, consulting the on-disk cache
+// first and populating it on a miss.
+//
+// This is the building block for parallel per-package instrumentation: each
+// call is independent and safe to run concurrently from multiple goroutines
+// as long as they target distinct cache directories (a fresh os.MkdirTemp
+// output path per call, as instrumentTestSources already arranges).
+func instrumentCached(cacheDir, srcPath string) (*instrument.InstrumentResult, error) {
+	src, err := os.ReadFile(srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cacheKey(src)
+	entryPath := filepath.Join(cacheDir, key+".go")
+
+	if cached, err := os.ReadFile(entryPath); err == nil {
+		return &instrument.InstrumentResult{Code: string(cached)}, nil
+	}
+
+	result, err := instrument.InstrumentFile(srcPath, src)
+	if err != nil {
+		return nil, err
+	}
+
+	// Best effort: write to a temp file and rename, so concurrent writers
+	// racing on the same key never observe a partially-written entry.
+	tmp, err := os.CreateTemp(cacheDir, "tmp-*")
+	if err == nil {
+		if _, werr := tmp.WriteString(result.Code); werr == nil {
+			_ = tmp.Close()
+			_ = os.Rename(tmp.Name(), entryPath)
+		} else {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,87 @@
+// cache_test.go implements tests for the instrumentation cache.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestInstrumentCacheDir tests the instrumentCacheDir function.
+func TestInstrumentCacheDir(t *testing.T) {
+	dir, err := instrumentCacheDir()
+	if err != nil {
+		t.Fatalf("instrumentCacheDir() error = %v", err)
+	}
+	if dir == "" {
+		t.Fatal("instrumentCacheDir() returned empty path")
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("instrumentCacheDir() did not create dir: %v", err)
+	}
+	if !info.IsDir() {
+		t.Fatalf("instrumentCacheDir() = %q, not a directory", dir)
+	}
+}
+
+// TestCacheKey tests the cacheKey function.
+func TestCacheKey(t *testing.T) {
+	keyA := cacheKey([]byte("package main\n"))
+	keyB := cacheKey([]byte("package main\n"))
+	if keyA != keyB {
+		t.Errorf("cacheKey() not deterministic: %q != %q", keyA, keyB)
+	}
+
+	keyC := cacheKey([]byte("package other\n"))
+	if keyA == keyC {
+		t.Errorf("cacheKey() collided for different content: %q", keyA)
+	}
+}
+
+// TestInstrumentCached tests the instrumentCached function, covering both
+// the cache-miss and cache-hit paths.
+func TestInstrumentCached(t *testing.T) {
+	cacheDir := t.TempDir()
+	srcDir := t.TempDir()
+
+	srcPath := filepath.Join(srcDir, "sample.go")
+	src := "package sample\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n"
+	if err := os.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := instrumentCached(cacheDir, srcPath)
+	if err != nil {
+		t.Fatalf("instrumentCached() error = %v", err)
+	}
+	if result.Code == "" {
+		t.Fatal("instrumentCached() returned empty code")
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("instrumentCached() left %d cache entries, want 1", len(entries))
+	}
+
+	// Second call with identical content should hit the cache and return
+	// the same code without re-instrumenting.
+	cached, err := instrumentCached(cacheDir, srcPath)
+	if err != nil {
+		t.Fatalf("instrumentCached() (cache hit) error = %v", err)
+	}
+	if cached.Code != result.Code {
+		t.Errorf("instrumentCached() cache hit code = %q, want %q", cached.Code, result.Code)
+	}
+
+	entriesAfter, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entriesAfter) != 1 {
+		t.Errorf("instrumentCached() cache hit created extra entries: %d", len(entriesAfter))
+	}
+}
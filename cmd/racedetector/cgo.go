@@ -0,0 +1,61 @@
+// cgo.go detects source files instrumentation must leave untouched: cgo
+// files (import "C") and Go assembly files (synth-3593).
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isCgoFile reports whether src is a cgo source file - one with a top-level
+// `import "C"` - by parsing just far enough to see its import declarations.
+//
+// cgo preprocessing (go tool cgo, which `go build` invokes automatically for
+// any file in this state) requires the comment immediately preceding
+// `import "C"` to survive byte-for-byte as the C preamble. go/printer's
+// AST-based regeneration gives no such guarantee - reformatting can reflow
+// or detach that comment - so instrumenting a cgo file risks breaking the
+// build in a way none of instrumentation's other conservative skips do.
+// Such files are left on disk and built as-is; see
+// instrumentSources/instrumentCommand.
+func isCgoFile(filename string, src []byte) bool {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ImportsOnly)
+	if err != nil {
+		// Not our job to report parse errors here - InstrumentFile will
+		// hit (and report) the same error shortly after.
+		return false
+	}
+	for _, imp := range file.Imports {
+		if imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+// assemblyFilesIn lists the Go assembly files (.s) directly inside dir.
+//
+// collectGoFiles never collects these in the first place - its scan only
+// matches ".go" - so an assembly file is already left untouched by
+// construction. This exists purely so callers can report that it happened,
+// instead of the file quietly never showing up anywhere.
+func assemblyFilesIn(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".s") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,39 @@
+// cgo_test.go tests cgo/assembly file detection.
+package main
+
+import "testing"
+
+func TestIsCgoFile_ImportC(t *testing.T) {
+	src := []byte(`package main
+
+// #include <stdlib.h>
+import "C"
+
+func f() {
+	C.free(nil)
+}
+`)
+	if !isCgoFile("cgo.go", src) {
+		t.Error("isCgoFile() = false, want true for a file importing \"C\"")
+	}
+}
+
+func TestIsCgoFile_PlainGo(t *testing.T) {
+	src := []byte(`package main
+
+import "fmt"
+
+func f() {
+	fmt.Println("hi")
+}
+`)
+	if isCgoFile("plain.go", src) {
+		t.Error("isCgoFile() = true, want false for a file with no cgo import")
+	}
+}
+
+func TestIsCgoFile_SyntaxError(t *testing.T) {
+	if isCgoFile("bad.go", []byte("not valid go {{{")) {
+		t.Error("isCgoFile() = true, want false for unparsable source")
+	}
+}
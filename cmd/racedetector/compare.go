@@ -0,0 +1,263 @@
+// compare.go implements the 'racedetector compare' command (synth-3583).
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// compareConfig holds configuration for the compare command.
+type compareConfig struct {
+	// Package patterns to test (e.g., "./...", "./internal/...")
+	packages []string
+
+	// Working directory
+	workDir string
+}
+
+// compareCommand implements 'racedetector compare ./...': runs the same
+// test packages under both the official CGO -race detector (when
+// available) and this pure-Go detector, and diffs the source locations
+// each one reports a race at.
+//
+// This exists to answer the question every prospective user of a
+// CGO-free race detector eventually asks: how much do its verdicts
+// actually agree with the real thing? A location -race finds that
+// racedetector misses is a false negative (silent risk); a location
+// racedetector finds that -race doesn't is a false positive (erodes
+// trust in adoption). Neither is visible from a single tool's own
+// output - the whole point of this command is to run both and diff.
+//
+// Flow:
+//  1. Run `go test -race` on config.packages, parsing WARNING: DATA RACE
+//     blocks from its output for reported source locations. Skipped, with
+//     a warning, when CGO_ENABLED=0 (see officialRaceAvailable).
+//  2. Run `racedetector test` on the same packages via the existing
+//     instrument/build/test cycle, parsing its own race reports the same
+//     way - both detectors format reports the same way (see
+//     detector.RaceReport.Format), so one extraction regex covers both.
+//  3. Diff the two location sets and print any discrepancies.
+//
+// Example:
+//
+//	racedetector compare ./...
+//	racedetector compare ./internal/...
+func compareCommand(args []string) {
+	config, err := parseCompareArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(runCompareCycle(config))
+}
+
+// parseCompareArgs parses command-line arguments for 'racedetector compare'.
+//
+// Unlike 'racedetector test', compare doesn't forward flags to either `go
+// test` invocation - it needs the exact same test run on both sides for
+// the diff to mean anything, so keeping the surface to package patterns
+// only avoids a flag silently being honored on one side and not the
+// other.
+func parseCompareArgs(args []string) (*compareConfig, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	config := &compareConfig{workDir: cwd}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		config.packages = append(config.packages, arg)
+	}
+
+	if len(config.packages) == 0 {
+		config.packages = []string{"./..."}
+	}
+
+	return config, nil
+}
+
+// runCompareCycle runs both detectors over config.packages and prints the
+// diff, returning the process exit code to use: 0 when both sides agree
+// (or -race is unavailable), 1 when they diverge.
+func runCompareCycle(config *compareConfig) int {
+	racedetectorLocations, err := runRacedetectorRace(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running racedetector test: %v\n", err)
+		return 1
+	}
+
+	if !officialRaceAvailable() {
+		fmt.Fprintf(os.Stderr, "racedetector compare: official -race detector unavailable (CGO_ENABLED=0 or no C toolchain) - showing racedetector's own findings only\n")
+		for _, loc := range sortedKeys(racedetectorLocations) {
+			fmt.Printf("racedetector: %s\n", loc)
+		}
+		return 0
+	}
+
+	officialLocations := runOfficialRace(config)
+
+	falsePositives := diffSet(racedetectorLocations, officialLocations)
+	falseNegatives := diffSet(officialLocations, racedetectorLocations)
+
+	if len(falsePositives) == 0 && len(falseNegatives) == 0 {
+		fmt.Printf("racedetector compare: agree (%d race location(s))\n", len(officialLocations))
+		return 0
+	}
+
+	for _, loc := range falsePositives {
+		fmt.Printf("false positive (racedetector only): %s\n", loc)
+	}
+	for _, loc := range falseNegatives {
+		fmt.Printf("false negative (-race only, missed by racedetector): %s\n", loc)
+	}
+
+	return 1
+}
+
+// officialRaceAvailable reports whether `go test -race` can run at all in
+// this environment. -race requires cgo, so CGO_ENABLED=0 (Docker images,
+// cross-compilation, etc. - exactly the environments racedetector exists
+// for) makes it unavailable, which compare must detect up front rather
+// than trying to interpret a failed build's error text.
+func officialRaceAvailable() bool {
+	out, err := exec.Command("go", "env", "CGO_ENABLED").Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) == "1"
+}
+
+// runOfficialRace runs `go test -race` on config.packages and returns the
+// source locations its race reports mention. Both a passing run and a run
+// that finds races exit non-zero-or-zero for reasons unrelated to whether
+// output is worth parsing, so the exit code is intentionally ignored -
+// the combined stdout+stderr text is what matters.
+func runOfficialRace(config *compareConfig) map[string]bool {
+	args := append([]string{"test", "-race"}, config.packages...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = config.workDir
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	_ = cmd.Run()
+
+	return extractRaceLocations(out.String())
+}
+
+// runRacedetectorRace runs `racedetector test` on config.packages via the
+// existing instrument/build/test cycle (see runTestCycle in test.go),
+// using the -junit race-reports side channel to recover the formatted
+// reports rather than parsing terminal output, and returns the source
+// locations they mention.
+func runRacedetectorRace(config *compareConfig) (map[string]bool, error) {
+	tConfig := &testConfig{
+		packages: config.packages,
+		workDir:  config.workDir,
+	}
+
+	workspace, err := createWorkspace()
+	if err != nil {
+		return nil, fmt.Errorf("creating workspace: %w", err)
+	}
+	defer workspace.cleanup()
+
+	if err := instrumentTestSources(tConfig, workspace); err != nil {
+		return nil, fmt.Errorf("instrumenting sources: %w", err)
+	}
+	if err := workspace.setupRuntimeLinking(); err != nil {
+		return nil, fmt.Errorf("setting up runtime: %w", err)
+	}
+
+	racesFile, err := racesFilePath()
+	if err != nil {
+		return nil, fmt.Errorf("creating races file: %w", err)
+	}
+	defer func() { _ = os.Remove(racesFile) }()
+
+	runTests(workspace, tConfig, "", []string{"RACEDETECTOR_RACES_FILE=" + racesFile})
+
+	locations := make(map[string]bool)
+	for _, report := range readRacesFile(racesFile) {
+		for loc := range extractRaceLocations(report) {
+			locations[loc] = true
+		}
+	}
+	return locations, nil
+}
+
+// raceHeaderRe matches the per-access header line inside a WARNING: DATA
+// RACE block, e.g. "Write at 0x00c0000180a0 by goroutine 7:" or
+// "Previous read at 0x00c0000180a0 by goroutine 6:" - see
+// detector.RaceReport.FormatWithSymbolizer, which both detectors' output
+// follows.
+var raceHeaderRe = regexp.MustCompile(`(?m)^(?:Previous )?(?:Read|Write) at 0x[0-9a-fA-F]+ by goroutine \d+:$`)
+
+// raceFrameRe matches one stack frame's file:line, e.g.
+// "      /path/to/file.go:10 +0x48".
+var raceFrameRe = regexp.MustCompile(`(?m)^\s+(\S+\.go):(\d+) \+0x[0-9a-fA-F]+$`)
+
+// extractRaceLocations scans output for WARNING: DATA RACE blocks and
+// returns the set of source locations at the top of each access's stack
+// trace - the site that actually performed the racing access, as opposed
+// to its callers further up the same stack.
+//
+// Locations are normalized to "basename.go:line" rather than a full path:
+// the official detector runs against the original source tree while
+// racedetector runs against an instrumented copy in a temporary
+// workspace (see runRacedetectorRace), so the two sides' absolute paths
+// never match even when they're reporting the exact same line. Basename
+// matching is an approximation - two same-named files in different
+// packages would collide - accepted here as a known simplification rather
+// than wiring the Symbolizer's ModuleRoot/ModuleImportPath rewriting
+// through this command, which would be its own follow-up.
+func extractRaceLocations(output string) map[string]bool {
+	locations := make(map[string]bool)
+
+	headers := raceHeaderRe.FindAllStringIndex(output, -1)
+	for i, h := range headers {
+		end := len(output)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+
+		segment := output[h[1]:end]
+		if m := raceFrameRe.FindStringSubmatch(segment); m != nil {
+			locations[filepath.Base(m[1])+":"+m[2]] = true
+		}
+	}
+
+	return locations
+}
+
+// diffSet returns the sorted locations present in a but not in b.
+func diffSet(a, b map[string]bool) []string {
+	var out []string
+	for loc := range a {
+		if !b[loc] {
+			out = append(out, loc)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sortedKeys returns m's keys in sorted order, for deterministic output.
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
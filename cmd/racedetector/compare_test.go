@@ -0,0 +1,144 @@
+// compare_test.go implements tests for the 'racedetector compare' command.
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestParseCompareArgs tests package-pattern parsing and the default
+// package list.
+func TestParseCompareArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "no args defaults to ./...",
+			args: nil,
+			want: []string{"./..."},
+		},
+		{
+			name: "single package",
+			args: []string{"./internal/..."},
+			want: []string{"./internal/..."},
+		},
+		{
+			name: "multiple packages",
+			args: []string{"./foo", "./bar"},
+			want: []string{"./foo", "./bar"},
+		},
+		{
+			name: "flags are ignored, not forwarded",
+			args: []string{"-v", "./foo"},
+			want: []string{"./foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseCompareArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseCompareArgs() error: %v", err)
+			}
+			if !reflect.DeepEqual(config.packages, tt.want) {
+				t.Errorf("packages = %v, want %v", config.packages, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractRaceLocations verifies the source location at the top of
+// each access's stack trace is recovered from a WARNING: DATA RACE block,
+// in the exact format both detector.RaceReport.Format and the official Go
+// race detector emit.
+func TestExtractRaceLocations(t *testing.T) {
+	output := `==================
+WARNING: DATA RACE
+Write at 0x00c0000180a0 by goroutine 7:
+  main.writer()
+      /home/user/proj/main.go:10 +0x48
+  main.worker()
+      /home/user/proj/main.go:25 +0x5c
+
+Previous Write at 0x00c0000180a0 by goroutine 6:
+  main.other()
+      /home/user/proj/other.go:42 +0x30
+  [epoch: 5@100]
+==================
+`
+
+	got := extractRaceLocations(output)
+	want := map[string]bool{
+		"main.go:10":  true,
+		"other.go:42": true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("extractRaceLocations() = %v, want %v", got, want)
+	}
+}
+
+// TestExtractRaceLocations_NoStackTrace verifies a header with no
+// matching frame (e.g. "(previous access stack trace not available)")
+// contributes nothing rather than bleeding into the next block's frame.
+func TestExtractRaceLocations_NoStackTrace(t *testing.T) {
+	output := `Write at 0x00c0000180a0 by goroutine 7:
+  main.writer()
+      /home/user/proj/main.go:10 +0x48
+
+Previous Write at 0x00c0000180a0 by goroutine 6:
+  (previous access stack trace not available)
+  [epoch: 5@100]
+`
+
+	got := extractRaceLocations(output)
+	want := map[string]bool{"main.go:10": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("extractRaceLocations() = %v, want %v", got, want)
+	}
+}
+
+// TestExtractRaceLocations_NoMatches verifies plain output (no race
+// reports) yields an empty, non-nil set.
+func TestExtractRaceLocations_NoMatches(t *testing.T) {
+	got := extractRaceLocations("PASS\nok  \tsome/pkg\t0.005s\n")
+	if len(got) != 0 {
+		t.Errorf("extractRaceLocations() = %v, want empty", got)
+	}
+}
+
+// TestDiffSet verifies diffSet returns only a's entries missing from b, sorted.
+func TestDiffSet(t *testing.T) {
+	a := map[string]bool{"a.go:1": true, "b.go:2": true, "c.go:3": true}
+	b := map[string]bool{"b.go:2": true}
+
+	got := diffSet(a, b)
+	want := []string{"a.go:1", "c.go:3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSet() = %v, want %v", got, want)
+	}
+}
+
+// TestDiffSet_NoDifference verifies identical sets diff to nothing.
+func TestDiffSet_NoDifference(t *testing.T) {
+	a := map[string]bool{"a.go:1": true}
+	if got := diffSet(a, a); len(got) != 0 {
+		t.Errorf("diffSet() = %v, want empty", got)
+	}
+}
+
+// TestSortedKeys verifies sortedKeys returns a deterministic, sorted view
+// of a set's keys.
+func TestSortedKeys(t *testing.T) {
+	m := map[string]bool{"c.go:3": true, "a.go:1": true, "b.go:2": true}
+	got := sortedKeys(m)
+	want := []string{"a.go:1", "b.go:2", "c.go:3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortedKeys() = %v, want %v", got, want)
+	}
+	if !sort.StringsAreSorted(got) {
+		t.Error("sortedKeys() result is not sorted")
+	}
+}
@@ -0,0 +1,163 @@
+// conformance.go implements the 'racedetector conformance' command
+// (synth-3637).
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kolkov/racedetector/cmd/racedetector/conformance"
+)
+
+// defaultCorpusDir is the small, hand-written stand-in for a real
+// TestRace*/TestNoRace* corpus shipped alongside the tool so
+// `racedetector conformance` has something to run out of the box - see
+// cmd/racedetector/conformance/testdata.
+const defaultCorpusDir = "cmd/racedetector/conformance/testdata"
+
+// conformanceCommand implements 'racedetector conformance [-corpus dir]':
+// it runs every TestRace*/TestNoRace* case discovered under the corpus
+// directory through this detector's own instrument/build/test cycle and
+// reports what percentage of verdicts match what each case's name
+// promises. That pass rate is the same conformance metric Go's own
+// runtime/race output_test.go computes for the reference implementation,
+// tracked here release to release the same way benchmarks/*.txt already
+// tracks performance numbers - see benchmarks/README or just append a
+// dated run's output alongside them.
+//
+// The corpus directory must live inside the module `racedetector
+// conformance` is run from (see corpusPackagePattern) - vendor a real
+// upstream corpus (e.g. a checkout of $GOROOT/src/runtime/race/testdata)
+// into your own module and point -corpus at it there, rather than at an
+// external path.
+//
+// Example:
+//
+//	racedetector conformance
+//	racedetector conformance -corpus vendor/upstream-race-tests
+func conformanceCommand(args []string) {
+	corpusDir := defaultCorpusDir
+	for i, arg := range args {
+		if arg == "-corpus" && i+1 < len(args) {
+			corpusDir = args[i+1]
+		}
+	}
+
+	report, err := conformance.RunCorpus(corpusDir, runConformanceCorpus)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if report.Total() == 0 {
+		fmt.Fprintf(os.Stderr, "racedetector conformance: no TestRace*/TestNoRace* cases found in %s\n", corpusDir)
+		os.Exit(1)
+	}
+
+	for _, v := range report.Verdicts {
+		status := "ok"
+		if !v.Correct() {
+			status = "MISMATCH"
+		}
+		if v.Err != nil {
+			fmt.Printf("%-8s %-24s expect-race=%-5v error=%v\n", status, v.Name, v.ExpectRace, v.Err)
+			continue
+		}
+		fmt.Printf("%-8s %-24s expect-race=%-5v got-race=%v\n", status, v.Name, v.ExpectRace, v.GotRace)
+	}
+
+	fmt.Printf("\nracedetector conformance: %d/%d correct (%.1f%%)\n", report.CorrectCount(), report.Total(), report.Percentage())
+
+	if report.CorrectCount() != report.Total() {
+		os.Exit(1)
+	}
+}
+
+// corpusPackagePattern turns corpusDir (as given to -corpus, absolute or
+// relative to the current directory) into a package pattern relative to
+// workDir, the way instrumentTestSources expects. Instrumented output
+// preserves each source file's path relative to workDir (see
+// instrumentOneFile), so a corpus outside workDir's tree has nowhere
+// consistent to land in the instrumented workspace.
+func corpusPackagePattern(workDir, corpusDir string) (string, error) {
+	abs := corpusDir
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(workDir, corpusDir)
+	}
+
+	rel, err := filepath.Rel(workDir, abs)
+	if err != nil {
+		return "", fmt.Errorf("resolving corpus dir %s relative to %s: %w", corpusDir, workDir, err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("corpus dir %s is outside the current module (%s) - vendor it inside the module instead", corpusDir, workDir)
+	}
+
+	return "./" + filepath.ToSlash(rel), nil
+}
+
+// runConformanceCorpus is the conformance.Executor backing
+// conformanceCommand: it instruments and builds corpusDir once, then runs
+// each case with its own `-run ^Name$` filter and a fresh
+// RACEDETECTOR_RACES_FILE side channel, giving exact per-case attribution
+// of "did this test's run produce a race" without having to correlate
+// interleaved `go test -v` output against async race reports the way
+// compare.go's location-diffing does for a whole-package run.
+func runConformanceCorpus(corpusDir string, cases []conformance.Case) (map[string]bool, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+
+	corpusPkg, err := corpusPackagePattern(workDir, corpusDir)
+	if err != nil {
+		return nil, err
+	}
+
+	tConfig := &testConfig{
+		packages: []string{corpusPkg},
+		workDir:  workDir,
+	}
+
+	workspace, err := createWorkspace()
+	if err != nil {
+		return nil, fmt.Errorf("creating workspace: %w", err)
+	}
+	defer workspace.cleanup()
+
+	if err := instrumentTestSources(tConfig, workspace); err != nil {
+		return nil, fmt.Errorf("instrumenting corpus: %w", err)
+	}
+	if err := workspace.setupRuntimeLinking(); err != nil {
+		return nil, fmt.Errorf("setting up runtime: %w", err)
+	}
+
+	raceByName := make(map[string]bool, len(cases))
+	for _, c := range cases {
+		cmd := exec.Command("go", "test", "-run", "^"+c.Name+"$", corpusPkg)
+		cmd.Dir = workspace.srcDir
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			raceByName[c.Name] = false
+			continue
+		}
+
+		// A race fails the test via the race.TestCleanup(t) call every
+		// instrumented test function gets spliced into it (see
+		// instrument.InjectTestShims) - api.TestCleanup's own t.Cleanup
+		// calls t.Errorf with this exact message when it sees the race
+		// count go up during the test. Any other failure (a compile error,
+		// a panic, an assertion in the case itself) means this run isn't a
+		// usable data point at all, so it's surfaced as an error for the
+		// whole corpus rather than silently scored as "no race".
+		if !strings.Contains(string(out), "race detector:") || !strings.Contains(string(out), "data race(s) detected during "+c.Name) {
+			return raceByName, fmt.Errorf("running %s: %w\n%s", c.Name, err, out)
+		}
+		raceByName[c.Name] = true
+	}
+
+	return raceByName, nil
+}
@@ -0,0 +1,175 @@
+// Package conformance implements a corpus-based conformance checker for
+// the detector: it discovers TestRace*/TestNoRace* functions in a corpus
+// directory and scores what fraction of them this detector classifies the
+// way their own names promise (synth-3637).
+//
+// The naming convention - TestRaceXxx for a function containing a genuine
+// data race, TestNoRaceXxx for one that's properly synchronized - is the
+// same one Go's own runtime/race test suite uses for its mop_test.go-style
+// TestData programs. This package only implements discovery and scoring;
+// it deliberately knows nothing about how a case is actually built and
+// run - see Executor - so it stays usable against any corpus, real
+// upstream testdata vendored into a module or a small hand-written one
+// like cmd/racedetector/conformance/testdata.
+package conformance
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Case is one discovered corpus test function.
+type Case struct {
+	// Name is the Go test function name, e.g. "TestRaceMutex".
+	Name string
+
+	// File is the name of the source file it was declared in, relative to
+	// the corpus directory.
+	File string
+
+	// ExpectRace is true for a TestRace* function, false for TestNoRace*.
+	ExpectRace bool
+}
+
+// Verdict is the outcome of running one Case through an Executor.
+type Verdict struct {
+	Case
+	GotRace bool
+	Err     error
+}
+
+// Correct reports whether the detector's verdict matched what the case's
+// name promised. A case that failed to build or run at all (Err != nil)
+// is never correct - an inconclusive result is not a data point.
+func (v Verdict) Correct() bool {
+	return v.Err == nil && v.GotRace == v.ExpectRace
+}
+
+// Executor runs every case in corpusDir - already built as a single test
+// binary, the same way `go test` would - and reports, for each test
+// function name, whether a data race was detected during its run.
+//
+// A single shared invocation rather than one per Case is intentional:
+// the cases already live in one Go package and would be compiled into one
+// test binary regardless, so there's no way to build or run them
+// independently without the caller reinstrumenting/rebuilding per case -
+// see conformanceCommand in cmd/racedetector, which pays that cost by
+// running each case with its own `-run` filter instead, trading build
+// time for exact per-case attribution.
+type Executor func(corpusDir string, cases []Case) (raceByName map[string]bool, err error)
+
+// Report summarizes a corpus run.
+type Report struct {
+	Verdicts []Verdict
+}
+
+// Total returns the number of cases in the report.
+func (r *Report) Total() int {
+	return len(r.Verdicts)
+}
+
+// CorrectCount returns how many cases' verdicts matched their name.
+func (r *Report) CorrectCount() int {
+	n := 0
+	for _, v := range r.Verdicts {
+		if v.Correct() {
+			n++
+		}
+	}
+	return n
+}
+
+// Percentage returns the conformance percentage: CorrectCount / Total *
+// 100, or 0 for an empty corpus.
+func (r *Report) Percentage() float64 {
+	if len(r.Verdicts) == 0 {
+		return 0
+	}
+	return float64(r.CorrectCount()) / float64(len(r.Verdicts)) * 100
+}
+
+// DiscoverCases walks corpusDir (a single directory, not a "..." pattern)
+// for *_test.go files and returns every top-level TestRace*/TestNoRace*
+// function it finds, sorted by file then name for a deterministic report
+// order.
+//
+// Discovery is a plain go/parser pass, not a compile - a case doesn't
+// need to build successfully to be discovered, only to be named right;
+// build failures surface later as a Verdict.Err from the Executor.
+func DiscoverCases(corpusDir string) ([]Case, error) {
+	entries, err := os.ReadDir(corpusDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading corpus dir %s: %w", corpusDir, err)
+	}
+
+	var cases []Case
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		path := filepath.Join(corpusDir, entry.Name())
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(fn.Name.Name, "TestNoRace"):
+				cases = append(cases, Case{Name: fn.Name.Name, File: entry.Name(), ExpectRace: false})
+			case strings.HasPrefix(fn.Name.Name, "TestRace"):
+				cases = append(cases, Case{Name: fn.Name.Name, File: entry.Name(), ExpectRace: true})
+			}
+		}
+	}
+
+	sort.Slice(cases, func(i, j int) bool {
+		if cases[i].File != cases[j].File {
+			return cases[i].File < cases[j].File
+		}
+		return cases[i].Name < cases[j].Name
+	})
+	return cases, nil
+}
+
+// RunCorpus discovers every case in corpusDir and hands the whole set to
+// exec in one call, building up a Report. A discovery error (bad corpus
+// directory, unparseable source) is returned immediately; an error from
+// exec itself is recorded on every case's Verdict instead of failing the
+// whole run, so the report still lists what was expected of each case
+// even when the corpus couldn't be built at all.
+func RunCorpus(corpusDir string, exec Executor) (*Report, error) {
+	cases, err := DiscoverCases(corpusDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return &Report{}, nil
+	}
+
+	raceByName, execErr := exec(corpusDir, cases)
+
+	report := &Report{Verdicts: make([]Verdict, 0, len(cases))}
+	for _, c := range cases {
+		v := Verdict{Case: c}
+		if execErr != nil {
+			v.Err = execErr
+		} else {
+			v.GotRace = raceByName[c.Name]
+		}
+		report.Verdicts = append(report.Verdicts, v)
+	}
+	return report, nil
+}
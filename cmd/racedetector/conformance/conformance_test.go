@@ -0,0 +1,166 @@
+package conformance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCorpusFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("writing corpus file %s: %v", name, err)
+	}
+}
+
+func TestDiscoverCases(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "mutex_test.go", `package testdata
+
+import "testing"
+
+func TestRaceMutex(t *testing.T) {}
+func TestNoRaceMutex(t *testing.T) {}
+func helperNotATest() {}
+`)
+	writeCorpusFile(t, dir, "chan_test.go", `package testdata
+
+import "testing"
+
+func TestRaceChan(t *testing.T) {}
+`)
+	// Not a _test.go file - must be ignored entirely.
+	writeCorpusFile(t, dir, "helpers.go", `package testdata
+
+func TestRaceIgnored() {}
+`)
+
+	cases, err := DiscoverCases(dir)
+	if err != nil {
+		t.Fatalf("DiscoverCases: %v", err)
+	}
+
+	want := []Case{
+		{Name: "TestRaceChan", File: "chan_test.go", ExpectRace: true},
+		{Name: "TestNoRaceMutex", File: "mutex_test.go", ExpectRace: false},
+		{Name: "TestRaceMutex", File: "mutex_test.go", ExpectRace: true},
+	}
+	if len(cases) != len(want) {
+		t.Fatalf("DiscoverCases returned %d cases, want %d: %+v", len(cases), len(want), cases)
+	}
+	for i, c := range cases {
+		if c != want[i] {
+			t.Errorf("case %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+}
+
+func TestDiscoverCases_MissingDir(t *testing.T) {
+	if _, err := DiscoverCases(filepath.Join(t.TempDir(), "does-not-exist")); err == nil {
+		t.Fatal("expected an error for a missing corpus directory, got nil")
+	}
+}
+
+func TestVerdict_Correct(t *testing.T) {
+	tests := []struct {
+		name string
+		v    Verdict
+		want bool
+	}{
+		{"race expected and found", Verdict{Case: Case{ExpectRace: true}, GotRace: true}, true},
+		{"no race expected, none found", Verdict{Case: Case{ExpectRace: false}, GotRace: false}, true},
+		{"race expected but missed", Verdict{Case: Case{ExpectRace: true}, GotRace: false}, false},
+		{"race found but not expected", Verdict{Case: Case{ExpectRace: false}, GotRace: true}, false},
+		{"run error counts as incorrect even if flags happen to match", Verdict{Case: Case{ExpectRace: true}, GotRace: true, Err: errors.New("build failed")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.v.Correct(); got != tt.want {
+				t.Errorf("Correct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCorpus(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "mop_test.go", `package testdata
+
+import "testing"
+
+func TestRaceMutex(t *testing.T) {}
+func TestNoRaceMutex(t *testing.T) {}
+`)
+
+	report, err := RunCorpus(dir, func(corpusDir string, cases []Case) (map[string]bool, error) {
+		if corpusDir != dir {
+			t.Errorf("exec got corpusDir %q, want %q", corpusDir, dir)
+		}
+		if len(cases) != 2 {
+			t.Fatalf("exec got %d cases, want 2", len(cases))
+		}
+		return map[string]bool{"TestRaceMutex": true}, nil
+	})
+	if err != nil {
+		t.Fatalf("RunCorpus: %v", err)
+	}
+
+	if report.Total() != 2 {
+		t.Fatalf("Total() = %d, want 2", report.Total())
+	}
+	if got, want := report.CorrectCount(), 2; got != want {
+		t.Errorf("CorrectCount() = %d, want %d (both TestRaceMutex=true and TestNoRaceMutex=false match)", got, want)
+	}
+	if got, want := report.Percentage(), 100.0; got != want {
+		t.Errorf("Percentage() = %v, want %v", got, want)
+	}
+}
+
+func TestRunCorpus_ExecutorErrorRecordedOnEveryVerdict(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "mop_test.go", `package testdata
+
+import "testing"
+
+func TestRaceMutex(t *testing.T) {}
+`)
+
+	execErr := errors.New("go test failed to build")
+	report, err := RunCorpus(dir, func(corpusDir string, cases []Case) (map[string]bool, error) {
+		return nil, execErr
+	})
+	if err != nil {
+		t.Fatalf("RunCorpus: %v", err)
+	}
+	if report.Total() != 1 {
+		t.Fatalf("Total() = %d, want 1", report.Total())
+	}
+	if report.Verdicts[0].Err != execErr {
+		t.Errorf("Verdicts[0].Err = %v, want %v", report.Verdicts[0].Err, execErr)
+	}
+	if report.CorrectCount() != 0 {
+		t.Errorf("CorrectCount() = %d, want 0 (an execution error is never correct)", report.CorrectCount())
+	}
+}
+
+func TestRunCorpus_EmptyCorpus(t *testing.T) {
+	dir := t.TempDir()
+	called := false
+	report, err := RunCorpus(dir, func(corpusDir string, cases []Case) (map[string]bool, error) {
+		called = true
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("RunCorpus: %v", err)
+	}
+	if called {
+		t.Error("exec was called for an empty corpus, want it skipped entirely")
+	}
+	if report.Total() != 0 {
+		t.Errorf("Total() = %d, want 0", report.Total())
+	}
+	if got, want := report.Percentage(), 0.0; got != want {
+		t.Errorf("Percentage() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+// diff.go implements a minimal unified-diff renderer for 'racedetector
+// instrument -diff' (synth-3590), so instrumentCommand doesn't need an
+// external diff dependency for what's normally a handful of inserted lines
+// per file.
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unifiedDiff renders a unified diff (like `diff -u`, minus the file
+// timestamp header) between oldSrc and newSrc, labeling the two sides
+// oldLabel and newLabel. Returns "" if the two are identical.
+func unifiedDiff(oldLabel, newLabel, oldSrc, newSrc string) string {
+	oldLines := splitLines(oldSrc)
+	newLines := splitLines(newSrc)
+
+	hunks := diffHunks(oldLines, newLines)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n", oldLabel)
+	fmt.Fprintf(&b, "+++ %s\n", newLabel)
+	for _, h := range hunks {
+		b.WriteString(h.header())
+		for _, line := range h.lines {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// splitLines splits src into lines without keeping the trailing newline,
+// matching the convention diffHunks' line-by-line LCS expects.
+func splitLines(src string) []string {
+	if src == "" {
+		return nil
+	}
+	lines := strings.Split(src, "\n")
+	// A trailing newline produces one spurious empty final element.
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffHunk is one contiguous block of context/added/removed lines, in the
+// unified diff format's "@@ -oldStart,oldCount +newStart,newCount @@" style.
+type diffHunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	lines              []string // each prefixed with " ", "-", or "+"
+}
+
+func (h diffHunk) header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", h.oldStart, h.oldCount, h.newStart, h.newCount)
+}
+
+// diffHunks computes the line-level diff between old and new via a
+// classic LCS (longest common subsequence) table, then groups the
+// resulting edit script into unified-diff hunks with 3 lines of
+// surrounding context, same as `diff -u`'s default.
+func diffHunks(old, new []string) []diffHunk {
+	lcs := longestCommonSubsequence(old, new)
+
+	type op struct {
+		kind byte // ' ', '-', or '+'
+		line string
+	}
+	var ops []op
+
+	i, j, k := 0, 0, 0
+	for i < len(old) || j < len(new) {
+		if k < len(lcs) && i < len(old) && j < len(new) && old[i] == lcs[k] && new[j] == lcs[k] {
+			ops = append(ops, op{' ', old[i]})
+			i++
+			j++
+			k++
+			continue
+		}
+		if i < len(old) && (k >= len(lcs) || old[i] != lcs[k]) {
+			ops = append(ops, op{'-', old[i]})
+			i++
+			continue
+		}
+		if j < len(new) {
+			ops = append(ops, op{'+', new[j]})
+			j++
+		}
+	}
+
+	const context = 3
+
+	var hunks []diffHunk
+	oldLine, newLine := 1, 1
+	n := len(ops)
+	for start := 0; start < n; {
+		if ops[start].kind == ' ' {
+			oldLine++
+			newLine++
+			start++
+			continue
+		}
+
+		// Found a change; back up to include leading context.
+		hunkStart := start
+		for c := 0; c < context && hunkStart > 0 && ops[hunkStart-1].kind == ' '; c++ {
+			hunkStart--
+		}
+
+		hunkOldLine := oldLine - (start - hunkStart)
+		hunkNewLine := newLine - (start - hunkStart)
+
+		// Extend the hunk through changes and the context between them,
+		// merging adjacent changes that are within 2*context of each other.
+		end := start
+		trailingContext := 0
+		for end < n && trailingContext < context {
+			if ops[end].kind == ' ' {
+				trailingContext++
+			} else {
+				trailingContext = 0
+			}
+			end++
+		}
+
+		var lines []string
+		oldCount, newCount := 0, 0
+		for idx := hunkStart; idx < end; idx++ {
+			lines = append(lines, string(ops[idx].kind)+ops[idx].line)
+			switch ops[idx].kind {
+			case ' ':
+				oldCount++
+				newCount++
+			case '-':
+				oldCount++
+			case '+':
+				newCount++
+			}
+		}
+
+		hunks = append(hunks, diffHunk{
+			oldStart: hunkOldLine,
+			oldCount: oldCount,
+			newStart: hunkNewLine,
+			newCount: newCount,
+			lines:    lines,
+		})
+
+		for idx := start; idx < end; idx++ {
+			if ops[idx].kind != '+' {
+				oldLine++
+			}
+			if ops[idx].kind != '-' {
+				newLine++
+			}
+		}
+		start = end
+	}
+
+	return hunks
+}
+
+// longestCommonSubsequence returns the longest common subsequence of a and
+// b, computed with the standard O(len(a)*len(b)) dynamic-programming table.
+// Instrumented files are small enough (single source files) that this
+// simple approach doesn't need Myers' linear-space refinement.
+func longestCommonSubsequence(a, b []string) []string {
+	n, m := len(a), len(b)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] >= dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	var lcs []string
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			lcs = append(lcs, a[i])
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return lcs
+}
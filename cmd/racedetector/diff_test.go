@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestUnifiedDiff_NoChange verifies identical inputs produce no diff.
+func TestUnifiedDiff_NoChange(t *testing.T) {
+	src := "package main\n\nfunc main() {}\n"
+	if diff := unifiedDiff("a.go", "b.go", src, src); diff != "" {
+		t.Errorf("unifiedDiff() on identical input = %q, want empty", diff)
+	}
+}
+
+// TestUnifiedDiff_InsertedLines verifies added lines show up with a "+"
+// prefix and enough context to place them.
+func TestUnifiedDiff_InsertedLines(t *testing.T) {
+	old := "package main\n\nfunc main() {\n\tx := 1\n\tprintln(x)\n}\n"
+	new := "package main\n\nfunc main() {\n\trace.Init()\n\tx := 1\n\tprintln(x)\n}\n"
+
+	diff := unifiedDiff("orig.go", "orig.go (instrumented)", old, new)
+	if diff == "" {
+		t.Fatal("unifiedDiff() = empty, want a diff")
+	}
+
+	if !strings.Contains(diff, "--- orig.go\n") {
+		t.Errorf("diff missing old-file header:\n%s", diff)
+	}
+	if !strings.Contains(diff, "+++ orig.go (instrumented)\n") {
+		t.Errorf("diff missing new-file header:\n%s", diff)
+	}
+	if !strings.Contains(diff, "+\trace.Init()") {
+		t.Errorf("diff missing added line:\n%s", diff)
+	}
+	// Unchanged surrounding lines should appear as context.
+	if !strings.Contains(diff, " func main() {") {
+		t.Errorf("diff missing context line:\n%s", diff)
+	}
+}
+
+// TestUnifiedDiff_RemovedLines verifies removed lines show up with a "-"
+// prefix.
+func TestUnifiedDiff_RemovedLines(t *testing.T) {
+	old := "package main\n\nfunc main() {\n\tx := 1\n\t_ = x\n}\n"
+	new := "package main\n\nfunc main() {\n}\n"
+
+	diff := unifiedDiff("a.go", "b.go", old, new)
+	if !strings.Contains(diff, "-\tx := 1") {
+		t.Errorf("diff missing removed line:\n%s", diff)
+	}
+	if !strings.Contains(diff, "-\t_ = x") {
+		t.Errorf("diff missing removed line:\n%s", diff)
+	}
+}
+
+// TestLongestCommonSubsequence verifies the LCS helper against a known case.
+func TestLongestCommonSubsequence(t *testing.T) {
+	a := []string{"a", "b", "c", "d"}
+	b := []string{"a", "c", "d", "e"}
+
+	got := longestCommonSubsequence(a, b)
+	want := []string{"a", "c", "d"}
+
+	if len(got) != len(want) {
+		t.Fatalf("longestCommonSubsequence() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("longestCommonSubsequence()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// instrument.go implements the 'racedetector instrument' command.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kolkov/racedetector/cmd/racedetector/instrument"
+)
+
+// instrumentConfig holds parsed arguments for 'racedetector instrument'.
+type instrumentConfig struct {
+	// Source files/patterns to instrument (same resolution as build's
+	// sourceFiles - see collectGoFiles).
+	sourceFiles []string
+
+	// Working directory, used to resolve relative sourceFiles.
+	workDir string
+
+	// diff selects unified-diff output (-diff flag) instead of printing
+	// the instrumented source in full.
+	diff bool
+}
+
+// parseInstrumentArgs parses command-line arguments for
+// 'racedetector instrument'.
+func parseInstrumentArgs(args []string) (*instrumentConfig, error) {
+	config := &instrumentConfig{
+		sourceFiles: []string{},
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	config.workDir = cwd
+
+	for _, arg := range args {
+		if arg == "-diff" {
+			config.diff = true
+			continue
+		}
+		config.sourceFiles = append(config.sourceFiles, arg)
+	}
+
+	if len(config.sourceFiles) == 0 {
+		return nil, fmt.Errorf("no source files specified")
+	}
+
+	return config, nil
+}
+
+// instrumentCommand implements 'racedetector instrument': it runs the same
+// AST instrumentation build would, but only prints the result - either the
+// full instrumented source or, with -diff, a unified diff against the
+// original file - without writing anything to disk or invoking `go build`
+// (synth-3590). Useful for inspecting exactly what instrumentation a file
+// will receive before committing to a full build.
+//
+// Example:
+//
+//	racedetector instrument -diff main.go
+//	racedetector instrument ./internal/worker/...
+func instrumentCommand(args []string) {
+	config, err := parseInstrumentArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	goFiles, err := collectGoFiles(config.sourceFiles, config.workDir, false, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to collect source files: %v\n", err)
+		os.Exit(1)
+	}
+	if len(goFiles) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no Go source files found")
+		os.Exit(1)
+	}
+
+	for i, srcPath := range goFiles {
+		original, err := os.ReadFile(srcPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", srcPath, err)
+			os.Exit(1)
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+
+		// Cgo files are left untouched rather than instrumented - see
+		// isCgoFile (synth-3593).
+		if isCgoFile(srcPath, original) {
+			fmt.Printf("%s: cgo file, left unmodified\n", srcPath)
+			continue
+		}
+
+		result, err := instrument.InstrumentFile(srcPath, original)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to instrument %s: %v\n", srcPath, err)
+			os.Exit(1)
+		}
+
+		if config.diff {
+			diff := unifiedDiff(srcPath, srcPath+" (instrumented)", string(original), result.Code)
+			if diff == "" {
+				fmt.Printf("%s: no instrumentation applied\n", srcPath)
+				continue
+			}
+			fmt.Print(diff)
+			continue
+		}
+
+		fmt.Printf("// --- %s (instrumented) ---\n", srcPath)
+		fmt.Print(result.Code)
+	}
+}
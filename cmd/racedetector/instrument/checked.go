@@ -0,0 +1,145 @@
+// checked.go implements the "//racedetector:checked" source annotation:
+// a standalone comment immediately preceding an explicit `{ ... }` block
+// that declares the block intentionally racy-but-benign, or protected by an
+// external synchronization mechanism the detector can't see (synth-3599).
+//
+// Unlike "//racedetector:ignore" (see visitor.go), which removes
+// instrumentation statically and leaves no runtime trace, a checked block
+// still runs inside a DisableCurrentGoroutine/EnableCurrentGoroutine region
+// and registers itself with race.RegisterCheckedAnnotation, so Fini()'s
+// summary report can list every checked block a given run actually
+// exercised - an auditor can tell a race-freedom claim that never ran from
+// one that did.
+//
+// Status: unlike CoalescingAnalyzer/LockRegionAnalyzer (see coalescing.go,
+// lockregion.go), this isn't an optional optimization pass gated behind a
+// future CLI flag - the annotation itself is the developer's opt-in, so
+// ApplyCheckedAnnotations always runs as part of instrumentAST's default
+// pipeline.
+//
+// Thread Safety: NOT thread-safe (single-threaded instrumentation).
+package instrument
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// checkedDirective is the comment text (after stripping "//" and
+// surrounding whitespace) that marks the following block as checked:
+// //racedetector:checked
+const checkedDirective = "racedetector:checked"
+
+// checkedBlock pairs a "//racedetector:checked" annotated block with the
+// source location recorded for Fini()'s audit report.
+type checkedBlock struct {
+	// Block is the annotated *ast.BlockStmt.
+	Block *ast.BlockStmt
+
+	// Location is the "file:line" string passed to
+	// race.RegisterCheckedAnnotation, pointing at the block's opening brace.
+	Location string
+}
+
+// CheckedAnnotationStats tracks "//racedetector:checked" processing,
+// mirroring CoalescingStats/LockRegionStats.
+type CheckedAnnotationStats struct {
+	AnnotationsFound int // Number of "//racedetector:checked" blocks found
+	RegionsWrapped   int // Blocks wrapped in a Disable/Enable region
+	SkippedEarlyExit int // Blocks left instrumented normally (see blockHasEarlyExit)
+}
+
+// buildCheckedBlocks scans file's comments for "//racedetector:checked"
+// markers immediately preceding a block statement, and returns each
+// annotated block paired with its source location.
+//
+// Only a standalone comment directly preceding an explicit `{ ... }` block
+// is recognized - a comment placed before, say, an if-statement documents
+// the if-statement, not its body, so it isn't treated as an annotation.
+// Wrap the intended code in its own block if it isn't one already:
+//
+//	//racedetector:checked
+//	{
+//	    sharedCounter++ // protected by an external barrier the detector can't see
+//	}
+//
+// Uses ast.NewCommentMap, same as buildIgnoredLines, so the directive is
+// recognized both as a standalone comment on the line above the block and
+// as a trailing comment on the opening brace's line.
+//
+// Requires the file to have been parsed with parser.ParseComments so
+// file.Comments is populated; InstrumentFile already does this.
+func buildCheckedBlocks(fset *token.FileSet, file *ast.File) []checkedBlock {
+	var blocks []checkedBlock
+	cmap := ast.NewCommentMap(fset, file, file.Comments)
+	for node, groups := range cmap {
+		block, ok := node.(*ast.BlockStmt)
+		if !ok {
+			continue
+		}
+		for _, group := range groups {
+			for _, c := range group.List {
+				if strings.TrimSpace(strings.TrimPrefix(c.Text, "//")) != checkedDirective {
+					continue
+				}
+				pos := fset.Position(block.Pos())
+				blocks = append(blocks, checkedBlock{
+					Block:    block,
+					Location: fmt.Sprintf("%s:%d", pos.Filename, pos.Line),
+				})
+			}
+		}
+	}
+	return blocks
+}
+
+// blockHasEarlyExit reports whether block contains, at its top level, a
+// return or branch statement (break/continue/goto/fallthrough) that could
+// leave the block without reaching its final statement.
+//
+// This matters because ApplyCheckedAnnotations splices
+// DisableCurrentGoroutine/EnableCurrentGoroutine calls in as the block's
+// first and last statements. A function body could rely on `defer` to
+// guarantee EnableCurrentGoroutine still runs on early return; a plain
+// block has no such mechanism. Blocks with a top-level early exit are left
+// instrumented normally instead of risking a goroutine whose detection
+// never gets re-enabled.
+//
+// A panic inside the block is not detected here - like everywhere else in
+// Go, only `defer` can guard against that, and a block can't defer. This
+// is a known, documented limitation of block-scoped (rather than
+// function-scoped) annotations.
+func blockHasEarlyExit(block *ast.BlockStmt) bool {
+	for _, stmt := range block.List {
+		switch stmt.(type) {
+		case *ast.ReturnStmt, *ast.BranchStmt:
+			return true
+		}
+	}
+	return false
+}
+
+// stripCheckedDirectives removes "//racedetector:checked" comments from
+// file.Comments in place, once buildCheckedBlocks has already read them.
+// See stripIgnoreDirectives for why directive comments must not survive
+// into the printed output.
+func stripCheckedDirectives(file *ast.File) {
+	kept := file.Comments[:0]
+	for _, group := range file.Comments {
+		keptComments := group.List[:0]
+		for _, c := range group.List {
+			if strings.TrimSpace(strings.TrimPrefix(c.Text, "//")) == checkedDirective {
+				continue
+			}
+			keptComments = append(keptComments, c)
+		}
+		if len(keptComments) == 0 {
+			continue
+		}
+		group.List = keptComments
+		kept = append(kept, group)
+	}
+	file.Comments = kept
+}
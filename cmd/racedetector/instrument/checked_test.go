@@ -0,0 +1,201 @@
+// Package instrument - Tests for "//racedetector:checked" annotations (synth-3599).
+package instrument
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// TestBuildCheckedBlocks_FindsAnnotatedBlock verifies a standalone comment
+// immediately preceding a block statement is recognized as an annotation.
+func TestBuildCheckedBlocks_FindsAnnotatedBlock(t *testing.T) {
+	code := `package main
+var counter int
+func update() {
+    //racedetector:checked
+    {
+        counter++
+    }
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	blocks := buildCheckedBlocks(fset, file)
+	if len(blocks) != 1 {
+		t.Fatalf("Expected 1 checked block, got %d", len(blocks))
+	}
+	if !strings.HasSuffix(blocks[0].Location, "test.go:5") {
+		t.Errorf("Expected location to point at the block's opening brace, got %q", blocks[0].Location)
+	}
+}
+
+// TestBuildCheckedBlocks_IgnoresOtherComments verifies an unrelated comment
+// before a block isn't mistaken for the directive.
+func TestBuildCheckedBlocks_IgnoresOtherComments(t *testing.T) {
+	code := `package main
+var counter int
+func update() {
+    // just a regular comment
+    {
+        counter++
+    }
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	blocks := buildCheckedBlocks(fset, file)
+	if len(blocks) != 0 {
+		t.Errorf("Expected 0 checked blocks, got %d", len(blocks))
+	}
+}
+
+// TestApplyCheckedAnnotations_WrapsBlock verifies a checked block is wrapped
+// in a Disable/Enable region with a RegisterCheckedAnnotation call, and its
+// own accesses aren't separately instrumented.
+func TestApplyCheckedAnnotations_WrapsBlock(t *testing.T) {
+	code := `package main
+var counter int
+func update() {
+    //racedetector:checked
+    {
+        counter++
+    }
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	visitor := newInstrumentVisitor(fset, file)
+	ast.Walk(visitor, file)
+
+	stats := visitor.ApplyCheckedAnnotations()
+	if stats.AnnotationsFound != 1 || stats.RegionsWrapped != 1 || stats.SkippedEarlyExit != 0 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+
+	if err := visitor.ApplyInstrumentation(); err != nil {
+		t.Fatalf("ApplyInstrumentation failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, file); err != nil {
+		t.Fatalf("Failed to print: %v", err)
+	}
+	code2 := buf.String()
+
+	if strings.Count(code2, "race.RaceWrite") != 0 {
+		t.Errorf("Expected checked block's write to have no RaceWrite call, got:\n%s", code2)
+	}
+	if !strings.Contains(code2, `race.RegisterCheckedAnnotation("test.go:5")`) {
+		t.Errorf("Expected a RegisterCheckedAnnotation call for the block, got:\n%s", code2)
+	}
+	if !strings.Contains(code2, "race.DisableCurrentGoroutine()") {
+		t.Errorf("Expected a DisableCurrentGoroutine call, got:\n%s", code2)
+	}
+	if !strings.Contains(code2, "race.EnableCurrentGoroutine()") {
+		t.Errorf("Expected an EnableCurrentGoroutine call, got:\n%s", code2)
+	}
+
+	disableIdx := strings.Index(code2, "race.DisableCurrentGoroutine()")
+	counterIdx := strings.Index(code2, "counter++")
+	enableIdx := strings.Index(code2, "race.EnableCurrentGoroutine()")
+	if disableIdx < 0 || counterIdx < 0 || enableIdx < 0 || !(disableIdx < counterIdx && counterIdx < enableIdx) {
+		t.Errorf("Expected Disable...counter++...Enable in that order, got:\n%s", code2)
+	}
+}
+
+// TestApplyCheckedAnnotations_EarlyExitLeftInstrumented verifies a checked
+// block containing a top-level return is left instrumented normally rather
+// than risking a permanently-disabled goroutine.
+func TestApplyCheckedAnnotations_EarlyExitLeftInstrumented(t *testing.T) {
+	code := `package main
+var counter int
+func update() bool {
+    //racedetector:checked
+    {
+        counter++
+        return true
+    }
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	visitor := newInstrumentVisitor(fset, file)
+	ast.Walk(visitor, file)
+
+	stats := visitor.ApplyCheckedAnnotations()
+	if stats.AnnotationsFound != 1 || stats.RegionsWrapped != 0 || stats.SkippedEarlyExit != 1 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+
+	if err := visitor.ApplyInstrumentation(); err != nil {
+		t.Fatalf("ApplyInstrumentation failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, file); err != nil {
+		t.Fatalf("Failed to print: %v", err)
+	}
+	code2 := buf.String()
+
+	if strings.Contains(code2, "race.DisableCurrentGoroutine()") {
+		t.Errorf("Expected no Disable/Enable region for an early-exit block, got:\n%s", code2)
+	}
+	if !strings.Contains(code2, "race.RaceWrite") {
+		t.Errorf("Expected the early-exit block's write to be instrumented normally, got:\n%s", code2)
+	}
+}
+
+// TestStripCheckedDirectives_RemovesDirectiveComment verifies the directive
+// comment itself doesn't survive into the printed output.
+func TestStripCheckedDirectives_RemovesDirectiveComment(t *testing.T) {
+	code := `package main
+var counter int
+func update() {
+    //racedetector:checked
+    {
+        counter++
+    }
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	visitor := newInstrumentVisitor(fset, file)
+	ast.Walk(visitor, file)
+	visitor.ApplyCheckedAnnotations()
+	if err := visitor.ApplyInstrumentation(); err != nil {
+		t.Fatalf("ApplyInstrumentation failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, file); err != nil {
+		t.Fatalf("Failed to print: %v", err)
+	}
+	if strings.Contains(buf.String(), "racedetector:checked") {
+		t.Errorf("Expected directive comment to be stripped, got:\n%s", buf.String())
+	}
+}
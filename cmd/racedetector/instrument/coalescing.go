@@ -26,11 +26,21 @@
 //
 // Safety Guarantees:
 // The algorithm is CONSERVATIVE - it only coalesces when proven safe:
-//  1. Operations must be consecutive (same basic block)
-//  2. No control flow between operations (no if/for/switch)
-//  3. No function calls between operations (may have side effects)
-//  4. Same variable/field (exact AST match)
-//  5. Same operation type (read OR write, not mixed)
+//  1. Operations must share a straight-line dominated region (same basic
+//     block, with nothing that branches away or calls out in between -
+//     see regionDominatesStraightLine, synth-3595)
+//  2. Same variable/field (exact AST match)
+//  3. Same operation type (read OR write, not mixed)
+//
+// Region Analysis (synth-3595):
+// The original MVP only merged operations that were textually adjacent,
+// missing the common case of an unrelated, side-effect-free statement
+// (a plain declaration, an assignment to a different variable) sitting
+// between two accesses to the same address. regionDominatesStraightLine
+// widens the rule to the full straight-line region a statement
+// dominates - anything reachable only by falling through, never by a
+// branch - which is where the 40-60% reduction target actually lives in
+// real, non-toy code.
 //
 // Performance Impact (from PLDI 2017):
 //   - 60% reduction in race check overhead (proven result)
@@ -227,8 +237,8 @@ func (ca *CoalescingAnalyzer) AnalyzeInstrumentationPoints(
 //  1. Current group must exist
 //  2. Same address (exact AST match)
 //  3. Same operation type (read or write)
-//  4. Consecutive statements (no control flow)
-//  5. No function calls between operations
+//  4. Same straight-line dominated region - no branch or call in between
+//     (regionDominatesStraightLine, synth-3595)
 //
 // Parameters:
 //   - point: Instrumentation point to check
@@ -263,20 +273,14 @@ func (ca *CoalescingAnalyzer) canJoinCurrentGroup(
 		return false
 	}
 
-	// Rule 4: Consecutive statements (no control flow)
-	// Check if there's any control flow between last operation and this one
+	// Rule 4: Same straight-line dominated region (synth-3595).
+	// The two operations don't need to be textually adjacent - only
+	// unconditionally reachable from one another with nothing that could
+	// observe or invalidate the address in between. See
+	// regionDominatesStraightLine.
 	if index > 0 {
 		lastPoint := points[index-1]
-		if hasControlFlowBetween(lastPoint.Node, point.Node, file) {
-			return false
-		}
-	}
-
-	// Rule 5: No function calls between operations
-	// Function calls may have side effects, so we break coalescing
-	if index > 0 {
-		lastPoint := points[index-1]
-		if hasFunctionCallBetween(lastPoint.Node, point.Node, file) {
+		if !regionDominatesStraightLine(lastPoint.Node, point.Node, file) {
 			return false
 		}
 	}
@@ -487,84 +491,92 @@ func astNodesEqual(a, b ast.Expr) bool {
 	}
 }
 
-// hasControlFlowBetween checks if there's control flow between two statements.
-//
-// Control Flow Statements (break coalescing):
-//   - if/else/switch: Conditional execution
-//   - for/range: Loops
-//   - goto: Unconditional jump
-//   - return: Early exit
-//   - defer: Deferred execution
-//
-// Safe Statements (allow coalescing):
-//   - Assignments: x = 42
-//   - Expressions: fmt.Println() (but breaks on function call rule)
-//   - Declarations: var x int
-//
-// For MVP, we use a conservative approach:
-// If statements are NOT in the same basic block, return true.
+// regionDominatesStraightLine reports whether stmt2 is reached from stmt1
+// through a straight-line dominated region: a run of statements, in the
+// same basic block, that stmt1 unconditionally falls through to reach
+// stmt2, none of which can affect or observe the address being coalesced
+// (synth-3595).
+//
+// This replaces the original MVP rule, which only allowed stmt2 to be the
+// textually immediate successor of stmt1 - correct but needlessly narrow,
+// since a plain declaration or unrelated assignment sitting between two
+// accesses to the same address doesn't actually threaten the coalescing
+// invariant. The dominance property itself is simple to establish without
+// building a full CFG: Go's structured control flow means any statement
+// physically between stmt1 and stmt2 IN THE SAME BLOCK is guaranteed to
+// execute on every path from stmt1 to stmt2 (there is no way to jump over
+// it), so "does the region between them dominate stmt2" reduces to
+// "does any statement in that region branch away or call out."
+//
+// Rules (still conservative - false when unsure):
+//  1. stmt1 and stmt2 must be direct children of the same BlockStmt. A
+//     different enclosing block means a branch (if/for/switch/select) sits
+//     between them, which is exactly the case a real dominator computation
+//     would also refuse to merge across without further analysis.
+//  2. Every statement strictly between them (if any) must contain no
+//     control-flow node (if/for/switch/select/branch/return/defer/go/
+//     labeled statement) and no function call - either could observe or
+//     invalidate the address, or transfer control around the region.
 //
 // Parameters:
-//   - stmt1, stmt2: Statements to check
+//   - stmt1, stmt2: Statements to check, in program order
 //   - file: AST file (for basic block analysis)
 //
 // Returns:
-//   - bool: true if control flow exists (unsafe to coalesce)
+//   - bool: true if the straight-line region from stmt1 to stmt2 is safe
+//     to coalesce across
 //
 // Thread Safety: Read-only, safe for concurrent use.
-func hasControlFlowBetween(stmt1, stmt2 ast.Node, file *ast.File) bool {
-	// For MVP, we perform simple check:
-	// If both statements are in the same BlockStmt (basic block), no control flow.
-	// Otherwise, assume control flow exists (conservative).
-
-	// Find parent blocks for both statements
-	block1 := findParentBlock(stmt1, file)
-	block2 := findParentBlock(stmt2, file)
-
-	// If different blocks, assume control flow
-	if block1 == nil || block2 == nil || block1 != block2 {
-		return true
+func regionDominatesStraightLine(stmt1, stmt2 ast.Node, file *ast.File) bool {
+	block := findParentBlock(stmt1, file)
+	if block == nil || block != findParentBlock(stmt2, file) {
+		return false
 	}
 
-	// Same block - check if statements are consecutive
-	return !areStatementsConsecutive(stmt1, stmt2, block1)
-}
+	idx1, idx2 := -1, -1
+	for i, s := range block.List {
+		if s == stmt1 {
+			idx1 = i
+		}
+		if s == stmt2 {
+			idx2 = i
+		}
+	}
+	if idx1 == -1 || idx2 == -1 || idx2 <= idx1 {
+		return false
+	}
 
-// hasFunctionCallBetween checks if there's a function call between two statements.
-//
-// Function calls may have side effects:
-//   - Modify global state
-//   - Trigger synchronization
-//   - Change variable values
-//
-// Therefore, we CANNOT coalesce operations separated by function calls.
-//
-// Examples (NOT safe to coalesce):
-//
-//	x = 1
-//	foo()  // May modify x!
-//	x = 2
-//
-// For MVP, we conservatively assume:
-// If statements are not consecutive, there MAY be a function call.
-//
-// Parameters:
-//   - stmt1, stmt2: Statements to check
-//   - file: AST file (for analysis)
-//
-// Returns:
-//   - bool: true if function call exists (unsafe to coalesce)
-//
-// Thread Safety: Read-only, safe for concurrent use.
-func hasFunctionCallBetween(stmt1, stmt2 ast.Node, file *ast.File) bool {
-	// For MVP, we assume function calls exist if statements are not consecutive
-	// This is conservative but safe
-	block := findParentBlock(stmt1, file)
-	if block == nil {
-		return true // Conservative: assume function call
+	for _, between := range block.List[idx1+1 : idx2] {
+		if regionHasBranchOrCall(between) {
+			return false
+		}
 	}
+	return true
+}
 
-	return !areStatementsConsecutive(stmt1, stmt2, block)
+// regionHasBranchOrCall reports whether stmt contains a control-flow node
+// or a function call anywhere in its subtree - either breaks the
+// straight-line dominance regionDominatesStraightLine relies on: a branch
+// can route execution around the region entirely, and a call may have side
+// effects (modify the coalesced address, or synchronize with another
+// goroutine) that a barrier deferred past it would miss.
+func regionHasBranchOrCall(stmt ast.Stmt) bool {
+	found := false
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt,
+			*ast.TypeSwitchStmt, *ast.SelectStmt, *ast.BranchStmt,
+			*ast.ReturnStmt, *ast.DeferStmt, *ast.GoStmt, *ast.LabeledStmt,
+			*ast.CallExpr:
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
 }
 
 // findParentBlock finds the BlockStmt containing a node.
@@ -602,57 +614,3 @@ func findParentBlock(node ast.Node, file *ast.File) *ast.BlockStmt {
 	return result
 }
 
-// areStatementsConsecutive checks if two statements are consecutive in a block.
-//
-// Consecutive means:
-//   - stmt2 immediately follows stmt1
-//   - No intervening statements
-//
-// Example:
-//
-//	{
-//	    x = 1  // stmt1
-//	    x = 2  // stmt2 (consecutive)
-//	}
-//
-// vs:
-//
-//	{
-//	    x = 1  // stmt1
-//	    y = 5  // intervening statement
-//	    x = 2  // stmt2 (NOT consecutive)
-//	}
-//
-// Parameters:
-//   - stmt1, stmt2: Statements to check
-//   - block: Block containing statements
-//
-// Returns:
-//   - bool: true if statements are consecutive
-//
-// Thread Safety: Read-only, safe for concurrent use.
-func areStatementsConsecutive(stmt1, stmt2 ast.Node, block *ast.BlockStmt) bool {
-	if block == nil {
-		return false
-	}
-
-	// Find indices of both statements in block
-	idx1 := -1
-	idx2 := -1
-
-	for i, s := range block.List {
-		if s == stmt1 {
-			idx1 = i
-		}
-		if s == stmt2 {
-			idx2 = i
-		}
-	}
-
-	// Check if found and consecutive
-	if idx1 == -1 || idx2 == -1 {
-		return false
-	}
-
-	return idx2 == idx1+1
-}
@@ -527,6 +527,103 @@ func main() {
 	}
 }
 
+// TestCoalescingAnalyzer_DominatedRegion verifies operations separated by a
+// side-effect-free, branch-free statement still coalesce, since the region
+// between them is straight-line dominated (synth-3595).
+func TestCoalescingAnalyzer_DominatedRegion(t *testing.T) {
+	code := `package main
+func main() {
+    x := 0
+    x = 1
+    y := 5
+    _ = y
+    x = 2
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	points := make([]InstrumentPoint, 0)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignStmt); ok && assign.Tok == token.ASSIGN {
+			for _, lhs := range assign.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name == "x" {
+					points = append(points, InstrumentPoint{
+						Node:       assign,
+						AccessType: AccessWrite,
+						Addr:       &ast.UnaryExpr{Op: token.AND, X: ident},
+					})
+				}
+			}
+		}
+		return true
+	})
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 instrumentation points, got %d", len(points))
+	}
+
+	analyzer := NewCoalescingAnalyzer()
+	groups, stats := analyzer.AnalyzeInstrumentationPoints(points, file)
+
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 coalescing group despite the intervening y := 5, got %d", len(groups))
+	}
+	if len(groups[0].Operations) != 2 {
+		t.Errorf("Expected 2 operations in group, got %d", len(groups[0].Operations))
+	}
+	if stats.BarriersRemoved != 1 {
+		t.Errorf("Expected BarriersRemoved=1, got %d", stats.BarriersRemoved)
+	}
+}
+
+// TestCoalescingAnalyzer_DominatedRegionBrokenByCall verifies a call
+// sitting in the otherwise-safe gap still breaks coalescing, since it may
+// have side effects on the coalesced address (synth-3595).
+func TestCoalescingAnalyzer_DominatedRegionBrokenByCall(t *testing.T) {
+	code := `package main
+func mutate() {}
+func main() {
+    x := 0
+    x = 1
+    y := 5
+    mutate()
+    _ = y
+    x = 2
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	points := make([]InstrumentPoint, 0)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignStmt); ok && assign.Tok == token.ASSIGN {
+			for _, lhs := range assign.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name == "x" {
+					points = append(points, InstrumentPoint{
+						Node:       assign,
+						AccessType: AccessWrite,
+						Addr:       &ast.UnaryExpr{Op: token.AND, X: ident},
+					})
+				}
+			}
+		}
+		return true
+	})
+
+	analyzer := NewCoalescingAnalyzer()
+	groups, _ := analyzer.AnalyzeInstrumentationPoints(points, file)
+
+	if len(groups) != 0 {
+		t.Errorf("Expected 0 coalescing groups with a call in the gap, got %d", len(groups))
+	}
+}
+
 // TestCoalescingStats_Empty tests statistics with no operations.
 func TestCoalescingStats_Empty(t *testing.T) {
 	analyzer := NewCoalescingAnalyzer()
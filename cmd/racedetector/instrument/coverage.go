@@ -0,0 +1,317 @@
+// Package instrument - instrumentation coverage reporting.
+//
+// This file implements a read-only analysis pass, separate from the
+// mutating instrumentAST pass, that classifies every expression
+// shouldInstrument rejects with a specific reason and attributes both
+// instrumented and skipped accesses to the function they occur in.
+//
+// It exists because InstrumentStats (visitor.go) only tracks a handful of
+// "obviously safe to skip" categories (constants, builtins, literals, the
+// blank identifier). It does not account for *ast.SelectorExpr, always
+// skipped for lack of type information, or *ast.IndexExpr, skipped unless
+// go/types confirms it isn't a map index (synth-3627) - meaning struct
+// fields, map elements, and index expressions on unresolvable types are
+// silently never instrumented, with no visibility into how often that
+// happens. Coverage reporting surfaces that blind spot on request, without
+// changing the conservative instrumentation behavior itself.
+package instrument
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+)
+
+// SkipReason classifies why an expression was not instrumented.
+type SkipReason string
+
+const (
+	SkipConstant        SkipReason = "constant"
+	SkipBuiltin         SkipReason = "builtin"
+	SkipLiteral         SkipReason = "literal"
+	SkipBlank           SkipReason = "blank"
+	SkipUnresolvedIdent SkipReason = "unresolved-identifier"
+	SkipSelectorExpr    SkipReason = "selector-expr"
+	SkipIndexExpr       SkipReason = "index-expr"
+	SkipGeneric         SkipReason = "generic-instantiation"
+	SkipOther           SkipReason = "other"
+)
+
+// initFunctionName is the bucket used for code that runs outside any
+// function body - package-level variable initializers.
+const initFunctionName = "<init>"
+
+// FunctionCoverage summarizes instrumented vs. skipped accesses within a
+// single function (or the initFunctionName bucket for file-level code).
+type FunctionCoverage struct {
+	Name               string             `json:"name"`
+	ReadsInstrumented  int                `json:"reads_instrumented"`
+	WritesInstrumented int                `json:"writes_instrumented"`
+	Skipped            map[SkipReason]int `json:"skipped,omitempty"`
+}
+
+// FileCoverage summarizes instrumentation coverage for a single source
+// file, broken down per function.
+type FileCoverage struct {
+	File      string             `json:"file"`
+	Functions []FunctionCoverage `json:"functions"`
+}
+
+// AnalyzeCoverage parses a Go source file and classifies every memory
+// access shouldInstrument would encounter, per function, without mutating
+// anything. It mirrors the access-detection logic in visitAssignment,
+// extractReads, and shouldInstrument, so its totals closely track what
+// InstrumentFile would actually instrument or skip for the same input -
+// but it is a reporting tool, not the instrumentation engine itself, so
+// treat its numbers as a close approximation rather than a guarantee.
+//
+// Parameters:
+//   - filename: Path to the Go source file (used for error messages and
+//     reported in the result)
+//   - src: Source code to analyze. Can be nil (read from filename), or any
+//     type accepted by go/parser.ParseFile.
+//
+// Returns:
+//   - *FileCoverage: Per-function breakdown, in declaration order
+//   - error: Parse error, or nil on success
+func AnalyzeCoverage(filename string, src interface{}) (*FileCoverage, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file %s: %w", filename, err)
+	}
+
+	// Best-effort type info (synth-3627), same as instrumentAST uses - so a
+	// slice/array index this reports as instrumented isn't one InstrumentFile
+	// would actually skip, and vice versa.
+	info := checkTypesBestEffort(fset, file)
+
+	result := &FileCoverage{File: filename}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Body == nil {
+				continue // external/assembly function, nothing to analyze
+			}
+			fc := newFunctionCoverage(funcDeclName(d))
+			ast.Walk(&coverageVisitor{fc: fc, info: info}, d.Body)
+			result.Functions = append(result.Functions, *fc)
+
+		case *ast.GenDecl:
+			if d.Tok != token.VAR {
+				continue
+			}
+			fc := newFunctionCoverage(initFunctionName)
+			for _, spec := range d.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for _, value := range vs.Values {
+					(&coverageVisitor{fc: fc, info: info}).recordRead(value)
+				}
+			}
+			if fc.ReadsInstrumented+fc.WritesInstrumented+len(fc.Skipped) > 0 {
+				result.Functions = append(result.Functions, *fc)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// funcDeclName formats a function declaration's name, qualifying methods
+// with their receiver type (e.g. "(*Counter).Inc") so functions with the
+// same name on different types don't collide in the report.
+func funcDeclName(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return d.Name.Name
+	}
+	recvType := d.Recv.List[0].Type
+	var recvName string
+	if star, ok := recvType.(*ast.StarExpr); ok {
+		if ident, ok := star.X.(*ast.Ident); ok {
+			recvName = "*" + ident.Name
+		}
+	} else if ident, ok := recvType.(*ast.Ident); ok {
+		recvName = ident.Name
+	}
+	if recvName == "" {
+		return d.Name.Name
+	}
+	return fmt.Sprintf("(%s).%s", recvName, d.Name.Name)
+}
+
+func newFunctionCoverage(name string) *FunctionCoverage {
+	return &FunctionCoverage{Name: name, Skipped: make(map[SkipReason]int)}
+}
+
+// coverageVisitor walks a single function body, classifying every
+// expression that visitAssignment/extractReads would consider an access
+// point in the mutating instrumentation pass.
+type coverageVisitor struct {
+	fc   *FunctionCoverage
+	info *types.Info
+}
+
+// Visit implements ast.Visitor, mirroring instrumentVisitor.Visit's node
+// selection so coverage totals line up with what InstrumentFile produces.
+func (v *coverageVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.AssignStmt:
+		for _, rhs := range n.Rhs {
+			v.recordRead(rhs)
+		}
+		if n.Tok != token.DEFINE {
+			for _, lhs := range n.Lhs {
+				v.recordWrite(lhs)
+			}
+		}
+		return nil // children already handled explicitly above
+
+	case *ast.IncDecStmt:
+		v.recordRead(n.X)
+		v.recordWrite(n.X)
+		return nil
+
+	case *ast.FuncLit:
+		// A nested closure is still part of the enclosing function's body
+		// for instrumentation purposes (it's instrumented in the same
+		// pass), so keep walking with the same visitor/bucket.
+		return v
+	}
+
+	return v
+}
+
+// recordRead classifies expr following the same descent rules as
+// extractReads (visitor.go) for composite expressions (KeyValueExpr,
+// CompositeLit, CallExpr, IndexListExpr, TypeAssertExpr, FuncLit). Keeping
+// these rules in lockstep with extractReads is what lets the coverage
+// report's totals track what InstrumentFile would actually do for the same
+// input.
+func (v *coverageVisitor) recordRead(expr ast.Expr) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.Ident:
+			v.classify(e)
+
+		case *ast.SelectorExpr:
+			v.classify(e)
+			return false // don't walk into X and Sel separately
+
+		case *ast.IndexExpr:
+			v.classify(e)
+			// A confirmed-addressable index (synth-3627) is counted as the
+			// whole access here; don't also descend into e.X and double-count
+			// the base identifier, mirroring extractReads' *ast.IndexExpr
+			// case in visitor.go. A skipped (map, or unresolvable) index
+			// still falls through to its children, same as extractReads'
+			// return true, so the base identifier is still counted.
+			if shouldInstrument(e, v.info) {
+				return false
+			}
+
+		case *ast.UnaryExpr:
+			if e.Op == token.MUL {
+				v.classify(e)
+			}
+
+		case *ast.KeyValueExpr:
+			// Only the value of a struct/map literal entry is a read; the
+			// key is a field name or map key, not a variable reference.
+			v.recordRead(e.Value)
+			return false
+
+		case *ast.CompositeLit:
+			for _, elt := range e.Elts {
+				v.recordRead(elt)
+			}
+			return false
+
+		case *ast.CallExpr:
+			for _, arg := range e.Args {
+				v.recordRead(arg)
+			}
+			return false
+
+		case *ast.IndexListExpr:
+			// Generic instantiation with 2+ type arguments (synth-3592) -
+			// classify so it shows up in the report instead of vanishing
+			// silently the way it did before shouldInstrument learned to
+			// reject it.
+			v.classify(e)
+			return false
+
+		case *ast.TypeAssertExpr:
+			v.recordRead(e.X)
+			return false
+
+		case *ast.FuncLit:
+			return false
+		}
+		return true
+	})
+}
+
+// recordWrite classifies expr as it would be classified by
+// visitAssignment's LHS handling - a single top-level classification, not a
+// recursive walk (assignment targets are not composite expressions).
+func (v *coverageVisitor) recordWrite(expr ast.Expr) {
+	if shouldInstrument(expr, v.info) {
+		v.fc.WritesInstrumented++
+		return
+	}
+	v.fc.Skipped[reasonForSkip(expr, v.info)]++
+}
+
+// classify records expr as instrumented or skipped (with reason).
+func (v *coverageVisitor) classify(expr ast.Expr) {
+	if shouldInstrument(expr, v.info) {
+		v.fc.ReadsInstrumented++
+		return
+	}
+	v.fc.Skipped[reasonForSkip(expr, v.info)]++
+}
+
+// reasonForSkip classifies why shouldInstrument rejected expr. It mirrors
+// shouldInstrument's checks in the same order, so it must be kept in sync
+// with that function.
+func reasonForSkip(expr ast.Expr, info *types.Info) SkipReason {
+	if isConstant(expr) {
+		return SkipConstant
+	}
+
+	if ident, ok := expr.(*ast.Ident); ok {
+		if ident.Name == "_" {
+			return SkipBlank
+		}
+		if isBuiltinIdent(ident.Name) {
+			return SkipBuiltin
+		}
+		if ident.Obj == nil || ident.Obj.Kind != ast.Var {
+			return SkipUnresolvedIdent
+		}
+	}
+
+	if _, ok := expr.(*ast.SelectorExpr); ok {
+		return SkipSelectorExpr
+	}
+
+	if _, ok := expr.(*ast.IndexExpr); ok {
+		return SkipIndexExpr
+	}
+
+	if _, ok := expr.(*ast.IndexListExpr); ok {
+		return SkipGeneric
+	}
+
+	if isLiteral(expr) {
+		return SkipLiteral
+	}
+
+	return SkipOther
+}
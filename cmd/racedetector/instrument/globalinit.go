@@ -0,0 +1,190 @@
+// globalinit.go instruments package-level variable initializers and wires a
+// happens-before barrier between package initialization and main() (synth-3594).
+//
+// Two gaps this closes:
+//
+//  1. A `var x = expr` declaration at package scope has no equivalent to the
+//     "insert before this statement" mechanism ApplyInstrumentation relies
+//     on (see findParentStatement) - Go doesn't allow bare statements
+//     outside a function body, only declarations. Reads inside such an
+//     initializer expression are recorded by collecting them into a
+//     synthesized init() function instead, mirroring the ":=" convention
+//     visitAssignment already applies: only the initializer's reads are
+//     recorded, never a write for the declared name itself, because a
+//     declaration isn't a modification.
+//
+//  2. Go guarantees that package initialization - every var initializer,
+//     then every init() function, in declaration order - completes before
+//     main() runs, but this detector has no way to know that on its own: two
+//     goroutines it never saw synchronize still look like a race to it. We
+//     record an explicit happens-before edge with the RaceAcquire/
+//     RaceRelease primitives race/api.go already exposes for exactly this
+//     purpose - release once package initialization has finished, acquire as
+//     the first statement of main().
+package instrument
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// raceInitBarrierVar is the package-level sentinel synthesized to carry the
+// happens-before edge between package initialization and main(). Its address
+// identifies the edge to RaceAcquire/RaceRelease; the byte itself is never
+// read or written, the same way a sync.Mutex's address identifies a lock
+// without the memory it occupies being part of what the lock protects.
+const raceInitBarrierVar = "raceInitDone"
+
+// instrumentGlobalVarReads scans file's top-level `var` declarations for
+// initializer expressions and records a read instrumentation point for every
+// variable reference found inside one, using the same shouldInstrument/
+// trackSkipped rules extractReads already applies inside function bodies.
+//
+// It returns the resulting race.RaceRead(...) statements in declaration
+// order, or nil if no top-level var declaration has an initializer worth
+// instrumenting.
+func instrumentGlobalVarReads(v *instrumentVisitor) []ast.Stmt {
+	// extractReads takes an ast.Stmt purely to tag recorded points with an
+	// owning node (used elsewhere by ApplyInstrumentation to find which
+	// block to splice a call into). A package-level initializer has no
+	// enclosing statement, so a throwaway marker stands in for one; the
+	// points collected below are turned directly into statements rather
+	// than routed through that block-splicing path.
+	marker := &ast.EmptyStmt{}
+	before := len(v.instrumentationPoints)
+
+	for _, decl := range v.file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for _, value := range valueSpec.Values {
+				v.extractReads(value, marker)
+			}
+		}
+	}
+
+	points := v.instrumentationPoints[before:]
+	if len(points) == 0 {
+		return nil
+	}
+
+	stmts := make([]ast.Stmt, 0, len(points))
+	for _, point := range points {
+		stmts = append(stmts, v.createRaceCall(point))
+	}
+	return stmts
+}
+
+// injectRaceInit wires the synthesized declarations that:
+//  1. enable the detector (race.Init()) before any init-time code runs,
+//  2. record reads from package-level var initializers, if any (varReads),
+//  3. release the raceinitdone barrier once package initialization has
+//     finished, and
+//  4. acquire that barrier as the first statement of main(), if this file
+//     declares one.
+//
+// Declaration order matters: Go runs a single file's init() functions in
+// the order they're declared, so the race.Init() init() must come first and
+// the barrier-release init() must come last for the ordering guarantee to
+// hold.
+func injectRaceInit(file *ast.File, varReads []ast.Stmt) {
+	enableInit := &ast.FuncDecl{
+		Name: ast.NewIdent("init"),
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ExprStmt{X: &ast.CallExpr{
+					Fun: &ast.SelectorExpr{X: ast.NewIdent(RacePackageAlias), Sel: ast.NewIdent("Init")},
+				}},
+			},
+		},
+	}
+
+	leading := []ast.Decl{enableInit}
+	if len(varReads) > 0 {
+		leading = append(leading, &ast.FuncDecl{
+			Name: ast.NewIdent("init"),
+			Type: &ast.FuncType{Params: &ast.FieldList{}},
+			Body: &ast.BlockStmt{List: varReads},
+		})
+	}
+
+	// Insert right after the import block(s) so these run before any
+	// user-declared init()/main - the printer requires imports to stay
+	// first, so they can't simply be prepended at index 0.
+	insertAt := 0
+	for i, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT {
+			break
+		}
+		insertAt = i + 1
+	}
+	rest := append([]ast.Decl{}, file.Decls[insertAt:]...)
+	file.Decls = append(append(file.Decls[:insertAt:insertAt], leading...), rest...)
+
+	file.Decls = append(file.Decls,
+		&ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{
+				&ast.ValueSpec{
+					Names: []*ast.Ident{ast.NewIdent(raceInitBarrierVar)},
+					Type:  ast.NewIdent("int"),
+				},
+			},
+		},
+		&ast.FuncDecl{
+			Name: ast.NewIdent("init"),
+			Type: &ast.FuncType{Params: &ast.FieldList{}},
+			Body: &ast.BlockStmt{
+				List: []ast.Stmt{raceBarrierCall("RaceRelease")},
+			},
+		},
+	)
+
+	if mainFunc := findMainFunc(file); mainFunc != nil {
+		mainFunc.Body.List = append([]ast.Stmt{raceBarrierCall("RaceAcquire")}, mainFunc.Body.List...)
+	}
+}
+
+// raceBarrierCall builds race.RaceAcquire(uintptr(unsafe.Pointer(&raceInitDone)))
+// or, with funcName "RaceRelease", its release counterpart.
+func raceBarrierCall(funcName string) ast.Stmt {
+	addr := &ast.UnaryExpr{Op: token.AND, X: ast.NewIdent(raceInitBarrierVar)}
+	unsafePointerCall := &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent("unsafe"), Sel: ast.NewIdent("Pointer")},
+		Args: []ast.Expr{addr},
+	}
+	uintptrConversion := &ast.CallExpr{
+		Fun:  ast.NewIdent("uintptr"),
+		Args: []ast.Expr{unsafePointerCall},
+	}
+	call := &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(RacePackageAlias), Sel: ast.NewIdent(funcName)},
+		Args: []ast.Expr{uintptrConversion},
+	}
+	return &ast.ExprStmt{X: call}
+}
+
+// findMainFunc returns file's package-level `func main()` declaration, or
+// nil if the file doesn't declare one - either because it isn't part of
+// package main, or because main lives in a different file of the same
+// package (InstrumentFile only ever sees one file at a time).
+func findMainFunc(file *ast.File) *ast.FuncDecl {
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if funcDecl.Recv == nil && funcDecl.Name.Name == "main" {
+			return funcDecl
+		}
+	}
+	return nil
+}
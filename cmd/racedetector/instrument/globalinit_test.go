@@ -0,0 +1,124 @@
+// globalinit_test.go tests package-level var initializer instrumentation
+// and the raceinitdone barrier (synth-3594).
+package instrument
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestInstrumentFile_GlobalVarInitializerRead verifies a read inside a
+// package-level var initializer is instrumented, even though it sits
+// outside any function body.
+func TestInstrumentFile_GlobalVarInitializerRead(t *testing.T) {
+	input := `package main
+
+var counter = 5
+var derived = counter + 1
+
+func main() {
+	println(derived)
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if !strings.Contains(result.Code, "race.RaceRead(uintptr(unsafe.Pointer(&counter)))") {
+		t.Errorf("Output missing RaceRead for counter's use in derived's initializer, got:\n%s", result.Code)
+	}
+	if result.Stats.ReadsInstrumented < 1 {
+		t.Errorf("Stats.ReadsInstrumented = %d, want at least 1", result.Stats.ReadsInstrumented)
+	}
+
+	// A declaration isn't a modification - derived itself must not receive
+	// a RaceWrite, mirroring the ":=" convention (see visitAssignment).
+	if strings.Contains(result.Code, "&derived") {
+		t.Errorf("Output instrumented the declared variable itself, got:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_GlobalVarInitializerLiteral verifies a literal-only
+// initializer (no variable references) produces no read instrumentation.
+func TestInstrumentFile_GlobalVarInitializerLiteral(t *testing.T) {
+	input := `package main
+
+var counter = 5
+
+func main() {
+	println(counter)
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if strings.Contains(result.Code, "race.RaceRead(uintptr(unsafe.Pointer(&5)))") {
+		t.Errorf("Output tried to instrument a literal, got:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_RaceInitBarrier verifies the raceinitdone barrier is
+// released once package initialization finishes and acquired as the first
+// statement of main().
+func TestInstrumentFile_RaceInitBarrier(t *testing.T) {
+	input := `package main
+
+func main() {
+	println("hi")
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if !strings.Contains(result.Code, "race.RaceRelease(uintptr(unsafe.Pointer(&raceInitDone)))") {
+		t.Errorf("Output missing raceinitdone RaceRelease, got:\n%s", result.Code)
+	}
+	if !strings.Contains(result.Code, "race.RaceAcquire(uintptr(unsafe.Pointer(&raceInitDone)))") {
+		t.Errorf("Output missing raceinitdone RaceAcquire, got:\n%s", result.Code)
+	}
+
+	acquireIdx := strings.Index(result.Code, "race.RaceAcquire")
+	mainIdx := strings.Index(result.Code, "func main()")
+	printlnIdx := strings.Index(result.Code, `println("hi")`)
+	if acquireIdx < mainIdx || acquireIdx > printlnIdx {
+		t.Errorf("RaceAcquire is not the first statement of main(), got:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_RaceInitRunsBeforeUserInit verifies race.Init() is
+// declared before the user's own init(), so instrumented reads/writes
+// inside init() actually take effect instead of running against a detector
+// that hasn't been enabled yet.
+func TestInstrumentFile_RaceInitRunsBeforeUserInit(t *testing.T) {
+	input := `package main
+
+var counter int
+
+func init() {
+	counter = 5
+}
+
+func main() {
+	println(counter)
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	raceInitIdx := strings.Index(result.Code, "race.Init()")
+	userInitIdx := strings.Index(result.Code, "counter = 5")
+	if raceInitIdx < 0 || userInitIdx < 0 || raceInitIdx > userInitIdx {
+		t.Errorf("race.Init() does not run before the user's init(), got:\n%s", result.Code)
+	}
+}
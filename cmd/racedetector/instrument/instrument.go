@@ -42,8 +42,15 @@ import (
 	"go/parser"
 	"go/printer"
 	"go/token"
+	"strings"
+
+	"github.com/kolkov/racedetector/internal/race/logging"
 )
 
+// log is instrument's internal diagnostic logger (synth-3622), silent
+// unless RACEDETECTOR_DEBUG enables it - see internal/race/logging.
+var log = logging.New("instrument")
+
 const (
 	// RacePackageImportPath is the import path for the race detector API.
 	// This will be injected into instrumented files.
@@ -139,6 +146,17 @@ func InstrumentFile(filename string, src interface{}) (*InstrumentResult, error)
 	// This will be returned along with the instrumented code
 	stats := visitor.GetStats()
 
+	// Step 3.6 (synth-3600): for _test.go files, inject a race.TestCleanup(t)
+	// call into every recognized TestXxx(t *testing.T) function, so races
+	// found while it runs - including any t.Parallel subtests - are
+	// attributed to the right test in the printed report. Runs after Pass 2
+	// rather than alongside it: the injected call is a plain, unrelated
+	// statement that ApplyInstrumentation's stmtToPoints lookups (keyed by
+	// the original access nodes) don't need to know about.
+	if strings.HasSuffix(filename, "_test.go") {
+		stats.TestFunctionsShimmed = InjectTestShims(file)
+	}
+
 	// Step 4: Generate Go source code from the modified AST.
 	// We use go/printer to convert the AST back to source code.
 	// The printer handles formatting and indentation automatically.
@@ -151,20 +169,13 @@ func InstrumentFile(filename string, src interface{}) (*InstrumentResult, error)
 		return nil, fmt.Errorf("failed to generate code: %w", err)
 	}
 
-	// Step 5: Add init function to call race.Init() (MVP workaround)
-	// TODO: In full implementation, inject Init/Fini into main() function via AST
-	code := buf.String()
-	code += `
-
-// init initializes race detector (added by racedetector tool)
-func init() {
-	race.Init()
-	_ = unsafe.Sizeof(0) // Ensure unsafe import is used
-}
-`
+	log.Debug("instrumented %s: %d reads, %d writes, %d skipped (const/builtin/literal/blank/ignored/generic/checked)",
+		filename, stats.ReadsInstrumented, stats.WritesInstrumented,
+		stats.ConstantsSkipped+stats.BuiltinsSkipped+stats.LiteralsSkipped+stats.BlanksSkipped+
+			stats.IgnoredSkipped+stats.GenericsSkipped+stats.CheckedSkipped)
 
 	return &InstrumentResult{
-		Code:  code,
+		Code:  buf.String(),
 		Stats: stats,
 	}, nil
 }
@@ -197,6 +208,12 @@ func init() {
 // This avoids modifying the AST while walking it, which can cause
 // iteration issues.
 //
+// Pass 3 (synth-3594): package-level var initializers can't be spliced into
+// a statement list the way Pass 2 splices function bodies - Go doesn't
+// allow bare statements outside a function - so their reads are collected
+// into a synthesized init() function instead, and a raceinitdone barrier is
+// wired between package initialization and main(). See globalinit.go.
+//
 // Thread Safety: NOT thread-safe (modifies AST in place).
 func instrumentAST(fset *token.FileSet, file *ast.File) (*instrumentVisitor, error) {
 	// Pass 1: Create visitor instance and walk the AST.
@@ -204,6 +221,15 @@ func instrumentAST(fset *token.FileSet, file *ast.File) (*instrumentVisitor, err
 	visitor := newInstrumentVisitor(fset, file)
 	ast.Walk(visitor, file)
 
+	// Pass 1.5 (synth-3599): wrap every "//racedetector:checked" block in a
+	// DisableCurrentGoroutine/EnableCurrentGoroutine region. Runs before
+	// Pass 2 so the statements it splices in are just ordinary pre-existing
+	// statements by the time ApplyInstrumentation rebuilds each function
+	// body's statement list - unlike ApplyCoalescing/ApplyLockRegionBatching,
+	// this isn't behind an opt-in flag: the annotation itself is the
+	// developer's opt-in.
+	visitor.ApplyCheckedAnnotations()
+
 	// Pass 2: Apply instrumentation - insert race detection calls into AST.
 	// This modifies the AST in place by inserting race.RaceRead/RaceWrite calls
 	// BEFORE each memory access operation identified in Pass 1.
@@ -211,5 +237,12 @@ func instrumentAST(fset *token.FileSet, file *ast.File) (*instrumentVisitor, err
 		return nil, fmt.Errorf("failed to apply instrumentation: %w", err)
 	}
 
+	// Pass 3: instrument package-level var initializers and wire the
+	// raceinitdone barrier. Runs after Pass 2 so its own new FuncDecls
+	// don't have to be accounted for by ApplyInstrumentation's statement-list
+	// splicing (synth-3594).
+	varReads := instrumentGlobalVarReads(visitor)
+	injectRaceInit(file, varReads)
+
 	return visitor, nil
 }
@@ -18,6 +18,7 @@
 package instrument
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -195,6 +196,106 @@ func main() {
 	t.Logf("Instrumented output:\n%s", result.Code)
 }
 
+// TestInstrumentFile_StructFieldSymbolName tests that a struct field write
+// go/types can confirm - unlike a plain obj.field read, or a selector that
+// might be a method value - is instrumented with RaceWriteSym and a matching
+// RegisterSymbol call carrying its qualified "TypeName.FieldName" name
+// (synth-3630).
+func TestInstrumentFile_StructFieldSymbolName(t *testing.T) {
+	input := `package main
+
+type S struct {
+	field int
+}
+
+func main() {
+	obj := S{}
+	obj.field = 42
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	wantID := fmt.Sprintf("0x%x", symbolID("S.field"))
+
+	if !strings.Contains(result.Code, `race.RegisterSymbol(`+wantID+`, "S.field", "field")`) {
+		t.Errorf("expected a RegisterSymbol call for S.field, got:\n%s", result.Code)
+	}
+	if !strings.Contains(result.Code, "race.RaceWriteSym(uintptr(unsafe.Pointer(&obj.field)), "+wantID+")") {
+		t.Errorf("expected a RaceWriteSym call for the field write, got:\n%s", result.Code)
+	}
+	if strings.Contains(result.Code, "race.RaceWrite(uintptr(unsafe.Pointer(&obj.field)))") {
+		t.Errorf("field write should not also produce a plain RaceWrite, got:\n%s", result.Code)
+	}
+
+	t.Logf("Instrumented output:\n%s", result.Code)
+}
+
+// TestInstrumentFile_GlobalSymbolName tests that a write to a package-level
+// variable declared in the same file is instrumented with RaceWriteSym and
+// a matching RegisterSymbol call carrying its qualified "pkg.VarName" name
+// (synth-3631).
+func TestInstrumentFile_GlobalSymbolName(t *testing.T) {
+	input := `package main
+
+var counter int
+
+func main() {
+	counter = 42
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	wantID := fmt.Sprintf("0x%x", symbolID("main.counter"))
+
+	if !strings.Contains(result.Code, `race.RegisterSymbol(`+wantID+`, "main.counter", "global")`) {
+		t.Errorf("expected a RegisterSymbol call for main.counter, got:\n%s", result.Code)
+	}
+	if !strings.Contains(result.Code, "race.RaceWriteSym(uintptr(unsafe.Pointer(&counter)), "+wantID+")") {
+		t.Errorf("expected a RaceWriteSym call for the global write, got:\n%s", result.Code)
+	}
+	if strings.Contains(result.Code, "race.RaceWrite(uintptr(unsafe.Pointer(&counter)))") {
+		t.Errorf("global write should not also produce a plain RaceWrite, got:\n%s", result.Code)
+	}
+
+	t.Logf("Instrumented output:\n%s", result.Code)
+}
+
+// TestInstrumentFile_LocalVariableNotSymbolized verifies a write to a
+// plain local variable - not a package-level declaration - is instrumented
+// as a normal RaceWrite, with no RegisterSymbol/RaceWriteSym call
+// (synth-3631: globalSymbolName must not fire on locals that merely share
+// isResolvedVar's "resolved *ast.Ident" test with package-level ones).
+func TestInstrumentFile_LocalVariableNotSymbolized(t *testing.T) {
+	input := `package main
+
+func main() {
+	var counter int
+	counter = 42
+	_ = counter
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if strings.Contains(result.Code, "race.RegisterSymbol(") {
+		t.Errorf("local variable should not produce a RegisterSymbol call, got:\n%s", result.Code)
+	}
+	if !strings.Contains(result.Code, "race.RaceWrite(uintptr(unsafe.Pointer(&counter)))") {
+		t.Errorf("expected a plain RaceWrite call for the local write, got:\n%s", result.Code)
+	}
+}
+
 // TestInstrumentFile_ImportInjection tests import injection with existing imports.
 //
 // Test Case:
@@ -808,6 +909,108 @@ func main() {
 	t.Logf("Instrumented output:\n%s", result.Code)
 }
 
+// TestInstrumentFile_SliceHeaderWrite tests that reassigning a slice-typed
+// variable (s = append(s, x)) is instrumented as a RaceWriteRange call
+// covering the whole 3-word header, not a plain single-word RaceWrite
+// (synth-3628, synth-3629).
+func TestInstrumentFile_SliceHeaderWrite(t *testing.T) {
+	input := `package main
+
+func main() {
+	s := []int{1, 2, 3}
+	s = append(s, 4)
+	_ = s
+}
+`
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if !strings.Contains(result.Code, "race.RaceWriteRange(uintptr(unsafe.Pointer(&s)), race.SliceHeaderWords)") {
+		t.Errorf("expected a RaceWriteRange call for the slice header write, got:\n%s", result.Code)
+	}
+	if strings.Contains(result.Code, "race.RaceWrite(uintptr(unsafe.Pointer(&s)))") {
+		t.Errorf("slice header write should not also produce a plain RaceWrite, got:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_SliceElementWriteUnaffected tests that writing to a
+// slice ELEMENT (s[0] = x) is unaffected by synth-3628/synth-3629 - it
+// still gets a plain per-element RaceWrite (synth-3627's addressing), not
+// a range write, since the element and the header are different memory
+// locations.
+func TestInstrumentFile_SliceElementWriteUnaffected(t *testing.T) {
+	input := `package main
+
+func main() {
+	s := []int{1, 2, 3}
+	s[0] = 4
+	_ = s
+}
+`
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if !strings.Contains(result.Code, "race.RaceWrite(uintptr(unsafe.Pointer(&s[0])))") {
+		t.Errorf("expected a plain per-element RaceWrite for s[0], got:\n%s", result.Code)
+	}
+	if strings.Contains(result.Code, "RaceWriteRange") {
+		t.Errorf("element write should not produce a range write, got:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_StringWrite tests that reassigning a string-typed
+// variable is instrumented as a RaceWriteRange call covering the whole
+// 2-word string header (synth-3629).
+func TestInstrumentFile_StringWrite(t *testing.T) {
+	input := `package main
+
+func main() {
+	s := "hello"
+	s = "world"
+	_ = s
+}
+`
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if !strings.Contains(result.Code, "race.RaceWriteRange(uintptr(unsafe.Pointer(&s)), race.StringWords)") {
+		t.Errorf("expected a RaceWriteRange call for the string write, got:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_InterfaceWrite tests that reassigning an
+// interface-typed variable is instrumented as a RaceWriteRange call
+// covering the whole 2-word (type, data) interface value - tearing races
+// on interface assignment are the motivating case for synth-3629.
+func TestInstrumentFile_InterfaceWrite(t *testing.T) {
+	input := `package main
+
+func main() {
+	var v interface{}
+	v = 42
+	v = "other"
+	_ = v
+}
+`
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if !strings.Contains(result.Code, "race.RaceWriteRange(uintptr(unsafe.Pointer(&v)), race.InterfaceWords)") {
+		t.Errorf("expected a RaceWriteRange call for the interface write, got:\n%s", result.Code)
+	}
+	if strings.Contains(result.Code, "race.RaceWrite(uintptr(unsafe.Pointer(&v)))") {
+		t.Errorf("interface write should not also produce a plain RaceWrite, got:\n%s", result.Code)
+	}
+}
+
 // TestInstrumentFile_MethodValue tests that method values are not instrumented.
 // Issue #9: Cannot take address of obj.Method.
 func TestInstrumentFile_MethodValue(t *testing.T) {
@@ -859,6 +1062,229 @@ func main() {
 	t.Logf("Instrumented output:\n%s", result.Code)
 }
 
+// TestInstrumentFile_IgnoreDirective_TrailingComment verifies that a
+// trailing "//racedetector:ignore" comment suppresses instrumentation of
+// the statement it's attached to (v0.4.0).
+func TestInstrumentFile_IgnoreDirective_TrailingComment(t *testing.T) {
+	input := `package main
+
+var x int
+
+func main() {
+	x = 42 //racedetector:ignore
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if result.Stats.WritesInstrumented != 0 {
+		t.Errorf("WritesInstrumented = %d, want 0 (write is annotated)", result.Stats.WritesInstrumented)
+	}
+	if result.Stats.IgnoredSkipped == 0 {
+		t.Error("IgnoredSkipped = 0, want > 0 for an annotated statement")
+	}
+	if strings.Contains(result.Code, "race.RaceWrite") {
+		t.Errorf("Output contains race.RaceWrite despite ignore annotation:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_IgnoreDirective_LeadingComment verifies that a
+// "//racedetector:ignore" comment on its own line above a statement
+// suppresses instrumentation of that statement (v0.4.0).
+func TestInstrumentFile_IgnoreDirective_LeadingComment(t *testing.T) {
+	input := `package main
+
+var x int
+
+func main() {
+	//racedetector:ignore
+	x = 42
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if result.Stats.WritesInstrumented != 0 {
+		t.Errorf("WritesInstrumented = %d, want 0 (write is annotated)", result.Stats.WritesInstrumented)
+	}
+	if strings.Contains(result.Code, "race.RaceWrite") {
+		t.Errorf("Output contains race.RaceWrite despite ignore annotation:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_IgnoreDirective_OnlyAffectsAnnotatedLine verifies that
+// the ignore annotation doesn't suppress instrumentation of unrelated
+// statements elsewhere in the function.
+func TestInstrumentFile_IgnoreDirective_OnlyAffectsAnnotatedLine(t *testing.T) {
+	input := `package main
+
+var x int
+var y int
+
+func main() {
+	x = 42 //racedetector:ignore
+	y = 43
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if result.Stats.WritesInstrumented != 1 {
+		t.Errorf("WritesInstrumented = %d, want 1 (only y = 43 should be instrumented)", result.Stats.WritesInstrumented)
+	}
+	if !strings.Contains(result.Code, "race.RaceWrite") {
+		t.Errorf("Output missing race.RaceWrite for the non-annotated statement:\n%s", result.Code)
+	}
+}
+
+// TestInstrumentFile_DeferredClosureInnerScope verifies that a memory
+// access nested inside an if statement inside a deferred closure is
+// instrumented inside that if statement's block, not the function's
+// top-level block. findParentStatement used to return the first enclosing
+// statement found by a top-down walk - typically the outermost
+// *ast.BlockStmt - instead of the innermost one, so the race call either
+// landed in the wrong scope or (since ApplyInstrumentation only looks up
+// points by the specific statements inside a block, never the block
+// itself) was silently dropped (v0.5.0).
+func TestInstrumentFile_DeferredClosureInnerScope(t *testing.T) {
+	input := `package main
+
+func f() {
+	var errFlag bool
+	defer func() {
+		if r := recover(); r != nil {
+			errFlag = true
+		}
+	}()
+	errFlag = false
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if result.Stats.WritesInstrumented != 2 {
+		t.Errorf("Stats.WritesInstrumented = %d, want 2", result.Stats.WritesInstrumented)
+	}
+
+	// The race call for "errFlag = true" must appear immediately before
+	// it, inside the if statement's block - not floated up to the
+	// function's top-level block (which would leave it before "defer" or
+	// dropped entirely).
+	if !strings.Contains(result.Code, "if r := recover(); r != nil {\n\t\t\trace.RaceWrite(uintptr(unsafe.Pointer(&errFlag)))\n\t\t\terrFlag = true\n\t\t}") {
+		t.Errorf("RaceWrite for errFlag = true is not correctly scoped inside the if block:\n%s", result.Code)
+	}
+
+	t.Logf("Instrumented output:\n%s", result.Code)
+}
+
+// TestInstrumentFile_GoroutineLoopBody verifies that a memory access inside
+// a for loop's body inside a goroutine literal is instrumented inside the
+// loop body, not floated up to the goroutine's top-level block or dropped.
+func TestInstrumentFile_GoroutineLoopBody(t *testing.T) {
+	input := `package main
+
+func f() {
+	x := 0
+	go func() {
+		for i := 0; i < 3; i++ {
+			x = i
+		}
+	}()
+	_ = x
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if !strings.Contains(result.Code, "for i := 0; i < 3; i++ {\n\t\t\trace.RaceRead(uintptr(unsafe.Pointer(&i)))\n\t\t\trace.RaceWrite(uintptr(unsafe.Pointer(&x)))\n\t\t\tx = i\n\t\t}") {
+		t.Errorf("RaceWrite for x = i is not correctly scoped inside the for loop body:\n%s", result.Code)
+	}
+
+	t.Logf("Instrumented output:\n%s", result.Code)
+}
+
+// TestInstrumentFile_GenericFunctionValue verifies that instantiating a
+// generic function with 2+ type arguments (an *ast.IndexListExpr) as a bare
+// value is tracked as a generics skip rather than misclassified as an
+// addressable read (synth-3592).
+func TestInstrumentFile_GenericFunctionValue(t *testing.T) {
+	input := `package main
+
+func Sum[T, U any](a T, b U) T {
+	return a
+}
+
+func main() {
+	f := Sum[int, string]
+	_ = f
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if result.Stats.GenericsSkipped != 1 {
+		t.Errorf("Stats.GenericsSkipped = %d, want 1", result.Stats.GenericsSkipped)
+	}
+
+	// Sum[int, string] is a type argument list, not an address - taking its
+	// address would not compile.
+	if strings.Contains(result.Code, "&Sum[") {
+		t.Errorf("Output contains invalid &Sum[...] - should not take address of a generic instantiation:\n%s", result.Code)
+	}
+
+	t.Logf("Instrumented output:\n%s", result.Code)
+}
+
+// TestInstrumentFile_GenericContainer verifies that a generic container
+// type with a single type parameter (Stack[int]{}, an *ast.IndexExpr rather
+// than IndexListExpr) still instruments accesses to its resolved local
+// variables normally, without trying to instrument the type instantiation
+// itself (synth-3592).
+func TestInstrumentFile_GenericContainer(t *testing.T) {
+	input := `package main
+
+type Stack[T any] struct {
+	items []T
+}
+
+func main() {
+	s := Stack[int]{}
+	local := 5
+	_ = s
+	_ = local
+}
+`
+
+	result, err := InstrumentFile("test.go", input)
+	if err != nil {
+		t.Fatalf("InstrumentFile failed: %v", err)
+	}
+
+	if strings.Contains(result.Code, "&Stack[") {
+		t.Errorf("Output contains invalid &Stack[...] - should not take address of a generic type instantiation:\n%s", result.Code)
+	}
+
+	t.Logf("Instrumented output:\n%s", result.Code)
+}
+
 func BenchmarkInstrumentFile(b *testing.B) {
 	input := `package main
 
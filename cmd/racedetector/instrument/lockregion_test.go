@@ -0,0 +1,188 @@
+// Package instrument - Tests for lock-region barrier batching (synth-3597).
+package instrument
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// TestAnalyzeLockRegions_BatchesWrites verifies two writes between Lock()
+// and Unlock() on the same mutex are recognized as one batchable region.
+func TestAnalyzeLockRegions_BatchesWrites(t *testing.T) {
+	code := `package main
+import "sync"
+var mu sync.Mutex
+var a, b int
+func update() {
+    mu.Lock()
+    a = 1
+    b = 2
+    mu.Unlock()
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	visitor := newInstrumentVisitor(fset, file)
+	ast.Walk(visitor, file)
+
+	regions, stats := AnalyzeLockRegions(visitor.GetInstrumentationPoints(), file)
+	if len(regions) != 1 {
+		t.Fatalf("Expected 1 lock region, got %d", len(regions))
+	}
+	if len(regions[0].Writes) != 2 {
+		t.Errorf("Expected 2 writes in region, got %d", len(regions[0].Writes))
+	}
+	if stats.RegionsCreated != 1 || stats.BarriersRemoved != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestAnalyzeLockRegions_MismatchedMutex verifies a Lock/Unlock pair on
+// different mutexes is not treated as one region.
+func TestAnalyzeLockRegions_MismatchedMutex(t *testing.T) {
+	code := `package main
+import "sync"
+var mu1, mu2 sync.Mutex
+var a, b int
+func update() {
+    mu1.Lock()
+    a = 1
+    b = 2
+    mu2.Unlock()
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	visitor := newInstrumentVisitor(fset, file)
+	ast.Walk(visitor, file)
+
+	regions, _ := AnalyzeLockRegions(visitor.GetInstrumentationPoints(), file)
+	if len(regions) != 0 {
+		t.Errorf("Expected 0 regions for mismatched mutex, got %d", len(regions))
+	}
+}
+
+// TestAnalyzeLockRegions_SingleWrite verifies a region with only one write
+// is discarded (no batching benefit).
+func TestAnalyzeLockRegions_SingleWrite(t *testing.T) {
+	code := `package main
+import "sync"
+var mu sync.Mutex
+var a int
+func update() {
+    mu.Lock()
+    a = 1
+    mu.Unlock()
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	visitor := newInstrumentVisitor(fset, file)
+	ast.Walk(visitor, file)
+
+	regions, _ := AnalyzeLockRegions(visitor.GetInstrumentationPoints(), file)
+	if len(regions) != 0 {
+		t.Errorf("Expected 0 regions for a single write, got %d", len(regions))
+	}
+}
+
+// TestApplyLockRegionBatching_EndToEnd verifies the batched region produces
+// one race.RaceRegionWrite call before Unlock and no individual RaceWrite
+// calls for the batched addresses.
+func TestApplyLockRegionBatching_EndToEnd(t *testing.T) {
+	code := `package main
+import "sync"
+var mu sync.Mutex
+var a, b int
+func update() {
+    mu.Lock()
+    a = 1
+    b = 2
+    mu.Unlock()
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	visitor := newInstrumentVisitor(fset, file)
+	ast.Walk(visitor, file)
+
+	stats := visitor.ApplyLockRegionBatching(true)
+	if stats.RegionsCreated != 1 {
+		t.Fatalf("Expected 1 region batched, got %d", stats.RegionsCreated)
+	}
+
+	if err := visitor.ApplyInstrumentation(); err != nil {
+		t.Fatalf("ApplyInstrumentation failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, file); err != nil {
+		t.Fatalf("Failed to print: %v", err)
+	}
+	code2 := buf.String()
+
+	if strings.Count(code2, "race.RaceWrite") != 0 {
+		t.Errorf("Expected batched writes to have no individual RaceWrite calls, got:\n%s", code2)
+	}
+	if !strings.Contains(code2, "race.RaceRegionWrite(uintptr(unsafe.Pointer(&a)), uintptr(unsafe.Pointer(&b)))") {
+		t.Errorf("Expected a single RaceRegionWrite batching both addresses, got:\n%s", code2)
+	}
+
+	batchIdx := strings.Index(code2, "race.RaceRegionWrite")
+	unlockIdx := strings.Index(code2, "mu.Unlock()")
+	if batchIdx < 0 || unlockIdx < 0 || batchIdx > unlockIdx {
+		t.Errorf("Expected RaceRegionWrite immediately before Unlock, got:\n%s", code2)
+	}
+}
+
+// TestApplyLockRegionBatching_Disabled verifies passing false is a no-op.
+func TestApplyLockRegionBatching_Disabled(t *testing.T) {
+	code := `package main
+import "sync"
+var mu sync.Mutex
+var a, b int
+func update() {
+    mu.Lock()
+    a = 1
+    b = 2
+    mu.Unlock()
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	visitor := newInstrumentVisitor(fset, file)
+	ast.Walk(visitor, file)
+
+	before := len(visitor.GetInstrumentationPoints())
+	visitor.ApplyLockRegionBatching(false)
+	after := len(visitor.GetInstrumentationPoints())
+
+	if before != after {
+		t.Errorf("Expected disabled batching to leave points unchanged: before=%d after=%d", before, after)
+	}
+}
@@ -0,0 +1,33 @@
+// symbols.go computes the numeric symbol id emitted alongside a
+// symbolized write - a struct field (synth-3630) or a package-level
+// variable (synth-3631) - see visitor.go's SymbolName/SymbolID/SymbolKind
+// and race.RegisterSymbol/RaceWriteSym.
+//
+// InstrumentFile instruments one file at a time, without its importing
+// package's other files or a resolved build list (see
+// checkTypesBestEffort's doc comment in typeinfo.go), so two files that
+// both write the same field or global can't coordinate on a shared counter.
+// Hashing the qualified name instead sidesteps that entirely: it's a pure
+// function of the name, so every site that touches "Config.Timeout" or
+// "main.counter" computes the same id independently, with no registry or
+// shared state needed at instrument time.
+package instrument
+
+import "hash/fnv"
+
+// symbolID hashes name (a "TypeName.Field" or "pkg.VarName" string) into
+// the numeric id embedded in generated race.RegisterSymbol/RaceWriteSym
+// calls.
+//
+// FNV-1a, matching the algorithm internal/race/stackdepot already uses to
+// fingerprint a stack trace (see stackdepot.HashPCs) - this package can't
+// import that one to share the implementation (an internal/race/... import
+// would pull runtime detector internals into the build-time instrumenter,
+// which today only ever emits calls against the public race package), but
+// there's no reason to invent a second hashing convention for the same
+// kind of problem.
+func symbolID(name string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return h.Sum64()
+}
@@ -0,0 +1,139 @@
+// testshim.go implements the auto-injected "test shim": a
+// race.TestCleanup(t) call spliced into every recognized Go test function,
+// so a race found while a test runs is attributed to that test - including
+// every t.Parallel subtest, each of which gets its own logical detector
+// scope for free (synth-3600).
+//
+// Only applies to _test.go files - see InjectTestShims's caller in
+// instrument.go, which checks the filename before calling it.
+package instrument
+
+import (
+	"go/ast"
+)
+
+// testCleanupFunc is the name InjectTestShims calls on RacePackageAlias.
+const testCleanupFunc = "TestCleanup"
+
+// InjectTestShims scans file for Go test functions - top-level
+// `func TestXxx(t *testing.T)` declarations matching the same shape `go
+// test` itself recognizes - and prepends a race.TestCleanup(t) call to
+// each one's body.
+//
+// This is what makes t.Parallel tests report races against the right test
+// name without the developer writing anything: Go's testing package always
+// runs each test function on its own goroutine (`go tRunner(t, fn)`),
+// whether or not it calls t.Parallel, so TestCleanup registering t.Name()
+// against the calling goroutine's TID (see detector.Detector.
+// SetGoroutineTestName) already gives every test - parallel siblings
+// included - a distinct logical detector scope. No new scope data
+// structure is needed here, only the call that names the scope.
+//
+// A test function that already starts with its own call to
+// race.TestCleanup (or <alias>.TestCleanup, if the caller aliased the
+// import) is left alone, so re-running the instrumenter on already-shimmed
+// output - or a test file that was migrated to call it manually before
+// this feature existed - doesn't inject a second, redundant call.
+//
+// Returns the number of test functions shimmed.
+func InjectTestShims(file *ast.File) int {
+	shimmed := 0
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		paramName, ok := testFuncParam(fn)
+		if !ok {
+			continue
+		}
+		if hasTestCleanupCall(fn.Body) {
+			continue
+		}
+
+		call := &ast.ExprStmt{X: &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent(RacePackageAlias), Sel: ast.NewIdent(testCleanupFunc)},
+			Args: []ast.Expr{ast.NewIdent(paramName)},
+		}}
+		fn.Body.List = append([]ast.Stmt{call}, fn.Body.List...)
+		shimmed++
+	}
+	return shimmed
+}
+
+// testFuncParam reports whether fn has the shape of a test function `go
+// test` runs - name starting with "Test" followed by an uppercase-or-empty
+// rune, taking exactly one parameter of type *testing.T - and if so
+// returns that parameter's name.
+//
+// Deliberately narrower than "anything testing.TB": t.Parallel is only
+// declared on *testing.T (not *testing.B or *testing.F, which are
+// benchmarks/fuzz targets `go test` schedules differently), and TestXxx is
+// the only shape this feature is about making parallel-aware.
+func testFuncParam(fn *ast.FuncDecl) (string, bool) {
+	if fn.Recv != nil || !isTestFuncName(fn.Name.Name) {
+		return "", false
+	}
+	if fn.Type.Params == nil || len(fn.Type.Params.List) != 1 {
+		return "", false
+	}
+
+	param := fn.Type.Params.List[0]
+	if len(param.Names) != 1 {
+		return "", false
+	}
+
+	star, ok := param.Type.(*ast.StarExpr)
+	if !ok {
+		return "", false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "T" {
+		return "", false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Name != "testing" {
+		return "", false
+	}
+
+	return param.Names[0].Name, true
+}
+
+// isTestFuncName reports whether name matches the "TestXxx" shape `go
+// test` looks for: "Test" followed by either nothing or an upper-case
+// letter (so TestFoo qualifies but Testfoo, which go test also refuses to
+// run as a test, does not).
+func isTestFuncName(name string) bool {
+	const prefix = "Test"
+	if len(name) < len(prefix) || name[:len(prefix)] != prefix {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r := name[len(prefix)]
+	return r >= 'A' && r <= 'Z'
+}
+
+// hasTestCleanupCall reports whether body's statement list already starts
+// with a call to some package's TestCleanup function, so InjectTestShims
+// doesn't inject a second one.
+func hasTestCleanupCall(body *ast.BlockStmt) bool {
+	if len(body.List) == 0 {
+		return false
+	}
+	exprStmt, ok := body.List[0].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := exprStmt.X.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	return sel.Sel.Name == testCleanupFunc
+}
@@ -0,0 +1,98 @@
+// Package instrument - Tests for the test-function shim (synth-3600).
+package instrument
+
+import (
+	"bytes"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// TestInjectTestShims_ShimsRecognizedTestFunc verifies a TestXxx(t
+// *testing.T) function gets race.TestCleanup(t) prepended.
+func TestInjectTestShims_ShimsRecognizedTestFunc(t *testing.T) {
+	code := `package pkg_test
+import "testing"
+func TestFoo(t *testing.T) {
+    t.Parallel()
+    x := 1
+    _ = x
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo_test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	shimmed := InjectTestShims(file)
+	if shimmed != 1 {
+		t.Fatalf("Expected 1 test function shimmed, got %d", shimmed)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, file); err != nil {
+		t.Fatalf("Failed to print: %v", err)
+	}
+	code2 := buf.String()
+
+	cleanupIdx := strings.Index(code2, "race.TestCleanup(t)")
+	parallelIdx := strings.Index(code2, "t.Parallel()")
+	if cleanupIdx < 0 || parallelIdx < 0 || cleanupIdx > parallelIdx {
+		t.Errorf("Expected race.TestCleanup(t) before t.Parallel(), got:\n%s", code2)
+	}
+}
+
+// TestInjectTestShims_IgnoresNonTestFuncs verifies helper functions and
+// benchmarks aren't shimmed.
+func TestInjectTestShims_IgnoresNonTestFuncs(t *testing.T) {
+	code := `package pkg_test
+import "testing"
+func helper() {}
+func BenchmarkFoo(b *testing.B) {}
+func testfoo(t *testing.T) {}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo_test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if shimmed := InjectTestShims(file); shimmed != 0 {
+		t.Errorf("Expected 0 functions shimmed, got %d", shimmed)
+	}
+}
+
+// TestInjectTestShims_SkipsAlreadyShimmed verifies a test that already
+// calls race.TestCleanup doesn't get a second call injected.
+func TestInjectTestShims_SkipsAlreadyShimmed(t *testing.T) {
+	code := `package pkg_test
+import (
+    "testing"
+    "github.com/kolkov/racedetector/race"
+)
+func TestFoo(t *testing.T) {
+    race.TestCleanup(t)
+    t.Parallel()
+}`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo_test.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if shimmed := InjectTestShims(file); shimmed != 0 {
+		t.Errorf("Expected 0 functions shimmed (already has a call), got %d", shimmed)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, file); err != nil {
+		t.Fatalf("Failed to print: %v", err)
+	}
+	if strings.Count(buf.String(), "TestCleanup(t)") != 1 {
+		t.Errorf("Expected exactly 1 TestCleanup call, got:\n%s", buf.String())
+	}
+}
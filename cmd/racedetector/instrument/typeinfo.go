@@ -0,0 +1,177 @@
+// typeinfo.go provides best-effort go/types checking used to resolve the one
+// ambiguity shouldInstrument's pure-AST heuristics cannot: whether an
+// *ast.IndexExpr indexes a map (not addressable - &m[k] doesn't compile) or a
+// slice/array (addressable, and per-element - &a[1] and &a[2] are distinct
+// addresses) (synth-3627).
+package instrument
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+)
+
+// checkTypesBestEffort type-checks file and returns the resulting
+// *types.Info, even if checking failed.
+//
+// InstrumentFile instruments one file at a time, without its importing
+// package's other files or a resolved build list, so a full, error-free
+// types.Check is not always possible - an import that isn't in the standard
+// library, or a sibling file that declares a symbol this file references,
+// will make the checker report errors. We still want whatever it managed to
+// resolve: types.Info's maps are populated incrementally as the checker
+// walks the file, so a later error doesn't erase earlier, correct entries.
+// isAddressableIndex treats a missing entry as "unknown" and leaves the
+// existing conservative skip in place, so a partially-failed check can only
+// ever enable additional instrumentation, never miscompile it.
+//
+// Parameters:
+//   - fset: File set the file was parsed with
+//   - file: AST to type-check
+//
+// Returns:
+//   - *types.Info: Best-effort type information (never nil)
+//
+// Thread Safety: Read-only, safe for concurrent use.
+func checkTypesBestEffort(fset *token.FileSet, file *ast.File) *types.Info {
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+
+	conf := types.Config{
+		Importer: importer.Default(),
+		Error:    func(error) {}, // collect what we can, ignore the rest
+	}
+
+	// Best-effort: Check's returned error is intentionally discarded, per
+	// the doc comment above.
+	_, _ = conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+
+	return info
+}
+
+// isAddressableIndex reports whether expr is confirmed, by info, to index a
+// slice, array, or pointer-to-array - the addressable cases, where &expr
+// compiles and identifies the specific element rather than the whole
+// collection. Anything info couldn't resolve returns false, preserving the
+// pre-synth-3627 conservative skip.
+//
+// Parameters:
+//   - info: Best-effort type information from checkTypesBestEffort
+//   - expr: Index expression to classify
+//
+// Returns:
+//   - bool: true only if info confirms expr is addressable
+//
+// Thread Safety: Read-only, safe for concurrent use.
+func isAddressableIndex(info *types.Info, expr *ast.IndexExpr) bool {
+	if info == nil {
+		return false
+	}
+
+	tv, ok := info.Types[expr.X]
+	if !ok || tv.Type == nil {
+		return false
+	}
+
+	switch t := tv.Type.Underlying().(type) {
+	case *types.Slice, *types.Array:
+		return true
+	case *types.Pointer:
+		_, isArray := t.Elem().Underlying().(*types.Array)
+		return isArray
+	default:
+		return false
+	}
+}
+
+// multiWordWriteWidth reports which race package word-count constant
+// applies to a write to expr itself (as opposed to one of its elements or
+// fields), per info: "SliceHeaderWords" for a slice header (data pointer,
+// len, cap), "StringWords" for a string header (data pointer, len),
+// "InterfaceWords" for an interface value (type word, data word), or ""
+// for anything else (synth-3628 for slices, generalized to strings and
+// interfaces by synth-3629).
+//
+// A slice, string, or interface variable's own words are a different
+// memory location than whatever they reference - s = append(s, x) writes
+// s's header, not an element of its backing array; v = other writes v's
+// type/data words, not the concrete value either one points at. Checking
+// only the first word would miss a race confined to a later word, which
+// for an interface assignment means missing a "tearing" race between its
+// type and data words - one of the most consequential race shapes in
+// practice.
+//
+// Unresolved types return "", so a write whose type info.Types couldn't
+// confirm is instrumented as a plain single-word write, the same
+// conservative fallback isAddressableIndex uses for indexing.
+// fieldSymbolName reports the qualified "TypeName.FieldName" name for a
+// struct field selector confirmed by info, or "", false if expr isn't a
+// field selection info could resolve (synth-3630).
+//
+// The pre-synth-3630 conservative rule for obj.field is to skip it
+// entirely: without a type checker, a selector's base is indistinguishable
+// from a package qualifier (os in os.Exit), and even once the base is
+// known to be a value, the selector itself might resolve to a method
+// value or method expression (buf.Write) rather than a field - and
+// &buf.Write doesn't compile (see TestInstrumentFile_MethodValue). info's
+// Selections map, populated by checkTypesBestEffort, answers exactly this:
+// types.Selection.Kind() is types.FieldVal only for an actual field
+// access, never a method. Anything else - unresolved, a method, or a field
+// on an unnamed struct type with no "TypeName" to report - returns false,
+// leaving the existing categorical skip in place.
+//
+// Parameters:
+//   - info: Best-effort type information from checkTypesBestEffort
+//   - expr: Selector expression to classify
+//
+// Returns:
+//   - string: "TypeName.FieldName", when resolved
+//   - bool: true only if info confirms expr is a named struct's field
+func fieldSymbolName(info *types.Info, expr *ast.SelectorExpr) (string, bool) {
+	if info == nil {
+		return "", false
+	}
+
+	sel, ok := info.Selections[expr]
+	if !ok || sel.Kind() != types.FieldVal {
+		return "", false
+	}
+
+	recvType := sel.Recv()
+	if ptr, ok := recvType.(*types.Pointer); ok {
+		recvType = ptr.Elem()
+	}
+	named, ok := recvType.(*types.Named)
+	if !ok {
+		return "", false
+	}
+
+	return named.Obj().Name() + "." + expr.Sel.Name, true
+}
+
+func multiWordWriteWidth(info *types.Info, expr ast.Expr) string {
+	if info == nil {
+		return ""
+	}
+
+	tv, ok := info.Types[expr]
+	if !ok || tv.Type == nil {
+		return ""
+	}
+
+	switch tv.Type.Underlying().(type) {
+	case *types.Slice:
+		return "SliceHeaderWords"
+	case *types.Interface:
+		return "InterfaceWords"
+	}
+
+	if basic, ok := tv.Type.Underlying().(*types.Basic); ok && basic.Kind() == types.String {
+		return "StringWords"
+	}
+
+	return ""
+}
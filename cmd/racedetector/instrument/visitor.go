@@ -5,10 +5,20 @@
 package instrument
 
 import (
+	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
 )
 
+// ignoreDirective is the comment text (after stripping "//" and surrounding
+// whitespace) that marks a line as exempt from instrumentation (v0.4.0),
+// the compile-time counterpart of the runtime Disable/IgnoreReads/Writes
+// API: //racedetector:ignore
+const ignoreDirective = "racedetector:ignore"
+
 // InstrumentStats tracks instrumentation statistics.
 //
 // This structure collects metrics during the instrumentation process,
@@ -37,6 +47,14 @@ type InstrumentStats struct {
 	BuiltinsSkipped    int // Number of built-in identifiers skipped (nil, true, false, iota)
 	LiteralsSkipped    int // Number of literals skipped (42, "hello", 3.14)
 	BlanksSkipped      int // Number of blank identifiers (_) skipped
+	IgnoredSkipped     int // Number of accesses skipped due to a //racedetector:ignore comment (v0.4.0)
+	GenericsSkipped    int // Number of generic instantiations skipped (Func[T], Container[K, V]{}) (synth-3592)
+	CheckedSkipped     int // Number of accesses skipped due to a //racedetector:checked block (synth-3599)
+
+	// TestFunctionsShimmed counts test functions that had a race.TestCleanup
+	// call injected as their first statement (synth-3600). See testshim.go.
+	// Not part of TotalSkipped - this counts an insertion, not a skip.
+	TestFunctionsShimmed int
 }
 
 // Total returns total number of instrumented accesses.
@@ -46,7 +64,7 @@ func (s *InstrumentStats) Total() int {
 
 // TotalSkipped returns total number of skipped items.
 func (s *InstrumentStats) TotalSkipped() int {
-	return s.ConstantsSkipped + s.BuiltinsSkipped + s.LiteralsSkipped + s.BlanksSkipped
+	return s.ConstantsSkipped + s.BuiltinsSkipped + s.LiteralsSkipped + s.BlanksSkipped + s.IgnoredSkipped + s.GenericsSkipped + s.CheckedSkipped
 }
 
 // instrumentVisitor implements ast.Visitor for instrumenting memory accesses.
@@ -85,9 +103,58 @@ type instrumentVisitor struct {
 	// Value: instrumentPoint (details about the instrumentation)
 	instrumentationPoints []instrumentPoint
 
+	// ignoredLines holds source line numbers annotated with a
+	// "//racedetector:ignore" comment (v0.4.0), built once by
+	// buildIgnoredLines. Visit consults this before recording an
+	// instrumentation point so annotated code is left untouched - the
+	// compile-time counterpart of the runtime Disable/IgnoreReads/Writes
+	// API for benign races that are easier to mark at the source line than
+	// to wrap at runtime.
+	ignoredLines map[int]bool
+
+	// checkedBlocks holds every "//racedetector:checked" annotated block
+	// (synth-3599), collected once by buildCheckedBlocks. Consumed by
+	// ApplyCheckedAnnotations, which - for every block it wraps in a
+	// DisableCurrentGoroutine/EnableCurrentGoroutine region - drops that
+	// block's already-collected instrumentation points, the same way
+	// ApplyLockRegionBatching drops the points it folds into a region
+	// batch. A block left un-wrapped (see blockHasEarlyExit) keeps its
+	// points and is instrumented normally.
+	checkedBlocks []checkedBlock
+
 	// stats tracks instrumentation statistics.
 	// Exported via GetStats() for reporting.
 	stats InstrumentStats
+
+	// writeTargets marks the LHS expressions of regular (non-":=") assignments
+	// that are *ast.StarExpr, *ast.IndexExpr, or *ast.SelectorExpr (v0.5.0).
+	// visitAssignment records these here instead of instrumenting them
+	// directly, because ast.Walk continues into the LHS after Visit returns
+	// and will reach the same node again via the generic dispatch in Visit -
+	// visitDereference/visitIndexAccess/visitFieldAccess consult this map to
+	// tell a write occurrence (*ptr = 1, arr[0] = 1, obj.field = 1) apart
+	// from a read occurrence (x = *ptr, x = arr[0], x = obj.field), rather
+	// than always recording a read as they used to. See those functions.
+	writeTargets map[ast.Expr]bool
+
+	// incDecTargets marks an *ast.IndexExpr that is the operand of an
+	// *ast.IncDecStmt (arr[0]++) once visitIncDec has already recorded both
+	// its read and its write itself (synth-3627). ast.Walk still continues
+	// into stmt.X after visitIncDec returns and will reach the same node via
+	// the generic dispatch in Visit, same as writeTargets guards against for
+	// assignments - but IncDec needs a read AND a write recorded, which the
+	// single writeTargets bool can't express, so visitIndexAccess just skips
+	// entirely here instead of recording a third, redundant point.
+	incDecTargets map[ast.Expr]bool
+
+	// typesInfo holds best-effort go/types information for file, built once
+	// by checkTypesBestEffort (synth-3627). shouldInstrument and
+	// visitIndexAccess consult it to tell a map index (m[k], not
+	// addressable) apart from a slice/array index (a[i], addressable) -
+	// the one case the rest of this file's pure-AST heuristics can't
+	// resolve on their own. Never nil, but frequently incomplete: see
+	// checkTypesBestEffort's doc comment.
+	typesInfo *types.Info
 }
 
 // InstrumentPoint represents a location where race detection should be inserted.
@@ -96,7 +163,7 @@ type instrumentVisitor struct {
 //nolint:revive // InstrumentPoint is a clear, descriptive name for this type
 type InstrumentPoint struct {
 	// Node is the AST node performing the memory access.
-	// Example: *ast.AssignStmt, *ast.UnaryExpr (dereference)
+	// Example: *ast.AssignStmt, *ast.StarExpr (dereference)
 	Node ast.Node
 
 	// AccessType indicates whether this is a read or write.
@@ -105,6 +172,59 @@ type InstrumentPoint struct {
 	// Addr is the expression representing the memory address.
 	// Example: &x, ptr, &arr[0], &obj.field
 	Addr ast.Expr
+
+	// Addressable marks an *ast.IndexExpr point (see visitIndexAccess) whose
+	// base type go/types confirmed is a slice, array, or pointer-to-array,
+	// meaning Addr (&arr[i]) actually compiles and names that one element -
+	// as opposed to a map index, where it doesn't (synth-3627). Ignored for
+	// every other Node kind. ApplyInstrumentation only emits a race call for
+	// an *ast.IndexExpr point when this is true.
+	Addressable bool
+
+	// WriteWordsConst, when non-empty, marks a write to a whole slice-,
+	// string-, or interface-typed variable (e.g. s = append(s, x),
+	// name = other, v = anotherValue) rather than one of a slice's
+	// elements (s[0] = x) or a struct's field - the two are different
+	// memory locations, the value's own words versus whatever they point
+	// at (synth-3628 for slices, generalized to strings and interfaces by
+	// synth-3629). It names the race package constant ("SliceHeaderWords",
+	// "StringWords", or "InterfaceWords") giving the value's width in
+	// machine words - a name rather than a bare int, since StringWords and
+	// InterfaceWords share the same numeric value but must still resolve
+	// to the right symbolic constant in generated code. createRaceCall
+	// emits race.RaceWriteRange(addr, race.<WriteWordsConst>) instead of
+	// race.RaceWrite(addr) when this is set, so a race on just one of the
+	// value's words is reported at that word's own address instead of
+	// being conflated with the others or with an element write. Ignored
+	// for read points and for every other Node kind - set only by
+	// visitAssignment's bare-identifier LHS case.
+	WriteWordsConst string
+
+	// SymbolName, when non-empty, is a human-readable qualified name for a
+	// write whose target this pass could resolve one for: "TypeName.Field"
+	// for a struct field write go/types confirmed (see fieldSymbolName in
+	// typeinfo.go, synth-3630), or "pkg.VarName" for a write to a
+	// package-level variable declared in this file (see globalSymbolName,
+	// synth-3631). Set only for AccessWrite points - reads are left exactly
+	// as conservative as before either change (see
+	// TestInstrumentFile_StructFieldConservative). When set, createRaceCall
+	// emits race.RaceWriteSym(addr, SymbolID) preceded by a
+	// race.RegisterSymbol(SymbolID, SymbolName, SymbolKind) call instead of
+	// race.RaceWrite(addr), so a race here prints this name instead of only
+	// the address.
+	SymbolName string
+
+	// SymbolID is symbolID(SymbolName) - an FNV-1a hash computed once
+	// here so createRaceCall and the register-call it precedes always agree
+	// on the same id. Meaningless when SymbolName is empty.
+	SymbolID uint64
+
+	// SymbolKind labels what kind of thing SymbolName names - "field" or
+	// "global" today - so a race report can print "race on field
+	// Config.Timeout" or "race on global main.counter" rather than a single
+	// generic phrasing for both (synth-3631). Meaningless when SymbolName
+	// is empty.
+	SymbolKind string
 }
 
 // AccessType classifies memory access operations.
@@ -121,6 +241,7 @@ const (
 // instrumentPoint is the internal type (lowercase for private use).
 type instrumentPoint = InstrumentPoint
 
+
 // Visit implements ast.Visitor interface.
 //
 // This method is called by ast.Walk() for each node in the AST.
@@ -129,7 +250,7 @@ type instrumentPoint = InstrumentPoint
 //
 // Nodes we care about (MVP scope):
 //  1. *ast.AssignStmt: Variable assignments (x = 42)
-//  2. *ast.UnaryExpr (MUL): Pointer dereferences (*ptr)
+//  2. *ast.StarExpr: Pointer dereferences (*ptr)
 //  3. *ast.IndexExpr: Array/slice accesses (arr[0])
 //  4. *ast.SelectorExpr: Struct field accesses (obj.field)
 //
@@ -158,6 +279,14 @@ func (v *instrumentVisitor) Visit(node ast.Node) ast.Visitor {
 		return nil
 	}
 
+	// //racedetector:ignore (v0.4.0): skip this subtree entirely rather
+	// than recording instrumentation points for it. Checked before the
+	// type switch so it applies uniformly to every access kind below.
+	if v.ignoredLines[v.fset.Position(node.Pos()).Line] {
+		v.stats.IgnoredSkipped++
+		return nil
+	}
+
 	switch n := node.(type) {
 	case *ast.AssignStmt:
 		// Assignment: x = 42, *ptr = 42, arr[0] = 42
@@ -170,25 +299,23 @@ func (v *instrumentVisitor) Visit(node ast.Node) ast.Visitor {
 		// Example: counter++ is equivalent to counter = counter + 1
 		v.visitIncDec(n)
 
-	case *ast.UnaryExpr:
-		// Dereference: *ptr
-		// Can be either read or write depending on context.
-		// For MVP, we'll instrument as READ (simpler).
-		// Future: Context-aware detection (read vs write).
-		if n.Op == token.MUL {
-			v.visitDereference(n)
-		}
+	case *ast.StarExpr:
+		// Dereference: *ptr. go/ast represents unary "*" exclusively as
+		// StarExpr, never as UnaryExpr{Op: token.MUL} - see StarExpr's doc.
+		// Can be either read or write depending on context; visitDereference
+		// consults writeTargets (set by visitAssignment) to tell them apart.
+		v.visitDereference(n)
 
 	case *ast.IndexExpr:
 		// Array/slice access: arr[0], slice[i]
-		// Context determines read vs write.
-		// For MVP, we'll instrument as READ.
+		// Context determines read vs write; visitIndexAccess consults
+		// writeTargets (set by visitAssignment) to tell them apart.
 		v.visitIndexAccess(n)
 
 	case *ast.SelectorExpr:
 		// Struct field access: obj.field, ptr.field
-		// Context determines read vs write.
-		// For MVP, we'll instrument as READ.
+		// Context determines read vs write; visitFieldAccess consults
+		// writeTargets (set by visitAssignment) to tell them apart.
 		v.visitFieldAccess(n)
 	}
 
@@ -240,8 +367,20 @@ func (v *instrumentVisitor) visitAssignment(stmt *ast.AssignStmt) {
 
 	// For regular assignment (=), instrument LHS writes
 	for _, lhs := range stmt.Lhs {
+		// *ptr = 1, arr[0] = 1, obj.field = 1: ast.Walk continues into lhs
+		// once Visit returns below, and will reach this same node again via
+		// the generic dispatch (visitDereference/visitIndexAccess/
+		// visitFieldAccess). Mark it as a write target here instead of
+		// instrumenting it ourselves, so that pass records a write instead
+		// of defaulting to a read - see writeTargets.
+		switch lhs.(type) {
+		case *ast.StarExpr, *ast.IndexExpr, *ast.SelectorExpr:
+			v.writeTargets[lhs] = true
+			continue
+		}
+
 		// Skip if this expression shouldn't be instrumented
-		if !shouldInstrument(lhs) {
+		if !v.shouldInstrument(lhs) {
 			v.trackSkipped(lhs)
 			continue
 		}
@@ -253,12 +392,32 @@ func (v *instrumentVisitor) visitAssignment(stmt *ast.AssignStmt) {
 			continue
 		}
 
+		// s = append(s, x), name = other, v = anotherValue: a bare
+		// slice-, string-, or interface-typed variable being reassigned
+		// writes its own multi-word value, not an element of a slice's
+		// backing array or a struct's field (synth-3628, synth-3629) -
+		// see WriteWordsConst.
+		writeWordsConst := multiWordWriteWidth(v.typesInfo, lhs)
+
+		point := InstrumentPoint{
+			Node:            stmt,
+			AccessType:      AccessWrite,
+			Addr:            addr,
+			WriteWordsConst: writeWordsConst,
+		}
+		// Global symbolization (synth-3631): a write to a package-level
+		// variable declared in this file gets the same treatment as a
+		// resolved struct field write - see globalSymbolName.
+		if ident, ok := lhs.(*ast.Ident); ok {
+			if name, ok := globalSymbolName(v.file, ident); ok {
+				point.SymbolName = name
+				point.SymbolID = symbolID(name)
+				point.SymbolKind = "global"
+			}
+		}
+
 		// Record instrumentation point.
-		v.instrumentationPoints = append(v.instrumentationPoints, InstrumentPoint{
-			Node:       stmt,
-			AccessType: AccessWrite,
-			Addr:       addr,
-		})
+		v.instrumentationPoints = append(v.instrumentationPoints, point)
 		v.stats.WritesInstrumented++
 	}
 }
@@ -284,7 +443,7 @@ func (v *instrumentVisitor) visitAssignment(stmt *ast.AssignStmt) {
 //   - stmt: IncDecStmt node
 func (v *instrumentVisitor) visitIncDec(stmt *ast.IncDecStmt) {
 	// Skip if this expression shouldn't be instrumented
-	if !shouldInstrument(stmt.X) {
+	if !v.shouldInstrument(stmt.X) {
 		v.trackSkipped(stmt.X)
 		return
 	}
@@ -314,6 +473,13 @@ func (v *instrumentVisitor) visitIncDec(stmt *ast.IncDecStmt) {
 		})
 		v.stats.WritesInstrumented++
 	}
+
+	// arr[0]++: both accesses are recorded above, against stmt itself. Stop
+	// visitIndexAccess from recording a third, redundant point when ast.Walk
+	// reaches stmt.X again via the generic dispatch (synth-3627).
+	if idx, ok := stmt.X.(*ast.IndexExpr); ok {
+		v.incDecTargets[idx] = true
+	}
 }
 
 // extractReads extracts read operations from an expression.
@@ -338,7 +504,7 @@ func (v *instrumentVisitor) extractReads(expr ast.Expr, stmt ast.Stmt) {
 		case *ast.Ident:
 			// Simple variable read: counter
 			// Skip if this expression shouldn't be instrumented
-			if !shouldInstrument(e) {
+			if !v.shouldInstrument(e) {
 				v.trackSkipped(e)
 				return true
 			}
@@ -355,7 +521,7 @@ func (v *instrumentVisitor) extractReads(expr ast.Expr, stmt ast.Stmt) {
 			// Struct field read: obj.field (e.g., os.Args, person.Name)
 			// IMPORTANT: Return false to stop walking into children (X and Sel)
 			// Otherwise we'd instrument both &os.Args AND &os AND &Args separately!
-			if !shouldInstrument(e) {
+			if !v.shouldInstrument(e) {
 				v.trackSkipped(e)
 				return false // Don't walk into children
 			}
@@ -369,23 +535,38 @@ func (v *instrumentVisitor) extractReads(expr ast.Expr, stmt ast.Stmt) {
 			return false // Don't walk into X (os) and Sel (Args) separately
 
 		case *ast.IndexExpr:
-			// Array/slice read: arr[i]
-			if !shouldInstrument(e) {
+			// Array/slice read: arr[i]. A confirmed-addressable index (see
+			// isAddressableIndex) is not recorded here - the generic
+			// dispatch in Visit() will reach this same node via ast.Walk
+			// continuing into the RHS after visitAssignment returns, and
+			// visitIndexAccess records it there with the correct
+			// per-element &arr[i] address (synth-3627). Recording it here
+			// too would double-count it, the same reasoning *ast.StarExpr
+			// below already follows.
+			//
+			// When it isn't confirmed addressable (a real map, or type info
+			// we couldn't resolve), we still can't instrument arr[i]
+			// itself, so fall through to the base/index identifiers - see
+			// TestInstrumentFile_MapIndex.
+			if !v.shouldInstrument(e) {
 				v.trackSkipped(e)
 				return true
 			}
-			addr := &ast.UnaryExpr{Op: token.AND, X: e}
-			v.instrumentationPoints = append(v.instrumentationPoints, InstrumentPoint{
-				Node:       stmt,
-				AccessType: AccessRead,
-				Addr:       addr,
-			})
-			v.stats.ReadsInstrumented++
+			return false
+
+		case *ast.StarExpr:
+			// Pointer dereference: *ptr. Don't walk into X (ptr) here - the
+			// generic dispatch in Visit() will reach this same node via
+			// ast.Walk continuing into the RHS after visitAssignment
+			// returns, and visitDereference records it there instead (see
+			// its doc comment). Recording it here too would double-count
+			// it (v0.5.0).
+			return false
 
 		case *ast.UnaryExpr:
 			if e.Op == token.MUL {
 				// Pointer dereference: *ptr
-				if !shouldInstrument(e) {
+				if !v.shouldInstrument(e) {
 					v.trackSkipped(e)
 					return true
 				}
@@ -423,8 +604,13 @@ func (v *instrumentVisitor) extractReads(expr ast.Expr, stmt ast.Stmt) {
 			return false // Don't continue walking - we handled it
 
 		case *ast.IndexListExpr:
-			// Generic instantiation: Func[T, U](args)
-			// Skip entirely - cannot take address of generic function
+			// Generic instantiation with 2+ type arguments: Func[T, U](args),
+			// Container[K, V]{} (synth-3592). Skip entirely - a type argument
+			// list is never addressable, and the same node shape appears in
+			// composite literal Type fields and call Fun expressions where
+			// walking into it would try to instrument type parameter names
+			// as if they were variables.
+			v.trackSkipped(e)
 			return false
 
 		case *ast.TypeAssertExpr:
@@ -542,8 +728,14 @@ func isBuiltinIdent(name string) bool {
 // Returns:
 //   - bool: true if expression needs instrumentation, false otherwise
 //
+// info supplies the go/types information (possibly incomplete - see
+// checkTypesBestEffort) used to resolve the one case AST shape alone can't:
+// telling a map index apart from a slice/array index (synth-3627). Package
+// level so coverage.go's read-only analysis pass, which has no
+// instrumentVisitor of its own, can call it with its own best-effort info.
+//
 // Thread Safety: Read-only, safe for concurrent use.
-func shouldInstrument(expr ast.Expr) bool {
+func shouldInstrument(expr ast.Expr, info *types.Info) bool {
 	// Skip constants
 	if isConstant(expr) {
 		return false
@@ -601,13 +793,20 @@ func shouldInstrument(expr ast.Expr) bool {
 		return false
 	}
 
-	// Skip IndexExpr on maps - cannot take address of map element
-	// Without type info, we cannot distinguish map[key] from slice[i]
-	// Conservative approach: skip all IndexExpr to avoid "cannot take address of" errors
-	// This may miss some race conditions on slice/array elements, but it's safer
-	if _, ok := expr.(*ast.IndexExpr); ok {
-		// TODO: With type info, we could distinguish maps from slices/arrays
-		// For now, skip all to avoid compilation errors
+	// IndexExpr: cannot take the address of a map element (&m[k] doesn't
+	// compile), but &a[i] on a slice or array is not just legal, it's the
+	// correct per-element address - a[1] and a[2] are genuinely distinct
+	// memory locations and must not be conflated. isAddressableIndex
+	// consults info to tell the two apart (synth-3627); when it can't
+	// (unresolved import, cross-file symbol, etc.) this keeps the original
+	// conservative skip.
+	if idx, ok := expr.(*ast.IndexExpr); ok {
+		return isAddressableIndex(info, idx)
+	}
+
+	// Skip IndexListExpr - a generic instantiation's type argument list
+	// (Func[T, U], Container[K, V]{}), never a memory address (synth-3592).
+	if _, ok := expr.(*ast.IndexListExpr); ok {
 		return false
 	}
 
@@ -619,6 +818,13 @@ func shouldInstrument(expr ast.Expr) bool {
 	return true
 }
 
+// shouldInstrument is shouldInstrument(expr, info) with info bound to the
+// visitor's own best-effort type information. See the package-level
+// function for the actual rules.
+func (v *instrumentVisitor) shouldInstrument(expr ast.Expr) bool {
+	return shouldInstrument(expr, v.typesInfo)
+}
+
 // trackSkipped tracks why an expression was skipped (for statistics).
 //
 // This helper method classifies skipped expressions and increments
@@ -634,6 +840,11 @@ func (v *instrumentVisitor) trackSkipped(expr ast.Expr) {
 		return
 	}
 
+	if _, ok := expr.(*ast.IndexListExpr); ok {
+		v.stats.GenericsSkipped++
+		return
+	}
+
 	if ident, ok := expr.(*ast.Ident); ok {
 		if ident.Name == "_" {
 			v.stats.BlanksSkipped++
@@ -731,25 +942,98 @@ func isLiteral(expr ast.Expr) bool {
 //	y := *ptr   → READ
 //	*ptr = 42   → WRITE
 //
-// For MVP, we'll conservatively instrument all dereferences as READS.
-// The WRITE case is handled separately in visitAssignment.
+// visitAssignment records a write-context StarExpr in writeTargets before
+// ast.Walk reaches it here, so we classify accordingly instead of always
+// recording a read. Since visitAssignment does not instrument these LHS
+// expressions itself (see writeTargets), this is the only place the point
+// is recorded - no duplicate.
 //
-// Future Enhancement (Phase 6B):
-// Perform context analysis to determine read vs write accurately.
+// *ast.StarExpr is also how go/ast represents a pointer TYPE (the *int in
+// "var ptr *int", or a field/param/result type), not just a dereference
+// expression - the two are only distinguishable by the surrounding syntax,
+// which we don't track here. We skip when the operand is a bare identifier
+// that isn't a resolved variable (see isResolvedVar): that covers "*int",
+// "*MyType", and "*pkg.Type" bottoms out the same way, while still
+// instrumenting "*ptr" for a real pointer variable.
 //
 // Parameters:
-//   - expr: Unary expression node (dereference)
-func (v *instrumentVisitor) visitDereference(expr *ast.UnaryExpr) {
+//   - expr: Star expression node (dereference)
+func (v *instrumentVisitor) visitDereference(expr *ast.StarExpr) {
+	if ident, ok := expr.X.(*ast.Ident); ok && !isResolvedVar(ident) {
+		return
+	}
+
 	// The operand of * is the pointer being dereferenced.
 	// Example: *ptr → operand is ptr
 	addr := expr.X
 
-	// Record instrumentation point.
+	accessType := AccessRead
+	if v.writeTargets[expr] {
+		accessType = AccessWrite
+		delete(v.writeTargets, expr)
+	}
+
 	v.instrumentationPoints = append(v.instrumentationPoints, InstrumentPoint{
 		Node:       expr,
-		AccessType: AccessRead,
+		AccessType: accessType,
 		Addr:       addr,
 	})
+	if accessType == AccessWrite {
+		v.stats.WritesInstrumented++
+	} else {
+		v.stats.ReadsInstrumented++
+	}
+}
+
+// isResolvedVar reports whether expr is an *ast.Ident that the parser
+// resolved to a local variable declaration (ident.Obj.Kind == ast.Var).
+//
+// This is the same ultra-conservative test shouldInstrument applies to
+// plain identifiers, factored out so visitIndexAccess/visitFieldAccess can
+// apply it to the base of an index or selector expression: without a type
+// checker, an unresolved identifier (ident.Obj == nil) is indistinguishable
+// from a package name (os in os.Exit) or a symbol declared in another
+// file, and neither is addressable the way a local variable is.
+//
+// Parameters:
+//   - expr: Expression to check
+//
+// Returns:
+//   - bool: true if expr is a resolved variable identifier
+//
+// Thread Safety: Read-only, safe for concurrent use.
+func isResolvedVar(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Obj != nil && ident.Obj.Kind == ast.Var
+}
+
+// globalSymbolName reports the qualified "pkg.VarName" name for ident, if it
+// resolves to a variable declared at package scope in this file, or "",
+// false otherwise (synth-3631).
+//
+// Unlike a struct field (see fieldSymbolName in typeinfo.go), telling a
+// package-level variable apart from a local one needs no type checker:
+// go/parser already resolves ident.Obj to the same *ast.Object for a
+// package-level declaration as for a local one (see isResolvedVar) - the
+// only extra fact needed is whether that Object lives in the file's own
+// Scope (the package block) rather than some inner function or block scope,
+// which file.Scope.Lookup answers directly.
+//
+// Parameters:
+//   - file: File ident was parsed as part of
+//   - ident: Identifier to classify
+//
+// Returns:
+//   - string: "pkg.VarName", when resolved
+//   - bool: true only if ident names a package-level variable in file
+func globalSymbolName(file *ast.File, ident *ast.Ident) (string, bool) {
+	if !isResolvedVar(ident) {
+		return "", false
+	}
+	if file.Scope == nil || file.Scope.Lookup(ident.Name) != ident.Obj {
+		return "", false
+	}
+	return file.Name.Name + "." + ident.Name, true
 }
 
 // visitIndexAccess handles array/slice accesses: arr[0], slice[i].
@@ -759,21 +1043,56 @@ func (v *instrumentVisitor) visitDereference(expr *ast.UnaryExpr) {
 //	y := arr[0]   → READ
 //	arr[0] = 42   → WRITE
 //
-// For MVP, we'll instrument as READS. WRITE case handled in visitAssignment.
+// visitAssignment records a write-context IndexExpr in writeTargets before
+// ast.Walk reaches it here, so we classify accordingly instead of always
+// recording a read. Since visitAssignment does not instrument these LHS
+// expressions itself (see writeTargets), this is the only place the point
+// is recorded - no duplicate.
+//
+// We only instrument when the base (arr) is a resolved local variable -
+// see isResolvedVar - which rules out indexing a function call result or a
+// package-level table we can't see the declaration of. Addr's validity
+// (&arr[index] doesn't compile if arr is a map) is a separate question,
+// resolved by isAddressableIndex via v.typesInfo and recorded on the point
+// as Addressable; ApplyInstrumentation is what actually acts on it
+// (synth-3627).
 //
 // Parameters:
 //   - expr: Index expression node
 func (v *instrumentVisitor) visitIndexAccess(expr *ast.IndexExpr) {
+	if !isResolvedVar(expr.X) {
+		return
+	}
+
+	if v.incDecTargets[expr] {
+		delete(v.incDecTargets, expr)
+		return
+	}
+
 	// Index access: arr[index]
-	// Address is &arr[index] conceptually, but we need arr base address.
-	// For simplicity, we'll use the entire IndexExpr as the address.
-	addr := expr
+	// Address: &arr[index]
+	addr := &ast.UnaryExpr{
+		Op: token.AND,
+		X:  expr,
+	}
+
+	accessType := AccessRead
+	if v.writeTargets[expr] {
+		accessType = AccessWrite
+		delete(v.writeTargets, expr)
+	}
 
 	v.instrumentationPoints = append(v.instrumentationPoints, InstrumentPoint{
-		Node:       expr,
-		AccessType: AccessRead,
-		Addr:       addr,
+		Node:        expr,
+		AccessType:  accessType,
+		Addr:        addr,
+		Addressable: isAddressableIndex(v.typesInfo, expr),
 	})
+	if accessType == AccessWrite {
+		v.stats.WritesInstrumented++
+	} else {
+		v.stats.ReadsInstrumented++
+	}
 }
 
 // visitFieldAccess handles struct field accesses: obj.field.
@@ -783,20 +1102,61 @@ func (v *instrumentVisitor) visitIndexAccess(expr *ast.IndexExpr) {
 //	y := obj.field   → READ
 //	obj.field = 42   → WRITE
 //
-// For MVP, we'll instrument as READS. WRITE case handled in visitAssignment.
+// visitAssignment records a write-context SelectorExpr in writeTargets
+// before ast.Walk reaches it here, so we classify accordingly instead of
+// always recording a read. Since visitAssignment does not instrument these
+// LHS expressions itself (see writeTargets), this is the only place the
+// point is recorded - no duplicate.
+//
+// We only instrument when the base (obj) is a resolved local variable -
+// see isResolvedVar. Without type info a selector's base is otherwise
+// indistinguishable from a package name (os in os.Exit) or a method value
+// (obj.Method), neither of which is addressable.
 //
 // Parameters:
 //   - expr: Selector expression node (struct field access)
 func (v *instrumentVisitor) visitFieldAccess(expr *ast.SelectorExpr) {
+	if !isResolvedVar(expr.X) {
+		return
+	}
+
 	// Field access: obj.field
-	// Address is &obj.field
-	addr := expr
+	// Address: &obj.field
+	addr := &ast.UnaryExpr{
+		Op: token.AND,
+		X:  expr,
+	}
 
-	v.instrumentationPoints = append(v.instrumentationPoints, InstrumentPoint{
+	accessType := AccessRead
+	if v.writeTargets[expr] {
+		accessType = AccessWrite
+		delete(v.writeTargets, expr)
+	}
+
+	point := InstrumentPoint{
 		Node:       expr,
-		AccessType: AccessRead,
+		AccessType: accessType,
 		Addr:       addr,
-	})
+	}
+	if accessType == AccessWrite {
+		// Field-sensitive symbol id (synth-3630): only for writes, and
+		// only once go/types confirms expr really is a field (not a
+		// method value) - see fieldSymbolName. A field ApplyInstrumentation
+		// couldn't resolve a name for falls back to being categorically
+		// skipped, exactly as every SelectorExpr point was before this.
+		if name, ok := fieldSymbolName(v.typesInfo, expr); ok {
+			point.SymbolName = name
+			point.SymbolID = symbolID(name)
+			point.SymbolKind = "field"
+		}
+	}
+
+	v.instrumentationPoints = append(v.instrumentationPoints, point)
+	if accessType == AccessWrite {
+		v.stats.WritesInstrumented++
+	} else {
+		v.stats.ReadsInstrumented++
+	}
 }
 
 // extractAddress extracts the address expression from an LHS expression.
@@ -864,11 +1224,83 @@ func (v *instrumentVisitor) extractAddress(expr ast.Expr) ast.Expr {
 // Returns:
 //   - *instrumentVisitor: New visitor instance
 func newInstrumentVisitor(fset *token.FileSet, file *ast.File) *instrumentVisitor {
+	ignoredLines := buildIgnoredLines(fset, file)
+	checkedBlocks := buildCheckedBlocks(fset, file)
+
+	// Strip the directive comments from the AST once they've been read.
+	// Leaving them in place would have go/printer try to reposition them
+	// relative to the statements we insert during ApplyInstrumentation,
+	// which can print them in a misleading spot since their original
+	// token.Pos no longer reflects a neighboring statement's new position.
+	stripIgnoreDirectives(file)
+	stripCheckedDirectives(file)
+
 	return &instrumentVisitor{
 		fset:                  fset,
 		file:                  file,
 		instrumentationPoints: make([]instrumentPoint, 0, 100), // Pre-allocate for typical file
+		ignoredLines:          ignoredLines,
+		checkedBlocks:         checkedBlocks,
+		writeTargets:          make(map[ast.Expr]bool),
+		incDecTargets:         make(map[ast.Expr]bool),
+		typesInfo:             checkTypesBestEffort(fset, file),
+	}
+}
+
+// stripIgnoreDirectives removes "//racedetector:ignore" comments from
+// file.Comments in place, once buildIgnoredLines has already read them.
+// See newInstrumentVisitor for why they must not survive into the printed
+// output.
+func stripIgnoreDirectives(file *ast.File) {
+	kept := file.Comments[:0]
+	for _, group := range file.Comments {
+		keptComments := group.List[:0]
+		for _, c := range group.List {
+			if strings.TrimSpace(strings.TrimPrefix(c.Text, "//")) == ignoreDirective {
+				continue
+			}
+			keptComments = append(keptComments, c)
+		}
+		if len(keptComments) == 0 {
+			continue
+		}
+		group.List = keptComments
+		kept = append(kept, group)
 	}
+	file.Comments = kept
+}
+
+// buildIgnoredLines scans file's comments for "//racedetector:ignore"
+// markers (v0.4.0) and returns the set of source line numbers they exempt
+// from instrumentation.
+//
+// Uses ast.NewCommentMap to resolve which statement each comment annotates,
+// since a directive is valid both as a trailing comment on the same line
+// (`x = racy() //racedetector:ignore`) and as a standalone comment on the
+// line above the statement it covers - the same two placements
+// go/ast.NewCommentMap already distinguishes for godoc-style comments.
+//
+// Requires the file to have been parsed with parser.ParseComments so
+// file.Comments is populated; InstrumentFile already does this.
+func buildIgnoredLines(fset *token.FileSet, file *ast.File) map[int]bool {
+	ignored := make(map[int]bool)
+	cmap := ast.NewCommentMap(fset, file, file.Comments)
+	for node, groups := range cmap {
+		for _, group := range groups {
+			for _, c := range group.List {
+				text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+				if text != ignoreDirective {
+					continue
+				}
+				startLine := fset.Position(node.Pos()).Line
+				endLine := fset.Position(node.End()).Line
+				for line := startLine; line <= endLine; line++ {
+					ignored[line] = true
+				}
+			}
+		}
+	}
+	return ignored
 }
 
 // GetInstrumentationPoints returns the collected instrumentation points.
@@ -991,6 +1423,208 @@ func (v *instrumentVisitor) applyCoalescingToPoints(groups []CoalescingGroup) []
 	return coalescedPoints
 }
 
+// ApplyLockRegionBatching folds write accesses inside a statically proven
+// lock-protected critical section into a single race.RaceRegionWrite call
+// (synth-3597) - the same "keep the barrier count down" goal as
+// ApplyCoalescing, but batching MULTIPLE addresses held under one mutex
+// instead of repeated accesses to the SAME address.
+//
+// Unlike ApplyCoalescing, which only drops instrumentationPoints and lets
+// ApplyInstrumentation place the surviving barriers, this method also
+// splices the batched call directly into the AST itself (immediately before
+// the region's Unlock statement), since there's no single surviving point
+// among region.Writes whose own position ApplyInstrumentation could reuse.
+//
+// Parameters:
+//   - enableLockRegionBatching: If false, skip batching (debugging mode)
+//
+// Returns:
+//   - LockRegionStats: Statistics about batching effectiveness
+//
+// Thread Safety: NOT thread-safe (modifies instrumentationPoints and file).
+func (v *instrumentVisitor) ApplyLockRegionBatching(enableLockRegionBatching bool) LockRegionStats {
+	totalWrites := 0
+	for _, point := range v.instrumentationPoints {
+		if point.AccessType == AccessWrite {
+			totalWrites++
+		}
+	}
+
+	if !enableLockRegionBatching || len(v.instrumentationPoints) < 2 {
+		return LockRegionStats{TotalWrites: totalWrites}
+	}
+
+	regions, stats := AnalyzeLockRegions(v.instrumentationPoints, v.file)
+	if len(regions) == 0 {
+		return stats
+	}
+
+	// Drop the batched writes' individual instrumentation points so
+	// ApplyInstrumentation never emits their per-access barriers.
+	shouldRemove := make(map[ast.Node]bool)
+	for _, region := range regions {
+		for _, point := range region.Writes {
+			shouldRemove[point.Node] = true
+		}
+	}
+	remaining := make([]instrumentPoint, 0, len(v.instrumentationPoints))
+	for _, point := range v.instrumentationPoints {
+		if !shouldRemove[point.Node] {
+			remaining = append(remaining, point)
+		}
+	}
+	v.instrumentationPoints = remaining
+
+	for _, region := range regions {
+		v.insertRegionBatchCall(region)
+	}
+
+	return stats
+}
+
+// insertRegionBatchCall splices a single race.RaceRegionWrite(...) call
+// into region's enclosing block, immediately before its Unlock statement.
+func (v *instrumentVisitor) insertRegionBatchCall(region LockRegion) {
+	batchCall := v.createRegionRaceCall(region.Writes)
+
+	ast.Inspect(v.file, func(n ast.Node) bool {
+		block, ok := n.(*ast.BlockStmt)
+		if !ok {
+			return true
+		}
+		for i, stmt := range block.List {
+			if stmt == region.Unlock {
+				newList := make([]ast.Stmt, 0, len(block.List)+1)
+				newList = append(newList, block.List[:i]...)
+				newList = append(newList, batchCall)
+				newList = append(newList, block.List[i:]...)
+				block.List = newList
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// createRegionRaceCall builds a single
+// race.RaceRegionWrite(uintptr(unsafe.Pointer(&a)), uintptr(unsafe.Pointer(&b)), ...)
+// statement from a region's collected write points, in the same "innermost
+// out" style as createRaceCall.
+func (v *instrumentVisitor) createRegionRaceCall(points []InstrumentPoint) ast.Stmt {
+	args := make([]ast.Expr, 0, len(points))
+	for _, point := range points {
+		unsafePointerCall := &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent("unsafe"), Sel: ast.NewIdent("Pointer")},
+			Args: []ast.Expr{point.Addr},
+		}
+		args = append(args, &ast.CallExpr{
+			Fun:  ast.NewIdent("uintptr"),
+			Args: []ast.Expr{unsafePointerCall},
+		})
+	}
+
+	call := &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(RacePackageAlias), Sel: ast.NewIdent("RaceRegionWrite")},
+		Args: args,
+	}
+	return &ast.ExprStmt{X: call}
+}
+
+// ApplyCheckedAnnotations wraps every "//racedetector:checked" block
+// collected by buildCheckedBlocks in a DisableCurrentGoroutine/
+// EnableCurrentGoroutine region, with a RegisterCheckedAnnotation call
+// recording the block's location for Fini()'s audit report (synth-3599).
+//
+// Must run before ApplyInstrumentation, so the statements spliced into each
+// block are just ordinary pre-existing statements by the time that pass
+// rebuilds each function body's statement list - same ordering requirement
+// as insertRegionBatchCall.
+//
+// A block with a top-level return/break/continue/goto (see
+// blockHasEarlyExit) is left alone and instrumented normally instead: a
+// plain block has no `defer` to guarantee EnableCurrentGoroutine still
+// runs on early exit, so wrapping it would risk leaving the goroutine's
+// detection permanently disabled.
+//
+// Thread Safety: NOT thread-safe (modifies AST in place).
+func (v *instrumentVisitor) ApplyCheckedAnnotations() CheckedAnnotationStats {
+	stats := CheckedAnnotationStats{AnnotationsFound: len(v.checkedBlocks)}
+
+	for _, cb := range v.checkedBlocks {
+		if blockHasEarlyExit(cb.Block) {
+			stats.SkippedEarlyExit++
+			continue
+		}
+
+		// Drop this block's already-collected instrumentation points -
+		// ApplyInstrumentation must never emit a per-access barrier inside
+		// a region whose only race-detector interaction is the
+		// Disable/Enable region below. Mirrors ApplyLockRegionBatching's
+		// shouldRemove, but by line range rather than by Node identity,
+		// since a checked block can hold any mix of access kinds.
+		startLine := v.fset.Position(cb.Block.Pos()).Line
+		endLine := v.fset.Position(cb.Block.End()).Line
+		remaining := make([]instrumentPoint, 0, len(v.instrumentationPoints))
+		for _, point := range v.instrumentationPoints {
+			line := v.fset.Position(point.Node.Pos()).Line
+			if line >= startLine && line <= endLine {
+				v.stats.CheckedSkipped++
+				continue
+			}
+			remaining = append(remaining, point)
+		}
+		v.instrumentationPoints = remaining
+
+		wrapped := make([]ast.Stmt, 0, len(cb.Block.List)+3)
+		wrapped = append(wrapped, v.createRegisterCheckedCall(cb.Location))
+		wrapped = append(wrapped, v.createDisableGoroutineCall())
+		wrapped = append(wrapped, cb.Block.List...)
+		wrapped = append(wrapped, v.createEnableGoroutineCall())
+		cb.Block.List = wrapped
+
+		stats.RegionsWrapped++
+	}
+
+	return stats
+}
+
+// createRegisterSymbolCall builds race.RegisterSymbol(0x<id>, "name", "kind")
+// (synth-3630, kind argument added by synth-3631), emitted immediately
+// before the race.RaceWriteSym call it documents so the id is always
+// registered before a report could need it.
+func (v *instrumentVisitor) createRegisterSymbolCall(point instrumentPoint) ast.Stmt {
+	call := &ast.CallExpr{
+		Fun: &ast.SelectorExpr{X: ast.NewIdent(RacePackageAlias), Sel: ast.NewIdent("RegisterSymbol")},
+		Args: []ast.Expr{
+			&ast.BasicLit{Kind: token.INT, Value: fmt.Sprintf("0x%x", point.SymbolID)},
+			&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(point.SymbolName)},
+			&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(point.SymbolKind)},
+		},
+	}
+	return &ast.ExprStmt{X: call}
+}
+
+// createRegisterCheckedCall builds race.RegisterCheckedAnnotation("file:line").
+func (v *instrumentVisitor) createRegisterCheckedCall(location string) ast.Stmt {
+	call := &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(RacePackageAlias), Sel: ast.NewIdent("RegisterCheckedAnnotation")},
+		Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(location)}},
+	}
+	return &ast.ExprStmt{X: call}
+}
+
+// createDisableGoroutineCall builds race.DisableCurrentGoroutine().
+func (v *instrumentVisitor) createDisableGoroutineCall() ast.Stmt {
+	call := &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(RacePackageAlias), Sel: ast.NewIdent("DisableCurrentGoroutine")}}
+	return &ast.ExprStmt{X: call}
+}
+
+// createEnableGoroutineCall builds race.EnableCurrentGoroutine().
+func (v *instrumentVisitor) createEnableGoroutineCall() ast.Stmt {
+	call := &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(RacePackageAlias), Sel: ast.NewIdent("EnableCurrentGoroutine")}}
+	return &ast.ExprStmt{X: call}
+}
+
 // ApplyInstrumentation inserts race detection calls into the AST.
 //
 // This function performs the second pass of instrumentation: it takes
@@ -1032,6 +1666,28 @@ func (v *instrumentVisitor) ApplyInstrumentation() error {
 
 	// Find parent statements for each instrumentation point
 	for _, point := range v.instrumentationPoints {
+		switch point.Node.(type) {
+		case *ast.SelectorExpr:
+			// visitFieldAccess records these for classification testing
+			// (see GetInstrumentationPoints), but obj.field is not safely
+			// distinguishable from a method value (obj.Method) without a
+			// type checker, and &obj.Method doesn't compile. SymbolName
+			// set means fieldSymbolName already confirmed, via go/types,
+			// that this one really is a field write (synth-3630) - let it
+			// through. Everything else keeps the pre-synth-3630 skip.
+			if point.SymbolName == "" {
+				continue
+			}
+		case *ast.IndexExpr:
+			// visitIndexAccess sets Addressable once go/types has confirmed
+			// arr[0] isn't a map index (m["k"] doesn't compile - see
+			// TestInstrumentFile_MapIndex, issue #9). Points it couldn't
+			// confirm stay skipped, same as before synth-3627.
+			if !point.Addressable {
+				continue
+			}
+		}
+
 		stmt := v.findParentStatement(point.Node)
 		if stmt != nil {
 			stmtToPoints[stmt] = append(stmtToPoints[stmt], point)
@@ -1050,6 +1706,9 @@ func (v *instrumentVisitor) ApplyInstrumentation() error {
 				if points, ok := stmtToPoints[stmt]; ok {
 					// Insert race calls BEFORE this statement
 					for _, point := range points {
+						if point.SymbolName != "" {
+							newStmts = append(newStmts, v.createRegisterSymbolCall(point))
+						}
 						raceCall := v.createRaceCall(point)
 						if raceCall != nil {
 							newStmts = append(newStmts, raceCall)
@@ -1067,6 +1726,9 @@ func (v *instrumentVisitor) ApplyInstrumentation() error {
 			for _, stmt := range block.Body {
 				if points, ok := stmtToPoints[stmt]; ok {
 					for _, point := range points {
+						if point.SymbolName != "" {
+							newStmts = append(newStmts, v.createRegisterSymbolCall(point))
+						}
 						raceCall := v.createRaceCall(point)
 						if raceCall != nil {
 							newStmts = append(newStmts, raceCall)
@@ -1083,6 +1745,9 @@ func (v *instrumentVisitor) ApplyInstrumentation() error {
 			for _, stmt := range block.Body {
 				if points, ok := stmtToPoints[stmt]; ok {
 					for _, point := range points {
+						if point.SymbolName != "" {
+							newStmts = append(newStmts, v.createRegisterSymbolCall(point))
+						}
 						raceCall := v.createRaceCall(point)
 						if raceCall != nil {
 							newStmts = append(newStmts, raceCall)
@@ -1100,17 +1765,31 @@ func (v *instrumentVisitor) ApplyInstrumentation() error {
 	return nil
 }
 
-// findParentStatement finds the statement containing the given node.
+// findParentStatement finds the innermost statement containing the given
+// node.
 //
 // This helper walks up the AST from a node to find the enclosing statement.
 // This is needed because we insert race calls at statement level, not
 // expression level.
 //
+// It must find the INNERMOST enclosing statement, not just any enclosing
+// one: a node nested inside a defer/go closure, an if/for body, or a
+// switch case is also contained by every statement that encloses that
+// closure or block in turn, all the way up to the function body. Stopping
+// at the first match found by a top-down walk over v.file returns that
+// outermost statement (typically the function's top-level *ast.BlockStmt)
+// instead of the specific one ApplyInstrumentation actually indexes by,
+// so the resulting race call would either land in the wrong scope or -
+// since *ast.BlockStmt is never itself a key in stmtToPoints - be silently
+// dropped. We track the stack of statements currently being walked and
+// take the top of the stack when we reach node, which is the innermost
+// one (v0.5.0).
+//
 // Parameters:
 //   - node: AST node to find parent for
 //
 // Returns:
-//   - ast.Stmt: Parent statement, or nil if not found
+//   - ast.Stmt: Innermost enclosing statement, or nil if not found
 func (v *instrumentVisitor) findParentStatement(node ast.Node) ast.Stmt {
 	// For assignments, the node itself is the statement
 	if stmt, ok := node.(ast.Stmt); ok {
@@ -1119,26 +1798,45 @@ func (v *instrumentVisitor) findParentStatement(node ast.Node) ast.Stmt {
 
 	// For expressions, we need to find the enclosing statement
 	// This is tricky without parent pointers, so we'll use a heuristic:
-	// Walk the AST and match nodes
-	var result ast.Stmt
+	// walk the AST, maintaining a stack of the innermost statement seen
+	// so far at each depth, and record its top when we reach node.
+	var (
+		stack  []ast.Stmt
+		result ast.Stmt
+	)
 
 	ast.Inspect(v.file, func(n ast.Node) bool {
-		// Check if this is a statement containing our node
+		if result != nil {
+			// Already found; nothing left to do, including popping the
+			// stack, since we never read it again.
+			return false
+		}
+
+		if n == nil {
+			// ast.Inspect calls f(nil) once it's done visiting a node's
+			// children, signalling that we're leaving whatever we pushed
+			// for that node.
+			stack = stack[:len(stack)-1]
+			return true
+		}
+
+		// A non-statement node (e.g. an expression) is still "inside"
+		// whatever statement most recently enclosed it, so inherit the
+		// current top of stack unless n itself narrows it further.
+		current := ast.Stmt(nil)
+		if len(stack) > 0 {
+			current = stack[len(stack)-1]
+		}
 		if stmt, ok := n.(ast.Stmt); ok {
-			// Check if our node is inside this statement
-			found := false
-			ast.Inspect(stmt, func(inner ast.Node) bool {
-				if inner == node {
-					found = true
-					return false
-				}
-				return true
-			})
-			if found {
-				result = stmt
-				return false // Found it, stop searching
-			}
+			current = stmt
 		}
+
+		if n == node {
+			result = current
+			return false
+		}
+
+		stack = append(stack, current)
 		return true
 	})
 
@@ -1148,11 +1846,15 @@ func (v *instrumentVisitor) findParentStatement(node ast.Node) ast.Stmt {
 // createRaceCall creates an AST node for a race detection call.
 //
 // This function generates an expression statement that calls race.RaceRead()
-// or race.RaceWrite() with the appropriate address.
+// or race.RaceWrite() with the appropriate address - or, for a point with
+// WriteWordsConst set (synth-3628, synth-3629), race.RaceWriteRange() with
+// the named width constant as a second argument.
 //
 // Generated Code:
 //   - race.RaceWrite(uintptr(unsafe.Pointer(&x)))
 //   - race.RaceRead(uintptr(unsafe.Pointer(&x)))
+//   - race.RaceWriteRange(uintptr(unsafe.Pointer(&s)), race.SliceHeaderWords)
+//   - race.RaceWriteRange(uintptr(unsafe.Pointer(&v)), race.InterfaceWords)
 //
 // Parameters:
 //   - point: Instrumentation point describing the access
@@ -1162,9 +1864,14 @@ func (v *instrumentVisitor) findParentStatement(node ast.Node) ast.Stmt {
 func (v *instrumentVisitor) createRaceCall(point instrumentPoint) ast.Stmt {
 	// Determine function name based on access type
 	var funcName string
-	if point.AccessType == AccessWrite {
+	switch {
+	case point.AccessType == AccessWrite && point.WriteWordsConst != "":
+		funcName = "RaceWriteRange"
+	case point.AccessType == AccessWrite && point.SymbolName != "":
+		funcName = "RaceWriteSym"
+	case point.AccessType == AccessWrite:
 		funcName = "RaceWrite"
-	} else {
+	default:
 		funcName = "RaceRead"
 	}
 
@@ -1198,13 +1905,32 @@ func (v *instrumentVisitor) createRaceCall(point instrumentPoint) ast.Stmt {
 		Args: []ast.Expr{unsafePointerCall},
 	}
 
-	// 4. race.RaceWrite(...) or race.RaceRead(...)
+	// 4. race.RaceWrite(...), race.RaceRead(...), or
+	// race.RaceWriteRange(..., race.SliceHeaderWords/StringWords/
+	// InterfaceWords) - the width is passed as the race package's own
+	// named constant rather than a literal so generated code always
+	// matches whatever RaceWriteRange expects, and so the constant's name
+	// itself documents which value (slice, string, interface) is being
+	// reassigned.
+	args := []ast.Expr{uintptrConversion}
+	if funcName == "RaceWriteRange" {
+		args = append(args, &ast.SelectorExpr{
+			X:   ast.NewIdent(RacePackageAlias),
+			Sel: ast.NewIdent(point.WriteWordsConst),
+		})
+	}
+	if funcName == "RaceWriteSym" {
+		args = append(args, &ast.BasicLit{
+			Kind:  token.INT,
+			Value: fmt.Sprintf("0x%x", point.SymbolID),
+		})
+	}
 	raceCall := &ast.CallExpr{
 		Fun: &ast.SelectorExpr{
 			X:   ast.NewIdent(RacePackageAlias),
 			Sel: ast.NewIdent(funcName),
 		},
-		Args: []ast.Expr{uintptrConversion},
+		Args: args,
 	}
 
 	// 5. Wrap in expression statement
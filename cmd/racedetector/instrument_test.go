@@ -0,0 +1,154 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseInstrumentArgs_Basic verifies plain source files parse with -diff
+// left unset.
+func TestParseInstrumentArgs_Basic(t *testing.T) {
+	config, err := parseInstrumentArgs([]string{"main.go"})
+	if err != nil {
+		t.Fatalf("parseInstrumentArgs() error: %v", err)
+	}
+
+	if len(config.sourceFiles) != 1 || config.sourceFiles[0] != "main.go" {
+		t.Errorf("sourceFiles = %v, want [main.go]", config.sourceFiles)
+	}
+	if config.diff {
+		t.Error("diff = true, want false when -diff isn't passed")
+	}
+}
+
+// TestParseInstrumentArgs_DiffFlag verifies -diff is recognized regardless
+// of its position among source files.
+func TestParseInstrumentArgs_DiffFlag(t *testing.T) {
+	config, err := parseInstrumentArgs([]string{"-diff", "main.go", "helper.go"})
+	if err != nil {
+		t.Fatalf("parseInstrumentArgs() error: %v", err)
+	}
+
+	if !config.diff {
+		t.Error("diff = false, want true when -diff is passed")
+	}
+	if len(config.sourceFiles) != 2 {
+		t.Errorf("sourceFiles = %v, want 2 entries", config.sourceFiles)
+	}
+}
+
+// TestParseInstrumentArgs_NoSources verifies an error when no source files
+// are given.
+func TestParseInstrumentArgs_NoSources(t *testing.T) {
+	if _, err := parseInstrumentArgs([]string{"-diff"}); err == nil {
+		t.Error("parseInstrumentArgs() with no source files, want error")
+	}
+}
+
+// TestInstrumentCommand_Diff verifies 'racedetector instrument -diff' prints
+// a unified diff for a file that gets instrumented, without writing
+// anything back to the source file itself.
+func TestInstrumentCommand_Diff(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "racedetector-instrument-cmd-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	source := `package main
+
+func main() {
+	x := 42
+	println(x)
+}
+`
+	testFile := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(testFile, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	output := captureStdout(t, func() {
+		instrumentCommand([]string{"-diff", testFile})
+	})
+
+	if !strings.Contains(output, "--- "+testFile) {
+		t.Errorf("output missing diff header, got:\n%s", output)
+	}
+	if !strings.Contains(output, "+") {
+		t.Errorf("output has no added lines, got:\n%s", output)
+	}
+
+	// The source file on disk must be untouched.
+	after, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to re-read test file: %v", err)
+	}
+	if string(after) != source {
+		t.Error("instrumentCommand modified the source file on disk")
+	}
+}
+
+// TestInstrumentCommand_SkipsCgoFile verifies 'racedetector instrument'
+// reports a cgo file as left unmodified instead of instrumenting it
+// (synth-3593).
+func TestInstrumentCommand_SkipsCgoFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "racedetector-instrument-cgo-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	source := `package main
+
+// #include <stdlib.h>
+import "C"
+
+func f() {
+	C.free(nil)
+}
+`
+	testFile := filepath.Join(tempDir, "cgo.go")
+	if err := os.WriteFile(testFile, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	output := captureStdout(t, func() {
+		instrumentCommand([]string{testFile})
+	})
+
+	if !strings.Contains(output, "cgo file, left unmodified") {
+		t.Errorf("output missing cgo skip notice, got:\n%s", output)
+	}
+	if strings.Contains(output, "race.RaceWrite") || strings.Contains(output, "race.RaceRead") {
+		t.Errorf("output instrumented a cgo file, got:\n%s", output)
+	}
+}
+
+// captureStdout runs fn with os.Stdout redirected to a pipe and returns
+// everything written to it.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error: %v", err)
+	}
+
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	fn()
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close pipe writer: %v", err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read captured output: %v", err)
+	}
+	return string(out)
+}
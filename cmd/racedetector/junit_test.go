@@ -0,0 +1,140 @@
+// junit_test.go implements tests for the 'racedetector test -junit' report.
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReadRacesFile tests splitting the NUL-separated race-reports side
+// channel file back into individual reports.
+func TestReadRacesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		write   bool
+		want    []string
+	}{
+		{
+			name:  "missing file",
+			write: false,
+			want:  nil,
+		},
+		{
+			name:    "empty file",
+			content: "",
+			write:   true,
+			want:    nil,
+		},
+		{
+			name:    "single race",
+			content: "report one",
+			write:   true,
+			want:    []string{"report one"},
+		},
+		{
+			name:    "multiple races",
+			content: "report one\x00report two\x00report three",
+			write:   true,
+			want:    []string{"report one", "report two", "report three"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "races.txt")
+			if tt.write {
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				path = filepath.Join(t.TempDir(), "does-not-exist.txt")
+			}
+
+			got := readRacesFile(path)
+			if len(got) != len(tt.want) {
+				t.Fatalf("readRacesFile() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("readRacesFile()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestReadRacesFile_EmptyPath tests that an empty path (no -junit requested,
+// or race report file creation failed) is treated as "no races".
+func TestReadRacesFile_EmptyPath(t *testing.T) {
+	if got := readRacesFile(""); got != nil {
+		t.Errorf("readRacesFile(\"\") = %v, want nil", got)
+	}
+}
+
+// TestWriteJUnitReport_NoRaces tests that an empty races slice still
+// produces a valid, all-passing suite.
+func TestWriteJUnitReport_NoRaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "races.xml")
+	if err := writeJUnitReport(path, nil); err != nil {
+		t.Fatalf("writeJUnitReport() error = %v", err)
+	}
+
+	var suite junitTestSuite
+	unmarshalJUnitFile(t, path, &suite)
+
+	if suite.Tests != 0 || suite.Failures != 0 {
+		t.Errorf("suite = %+v, want Tests=0 Failures=0", suite)
+	}
+	if len(suite.TestCases) != 0 {
+		t.Errorf("TestCases = %v, want none", suite.TestCases)
+	}
+}
+
+// TestWriteJUnitReport_OneFailurePerRace tests that each race becomes its
+// own testcase carrying a failure element whose body is the formatted
+// report.
+func TestWriteJUnitReport_OneFailurePerRace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "races.xml")
+	races := []string{"race report A", "race report B"}
+	if err := writeJUnitReport(path, races); err != nil {
+		t.Fatalf("writeJUnitReport() error = %v", err)
+	}
+
+	var suite junitTestSuite
+	unmarshalJUnitFile(t, path, &suite)
+
+	if suite.Tests != 2 || suite.Failures != 2 {
+		t.Errorf("suite = %+v, want Tests=2 Failures=2", suite)
+	}
+	if len(suite.TestCases) != 2 {
+		t.Fatalf("TestCases = %v, want 2 entries", suite.TestCases)
+	}
+	for i, tc := range suite.TestCases {
+		if tc.Failure == nil {
+			t.Fatalf("TestCases[%d].Failure = nil, want a failure", i)
+		}
+		if tc.Failure.Body != races[i] {
+			t.Errorf("TestCases[%d].Failure.Body = %q, want %q", i, tc.Failure.Body, races[i])
+		}
+	}
+}
+
+// unmarshalJUnitFile reads and parses a JUnit XML file written by
+// writeJUnitReport into dst.
+func unmarshalJUnitFile(t *testing.T, path string, dst *junitTestSuite) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error = %v", path, err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header[:10]) {
+		t.Errorf("JUnit report %s missing XML header", path)
+	}
+	if err := xml.Unmarshal(data, dst); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+}
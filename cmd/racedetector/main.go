@@ -49,6 +49,20 @@ func main() {
 		runCommand(os.Args[2:])
 	case "test":
 		testCommand(os.Args[2:])
+	case "watch":
+		watchCommand(os.Args[2:])
+	case "vet":
+		vetCommand(os.Args[2:])
+	case "analyze":
+		analyzeCommand(os.Args[2:])
+	case "compare":
+		compareCommand(os.Args[2:])
+	case "bench-overhead":
+		benchOverheadCommand(os.Args[2:])
+	case "instrument":
+		instrumentCommand(os.Args[2:])
+	case "conformance":
+		conformanceCommand(os.Args[2:])
 	case "version", "--version", "-v":
 		fmt.Printf("racedetector version %s\n", version)
 		fmt.Printf("  commit: %s\n", commit)
@@ -72,6 +86,13 @@ COMMANDS:
     build      Build Go program with race detection
     run        Run Go program with race detection
     test       Test Go packages with race detection
+    watch      Watch packages and rerun tests with race detection on change
+    vet        Statically pre-screen packages for likely race sites
+    analyze    Detect races by replaying a previously recorded trace
+    compare    Diff race locations against the official CGO -race detector
+    bench-overhead  Measure instrumentation slowdown and allocation cost on your own benchmarks
+    instrument Print instrumented source (or a diff) without building
+    conformance Score a TestRace*/TestNoRace* corpus against this detector
     version    Show version information
     help       Show this help message
 
@@ -79,6 +100,9 @@ EXAMPLES:
     # Build a program with race detection
     racedetector build -o myapp main.go
 
+    # Build and audit instrumentation blind spots (skipped fields/indexes)
+    racedetector build -coverage-report coverage.json main.go
+
     # Run a program with race detection
     racedetector run main.go --flag=value
 
@@ -88,6 +112,37 @@ EXAMPLES:
     # Test with coverage
     racedetector test -cover ./internal/...
 
+    # Test and emit a JUnit XML race report for CI dashboards
+    racedetector test -junit races.xml ./...
+
+    # Stress-test for rare interleavings across 50 randomized seeds
+    racedetector test -stress -stress-iterations 50 ./...
+
+    # Isolate each test's race reporting and dedup state from the others
+    racedetector test -reset-between-tests ./...
+
+    # Watch packages and rerun tests with race detection on every save
+    racedetector watch ./...
+
+    # Statically pre-screen packages for likely race sites, no build/run
+    racedetector vet ./...
+
+    # Record a trace, then detect races by replaying it offline
+    RACEDETECTOR_RECORD_SCHEDULE=trace.jsonl racedetector test ./...
+    racedetector analyze trace.jsonl
+
+    # Check racedetector's findings against the official CGO -race detector
+    racedetector compare ./...
+
+    # Measure the real slowdown and allocation cost on your own benchmarks
+    racedetector bench-overhead ./internal/mypackage/...
+
+    # Preview exactly what instrumentation a file would receive
+    racedetector instrument -diff main.go
+
+    # Score the bundled TestRace*/TestNoRace* corpus against this detector
+    racedetector conformance
+
 ABOUT:
     racedetector is a standalone tool that provides race detection for Go
     programs without requiring CGO or a custom Go toolchain. It uses the
@@ -114,3 +169,8 @@ FOR MORE INFORMATION:
 // buildCommand is implemented in build.go
 // runCommand is implemented in run.go
 // testCommand is implemented in test.go
+// watchCommand is implemented in watch.go
+// vetCommand is implemented in vet.go
+// analyzeCommand is implemented in analyze.go
+// compareCommand is implemented in compare.go
+// benchOverheadCommand is implemented in bench_overhead.go
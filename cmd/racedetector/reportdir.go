@@ -0,0 +1,122 @@
+// reportdir.go implements the race-report aggregation directory
+// (RACEDETECTOR_REPORT_DIR) used by 'racedetector test' to combine race
+// reports across the many test binaries `go test ./...` spawns - one per
+// package, each its own OS process (synth-3601).
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// reportDirPath creates a temporary directory for the race-report
+// aggregation side channel (RACEDETECTOR_REPORT_DIR) used by
+// internal/race/api.Fini(). Each instrumented test binary writes its own
+// <pid>.report/<pid>.races pair into it, so packages running concurrently
+// under `go test ./...` can't clobber each other's results the way they
+// would writing the single shared file raceReportFilePath/racesFilePath
+// use for 'racedetector run' and '-junit'.
+func reportDirPath() (string, error) {
+	return os.MkdirTemp("", "racedetector-reports-")
+}
+
+// aggregateReportDir reads every <pid>.report/<pid>.races pair written
+// into dir and returns the total race count across all processes and the
+// deduplicated set of formatted race reports, in first-seen order.
+//
+// Deduplication is by exact formatted-report text: cheap, and effective
+// in practice since the same race (same addresses, same goroutine stacks)
+// tends to format identically run to run - unlike the detector's own
+// intra-process dedup key (see detector.generateDeduplicationKey), which
+// isn't available here since only the formatted text crosses the process
+// boundary.
+//
+// A missing or empty directory yields zero races - best effort, same as
+// racesDetectedInReportFile.
+func aggregateReportDir(dir string) (totalRaces int, uniqueRaces []string) {
+	if dir == "" {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasSuffix(name, ".report"):
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				totalRaces += n
+			}
+
+		case strings.HasSuffix(name, ".races"):
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil || len(data) == 0 {
+				continue
+			}
+			for _, report := range strings.Split(string(data), "\x00") {
+				if report == "" || seen[report] {
+					continue
+				}
+				seen[report] = true
+				uniqueRaces = append(uniqueRaces, report)
+			}
+		}
+	}
+
+	return totalRaces, uniqueRaces
+}
+
+// clearReportDir removes every entry from dir without removing dir itself,
+// so `racedetector test -stress` can reuse the same aggregation directory
+// across iterations while only attributing each iteration's summary to
+// that iteration's own runs.
+func clearReportDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		_ = os.Remove(filepath.Join(dir, entry.Name()))
+	}
+}
+
+// printReportSummary prints a one-line-per-race summary of an aggregated
+// `racedetector test` run to stderr, so races found across many packages'
+// test binaries are visible in one place instead of only as interleaved
+// per-binary "WARNING: DATA RACE" blocks on stderr.
+func printReportSummary(totalRaces int, uniqueRaces []string) {
+	if totalRaces == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nracedetector: %d race(s) detected across all packages (%d unique):\n",
+		totalRaces, len(uniqueRaces))
+	for i, report := range uniqueRaces {
+		fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, raceSummaryLine(report))
+	}
+}
+
+// raceSummaryLine extracts the "Current access" line from a formatted race
+// report (see detector.RaceReport.Format) for use in printReportSummary -
+// the line naming the address, access type, and goroutine, without the
+// full stack trace that follows it.
+func raceSummaryLine(report string) string {
+	lines := strings.Split(report, "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && line != "==================" && line != "WARNING: DATA RACE" {
+			return line
+		}
+	}
+	return "(unable to summarize)"
+}
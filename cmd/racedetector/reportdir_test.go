@@ -0,0 +1,121 @@
+// reportdir_test.go implements tests for the race-report aggregation
+// directory (synth-3601).
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReportDirPath verifies that the aggregation directory is created and
+// usable.
+func TestReportDirPath(t *testing.T) {
+	dir, err := reportDirPath()
+	if err != nil {
+		t.Fatalf("reportDirPath() error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("expected report directory to exist: %v", err)
+	}
+}
+
+// TestAggregateReportDir_EmptyPath verifies an empty path is treated as
+// "no races", matching racesDetectedInReportFile's empty-path behavior.
+func TestAggregateReportDir_EmptyPath(t *testing.T) {
+	total, races := aggregateReportDir("")
+	if total != 0 || races != nil {
+		t.Errorf("aggregateReportDir(\"\") = (%d, %v), want (0, nil)", total, races)
+	}
+}
+
+// TestAggregateReportDir_MissingDir verifies a nonexistent directory is
+// treated as "no races" (best effort, same as racesDetectedInReportFile).
+func TestAggregateReportDir_MissingDir(t *testing.T) {
+	total, races := aggregateReportDir(filepath.Join(t.TempDir(), "does-not-exist"))
+	if total != 0 || races != nil {
+		t.Errorf("aggregateReportDir(missing) = (%d, %v), want (0, nil)", total, races)
+	}
+}
+
+// TestAggregateReportDir_SumsAcrossProcesses verifies that per-process
+// <pid>.report files (one per package's test binary) are summed rather
+// than one clobbering another, the bug this aggregation directory
+// replaces the single shared RACEDETECTOR_REPORT_FILE for.
+func TestAggregateReportDir_SumsAcrossProcesses(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "111.report", "2")
+	write(t, dir, "222.report", "1")
+	write(t, dir, "333.report", "0")
+
+	total, races := aggregateReportDir(dir)
+	if total != 3 {
+		t.Errorf("total races = %d, want 3", total)
+	}
+	if races != nil {
+		t.Errorf("races = %v, want nil (no .races files written)", races)
+	}
+}
+
+// TestAggregateReportDir_DedupsIdenticalReports verifies the same race
+// reported by two processes (e.g. the same racy global touched by two
+// packages' tests) is only counted once in the deduplicated slice.
+func TestAggregateReportDir_DedupsIdenticalReports(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "111.races", "race A\x00race B")
+	write(t, dir, "222.races", "race B\x00race C")
+
+	_, races := aggregateReportDir(dir)
+	want := []string{"race A", "race B", "race C"}
+	if len(races) != len(want) {
+		t.Fatalf("races = %v, want %v", races, want)
+	}
+	for i := range want {
+		if races[i] != want[i] {
+			t.Errorf("races[%d] = %q, want %q", i, races[i], want[i])
+		}
+	}
+}
+
+// TestClearReportDir verifies that clearReportDir empties a directory
+// without removing it, so -stress can reuse it across iterations.
+func TestClearReportDir(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "111.report", "1")
+
+	clearReportDir(dir)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("directory should still exist: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected directory to be empty, got %v", entries)
+	}
+}
+
+// TestRaceSummaryLine verifies the "Current access" header line is
+// extracted from a formatted race report for printReportSummary.
+func TestRaceSummaryLine(t *testing.T) {
+	report := "==================\n" +
+		"WARNING: DATA RACE\n" +
+		"Write at 0x00c0000180a0 by goroutine 7:\n" +
+		"  main.writer()\n" +
+		"==================\n"
+
+	got := raceSummaryLine(report)
+	want := "Write at 0x00c0000180a0 by goroutine 7:"
+	if got != want {
+		t.Errorf("raceSummaryLine() = %q, want %q", got, want)
+	}
+}
+
+// write is a small test helper that writes content to name inside dir.
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 )
 
 // runCommand implements the 'racedetector run' command.
@@ -43,11 +47,60 @@ func runCommand(args []string) {
 	}
 	defer func() { _ = os.Remove(tempBinary) }() // Best effort cleanup
 
-	// Execute the binary with program arguments
-	exitCode := executeBinary(tempBinary, programArgs)
+	// Execute the binary with program arguments.
+	// raceReportFile gives the instrumented binary a side channel to report
+	// its race count back to us, so we can fail the run even if the program
+	// itself exited 0 (see writeReportFile in internal/race/api).
+	reportFile, err := raceReportFilePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not create race report file: %v\n", err)
+	}
+	if reportFile != "" {
+		defer func() { _ = os.Remove(reportFile) }()
+	}
+
+	exitCode := executeBinary(tempBinary, programArgs, reportFile)
+	if racesDetectedInReportFile(reportFile) {
+		fmt.Fprintf(os.Stderr, "racedetector: data races were detected, failing run\n")
+		exitCode = 1
+	}
 	os.Exit(exitCode)
 }
 
+// raceReportFilePath creates a temporary file path for the race-count side
+// channel (RACEDETECTOR_REPORT_FILE) used by internal/race/api.Fini().
+//
+// The file itself is created empty and immediately closed; the instrumented
+// binary overwrites it with the final race count on exit.
+func raceReportFilePath() (string, error) {
+	f, err := os.CreateTemp("", "racedetector-report-*.txt")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	_ = f.Close()
+	return path, nil
+}
+
+// racesDetectedInReportFile reads the race-count side channel file and
+// reports whether any races were recorded. Missing or unparsable files are
+// treated as "no races" (best effort - the file is only a supplement to the
+// program's own exit code and stderr report).
+func racesDetectedInReportFile(path string) bool {
+	if path == "" {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return false
+	}
+	return count > 0
+}
+
 // parseRunArgs separates source files from program arguments.
 //
 // The 'go run' command format is:
@@ -216,12 +269,24 @@ func validateRuntimeAvailable() error {
 
 // executeBinary runs the instrumented binary with given arguments.
 //
-// This forwards stdin/stdout/stderr to the child process and
-// returns the process exit code.
+// This forwards stdin/stdout/stderr to the child process unbuffered (they're
+// connected directly to our own, so the child's output appears as it's
+// written rather than after it exits) and returns the process exit code.
+// SIGINT and SIGTERM received while the child is running (synth-3591) are
+// forwarded to it instead of killing racedetector itself, so a long-running
+// instrumented server can be stopped the same way an uninstrumented one
+// would be: the child gets the signal, runs its own shutdown path, and this
+// function still reports its real exit code once it does.
+//
+// If reportFile is non-empty, it is passed to the child via the
+// RACEDETECTOR_REPORT_FILE environment variable so the instrumented
+// runtime can report its race count back to us (see raceReportFilePath);
+// runCommand appends the race summary after this returns, once the child
+// has actually exited.
 //
 // Returns:
 //   - Exit code of the process (0 = success)
-func executeBinary(binaryPath string, args []string) int {
+func executeBinary(binaryPath string, args []string, reportFile string) int {
 	// Create command
 	cmd := exec.Command(binaryPath, args...)
 
@@ -230,17 +295,39 @@ func executeBinary(binaryPath string, args []string) int {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	// Run and wait
-	if err := cmd.Run(); err != nil {
-		// Check if it's an exit error using errors.As (errorlint compliant)
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			return exitErr.ExitCode()
-		}
-		// Other error (failed to start, etc.)
+	if reportFile != "" {
+		cmd.Env = append(os.Environ(), "RACEDETECTOR_REPORT_FILE="+reportFile)
+	}
+
+	if err := cmd.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error executing binary: %v\n", err)
 		return 1
 	}
 
-	return 0
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	for {
+		select {
+		case sig := <-sigCh:
+			// Best effort - if the child already exited, Signal fails and
+			// the loop simply picks up its result on the next iteration.
+			_ = cmd.Process.Signal(sig)
+		case err := <-done:
+			if err == nil {
+				return 0
+			}
+			// Check if it's an exit error using errors.As (errorlint compliant)
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.ExitCode()
+			}
+			fmt.Fprintf(os.Stderr, "Error executing binary: %v\n", err)
+			return 1
+		}
+	}
 }
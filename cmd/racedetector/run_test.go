@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 // TestParseRunArgs_SimpleFile tests parsing a single source file.
@@ -230,7 +232,7 @@ func main() {
 	defer os.Remove(binaryPath)
 
 	// Execute binary
-	exitCode := executeBinary(binaryPath, []string{})
+	exitCode := executeBinary(binaryPath, []string{}, "")
 
 	if exitCode != 0 {
 		t.Errorf("Expected exit code 0, got %d", exitCode)
@@ -273,7 +275,7 @@ func main() {
 
 	// Execute with arguments
 	args := []string{"arg1", "arg2", "--flag=value"}
-	exitCode := executeBinary(binaryPath, args)
+	exitCode := executeBinary(binaryPath, args, "")
 
 	if exitCode != 0 {
 		t.Errorf("Expected exit code 0, got %d", exitCode)
@@ -314,13 +316,78 @@ func main() {
 	defer os.Remove(binaryPath)
 
 	// Execute binary
-	exitCode := executeBinary(binaryPath, []string{})
+	exitCode := executeBinary(binaryPath, []string{}, "")
 
 	if exitCode != 42 {
 		t.Errorf("Expected exit code 42, got %d", exitCode)
 	}
 }
 
+// TestExecuteBinary_ForwardsSIGTERM verifies executeBinary forwards a
+// SIGTERM it receives to the child process (synth-3591), rather than the
+// child being left running (or racedetector itself dying without ever
+// signaling it).
+func TestExecuteBinary_ForwardsSIGTERM(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "test-exec-sigterm-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Traps SIGTERM and exits 7 instead of the default signal-death
+	// behavior, so the test can tell "forwarded and handled" apart from
+	// "process just never received anything and timed out".
+	testSource := `package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGTERM)
+	<-c
+	os.Exit(7)
+}
+`
+	testFile := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(testFile, []byte(testSource), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	config := &buildConfig{
+		sourceFiles: []string{testFile},
+		workDir:     tempDir,
+	}
+
+	binaryPath, err := buildTemporary(config)
+	if err != nil {
+		t.Fatalf("buildTemporary() error: %v", err)
+	}
+	defer os.Remove(binaryPath)
+
+	done := make(chan int, 1)
+	go func() { done <- executeBinary(binaryPath, []string{}, "") }()
+
+	// Give the child a moment to install its signal handler before
+	// racedetector itself receives (and forwards) a SIGTERM.
+	time.Sleep(200 * time.Millisecond)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("failed to signal self: %v", err)
+	}
+
+	select {
+	case exitCode := <-done:
+		if exitCode != 7 {
+			t.Errorf("exitCode = %d, want 7 (child's SIGTERM handler exit code)", exitCode)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("executeBinary() did not return after SIGTERM - signal not forwarded?")
+	}
+}
+
 // BenchmarkParseRunArgs benchmarks argument parsing.
 func BenchmarkParseRunArgs(b *testing.B) {
 	args := []string{"main.go", "helper.go", "arg1", "arg2", "--flag=value"}
@@ -362,3 +429,60 @@ func main() { println("test") }
 		os.Remove(binaryPath)
 	}
 }
+
+// TestRaceReportFilePath verifies that the race report side-channel file is
+// created empty and is writable.
+func TestRaceReportFilePath(t *testing.T) {
+	path, err := raceReportFilePath()
+	if err != nil {
+		t.Fatalf("raceReportFilePath() error: %v", err)
+	}
+	defer os.Remove(path)
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected report file to exist: %v", err)
+	}
+}
+
+// TestRacesDetectedInReportFile verifies parsing of the race-count side
+// channel file written by internal/race/api.Fini().
+func TestRacesDetectedInReportFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		writeFn  func() string // returns path, empty string means "no file"
+		want     bool
+	}{
+		{name: "empty path", writeFn: func() string { return "" }, want: false},
+		{name: "missing file", writeFn: func() string { return filepath.Join(t.TempDir(), "missing.txt") }, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.writeFn()
+			if got := racesDetectedInReportFile(path); got != tt.want {
+				t.Errorf("racesDetectedInReportFile(%q) = %v, want %v", path, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("zero races", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "report.txt")
+		if err := os.WriteFile(path, []byte("0"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if racesDetectedInReportFile(path) {
+			t.Error("expected no races detected for count 0")
+		}
+	})
+
+	t.Run("races detected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "report.txt")
+		if err := os.WriteFile(path, []byte("3\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if !racesDetectedInReportFile(path) {
+			t.Error("expected races detected for count 3")
+		}
+	})
+}
@@ -8,6 +8,7 @@ package runtime
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -130,6 +131,14 @@ func findProjectRoot() (string, error) {
 	return "", fmt.Errorf("could not find racedetector project root")
 }
 
+// FindOriginalGoMod finds the go.mod file governing startDir, or "" if none
+// exists between startDir and the filesystem root (synth-3590). Exported so
+// build's -overlay path (see AugmentedGoMod) knows which real path an
+// augmented go.mod needs to be overlaid onto.
+func FindOriginalGoMod(startDir string) string {
+	return findOriginalGoMod(startDir)
+}
+
 // findOriginalGoMod finds the go.mod file of the project being instrumented.
 //
 // This walks up from the given directory looking for go.mod file.
@@ -159,25 +168,106 @@ func findOriginalGoMod(startDir string) string {
 	return ""
 }
 
-// BuildFlags returns additional flags needed for building instrumented code.
-//
-// These flags ensure the runtime library is linked correctly and
-// initialization code runs.
-//
-// Returns:
-//   - Slice of build flags to pass to 'go build'
-//
-// Example:
-//
-//	flags := BuildFlags()
-//	// flags = ["-tags=race", ...]
-func BuildFlags() []string {
-	// For now, no special flags needed
-	// In future, might add:
-	// - Custom build tags
-	// - Linker flags
-	// - Optimization flags
-	return []string{}
+// WorkspaceModuleDirs returns the absolute directories of every member
+// module listed in the go.work workspace governing startDir (synth-3625),
+// or nil if startDir isn't part of a workspace. Shells out to `go env
+// GOWORK` to find the workspace file - this respects both the GOWORK
+// environment variable and the toolchain's own upward-search auto-discovery
+// exactly as `go build` resolves it, rather than reimplementing that search.
+func WorkspaceModuleDirs(startDir string) ([]string, error) {
+	cmd := exec.Command("go", "env", "GOWORK")
+	cmd.Dir = startDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env GOWORK: %w", err)
+	}
+
+	goWorkPath := strings.TrimSpace(string(out))
+	if goWorkPath == "" || goWorkPath == "off" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(goWorkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", goWorkPath, err)
+	}
+	wf, err := modfile.ParseWork(goWorkPath, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", goWorkPath, err)
+	}
+
+	workDir := filepath.Dir(goWorkPath)
+	var dirs []string
+	for _, use := range wf.Use {
+		dir := use.Path
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workDir, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// BuildMetadata carries the racedetector CLI's own version/commit and the
+// instrumentation settings in effect for one build/run/test invocation, so
+// BuildFlags can embed them into the instrumented binary (synth-3624). A
+// zero-value BuildMetadata (ToolVersion == "") means "don't embed anything" -
+// used by callers that haven't been taught the CLI's version yet.
+type BuildMetadata struct {
+	// ToolVersion is the racedetector CLI's own version (main.version).
+	ToolVersion string
+
+	// ToolCommit is the racedetector CLI's own commit (main.commit).
+	ToolCommit string
+
+	// Scope describes which package pattern(s) this invocation instrumented,
+	// e.g. "main.go helper.go" or "./...".
+	Scope string
+
+	// Coalescing indicates whether BigFoot barrier coalescing (see
+	// instrument.ApplyCoalescing) was applied. Always false today - no
+	// build/run/test command exposes a flag to enable it yet - but the field
+	// exists so the report format doesn't need to change once one does.
+	Coalescing bool
+}
+
+// buildInfoPackage is the import path of the package whose package-level
+// vars BuildFlags' -ldflags -X assignments target. Fini's report reads them
+// back at runtime to print the build-info line (synth-3624).
+const buildInfoPackage = "github.com/kolkov/racedetector/internal/race/api"
+
+// BuildFlags returns flags with meta's build metadata folded into an
+// "-ldflags -X ..." assignment and merged into flags.
+//
+// Merging matters: `go build`/`go test` only honor the last occurrence of a
+// repeated flag, so if flags already has a user-supplied -ldflags (e.g. from
+// `racedetector build -ldflags="-s -w"`), naively appending a second one
+// would silently discard whichever came first. BuildFlags instead finds and
+// extends the existing -ldflags value, or adds one if flags has none.
+//
+// meta.ToolVersion == "" means the caller has no metadata to embed (e.g. it
+// hasn't been wired to the CLI's version/commit), in which case flags is
+// returned unchanged.
+func BuildFlags(flags []string, meta BuildMetadata) []string {
+	if meta.ToolVersion == "" {
+		return flags
+	}
+
+	inject := fmt.Sprintf(
+		"-X %[1]s.buildToolVersion=%[2]s -X %[1]s.buildToolCommit=%[3]s -X %[1]s.buildScope=%[4]s -X %[1]s.buildCoalescing=%[5]t",
+		buildInfoPackage, meta.ToolVersion, meta.ToolCommit, meta.Scope, meta.Coalescing)
+
+	for i, f := range flags {
+		if f == "-ldflags" && i+1 < len(flags) {
+			flags[i+1] = flags[i+1] + " " + inject
+			return flags
+		}
+		if strings.HasPrefix(f, "-ldflags=") {
+			flags[i] = f + " " + inject
+			return flags
+		}
+	}
+	return append(flags, "-ldflags", inject)
 }
 
 // ModFileOverlay creates a temporary go.mod overlay for instrumented code.
@@ -247,6 +337,130 @@ func ModFileOverlay(tempDir, sourceDir string) (string, error) {
 	return overlayPath, nil
 }
 
+// AugmentedGoMod returns go.mod content that adds a dependency on the race
+// detector runtime on top of whatever's already at originalGoModPath,
+// preserving the real module's identity and existing requires/replaces
+// (synth-3590) - unlike ModFileOverlay, which declares a throwaway
+// "instrumented" module for the mirrored-tree build path. This is meant to
+// be fed to `go build -overlay` mapped onto originalGoModPath itself, so
+// building runs against the real source tree in place instead of a copy;
+// renaming the module out from under that tree (as ModFileOverlay's content
+// does) would break every import statement still pointing at the real name.
+//
+// originalGoModPath may not exist on disk (a standalone file/directory with
+// no go.mod of its own, per FindOriginalGoMod) - a minimal one is
+// synthesized instead of read in that case, exactly as ModFileOverlay does
+// for the same situation.
+func AugmentedGoMod(originalGoModPath string) ([]byte, error) {
+	var mf *modfile.File
+
+	if data, err := os.ReadFile(originalGoModPath); err == nil {
+		mf, err = modfile.Parse(originalGoModPath, data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", originalGoModPath, err)
+		}
+
+		// Absolutize the original go.mod's own local replace directives
+		// (synth-3625): this content gets written out both as -overlay
+		// content (where a relative path would still resolve correctly,
+		// since the overlay preserves originalGoModPath's real location)
+		// and, via GoSumForOverlay below, into an unrelated scratch
+		// directory (where it would not). A workspace's member modules
+		// commonly replace each other with paths like "../lib", so leaving
+		// them relative breaks exactly the multi-module case this function
+		// exists to support.
+		if err := absolutizeLocalReplaces(mf, filepath.Dir(originalGoModPath)); err != nil {
+			return nil, fmt.Errorf("failed to absolutize replace directives in %s: %w", originalGoModPath, err)
+		}
+	} else {
+		mf = &modfile.File{}
+		if err := mf.AddModuleStmt("instrumented"); err != nil {
+			return nil, fmt.Errorf("failed to create go.mod: %w", err)
+		}
+		// Go's own normalized form (e.g. "1.24.0", not "1.24") - go build
+		// silently rewrites a bare minor version to this on disk, which it
+		// can't do when go.mod is an overlay path instead of a real file,
+		// and fails the build rather than building against unnormalized
+		// input.
+		if err := mf.AddGoStmt("1.24.0"); err != nil {
+			return nil, fmt.Errorf("failed to set go directive: %w", err)
+		}
+	}
+
+	// Building racedetector's own source (e.g. its dogfooding examples)
+	// needs no added require/replace at all - the module already is the
+	// runtime, and requiring itself would create a self-reference cycle
+	// the go tool rejects. Same check ModFileOverlay makes for the
+	// mirrored-tree path.
+	if mf.Module != nil && mf.Module.Mod.Path == "github.com/kolkov/racedetector" {
+		mf.Cleanup()
+		return mf.Format()
+	}
+
+	projectRoot, err := findProjectRoot()
+	if err == nil {
+		// Development mode - point at this checkout instead of a published
+		// version, same rationale as ModFileOverlay.
+		if err := mf.AddRequire("github.com/kolkov/racedetector", "v0.0.0"); err != nil {
+			return nil, fmt.Errorf("failed to add require: %w", err)
+		}
+		if err := mf.AddReplace("github.com/kolkov/racedetector", "", projectRoot, ""); err != nil {
+			return nil, fmt.Errorf("failed to add replace: %w", err)
+		}
+	} else {
+		if err := mf.AddRequire("github.com/kolkov/racedetector", Version); err != nil {
+			return nil, fmt.Errorf("failed to add require: %w", err)
+		}
+	}
+
+	mf.Cleanup()
+	return mf.Format()
+}
+
+// GoSumForOverlay computes the go.sum content goModContent needs, without
+// ever touching the real project's go.mod/go.sum (synth-3590): goModContent
+// is written into an isolated scratch directory of its own, and `go mod
+// download` runs there to fetch checksums, so any go.sum entries it decides
+// to add land in that throwaway module instead of a real one.
+//
+// A locally-replaced require (development mode - see AugmentedGoMod) needs
+// no checksums at all, so this returns (nil, nil) in that case; a published
+// version require does, and this fetches them over the network exactly as
+// `go mod tidy` would.
+func GoSumForOverlay(goModContent []byte) ([]byte, error) {
+	scratchDir, err := os.MkdirTemp("", "racedetector-gosum-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch dir: %w", err)
+	}
+	defer os.RemoveAll(scratchDir)
+
+	scratchGoMod := filepath.Join(scratchDir, "go.mod")
+	if err := os.WriteFile(scratchGoMod, goModContent, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write scratch go.mod: %w", err)
+	}
+
+	// "all" (rather than the bare form) also fetches full content hashes
+	// for modules whose packages actually get built, not just their
+	// go.mod hashes - `go build` needs those too, and can't fetch them
+	// itself once go.sum is part of the overlay.
+	cmd := exec.Command("go", "mod", "download", "all")
+	cmd.Dir = scratchDir
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to download modules for go.sum: %w", err)
+	}
+
+	scratchGoSum := filepath.Join(scratchDir, "go.sum")
+	content, err := os.ReadFile(scratchGoSum)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// No checksums needed - e.g. every require is a local replace.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read scratch go.sum: %w", err)
+	}
+	return content, nil
+}
+
 // getModuleName reads a go.mod file and returns the module name.
 //
 // Parameters:
@@ -338,6 +552,39 @@ func extractReplaceDirectives(goModPath string) string {
 	return result.String()
 }
 
+// absolutizeLocalReplaces rewrites mf's local relative replace directives
+// (e.g. "replace example.com/lib => ../lib") to absolute paths anchored at
+// baseDir, in place (synth-3625).
+//
+// This mirrors extractReplaceDirectives' relative-to-absolute conversion,
+// but mutates a *modfile.File directly instead of building go.mod text,
+// since AugmentedGoMod's result is reused for two purposes with two
+// different working directories: written as -overlay content (where a
+// relative path resolves against the original go.mod's real location, so
+// would look fine on its own) and fed to GoSumForOverlay, which copies it
+// into an unrelated scratch directory to run `go mod download` - where the
+// same relative path resolves against the wrong directory entirely and the
+// download fails outright, even for modules that need no new checksums at
+// all.
+func absolutizeLocalReplaces(mf *modfile.File, baseDir string) error {
+	for _, rep := range mf.Replace {
+		if rep.New.Version != "" || !isLocalPath(rep.New.Path) || filepath.IsAbs(rep.New.Path) {
+			continue
+		}
+
+		absPath, err := filepath.Abs(filepath.Join(baseDir, rep.New.Path))
+		if err != nil {
+			return fmt.Errorf("failed to resolve replace path %q: %w", rep.New.Path, err)
+		}
+
+		if err := mf.AddReplace(rep.Old.Path, rep.Old.Version, absPath, ""); err != nil {
+			return fmt.Errorf("failed to rewrite replace directive for %s: %w", rep.Old.Path, err)
+		}
+	}
+
+	return nil
+}
+
 // isLocalPath checks if a path is a local filesystem path (not a module path).
 //
 // Local paths start with ./, ../, /, or a drive letter on Windows.
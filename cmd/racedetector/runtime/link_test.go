@@ -88,19 +88,52 @@ func TestFindProjectRoot(t *testing.T) {
 	t.Logf("Project root found: %s (hasGoMod=%v, hasRuntime=%v)", root, hasGoMod, hasRuntime)
 }
 
-// TestBuildFlags verifies build flags are returned correctly.
+// TestBuildFlags verifies that an empty BuildMetadata (no ToolVersion) is a
+// no-op, leaving the caller's flags untouched.
 func TestBuildFlags(t *testing.T) {
-	flags := BuildFlags()
+	flags := BuildFlags([]string{"-v"}, BuildMetadata{})
 
-	// Should return a slice (even if empty for MVP)
-	if flags == nil {
-		t.Errorf("BuildFlags() returned nil, want empty slice")
+	if len(flags) != 1 || flags[0] != "-v" {
+		t.Errorf("BuildFlags with empty metadata = %v, want unchanged [-v]", flags)
 	}
+}
 
-	// For MVP, we expect empty flags
-	// Future versions might add custom build tags or linker flags
-	if len(flags) > 0 {
-		t.Logf("BuildFlags() returned: %v", flags)
+// TestBuildFlags_InjectsMetadata verifies BuildFlags adds an -ldflags -X
+// assignment for each BuildMetadata field when no -ldflags already exists.
+func TestBuildFlags_InjectsMetadata(t *testing.T) {
+	flags := BuildFlags([]string{"-o", "app"}, BuildMetadata{
+		ToolVersion: "v1.2.3",
+		ToolCommit:  "abc123",
+		Scope:       "./...",
+	})
+
+	if len(flags) != 4 || flags[0] != "-o" || flags[1] != "app" || flags[2] != "-ldflags" {
+		t.Fatalf("BuildFlags = %v, want [-o app -ldflags ...]", flags)
+	}
+	ldflags := flags[3]
+	for _, want := range []string{
+		"buildToolVersion=v1.2.3",
+		"buildToolCommit=abc123",
+		"buildScope=./...",
+		"buildCoalescing=false",
+	} {
+		if !strings.Contains(ldflags, want) {
+			t.Errorf("ldflags %q missing %q", ldflags, want)
+		}
+	}
+}
+
+// TestBuildFlags_MergesExistingLdflags verifies BuildFlags extends a
+// user-supplied -ldflags instead of appending a second one, since `go
+// build` only honors the last occurrence of a repeated flag.
+func TestBuildFlags_MergesExistingLdflags(t *testing.T) {
+	flags := BuildFlags([]string{"-ldflags", "-s -w"}, BuildMetadata{ToolVersion: "dev"})
+
+	if len(flags) != 2 || flags[0] != "-ldflags" {
+		t.Fatalf("BuildFlags = %v, want a single merged -ldflags", flags)
+	}
+	if !strings.Contains(flags[1], "-s -w") || !strings.Contains(flags[1], "buildToolVersion=dev") {
+		t.Errorf("merged ldflags %q missing user or injected content", flags[1])
 	}
 }
 
@@ -254,6 +287,225 @@ replace example.com/other => %s
 	}
 }
 
+// TestFindOriginalGoMod verifies the exported wrapper walks up from startDir
+// the same way the unexported implementation does.
+func TestFindOriginalGoMod(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "racedetector-findgomod-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	subDir := filepath.Join(tempDir, "a", "b")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+
+	goModPath := filepath.Join(tempDir, "go.mod")
+	if err := os.WriteFile(goModPath, []byte("module example.com/test\n\ngo 1.24.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	if got := FindOriginalGoMod(subDir); got != goModPath {
+		t.Errorf("FindOriginalGoMod(%q) = %q, want %q", subDir, got, goModPath)
+	}
+}
+
+// TestWorkspaceModuleDirs_NoWorkspace verifies a directory with no go.work
+// (and no GOWORK override) reports no workspace at all, rather than
+// mistakenly treating an ordinary module as a one-member workspace.
+func TestWorkspaceModuleDirs_NoWorkspace(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "racedetector-noworkspace-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte("module example.com/solo\n\ngo 1.24.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	t.Setenv("GOWORK", "off")
+	dirs, err := WorkspaceModuleDirs(tempDir)
+	if err != nil {
+		t.Fatalf("WorkspaceModuleDirs() failed: %v", err)
+	}
+	if dirs != nil {
+		t.Errorf("WorkspaceModuleDirs() = %v, want nil", dirs)
+	}
+}
+
+// TestWorkspaceModuleDirs_ResolvesMembers verifies every "use" directive in
+// a real go.work file resolves to its member module's absolute directory
+// (synth-3625).
+func TestWorkspaceModuleDirs_ResolvesMembers(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "racedetector-workspace-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	appDir := filepath.Join(tempDir, "app")
+	libDir := filepath.Join(tempDir, "lib")
+	for _, dir := range []string{appDir, libDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(appDir, "go.mod"), []byte("module example.com/app\n\ngo 1.24.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write app go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(libDir, "go.mod"), []byte("module example.com/lib\n\ngo 1.24.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lib go.mod: %v", err)
+	}
+	goWork := "go 1.24.0\n\nuse ./app\nuse ./lib\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "go.work"), []byte(goWork), 0644); err != nil {
+		t.Fatalf("Failed to write go.work: %v", err)
+	}
+
+	dirs, err := WorkspaceModuleDirs(appDir)
+	if err != nil {
+		t.Fatalf("WorkspaceModuleDirs() failed: %v", err)
+	}
+
+	want := map[string]bool{appDir: false, libDir: false}
+	for _, dir := range dirs {
+		if _, ok := want[dir]; !ok {
+			t.Errorf("WorkspaceModuleDirs() returned unexpected dir %q", dir)
+			continue
+		}
+		want[dir] = true
+	}
+	for dir, found := range want {
+		if !found {
+			t.Errorf("WorkspaceModuleDirs() missing expected member %q, got %v", dir, dirs)
+		}
+	}
+}
+
+// TestAugmentedGoMod_NoExistingGoMod verifies a fresh synthetic go.mod is
+// produced when the target path doesn't exist on disk, for building a
+// directory that has no go.mod of its own (synth-3590).
+func TestAugmentedGoMod_NoExistingGoMod(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "racedetector-augmod-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	content, err := AugmentedGoMod(filepath.Join(tempDir, "go.mod"))
+	if err != nil {
+		t.Fatalf("AugmentedGoMod() failed: %v", err)
+	}
+
+	contentStr := string(content)
+	t.Logf("Generated go.mod:\n%s", contentStr)
+
+	if !strings.Contains(contentStr, "module instrumented") {
+		t.Errorf("AugmentedGoMod() missing 'module instrumented' declaration")
+	}
+	if !strings.Contains(contentStr, "require github.com/kolkov/racedetector") {
+		t.Errorf("AugmentedGoMod() missing require directive")
+	}
+	// The go directive must be fully normalized (e.g. "1.24.0", not
+	// "1.24") - go build can't rewrite it into that form itself when the
+	// file is an -overlay path rather than a real one on disk.
+	if !strings.Contains(contentStr, "go 1.24.0") {
+		t.Errorf("AugmentedGoMod() missing normalized go version directive")
+	}
+}
+
+// TestAugmentedGoMod_PreservesExistingGoMod verifies the original module's
+// identity and requires survive, with only the racedetector runtime
+// require/replace layered on top (synth-3590) - unlike ModFileOverlay,
+// which discards the original module declaration in favor of a throwaway
+// "module instrumented".
+func TestAugmentedGoMod_PreservesExistingGoMod(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "racedetector-augmod-existing-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	goModPath := filepath.Join(tempDir, "go.mod")
+	goModContent := `module example.com/myapp
+
+go 1.24.0
+
+require example.com/lib v1.0.0
+`
+	if err := os.WriteFile(goModPath, []byte(goModContent), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	content, err := AugmentedGoMod(goModPath)
+	if err != nil {
+		t.Fatalf("AugmentedGoMod() failed: %v", err)
+	}
+
+	contentStr := string(content)
+	t.Logf("Generated go.mod:\n%s", contentStr)
+
+	if !strings.Contains(contentStr, "module example.com/myapp") {
+		t.Errorf("AugmentedGoMod() lost the original module declaration")
+	}
+	if !strings.Contains(contentStr, "example.com/lib v1.0.0") {
+		t.Errorf("AugmentedGoMod() lost an existing require directive")
+	}
+	if !strings.Contains(contentStr, "github.com/kolkov/racedetector v0.0.0") {
+		t.Errorf("AugmentedGoMod() missing the racedetector runtime require")
+	}
+}
+
+// TestAugmentedGoMod_AbsolutizesLocalReplace verifies a local relative
+// replace directive in the original go.mod (e.g. "replace example.com/lib
+// => ../lib", the common shape in a go.work workspace's member modules) is
+// rewritten to an absolute path (synth-3625). GoSumForOverlay writes this
+// same content into an unrelated scratch directory to compute checksums,
+// where a relative replace path would resolve against the wrong directory
+// and fail the whole build.
+func TestAugmentedGoMod_AbsolutizesLocalReplace(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "racedetector-augmod-replace-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	appDir := filepath.Join(tempDir, "app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create app dir: %v", err)
+	}
+
+	goModPath := filepath.Join(appDir, "go.mod")
+	goModContent := `module example.com/app
+
+go 1.24.0
+
+require example.com/lib v0.0.0
+
+replace example.com/lib => ../lib
+`
+	if err := os.WriteFile(goModPath, []byte(goModContent), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	content, err := AugmentedGoMod(goModPath)
+	if err != nil {
+		t.Fatalf("AugmentedGoMod() failed: %v", err)
+	}
+
+	contentStr := string(content)
+	t.Logf("Generated go.mod:\n%s", contentStr)
+
+	wantPath := filepath.Join(tempDir, "lib")
+	if strings.Contains(contentStr, "=> ../lib") {
+		t.Errorf("AugmentedGoMod() left the replace directive relative: %s", contentStr)
+	}
+	if !strings.Contains(contentStr, wantPath) {
+		t.Errorf("AugmentedGoMod() replace directive missing absolutized path %q:\n%s", wantPath, contentStr)
+	}
+}
+
 // TestInjectInitCalls verifies initialization code injection.
 func TestInjectInitCalls(t *testing.T) {
 	tests := []struct {
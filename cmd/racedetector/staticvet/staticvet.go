@@ -0,0 +1,325 @@
+// Package staticvet implements a lightweight static pre-screen for likely
+// data races, used by the `racedetector vet` subcommand.
+//
+// Unlike the dynamic FastTrack detector in internal/race, staticvet never
+// runs the program - it only inspects the AST looking for patterns that are
+// common sources of races in Go code:
+//
+//  1. Loop variables captured by a goroutine closure instead of being
+//     passed in as an argument.
+//  2. Closures passed to `go` statements that read or write variables from
+//     an enclosing scope with no synchronization primitive anywhere in the
+//     closure body.
+//  3. Writes to exported struct fields from inside a goroutine closure with
+//     no synchronization primitive anywhere in the closure body.
+//
+// These checks are intentionally conservative heuristics, not a sound
+// points-to analysis: they report "likely" race sites to help users decide
+// which packages are worth running under the full dynamic detector, and
+// will have both false positives and false negatives.
+//
+// Thread Safety: AnalyzeFile is not thread-safe - it is not meant to be
+// called concurrently for the same file, matching the instrument package's
+// conventions.
+package staticvet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// FindingKind identifies which heuristic produced a Finding.
+type FindingKind string
+
+const (
+	// KindLoopVarCapture flags a goroutine closure referencing its
+	// enclosing loop's index/key/value variable directly, instead of
+	// taking it as a parameter.
+	KindLoopVarCapture FindingKind = "loop-var-capture"
+
+	// KindUnsyncedCapture flags a goroutine closure reading or writing an
+	// enclosing-scope variable with no synchronization primitive anywhere
+	// in the closure body.
+	KindUnsyncedCapture FindingKind = "unsynced-capture"
+
+	// KindUnguardedField flags a write to an exported struct field from
+	// inside a goroutine closure with no synchronization primitive
+	// anywhere in the closure body.
+	KindUnguardedField FindingKind = "unguarded-field"
+)
+
+// Finding describes one likely race site.
+type Finding struct {
+	File    string      // Source file path
+	Line    int         // Line number (1-indexed)
+	Column  int         // Column number (1-indexed)
+	Kind    FindingKind // Which heuristic produced this finding
+	Message string      // Human-readable description
+}
+
+// String formats a Finding as "file:line:column: message", matching
+// instrument.InstrumentationError's format so both tools read consistently
+// on a terminal.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", f.File, f.Line, f.Column, f.Message)
+}
+
+// AnalyzeFile runs every static heuristic against a single Go source file.
+//
+// Parameters:
+//   - filename: Path to the Go source file (used for error messages and
+//     reported in each Finding)
+//   - src: Source code to analyze. Can be nil (read from filename), or any
+//     type accepted by go/parser.ParseFile.
+//
+// Returns:
+//   - []Finding: Likely race sites, in source order. Empty (not nil) if
+//     none are found.
+//   - error: Parse error, or nil on success.
+func AnalyzeFile(filename string, src interface{}) ([]Finding, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file %s: %w", filename, err)
+	}
+
+	a := &analyzer{fset: fset, filename: filename, findings: make([]Finding, 0)}
+	a.walk(file, nil)
+	return a.findings, nil
+}
+
+// analyzer walks a file's AST once, threading the set of names bound by
+// enclosing for/range loops so that goroutine closures can be checked
+// against the loops they are nested in.
+type analyzer struct {
+	fset     *token.FileSet
+	filename string
+	findings []Finding
+}
+
+// walk visits node and its children, extending loopVars whenever it
+// descends into a for/range statement's body.
+func (a *analyzer) walk(node ast.Node, loopVars []string) {
+	if node == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *ast.ForStmt:
+		vars := loopVars
+		if assign, ok := n.Init.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+			vars = append(append([]string{}, loopVars...), identNames(assign.Lhs)...)
+		}
+		a.walk(n.Body, vars)
+		return
+
+	case *ast.RangeStmt:
+		vars := loopVars
+		if n.Tok == token.DEFINE {
+			vars = append(append([]string{}, loopVars...), identNames([]ast.Expr{n.Key, n.Value})...)
+		}
+		a.walk(n.Body, vars)
+		return
+
+	case *ast.GoStmt:
+		if lit, ok := n.Call.Fun.(*ast.FuncLit); ok {
+			a.checkGoStmt(n, lit, loopVars)
+			// Still descend, in case this closure contains its own
+			// nested `go` statements or loops. loopVars carries through
+			// since a loop variable from an outer scope remains capturable
+			// by a goroutine nested two closures deep.
+			a.walk(lit.Body, loopVars)
+		}
+		return
+	}
+
+	// Generic descent for every other node kind, preserving loopVars.
+	ast.Inspect(node, func(child ast.Node) bool {
+		if child == node {
+			return true
+		}
+		switch child.(type) {
+		case *ast.ForStmt, *ast.RangeStmt, *ast.GoStmt:
+			a.walk(child, loopVars)
+			return false
+		}
+		return true
+	})
+}
+
+// identNames extracts the identifier names from a list of expressions,
+// skipping the blank identifier and any non-identifier expression.
+func identNames(exprs []ast.Expr) []string {
+	var names []string
+	for _, e := range exprs {
+		if ident, ok := e.(*ast.Ident); ok && ident.Name != "_" {
+			names = append(names, ident.Name)
+		}
+	}
+	return names
+}
+
+// checkGoStmt runs every heuristic against a single `go func(){...}()`
+// statement, appending any findings to a.findings.
+func (a *analyzer) checkGoStmt(goStmt *ast.GoStmt, lit *ast.FuncLit, loopVars []string) {
+	params := funcLitParamNames(lit)
+	free := collectFreeIdents(lit.Body, params)
+	hasSync := bodyHasSyncPrimitive(lit.Body)
+
+	for _, v := range loopVars {
+		if free[v] {
+			a.addFinding(goStmt.Pos(), KindLoopVarCapture,
+				fmt.Sprintf("goroutine closure captures loop variable %q instead of taking it as a parameter", v))
+		}
+	}
+
+	if !hasSync {
+		for name := range free {
+			a.addFinding(goStmt.Pos(), KindUnsyncedCapture,
+				fmt.Sprintf("goroutine closure captures %q from the enclosing scope with no synchronization in its body", name))
+		}
+
+		for _, sel := range exportedFieldWrites(lit.Body) {
+			a.addFinding(sel.Pos(), KindUnguardedField,
+				fmt.Sprintf("write to exported field %q inside goroutine closure with no synchronization in its body", sel.Sel.Name))
+		}
+	}
+}
+
+func (a *analyzer) addFinding(pos token.Pos, kind FindingKind, message string) {
+	p := a.fset.Position(pos)
+	a.findings = append(a.findings, Finding{
+		File:    a.filename,
+		Line:    p.Line,
+		Column:  p.Column,
+		Kind:    kind,
+		Message: message,
+	})
+}
+
+// funcLitParamNames returns the names of a function literal's parameters,
+// used to distinguish identifiers the closure receives explicitly from
+// ones it captures implicitly from the enclosing scope.
+func funcLitParamNames(lit *ast.FuncLit) map[string]bool {
+	names := make(map[string]bool)
+	if lit.Type.Params == nil {
+		return names
+	}
+	for _, field := range lit.Type.Params.List {
+		for _, name := range field.Names {
+			names[name.Name] = true
+		}
+	}
+	return names
+}
+
+// collectFreeIdents returns the set of identifier names referenced in body
+// that are neither parameters nor declared within body itself (by := or
+// var). This is a syntactic approximation of free variables, not a scope-
+// resolved one: it does not distinguish shadowing at different nesting
+// depths within body, which only makes the heuristic more conservative
+// (it may treat a shadowed local as "free" and report a false positive,
+// but never misses a genuine capture).
+func collectFreeIdents(body *ast.BlockStmt, exclude map[string]bool) map[string]bool {
+	declared := map[string]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE {
+				for _, name := range identNames(node.Lhs) {
+					declared[name] = true
+				}
+			}
+		case *ast.ValueSpec:
+			for _, ident := range node.Names {
+				declared[ident.Name] = true
+			}
+		case *ast.FuncLit:
+			// Parameters of a nested closure are locals of that closure,
+			// not free identifiers of the outer one.
+			for name := range funcLitParamNames(node) {
+				declared[name] = true
+			}
+		}
+		return true
+	})
+
+	free := map[string]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		sel, isSelector := n.(*ast.SelectorExpr)
+		if isSelector {
+			// Only the base of a selector (x in x.Field) can be a free
+			// identifier; the field name itself never is.
+			n = sel.X
+		}
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return !isSelector
+		}
+		if !exclude[ident.Name] && !declared[ident.Name] && ident.Name != "_" {
+			free[ident.Name] = true
+		}
+		return true
+	})
+
+	return free
+}
+
+// bodyHasSyncPrimitive reports whether a closure body contains anything
+// that looks like synchronization: a Lock/Unlock/RLock/RUnlock/Wait/Done/Add
+// method call, or a channel send/receive.
+//
+// This is deliberately coarse - it does not verify the call target is
+// actually a sync.Mutex, only that the shape matches - so as not to miss
+// synchronization behind a helper type or interface.
+func bodyHasSyncPrimitive(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch node := n.(type) {
+		case *ast.SendStmt:
+			found = true
+		case *ast.UnaryExpr:
+			if node.Op == token.ARROW {
+				found = true
+			}
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok {
+				switch sel.Sel.Name {
+				case "Lock", "Unlock", "RLock", "RUnlock", "Wait", "Done", "Add":
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// exportedFieldWrites returns every selector expression on the left side of
+// an assignment within body whose field name is exported (e.g. s.Count =
+// ... but not s.count = ...).
+func exportedFieldWrites(body *ast.BlockStmt) []*ast.SelectorExpr {
+	var writes []*ast.SelectorExpr
+	ast.Inspect(body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok {
+			return true
+		}
+		for _, lhs := range assign.Lhs {
+			sel, ok := lhs.(*ast.SelectorExpr)
+			if !ok {
+				continue
+			}
+			if ast.IsExported(sel.Sel.Name) {
+				writes = append(writes, sel)
+			}
+		}
+		return true
+	})
+	return writes
+}
@@ -0,0 +1,207 @@
+// Package staticvet - tests for the static race pre-screen heuristics.
+package staticvet
+
+import (
+	"testing"
+)
+
+// findingKinds extracts the Kind of each finding, for easy comparison in
+// tests that only care which heuristics fired.
+func findingKinds(findings []Finding) []FindingKind {
+	kinds := make([]FindingKind, len(findings))
+	for i, f := range findings {
+		kinds[i] = f.Kind
+	}
+	return kinds
+}
+
+func containsKind(kinds []FindingKind, want FindingKind) bool {
+	for _, k := range kinds {
+		if k == want {
+			return true
+		}
+	}
+	return false
+}
+
+// TestAnalyzeFile_LoopVarCapture tests detection of a goroutine closure
+// capturing its enclosing loop variable directly.
+func TestAnalyzeFile_LoopVarCapture(t *testing.T) {
+	src := `package main
+
+func main() {
+	for i := 0; i < 10; i++ {
+		go func() {
+			println(i)
+		}()
+	}
+}
+`
+	findings, err := AnalyzeFile("test.go", src)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if !containsKind(findingKinds(findings), KindLoopVarCapture) {
+		t.Errorf("expected KindLoopVarCapture, got %v", findingKinds(findings))
+	}
+}
+
+// TestAnalyzeFile_LoopVarCapture_Range tests detection over a range loop.
+func TestAnalyzeFile_LoopVarCapture_Range(t *testing.T) {
+	src := `package main
+
+func main() {
+	items := []int{1, 2, 3}
+	for _, item := range items {
+		go func() {
+			println(item)
+		}()
+	}
+}
+`
+	findings, err := AnalyzeFile("test.go", src)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if !containsKind(findingKinds(findings), KindLoopVarCapture) {
+		t.Errorf("expected KindLoopVarCapture, got %v", findingKinds(findings))
+	}
+}
+
+// TestAnalyzeFile_LoopVarPassedAsArgument tests that a loop variable passed
+// in as an explicit parameter is NOT flagged as a capture.
+func TestAnalyzeFile_LoopVarPassedAsArgument(t *testing.T) {
+	src := `package main
+
+func main() {
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			println(i)
+		}(i)
+	}
+}
+`
+	findings, err := AnalyzeFile("test.go", src)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if containsKind(findingKinds(findings), KindLoopVarCapture) {
+		t.Errorf("did not expect KindLoopVarCapture, got %v", findingKinds(findings))
+	}
+}
+
+// TestAnalyzeFile_UnsyncedCapture tests detection of a goroutine closure
+// capturing an enclosing variable with no synchronization in its body.
+func TestAnalyzeFile_UnsyncedCapture(t *testing.T) {
+	src := `package main
+
+func main() {
+	counter := 0
+	go func() {
+		counter++
+	}()
+	println(counter)
+}
+`
+	findings, err := AnalyzeFile("test.go", src)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if !containsKind(findingKinds(findings), KindUnsyncedCapture) {
+		t.Errorf("expected KindUnsyncedCapture, got %v", findingKinds(findings))
+	}
+}
+
+// TestAnalyzeFile_MutexGuardedCapture tests that a capture guarded by a
+// Lock/Unlock pair in the same closure is NOT flagged.
+func TestAnalyzeFile_MutexGuardedCapture(t *testing.T) {
+	src := `package main
+
+import "sync"
+
+func main() {
+	var mu sync.Mutex
+	counter := 0
+	go func() {
+		mu.Lock()
+		counter++
+		mu.Unlock()
+	}()
+	println(counter)
+}
+`
+	findings, err := AnalyzeFile("test.go", src)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if containsKind(findingKinds(findings), KindUnsyncedCapture) {
+		t.Errorf("did not expect KindUnsyncedCapture, got %v", findingKinds(findings))
+	}
+}
+
+// TestAnalyzeFile_UnguardedField tests detection of a write to an exported
+// struct field from inside a goroutine closure with no synchronization.
+func TestAnalyzeFile_UnguardedField(t *testing.T) {
+	src := `package main
+
+type Counter struct {
+	Value int
+}
+
+func main() {
+	c := &Counter{}
+	go func() {
+		c.Value = 42
+	}()
+}
+`
+	findings, err := AnalyzeFile("test.go", src)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if !containsKind(findingKinds(findings), KindUnguardedField) {
+		t.Errorf("expected KindUnguardedField, got %v", findingKinds(findings))
+	}
+}
+
+// TestAnalyzeFile_NoFindings tests that goroutines launched with ordinary
+// named functions (no closure) are not flagged.
+func TestAnalyzeFile_NoFindings(t *testing.T) {
+	src := `package main
+
+func worker(n int) {
+	println(n)
+}
+
+func main() {
+	for i := 0; i < 10; i++ {
+		go worker(i)
+	}
+}
+`
+	findings, err := AnalyzeFile("test.go", src)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+}
+
+// TestFinding_String tests the Finding.String format.
+func TestFinding_String(t *testing.T) {
+	f := Finding{File: "main.go", Line: 10, Column: 2, Kind: KindUnsyncedCapture, Message: "example"}
+	want := "main.go:10:2: example"
+	if got := f.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// TestAnalyzeFile_ParseError tests that a syntax error is surfaced as an
+// error, not a panic.
+func TestAnalyzeFile_ParseError(t *testing.T) {
+	_, err := AnalyzeFile("bad.go", "not valid go source {{{")
+	if err == nil {
+		t.Error("expected parse error, got nil")
+	}
+}
@@ -4,10 +4,16 @@ package main
 import (
 	"errors"
 	"fmt"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/kolkov/racedetector/cmd/racedetector/instrument"
 	"github.com/kolkov/racedetector/cmd/racedetector/runtime"
@@ -26,8 +32,34 @@ type testConfig struct {
 
 	// Verbose output flag (-v)
 	verbose bool
+
+	// Path to write a JUnit XML report of detected races (-junit flag).
+	// Empty means no report is written.
+	junitReport string
+
+	// stress enables the deterministic stress scheduler (-stress flag):
+	// instead of one test run, the instrumented binary is run repeatedly,
+	// each time with a different RACEDETECTOR_STRESS_SEED, to surface races
+	// that depend on a rare interleaving. See runStressCycle.
+	stress bool
+
+	// stressIterations caps how many seeds -stress tries before giving up
+	// (-stress-iterations flag). 0 means defaultStressIterations.
+	stressIterations int
+
+	// resetBetweenTests enables per-test detector isolation (-reset-between-tests
+	// flag): tests that call race.TestCleanup reset the detector's shadow
+	// memory and dedup state once they finish, so a race on an address
+	// already reported by an earlier test isn't silently suppressed in a
+	// later one. See internal/race/api.TestCleanup.
+	resetBetweenTests bool
 }
 
+// defaultStressIterations is how many seeds `racedetector test -stress`
+// tries when -stress-iterations wasn't given - enough to have a reasonable
+// chance of hitting a rare interleaving without making CI runs too slow.
+const defaultStressIterations = 20
+
 // testCommand implements the 'racedetector test' command.
 //
 // This command instruments Go source files (including test files),
@@ -66,29 +98,127 @@ func testCommand(args []string) {
 		os.Exit(1)
 	}
 
+	os.Exit(runTestCycle(config))
+}
+
+// runTestCycle runs a single instrument-build-test cycle for config,
+// returning the process exit code to use.
+//
+// This is the reusable core of testCommand, factored out so watchCommand
+// can repeat it on every file change without duplicating workspace
+// management or the race-report side channel wiring.
+func runTestCycle(config *testConfig) int {
 	// Create temporary workspace
 	workspace, err := createWorkspace()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating workspace: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 	defer workspace.cleanup()
 
 	// Instrument source files (including test files)
 	if err := instrumentTestSources(config, workspace); err != nil {
 		fmt.Fprintf(os.Stderr, "Error instrumenting sources: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
 	// Setup runtime linking
 	if err := workspace.setupRuntimeLinking(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error setting up runtime: %v\n", err)
-		os.Exit(1)
+		return 1
+	}
+
+	// Run tests.
+	// reportDir gives every instrumented test binary `go test ./...` spawns
+	// (one per package, each its own process) a side channel to report its
+	// race count and formatted reports back to us, so `racedetector test`
+	// fails reliably even when every test assertion passed, and so races
+	// found in different packages can be aggregated instead of only ever
+	// showing up as interleaved stderr output (synth-3601, see
+	// writeReportDir in internal/race/api).
+	reportDir, err := reportDirPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not create race report directory: %v\n", err)
+	}
+	if reportDir != "" {
+		defer func() { _ = os.RemoveAll(reportDir) }()
+	}
+
+	var baseExtraEnv []string
+	if config.resetBetweenTests {
+		baseExtraEnv = append(baseExtraEnv, "RACEDETECTOR_RESET_BETWEEN_TESTS=1")
+	}
+
+	var exitCode int
+	var totalRaces int
+	var uniqueRaces []string
+	if config.stress {
+		exitCode = runStressCycle(workspace, config, reportDir, baseExtraEnv)
+		totalRaces, uniqueRaces = aggregateReportDir(reportDir)
+	} else {
+		exitCode = runTests(workspace, config, reportDir, baseExtraEnv)
+		totalRaces, uniqueRaces = aggregateReportDir(reportDir)
+		if totalRaces > 0 {
+			fmt.Fprintf(os.Stderr, "racedetector: data races were detected, failing test run\n")
+			exitCode = 1
+		}
+	}
+	printReportSummary(totalRaces, uniqueRaces)
+
+	if config.junitReport != "" {
+		if err := writeJUnitReport(config.junitReport, uniqueRaces); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not write JUnit report: %v\n", err)
+		}
+	}
+
+	return exitCode
+}
+
+// runStressCycle repeats the already-instrumented test run once per seed
+// (-stress), forwarding RACEDETECTOR_STRESS_SEED to the instrumented binary
+// so detector.StressScheduler perturbs scheduling differently each time.
+// Stops and prints the seed as soon as a run detects a race, so it can be
+// reproduced with `RACEDETECTOR_STRESS=1 RACEDETECTOR_STRESS_SEED=<seed>`.
+//
+// Seeds are drawn from a process-local RNG rather than being sequential
+// (0, 1, 2, ...), so consecutive `racedetector test -stress` invocations
+// explore different parts of the interleaving space instead of always
+// retrying the exact same seeds.
+// reportDir is cleared at the start of each iteration (not the end) so
+// that, on failure, the files left behind belong to the failing iteration
+// and runTestCycle's aggregateReportDir(reportDir) call afterward reports
+// on exactly that run.
+func runStressCycle(workspace *workspace, config *testConfig, reportDir string, baseExtraEnv []string) int {
+	iterations := config.stressIterations
+	if iterations <= 0 {
+		iterations = defaultStressIterations
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec // seed selection, not security.
+
+	for i := 0; i < iterations; i++ {
+		clearReportDir(reportDir)
+
+		seed := rng.Int63()
+		extraEnv := append([]string{
+			"RACEDETECTOR_STRESS=1",
+			fmt.Sprintf("RACEDETECTOR_STRESS_SEED=%d", seed),
+		}, baseExtraEnv...)
+
+		fmt.Fprintf(os.Stderr, "racedetector: stress iteration %d/%d (seed=%d)\n", i+1, iterations, seed)
+
+		exitCode := runTests(workspace, config, reportDir, extraEnv)
+		totalRaces, _ := aggregateReportDir(reportDir)
+		if totalRaces > 0 || exitCode != 0 {
+			fmt.Fprintf(os.Stderr, "racedetector: stress run failed on iteration %d/%d - reproduce with:\n", i+1, iterations)
+			fmt.Fprintf(os.Stderr, "  RACEDETECTOR_STRESS=1 RACEDETECTOR_STRESS_SEED=%d racedetector test %s\n",
+				seed, strings.Join(config.packages, " "))
+			return 1
+		}
 	}
 
-	// Run tests
-	exitCode := runTests(workspace, config)
-	os.Exit(exitCode)
+	fmt.Fprintf(os.Stderr, "racedetector: stress passed (%d iterations, no races found)\n", iterations)
+	return 0
 }
 
 // parseTestArgs parses command-line arguments for 'racedetector test'.
@@ -129,6 +259,61 @@ func parseTestArgs(args []string) (*testConfig, error) {
 			continue
 		}
 
+		// Handle -junit flag (JUnit XML race report, racedetector-only -
+		// not forwarded to go test)
+		if arg == "-junit" {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("-junit flag requires an argument")
+			}
+			i++
+			config.junitReport = args[i]
+			continue
+		}
+
+		// Handle -junit=file format
+		if strings.HasPrefix(arg, "-junit=") {
+			config.junitReport = strings.TrimPrefix(arg, "-junit=")
+			continue
+		}
+
+		// Handle -stress flag (deterministic stress scheduler,
+		// racedetector-only - not forwarded to go test)
+		if arg == "-stress" {
+			config.stress = true
+			continue
+		}
+
+		// Handle -stress-iterations flag (how many seeds -stress tries)
+		if arg == "-stress-iterations" {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("-stress-iterations flag requires an argument")
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil {
+				return nil, fmt.Errorf("-stress-iterations: %w", err)
+			}
+			config.stressIterations = n
+			continue
+		}
+
+		// Handle -stress-iterations=N format
+		if strings.HasPrefix(arg, "-stress-iterations=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "-stress-iterations="))
+			if err != nil {
+				return nil, fmt.Errorf("-stress-iterations: %w", err)
+			}
+			config.stressIterations = n
+			continue
+		}
+
+		// Handle -reset-between-tests flag (per-test detector isolation,
+		// racedetector-only - not forwarded to go test)
+		if arg == "-reset-between-tests" {
+			config.resetBetweenTests = true
+			continue
+		}
+
 		// Handle flags (starts with -)
 		if strings.HasPrefix(arg, "-") {
 			config.testFlags = append(config.testFlags, arg)
@@ -208,43 +393,19 @@ func instrumentTestSources(config *testConfig, workspace *workspace) error {
 		return fmt.Errorf("no Go source files found")
 	}
 
-	// Instrument each file
-	for _, srcPath := range allGoFiles {
-		// Instrument the file
-		result, err := instrument.InstrumentFile(srcPath, nil)
-		if err != nil {
-			return fmt.Errorf("failed to instrument %s: %w", srcPath, err)
-		}
-
-		// Determine output path in workspace
-		// Preserve relative path structure for package resolution
-		relPath, err := filepath.Rel(config.workDir, srcPath)
-		if err != nil {
-			// Fallback to just filename
-			relPath = filepath.Base(srcPath)
-		}
-
-		outPath := filepath.Join(workspace.srcDir, relPath)
-
-		// Create parent directories if needed
-		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
-			return fmt.Errorf("failed to create directory for %s: %w", outPath, err)
-		}
-
-		// Write instrumented code to workspace
-		if err := os.WriteFile(outPath, []byte(result.Code), 0644); err != nil {
-			return fmt.Errorf("failed to write instrumented file %s: %w", outPath, err)
-		}
+	// Resolve the on-disk instrumentation cache. Falling back to instrumenting
+	// without a cache (empty cacheDir) is not fatal - it just means every
+	// file is re-instrumented.
+	cacheDir, err := instrumentCacheDir()
+	if err != nil {
+		cacheDir = ""
+	}
 
-		// Print instrumentation info (only in verbose mode)
-		if config.verbose {
-			fmt.Printf("Instrumented: %s\n", relPath)
-			stats := result.Stats
-			if stats.Total() > 0 {
-				fmt.Printf("  - %d writes, %d reads instrumented\n",
-					stats.WritesInstrumented, stats.ReadsInstrumented)
-			}
-		}
+	// Instrument files in parallel, one worker per available CPU.
+	// Each file is independent (its own AST, its own output path), so this
+	// is an embarrassingly parallel fan-out.
+	if err := instrumentFilesParallel(allGoFiles, cacheDir, config, workspace); err != nil {
+		return err
 	}
 
 	// Copy go.mod to srcDir and add racedetector dependency
@@ -255,9 +416,26 @@ func instrumentTestSources(config *testConfig, workspace *workspace) error {
 		goModDst := filepath.Join(workspace.srcDir, "go.mod")
 		data, err := os.ReadFile(goModSrc)
 		if err == nil {
-			// Append racedetector require to the go.mod
 			modContent := string(data)
-			modContent += fmt.Sprintf("\nrequire github.com/kolkov/racedetector %s\n", runtime.Version)
+			if selfHostedModule(modContent) {
+				// Testing racedetector's own source against itself (e.g.
+				// the conformance corpus under cmd/racedetector/conformance
+				// /testdata, synth-3637) can't just keep the copied go.mod's
+				// own module directive and add a replace for that same
+				// path: srcDir only mirrors the package(s) under test, not
+				// the rest of the module, so it isn't really
+				// github.com/kolkov/racedetector any more, and a replace
+				// that targets the main module's own path sends `go mod
+				// tidy` into a non-terminating resolution loop instead of
+				// an error. Renaming the module first, then requiring and
+				// replacing the real one against config.workDir, mirrors
+				// how runtime.ModFileOverlay names the outer workspace
+				// module "instrumented" for the same reason.
+				modContent = moduleDirectiveRe.ReplaceAllString(modContent, "module instrumented-selfhost")
+				modContent += fmt.Sprintf("\nrequire github.com/kolkov/racedetector v0.0.0\nreplace github.com/kolkov/racedetector => %s\n", config.workDir)
+			} else {
+				modContent += fmt.Sprintf("\nrequire github.com/kolkov/racedetector %s\n", runtime.Version)
+			}
 			_ = os.WriteFile(goModDst, []byte(modContent), 0644)
 		}
 	}
@@ -281,6 +459,102 @@ func instrumentTestSources(config *testConfig, workspace *workspace) error {
 	return nil
 }
 
+// instrumentFilesParallel instruments srcFiles concurrently, one worker per
+// available CPU, writing each result into the workspace.
+//
+// Instrumentation of one file never depends on another (each parses its own
+// AST and writes to its own output path), so a simple bounded worker pool is
+// sufficient - no per-file ordering or shared mutable state beyond the
+// cache, which is safe for concurrent use (see instrumentCached).
+//
+// The first error encountered is returned after all in-flight workers drain,
+// matching the fail-fast behavior of the previous sequential loop.
+func instrumentFilesParallel(srcFiles []string, cacheDir string, config *testConfig, workspace *workspace) error {
+	workers := goruntime.NumCPU()
+	if workers > len(srcFiles) {
+		workers = len(srcFiles)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	errs := make(chan error, len(srcFiles))
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for srcPath := range jobs {
+				errs <- instrumentOneFile(srcPath, cacheDir, config, workspace)
+			}
+		}()
+	}
+
+	for _, srcPath := range srcFiles {
+		jobs <- srcPath
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instrumentOneFile instruments a single source file (via the content-hash
+// cache) and writes the result into the workspace. Safe to call concurrently
+// for distinct srcPath values.
+func instrumentOneFile(srcPath, cacheDir string, config *testConfig, workspace *workspace) error {
+	var result *instrument.InstrumentResult
+	var err error
+	if cacheDir != "" {
+		result, err = instrumentCached(cacheDir, srcPath)
+	} else {
+		result, err = instrument.InstrumentFile(srcPath, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to instrument %s: %w", srcPath, err)
+	}
+
+	// Determine output path in workspace.
+	// Preserve relative path structure for package resolution.
+	relPath, err := filepath.Rel(config.workDir, srcPath)
+	if err != nil {
+		// Fallback to just filename
+		relPath = filepath.Base(srcPath)
+	}
+
+	outPath := filepath.Join(workspace.srcDir, relPath)
+
+	// Create parent directories if needed.
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", outPath, err)
+	}
+
+	// Write instrumented code to workspace.
+	if err := os.WriteFile(outPath, []byte(result.Code), 0644); err != nil {
+		return fmt.Errorf("failed to write instrumented file %s: %w", outPath, err)
+	}
+
+	// Print instrumentation info (only in verbose mode).
+	if config.verbose {
+		fmt.Printf("Instrumented: %s\n", relPath)
+		stats := result.Stats
+		if stats.Total() > 0 {
+			fmt.Printf("  - %d writes, %d reads instrumented\n",
+				stats.WritesInstrumented, stats.ReadsInstrumented)
+		}
+	}
+
+	return nil
+}
+
 // resolvePackagePatterns resolves package patterns like "./..." to directories.
 func resolvePackagePatterns(patterns []string, workDir string) ([]string, error) {
 	var dirs []string
@@ -358,6 +632,22 @@ func hasGoFiles(dir string) (bool, error) {
 	return false, nil
 }
 
+// moduleDirectiveRe matches a go.mod's module directive line, e.g.
+// "module github.com/kolkov/racedetector".
+var moduleDirectiveRe = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+
+// selfHostedModule reports whether goModContent's own module directive is
+// github.com/kolkov/racedetector itself (synth-3637) - the case where the
+// package(s) being tested already live inside the race detector's own
+// module, so the copied go.mod can't keep claiming that module identity
+// once it only mirrors a subset of it. See runtime.AugmentedGoMod's
+// related check for the -overlay build/run path, where the real source
+// tree is built in place instead of mirrored and no renaming is needed.
+func selfHostedModule(goModContent string) bool {
+	m := moduleDirectiveRe.FindStringSubmatch(goModContent)
+	return len(m) == 2 && m[1] == "github.com/kolkov/racedetector"
+}
+
 // collectTestGoFiles collects all .go files from a directory (including _test.go).
 func collectTestGoFiles(dir string) ([]string, error) {
 	entries, err := os.ReadDir(dir)
@@ -383,16 +673,28 @@ func collectTestGoFiles(dir string) ([]string, error) {
 }
 
 // runTests executes 'go test' in the workspace with instrumented code.
-func runTests(workspace *workspace, config *testConfig) int {
+//
+// If reportDir is non-empty, it is passed to the test binary via the
+// RACEDETECTOR_REPORT_DIR environment variable (synth-3601), so every
+// per-package test binary `go test ./...` spawns can report its own race
+// count and formatted reports back to us without clobbering its siblings
+// (see writeReportDir, aggregateReportDir). extraEnv is appended as-is
+// (e.g. RACEDETECTOR_STRESS_SEED for -stress, see runStressCycle).
+func runTests(workspace *workspace, config *testConfig, reportDir string, extraEnv []string) int {
 	// Prepare go test command
 	args := []string{"test"}
 
 	// Add test flags
 	args = append(args, config.testFlags...)
 
-	// Add runtime build flags
-	runtimeFlags := runtime.BuildFlags()
-	args = append(args, runtimeFlags...)
+	// Fold build metadata (tool version/commit, instrumentation scope) into
+	// an -ldflags -X assignment, merging with any -ldflags already added
+	// above (synth-3624).
+	args = runtime.BuildFlags(args, runtime.BuildMetadata{
+		ToolVersion: version,
+		ToolCommit:  commit,
+		Scope:       strings.Join(config.packages, " "),
+	})
 
 	// Test the current package (instrumented sources are in workspace)
 	args = append(args, "./...")
@@ -404,6 +706,12 @@ func runTests(workspace *workspace, config *testConfig) int {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
+	cmd.Env = os.Environ()
+	if reportDir != "" {
+		cmd.Env = append(cmd.Env, "RACEDETECTOR_REPORT_DIR="+reportDir)
+	}
+	cmd.Env = append(cmd.Env, extraEnv...)
+
 	if err := cmd.Run(); err != nil {
 		// Check if it's an exit error
 		var exitErr *exec.ExitError
@@ -22,6 +22,10 @@ func TestParseTestArgs(t *testing.T) {
 		wantPackages []string
 		wantFlags    []string
 		wantVerbose  bool
+		wantJUnit    string
+		wantStress   bool
+		wantStressN  int
+		wantReset    bool
 		wantErr      bool
 	}{
 		{
@@ -87,6 +91,50 @@ func TestParseTestArgs(t *testing.T) {
 			wantFlags:    []string{},
 			wantVerbose:  false,
 		},
+		{
+			name:         "junit flag with value",
+			args:         []string{"-junit", "races.xml", "./..."},
+			wantPackages: []string{"./..."},
+			wantFlags:    []string{},
+			wantJUnit:    "races.xml",
+		},
+		{
+			name:         "junit flag with equals",
+			args:         []string{"-junit=out/races.xml", "./..."},
+			wantPackages: []string{"./..."},
+			wantFlags:    []string{},
+			wantJUnit:    "out/races.xml",
+		},
+		{
+			name:         "stress flag",
+			args:         []string{"-stress", "./..."},
+			wantPackages: []string{"./..."},
+			wantFlags:    []string{},
+			wantStress:   true,
+		},
+		{
+			name:         "stress-iterations flag with value",
+			args:         []string{"-stress", "-stress-iterations", "50", "./..."},
+			wantPackages: []string{"./..."},
+			wantFlags:    []string{},
+			wantStress:   true,
+			wantStressN:  50,
+		},
+		{
+			name:         "stress-iterations flag with equals",
+			args:         []string{"-stress", "-stress-iterations=5", "./..."},
+			wantPackages: []string{"./..."},
+			wantFlags:    []string{},
+			wantStress:   true,
+			wantStressN:  5,
+		},
+		{
+			name:         "reset-between-tests flag",
+			args:         []string{"-reset-between-tests", "./..."},
+			wantPackages: []string{"./..."},
+			wantFlags:    []string{},
+			wantReset:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -126,11 +174,52 @@ func TestParseTestArgs(t *testing.T) {
 			if config.verbose != tt.wantVerbose {
 				t.Errorf("verbose = %v, want %v", config.verbose, tt.wantVerbose)
 			}
+
+			// Check junit
+			if config.junitReport != tt.wantJUnit {
+				t.Errorf("junitReport = %q, want %q", config.junitReport, tt.wantJUnit)
+			}
+
+			// Check stress
+			if config.stress != tt.wantStress {
+				t.Errorf("stress = %v, want %v", config.stress, tt.wantStress)
+			}
+			if config.stressIterations != tt.wantStressN {
+				t.Errorf("stressIterations = %d, want %d", config.stressIterations, tt.wantStressN)
+			}
+
+			// Check reset-between-tests
+			if config.resetBetweenTests != tt.wantReset {
+				t.Errorf("resetBetweenTests = %v, want %v", config.resetBetweenTests, tt.wantReset)
+			}
 		})
 	}
 }
 
 // TestTestFlagNeedsValue tests the testFlagNeedsValue function.
+// TestSelfHostedModule tests the selfHostedModule function.
+func TestSelfHostedModule(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"racedetector's own go.mod", "module github.com/kolkov/racedetector\n\ngo 1.24.0\n", true},
+		{"external project", "module github.com/example/myapp\n\ngo 1.24.0\n", false},
+		{"module directive indented mid-file is still matched", "// comment\nmodule github.com/kolkov/racedetector\n", true},
+		{"no module directive", "go 1.24.0\n", false},
+		{"path merely containing racedetector isn't a match", "module github.com/kolkov/racedetector-fork\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selfHostedModule(tt.content); got != tt.want {
+				t.Errorf("selfHostedModule(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTestFlagNeedsValue(t *testing.T) {
 	tests := []struct {
 		flag string
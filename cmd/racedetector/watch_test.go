@@ -0,0 +1,94 @@
+// watch_test.go implements tests for the 'racedetector watch' command.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatchSnapshotStateEqual tests the watchSnapshotState.equal method.
+func TestWatchSnapshotStateEqual(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	t1 := time.Unix(2000, 0)
+
+	tests := []struct {
+		name string
+		a    watchSnapshotState
+		b    watchSnapshotState
+		want bool
+	}{
+		{
+			name: "identical snapshots",
+			a:    watchSnapshotState{"a.go": t0, "b.go": t1},
+			b:    watchSnapshotState{"a.go": t0, "b.go": t1},
+			want: true,
+		},
+		{
+			name: "modified file",
+			a:    watchSnapshotState{"a.go": t0},
+			b:    watchSnapshotState{"a.go": t1},
+			want: false,
+		},
+		{
+			name: "added file",
+			a:    watchSnapshotState{"a.go": t0},
+			b:    watchSnapshotState{"a.go": t0, "b.go": t1},
+			want: false,
+		},
+		{
+			name: "removed file",
+			a:    watchSnapshotState{"a.go": t0, "b.go": t1},
+			b:    watchSnapshotState{"a.go": t0},
+			want: false,
+		},
+		{
+			name: "both empty",
+			a:    watchSnapshotState{},
+			b:    watchSnapshotState{},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.equal(tt.b); got != tt.want {
+				t.Errorf("equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWatchSnapshot tests the watchSnapshot function against a real
+// directory tree.
+func TestWatchSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	goFile := filepath.Join(tmpDir, "main.go")
+	if err := os.WriteFile(goFile, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &testConfig{
+		packages: []string{tmpDir},
+		workDir:  tmpDir,
+	}
+
+	snap := watchSnapshot(config)
+	if len(snap) != 1 {
+		t.Fatalf("watchSnapshot() returned %d entries, want 1", len(snap))
+	}
+
+	// Touching the file should change its recorded mod time, producing an
+	// unequal snapshot.
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(goFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	snap2 := watchSnapshot(config)
+	if snap.equal(snap2) {
+		t.Error("watchSnapshot() did not observe modification time change")
+	}
+}
@@ -34,6 +34,10 @@ func main() {
 	ctx2.C.Set(2, 20)
 	d.OnWrite(addr, ctx2) // This will trigger a race report
 
+	// Race reporting happens on a separate goroutine (synth-3587); wait for
+	// it to print the report above before this prints its own summary.
+	d.WaitForPendingReports()
+
 	fmt.Println("\n=== Demo Complete ===")
 	fmt.Printf("Total races detected: %d\n", d.RacesDetected())
 	fmt.Println("\nNote: This example demonstrates the basic race report format")
@@ -42,6 +42,10 @@ func simulateRace() {
 	ctx2.Epoch = epoch.NewEpoch(2, 20)
 	ctx2.C.Set(2, 20)
 	writeDataThroughHelper(d, addr, ctx2) // This will trigger race report with stack trace
+
+	// Race reporting happens on a separate goroutine (synth-3587); wait for
+	// it to print the report above before main prints its own summary.
+	d.WaitForPendingReports()
 }
 
 // writeData performs a write access (simulating business logic).
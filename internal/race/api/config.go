@@ -0,0 +1,95 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// ConfigOptions mirrors the subset of detector.DetectorOptions exposed
+// through the public race.Configure API (v0.5.0). It exists separately
+// from detector.DetectorOptions so the internal detector can gain new
+// knobs (e.g. RecordSchedule, ReplaySchedule) without those leaking into
+// the public API's compatibility surface until they're deliberately
+// promoted here.
+type ConfigOptions struct {
+	// SampleRate, see detector.DetectorOptions.SampleRate.
+	SampleRate uint64
+
+	// WarmupDuration, see detector.DetectorOptions.WarmupDuration.
+	WarmupDuration time.Duration
+
+	// OverheadTargetPercent, see detector.DetectorOptions.OverheadTargetPercent.
+	OverheadTargetPercent float64
+
+	// HistorySize, see detector.DetectorOptions.HistorySize.
+	HistorySize int
+
+	// ProfilingEnabled, see detector.DetectorOptions.ProfilingEnabled.
+	ProfilingEnabled bool
+
+	// StripPathPrefix, see detector.SymbolizeOptions.StripPathPrefix.
+	StripPathPrefix string
+
+	// ModuleRoot, see detector.SymbolizeOptions.ModuleRoot.
+	ModuleRoot string
+
+	// ModuleImportPath, see detector.SymbolizeOptions.ModuleImportPath.
+	ModuleImportPath string
+
+	// ExportURL, see detector.ExportOptions.URL.
+	ExportURL string
+
+	// ExportFormat, see detector.ExportOptions.Format.
+	ExportFormat string
+
+	// ReportMaxFrames, see detector.ReportOptions.MaxFrames.
+	ReportMaxFrames int
+
+	// ReportSkipInternalFrames, see detector.ReportOptions.SkipInternalFrames.
+	ReportSkipInternalFrames bool
+
+	// ReportCollapseWrappers, see detector.ReportOptions.CollapseWrappers.
+	ReportCollapseWrappers bool
+
+	// ReportMaxReportsPerSite, see detector.ReportOptions.MaxReportsPerSite.
+	ReportMaxReportsPerSite int
+
+	// SingleThreaded, see detector.DetectorOptions.SingleThreaded. Init()
+	// ORs this with the platform default (true on GOOS=js/wasip1, false
+	// elsewhere - synth-3615), so setting it here only ever turns the
+	// single-threaded backpressure policy on, never off; use
+	// RACEDETECTOR_SINGLE_THREADED=0 to force it off on a platform whose
+	// default is true.
+	SingleThreaded bool
+}
+
+var (
+	configuredOptionsMu sync.Mutex
+	configuredOptions   ConfigOptions
+)
+
+// Configure records detector options for the next Init() call to apply,
+// surfacing knobs that would otherwise only be reachable via the
+// RACEDETECTOR_* environment variables Init() also recognizes.
+//
+// Configure must be called before Init(); it has no effect on an
+// already-running detector. Where both are set, a RACEDETECTOR_*
+// environment variable takes precedence over the matching Configure
+// option, so an operator can still override a program's compiled-in
+// configuration at deploy time without a rebuild.
+//
+// Thread Safety: NOT safe for concurrent calls, and not safe to call
+// concurrently with Init() - same convention as Init() itself.
+func Configure(opts ConfigOptions) {
+	configuredOptionsMu.Lock()
+	defer configuredOptionsMu.Unlock()
+	configuredOptions = opts
+}
+
+// consumeConfiguredOptions returns the options passed to the most recent
+// Configure call (the zero value if Configure was never called).
+func consumeConfiguredOptions() ConfigOptions {
+	configuredOptionsMu.Lock()
+	defer configuredOptionsMu.Unlock()
+	return configuredOptions
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// TestConsumeConfiguredOptions_RoundTrips verifies Configure's options are
+// returned by the next consumeConfiguredOptions call, and that
+// consuming resets nothing (Init may run more than once per Configure).
+func TestConsumeConfiguredOptions_RoundTrips(t *testing.T) {
+	defer Configure(ConfigOptions{})
+
+	want := ConfigOptions{SampleRate: 10, HistorySize: 3, ProfilingEnabled: true}
+	Configure(want)
+
+	if got := consumeConfiguredOptions(); got != want {
+		t.Errorf("consumeConfiguredOptions() = %+v, want %+v", got, want)
+	}
+}
+
+// TestConfigure_SeedsInitOptions verifies a Configure call before Init is
+// reflected in the detector Init() builds, via WriteOverheadProfile's
+// observable enabled/disabled behavior.
+func TestConfigure_SeedsInitOptions(t *testing.T) {
+	defer Configure(ConfigOptions{})
+	Configure(ConfigOptions{ProfilingEnabled: true})
+
+	Init()
+	defer Fini()
+
+	if err := WriteOverheadProfile(io.Discard); err != nil {
+		t.Errorf("WriteOverheadProfile() = %v, want nil (ProfilingEnabled set via Configure)", err)
+	}
+}
+
+// TestConfigure_EnvironmentOverridesConfigure verifies a RACEDETECTOR_*
+// environment variable still takes precedence over a Configure option, so
+// an operator can override a program's compiled-in configuration without
+// a rebuild.
+func TestConfigure_EnvironmentOverridesConfigure(t *testing.T) {
+	defer Configure(ConfigOptions{})
+	Configure(ConfigOptions{ProfilingEnabled: false})
+
+	os.Setenv("RACEDETECTOR_PROFILE", "1")
+	defer os.Unsetenv("RACEDETECTOR_PROFILE")
+
+	Init()
+	defer Fini()
+
+	if err := WriteOverheadProfile(io.Discard); err != nil {
+		t.Errorf("WriteOverheadProfile() = %v, want nil (RACEDETECTOR_PROFILE overrides Configure)", err)
+	}
+}
+
+// TestDefaultSingleThreaded_MatchesPlatform verifies the build-tag-selected
+// defaultSingleThreaded constant (singlethreaded_wasm.go /
+// singlethreaded_other.go, synth-3615) agrees with runtime.GOOS: true on
+// GOOS=js/wasip1, false everywhere else.
+func TestDefaultSingleThreaded_MatchesPlatform(t *testing.T) {
+	want := runtime.GOOS == "js" || runtime.GOOS == "wasip1"
+	if defaultSingleThreaded != want {
+		t.Errorf("defaultSingleThreaded = %v on GOOS=%s, want %v", defaultSingleThreaded, runtime.GOOS, want)
+	}
+}
+
+// TestInit_SingleThreadedEnvOverride verifies RACEDETECTOR_SINGLE_THREADED
+// can force the single-threaded backpressure policy on or off regardless
+// of the platform default or Configure (synth-3615).
+func TestInit_SingleThreadedEnvOverride(t *testing.T) {
+	defer Configure(ConfigOptions{})
+	defer os.Unsetenv("RACEDETECTOR_SINGLE_THREADED")
+
+	Configure(ConfigOptions{SingleThreaded: false})
+	os.Setenv("RACEDETECTOR_SINGLE_THREADED", "1")
+
+	Init()
+	defer Fini()
+
+	if !det.SingleThreaded() {
+		t.Error("RACEDETECTOR_SINGLE_THREADED=1 did not force the detector into single-threaded mode")
+	}
+}
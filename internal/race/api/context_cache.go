@@ -0,0 +1,98 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// contextGeneration is bumped by Reset() and by re-Init() so that
+// getCurrentContext's fast path (synth-3586) can't hand back a
+// contextCacheEntry cached under a previous generation of the detector -
+// gid alone doesn't catch that case, since a still-running goroutine keeps
+// its gid across a Reset().
+var contextGeneration atomic.Uint64
+
+// contextCacheEntry is what getCurrentContext stashes in the current
+// goroutine's g.racectx slot (synth-3586) to turn its cached path into a
+// single pointer load instead of a sync.Map lookup.
+//
+// gid guards against g struct reuse: the Go runtime pools g structs, so a
+// goroutine that exits and a brand-new, unrelated goroutine can end up
+// sharing the same g memory (and therefore the same racectx slot) with no
+// notification to us. Tagging the cached entry with the gid it was filled
+// for turns a stale slot into a cheap mismatch instead of a wrong
+// RaceContext handed to the wrong goroutine - see loadContextFast.
+//
+// gen guards the other staleness case: a goroutine that is still alive
+// across a Reset()/re-Init() call, whose gid doesn't change but whose
+// cached RaceContext must not survive the reset.
+type contextCacheEntry struct {
+	gid int64
+	gen uint64
+	ctx *goroutine.RaceContext
+}
+
+// racectx is a raw uintptr field on runtime.g reserved by the upstream Go
+// runtime for the cgo race detector's per-goroutine context pointer (see
+// runtime/race.go's raceenabled paths). This library's own detector never
+// sets raceenabled, but the *consuming binary* might: raceenabled is a
+// process-wide runtime flag set the moment anything in the binary is
+// compiled with `go build -race` / `go test -race`, independent of this
+// package. Only when raceenabled is false for the whole process does the
+// runtime leave that field unused for the entire life of every goroutine,
+// making it a safe slot to repurpose as a lookup-free cache key for our own
+// RaceContext (synth-3586). racectx_go124.go/racectx_go125.go are therefore
+// built with `!race`, so a `-race` build falls back to the sync.Map-only
+// path in racectx_fallback.go instead of colliding with the runtime's own
+// ThreadSanitizer context pointer.
+//
+// getContextSlotFast/setContextSlotFast (declared per Go version, see
+// racectx_go123.go/racectx_go124.go/racectx_go125.go/racectx_fallback.go)
+// read and write that slot. They never dereference it as a RaceContext
+// directly - the *contextCacheEntry stored there is only trusted after its
+// gid tag is checked against the live goroutine ID.
+//
+// contexts sync.Map (see getCurrentContext) remains the source of truth: it
+// is still populated on every slow-path allocation, which is what keeps the
+// RaceContext reachable to the garbage collector. racectx's raw uintptr
+// field is invisible to the GC, so a *contextCacheEntry reachable only
+// through it could be collected out from under us; contexts sync.Map is
+// what actually keeps it alive.
+
+// loadContextFast returns the RaceContext cached in the current goroutine's
+// racectx slot, or (nil, false) on a miss (empty slot, gid mismatch from a
+// reused g, or an unsupported architecture/Go version).
+//
+//go:nosplit
+//go:nocheckptr
+func loadContextFast(gid int64) (*goroutine.RaceContext, bool) {
+	slot := getContextSlotFast()
+	if slot == 0 {
+		return nil, false
+	}
+
+	//nolint:gosec // G103: Intentional unsafe pointer arithmetic for runtime access
+	entry := (*contextCacheEntry)(unsafe.Pointer(slot))
+	if entry.gid != gid || entry.gen != contextGeneration.Load() {
+		return nil, false
+	}
+
+	return entry.ctx, true
+}
+
+// storeContextFast caches ctx in the current goroutine's racectx slot,
+// tagged with gid and the current contextGeneration, and registers it in
+// contextCacheEntries so the garbage collector keeps it alive - see the
+// racectx field comment above. A no-op on architectures/Go versions without
+// a verified racectxOffset (see racectx_fallback.go).
+func storeContextFast(gid int64, ctx *goroutine.RaceContext) {
+	entry := &contextCacheEntry{gid: gid, gen: contextGeneration.Load(), ctx: ctx}
+	contextCacheEntries.Store(gid, entry)
+	setContextSlotFast(uintptr(unsafe.Pointer(entry)))
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// TestContextCache_StoreThenLoadHits verifies a stored entry is returned by
+// the fast path for the same goroutine.
+func TestContextCache_StoreThenLoadHits(t *testing.T) {
+	if builtWithGoRace {
+		t.Skip("racectx fast path is disabled under -race (synth-3586); see TestRacectxFastPath_DisabledUnderRace")
+	}
+	gid := getGoroutineID()
+	ctx := goroutine.Alloc(1)
+
+	storeContextFast(gid, ctx)
+	defer setContextSlotFast(0)
+
+	got, ok := loadContextFast(gid)
+	if !ok {
+		t.Fatal("loadContextFast() ok = false after storeContextFast, want true")
+	}
+	if got != ctx {
+		t.Errorf("loadContextFast() = %p, want %p", got, ctx)
+	}
+}
+
+// TestContextCache_EmptySlotMisses verifies a goroutine that never called
+// storeContextFast gets a clean miss, not a stale hit from a previous test.
+func TestContextCache_EmptySlotMisses(t *testing.T) {
+	setContextSlotFast(0)
+
+	if _, ok := loadContextFast(getGoroutineID()); ok {
+		t.Error("loadContextFast() ok = true on an empty slot, want false")
+	}
+}
+
+// TestContextCache_GIDMismatchMisses simulates the g-struct-reuse hazard
+// this cache guards against (synth-3586): a slot holding an entry tagged
+// for a different gid than the caller's must miss rather than hand back
+// the wrong goroutine's RaceContext.
+func TestContextCache_GIDMismatchMisses(t *testing.T) {
+	gid := getGoroutineID()
+	ctx := goroutine.Alloc(1)
+	storeContextFast(gid, ctx)
+	defer setContextSlotFast(0)
+
+	if _, ok := loadContextFast(gid + 1); ok {
+		t.Error("loadContextFast() ok = true for a mismatched gid, want false")
+	}
+}
+
+// TestContextCache_GenerationMismatchMisses simulates the Reset()/Restore()
+// hazard: a still-live goroutine's cached entry must not survive a
+// contextGeneration bump even though its gid is unchanged.
+func TestContextCache_GenerationMismatchMisses(t *testing.T) {
+	gid := getGoroutineID()
+	ctx := goroutine.Alloc(1)
+	storeContextFast(gid, ctx)
+	defer setContextSlotFast(0)
+
+	contextGeneration.Add(1)
+	defer contextGeneration.Add(1) // restore parity for later tests in this process
+
+	if _, ok := loadContextFast(gid); ok {
+		t.Error("loadContextFast() ok = true after a generation bump, want false")
+	}
+}
+
+// TestGetCurrentContext_UsesFastPathOnSecondCall verifies the public
+// getCurrentContext entry point backfills the racectx slot on first access
+// and that the fast path alone is sufficient to reproduce the same
+// RaceContext on a later call, matching contexts sync.Map.
+func TestGetCurrentContext_UsesFastPathOnSecondCall(t *testing.T) {
+	if builtWithGoRace {
+		t.Skip("racectx fast path is disabled under -race (synth-3586); see TestRacectxFastPath_DisabledUnderRace")
+	}
+	Reset()
+	defer Reset()
+
+	first := getCurrentContext()
+	gid := getGoroutineID()
+
+	fast, ok := loadContextFast(gid)
+	if !ok {
+		t.Fatal("loadContextFast() ok = false after getCurrentContext, want true")
+	}
+	if fast != first {
+		t.Errorf("racectx slot holds %p, want %p (from getCurrentContext)", fast, first)
+	}
+
+	second := getCurrentContext()
+	if second != first {
+		t.Errorf("getCurrentContext() returned %p on second call, want the same %p", second, first)
+	}
+}
+
+// TestGetCurrentContext_ConcurrentGoroutinesGetDistinctContexts verifies
+// many concurrently running goroutines each populate and read back their
+// own racectx slot without cross-talk, the scenario the gid tag in
+// contextCacheEntry exists to protect against.
+func TestGetCurrentContext_ConcurrentGoroutinesGetDistinctContexts(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	const n = 200
+	tids := make(chan uint16, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := getCurrentContext()
+			// Call again from the same goroutine: must be the identical
+			// context, exercising the fast path this time.
+			if again := getCurrentContext(); again != ctx {
+				t.Errorf("getCurrentContext() unstable within one goroutine: %p then %p", ctx, again)
+			}
+			tids <- ctx.TID
+		}()
+	}
+	wg.Wait()
+	close(tids)
+
+	seen := make(map[uint16]bool, n)
+	for tid := range tids {
+		if seen[tid] {
+			t.Errorf("TID %d handed out to more than one goroutine", tid)
+		}
+		seen[tid] = true
+	}
+}
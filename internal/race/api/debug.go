@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// WriteDebugState writes a plain-text snapshot of the detector's live
+// state (v0.5.0): current race count, recent race reports, per-goroutine
+// vector clocks, the top shadow-memory consumers by write count, and the
+// top addresses by total access count with their promotion/demotion
+// churn (synth-3642) - useful for spotting which variables dominate
+// detector overhead independently of how "hot" they look by writes alone.
+//
+// Unlike WritePrometheusMetrics (aimed at scrapers), this is meant to be
+// read by a human staring at a staging service for hours, so it favors
+// readability over a fixed exposition format.
+//
+// Thread Safety: Safe for concurrent calls.
+func WriteDebugState(w io.Writer) {
+	fmt.Fprintf(w, "=== Race Detector Debug State ===\n\n")
+
+	fmt.Fprintf(w, "Races detected: %d\n\n", det.RacesDetected())
+
+	fmt.Fprintf(w, "--- Recent Race Reports ---\n")
+	reports := det.RecentReports()
+	if len(reports) == 0 {
+		fmt.Fprintf(w, "(none)\n")
+	}
+	for _, report := range reports {
+		report.Format(w)
+	}
+	fmt.Fprintf(w, "\n")
+
+	fmt.Fprintf(w, "--- Per-Goroutine Clocks ---\n")
+	contexts.Range(func(key, value interface{}) bool {
+		gid := key.(int64)
+		ctx := value.(*goroutine.RaceContext)
+		fmt.Fprintf(w, "goroutine %d (tid=%d): %s\n", gid, ctx.TID, ctx.C.String())
+		return true
+	})
+	fmt.Fprintf(w, "\n")
+
+	fmt.Fprintf(w, "--- Top Shadow Memory Consumers ---\n")
+	consumers := det.TopShadowConsumers(10)
+	if len(consumers) == 0 {
+		fmt.Fprintf(w, "(none)\n")
+	}
+	for _, c := range consumers {
+		fmt.Fprintf(w, "0x%016x: %d writes\n", c.Addr, c.WriteCount)
+	}
+	fmt.Fprintf(w, "\n")
+
+	fmt.Fprintf(w, "--- Top Addresses by Access Count (synth-3642) ---\n")
+	addrs := det.TopAddresses(10)
+	if len(addrs) == 0 {
+		fmt.Fprintf(w, "(none)\n")
+	}
+	for _, a := range addrs {
+		owner := a.Owner
+		if owner == "" {
+			owner = fmt.Sprintf("0x%016x", a.Addr)
+		}
+		fmt.Fprintf(w, "%s: %d checks, %d promotions, %d demotions (last accessed in %s)\n",
+			owner, a.CheckCount, a.Promotions, a.Demotions, a.OwnerFunc)
+	}
+}
+
+// DebugHandler returns an http.Handler showing the detector's live state
+// (v0.5.0): current race count, recent reports, per-goroutine clocks,
+// top shadow-memory consumers, and top addresses by access count. Meant
+// to be registered on a user's mux for inspecting a service that's been
+// running the detector for hours:
+//
+//	mux.Handle("/debug/race", api.DebugHandler())
+//
+// Thread Safety: Safe for concurrent calls.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		WriteDebugState(w)
+	})
+}
@@ -0,0 +1,82 @@
+package api
+
+import "testing"
+
+// TestEnableFullDetectionForGoroutine_ForcesCurrentContext verifies the
+// exported wrapper reaches the calling goroutine's RaceContext (synth-3641).
+func TestEnableFullDetectionForGoroutine_ForcesCurrentContext(t *testing.T) {
+	Init()
+	defer Fini()
+
+	if getCurrentContext().IsFullDetectionForced() {
+		t.Fatal("IsFullDetectionForced() = true before EnableFullDetectionForGoroutine, want false")
+	}
+
+	EnableFullDetectionForGoroutine()
+	if !getCurrentContext().IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = false after EnableFullDetectionForGoroutine, want true")
+	}
+
+	DisableFullDetectionForGoroutine()
+	if getCurrentContext().IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = true after matching DisableFullDetectionForGoroutine, want false")
+	}
+}
+
+// TestDisableFullDetectionForGoroutine_UnbalancedIsNoOp verifies an
+// unmatched Disable call doesn't underflow into a false "forced" state.
+func TestDisableFullDetectionForGoroutine_UnbalancedIsNoOp(t *testing.T) {
+	Init()
+	defer Fini()
+
+	DisableFullDetectionForGoroutine()
+	if getCurrentContext().IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = true after unbalanced DisableFullDetectionForGoroutine, want false")
+	}
+}
+
+// TestGoStart_PropagatesForcedFullDetectionToChild verifies a goroutine
+// spawned while the parent has full detection forced inherits that state,
+// mirroring GoStart's existing VectorClock inheritance (synth-3641).
+func TestGoStart_PropagatesForcedFullDetectionToChild(t *testing.T) {
+	Init()
+	defer Fini()
+
+	EnableFullDetectionForGoroutine()
+	defer DisableFullDetectionForGoroutine()
+
+	RaceGoStart(0)
+	done := make(chan bool)
+	childForced := make(chan bool, 1)
+	go func() {
+		childForced <- getCurrentContext().IsFullDetectionForced()
+		RaceGoEnd()
+		done <- true
+	}()
+	<-done
+
+	if !<-childForced {
+		t.Error("child goroutine's IsFullDetectionForced() = false, want true (should inherit from forced parent)")
+	}
+}
+
+// TestGoStart_DoesNotForceChildWithoutParentForcing verifies GoStart
+// inheritance is opt-in: a child of an unforced parent isn't forced.
+func TestGoStart_DoesNotForceChildWithoutParentForcing(t *testing.T) {
+	Init()
+	defer Fini()
+
+	RaceGoStart(0)
+	done := make(chan bool)
+	childForced := make(chan bool, 1)
+	go func() {
+		childForced <- getCurrentContext().IsFullDetectionForced()
+		RaceGoEnd()
+		done <- true
+	}()
+	<-done
+
+	if <-childForced {
+		t.Error("child goroutine's IsFullDetectionForced() = true, want false (parent wasn't forced)")
+	}
+}
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-//go:build !go1.23 || go1.26 || !(amd64 || arm64)
+//go:build !go1.23 || go1.26 || !(amd64 || arm64 || riscv64)
 
 // Fallback goroutine ID extraction for unsupported platforms.
 //
@@ -11,13 +11,13 @@
 //
 //   - Go versions < 1.23 (runtime.g layout not verified)
 //   - Go versions >= 1.26 (runtime.g layout may have changed)
-//   - Architectures other than amd64/arm64 (no assembly implementation)
+//   - Architectures other than amd64/arm64/riscv64 (no assembly implementation)
 //
 // Performance: ~1500ns per call (runtime.Stack parsing).
 //
 // Supported platforms (fallback to this):
 //   - 386, arm, ppc64, ppc64le, mips, mips64, mips64le
-//   - riscv64, s390x, wasm, loong64
+//   - s390x, wasm, loong64
 //   - Any architecture on Go < 1.23 or Go >= 1.26
 //
 // The fallback uses runtime.Stack() to get the current goroutine's stack
@@ -26,6 +26,11 @@
 
 package api
 
+// fastGoidAvailable reports that this build has no assembly fast path -
+// getGoroutineIDFast below just delegates to the slow path - for GetInfo
+// (synth-3638).
+const fastGoidAvailable = false
+
 // getGoroutineIDFast is the fallback implementation for unsupported platforms.
 //
 // On platforms without assembly optimization, this function simply delegates
@@ -35,7 +40,7 @@ package api
 // Performance: ~1500ns per call (same as getGoroutineIDSlow).
 //
 // This function is used when:
-//   - Running on unsupported architecture (not amd64/arm64)
+//   - Running on unsupported architecture (not amd64/arm64/riscv64)
 //   - Running on unsupported Go version (< 1.23 or >= 1.26)
 //
 // Returns:
@@ -8,7 +8,7 @@
 // (assembly) and slow (runtime.Stack) paths for goroutine ID extraction.
 //
 // The actual getGoroutineIDFast() function is provided by:
-//   - goid_fast.go: Assembly-optimized path (Go 1.23-1.25, amd64/arm64)
+//   - goid_fast.go: Assembly-optimized path (Go 1.23-1.25, amd64/arm64/riscv64)
 //   - goid_fallback.go: Stack parsing path (all other configurations)
 //
 // API:
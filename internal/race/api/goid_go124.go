@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-//go:build go1.24 && !go1.25 && (amd64 || arm64)
+//go:build go1.24 && !go1.25 && (amd64 || arm64 || riscv64)
 
 // Go 1.24 specific goid extraction.
 //
@@ -36,8 +36,12 @@ import "unsafe"
 // goidOffset for Go 1.24 is 160 bytes (same as Go 1.23, gobuf still has 'ret' field).
 const goidOffset = 160
 
+// fastGoidAvailable reports that this build has the assembly fast path
+// wired up, for GetInfo (synth-3638).
+const fastGoidAvailable = true
+
 // getg returns the current goroutine's g struct pointer.
-// Implemented in assembly (goid_amd64.s or goid_arm64.s).
+// Implemented in assembly (goid_amd64_unix.s/goid_amd64_windows.s or goid_arm64.s).
 //
 //go:noescape
 func getg() uintptr
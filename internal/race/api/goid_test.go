@@ -313,7 +313,7 @@ func TestParseGID(t *testing.T) {
 //
 // This is critical for performance - the fast path must not allocate.
 // Uses outrigdev/goid library which provides assembly-optimized path
-// for Go 1.23+ on amd64/arm64.
+// for Go 1.23+ on amd64/arm64/riscv64.
 func TestGetGoroutineID_NoAllocations(t *testing.T) {
 	// Warm up
 	for i := 0; i < 100; i++ {
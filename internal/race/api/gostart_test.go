@@ -195,7 +195,7 @@ func TestGoStart_SpawnContextExpiry(t *testing.T) {
 	time.Sleep(150 * time.Millisecond)
 
 	// Try to consume - should return nil (expired)
-	clock := findAndConsumeSpawnContext()
+	clock, _, _ := findAndConsumeSpawnContext()
 	if clock != nil {
 		t.Error("Spawn context should have expired after TTL")
 	}
@@ -210,13 +210,13 @@ func TestGoStart_SpawnContextConsumption(t *testing.T) {
 	RaceGoStart(0)
 
 	// First consumer gets the context
-	clock1 := findAndConsumeSpawnContext()
+	clock1, _, _ := findAndConsumeSpawnContext()
 	if clock1 == nil {
 		t.Fatal("First consumer should get spawn context")
 	}
 
 	// Second consumer gets nothing (already consumed)
-	clock2 := findAndConsumeSpawnContext()
+	clock2, _, _ := findAndConsumeSpawnContext()
 	if clock2 != nil {
 		t.Error("Second consumer should NOT get spawn context (already consumed)")
 	}
@@ -0,0 +1,32 @@
+package api
+
+// Enabled reports whether the most recent Init() call turned race
+// detection on. It stays true until process exit - there is no matching
+// "Disabled" state once Init() has run (synth-3638).
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// SamplingEnabled reports whether the most recent Init() call started the
+// detector with access sampling on (see ConfigOptions.SampleRate and the
+// RACEDETECTOR_SAMPLE_RATE environment variable, either of which can turn
+// it on). Returns false if Init() has not been called yet (synth-3638).
+func SamplingEnabled() bool {
+	return initSamplingEnabled
+}
+
+// SampleRate returns the sample rate the most recent Init() call started
+// the detector with - 1 in SampleRate memory accesses is checked, the rest
+// skipped. 0 or 1 means sampling is off and every access is checked, the
+// same as before SampleRate existed (synth-3638).
+func SampleRate() uint64 {
+	return initSampleRate
+}
+
+// FastGoidAvailable reports whether this build has the assembly-optimized
+// goroutine ID extraction wired up (see the goid_go12x.go files' build
+// tags) rather than falling back to the ~1500ns runtime.Stack-parsing
+// path in goid_fallback.go (synth-3638).
+func FastGoidAvailable() bool {
+	return fastGoidAvailable
+}
@@ -0,0 +1,44 @@
+package api
+
+import "testing"
+
+// TestEnabled_ReflectsInitFini verifies Enabled tracks the detector's
+// on/off state across an Init/Fini cycle (synth-3638).
+func TestEnabled_ReflectsInitFini(t *testing.T) {
+	Init()
+	if !Enabled() {
+		t.Error("Enabled() = false after Init(), want true")
+	}
+	Fini()
+}
+
+// TestSampleRate_MatchesConfigure verifies SamplingEnabled/SampleRate
+// report the values the most recent Init() actually started with
+// (synth-3638).
+func TestSampleRate_MatchesConfigure(t *testing.T) {
+	defer Configure(ConfigOptions{})
+
+	Configure(ConfigOptions{SampleRate: 10})
+	Init()
+	defer Fini()
+
+	if !SamplingEnabled() {
+		t.Error("SamplingEnabled() = false, want true (SampleRate: 10 was configured)")
+	}
+	if got := SampleRate(); got != 10 {
+		t.Errorf("SampleRate() = %d, want 10", got)
+	}
+}
+
+// TestFastGoidAvailable_MatchesOffset verifies FastGoidAvailable agrees
+// with whether this build has an assembly-optimized getGoroutineIDFast
+// (i.e. it isn't just delegating to the slow path), by comparing goid
+// results from repeated calls on the same goroutine - either path must
+// return a stable, positive ID (synth-3638).
+func TestFastGoidAvailable_MatchesOffset(t *testing.T) {
+	id1 := getGoroutineIDFast()
+	id2 := getGoroutineIDFast()
+	if id1 <= 0 || id1 != id2 {
+		t.Errorf("getGoroutineIDFast() = %d, %d, want equal positive IDs (fastGoidAvailable=%v)", id1, id2, fastGoidAvailable)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+
+	"github.com/kolkov/racedetector/internal/race/detector"
+)
+
+// Metrics are registered with expvar under this prefix so they sit together
+// in a /debug/vars dump and don't collide with names an instrumented
+// program registers for itself.
+const metricsPrefix = "racedetector_"
+
+// init registers expvar.Func callbacks that read the live global state
+// (det, freeTIDs) on every scrape rather than snapshotting it once. This
+// matters because det is replaced on every Init() call (e.g. in tests),
+// so a one-time expvar.Publish(det.RacesDetected()) would go stale.
+//
+// expvar.Publish panics on a duplicate name, which is why these are
+// registered exactly once here instead of from Init() (which may run many
+// times in the same process).
+func init() {
+	expvar.Publish(metricsPrefix+"races_detected", expvar.Func(func() interface{} {
+		return det.RacesDetected()
+	}))
+	expvar.Publish(metricsPrefix+"shadow_cell_count", expvar.Func(func() interface{} {
+		return det.ShadowCellCount()
+	}))
+	expvar.Publish(metricsPrefix+"promoted_var_count", expvar.Func(func() interface{} {
+		return det.GetPromotionStats().PromotedVars
+	}))
+	expvar.Publish(metricsPrefix+"sampler_total_accesses", expvar.Func(func() interface{} {
+		return samplerStat(func(s detector.SamplerStats) uint64 { return s.TotalAccesses })
+	}))
+	expvar.Publish(metricsPrefix+"sampler_sampled_accesses", expvar.Func(func() interface{} {
+		return samplerStat(func(s detector.SamplerStats) uint64 { return s.SampledAccesses })
+	}))
+	expvar.Publish(metricsPrefix+"sampler_skipped_accesses", expvar.Func(func() interface{} {
+		return samplerStat(func(s detector.SamplerStats) uint64 { return s.SkippedAccesses })
+	}))
+	expvar.Publish(metricsPrefix+"tid_pool_occupancy", expvar.Func(func() interface{} {
+		return TIDPoolOccupancy()
+	}))
+}
+
+// samplerStat reads one field out of the current detector's sampler stats,
+// returning 0 when sampling is disabled (det.GetSamplerStats() is nil).
+func samplerStat(field func(detector.SamplerStats) uint64) uint64 {
+	stats := det.GetSamplerStats()
+	if stats == nil {
+		return 0
+	}
+	return field(*stats)
+}
+
+// promMetric is one line of Prometheus text exposition format output.
+type promMetric struct {
+	name string
+	help string
+	val  uint64
+}
+
+// WritePrometheusMetrics writes the same metrics published to expvar in
+// Prometheus text exposition format (v0.5.0), so production canaries can
+// scrape the detector's health without an expvar-to-Prometheus bridge.
+//
+// Thread Safety: Safe for concurrent calls.
+func WritePrometheusMetrics(w io.Writer) error {
+	promotionStats := det.GetPromotionStats()
+	samplerStats := det.GetSamplerStats()
+
+	metrics := []promMetric{
+		{"racedetector_races_detected", "Total number of races detected since initialization.", uint64(det.RacesDetected())},
+		{"racedetector_shadow_cell_count", "Number of shadow memory cells currently tracked.", uint64(det.ShadowCellCount())},
+		{"racedetector_promoted_var_count", "Number of variables currently promoted to VectorClock representation.", promotionStats.PromotedVars},
+		{"racedetector_tid_pool_occupancy", "Number of goroutine TIDs currently allocated out of the fixed-size pool.", uint64(TIDPoolOccupancy())},
+	}
+	if samplerStats != nil {
+		metrics = append(metrics,
+			promMetric{"racedetector_sampler_total_accesses", "Total memory accesses seen by the sampler.", samplerStats.TotalAccesses},
+			promMetric{"racedetector_sampler_sampled_accesses", "Memory accesses the sampler selected for checking.", samplerStats.SampledAccesses},
+			promMetric{"racedetector_sampler_skipped_accesses", "Memory accesses the sampler skipped.", samplerStats.SkippedAccesses},
+		)
+	}
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", m.name, m.help, m.name, m.name, m.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"expvar"
+	"strings"
+	"testing"
+)
+
+// TestWritePrometheusMetrics_IncludesCoreGauges verifies the always-present
+// metrics (no sampler required) show up in the Prometheus text dump.
+func TestWritePrometheusMetrics_IncludesCoreGauges(t *testing.T) {
+	Reset()
+	Enable()
+	racewrite(uintptr(0x7000))
+
+	var buf bytes.Buffer
+	if err := WritePrometheusMetrics(&buf); err != nil {
+		t.Fatalf("WritePrometheusMetrics() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"racedetector_races_detected",
+		"racedetector_shadow_cell_count",
+		"racedetector_promoted_var_count",
+		"racedetector_tid_pool_occupancy",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WritePrometheusMetrics() output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// TestWritePrometheusMetrics_OmitsSamplerGaugesWhenDisabled verifies sampler
+// metrics are left out rather than printed as zero when sampling is off.
+func TestWritePrometheusMetrics_OmitsSamplerGaugesWhenDisabled(t *testing.T) {
+	Reset()
+
+	var buf bytes.Buffer
+	if err := WritePrometheusMetrics(&buf); err != nil {
+		t.Fatalf("WritePrometheusMetrics() error = %v", err)
+	}
+
+	if strings.Contains(buf.String(), "racedetector_sampler_total_accesses") {
+		t.Error("WritePrometheusMetrics() included sampler metrics with sampling disabled")
+	}
+}
+
+// TestExpvarMetrics_RacesDetectedTracksLiveDetector verifies the expvar.Func
+// callback reads the current global detector rather than a stale snapshot
+// from whenever the package was first imported.
+func TestExpvarMetrics_RacesDetectedTracksLiveDetector(t *testing.T) {
+	Reset()
+	Enable()
+
+	v := expvar.Get("racedetector_races_detected")
+	if v == nil {
+		t.Fatal("expvar.Get(\"racedetector_races_detected\") = nil, want a registered Func")
+	}
+
+	before := v.String()
+	racewrite(uintptr(0x7100))
+	raceread(uintptr(0x7100))
+	after := v.String()
+
+	// A sequential write+read shouldn't register a race, but this at least
+	// confirms the callback executes against the live detector instance
+	// without panicking across a Reset()/Init() cycle.
+	if before == "" || after == "" {
+		t.Error("expvar racedetector_races_detected returned an empty value")
+	}
+}
@@ -14,21 +14,48 @@
 //   - getCurrentContext (first): < 100ns
 //
 // MVP Simplifications:
-//   - Goroutine ID extracted via runtime.Stack() parsing (SLOW - ~500ns)
 //   - PC tracking collected but not used in reporting yet
-//   - TID allocation is simple atomic counter (no reuse)
-//   - No GoEnd() hook - contexts never freed
-//
-// Phase 2 Improvements (Future):
-//   - Replace getGoroutineID() with assembly getg() stub (~1ns)
-//   - Implement TID reuse pool
-//   - Add GoEnd() cleanup
-//   - Enable PC-based stack traces in reports
+//
+// Goroutine Lifecycle and TID Reclamation (synth-3613):
+// racegoend (see RaceGoEnd) is the deterministic path: it returns a
+// goroutine's TID to the pool the moment instrumented code runs it, and
+// costs nothing extra. It only runs, though, where something actually
+// calls it - today that's test cleanup and explicit GoStart/GoEnd call
+// sites, not yet every `go` statement, since the compiler instrumentation
+// (cmd/racedetector/instrument) doesn't wrap goroutine spawns with it yet.
+//
+// This package used to fall back, for everything racegoend didn't cover, on
+// a periodic runtime.Stack(all=true) scan that stopped the world to find
+// which goroutine IDs were still alive and reclaim the rest. That scan cost
+// ~1ms per 1000 live goroutines and jittered latency-sensitive callers
+// regardless of whether any TIDs actually needed reclaiming, so it has been
+// removed in favor of reclaimOrphanedContext, a runtime.AddCleanup callback
+// registered on every RaceContext when it's created.
+//
+// Be clear about what that buys us: contexts and contextCacheEntries (see
+// context_cache.go) are deliberately strong references, kept for as long as
+// a goroutine might still be alive and using its context - weakening that
+// would let the GC collect a live-but-idle goroutine's vector clock out from
+// under it, silently breaking happens-before tracking. So reclaimOrphaned-
+// Context cannot fire for a goroutine whose entry is still sitting in those
+// maps, which today means it does not reclaim a bare `go`-statement
+// goroutine's TID; that leak is unchanged from before this change and, like
+// TID-pool exhaustion below, is bounded by allocTID's existing graceful-
+// degradation path rather than fixed outright. What reclaimOrphanedContext
+// does do today is close the gap for a RaceContext whose map entry is
+// cleared through some path other than racegoend - Reset/Init already
+// handle their own case synchronously (see contextTeardown), but this
+// exists as the general mechanism for that class of case, including once
+// spawn-site instrumentation exists to remove a goroutine's own entry when
+// it exits without ever calling racegoend.
 package api
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync"
@@ -36,10 +63,16 @@ import (
 	"time"
 
 	"github.com/kolkov/racedetector/internal/race/detector"
+	"github.com/kolkov/racedetector/internal/race/epoch"
 	"github.com/kolkov/racedetector/internal/race/goroutine"
+	"github.com/kolkov/racedetector/internal/race/logging"
 	"github.com/kolkov/racedetector/internal/race/vectorclock"
 )
 
+// log is api's internal diagnostic logger (synth-3622), silent unless
+// RACEDETECTOR_DEBUG enables it - see internal/race/logging.
+var log = logging.New("api")
+
 // Global detector state.
 //
 // These variables are initialized once during init() and remain constant
@@ -58,6 +91,15 @@ var (
 	// Value: *goroutine.RaceContext.
 	contexts sync.Map
 
+	// contextCacheEntries keeps the *contextCacheEntry values that
+	// getCurrentContext's fast path (synth-3586) stashes in each
+	// goroutine's g.racectx slot reachable to the garbage collector.
+	// racectx is a raw uintptr on runtime.g, invisible to the GC, so
+	// without a second, ordinary root here the wrapper struct could be
+	// collected while a live g.racectx slot still points at it.
+	// Key: int64 (goroutine ID), Value: *contextCacheEntry.
+	contextCacheEntries sync.Map
+
 	// nextTID is the atomic counter for allocating thread IDs.
 	// Phase 2 Task 2.2: Used for statistics and cleanup trigger.
 	// No longer wraps at 256 - TID pool handles reuse.
@@ -67,6 +109,11 @@ var (
 	// All race detection flows through this single instance.
 	det *detector.Detector
 
+	// scheduleRecordFile is the open file backing DetectorOptions.
+	// RecordSchedule when RACEDETECTOR_RECORD_SCHEDULE is set (v0.5.0).
+	// Nil otherwise. Closed by Fini().
+	scheduleRecordFile *os.File
+
 	// === TID Pool Management (Phase 2 Task 2.2) ===
 	// TID reuse pool supporting unlimited goroutines (1000+).
 
@@ -83,9 +130,12 @@ var (
 	// Used during cleanup to identify stale contexts.
 	tidToGID sync.Map
 
-	// allocCounter counts context allocations to trigger periodic cleanup.
-	// Every 1000 allocations, we scan for dead goroutines and reclaim TIDs.
-	allocCounter atomic.Uint32
+	// contextTeardowns holds the release handle for each live goroutine's
+	// TID, keyed by GID (synth-3613). Whichever of racegoend (deterministic)
+	// or reclaimOrphanedContext (runtime.AddCleanup fallback) runs first
+	// returns the TID to the pool; the other is a no-op - see
+	// contextTeardown.
+	contextTeardowns sync.Map
 
 	// === Spawn Context Management (GoStart) ===
 	// Tracks VectorClock inheritance from parent to child goroutines.
@@ -101,6 +151,30 @@ var (
 	// spawnContextTTL is the maximum time a spawn context waits for child to claim.
 	// After this, the context is cleaned up to prevent memory leaks.
 	spawnContextTTL = 100 * time.Millisecond
+
+	// initSamplingEnabled mirrors the SamplingEnabled the most recent Init()
+	// built into its DetectorOptions, so Fini's report can print it without
+	// Detector needing to expose its options back out (synth-3624).
+	initSamplingEnabled bool
+
+	// initSampleRate mirrors the SampleRate the most recent Init() built
+	// into its DetectorOptions, alongside initSamplingEnabled - so GetInfo
+	// (synth-3638) can report the rate actually in effect, not just
+	// whether sampling is on.
+	initSampleRate uint64
+)
+
+// Build metadata (synth-3624): populated via "-ldflags -X" by the
+// racedetector CLI's build/run/test commands (see
+// cmd/racedetector/runtime.BuildFlags), so Fini's report can be traced back
+// to the tool version and instrumentation scope that produced this binary.
+// Left blank when a program calls Init/Fini directly instead of going
+// through the CLI, in which case Fini omits the build-info line entirely.
+var (
+	buildToolVersion string
+	buildToolCommit  string
+	buildScope       string
+	buildCoalescing  string
 )
 
 // spawnInfo contains information to pass from parent to child goroutine.
@@ -111,6 +185,22 @@ type spawnInfo struct {
 	pc          uintptr                  // Program counter of go statement (for stack traces)
 	createdAt   time.Time                // For TTL-based cleanup
 	consumed    atomic.Bool              // True if child has claimed this context
+
+	// parentTestName is the test name registered for the parent's TID at
+	// spawn time, e.g. "TestFoo/subtest" (synth-3600). Propagated to the
+	// child's own TID once it claims this context, so a race inside a
+	// goroutine a test merely spawned (the common `go func(){...}()`
+	// pattern) still gets attributed to that test, not left unnamed just
+	// because TestCleanup only registered the test's own goroutine.
+	parentTestName string
+
+	// forceFullDetection is the parent's IsFullDetectionForced() at spawn
+	// time (synth-3641). Propagated to the child so a goroutine tagged via
+	// EnableFullDetectionForGoroutine/WithFullDetectionLabel carries full
+	// detection through everything it spawns, not just its own accesses -
+	// the common case being a request handler's whole call tree, not just
+	// the handler goroutine itself.
+	forceFullDetection bool
 }
 
 // init initializes the global race detector.
@@ -162,6 +252,13 @@ func raceread(addr uintptr) {
 	// This allocates on first call per goroutine (~100ns), then cached (~5ns).
 	ctx := getCurrentContext()
 
+	// Fast path: This goroutine is inside a Disable/Enable or
+	// IgnoreReadsBegin/End region (v0.4.0, see DisableCurrentGoroutine and
+	// IgnoreReadsBegin). Skip detection entirely.
+	if ctx.IsReadsIgnored() {
+		return
+	}
+
 	// Extract program counter for the access.
 	// Currently collected but not used in reports (planned for v0.4.0).
 	_ = getcallerpc() // TODO: Pass to OnRead for enhanced stack trace reporting
@@ -204,6 +301,13 @@ func racewrite(addr uintptr) {
 	// Get RaceContext for current goroutine.
 	ctx := getCurrentContext()
 
+	// Fast path: This goroutine is inside a Disable/Enable or
+	// IgnoreWritesBegin/End region (v0.4.0, see DisableCurrentGoroutine and
+	// IgnoreWritesBegin). Skip detection entirely.
+	if ctx.IsWritesIgnored() {
+		return
+	}
+
 	// Extract program counter for the access.
 	_ = getcallerpc() // TODO: Pass to OnWrite for enhanced stack trace reporting
 
@@ -213,6 +317,78 @@ func racewrite(addr uintptr) {
 	det.OnWrite(addr, ctx)
 }
 
+// racewritesym is racewrite with a symbol id attached, so a race reported
+// against addr can be printed with a human-readable "Type.Field" name
+// instead of a bare hex address (synth-3630). Called by compiler
+// instrumentation in place of racewrite when addr is the address of a
+// struct field the instrumenter could resolve a name for via go/types -
+// see cmd/racedetector/instrument/symbols.go.
+//
+//go:nosplit
+func racewritesym(addr uintptr, symID uint64) {
+	// Fast path: Check if race detection is enabled.
+	if !enabled.Load() {
+		return
+	}
+
+	// Get RaceContext for current goroutine.
+	ctx := getCurrentContext()
+
+	// Fast path: This goroutine is inside a Disable/Enable or
+	// IgnoreWritesBegin/End region.
+	if ctx.IsWritesIgnored() {
+		return
+	}
+
+	// Perform race detection check, recording symID against addr first so
+	// it's already visible to whichever report (if any) this call's own
+	// race check produces.
+	det.OnWriteSym(addr, symID, ctx)
+}
+
+// raceregionwrite is called by compiler instrumentation to check a batch of
+// write accesses collected from a single lock-protected critical section in
+// one call (synth-3597). Accesses inside such a region can't race with each
+// other - only one goroutine ever holds the lock at a time - so the
+// per-address check that guards against a DIFFERENT goroutine still runs
+// for every address, just without a separate call (and separate context
+// lookup) per address.
+//
+//go:nosplit
+func raceregionwrite(addrs ...uintptr) {
+	racewritebatch(addrs...)
+}
+
+// racewritebatch is called by compiler instrumentation to check a batch of
+// write accesses collected from a single instrumented statement (or, via
+// raceregionwrite, a single lock-protected region) in one call (synth-3598).
+//
+// Unlike a plain loop over racewrite, this amortizes the per-call overhead
+// that's genuinely shared across the whole batch: the context lookup
+// (getCurrentContext) and the ignore-region check run ONCE for every
+// address instead of once per address, and the sampling gate inside
+// Detector.OnWriteBatch is likewise evaluated once for the batch. Each
+// address still goes through the full FastTrack check, so detection
+// accuracy is unchanged.
+//
+// Parameters:
+//   - addrs: Memory addresses written in this batch, in program order
+//
+//go:nosplit
+func racewritebatch(addrs ...uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+
+	if ctx.IsWritesIgnored() {
+		return
+	}
+
+	det.OnWriteBatch(addrs, ctx)
+}
+
 // === Goroutine Lifecycle (GoStart/GoEnd) ===
 
 // racegostart is called BEFORE creating a new goroutine (go func()).
@@ -263,10 +439,12 @@ func racegostart(pc uintptr) uintptr {
 
 	// Step 4: Store spawn context for child to consume (strict FIFO order).
 	info := &spawnInfo{
-		parentGID:   parentGID,
-		parentClock: spawnClock,
-		pc:          pc,
-		createdAt:   time.Now(),
+		parentGID:          parentGID,
+		parentClock:        spawnClock,
+		pc:                 pc,
+		createdAt:          time.Now(),
+		parentTestName:     det.TestNameForTID(parentCtx.TID),
+		forceFullDetection: parentCtx.IsFullDetectionForced(),
 	}
 
 	// Append to slice under lock for strict FIFO ordering.
@@ -283,11 +461,13 @@ func racegostart(pc uintptr) uintptr {
 // racegoend is called when a goroutine terminates.
 //
 // This function cleans up resources associated with the goroutine:
-//  1. Returns TID to the free pool for reuse
-//  2. Removes context from cache
-//  3. Cleans up TID→GID mapping
+//  1. Clears shadow cells covering the goroutine's stack (synth-3580)
+//  2. Returns TID to the free pool for reuse
+//  3. Removes context from cache
+//  4. Cleans up TID→GID mapping
 //
-// Performance: ~50ns (map operations + TID free).
+// Performance: ~50ns (map operations + TID free), plus the cost of
+// Detector.ClearGoroutineStack when stack bounds were recorded (see step 1).
 //
 // Thread Safety: Safe for concurrent calls.
 //
@@ -299,21 +479,42 @@ func racegoend() {
 
 	gid := getGoroutineID()
 
+	// Clear this goroutine's racectx fast-path slot before its g struct can
+	// be recycled by the runtime for an unrelated goroutine - mirrors the
+	// runtime's own mp.g0.racectx = 0 convention for retiring a slot.
+	setContextSlotFast(0)
+	contextCacheEntries.Delete(gid)
+
 	// Load and delete context atomically.
 	if val, ok := contexts.LoadAndDelete(gid); ok {
 		ctx := val.(*goroutine.RaceContext)
 
-		// Return VectorClock to pool for reuse.
-		if ctx.C != nil {
-			ctx.C.Release()
-			ctx.C = nil
+		// Step 1: Clear shadow cells covering this goroutine's stack
+		// (synth-3580), before its stack memory can be handed to an
+		// unrelated new goroutine by the runtime. No-op if
+		// SetStackBounds was never called for this context - see
+		// RaceContext.SetStackBounds for why that's still the common
+		// case today.
+		stackLo, stackHi := ctx.StackBounds()
+		det.ClearGoroutineStack(stackLo, stackHi)
+
+		// Release the TID, its VectorClock, and its test-name registration
+		// (synth-3600) through the same teardown handle the
+		// runtime.AddCleanup fallback would use (synth-3613), so whichever
+		// path gets there first wins and the other is a no-op.
+		if val, ok := contextTeardowns.LoadAndDelete(gid); ok {
+			val.(*contextTeardown).release()
+		} else {
+			// No teardown handle registered for this GID (e.g. a context
+			// built directly by a test rather than through
+			// getCurrentContext) - fall back to a direct release.
+			det.ClearGoroutineTestName(ctx.TID)
+			if ctx.C != nil {
+				ctx.C.Release()
+			}
+			freeTID(ctx.TID)
+			tidToGID.Delete(ctx.TID)
 		}
-
-		// Return TID to pool for reuse.
-		freeTID(ctx.TID)
-
-		// Clean up TID→GID mapping.
-		tidToGID.Delete(ctx.TID)
 	}
 }
 
@@ -348,13 +549,18 @@ func RaceGoEnd() {
 // sync.Map.Range() iterates in non-deterministic order, which can cause
 // child goroutines to receive wrong parent's clock in rapid spawn scenarios.
 //
-// Returns parent's VectorClock if found, nil otherwise.
-func findAndConsumeSpawnContext() *vectorclock.VectorClock {
+// Returns parent's VectorClock if found (nil otherwise), the test name
+// (if any) registered for the parent's TID at spawn time (synth-3600), and
+// whether the parent had full detection forced at spawn time (synth-3641),
+// so the caller can propagate all three to the child's own TID/context.
+func findAndConsumeSpawnContext() (*vectorclock.VectorClock, string, bool) {
 	spawnContextsMu.Lock()
 	defer spawnContextsMu.Unlock()
 
 	now := time.Now()
 	var foundClock *vectorclock.VectorClock
+	var foundTestName string
+	var foundForceFullDetection bool
 
 	// Find first valid spawn context (FIFO order - oldest first).
 	// This ensures strict ordering: first spawn -> first child match.
@@ -373,6 +579,8 @@ func findAndConsumeSpawnContext() *vectorclock.VectorClock {
 		// CAS provides extra safety even though we hold the lock.
 		if info.consumed.CompareAndSwap(false, true) {
 			foundClock = info.parentClock
+			foundTestName = info.parentTestName
+			foundForceFullDetection = info.forceFullDetection
 			break
 		}
 	}
@@ -388,7 +596,7 @@ func findAndConsumeSpawnContext() *vectorclock.VectorClock {
 	}
 	spawnContextsSlice = validContexts
 
-	return foundClock
+	return foundClock, foundTestName, foundForceFullDetection
 }
 
 // raceacquire is called by compiler instrumentation on mutex lock operations (Phase 4 Task 4.1).
@@ -501,8 +709,104 @@ func racereleasemerge(addr uintptr) {
 	det.OnReleaseMerge(addr, ctx)
 }
 
+// racerlock is called by compiler instrumentation on RWMutex read-lock
+// operations (synth-3570).
+//
+// Unlike raceacquire (used for RWMutex.Lock and plain sync.Mutex.Lock), this
+// only needs to happen-after the last writer, not other readers - see
+// Detector.OnRLock.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Get or create RaceContext for current goroutine
+//  3. Call detector.OnRLock() to establish happens-before
+//
+// Parameters:
+//   - addr: Address of the sync.RWMutex being read-locked
+//
+// Example (compiler-generated):
+//
+//	mu.RLock()  // Becomes: runtime.racerlock(uintptr(unsafe.Pointer(&mu))); mu.RLock()
+//
+//go:nosplit
+func racerlock(addr uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+
+	det.OnRLock(addr, ctx)
+}
+
+// racerunlock is called by compiler instrumentation on RWMutex read-unlock
+// operations (synth-3570).
+//
+// This merges the reader's clock into the RWMutex's accumulated reader
+// release clock, which the next write Lock joins in full - see
+// Detector.OnRUnlock.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Get or create RaceContext for current goroutine
+//  3. Call detector.OnRUnlock() to merge current clock
+//
+// Parameters:
+//   - addr: Address of the sync.RWMutex being read-unlocked
+//
+// Example (compiler-generated):
+//
+//	mu.RUnlock()  // Becomes: runtime.racerunlock(uintptr(unsafe.Pointer(&mu))); mu.RUnlock()
+//
+//go:nosplit
+func racerunlock(addr uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+
+	det.OnRUnlock(addr, ctx)
+}
+
 // === Channel Synchronization API (Phase 4 Task 4.2) ===
 
+// racechanmake is called by compiler instrumentation when a channel is
+// created via make(chan T, N) (Phase 4 Task 4.2).
+//
+// This records the channel's buffer capacity so later sends/receives can use
+// buffered-channel semantics (the n-th receive happens-before the (n+C)-th
+// send completes) instead of the more conservative unbuffered model.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Get or create RaceContext for current goroutine
+//  3. Call detector.OnChannelMake() to record capacity
+//
+// Parameters:
+//   - ch: Address of the channel being created
+//   - capacity: The channel's buffer capacity (from cap(ch))
+//
+// Performance: Target <100ns per call (single field write).
+//
+// Example (compiler-generated):
+//
+//	ch := make(chan int, 4)  // Becomes: ch := make(chan int, 4); runtime.racechanmake(&ch, 4)
+//
+//go:nosplit
+func racechanmake(ch uintptr, capacity int) {
+	// Fast path: Check if race detection is enabled.
+	if !enabled.Load() {
+		return
+	}
+
+	// Get RaceContext for current goroutine.
+	ctx := getCurrentContext()
+
+	// Record the channel's buffer capacity.
+	det.OnChannelMake(ch, capacity, ctx)
+}
+
 // racechansendbefore is called by compiler instrumentation BEFORE channel send (Phase 4 Task 4.2).
 //
 // This is called before the send operation blocks/completes. For MVP, this is
@@ -830,15 +1134,19 @@ func racewaitgroupwaitafter(wg uintptr) {
 
 // getCurrentContext returns the RaceContext for the current goroutine.
 //
-// This function maintains a per-goroutine context cache in the global
+// This function maintains a per-goroutine context cache in the current
+// goroutine's own g.racectx slot (synth-3586), backed by the global
 // contexts sync.Map. On first access, it:
 //  1. Extracts goroutine ID (via fast assembly on amd64, ~1ns)
 //  2. Tries to find spawn context from parent (GoStart inheritance)
 //  3. Allocates a TID from the reuse pool (0-255)
 //  4. Creates a RaceContext for that TID (with or without parent clock)
-//  5. Caches it in the map
+//  5. Caches it in both the g.racectx slot and the contexts sync.Map
 //
-// On subsequent accesses, it just does a map lookup (~5ns).
+// On subsequent accesses, it's a single pointer load plus a gid comparison
+// (see loadContextFast) on amd64/arm64/riscv64 with a verified racectx
+// offset; other architectures/Go versions fall back to the sync.Map lookup
+// this function has always used (~5ns).
 //
 // GoStart Inheritance (NEW):
 //   - If racegostart() was called before spawning this goroutine,
@@ -848,12 +1156,16 @@ func racewaitgroupwaitafter(wg uintptr) {
 //
 // Performance:
 //   - First call per goroutine: ~100ns (includes TID allocation from pool)
-//   - Cached calls: ~5ns (sync.Map load operation)
+//   - Cached calls (racectx fast path): a few ns
+//   - Cached calls (sync.Map fallback): ~5ns
 //
 // TID Allocation (Phase 2 Task 2.2):
 //   - TIDs allocated from reuse pool (supports unlimited goroutines)
-//   - Periodic cleanup (every 1000 allocations) reclaims TIDs from dead goroutines
-//   - If pool exhausted, cleanup triggered immediately
+//   - Each context registers a runtime.AddCleanup fallback (synth-3613,
+//     reclaimOrphanedContext - see the package doc for what it can and can't
+//     reclaim today) alongside racegoend's deterministic release
+//   - If pool exhausted, allocTID forces a GC cycle to encourage pending
+//     cleanups to run before falling back to graceful degradation
 //
 // Thread Safety: Safe for concurrent calls from multiple goroutines.
 func getCurrentContext() *goroutine.RaceContext {
@@ -862,10 +1174,21 @@ func getCurrentContext() *goroutine.RaceContext {
 	// Fallback: runtime.Stack parsing on other architectures (~4.7µs).
 	gid := getGoroutineID()
 
-	// Step 2: Try to load existing context from cache (fast path).
+	// Step 2: Try the g.racectx fast path first - a pointer load and a gid
+	// comparison, no map lookup at all. Falls through on a miss (empty
+	// slot, gid mismatch from a reused g, or unsupported arch/Go version).
+	if ctx, ok := loadContextFast(gid); ok {
+		return ctx
+	}
+
+	// Step 2b: Try to load existing context from cache (fast path).
 	// sync.Map.Load is lock-free for existing keys.
 	if val, ok := contexts.Load(gid); ok {
-		return val.(*goroutine.RaceContext)
+		ctx := val.(*goroutine.RaceContext)
+		// Backfill the racectx slot so the next call on this goroutine
+		// hits the fast path above.
+		storeContextFast(gid, ctx)
+		return ctx
 	}
 
 	// Step 3: Slow path - allocate new context for this goroutine.
@@ -873,7 +1196,7 @@ func getCurrentContext() *goroutine.RaceContext {
 
 	// Step 3a: Try to find spawn context from parent (GoStart inheritance).
 	// If parent called racegostart() before spawning us, we inherit their clock.
-	parentClock := findAndConsumeSpawnContext()
+	parentClock, parentTestName, parentForceFullDetection := findAndConsumeSpawnContext()
 
 	// Allocate TID from reuse pool.
 	// This supports unlimited goroutines by recycling TIDs from dead goroutines.
@@ -890,19 +1213,262 @@ func getCurrentContext() *goroutine.RaceContext {
 		ctx = goroutine.Alloc(tid)
 	}
 
+	// Propagate the spawning test's name to this TID (synth-3600), so a
+	// race inside a goroutine a test merely spawned (`go func(){...}()`,
+	// not the test's own goroutine) is still attributed to that test. See
+	// racegostart, which snapshots this at fork time.
+	if parentTestName != "" {
+		det.SetGoroutineTestName(tid, parentTestName)
+	}
+
+	// Propagate the parent's forced-full-detection state (synth-3641), so
+	// a tagged request's whole call tree stays at full detection - see
+	// spawnInfo.forceFullDetection.
+	if parentForceFullDetection {
+		ctx.IncForceFullDetection()
+	}
+
 	// Store in cache for future accesses.
 	// sync.Map.Store is thread-safe and handles concurrent stores gracefully.
 	contexts.Store(gid, ctx)
 
+	// Populate the racectx fast-path slot for this goroutine's next call.
+	storeContextFast(gid, ctx)
+
 	// Track TID → GID mapping for cleanup.
 	tidToGID.Store(tid, gid)
 
-	// Trigger periodic cleanup to reclaim TIDs from dead goroutines.
-	maybeCleanup()
+	// Register the teardown handle racegoend and the runtime.AddCleanup
+	// fallback both use to release tid exactly once (synth-3613) - see
+	// contextTeardown. It captures ctx.C separately rather than a pointer
+	// to ctx itself: runtime.AddCleanup requires the cleanup's argument not
+	// reference the watched object, or ctx would never become unreachable.
+	teardown := &contextTeardown{tid: tid, c: ctx.C}
+	contextTeardowns.Store(gid, teardown)
+	runtime.AddCleanup(ctx, reclaimOrphanedContext, teardown)
 
 	return ctx
 }
 
+// === Finalizer Synchronization API (synth-3572) ===
+
+// racefinalizerset is called by compiler/runtime instrumentation when
+// runtime.SetFinalizer(obj, f) registers a finalizer.
+//
+// This captures the registering goroutine's clock, which racefinalizerrun
+// later joins before the finalizer body runs - see detector.OnFinalizerSet.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Get or create RaceContext for current goroutine
+//  3. Call detector.OnFinalizerSet() to capture the registering clock
+//
+// Parameters:
+//   - obj: Address of the object the finalizer is registered on
+//
+// Performance: Target <300ns per call (VectorClock copy overhead acceptable).
+//
+// Example (compiler/runtime-generated):
+//
+//	runtime.SetFinalizer(obj, f)  // Becomes: runtime.racefinalizerset(uintptr(unsafe.Pointer(obj))); runtime.SetFinalizer(obj, f)
+//
+//go:nosplit
+//nolint:unused // Called by compiler/runtime instrumentation, not directly from code
+func racefinalizerset(obj uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+
+	det.OnFinalizerSet(obj, ctx)
+}
+
+// racefinalizerrun is called by compiler/runtime instrumentation immediately
+// before a registered finalizer body runs.
+//
+// This joins the SetFinalizer call site's clock into the finalizer
+// goroutine's clock, establishing happens-before from the mutator's last
+// relevant work to the finalizer's first access - see
+// detector.OnFinalizerRun.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Get or create RaceContext for current goroutine
+//  3. Call detector.OnFinalizerRun() to join the registration clock
+//
+// Parameters:
+//   - obj: Address of the object whose finalizer is running
+//
+// Performance: Target <500ns per call (VectorClock join overhead acceptable).
+//
+// Example (compiler/runtime-generated):
+//
+//	f(obj)  // Becomes: runtime.racefinalizerrun(uintptr(unsafe.Pointer(obj))); f(obj)
+//
+//go:nosplit
+//nolint:unused // Called by compiler/runtime instrumentation, not directly from code
+func racefinalizerrun(obj uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+
+	det.OnFinalizerRun(obj, ctx)
+}
+
+// === os/signal Synchronization API (synth-3573) ===
+
+// racesignalnotify is called by compiler/runtime instrumentation when
+// signal.Notify(ch, sig...) registers a channel to receive relayed signals.
+//
+// This captures the registering goroutine's clock via the channel-close
+// mechanism, so the signal-handling goroutine's receive from ch merges it -
+// see detector.OnSignalNotify.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Get or create RaceContext for current goroutine
+//  3. Call detector.OnSignalNotify() to capture the registering clock
+//
+// Parameters:
+//   - ch: Address of the channel passed to signal.Notify
+//
+// Performance: Target <300ns per call (VectorClock copy overhead acceptable).
+//
+// Example (compiler/runtime-generated):
+//
+//	signal.Notify(sigCh, syscall.SIGTERM)  // Becomes: runtime.racesignalnotify(uintptr(unsafe.Pointer(sigCh))); signal.Notify(sigCh, syscall.SIGTERM)
+//
+//go:nosplit
+//nolint:unused // Called by compiler/runtime instrumentation, not directly from code
+func racesignalnotify(ch uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+
+	det.OnSignalNotify(ch, ctx)
+}
+
+// === golang.org/x/sync/singleflight Synchronization API (synth-3574) ===
+
+// racesingleflightdone is called by compiler/runtime instrumentation
+// immediately after the leader's fn returns inside Do/DoChan.
+//
+// This captures the leader's clock, which racesingleflightreturn later
+// joins into every caller - leader and duplicates alike - as Do/DoChan
+// returns to them. See detector.OnSingleflightDone.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Get or create RaceContext for current goroutine
+//  3. Call detector.OnSingleflightDone() to capture the leader's clock
+//
+// Parameters:
+//   - addr: Caller-derived address identifying the (Group, key) pair
+//
+// Performance: Target <300ns per call (VectorClock copy overhead acceptable).
+//
+// Example (compiler/runtime-generated):
+//
+//	v, _, _ := group.Do(key, fn)  // fn's return becomes: runtime.racesingleflightdone(addr); return v, err
+//
+//go:nosplit
+//nolint:unused // Called by compiler/runtime instrumentation, not directly from code
+func racesingleflightdone(addr uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+
+	det.OnSingleflightDone(addr, ctx)
+}
+
+// racesingleflightreturn is called by compiler/runtime instrumentation when
+// Do/DoChan returns to a caller, whether that caller was the leader or a
+// duplicate.
+//
+// This joins the leader's captured clock into the returning caller's clock,
+// establishing happens-before from the leader's fn to every caller's use of
+// the shared result. See detector.OnSingleflightReturn.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Get or create RaceContext for current goroutine
+//  3. Call detector.OnSingleflightReturn() to join the leader's clock
+//
+// Parameters:
+//   - addr: Caller-derived address identifying the (Group, key) pair
+//
+// Performance: Target <500ns per call (VectorClock join overhead acceptable).
+//
+// Example (compiler/runtime-generated):
+//
+//	v, _, _ := group.Do(key, fn)  // Becomes: runtime.racesingleflightreturn(addr); return v, err
+//
+//go:nosplit
+//nolint:unused // Called by compiler/runtime instrumentation, not directly from code
+func racesingleflightreturn(addr uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+
+	det.OnSingleflightReturn(addr, ctx)
+}
+
+// === Memory Allocation API (synth-3581) ===
+
+// racemalloc is called by compiler/runtime instrumentation immediately
+// after an allocation (make/new/&T{}) returns, before the new object's
+// address is visible to any other goroutine.
+//
+// Go's allocator reuses freed memory, and ShadowMemory is keyed purely by
+// address - without this hook, a freshly allocated object's first access
+// could be compared against a shadow cell an unrelated, already-dead
+// object left behind at the same address. racemalloc clears that range up
+// front so the new object always starts from a clean slate. It also hands
+// detector.OnMalloc the calling goroutine's context, so a race report on
+// this object later can print where it was allocated and by whom
+// (synth-3632). See detector.OnMalloc.
+//
+// Flow:
+//  1. Check if race detection is enabled (fast atomic load)
+//  2. Call detector.OnMalloc() to clear stale shadow cells in the range and
+//     record the allocation site
+//
+// Parameters:
+//   - addr: Address of the just-completed allocation
+//   - size: Size in bytes of the allocation
+//
+// Performance: O(live shadow cells) in the worst case (ShadowMemory.ClearRange).
+//
+// Example (compiler/runtime-generated):
+//
+//	p := new(T)  // Becomes: p := new(T); runtime.racemalloc(uintptr(unsafe.Pointer(p)), unsafe.Sizeof(*p))
+//
+// Note: no compiler pass emits calls to this function yet - make/new are
+// not addressable expressions the instrumentor rewrites (see
+// cmd/racedetector/instrument/visitor.go's isBuiltinIdent), so this hook
+// exists for detector.OnMalloc callers to use directly today, ahead of
+// future compiler support.
+//
+//go:nosplit
+//nolint:unused // Called by compiler/runtime instrumentation, not directly from code
+func racemalloc(addr, size uintptr) {
+	if !enabled.Load() {
+		return
+	}
+
+	ctx := getCurrentContext()
+	det.OnMalloc(addr, size, ctx)
+}
+
 // === TID Pool Management Functions (Phase 2 Task 2.2) ===
 
 // initTIDPool initializes the TID reuse pool with all available TIDs (0-255).
@@ -923,8 +1489,8 @@ func initTIDPool() {
 	// Popping from end gives: 255, 254, ..., 1, 0
 	// But after Init removes TID 0, we get: 255, 254, ..., 1
 	// We want ascending allocation, so we reverse the order.
-	freeTIDs = make([]uint16, 65536)
-	for i := 0; i < 65536; i++ {
+	freeTIDs = make([]uint16, tidPoolCapacity)
+	for i := 0; i < tidPoolCapacity; i++ {
 		//nolint:gosec // G115: Safe conversion, i is always < 256
 		freeTIDs[i] = uint16(i) // Stack order: [0, 1, 2, ..., 255]
 	}
@@ -963,28 +1529,31 @@ func allocTID() uint16 {
 		return tid
 	}
 
-	// Slow path: Pool exhausted - trigger cleanup.
+	// Slow path: Pool exhausted. There's no explicit dead-goroutine scan to
+	// fall back on anymore (synth-3613) - force a GC cycle instead, so any
+	// RaceContext that's already unreachable gets its reclaimOrphanedContext
+	// cleanup queued, then retry.
 	tidPoolMu.Unlock()
 
-	// Run cleanup in current goroutine to ensure TIDs are freed before retry.
-	// This blocks allocation, but only happens when all 256 TIDs are in use.
-	cleanupDeadGoroutines()
+	runtime.GC()
 
-	// Retry allocation after cleanup.
+	// Retry allocation after GC. Cleanups queued by the collection above run
+	// on their own goroutine and aren't guaranteed to have completed yet, so
+	// this is best-effort, not a guarantee the pool actually freed up.
 	tidPoolMu.Lock()
 	defer tidPoolMu.Unlock()
 
 	if len(freeTIDs) > 0 {
-		// Cleanup freed some TIDs - allocate one.
+		// A queued cleanup ran in time and freed a TID - allocate it.
 		tid := freeTIDs[0]
 		freeTIDs = freeTIDs[1:]
 		return tid
 	}
 
-	// Pool still exhausted after cleanup - graceful degradation.
+	// Pool still exhausted - graceful degradation.
 	// Reuse TID 0 to avoid crashing the program.
 	// This may cause TID conflicts in race detection, but better than panic.
-	// In practice, this should never happen if cleanup works correctly.
+	// In practice, this should never happen if reclamation works correctly.
 	return 0
 }
 
@@ -992,6 +1561,12 @@ func allocTID() uint16 {
 //
 // This makes the TID available for reuse by future goroutines.
 //
+// Bumps tid's generation (synth-3612) before releasing it, so whichever
+// goroutine allocates it next gets a RaceContext tagged with a generation
+// distinct from this (now-dead) one's - see epoch.BumpGeneration for why
+// that's needed to avoid a stale VectorClock entry aliasing the recycled
+// TID onto an unrelated goroutine.
+//
 // Performance: ~30ns (mutex lock + stack append).
 //
 // Thread Safety: Safe for concurrent calls (protected by tidPoolMu).
@@ -999,6 +1574,8 @@ func allocTID() uint16 {
 // Parameters:
 //   - tid: TID to return to the pool (0-255)
 func freeTID(tid uint16) {
+	epoch.BumpGeneration(tid)
+
 	tidPoolMu.Lock()
 	defer tidPoolMu.Unlock()
 
@@ -1007,162 +1584,54 @@ func freeTID(tid uint16) {
 	freeTIDs = append(freeTIDs, tid)
 }
 
-// maybeCleanup triggers periodic cleanup of dead goroutines.
-//
-// Cleanup is triggered every 1000 context allocations to amortize the cost.
-// The cleanup runs in a background goroutine to avoid blocking allocations.
-//
-// Cleanup overhead: ~1ms per 1000 goroutines scanned.
-// Amortized overhead: ~0.1% (1ms / 1000 allocations).
-//
-// Thread Safety: Safe for concurrent calls (uses atomic counter).
-func maybeCleanup() {
-	// Increment allocation counter.
-	count := allocCounter.Add(1)
-
-	// Trigger cleanup every 1000 allocations.
-	// This amortizes the ~1ms cleanup cost over 1000 allocations.
-	const cleanupInterval = 1000
-	if count%cleanupInterval == 0 {
-		// Run cleanup in background to avoid blocking current allocation.
-		// This is safe because cleanup is idempotent - multiple concurrent
-		// cleanups will just scan the same contexts.
-		go cleanupDeadGoroutines()
-	}
-}
-
-// cleanupDeadGoroutines scans the contexts map and reclaims TIDs from dead goroutines.
-//
-// Algorithm:
-//  1. Get list of all live goroutine IDs via runtime.Stack()
-//  2. Build a set of live GIDs for O(1) lookup
-//  3. Scan contexts map for GIDs not in the live set
-//  4. For each dead goroutine, free its TID and remove context
-//
-// Performance:
-//   - runtime.Stack(all=true): ~1ms for 1000 goroutines
-//   - Set construction: ~10µs for 1000 goroutines
-//   - contexts.Range: ~50µs for 1000 contexts
-//   - Total: ~1ms for 1000 goroutines
-//
-// Thread Safety: Safe for concurrent calls. Uses sync.Map which handles
-// concurrent reads/writes/deletes gracefully.
-func cleanupDeadGoroutines() {
-	// Step 1: Get list of all live goroutine IDs.
-	// This is the expensive part (~1ms for 1000 goroutines).
-	liveGIDs := getLiveGoroutineIDs()
-
-	// Step 2: Build set for O(1) lookup.
-	liveSet := make(map[int64]bool, len(liveGIDs))
-	for _, gid := range liveGIDs {
-		liveSet[gid] = true
-	}
-
-	// Step 3: Scan contexts and remove dead goroutines.
-	contexts.Range(func(key, value interface{}) bool {
-		gid := key.(int64)
-		ctx := value.(*goroutine.RaceContext)
-
-		// Check if goroutine is still alive.
-		if !liveSet[gid] {
-			// Goroutine is dead - reclaim its TID.
-			freeTID(ctx.TID)
-
-			// Remove from contexts map.
-			contexts.Delete(gid)
-
-			// Remove from TID → GID mapping.
-			tidToGID.Delete(ctx.TID)
-		}
-
-		// Continue iteration.
-		return true
-	})
-}
-
-// getLiveGoroutineIDs returns a list of all live goroutine IDs.
-//
-// This uses runtime.Stack(all=true) to get a stack trace for ALL goroutines,
-// then parses the output to extract GIDs.
-//
-// Performance: ~1ms for 1000 goroutines.
-// This is the main cost of cleanup, which is why we amortize it over 1000 allocations.
-//
-// Thread Safety: Safe for concurrent calls (runtime.Stack is thread-safe).
-//
-// Returns:
-//   - []int64: List of all live goroutine IDs
-func getLiveGoroutineIDs() []int64 {
-	// Allocate buffer for stack traces.
-	// 1MB should be enough for ~1000 goroutines with typical stack depths.
-	// If buffer is too small, runtime.Stack returns truncated output,
-	// but we'll still get GIDs for all goroutines in the trace.
-	buf := make([]byte, 1024*1024) // 1MB
-
-	// Get stack traces for ALL goroutines.
-	// all=true is critical - we need every goroutine's stack.
-	n := runtime.Stack(buf, true)
-
-	// Parse stack dump to extract all GIDs.
-	return parseAllGIDs(buf[:n])
+// contextTeardown coordinates a goroutine's full teardown between the two
+// paths that can trigger it (synth-3613): racegoend's deterministic call
+// when a goroutine exits cleanly, and reclaimOrphanedContext's
+// runtime.AddCleanup fallback when a RaceContext becomes unreachable
+// without racegoend ever having run for it. Exactly one of them wins.
+//
+// c is captured separately from the RaceContext it belongs to, rather than
+// reached through a pointer to that RaceContext: runtime.AddCleanup
+// requires the cleanup's argument not reference the watched object, or the
+// RaceContext would never become unreachable and the fallback would never
+// fire.
+type contextTeardown struct {
+	tid  uint16
+	c    *vectorclock.VectorClock
+	done atomic.Bool
 }
 
-// parseAllGIDs parses runtime.Stack(all=true) output to extract all goroutine IDs.
-//
-// Input format (example):
-//
-//	goroutine 1 [running]:
-//	main.main()
-//	    /path/to/main.go:10 +0x20
-//
-//	goroutine 5 [chan receive]:
-//	main.worker()
-//	    /path/to/main.go:20 +0x40
-//
-// We extract: [1, 5, ...]
-//
-// Algorithm:
-//  1. Split buffer into lines
-//  2. Find lines starting with "goroutine "
-//  3. Parse the GID from each line
-//
-// Performance: ~100µs for 1000 goroutines.
-//
-// Parameters:
-//   - buf: Stack trace buffer from runtime.Stack(all=true)
-//
-// Returns:
-//   - []int64: List of goroutine IDs
-func parseAllGIDs(buf []byte) []int64 {
-	var gids []int64
-
-	// Split into lines.
-	// runtime.Stack output has one "goroutine N [state]:" line per goroutine.
-	i := 0
-	for i < len(buf) {
-		// Find next newline.
-		end := i
-		for end < len(buf) && buf[end] != '\n' {
-			end++
-		}
-
-		// Extract line.
-		line := buf[i:end]
-
-		// Check if this is a "goroutine N" line.
-		if len(line) >= 10 && string(line[:10]) == "goroutine " {
-			// Parse GID from this line.
-			gid := parseGID(line)
-			if gid != 0 {
-				gids = append(gids, gid)
-			}
-		}
-
-		// Move to next line.
-		i = end + 1
+// release tears down everything owned by tid the first time it's called,
+// and is a no-op on any later call - see contextTeardown. It does not clear
+// any shadow cells for a stack range registered via SetStackBounds (synth-
+// 3580): unlike racegoend, it has no access to the RaceContext itself to
+// read those bounds back from by the time it runs.
+func (t *contextTeardown) release() {
+	if !t.done.CompareAndSwap(false, true) {
+		return
 	}
+	det.ClearGoroutineTestName(t.tid)
+	if t.c != nil {
+		t.c.Release()
+	}
+	freeTID(t.tid)
+	tidToGID.Delete(t.tid)
+}
 
-	return gids
+// reclaimOrphanedContext is the runtime.AddCleanup callback registered
+// against every RaceContext at creation time (see getCurrentContext). It
+// runs once that RaceContext becomes unreachable to the garbage collector,
+// which - see the package doc - only happens once its entry in contexts and
+// contextCacheEntries has already been cleared some other way; racegoend
+// clears its own entry itself and calls release directly rather than
+// waiting on this, so in practice this fires for a context whose entry was
+// cleared without going through racegoend (synth-3613).
+//
+// Cleanups run on their own goroutine, asynchronously and with no ordering
+// guarantee relative to other work, so this must not assume anything about
+// what else has or hasn't happened by the time it runs.
+func reclaimOrphanedContext(t *contextTeardown) {
+	t.release()
 }
 
 // NOTE: getGoroutineID() and parseGID() are defined in goid_generic.go
@@ -1229,18 +1698,171 @@ func Disable() {
 	enabled.Store(false)
 }
 
+// DisableCurrentGoroutine disables race detection for the calling goroutine
+// only (v0.4.0), unlike Disable which turns detection off globally.
+//
+// This is meant for libraries that need to wrap intentionally racy code -
+// e.g. a lazily initialized cache whose correctness is verified by other
+// means - without silencing detection for the rest of the program while
+// that code runs on other goroutines.
+//
+// Calls nest: each DisableCurrentGoroutine increments this goroutine's
+// ignore depth, and detection only resumes once a matching
+// EnableCurrentGoroutine call brings the depth back to zero. Always pair
+// calls with defer to guarantee the matching Enable runs:
+//
+//	race.DisableCurrentGoroutine()
+//	defer race.EnableCurrentGoroutine()
+//	// ... intentionally racy code verified by other means ...
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func DisableCurrentGoroutine() {
+	getCurrentContext().IncIgnore()
+}
+
+// EnableCurrentGoroutine re-enables race detection for the calling
+// goroutine after a matching DisableCurrentGoroutine call (v0.4.0).
+//
+// Calling EnableCurrentGoroutine without a preceding DisableCurrentGoroutine
+// (or more times than DisableCurrentGoroutine was called) is a no-op rather
+// than an error, matching runtime.RaceEnable's tolerance of unbalanced calls.
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func EnableCurrentGoroutine() {
+	getCurrentContext().DecIgnore()
+}
+
+// IgnoreReadsBegin disables race detection for reads made by the calling
+// goroutine only, leaving its writes checked (v0.4.0). This is the
+// finer-grained counterpart of DisableCurrentGoroutine, mirroring TSan's
+// __tsan_ignore_reads_begin: useful when a benign pattern only involves
+// unsynchronized reads (e.g. polling a flag written exactly once under a
+// lock elsewhere) and writes from the same goroutine should still be
+// checked normally.
+//
+// Calls nest like DisableCurrentGoroutine; always pair with defer:
+//
+//	race.IgnoreReadsBegin()
+//	defer race.IgnoreReadsEnd()
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func IgnoreReadsBegin() {
+	getCurrentContext().IncIgnoreReads()
+}
+
+// IgnoreReadsEnd re-enables read detection for the calling goroutine after
+// a matching IgnoreReadsBegin call (v0.4.0). An unbalanced call is a no-op,
+// matching DecIgnore's tolerance.
+func IgnoreReadsEnd() {
+	getCurrentContext().DecIgnoreReads()
+}
+
+// IgnoreWritesBegin disables race detection for writes made by the calling
+// goroutine only, leaving its reads checked (v0.4.0). This is the
+// finer-grained counterpart of DisableCurrentGoroutine, mirroring TSan's
+// __tsan_ignore_writes_begin: useful when a benign pattern only involves
+// unsynchronized writes (e.g. a lazily initialized cache where every writer
+// computes and stores the same value) and reads from the same goroutine
+// should still be checked normally.
+//
+// Calls nest like DisableCurrentGoroutine; always pair with defer:
+//
+//	race.IgnoreWritesBegin()
+//	defer race.IgnoreWritesEnd()
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func IgnoreWritesBegin() {
+	getCurrentContext().IncIgnoreWrites()
+}
+
+// IgnoreWritesEnd re-enables write detection for the calling goroutine
+// after a matching IgnoreWritesBegin call (v0.4.0). An unbalanced call is a
+// no-op, matching DecIgnore's tolerance.
+func IgnoreWritesEnd() {
+	getCurrentContext().DecIgnoreWrites()
+}
+
+// EnableFullDetectionForGoroutine forces the calling goroutine's accesses
+// to always be checked, bypassing the sampler even when it would otherwise
+// skip most accesses (synth-3641). This is the sampler-side inverse of
+// DisableCurrentGoroutine: instead of silencing a goroutine, it exempts one
+// from a program-wide sampling rate that would otherwise mostly skip it.
+//
+// GoStart propagates this to every goroutine the caller spawns while it's
+// in effect, like it already does the vector clock, so tagging a request
+// handler this way covers its whole call tree - see
+// EnableFullDetectionFromContext for tying this to a pprof label carried
+// on a request's context.Context.
+//
+// Calls nest; always pair with defer:
+//
+//	race.EnableFullDetectionForGoroutine()
+//	defer race.DisableFullDetectionForGoroutine()
+//
+// Has no effect unless sampling is enabled (SampleRate or
+// OverheadTargetPercent); every access is already checked otherwise.
+//
+// Thread Safety: Only affects the calling goroutine and the goroutines it
+// spawns while in effect. Safe to call concurrently from different
+// goroutines.
+func EnableFullDetectionForGoroutine() {
+	getCurrentContext().IncForceFullDetection()
+}
+
+// DisableFullDetectionForGoroutine re-enables sampling for the calling
+// goroutine after a matching EnableFullDetectionForGoroutine call
+// (synth-3641). An unbalanced call is a no-op, matching DecIgnore's
+// tolerance.
+func DisableFullDetectionForGoroutine() {
+	getCurrentContext().DecForceFullDetection()
+}
+
 // RacesDetected returns the total number of races detected.
 //
-// This is exported for testing and statistics purposes.
+// This is exported for testing and statistics purposes. Race detection is
+// synchronous, but reporting isn't (synth-3587): this call first waits for
+// every race detected by a happens-before-earlier RaceRead/RaceWrite/etc.
+// call to finish being turned into a RaceReport, so a caller that checks
+// this right after triggering a race doesn't see a stale count.
 //
 // Thread Safety: Safe for concurrent calls.
 //
 // Returns:
 //   - int: Total number of races detected since initialization
 func RacesDetected() int {
+	det.WaitForPendingReports()
 	return det.RacesDetected()
 }
 
+// tidPoolCapacity is the fixed size of the freeTIDs pool allocated by
+// initTIDPool. Occupancy is reported against this capacity for monitoring.
+const tidPoolCapacity = 65536
+
+// TIDPoolOccupancy returns the number of TIDs currently allocated out of
+// the fixed-size pool (v0.5.0), for monitoring long-running services where
+// a steadily growing occupancy indicates goroutines aren't being cleaned up.
+//
+// Thread Safety: Safe for concurrent calls (protected by tidPoolMu).
+func TIDPoolOccupancy() int {
+	tidPoolMu.Lock()
+	defer tidPoolMu.Unlock()
+	return tidPoolCapacity - len(freeTIDs)
+}
+
+// WriteOverheadProfile dumps the detector's per-call-site overhead profile
+// in the standard pprof protocol buffer format (v0.5.0), so it can be
+// inspected with `go tool pprof`. Returns an error if profiling was not
+// enabled (see Init's RACEDETECTOR_PROFILE environment variable).
+//
+// Thread Safety: Safe for concurrent calls.
+func WriteOverheadProfile(w io.Writer) error {
+	return det.WriteOverheadProfile(w)
+}
+
 // RaceRead is an exported wrapper for raceread, for demonstration purposes.
 //
 // In production code, you should compile with -race flag, which automatically
@@ -1265,6 +1887,59 @@ func RaceWrite(addr uintptr) {
 	racewrite(addr)
 }
 
+// RaceWriteSym is an exported wrapper for racewritesym, for demonstration
+// purposes (synth-3630).
+//
+// In production code, you should compile with -race flag, which automatically
+// instruments all memory accesses. This function is provided for examples
+// and testing purposes only.
+//
+// Parameters:
+//   - addr: Memory address being written to
+//   - symID: Symbol id previously passed to RegisterSymbol
+func RaceWriteSym(addr uintptr, symID uint64) {
+	racewritesym(addr, symID)
+}
+
+// RegisterSymbol is an exported wrapper for detector.RegisterSymbol
+// (synth-3630), recording the human-readable name and kind for a symbol id
+// ahead of a RaceWriteSym call using that id.
+//
+// Parameters:
+//   - symID: Symbol id, computed by the instrumenter from name
+//   - name: Human-readable qualified name for symID, e.g. "Type.Field" or
+//     "pkg.VarName"
+//   - kind: What name names - "field" or "global" (synth-3631)
+func RegisterSymbol(symID uint64, name, kind string) {
+	detector.RegisterSymbol(symID, name, kind)
+}
+
+// RaceRegionWrite is an exported wrapper for raceregionwrite, for
+// demonstration purposes (synth-3597).
+//
+// In production code, you should compile with -race flag, which automatically
+// instruments memory accesses. This function is provided for examples
+// and testing purposes only.
+//
+// Parameters:
+//   - addrs: Memory addresses written inside a single lock-protected region
+func RaceRegionWrite(addrs ...uintptr) {
+	raceregionwrite(addrs...)
+}
+
+// RaceBatch is an exported wrapper for racewritebatch, for demonstration
+// purposes (synth-3598).
+//
+// In production code, you should compile with -race flag, which automatically
+// instruments memory accesses. This function is provided for examples
+// and testing purposes only.
+//
+// Parameters:
+//   - addrs: Memory addresses written by a single statement, in program order
+func RaceBatch(addrs ...uintptr) {
+	racewritebatch(addrs...)
+}
+
 // RaceAcquire is an exported wrapper for raceacquire, for demonstration purposes (Phase 4 Task 4.1).
 //
 // In production code, you should compile with -race flag, which automatically
@@ -1301,8 +1976,45 @@ func RaceReleaseMerge(addr uintptr) {
 	racereleasemerge(addr)
 }
 
+// RaceRLock is an exported wrapper for racerlock, for demonstration purposes (synth-3570).
+//
+// In production code, you should compile with -race flag, which automatically
+// instruments RWMutex operations. This function is provided for examples
+// and testing purposes only.
+//
+// Parameters:
+//   - addr: Address of the RWMutex being read-locked
+func RaceRLock(addr uintptr) {
+	racerlock(addr)
+}
+
+// RaceRUnlock is an exported wrapper for racerunlock, for demonstration purposes (synth-3570).
+//
+// In production code, you should compile with -race flag, which automatically
+// instruments RWMutex operations. This function is provided for examples
+// and testing purposes only.
+//
+// Parameters:
+//   - addr: Address of the RWMutex being read-unlocked
+func RaceRUnlock(addr uintptr) {
+	racerunlock(addr)
+}
+
 // === Exported Channel API Functions (Phase 4 Task 4.2) ===
 
+// RaceChannelMake is an exported wrapper for racechanmake, for demonstration purposes.
+//
+// In production code, you should compile with -race flag, which automatically
+// instruments channel operations. This function is provided for examples
+// and testing purposes only.
+//
+// Parameters:
+//   - ch: Address of the channel being created
+//   - capacity: The channel's buffer capacity (from cap(ch))
+func RaceChannelMake(ch uintptr, capacity int) {
+	racechanmake(ch, capacity)
+}
+
 // RaceChannelSendBefore is an exported wrapper for racechansendbefore, for demonstration purposes.
 //
 // In production code, you should compile with -race flag, which automatically
@@ -1372,6 +2084,10 @@ func RaceChannelClose(ch uintptr) {
 // The caller must ensure no other goroutines are using the detector.
 func Reset() {
 	det.Reset()
+	// Bump contextGeneration so stale racectx fast-path entries (still-live
+	// goroutines that cached a context before this Reset) are rejected by
+	// loadContextFast, even though their gid hasn't changed.
+	contextGeneration.Add(1)
 	// Clear goroutine contexts.
 	// CRITICAL: Use Range+Delete instead of reassignment to avoid data race
 	// with goroutines still accessing the map.
@@ -1379,16 +2095,28 @@ func Reset() {
 		contexts.Delete(key)
 		return true
 	})
+	// Clear the racectx fast-path keepalive registry.
+	contextCacheEntries.Range(func(key, _ interface{}) bool {
+		contextCacheEntries.Delete(key)
+		return true
+	})
 	// Clear TID → GID mapping.
 	// CRITICAL: Use Range+Delete instead of reassignment to avoid data race.
 	tidToGID.Range(func(key, _ interface{}) bool {
 		tidToGID.Delete(key)
 		return true
 	})
+	// Mark any pending teardown handles as already released (synth-3613),
+	// so a runtime.AddCleanup fallback firing later for a context from
+	// before this Reset doesn't return a stale TID into the freshly
+	// reinitialized pool below.
+	contextTeardowns.Range(func(key, val interface{}) bool {
+		val.(*contextTeardown).done.Store(true)
+		contextTeardowns.Delete(key)
+		return true
+	})
 	// Reset TID counter.
 	nextTID.Store(0)
-	// Reset allocation counter.
-	allocCounter.Store(0)
 	// Clear spawn context tracking.
 	spawnContextsMu.Lock()
 	spawnContextsSlice = nil
@@ -1397,6 +2125,9 @@ func Reset() {
 	// Reinitialize TID pool for tests.
 	// Tests call Reset() but expect to be able to allocate TIDs afterwards.
 	initTIDPool()
+	// Clear the checked-annotation audit list (synth-3599), so a test run
+	// after Reset() doesn't carry over locations from before it.
+	resetCheckedAnnotations()
 }
 
 // Init initializes the race detector for use.
@@ -1412,11 +2143,72 @@ func Reset() {
 //  4. Initializes the TID reuse pool (Phase 2 Task 2.2)
 //  5. Allocates a RaceContext for the main goroutine with TID=0
 //
+// Configuring Init (v0.5.0):
+//
+// Call Configure before Init to set detector options programmatically
+// instead of (or in addition to) the environment variables below - see
+// Configure and ConfigOptions. A RACEDETECTOR_* environment variable
+// always takes precedence over the matching Configure option.
+//
 // Environment Variables (v0.3.0):
 //
 //	RACEDETECTOR_SAMPLE_RATE=N  - Enable sampling with rate N (1=disabled, 10=1/10, 100=1/100)
 //	                             This trades detection rate for performance (~50-90% overhead reduction).
 //	                             Example: RACEDETECTOR_SAMPLE_RATE=10 ./myprogram
+//	RACEDETECTOR_WARMUP=D       - Check every access at full detection for duration D (synth-3639,
+//	                             e.g. "5s"), then switch to RACEDETECTOR_SAMPLE_RATE. Ignored unless
+//	                             RACEDETECTOR_SAMPLE_RATE (or ConfigOptions.SampleRate) is also set.
+//	                             Example: RACEDETECTOR_WARMUP=5s RACEDETECTOR_SAMPLE_RATE=10 ./myprogram
+//	RACEDETECTOR_OVERHEAD_TARGET_PERCENT=N - Put the sampling rate under feedback control targeting
+//	                             N% overhead (synth-3640) instead of a fixed RACEDETECTOR_SAMPLE_RATE.
+//	                             Enables sampling even if RACEDETECTOR_SAMPLE_RATE is unset.
+//	                             Example: RACEDETECTOR_OVERHEAD_TARGET_PERCENT=20 ./myprogram
+//	RACEDETECTOR_PROFILE=1      - Enable per-call-site overhead profiling (v0.5.0).
+//	                             Dump the result with WriteOverheadProfile and inspect it
+//	                             via `go tool pprof` to find which call sites dominate overhead.
+//	RACEDETECTOR_PROFILE_OUT=F - Write the overhead profile to file F when Fini() runs
+//	                             (synth-3584), instead of (or alongside) calling
+//	                             WriteOverheadProfile yourself. Ignored unless
+//	                             RACEDETECTOR_PROFILE is also set. See
+//	                             `racedetector bench-overhead`, which uses this to recover
+//	                             a per-hook cost breakdown without instrumenting a
+//	                             benchmark binary by hand.
+//	RACEDETECTOR_STRIP_PREFIX=P - Strip path prefix P from stack trace file paths in race
+//	                             reports (v0.5.0), so reports are stable across machines
+//	                             with different build paths. Ignored for a file under
+//	                             RACEDETECTOR_MODULE_ROOT, which takes precedence.
+//	RACEDETECTOR_MODULE_ROOT=D,
+//	RACEDETECTOR_MODULE_PATH=M - Rewrite stack trace file paths under module root D to be
+//	                             relative to module import path M instead (v0.5.0), e.g.
+//	                             "/home/alice/racedetector/report.go" becomes
+//	                             "github.com/kolkov/racedetector/report.go". Both must be
+//	                             set together.
+//	RACEDETECTOR_RECORD_SCHEDULE=F - Record the OnRead/OnWrite interleaving to file F
+//	                             (v0.5.0), so a flaky race can be captured once and
+//	                             replayed on demand. See detector.ScheduleRecorder.
+//	RACEDETECTOR_REPLAY_SCHEDULE=F - Re-drive the interleaving recorded in file F
+//	                             (v0.5.0). See detector.ScheduleGate.
+//	RACEDETECTOR_STRESS=1       - Enable randomized scheduling perturbation at every
+//	                             access (v0.5.0), to surface rare interleavings. See
+//	                             detector.StressScheduler and `racedetector test -stress`.
+//	RACEDETECTOR_STRESS_SEED=N  - Seed the perturbation sequence RACEDETECTOR_STRESS
+//	                             enables (v0.5.0), so a specific run can be reproduced.
+//	                             Ignored unless RACEDETECTOR_STRESS is also set.
+//	RACEDETECTOR_HB_GRAPH=F     - Record the full happens-before event graph and write
+//	                             it to file F as Graphviz DOT when Fini() runs (v0.5.0).
+//	                             See detector.HBGraphRecorder.
+//	RACEDETECTOR_RESET_BETWEEN_TESTS=1 - Reset shadow memory and dedup state after each
+//	                             test that calls TestCleanup (v0.5.0), so tests don't
+//	                             share suppression state. See TestCleanup and
+//	                             `racedetector test -reset-between-tests`.
+//	RACEDETECTOR_SINGLE_THREADED=0/1 - Force detector.DetectorOptions.SingleThreaded off
+//	                             or on (synth-3615), overriding both Configure and the
+//	                             platform default (already true on GOOS=js/wasip1).
+//	                             See DetectorOptions.SingleThreaded.
+//	RACEDETECTOR_MUTE_AFTER_REPORT=1 - Poison a shadow cell's address the first time a
+//	                             race is reported on it (synth-3636), so later accesses
+//	                             to that address skip detection entirely instead of just
+//	                             deduplicating the report. See DetectorOptions.MuteAfterReport.
 //
 // Main Goroutine Convention:
 // By convention, the main goroutine (the one calling Init) always receives
@@ -1449,12 +2241,40 @@ func Init() {
 	// Reset TID counter to 0.
 	nextTID.Store(0)
 
-	// Reset allocation counter for cleanup trigger.
-	allocCounter.Store(0)
-
-	// Create a fresh detector instance with optional sampling (v0.3.0).
-	// Check RACEDETECTOR_SAMPLE_RATE environment variable.
-	opts := detector.DetectorOptions{}
+	// Clear the checked-annotation audit list (synth-3599), so Fini()'s
+	// report reflects only this run's checked blocks.
+	resetCheckedAnnotations()
+
+	// Create a fresh detector instance, starting from whatever Configure
+	// (v0.5.0) was last called with, then layering the RACEDETECTOR_*
+	// environment variables on top so an operator can still override a
+	// program's compiled-in configuration at deploy time without a rebuild.
+	configured := consumeConfiguredOptions()
+	opts := detector.DetectorOptions{
+		SampleRate:            configured.SampleRate,
+		SamplingEnabled:       configured.SampleRate > 1,
+		WarmupDuration:        configured.WarmupDuration,
+		OverheadTargetPercent: configured.OverheadTargetPercent,
+		HistorySize:           configured.HistorySize,
+		ProfilingEnabled:      configured.ProfilingEnabled,
+		Symbolize: detector.SymbolizeOptions{
+			StripPathPrefix:  configured.StripPathPrefix,
+			ModuleRoot:       configured.ModuleRoot,
+			ModuleImportPath: configured.ModuleImportPath,
+		},
+		Export: detector.ExportOptions{
+			URL:    configured.ExportURL,
+			Format: detector.ExportFormat(configured.ExportFormat),
+		},
+		Report: detector.ReportOptions{
+			MaxFrames:          configured.ReportMaxFrames,
+			SkipInternalFrames: configured.ReportSkipInternalFrames,
+			CollapseWrappers:   configured.ReportCollapseWrappers,
+			MaxReportsPerSite:  configured.ReportMaxReportsPerSite,
+		},
+		SingleThreaded: defaultSingleThreaded || configured.SingleThreaded,
+		OnReport:       currentReportCallback(),
+	}
 	if sampleRateStr := os.Getenv("RACEDETECTOR_SAMPLE_RATE"); sampleRateStr != "" {
 		if rate, err := strconv.ParseUint(sampleRateStr, 10, 64); err == nil && rate > 1 {
 			opts.SamplingEnabled = true
@@ -1463,8 +2283,120 @@ func Init() {
 				rate, float64(rate-1)/float64(rate)*100)
 		}
 	}
+	if warmupStr := os.Getenv("RACEDETECTOR_WARMUP"); warmupStr != "" {
+		if warmup, err := time.ParseDuration(warmupStr); err == nil && warmup > 0 {
+			opts.WarmupDuration = warmup
+			fmt.Fprintf(os.Stderr, "Race detector: sampling warmup enabled (%s at full detection before sampling applies)\n", warmup)
+		}
+	}
+	if targetStr := os.Getenv("RACEDETECTOR_OVERHEAD_TARGET_PERCENT"); targetStr != "" {
+		if target, err := strconv.ParseFloat(targetStr, 64); err == nil && target > 0 {
+			opts.OverheadTargetPercent = target
+			fmt.Fprintf(os.Stderr, "Race detector: overhead-targeted sampling enabled (target=%.1f%%)\n", target)
+		}
+	}
+	if profileStr := os.Getenv("RACEDETECTOR_PROFILE"); profileStr != "" && profileStr != "0" {
+		opts.ProfilingEnabled = true
+		fmt.Fprintf(os.Stderr, "Race detector: overhead profiling enabled (dump via WriteOverheadProfile)\n")
+	}
+	if stripPrefix := os.Getenv("RACEDETECTOR_STRIP_PREFIX"); stripPrefix != "" {
+		opts.Symbolize.StripPathPrefix = stripPrefix
+	}
+	if moduleRoot, modulePath := os.Getenv("RACEDETECTOR_MODULE_ROOT"), os.Getenv("RACEDETECTOR_MODULE_PATH"); moduleRoot != "" && modulePath != "" {
+		opts.Symbolize.ModuleRoot = moduleRoot
+		opts.Symbolize.ModuleImportPath = modulePath
+	}
+	if exportURL := os.Getenv("RACEDETECTOR_EXPORT_URL"); exportURL != "" {
+		opts.Export.URL = exportURL
+		if exportFormat := os.Getenv("RACEDETECTOR_EXPORT_FORMAT"); exportFormat != "" {
+			opts.Export.Format = detector.ExportFormat(exportFormat)
+		}
+		format := opts.Export.Format
+		if format == "" {
+			format = detector.ExportFormatJSON
+		}
+		fmt.Fprintf(os.Stderr, "Race detector: exporting race reports to %s (format=%s)\n", exportURL, format)
+	}
+	if maxFramesStr := os.Getenv("RACEDETECTOR_REPORT_MAX_FRAMES"); maxFramesStr != "" {
+		if maxFrames, err := strconv.Atoi(maxFramesStr); err == nil && maxFrames > 0 {
+			opts.Report.MaxFrames = maxFrames
+		}
+	}
+	if skipInternal := os.Getenv("RACEDETECTOR_REPORT_SKIP_INTERNAL"); skipInternal != "" && skipInternal != "0" {
+		opts.Report.SkipInternalFrames = true
+	}
+	if collapseWrappers := os.Getenv("RACEDETECTOR_REPORT_COLLAPSE_WRAPPERS"); collapseWrappers != "" && collapseWrappers != "0" {
+		opts.Report.CollapseWrappers = true
+	}
+	if maxPerSiteStr := os.Getenv("RACEDETECTOR_REPORT_MAX_PER_SITE"); maxPerSiteStr != "" {
+		if maxPerSite, err := strconv.Atoi(maxPerSiteStr); err == nil && maxPerSite > 0 {
+			opts.Report.MaxReportsPerSite = maxPerSite
+		}
+	}
+	if maxReportsStr := os.Getenv("RACEDETECTOR_REPORT_MAX_REPORTS"); maxReportsStr != "" {
+		if maxReports, err := strconv.Atoi(maxReportsStr); err == nil && maxReports > 0 {
+			opts.Report.MaxReports = maxReports
+		}
+	}
+
+	// scheduleRecordFile is closed by Fini(), once recording is done -
+	// unlike the other options above, this one holds an open resource for
+	// the life of the program.
+	scheduleRecordFile = nil
+	if path := os.Getenv("RACEDETECTOR_RECORD_SCHEDULE"); path != "" {
+		if f, err := os.Create(path); err == nil {
+			scheduleRecordFile = f
+			opts.RecordSchedule = f
+			fmt.Fprintf(os.Stderr, "Race detector: recording schedule to %s\n", path)
+		} else {
+			fmt.Fprintf(os.Stderr, "Race detector: could not create schedule recording %s: %v\n", path, err)
+		}
+	}
+	if path := os.Getenv("RACEDETECTOR_REPLAY_SCHEDULE"); path != "" {
+		if f, err := os.Open(path); err == nil {
+			events, err := detector.LoadSchedule(f)
+			_ = f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Race detector: could not load schedule replay %s: %v\n", path, err)
+			} else {
+				opts.ReplaySchedule = events
+				fmt.Fprintf(os.Stderr, "Race detector: replaying schedule from %s (%d events)\n", path, len(events))
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Race detector: could not open schedule replay %s: %v\n", path, err)
+		}
+	}
+
+	if path := os.Getenv(hbGraphFileEnvVar); path != "" {
+		opts.HappensBeforeGraphEnabled = true
+		fmt.Fprintf(os.Stderr, "Race detector: happens-before graph recording enabled (written to %s on exit)\n", path)
+	}
+
+	if singleThreadedStr := os.Getenv("RACEDETECTOR_SINGLE_THREADED"); singleThreadedStr != "" {
+		opts.SingleThreaded = singleThreadedStr != "0"
+	}
+
+	if muteStr := os.Getenv("RACEDETECTOR_MUTE_AFTER_REPORT"); muteStr != "" && muteStr != "0" {
+		opts.MuteAfterReport = true
+	}
+
+	if stressStr := os.Getenv("RACEDETECTOR_STRESS"); stressStr != "" && stressStr != "0" {
+		opts.StressEnabled = true
+		if seedStr := os.Getenv("RACEDETECTOR_STRESS_SEED"); seedStr != "" {
+			if seed, err := strconv.ParseInt(seedStr, 10, 64); err == nil {
+				opts.StressSeed = seed
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Race detector: stress scheduling enabled (seed=%d)\n", opts.StressSeed)
+	}
+
 	det = detector.NewDetectorWithOptions(opts)
 
+	// Bump contextGeneration so stale racectx fast-path entries (still-live
+	// goroutines that cached a context before this re-Init) are rejected by
+	// loadContextFast, even though their gid hasn't changed.
+	contextGeneration.Add(1)
+
 	// Clear any existing goroutine contexts.
 	// This ensures a clean slate when re-initializing.
 	// CRITICAL: Use Range+Delete instead of reassignment to avoid data race
@@ -1474,6 +2406,12 @@ func Init() {
 		return true
 	})
 
+	// Clear the racectx fast-path keepalive registry.
+	contextCacheEntries.Range(func(key, _ interface{}) bool {
+		contextCacheEntries.Delete(key)
+		return true
+	})
+
 	// Clear TID → GID mapping.
 	// CRITICAL: Use Range+Delete instead of reassignment to avoid data race.
 	tidToGID.Range(func(key, _ interface{}) bool {
@@ -1481,6 +2419,16 @@ func Init() {
 		return true
 	})
 
+	// Mark any pending teardown handles as already released (synth-3613),
+	// so a runtime.AddCleanup fallback firing later for a context from
+	// before this re-Init doesn't return a stale TID into the freshly
+	// reinitialized pool below.
+	contextTeardowns.Range(func(key, val interface{}) bool {
+		val.(*contextTeardown).done.Store(true)
+		contextTeardowns.Delete(key)
+		return true
+	})
+
 	// Clear spawn context tracking (GoStart).
 	spawnContextsMu.Lock()
 	spawnContextsSlice = nil
@@ -1518,6 +2466,12 @@ func Init() {
 	// TID 1: Main goroutine (already allocated above)
 	// TID 2+: Child goroutines (allocated dynamically)
 	nextTID.Store(2)
+
+	initSamplingEnabled = opts.SamplingEnabled
+	initSampleRate = opts.SampleRate
+
+	log.Info("initialized (sampling=%v singleThreaded=%v exportURL=%q)",
+		opts.SamplingEnabled, opts.SingleThreaded, opts.Export.URL)
 }
 
 // Fini finalizes the race detector and prints a summary report.
@@ -1533,9 +2487,35 @@ func Init() {
 // After Fini() is called, the detector is disabled and raceread/racewrite
 // become no-ops. If you need to re-enable detection, call Init() again.
 //
+// Side Channel Reporting (v0.4.11):
+// If the RACEDETECTOR_REPORT_FILE environment variable is set, Fini() writes
+// the total race count to that file as a plain decimal integer. The
+// racedetector CLI uses this to detect races in instrumented binaries that
+// otherwise exit 0 (e.g., a `go test` run where no test assertion failed),
+// so CI pipelines fail reliably whenever a race was found.
+//
+// Side Channel Reporting (v0.5.0):
+// If RACEDETECTOR_RACES_FILE is also set, Fini() writes the formatted,
+// recently recorded race reports to that file (NUL-separated - see
+// writeRacesFile), so the CLI can surface each race individually, e.g. as
+// a `racedetector test -junit` failure element.
+//
+// Side Channel Reporting (synth-3601):
+// If RACEDETECTOR_REPORT_DIR is set, Fini() additionally writes its count
+// and reports into that directory as <pid>.report/<pid>.races (see
+// writeReportDir), instead of one shared path every process would fight
+// over. `racedetector test ./...` uses this to aggregate, dedup, and
+// summarize races across all the per-package test binaries it spawns.
+//
 // Thread Safety: Safe to call multiple times, but only the first call
 // will print the summary. Subsequent calls are no-ops.
 //
+// topRaceSitesLimit bounds how many rows Fini's "Top race sites" summary
+// prints (synth-3607) - see Detector.TopSites - so a run with hundreds of
+// distinct sites still gets a scannable table instead of one line per
+// site.
+const topRaceSitesLimit = 10
+
 // Example:
 //
 //	func main() {
@@ -1555,9 +2535,54 @@ func Fini() {
 	// This ensures no more race checks happen while we're printing the report.
 	enabled.Store(false)
 
+	// Flush guarantee (synth-3588): race reporting runs on a separate
+	// goroutine (synth-3587), so a race detected just before Fini() was
+	// called could still be queued, not yet reflected in racesDetected or
+	// in the recentReports writeRacesFile below reads from. RacesDetected
+	// already waits for this internally, but doing it explicitly up front
+	// makes the guarantee obvious at the one place callers depend on it:
+	// nothing after this line should observe an incomplete report.
+	det.WaitForPendingReports()
+
+	// Flush the remote report exporter (synth-3602), if configured, so a
+	// race reported just before process exit still reaches the collector
+	// instead of being dropped along with the rest of the process. Bounded
+	// by ExportOptions.Timeout - see Detector.FlushExporter.
+	det.FlushExporter()
+
 	// Get the total number of races detected.
 	racesDetected := det.RacesDetected()
 
+	// Write race count to the side-channel file, if requested.
+	// This lets the CLI observe the race count even when the process's own
+	// exit code doesn't reflect it (e.g., `go test` with passing assertions).
+	writeReportFile(racesDetected)
+
+	// Write the formatted race reports to a second side channel, if
+	// requested. Unlike the count above, this lets the CLI (e.g. `test
+	// -junit`) surface the actual reports without scraping stderr.
+	writeRacesFile()
+
+	// Write this process's own count/reports into the aggregation
+	// directory, if RACEDETECTOR_REPORT_DIR asked for one (synth-3601).
+	// `racedetector test ./...` sets this instead of the single-file
+	// channels above, since it spawns one test binary per package and a
+	// shared file would have each package's Fini() clobber the last.
+	writeReportDir(racesDetected)
+
+	// Write the happens-before graph, if RACEDETECTOR_HB_GRAPH asked for one.
+	writeHBGraphFile()
+
+	// Write the overhead profile, if RACEDETECTOR_PROFILE_OUT asked for one.
+	writeProfileFile()
+
+	// Flush and close the schedule recording, if RACEDETECTOR_RECORD_SCHEDULE
+	// asked Init() to open one.
+	if scheduleRecordFile != nil {
+		_ = scheduleRecordFile.Close()
+		scheduleRecordFile = nil
+	}
+
 	// Print summary report to stderr.
 	// This matches Go's runtime race detector output format.
 	fmt.Fprintf(os.Stderr, "\n")
@@ -1565,6 +2590,16 @@ func Fini() {
 	fmt.Fprintf(os.Stderr, "Race Detector Report\n")
 	fmt.Fprintf(os.Stderr, "==================\n")
 
+	// Build-info line (synth-3624): only present when this binary went
+	// through the racedetector CLI's build/run/test commands, which embed
+	// buildToolVersion (and friends) via -ldflags -X. Manually-instrumented
+	// programs that import race directly never set it, so the line is
+	// omitted rather than printed with misleading empty fields.
+	if buildToolVersion != "" {
+		fmt.Fprintf(os.Stderr, "Tool: racedetector %s (%s) | scope=%q sampling=%v coalescing=%s\n",
+			buildToolVersion, buildToolCommit, buildScope, initSamplingEnabled, buildCoalescing)
+	}
+
 	if racesDetected == 0 {
 		// Success case - no races found.
 		fmt.Fprintf(os.Stderr, "✓ No data races detected.\n")
@@ -1572,7 +2607,173 @@ func Fini() {
 		// Warning case - races were detected.
 		fmt.Fprintf(os.Stderr, "WARNING: %d data race(s) detected!\n", racesDetected)
 		fmt.Fprintf(os.Stderr, "\nSee above for details.\n")
+
+		// Top-N race sites (synth-3607): aggregate races by racing site
+		// pair and print a ranked summary, so a suite with hundreds of
+		// findings shows its hot spots at a glance instead of only a total
+		// count.
+		if sites := det.TopSites(topRaceSitesLimit); len(sites) > 0 {
+			fmt.Fprintf(os.Stderr, "\nTop race sites:\n")
+			for _, site := range sites {
+				plural := "s"
+				if site.Count == 1 {
+					plural = ""
+				}
+				fmt.Fprintf(os.Stderr, "  %d race%s at %s\n", site.Count, plural, site.Site)
+			}
+		}
+
+		// Global report cap (synth-3635): if RACEDETECTOR_REPORT_MAX_REPORTS
+		// held any full reports back from stderr, say so - otherwise a run
+		// with the cap set could easily look like it found fewer races than
+		// it actually did.
+		if suppressed := det.SuppressedReports(); suppressed > 0 {
+			plural := "s"
+			if suppressed == 1 {
+				plural = ""
+			}
+			fmt.Fprintf(os.Stderr, "\n(suppressed %d further race%s: RACEDETECTOR_REPORT_MAX_REPORTS reached)\n", suppressed, plural)
+		}
+	}
+
+	// Checked-annotation audit (synth-3599): list every
+	// "//racedetector:checked" block this run actually exercised, so a
+	// reviewer can tell a race-freedom claim that ran from one that never
+	// did.
+	if annotations := CheckedAnnotations(); len(annotations) > 0 {
+		fmt.Fprintf(os.Stderr, "\nChecked annotations exercised (%d):\n", len(annotations))
+		for _, location := range annotations {
+			fmt.Fprintf(os.Stderr, "  - %s\n", location)
+		}
 	}
 
 	fmt.Fprintf(os.Stderr, "==================\n\n")
 }
+
+// reportFileEnvVar is the environment variable the CLI uses to request a
+// race-count side channel from the instrumented binary.
+const reportFileEnvVar = "RACEDETECTOR_REPORT_FILE"
+
+// writeReportFile writes the detected race count to the file named by the
+// RACEDETECTOR_REPORT_FILE environment variable, if set.
+//
+// This is the side channel described in Fini()'s documentation: it lets the
+// racedetector CLI (build/run/test) propagate a non-zero exit code whenever
+// races were detected, even if the instrumented program or test binary
+// itself exits 0.
+//
+// Failures to write the file are intentionally silent (best effort) - the
+// stderr report is always printed regardless, so the information is never
+// lost entirely.
+func writeReportFile(racesDetected int) {
+	path := os.Getenv(reportFileEnvVar)
+	if path == "" {
+		return
+	}
+	_ = os.WriteFile(path, []byte(strconv.Itoa(racesDetected)), 0644)
+}
+
+// racesFileEnvVar is the environment variable the CLI uses to request a
+// formatted-race-reports side channel from the instrumented binary, e.g.
+// for `racedetector test -junit`.
+const racesFileEnvVar = "RACEDETECTOR_RACES_FILE"
+
+// writeRacesFile writes every recently recorded race report to the file
+// named by the RACEDETECTOR_RACES_FILE environment variable, if set,
+// using Detector.FormatRecentReports (NUL-separated, one formatted report
+// per entry).
+//
+// Like writeReportFile, failures to write the file are intentionally
+// silent - the stderr report printed by reportRaceV2 is always there
+// regardless.
+func writeRacesFile() {
+	path := os.Getenv(racesFileEnvVar)
+	if path == "" {
+		return
+	}
+	var buf bytes.Buffer
+	det.FormatRecentReports(&buf)
+	_ = os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// reportDirEnvVar is the environment variable the CLI uses to request a
+// per-process side channel directory (synth-3601), for aggregating race
+// reports across the many test binaries `racedetector test ./...` spawns -
+// one per package, each its own OS process with its own PID.
+//
+// Unlike RACEDETECTOR_REPORT_FILE/RACEDETECTOR_RACES_FILE, which name a
+// single shared file, every process sharing this env var writes into its
+// own <pid>.report/<pid>.races pair inside the directory, so two package
+// binaries running at once (the default `go test` concurrency) can't
+// clobber each other's results the way they would writing the same path.
+const reportDirEnvVar = "RACEDETECTOR_REPORT_DIR"
+
+// writeReportDir writes racesDetected and the recently recorded race
+// reports into <dir>/<pid>.report and <dir>/<pid>.races, where dir is
+// named by RACEDETECTOR_REPORT_DIR, if set (synth-3601). Uses the exact
+// same formats as writeReportFile/writeRacesFile (plain decimal count,
+// NUL-separated reports) - only the destination changes, so the CLI's
+// aggregation step (see cmd/racedetector's reportDir.go) can read each
+// process's pair with the same parsing it already had.
+//
+// Like the other side-channel writers, failures are silent - the stderr
+// report is always printed regardless.
+func writeReportDir(racesDetected int) {
+	dir := os.Getenv(reportDirEnvVar)
+	if dir == "" {
+		return
+	}
+	pid := os.Getpid()
+	_ = os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.report", pid)), []byte(strconv.Itoa(racesDetected)), 0644)
+
+	var buf bytes.Buffer
+	det.FormatRecentReports(&buf)
+	_ = os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.races", pid)), buf.Bytes(), 0644)
+}
+
+// hbGraphFileEnvVar is the environment variable the CLI (or a user running
+// the instrumented binary directly) uses to request a Graphviz DOT export
+// of the full happens-before event graph, for post-mortem analysis (v0.5.0).
+const hbGraphFileEnvVar = "RACEDETECTOR_HB_GRAPH"
+
+// writeHBGraphFile writes the recorded happens-before graph to the file
+// named by RACEDETECTOR_HB_GRAPH, if set, using Detector.
+// WriteHappensBeforeGraph. A missing detector.errHBGraphDisabled error
+// (recording wasn't actually enabled) is silently ignored, like the other
+// side-channel writers above.
+func writeHBGraphFile() {
+	path := os.Getenv(hbGraphFileEnvVar)
+	if path == "" {
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	_ = det.WriteHappensBeforeGraph(f)
+}
+
+// profileOutFileEnvVar is the environment variable the CLI (or a user
+// running the instrumented binary directly) uses to request the
+// per-call-site overhead profile be written to a file automatically on
+// exit, rather than calling WriteOverheadProfile from application code
+// (synth-3584).
+const profileOutFileEnvVar = "RACEDETECTOR_PROFILE_OUT"
+
+// writeProfileFile writes the accumulated overhead profile to the file
+// named by RACEDETECTOR_PROFILE_OUT, if set. A missing
+// detector.errProfilingDisabled error (RACEDETECTOR_PROFILE wasn't also
+// set) is silently ignored, like the other side-channel writers above.
+func writeProfileFile() {
+	path := os.Getenv(profileOutFileEnvVar)
+	if path == "" {
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	_ = det.WriteOverheadProfile(f)
+}
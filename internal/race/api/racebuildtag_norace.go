@@ -0,0 +1,11 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !race
+
+package api
+
+// builtWithGoRace mirrors racebuildtag_race.go's constant for a normal
+// (non -race) build (synth-3586).
+const builtWithGoRace = false
@@ -0,0 +1,15 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race
+
+package api
+
+// builtWithGoRace reports whether this binary was compiled with Go's own
+// race detector (`go build -race` / `go test -race`), the same `race`
+// build constraint the compiler sets automatically in that case (synth-3586).
+// Tests use this to skip assertions that only hold when the g.racectx fast
+// path (racectx_go124.go/racectx_go125.go) is actually wired in - see
+// racectx_fallback.go, which is what -race builds fall back to instead.
+const builtWithGoRace = true
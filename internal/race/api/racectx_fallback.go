@@ -0,0 +1,42 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.24 || go1.26 || !(amd64 || arm64 || riscv64) || race
+
+// Fallback racectx slot access for platforms/Go versions without a
+// DWARF-verified racectxOffset, and for any build compiled with Go's real
+// race detector (synth-3586).
+//
+// Unlike goid's fallback (goid_fallback.go), this also covers Go 1.23:
+// goidOffset's 1.23 value was carried over unverified from 1.24 on the
+// (correct, but unrelated) assumption that the two versions' g struct
+// layouts agree up to goid. racectxOffset needs the whole layout up to a
+// point 150+ bytes further into the struct, and a hand-derived guess at
+// that distance already turned out wrong once for Go 1.24 itself (see
+// racectx_go124.go) - not a mistake worth repeating for a version this
+// package has no toolchain to verify against. Every unverified
+// version/architecture simply falls back to the contexts sync.Map lookup
+// getCurrentContext has always used.
+//
+// The `race` case is different: racectxOffset itself is verified fine
+// under -race, but the slot it points at is not ours to write once
+// raceenabled is true - the Go runtime is using g.racectx for its own
+// ThreadSanitizer context at that point, and stomping it segfaults the
+// process (see racectx_go124.go/racectx_go125.go). `race` is a build
+// constraint Go's compiler sets automatically for any `-race` build, so
+// this falls back to the safe sync.Map-only path with no user action
+// required.
+
+package api
+
+// getContextSlotFast always misses on unsupported platforms/Go versions,
+// forcing getCurrentContext onto its sync.Map fallback.
+func getContextSlotFast() uintptr {
+	return 0
+}
+
+// setContextSlotFast is a no-op on unsupported platforms/Go versions -
+// there is no verified racectx slot to write to.
+func setContextSlotFast(uintptr) {
+}
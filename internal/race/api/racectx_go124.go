@@ -0,0 +1,73 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.24 && !go1.25 && (amd64 || arm64 || riscv64) && !race
+
+// Go 1.24 specific racectx slot access (synth-3586).
+//
+// Excluded by the race build tag: when the consuming binary is compiled
+// with `go test -race` / `go build -race`, Go's runtime sets raceenabled
+// and unconditionally uses g.racectx for its own ThreadSanitizer context,
+// regardless of whether this package's detector is active. Writing a
+// *contextCacheEntry into that slot under those conditions stomps the
+// real TSan context pointer and segfaults the process the moment any
+// goroutine touches race-instrumented code. See racectx_fallback.go,
+// which picks up the safe sync.Map-only path whenever `race` is set.
+//
+// racectxOffset was verified by compiling a Go 1.24 binary with debug info
+// and reading the DWARF type descriptor for runtime.g directly, rather than
+// hand-summing field sizes: an earlier attempt at hand-summing got this
+// offset wrong (undercounted internal/runtime/atomic.Bool's size, a
+// different, larger type than the look-alike sync/atomic.Bool) and the
+// resulting stray write corrupted the adjacent waiting *sudog field,
+// crashing goroutines on their next stack growth. Trust the DWARF
+// cross-check technique below over a hand-derived table if this ever needs
+// re-verifying for a new Go version.
+//
+// g struct layout (Go 1.24, DWARF-verified, continued from goid at 160):
+//
+//	Field      Offset
+//	-----      ------
+//	goid       160
+//	...
+//	startpc    304
+//	racectx    312  <- TARGET
+//	waiting    320
+
+package api
+
+import "unsafe"
+
+// racectxOffset for Go 1.24 is 312 bytes.
+const racectxOffset = 312
+
+// getContextSlotFast returns the raw uintptr stored in the current
+// goroutine's g.racectx slot, or 0 if unset. getg is declared in
+// goid_go124.go (same package, same build tag).
+//
+//go:nosplit
+//go:nocheckptr
+func getContextSlotFast() uintptr {
+	gptr := getg()
+	if gptr == 0 {
+		return 0
+	}
+
+	//nolint:gosec // G103: Intentional unsafe pointer arithmetic for runtime access
+	return *(*uintptr)(unsafe.Pointer(gptr + racectxOffset))
+}
+
+// setContextSlotFast stores v in the current goroutine's g.racectx slot.
+//
+//go:nosplit
+//go:nocheckptr
+func setContextSlotFast(v uintptr) {
+	gptr := getg()
+	if gptr == 0 {
+		return
+	}
+
+	//nolint:gosec // G103: Intentional unsafe pointer arithmetic for runtime access
+	*(*uintptr)(unsafe.Pointer(gptr + racectxOffset)) = v
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.25 && !go1.26 && (amd64 || arm64 || riscv64) && !race
+
+// Go 1.25 specific racectx slot access (synth-3586).
+//
+// Excluded by the race build tag: when the consuming binary is compiled
+// with `go test -race` / `go build -race`, Go's runtime sets raceenabled
+// and unconditionally uses g.racectx for its own ThreadSanitizer context,
+// regardless of whether this package's detector is active. Writing a
+// *contextCacheEntry into that slot under those conditions stomps the
+// real TSan context pointer and segfaults the process the moment any
+// goroutine touches race-instrumented code. See racectx_fallback.go,
+// which picks up the safe sync.Map-only path whenever `race` is set.
+//
+// racectxOffset was verified the same way as racectx_go124.go: reading the
+// DWARF type descriptor for runtime.g out of a compiled Go 1.25 binary,
+// not by hand-summing field sizes. It moved to 304 (8 bytes earlier than
+// Go 1.24's 312) for the same reason goidOffset moved from 160 to 152
+// between these versions - see goid_go125.go: gobuf lost its 'ret' field.
+//
+// g struct layout (Go 1.25, DWARF-verified, continued from goid at 152):
+//
+//	Field      Offset
+//	-----      ------
+//	goid       152
+//	...
+//	startpc    296
+//	racectx    304  <- TARGET
+//	waiting    312
+
+package api
+
+import "unsafe"
+
+// racectxOffset for Go 1.25 is 304 bytes.
+const racectxOffset = 304
+
+// getContextSlotFast returns the raw uintptr stored in the current
+// goroutine's g.racectx slot, or 0 if unset. getg is declared in
+// goid_go125.go (same package, same build tag).
+//
+//go:nosplit
+//go:nocheckptr
+func getContextSlotFast() uintptr {
+	gptr := getg()
+	if gptr == 0 {
+		return 0
+	}
+
+	//nolint:gosec // G103: Intentional unsafe pointer arithmetic for runtime access
+	return *(*uintptr)(unsafe.Pointer(gptr + racectxOffset))
+}
+
+// setContextSlotFast stores v in the current goroutine's g.racectx slot.
+//
+//go:nosplit
+//go:nocheckptr
+func setContextSlotFast(v uintptr) {
+	gptr := getg()
+	if gptr == 0 {
+		return
+	}
+
+	//nolint:gosec // G103: Intentional unsafe pointer arithmetic for runtime access
+	*(*uintptr)(unsafe.Pointer(gptr + racectxOffset)) = v
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package api
+
+import "testing"
+
+// TestRacectxFastPath_DisabledUnderRace is a regression test for synth-3586:
+// a binary built with Go's own race detector (`go test -race`) must never
+// let this package write into a goroutine's g.racectx slot, because the Go
+// runtime is simultaneously using that exact field for its own
+// ThreadSanitizer context whenever raceenabled is true. An earlier version
+// of racectx_go124.go/racectx_go125.go lacked the `!race` build constraint
+// and stomped that pointer, segfaulting any consumer that ran `go test
+// -race` in the same binary as this library.
+//
+// This only runs (and only means anything) under `go test -race`, which
+// sets the `race` build constraint builtWithGoRace mirrors; under a normal
+// build it degenerates into an assertion that builtWithGoRace is false.
+func TestRacectxFastPath_DisabledUnderRace(t *testing.T) {
+	if !builtWithGoRace {
+		t.Skip("only meaningful under `go test -race`; see context_cache_test.go for the fast-path-enabled equivalents")
+	}
+
+	if got := getContextSlotFast(); got != 0 {
+		t.Fatalf("getContextSlotFast() = %#x under -race, want 0 (racectx_fallback.go must be in effect, not racectx_go124.go/racectx_go125.go)", got)
+	}
+
+	// setContextSlotFast must be the fallback's no-op: calling it (as
+	// storeContextFast does on every getCurrentContext slow path) must not
+	// touch the real g.racectx the runtime's race detector owns.
+	setContextSlotFast(0xdeadbeef)
+	if got := getContextSlotFast(); got != 0 {
+		t.Fatalf("getContextSlotFast() = %#x after setContextSlotFast under -race, want 0 (slot write should be a no-op)", got)
+	}
+
+	// getCurrentContext must still work correctly end to end via the
+	// sync.Map-only path with the fast path inert.
+	Reset()
+	defer Reset()
+	ctx := getCurrentContext()
+	if ctx == nil {
+		t.Fatal("getCurrentContext() = nil under -race fallback, want a valid RaceContext")
+	}
+	if again := getCurrentContext(); again != ctx {
+		t.Errorf("getCurrentContext() unstable under -race fallback: %p then %p", ctx, again)
+	}
+}
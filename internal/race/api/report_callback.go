@@ -0,0 +1,48 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/kolkov/racedetector/internal/race/detector"
+)
+
+var (
+	reportCallbackMu sync.Mutex
+	reportCallback   func(*detector.RaceReport)
+)
+
+// OnReport registers fn to be called synchronously with each newly detected
+// race, in addition to (not instead of) the stderr report, exporter, and
+// tracer (synth-3609) - the extension point race.OnReport wraps, so an
+// application can panic in a test, increment a metric, capture an event to
+// an error tracker, or run a programmatic assertion the moment a race is
+// found, instead of only ever seeing it in stderr.
+//
+// OnReport may be called before or after Init(): if called before, fn is
+// picked up by the next Init() call; if called after, it takes effect on
+// the already-running detector immediately. Pass nil to disable a
+// previously registered callback.
+//
+// Called while the detector's internal lock is held - fn must not call
+// back into any race.* function, and should copy anything from the report
+// it needs to keep past the call.
+//
+// Thread Safety: Safe for concurrent calls.
+func OnReport(fn func(*detector.RaceReport)) {
+	reportCallbackMu.Lock()
+	reportCallback = fn
+	reportCallbackMu.Unlock()
+
+	if det != nil {
+		det.SetOnReport(fn)
+	}
+}
+
+// currentReportCallback returns the callback most recently passed to
+// OnReport (nil if it has never been called), for Init() to include when
+// building a fresh Detector's options.
+func currentReportCallback() func(*detector.RaceReport) {
+	reportCallbackMu.Lock()
+	defer reportCallbackMu.Unlock()
+	return reportCallback
+}
@@ -0,0 +1,14 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !js && !wasip1
+
+// Default detector.DetectorOptions.SingleThreaded value for every target
+// other than wasm (synth-3615). Everywhere else Init() assumes genuine
+// OS-thread parallelism unless the embedder opts in via Configure or
+// RACEDETECTOR_SINGLE_THREADED=1.
+
+package api
+
+const defaultSingleThreaded = false
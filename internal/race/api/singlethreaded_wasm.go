@@ -0,0 +1,15 @@
+// Copyright 2025 The racedetector Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build js || wasip1
+
+// Default detector.DetectorOptions.SingleThreaded value for wasm targets
+// (synth-3615). GOOS=js and GOOS=wasip1 both pin the whole program to one
+// OS thread, so unless the embedder explicitly opts out via
+// RACEDETECTOR_SINGLE_THREADED=0, Init() assumes the single-threaded
+// backpressure policy described on DetectorOptions.SingleThreaded.
+
+package api
+
+const defaultSingleThreaded = true
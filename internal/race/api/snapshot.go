@@ -0,0 +1,106 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/kolkov/racedetector/internal/race/detector"
+	"github.com/kolkov/racedetector/internal/race/epoch"
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+	"github.com/kolkov/racedetector/internal/race/vectorclock"
+)
+
+// FullSnapshot is an opaque checkpoint of detector and goroutine context
+// state captured by Snapshot, to be handed to Restore (synth-3576).
+//
+// This is the API-layer counterpart of detector.Snapshot: the detector
+// itself only owns shadow memory and sync shadow (see detector.Snapshot),
+// while the goroutine ID -> RaceContext mapping this package uses to find
+// the calling goroutine's clock lives here instead, so a checkpoint of
+// "everything a test framework would want to roll back" has to span both.
+//
+// The zero FullSnapshot is not meaningful - always obtain one from
+// Snapshot().
+type FullSnapshot struct {
+	detector *detector.Snapshot
+	contexts map[int64]contextSnapshot
+}
+
+// contextSnapshot captures the fields of a goroutine.RaceContext that
+// matter for happens-before tracking: its thread ID, full vector clock, and
+// cached epoch. It deliberately excludes the per-goroutine shadow cell
+// cache (self-healing, and would otherwise reference VarState instances a
+// Restore replaces) and the ignore-region depth counters (expected to be
+// zero at any checkpoint boundary a well-behaved caller pairs with defer).
+type contextSnapshot struct {
+	tid   uint16
+	clock *vectorclock.VectorClock
+	epoch epoch.Epoch
+}
+
+// Snapshot captures the detector's shadow memory, sync shadow, race
+// counter, and every live goroutine's happens-before clock, for a later
+// Restore (synth-3576).
+//
+// This is the finer-grained alternative to Reset() a test framework needs
+// to checkpoint state before a subtest and roll back after. See
+// detector.Detector.Snapshot for exactly what shadow-memory-side state is
+// (and is deliberately not) captured.
+//
+// A goroutine started after Snapshot and still alive at Restore has its
+// context discarded, the same as it would be under Reset() - Restore does
+// not attempt to roll back the TID allocation pool, so a new goroutine
+// started during the checkpointed section is simply forgotten rather than
+// un-started.
+//
+// Thread Safety: NOT safe for concurrent access. The caller must ensure no
+// other goroutines are using the detector while snapshotting, same
+// convention as Reset().
+func Snapshot() *FullSnapshot {
+	snap := &FullSnapshot{
+		detector: det.Snapshot(),
+		contexts: make(map[int64]contextSnapshot),
+	}
+
+	contexts.Range(func(key, value interface{}) bool {
+		gid := key.(int64)
+		ctx := value.(*goroutine.RaceContext)
+		snap.contexts[gid] = contextSnapshot{
+			tid:   ctx.TID,
+			clock: ctx.C.Clone(),
+			epoch: ctx.Epoch,
+		}
+		return true
+	})
+
+	return snap
+}
+
+// Restore replaces the detector's shadow memory, sync shadow, race
+// counter, and goroutine contexts with a deep copy of snap, as previously
+// returned by Snapshot (synth-3576).
+//
+// Cloning snap's entries (rather than adopting them directly) means the
+// same snapshot can be restored from more than once, e.g. to reset between
+// several subtests that all build on the same fixture.
+//
+// Thread Safety: NOT safe for concurrent access. The caller must ensure no
+// other goroutines are using the detector during Restore(), same
+// convention as Reset().
+func Restore(snap *FullSnapshot) {
+	det.Restore(snap.detector)
+
+	// Bump contextGeneration so any racectx fast-path entries cached before
+	// this Restore (see context_cache.go) are rejected on next access, even
+	// for a goroutine whose gid survives the restore unchanged.
+	contextGeneration.Add(1)
+
+	contexts = sync.Map{}
+	contextCacheEntries = sync.Map{}
+	for gid, cs := range snap.contexts {
+		contexts.Store(gid, &goroutine.RaceContext{
+			TID:   cs.tid,
+			C:     cs.clock.Clone(),
+			Epoch: cs.epoch,
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import "testing"
+
+// TestSnapshotRestore_RoundTripsContextClock verifies a goroutine's vector
+// clock recorded before Snapshot is restored after Restore, even after the
+// live context diverges in between (synth-3576).
+func TestSnapshotRestore_RoundTripsContextClock(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	ctx := getCurrentContext()
+	ctx.C.Set(0, 5)
+
+	snap := Snapshot()
+
+	ctx.C.Set(0, 999) // Diverge after snapshotting.
+
+	Restore(snap)
+
+	restored := getCurrentContext()
+	if got := restored.C.Get(0); got != 5 {
+		t.Errorf("restored context clock[0] = %d, want 5", got)
+	}
+}
+
+// TestSnapshotRestore_RoundTripsDetectorState verifies Restore also rolls
+// back the detector-owned portion of the snapshot (delegated to
+// detector.Detector.Snapshot/Restore).
+func TestSnapshotRestore_RoundTripsDetectorState(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	ctx := getCurrentContext()
+	det.OnWrite(0x1000, ctx)
+
+	snap := Snapshot()
+
+	det.OnWrite(0x2000, ctx)
+
+	Restore(snap)
+
+	if det.RacesDetected() != 0 {
+		t.Errorf("RacesDetected() after Restore = %d, want 0", det.RacesDetected())
+	}
+}
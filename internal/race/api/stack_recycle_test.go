@@ -0,0 +1,84 @@
+package api
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestRaceGoEnd_ClearsStaleShadowForRecycledStackAddress verifies the
+// synth-3580 GoEnd hook: once a goroutine that recorded stack bounds via
+// SetStackBounds exits, its shadow cells in that range are cleared, so a
+// later, unrelated goroutine reusing the same address (as the Go runtime
+// does with retired stacks) isn't compared against the exited goroutine's
+// stale epoch.
+func TestRaceGoEnd_ClearsStaleShadowForRecycledStackAddress(t *testing.T) {
+	Init()
+	defer Fini()
+
+	const addr = uintptr(0xC000)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx := getCurrentContext()
+		ctx.SetStackBounds(addr, addr+8)
+		RaceWrite(addr)
+		RaceGoEnd()
+	}()
+	wg.Wait()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// A different goroutine now "owns" the recycled address; without
+		// the GoEnd cleanup this write would be compared against the
+		// exited goroutine's leftover epoch at the same address.
+		RaceWrite(addr)
+		RaceGoEnd()
+	}()
+	wg.Wait()
+
+	if RacesDetected() > 0 {
+		t.Errorf("RacesDetected() = %d, want 0 (second goroutine's first access to a recycled stack address)", RacesDetected())
+	}
+}
+
+// TestRaceGoEnd_WithoutStackBoundsIsUnaffected verifies goroutines that
+// never call SetStackBounds (the common case today, since no compiler
+// instrumentation hook supplies real bounds yet) behave exactly as before
+// synth-3580: RaceGoEnd still cleans up TID/context state without touching
+// shadow memory.
+func TestRaceGoEnd_WithoutStackBoundsIsUnaffected(t *testing.T) {
+	Init()
+	defer Fini()
+
+	var x int
+	addr := addrOf(&x)
+	var mu sync.Mutex
+	var lock int
+	lockAddr := addrOf(&lock)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mu.Lock()
+		RaceAcquire(lockAddr)
+		RaceWrite(addr)
+		RaceRelease(lockAddr)
+		mu.Unlock()
+		RaceGoEnd()
+	}()
+	wg.Wait()
+
+	mu.Lock()
+	RaceAcquire(lockAddr)
+	RaceRead(addr)
+	RaceRelease(lockAddr)
+	mu.Unlock()
+
+	if RacesDetected() > 0 {
+		t.Errorf("RacesDetected() = %d, want 0", RacesDetected())
+	}
+}
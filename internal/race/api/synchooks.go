@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SyncHookKind identifies which happens-before role a registered method
+// plays: acquiring a synchronization object (blocking until some earlier
+// release becomes visible to it) or releasing one (publishing this
+// goroutine's prior writes to whichever acquire happens next).
+type SyncHookKind int
+
+const (
+	// SyncHookAcquire marks a method as acquire-like, e.g. sync.Mutex.Lock -
+	// it should be instrumented with a RaceAcquire call.
+	SyncHookAcquire SyncHookKind = iota
+
+	// SyncHookRelease marks a method as release-like, e.g. sync.Mutex.Unlock -
+	// it should be instrumented with a RaceRelease call.
+	SyncHookRelease
+)
+
+// String returns the human-readable name of a SyncHookKind, used in error
+// messages and instrumenter diagnostics.
+func (k SyncHookKind) String() string {
+	switch k {
+	case SyncHookAcquire:
+		return "acquire"
+	case SyncHookRelease:
+		return "release"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	syncHooksMu sync.Mutex
+	syncHooks   = map[string]SyncHookKind{}
+)
+
+// RegisterSyncHook declares that typeName.methodName establishes
+// acquire/release happens-before semantics, letting library authors whose
+// custom lock/queue types the instrumenter has never heard of get the same
+// treatment as sync.Mutex.Lock/Unlock.
+//
+// typeName is the type's name as it appears in source (e.g. "MyMutex"),
+// methodName is the method's name (e.g. "Lock"), and kind is
+// SyncHookAcquire or SyncHookRelease. The instrumenter emits a
+// RaceAcquire/RaceRelease call keyed on the receiver's address at every
+// call site matching a registered pair, exactly as it would for a built-in
+// sync.Mutex method.
+//
+// RegisterSyncHook is meant to be called from an init() function, before
+// the instrumenter processes the package, since a registration only
+// affects instrumentation performed after it is recorded.
+//
+// Returns an error if typeName or methodName is empty, or kind is not one
+// of the two defined SyncHookKind values.
+//
+// Thread Safety: Safe for concurrent calls.
+func RegisterSyncHook(typeName, methodName string, kind SyncHookKind) error {
+	if typeName == "" || methodName == "" {
+		return fmt.Errorf("race: RegisterSyncHook: typeName and methodName must not be empty")
+	}
+	if kind != SyncHookAcquire && kind != SyncHookRelease {
+		return fmt.Errorf("race: RegisterSyncHook: invalid SyncHookKind %d", kind)
+	}
+
+	syncHooksMu.Lock()
+	defer syncHooksMu.Unlock()
+	syncHooks[typeName+"."+methodName] = kind
+	return nil
+}
+
+// RegisteredSyncHooks returns a snapshot of every (typeName, methodName)
+// pair registered via RegisterSyncHook, keyed as "typeName.methodName".
+//
+// The instrumenter calls this to build its call-site recognition table
+// before rewriting a package's AST. The returned map is a copy; mutating
+// it has no effect on the registry.
+//
+// Thread Safety: Safe for concurrent calls.
+func RegisteredSyncHooks() map[string]SyncHookKind {
+	syncHooksMu.Lock()
+	defer syncHooksMu.Unlock()
+
+	out := make(map[string]SyncHookKind, len(syncHooks))
+	for k, v := range syncHooks {
+		out[k] = v
+	}
+	return out
+}
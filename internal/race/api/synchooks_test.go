@@ -0,0 +1,83 @@
+package api
+
+import "testing"
+
+// TestRegisterSyncHook_RoundTrips verifies a registered hook appears in
+// RegisteredSyncHooks with the same kind it was registered under.
+func TestRegisterSyncHook_RoundTrips(t *testing.T) {
+	defer clearSyncHooksForTest()
+
+	if err := RegisterSyncHook("MyMutex", "Lock", SyncHookAcquire); err != nil {
+		t.Fatalf("RegisterSyncHook() = %v, want nil", err)
+	}
+	if err := RegisterSyncHook("MyMutex", "Unlock", SyncHookRelease); err != nil {
+		t.Fatalf("RegisterSyncHook() = %v, want nil", err)
+	}
+
+	hooks := RegisteredSyncHooks()
+	if got := hooks["MyMutex.Lock"]; got != SyncHookAcquire {
+		t.Errorf("hooks[\"MyMutex.Lock\"] = %v, want SyncHookAcquire", got)
+	}
+	if got := hooks["MyMutex.Unlock"]; got != SyncHookRelease {
+		t.Errorf("hooks[\"MyMutex.Unlock\"] = %v, want SyncHookRelease", got)
+	}
+}
+
+// TestRegisterSyncHook_Reregistration verifies a second registration for
+// the same (typeName, methodName) pair replaces the first.
+func TestRegisterSyncHook_Reregistration(t *testing.T) {
+	defer clearSyncHooksForTest()
+
+	_ = RegisterSyncHook("MyMutex", "Lock", SyncHookAcquire)
+	_ = RegisterSyncHook("MyMutex", "Lock", SyncHookRelease)
+
+	if got := RegisteredSyncHooks()["MyMutex.Lock"]; got != SyncHookRelease {
+		t.Errorf("hooks[\"MyMutex.Lock\"] = %v, want SyncHookRelease (re-registration replaces)", got)
+	}
+}
+
+// TestRegisterSyncHook_RejectsEmptyNames verifies empty typeName/methodName
+// is rejected rather than silently registered.
+func TestRegisterSyncHook_RejectsEmptyNames(t *testing.T) {
+	defer clearSyncHooksForTest()
+
+	if err := RegisterSyncHook("", "Lock", SyncHookAcquire); err == nil {
+		t.Error("RegisterSyncHook() with empty typeName = nil error, want non-nil")
+	}
+	if err := RegisterSyncHook("MyMutex", "", SyncHookAcquire); err == nil {
+		t.Error("RegisterSyncHook() with empty methodName = nil error, want non-nil")
+	}
+}
+
+// TestRegisterSyncHook_RejectsInvalidKind verifies an out-of-range
+// SyncHookKind is rejected rather than silently registered.
+func TestRegisterSyncHook_RejectsInvalidKind(t *testing.T) {
+	defer clearSyncHooksForTest()
+
+	if err := RegisterSyncHook("MyMutex", "Lock", SyncHookKind(99)); err == nil {
+		t.Error("RegisterSyncHook() with invalid kind = nil error, want non-nil")
+	}
+}
+
+// TestRegisteredSyncHooks_ReturnsCopy verifies mutating the returned map
+// does not affect the registry.
+func TestRegisteredSyncHooks_ReturnsCopy(t *testing.T) {
+	defer clearSyncHooksForTest()
+
+	_ = RegisterSyncHook("MyMutex", "Lock", SyncHookAcquire)
+
+	hooks := RegisteredSyncHooks()
+	hooks["MyMutex.Lock"] = SyncHookRelease
+
+	if got := RegisteredSyncHooks()["MyMutex.Lock"]; got != SyncHookAcquire {
+		t.Errorf("registry mutated via returned map: got %v, want SyncHookAcquire", got)
+	}
+}
+
+// clearSyncHooksForTest resets the package-level registry between tests,
+// since RegisterSyncHook has no unregister call of its own.
+func clearSyncHooksForTest() {
+	syncHooksMu.Lock()
+	defer syncHooksMu.Unlock()
+	syncHooks = map[string]SyncHookKind{}
+}
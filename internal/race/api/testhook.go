@@ -0,0 +1,82 @@
+package api
+
+import (
+	"os"
+	"testing"
+)
+
+// resetBetweenTestsEnvVar, when set to a non-empty, non-"0" value, makes
+// TestCleanup reset the detector's shadow memory and dedup state once a
+// test finishes (v0.5.0). See TestCleanup.
+const resetBetweenTestsEnvVar = "RACEDETECTOR_RESET_BETWEEN_TESTS"
+
+// TestCleanup scopes race reporting to a single test (v0.5.0), so a race
+// found while one test runs is attributed to that test specifically,
+// rather than only ever surfacing via the binary-wide RACEDETECTOR_REPORT_FILE
+// side channel once the whole `go test` binary exits.
+//
+// Call it at the top of a test function - the racedetector tool will do
+// this automatically once test-function instrumentation supports it; until
+// then, call it manually:
+//
+//	func TestFoo(t *testing.T) {
+//	    api.TestCleanup(t)
+//	    // ... exercise racy code ...
+//	}
+//
+// TestCleanup records the detector's race count at the time it's called,
+// and registers a t.Cleanup that fails the test with t.Errorf if any new
+// races were detected while it ran.
+//
+// It also registers t.Name() against the calling goroutine's TID (synth-3600),
+// so a race report naming that goroutine includes which test found it - see
+// detector.Detector.SetGoroutineTestName. This works for t.Parallel subtests
+// with no extra plumbing: Go's testing package always runs each test
+// function on its own goroutine (`go tRunner(t, fn)`), parallel or not, so
+// every call to TestCleanup is already registering a distinct goroutine.
+//
+// If RACEDETECTOR_RESET_BETWEEN_TESTS=1 is set, the same t.Cleanup also
+// resets the detector's shadow memory and dedup state (see
+// detector.Detector.Reset) after reporting, so a race on an address
+// already reported by an earlier test isn't silently suppressed in a
+// later one. This is opt-in and trades away the binary-wide cumulative
+// race count Fini() reports via RACEDETECTOR_REPORT_FILE/RACEDETECTOR_RACES_FILE,
+// which would otherwise only reflect races since the last reset - so don't
+// combine it with -junit or other workflows that need a whole-binary total.
+// It also loses happens-before history for goroutines that outlive a
+// single test (e.g. a shared background worker), which could cause missed
+// races at test boundaries.
+//
+// Thread Safety: Safe to call from multiple tests (e.g. run with -parallel),
+// since each call only touches its own t.Cleanup closure and the race
+// count snapshot it captures.
+func TestCleanup(t testing.TB) {
+	det.WaitForPendingReports()
+	before := det.RacesDetected()
+
+	tid := getCurrentContext().TID
+	det.SetGoroutineTestName(tid, t.Name())
+
+	t.Cleanup(func() {
+		// Race reporting happens on a separate goroutine (synth-3587); wait
+		// for it to catch up before reading the post-test count, or a race
+		// detected just before this cleanup runs could still be in flight.
+		det.WaitForPendingReports()
+		if delta := det.RacesDetected() - before; delta > 0 {
+			t.Errorf("race detector: %d data race(s) detected during %s", delta, t.Name())
+		}
+		// Unregister before Reset(), not after: Reset() deliberately leaves
+		// testNames alone (see its field doc), so a TID this test's own
+		// goroutine won't touch again must be cleared here or it would keep
+		// mislabeling races on whatever goroutine reuses tid next.
+		det.ClearGoroutineTestName(tid)
+		if resetBetweenTests() {
+			det.Reset()
+		}
+	})
+}
+
+func resetBetweenTests() bool {
+	v := os.Getenv(resetBetweenTestsEnvVar)
+	return v != "" && v != "0"
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// fakeTB implements just enough of testing.TB for TestCleanup - Cleanup,
+// Errorf, and Name - to drive it without making a genuine race detected in
+// a subtest also fail this package's own test run. Embedding the nil
+// testing.TB interface satisfies the rest of the interface; TestCleanup
+// never calls those other methods.
+type fakeTB struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (f *fakeTB) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+func (f *fakeTB) Errorf(format string, a ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, a...))
+}
+func (f *fakeTB) Name() string { return "fakeTB" }
+func (f *fakeTB) runCleanups() {
+	for _, fn := range f.cleanups {
+		fn()
+	}
+}
+
+// TestTestCleanup_FlagsRaceDuringTest verifies TestCleanup's registered
+// cleanup reports an error when a new race is detected while the test
+// ran, and stays silent when no race occurs.
+func TestTestCleanup_FlagsRaceDuringTest(t *testing.T) {
+	Init()
+	defer Fini()
+
+	racy := &fakeTB{}
+	TestCleanup(racy)
+	addr := uintptr(0x9000)
+	det.OnWrite(addr, goroutine.Alloc(10))
+	det.OnWrite(addr, goroutine.Alloc(11))
+	racy.runCleanups()
+
+	if len(racy.errors) == 0 {
+		t.Error("TestCleanup should have reported an error: a race was detected during the test")
+	}
+
+	clean := &fakeTB{}
+	TestCleanup(clean)
+	det.OnWrite(0xA000, goroutine.Alloc(12))
+	clean.runCleanups()
+
+	if len(clean.errors) != 0 {
+		t.Errorf("TestCleanup reported unexpected errors %v: no race was detected during the test", clean.errors)
+	}
+}
+
+// TestTestCleanup_ResetBetweenTests verifies RACEDETECTOR_RESET_BETWEEN_TESTS=1
+// resets the detector's shadow memory after a test's cleanup runs, so a
+// subsequent test's access to the same address isn't still flagged against
+// state left over from a race already reported.
+func TestTestCleanup_ResetBetweenTests(t *testing.T) {
+	Init()
+	defer Fini()
+
+	os.Setenv(resetBetweenTestsEnvVar, "1")
+	defer os.Unsetenv(resetBetweenTestsEnvVar)
+
+	first := &fakeTB{}
+	TestCleanup(first)
+	addr := uintptr(0xB000)
+	det.OnWrite(addr, goroutine.Alloc(20))
+	det.OnWrite(addr, goroutine.Alloc(21))
+	first.runCleanups()
+
+	if got := det.RacesDetected(); got != 0 {
+		t.Errorf("RacesDetected() = %d, want 0 after reset between tests", got)
+	}
+}
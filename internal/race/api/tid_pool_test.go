@@ -259,212 +259,90 @@ func TestTIDConcurrentFree(t *testing.T) {
 	}
 }
 
-// TestParseAllGIDs verifies parsing of runtime.Stack output.
-func TestParseAllGIDs(t *testing.T) {
-	// Sample runtime.Stack output.
-	stackTrace := []byte(`goroutine 1 [running]:
-main.main()
-	/path/to/main.go:10 +0x20
-
-goroutine 5 [chan receive]:
-main.worker()
-	/path/to/worker.go:20 +0x40
-
-goroutine 123 [semacquire]:
-sync.(*WaitGroup).Wait()
-	/path/to/sync.go:30 +0x60
-`)
-
-	gids := parseAllGIDs(stackTrace)
-
-	// Should extract GIDs: 1, 5, 123.
-	expected := []int64{1, 5, 123}
-	if len(gids) != len(expected) {
-		t.Fatalf("parseAllGIDs() returned %d GIDs, want %d", len(gids), len(expected))
-	}
-
-	for i, gid := range gids {
-		if gid != expected[i] {
-			t.Errorf("GID %d = %d, want %d", i, gid, expected[i])
-		}
-	}
-}
-
-// TestParseAllGIDs_EmptyInput verifies parsing empty input.
-func TestParseAllGIDs_EmptyInput(t *testing.T) {
-	gids := parseAllGIDs([]byte{})
-
-	if len(gids) != 0 {
-		t.Errorf("parseAllGIDs(empty) returned %d GIDs, want 0", len(gids))
-	}
-}
-
-// TestParseAllGIDs_NoGoroutines verifies parsing with no goroutine lines.
-func TestParseAllGIDs_NoGoroutines(t *testing.T) {
-	stackTrace := []byte("some random text\nwithout goroutine lines\n")
-	gids := parseAllGIDs(stackTrace)
-
-	if len(gids) != 0 {
-		t.Errorf("parseAllGIDs(no goroutines) returned %d GIDs, want 0", len(gids))
+// waitForOrphanReclaim runs a GC cycle a few times, giving the
+// runtime.AddCleanup fallback (synth-3613, reclaimOrphanedContext) a chance
+// to run on its own goroutine before the caller checks pool state. Cleanups
+// aren't guaranteed to have completed by the time GC() returns, so this is
+// best-effort, matching how allocTID itself treats reclamation.
+func waitForOrphanReclaim() {
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(20 * time.Millisecond)
 	}
 }
 
-// TestGetLiveGoroutineIDs verifies we can get all live GIDs.
-func TestGetLiveGoroutineIDs(t *testing.T) {
-	// Launch a few goroutines.
-	done := make(chan bool)
-	const numGoroutines = 5
-
-	for i := 0; i < numGoroutines; i++ {
-		go func() {
-			<-done
-		}()
-	}
-
-	// Get live GIDs.
-	gids := getLiveGoroutineIDs()
-
-	// Should have at least numGoroutines + 1 (test goroutine).
-	// There may be more due to Go runtime goroutines.
-	if len(gids) < numGoroutines+1 {
-		t.Errorf("getLiveGoroutineIDs() returned %d GIDs, want >= %d", len(gids), numGoroutines+1)
-	}
-
-	// Verify all GIDs are unique.
-	gidSet := make(map[int64]bool)
-	for _, gid := range gids {
-		if gidSet[gid] {
-			t.Errorf("Duplicate GID %d", gid)
-		}
-		gidSet[gid] = true
-	}
-
-	// Clean up goroutines.
-	close(done)
-}
-
-// TestCleanupDeadGoroutines verifies cleanup reclaims TIDs.
-func TestCleanupDeadGoroutines(t *testing.T) {
-	Init() // Initialize with TID pool
-
-	// Current GID (test goroutine).
-	testGID := getGoroutineID()
+// TestReclaimOrphanedContext verifies the runtime.AddCleanup fallback
+// reclaims a TID once its RaceContext becomes unreachable (synth-3613).
+//
+// contexts and contextCacheEntries deliberately keep every live goroutine's
+// RaceContext strongly reachable for as long as it might still be running
+// (see the package doc), so a context whose entry is still sitting in those
+// maps - e.g. a goroutine that simply never calls RaceGoEnd - never becomes
+// unreachable and this fallback never fires for it; that gap is unchanged
+// by this test. What this exercises is the case reclaimOrphanedContext
+// actually covers: an entry cleared from both maps by something other than
+// racegoend, whose RaceContext has no other referrers.
+func TestReclaimOrphanedContext(t *testing.T) {
+	initTIDPool()
 
-	// Launch 10 short-lived goroutines.
+	gidCh := make(chan int64, 1)
 	var wg sync.WaitGroup
-	for i := 0; i < 10; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			// Allocate context (gets TID).
-			ctx := getCurrentContext()
-			_ = ctx
-			// Goroutine exits here.
-		}()
-	}
-
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx := getCurrentContext()
+		_ = ctx.TID
+		gidCh <- getGoroutineID()
+	}()
+	gid := <-gidCh
 	wg.Wait()
 
-	// At this point, 10 goroutines are dead but their TIDs are still allocated.
-	// Pool should have 256 - 1 (main/test) - 10 (dead goroutines) = 245 TIDs.
-	// Actually, with Init(), main goroutine gets TID 0, so pool has 255 TIDs.
-	// After 10 allocations, pool has 255 - 10 = 245 TIDs.
+	// Clear this goroutine's entries the way a future spawn-instrumentation
+	// hook would, without going through racegoend - the case
+	// reclaimOrphanedContext exists for (see the package doc).
+	contexts.Delete(gid)
+	contextCacheEntries.Delete(gid)
 
-	// Check pool size before cleanup.
-	tidPoolMu.Lock()
-	poolSizeBefore := len(freeTIDs)
-	tidPoolMu.Unlock()
+	before := tidPoolLen()
+	waitForOrphanReclaim()
+	after := tidPoolLen()
 
-	// Should be around 245 (256 - 1 main - 10 allocated).
-	// Actually, Init() removes TID 0, so we start with 255, and after 10 allocs we have 245.
-	expectedBefore := 245
-	if poolSizeBefore != expectedBefore {
-		// This may vary due to runtime goroutines, so just log it.
-		t.Logf("Pool size before cleanup = %d, expected %d", poolSizeBefore, expectedBefore)
+	if after != before+1 {
+		t.Errorf("pool size after reclaim = %d, want %d (orphaned context's TID not reclaimed)", after, before+1)
 	}
+}
 
-	// Run cleanup - should reclaim the 10 TIDs from dead goroutines.
-	cleanupDeadGoroutines()
-
-	// Give cleanup time to complete (it scans runtime stacks).
-	time.Sleep(10 * time.Millisecond)
-
-	// Check pool size after cleanup.
+// tidPoolLen returns the current number of free TIDs.
+func tidPoolLen() int {
 	tidPoolMu.Lock()
-	poolSizeAfter := len(freeTIDs)
-	tidPoolMu.Unlock()
-
-	// Should have reclaimed 10 TIDs: 245 + 10 = 255.
-	// But test goroutine (GID testGID) is still alive with TID, so we have 255.
-	// The cleanup should have increased the pool size.
-	if poolSizeAfter < poolSizeBefore {
-		t.Errorf("Pool size after cleanup = %d, decreased from %d (expected increase)", poolSizeAfter, poolSizeBefore)
-	}
-
-	// Verify TID was reclaimed by checking we can allocate more.
-	// We should be able to allocate 255 TIDs.
-	tidsAllocated := 0
-	for i := 0; i < 260; i++ { // Try to allocate more than possible
-		tid := allocTID()
-		if tid == 0 && i >= 255 {
-			// Graceful degradation after exhaustion.
-			break
-		}
-		tidsAllocated++
-	}
-
-	if tidsAllocated < 250 {
-		t.Errorf("After cleanup, could only allocate %d TIDs, want >= 250", tidsAllocated)
-	}
-
-	t.Logf("Test GID: %d, Pool before cleanup: %d, Pool after cleanup: %d, TIDs allocated: %d",
-		testGID, poolSizeBefore, poolSizeAfter, tidsAllocated)
+	defer tidPoolMu.Unlock()
+	return len(freeTIDs)
 }
 
-// TestMaybeCleanup verifies periodic cleanup is triggered.
-func TestMaybeCleanup(t *testing.T) {
-	Init()
-
-	// Reset allocation counter.
-	allocCounter.Store(0)
+// TestContextTeardownReleaseOnce verifies release() only returns a TID to
+// the pool the first time it's called, whichever of racegoend or
+// reclaimOrphanedContext gets there first (synth-3613).
+func TestContextTeardownReleaseOnce(t *testing.T) {
+	initTIDPool()
 
-	// Call maybeCleanup 1000 times - should trigger cleanup once.
-	for i := 0; i < 1000; i++ {
-		maybeCleanup()
-	}
+	tid := allocTID()
+	teardown := &contextTeardown{tid: tid}
 
-	// Verify counter is 1000.
-	count := allocCounter.Load()
-	if count != 1000 {
-		t.Errorf("After 1000 maybeCleanup calls, counter = %d, want 1000", count)
+	poolBefore := func() int {
+		tidPoolMu.Lock()
+		defer tidPoolMu.Unlock()
+		return len(freeTIDs)
 	}
 
-	// Cleanup should have been triggered at count=1000.
-	// We can't easily verify cleanup ran, but we can verify no panic.
-	// Wait a bit for background cleanup goroutine.
-	time.Sleep(50 * time.Millisecond)
-}
-
-// TestMaybeCleanup_NoSpam verifies cleanup isn't triggered too often.
-func TestMaybeCleanup_NoSpam(t *testing.T) {
-	Init()
-
-	// Reset counter.
-	allocCounter.Store(0)
+	teardown.release()
+	afterFirst := poolBefore()
 
-	// Call maybeCleanup 500 times - should NOT trigger cleanup.
-	for i := 0; i < 500; i++ {
-		maybeCleanup()
-	}
+	teardown.release()
+	afterSecond := poolBefore()
 
-	// Verify counter is 500.
-	count := allocCounter.Load()
-	if count != 500 {
-		t.Errorf("After 500 maybeCleanup calls, counter = %d, want 500", count)
+	if afterSecond != afterFirst {
+		t.Errorf("second release() changed pool size (%d -> %d), want no-op", afterFirst, afterSecond)
 	}
-
-	// No cleanup should have run (threshold is 1000).
-	// We just verify no panic.
 }
 
 // TestIntegration_1000Goroutines tests 1000 concurrent goroutines with TID reuse.
@@ -474,8 +352,9 @@ func TestIntegration_1000Goroutines(t *testing.T) {
 	const numGoroutines = 1000
 	const batchSize = 100
 
-	// Launch goroutines in batches to trigger TID reuse.
-	// Each batch allocates 100 TIDs, then goroutines exit, freeing TIDs.
+	// Launch goroutines in batches, each allocating 100 TIDs from the pool.
+	// None of these call racegoend, so none of their TIDs come back - this
+	// is exercising that a 65536-deep pool comfortably absorbs that.
 	for batch := 0; batch < numGoroutines/batchSize; batch++ {
 		var wg sync.WaitGroup
 
@@ -487,16 +366,19 @@ func TestIntegration_1000Goroutines(t *testing.T) {
 				ctx := getCurrentContext()
 				// Do some work.
 				_ = ctx.TID
-				// Goroutine exits, TID should be reclaimed.
 			}()
 		}
 
 		wg.Wait()
 
-		// Trigger cleanup after each batch.
+		// These goroutines never call racegoend, so - see the package doc -
+		// reclaimOrphanedContext can't reclaim their TIDs; this pool is
+		// 65536 TIDs deep, so a mere 1000 never coming back doesn't come
+		// close to exhausting it either way. Still force a GC pass so any
+		// unrelated pending cleanup (e.g. from a previous test) gets a
+		// chance to run (synth-3613).
 		if batch%10 == 0 {
-			cleanupDeadGoroutines()
-			time.Sleep(10 * time.Millisecond) // Let cleanup run
+			waitForOrphanReclaim()
 		}
 	}
 
@@ -505,20 +387,18 @@ func TestIntegration_1000Goroutines(t *testing.T) {
 		t.Error("Detector disabled after 1000 goroutines")
 	}
 
-	// Run final cleanup and wait for it to complete.
-	cleanupDeadGoroutines()
-	time.Sleep(100 * time.Millisecond)
+	waitForOrphanReclaim()
 
 	// Verify pool has TIDs available.
 	tidPoolMu.Lock()
 	poolSize := len(freeTIDs)
 	tidPoolMu.Unlock()
 
-	// After cleanup, should have most TIDs back.
-	// We may not get all 255 back because some runtime goroutines may still be alive.
-	// But we should have at least 150+ available.
-	if poolSize < 150 {
-		t.Errorf("After 1000 goroutines with cleanup, pool size = %d, want >= 150", poolSize)
+	// 65536 TIDs deep, only 1000 ever allocated and none of them freed -
+	// plenty of headroom either way. This is mainly a smoke test that the
+	// detector survives 1000 concurrent allocations without panicking.
+	if poolSize < 64000 {
+		t.Errorf("After 1000 goroutines, pool size = %d, want >= 64000", poolSize)
 	}
 
 	t.Logf("After 1000 goroutines: pool size = %d, detector enabled = %v", poolSize, enabled.Load())
@@ -552,19 +432,21 @@ func TestIntegration_LongLivedAndShortLived(t *testing.T) {
 
 	wg.Wait()
 
-	// Run cleanup to reclaim short-lived TIDs.
-	cleanupDeadGoroutines()
-	time.Sleep(10 * time.Millisecond)
+	// None of these call racegoend either, long-lived or short-lived, so
+	// none of their TIDs come back - see TestIntegration_1000Goroutines.
+	// Force a GC pass so any unrelated pending cleanup gets a chance to run
+	// (synth-3613).
+	waitForOrphanReclaim()
 
-	// Verify pool has TIDs (short-lived ones reclaimed).
+	// Verify the pool absorbed 110 allocations (10 long-lived + 100
+	// short-lived) plus whatever main and the test runner hold, out of its
+	// 65536 depth, without panicking.
 	tidPoolMu.Lock()
 	poolSize := len(freeTIDs)
 	tidPoolMu.Unlock()
 
-	// Should have ~245 TIDs (256 - 1 main - 10 long-lived).
-	// Actually depends on cleanup efficiency.
-	if poolSize < 200 {
-		t.Errorf("After mixed lifetimes, pool size = %d, want >= 200", poolSize)
+	if poolSize < 65000 {
+		t.Errorf("After mixed lifetimes, pool size = %d, want >= 65000", poolSize)
 	}
 
 	// Clean up long-lived goroutines.
@@ -645,49 +527,14 @@ func BenchmarkFreeTID(b *testing.B) {
 	}
 }
 
-// BenchmarkGetLiveGoroutineIDs benchmarks goroutine ID enumeration.
-func BenchmarkGetLiveGoroutineIDs(b *testing.B) {
-	// Launch some goroutines to make it realistic.
-	done := make(chan bool)
-	for i := 0; i < 100; i++ {
-		go func() { <-done }()
-	}
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_ = getLiveGoroutineIDs()
-	}
-
-	close(done)
-}
-
-// BenchmarkCleanupDeadGoroutines benchmarks cleanup with realistic goroutine count.
-func BenchmarkCleanupDeadGoroutines(b *testing.B) {
-	Init()
-
-	// Create some contexts for cleanup to scan.
-	for i := 0; i < 100; i++ {
-		go func() {
-			ctx := getCurrentContext()
-			_ = ctx
-			time.Sleep(time.Millisecond)
-		}()
-	}
-
-	time.Sleep(50 * time.Millisecond) // Let goroutines start
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		cleanupDeadGoroutines()
-	}
-}
-
-// BenchmarkMaybeCleanup benchmarks cleanup trigger check.
-func BenchmarkMaybeCleanup(b *testing.B) {
-	Init()
+// BenchmarkContextTeardownRelease benchmarks the release path shared by
+// racegoend and reclaimOrphanedContext (synth-3613).
+func BenchmarkContextTeardownRelease(b *testing.B) {
+	initTIDPool()
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		maybeCleanup()
+		tid := allocTID()
+		(&contextTeardown{tid: tid}).release()
 	}
 }
@@ -0,0 +1,79 @@
+// writerange.go implements write instrumentation for multi-word values
+// reassigned as a whole - slice headers, strings, and interfaces - as
+// opposed to instrumenting only their first word (synth-3628, generalized
+// to strings and interfaces by synth-3629).
+//
+// A slice header is a 3-word value: data pointer, len, cap. A string or an
+// interface value is a 2-word value: (data pointer, len) for a string,
+// (type word, data word) for an interface. In every case the value's own
+// words are a different memory location than whatever they point at -
+// reassigning s = append(s, x) races with a concurrent access to s's own
+// header, not to an element of its backing array; reassigning an interface
+// variable races with a concurrent access to that variable's own type/data
+// words, not to whatever concrete value either word happens to reference.
+// An interface assignment in particular can "tear" - one goroutine observes
+// the new type word paired with the old data word - if only one of its two
+// words is checked, which is why this is one of the most consequential
+// races to get right.
+//
+// Checking only the first word (as a plain RaceWrite would) misses a race
+// confined to a later word, and folding all the words into one address
+// would either under- or over-report depending on which word actually
+// collided. Giving each word its own shadow memory address - the same "one
+// address per unit of interest" technique synth-3627 introduced for array
+// elements - lets the existing Detector.OnWrite check tell them apart with
+// no changes to shadow memory itself.
+package api
+
+// WordSize is the size, in bytes, of one machine word - a plain pointer or
+// int-sized field on every architecture this package supports.
+const WordSize = 8
+
+// SliceHeaderWords is the number of machine words in a Go slice header:
+// data pointer, len, and cap.
+const SliceHeaderWords = 3
+
+// StringWords is the number of machine words in a Go string header: data
+// pointer and len.
+const StringWords = 2
+
+// InterfaceWords is the number of machine words in a Go interface value:
+// the type word and the data word.
+const InterfaceWords = 2
+
+// RaceWriteRange is an exported wrapper for racewriterange, for
+// demonstration purposes.
+//
+// In production code, you should compile with -race flag, which
+// automatically instruments memory accesses. This function is provided for
+// examples and testing purposes only.
+//
+// Parameters:
+//   - addr: Address of the value's first word
+//   - words: Number of consecutive machine words the value occupies
+func RaceWriteRange(addr uintptr, words int) {
+	racewriterange(addr, words)
+}
+
+// racewriterange is called by compiler instrumentation when a slice-,
+// string-, or interface-typed variable itself is reassigned, rather than
+// one of a slice's elements or a struct's field. It checks each of the
+// value's words as an independent write access at addr, addr+WordSize,
+// addr+2*WordSize, and so on - reusing racewritebatch so the
+// enabled/ignore-region checks below run once for the whole value instead
+// of once per word.
+//
+// Parameters:
+//   - addr: Address of the value's first word
+//   - words: Number of consecutive machine words to check
+func racewriterange(addr uintptr, words int) {
+	if words <= 0 {
+		return
+	}
+
+	addrs := make([]uintptr, words)
+	for i := range addrs {
+		addrs[i] = addr + uintptr(i)*WordSize
+	}
+	racewritebatch(addrs...)
+}
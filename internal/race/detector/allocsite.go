@@ -0,0 +1,99 @@
+package detector
+
+import (
+	"sort"
+	"sync"
+)
+
+// === Heap Object Attribution (synth-3632) ===
+//
+// Knowing where two racing accesses happened is only half the story for a
+// heap-allocated object: the official Go race detector also prints where
+// the object itself came from, which is often the fastest way to tell "a
+// slice's backing array got resized out from under a reader" from "two
+// goroutines share a struct pointer they shouldn't". OnMalloc (synth-3581)
+// already runs at every allocation site to clear stale shadow cells, so it
+// is also the only place that ever sees an allocation's base address, size,
+// and call stack together - after it returns, that association is gone
+// unless something records it.
+//
+// allocIndex is that record: a sorted-by-base-address index of live
+// allocations, so a race report on some address addr can answer "is addr
+// inside a tracked object, and if so, which one" - an interval-containment
+// query ShadowMemory itself has no notion of, hence a separate index
+// alongside it rather than a shadow memory field.
+type allocSite struct {
+	base uintptr
+	size uintptr
+	pcs  []uintptr
+	gid  uint32
+}
+
+// allocIndex is a sorted-by-base-address list of live heap allocations,
+// queried by address to attribute a race to the object it landed in.
+//
+// A plain sorted slice under a mutex, not a sync.Map, because the query
+// this exists to answer - "find the allocation whose range contains addr"
+// - needs an ordered floor lookup (binary search for the largest base <=
+// addr), which sync.Map cannot do. Allocation and race reporting are both
+// far colder paths than the read/write fast path ShadowMemory optimizes
+// for, so a mutex-guarded slice's O(log n) lookup and O(n) insert are an
+// acceptable trade for the simplicity.
+type allocIndex struct {
+	mu    sync.Mutex
+	sites []allocSite
+}
+
+// record adds or replaces the allocation covering [base, base+size), along
+// with the stack that produced it and the id of the goroutine that called
+// OnMalloc.
+//
+// Replacing rather than merely appending matters because Go's allocator
+// reuses addresses: a later allocation at a base this index already knows
+// about means the earlier occupant is dead, and any race report on that
+// address from now on belongs to the new object, not the old one.
+func (idx *allocIndex) record(base, size uintptr, pcs []uintptr, gid uint32) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	i := sort.Search(len(idx.sites), func(i int) bool { return idx.sites[i].base >= base })
+	site := allocSite{base: base, size: size, pcs: pcs, gid: gid}
+	if i < len(idx.sites) && idx.sites[i].base == base {
+		idx.sites[i] = site
+		return
+	}
+	idx.sites = append(idx.sites, allocSite{})
+	copy(idx.sites[i+1:], idx.sites[i:])
+	idx.sites[i] = site
+}
+
+// lookup finds the allocation whose [base, base+size) range contains addr,
+// if any is currently tracked.
+func (idx *allocIndex) lookup(addr uintptr) (allocSite, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	i := sort.Search(len(idx.sites), func(i int) bool { return idx.sites[i].base > addr }) - 1
+	if i < 0 {
+		return allocSite{}, false
+	}
+	site := idx.sites[i]
+	if addr < site.base+site.size {
+		return site, true
+	}
+	return allocSite{}, false
+}
+
+// clear discards every tracked allocation, called from Detector.Reset() so
+// a race after a reset is never attributed to an allocation from before it.
+func (idx *allocIndex) clear() {
+	idx.mu.Lock()
+	idx.sites = nil
+	idx.mu.Unlock()
+}
+
+// allocSiteForAddr returns the allocation that addr falls inside, if
+// OnMalloc ever recorded one covering it.
+func (d *Detector) allocSiteForAddr(addr uintptr) (allocSite, bool) {
+	return d.allocSites.lookup(addr)
+}
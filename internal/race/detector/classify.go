@@ -0,0 +1,178 @@
+package detector
+
+import (
+	"runtime"
+	"strings"
+)
+
+// RaceKind labels the likely shape of a detected race (synth-3606), so
+// teams triaging hundreds of findings from a large suite can group and
+// prioritize by pattern instead of reading every stack trace by hand.
+//
+// Classify's heuristics only have a RaceReport's addresses, access types,
+// and stack traces to work with - no source-level variable names or types
+// - so a RaceKind is a best-effort label, not a guarantee. When nothing
+// matches a more specific heuristic, RaceKindGeneric is returned.
+type RaceKind string
+
+const (
+	// RaceKindCounter is a write-write race whose stack traces mention a
+	// counter/increment-style function name (e.g. "count", "total",
+	// "sum") - typically an unsynchronized `x++` or `x += n`.
+	RaceKindCounter RaceKind = "counter"
+
+	// RaceKindLazyInit is a race whose stack traces mention a
+	// singleton/lazy-initialization-style function name (e.g.
+	// "getInstance", "lazyInit", "ensureInit") without any sync.Once
+	// frame guarding it - the classic double-checked-locking bug.
+	RaceKindLazyInit RaceKind = "lazy-init"
+
+	// RaceKindMapAccess is a race whose stack traces pass through the Go
+	// runtime's map implementation (mapassign/mapaccess/mapdelete),
+	// meaning two goroutines are reading/writing a plain map without a
+	// sync.Map or mutex - a race the Go runtime itself may fatally abort
+	// on, independent of what racedetector reports.
+	RaceKindMapAccess RaceKind = "map-access"
+
+	// RaceKindBenignFlag is a read-write race whose stack traces mention
+	// a status/flag-style function name (e.g. "isReady", "isDone",
+	// "isRunning") - often a benign race in practice (a stale read of a
+	// boolean that settles quickly), but still UB under the Go memory
+	// model and worth fixing with an atomic or channel.
+	RaceKindBenignFlag RaceKind = "benign-flag"
+
+	// RaceKindGeneric is any race that doesn't match a more specific
+	// heuristic above.
+	RaceKindGeneric RaceKind = "generic"
+)
+
+// Severity ranks how urgently a classified race is worth triaging
+// (synth-3606).
+type Severity string
+
+const (
+	// SeverityCritical marks races most likely to cause crashes or data
+	// corruption in production (e.g. concurrent map writes).
+	SeverityCritical Severity = "critical"
+	// SeverityHigh marks races likely to produce wrong results.
+	SeverityHigh Severity = "high"
+	// SeverityMedium marks races of unclear real-world impact.
+	SeverityMedium Severity = "medium"
+	// SeverityLow marks races that are likely benign in practice, but
+	// still worth fixing since they remain undefined behavior.
+	SeverityLow Severity = "low"
+)
+
+// Classification is Classify's result: a race's kind and severity, plus a
+// short human-readable reason for the classification.
+type Classification struct {
+	Kind     RaceKind
+	Severity Severity
+	Reason   string
+}
+
+// counterNameHints, lazyInitNameHints, and flagNameHints are the
+// case-insensitive substrings Classify looks for in a stack trace's
+// function names to recognize each RaceKind. They're deliberately broad
+// (matching e.g. "IncrementTotal" as well as "count") since a false
+// positive here only costs a mislabeled triage bucket, not a missed race.
+var (
+	counterNameHints  = []string{"count", "counter", "increment", "total", "sum"}
+	lazyInitNameHints = []string{"lazyinit", "singleton", "getinstance", "ensureinit", "ensure"}
+	flagNameHints     = []string{"isready", "isdone", "isrunning", "isclosed", "status", "flag"}
+)
+
+// Classify labels report with its likely RaceKind and a Severity, using
+// only information already captured on the report (race type, and the
+// current/previous accesses' stack traces) - see RaceKind.
+func Classify(report *RaceReport) Classification {
+	raceType := raceTypeFromKey(report.DeduplicationKey)
+	names := append(frameFunctionNames(report.Current.StackTrace), frameFunctionNames(report.Previous.StackTrace)...)
+
+	switch {
+	case anyFrameContainsRuntimeMap(names):
+		return Classification{
+			Kind:     RaceKindMapAccess,
+			Severity: SeverityCritical,
+			Reason:   "stack trace passes through the Go runtime's map implementation - concurrent map access can fatally crash the program independent of this report",
+		}
+	case anyFrameNameContains(names, lazyInitNameHints):
+		return Classification{
+			Kind:     RaceKindLazyInit,
+			Severity: SeverityCritical,
+			Reason:   "stack trace suggests a lazy-initialization/singleton pattern without a guarding sync.Once - classic double-checked-locking bug",
+		}
+	case raceType == RaceTypeReadWrite || raceType == RaceTypeWriteRead:
+		if anyFrameNameContains(names, flagNameHints) {
+			return Classification{
+				Kind:     RaceKindBenignFlag,
+				Severity: SeverityLow,
+				Reason:   "read-write race on what looks like a status/flag value - often benign in practice, but still undefined behavior",
+			}
+		}
+	case raceType == RaceTypeWriteWrite:
+		if anyFrameNameContains(names, counterNameHints) {
+			return Classification{
+				Kind:     RaceKindCounter,
+				Severity: SeverityMedium,
+				Reason:   "write-write race on what looks like a counter - typically an unsynchronized increment, producing an undercounted total rather than a crash",
+			}
+		}
+	}
+
+	severity := SeverityMedium
+	if raceType == RaceTypeWriteWrite {
+		severity = SeverityHigh
+	}
+	return Classification{
+		Kind:     RaceKindGeneric,
+		Severity: severity,
+		Reason:   "no more specific pattern matched",
+	}
+}
+
+// frameFunctionNames symbolizes pcs into their fully-qualified function
+// names, for the name-based heuristics in Classify above. Unlike
+// formatStackTrace, this applies no filtering: even a runtime-internal
+// frame name is a useful signal here (e.g. detecting a map race via
+// runtime.mapassign).
+func frameFunctionNames(pcs []uintptr) []string {
+	if len(pcs) == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(pcs)
+	names := make([]string, 0, len(pcs))
+	for {
+		frame, more := frames.Next()
+		names = append(names, frame.Function)
+		if !more {
+			break
+		}
+	}
+	return names
+}
+
+// anyFrameContainsRuntimeMap reports whether names includes one of the Go
+// runtime's map implementation functions.
+func anyFrameContainsRuntimeMap(names []string) bool {
+	for _, name := range names {
+		if strings.HasPrefix(name, "runtime.map") {
+			return true
+		}
+	}
+	return false
+}
+
+// anyFrameNameContains reports whether any of names case-insensitively
+// contains one of hints.
+func anyFrameNameContains(names []string, hints []string) bool {
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		for _, hint := range hints {
+			if strings.Contains(lower, hint) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package detector
+
+import "testing"
+
+// incrementCounter, getInstanceSingleton, and isReadyFlag exist only so
+// their own frame appears at the top of a captured stack trace, giving
+// Classify's function-name heuristics something to match against.
+func incrementCounter() []uintptr     { return captureStackTrace(2) }
+func getInstanceSingleton() []uintptr { return captureStackTrace(2) }
+func isReadyFlag() []uintptr          { return captureStackTrace(2) }
+
+func reportWith(raceType string, currentStack []uintptr) *RaceReport {
+	return &RaceReport{
+		DeduplicationKey: generateDeduplicationKey(raceType, 1, 2),
+		Current:          AccessInfo{Type: AccessWrite, StackTrace: currentStack},
+		Previous:         AccessInfo{Type: AccessWrite},
+	}
+}
+
+func TestClassify_Counter(t *testing.T) {
+	report := reportWith(RaceTypeWriteWrite, incrementCounter())
+
+	got := Classify(report)
+	if got.Kind != RaceKindCounter {
+		t.Errorf("Kind = %q, want %q (reason: %s)", got.Kind, RaceKindCounter, got.Reason)
+	}
+	if got.Severity != SeverityMedium {
+		t.Errorf("Severity = %q, want %q", got.Severity, SeverityMedium)
+	}
+}
+
+func TestClassify_LazyInit(t *testing.T) {
+	report := reportWith(RaceTypeWriteWrite, getInstanceSingleton())
+
+	got := Classify(report)
+	if got.Kind != RaceKindLazyInit {
+		t.Errorf("Kind = %q, want %q (reason: %s)", got.Kind, RaceKindLazyInit, got.Reason)
+	}
+	if got.Severity != SeverityCritical {
+		t.Errorf("Severity = %q, want %q", got.Severity, SeverityCritical)
+	}
+}
+
+func TestClassify_BenignFlag(t *testing.T) {
+	report := reportWith(RaceTypeReadWrite, isReadyFlag())
+
+	got := Classify(report)
+	if got.Kind != RaceKindBenignFlag {
+		t.Errorf("Kind = %q, want %q (reason: %s)", got.Kind, RaceKindBenignFlag, got.Reason)
+	}
+	if got.Severity != SeverityLow {
+		t.Errorf("Severity = %q, want %q", got.Severity, SeverityLow)
+	}
+}
+
+func TestClassify_GenericFallback(t *testing.T) {
+	report := reportWith(RaceTypeWriteWrite, nil)
+
+	got := Classify(report)
+	if got.Kind != RaceKindGeneric {
+		t.Errorf("Kind = %q, want %q (reason: %s)", got.Kind, RaceKindGeneric, got.Reason)
+	}
+	if got.Severity != SeverityHigh {
+		t.Errorf("write-write generic Severity = %q, want %q", got.Severity, SeverityHigh)
+	}
+}
+
+func TestAnyFrameContainsRuntimeMap(t *testing.T) {
+	cases := []struct {
+		names []string
+		want  bool
+	}{
+		{[]string{"runtime.mapassign_faststr", "main.main"}, true},
+		{[]string{"runtime.mapaccess2", "main.main"}, true},
+		{[]string{"main.main", "main.worker"}, false},
+	}
+	for _, tt := range cases {
+		if got := anyFrameContainsRuntimeMap(tt.names); got != tt.want {
+			t.Errorf("anyFrameContainsRuntimeMap(%v) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
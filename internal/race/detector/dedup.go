@@ -0,0 +1,82 @@
+package detector
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// dedupShardCount is the number of independent shards dedupSet splits its
+// keys across (synth-3634). A power of two so shard selection is a mask,
+// not a modulo. 64 is generous slack for the handful of distinct race
+// sites a real program racks up before someone fixes them - this isn't
+// sized for throughput so much as for keeping any one shard's lock
+// uncontended when a burst of first-time races land close together.
+const dedupShardCount = 64
+
+// dedupShard is one independently-locked bucket of dedupSet.
+type dedupShard struct {
+	mu      sync.Mutex
+	entries map[string]struct{}
+}
+
+// dedupSet is a sharded, concurrency-safe set of race deduplication keys
+// (synth-3634), replacing a single sync.Map.
+//
+// sync.Map is tuned for a read-mostly workload over a roughly stable key
+// set - lookups fall through to a lock-free read map, and only a miss
+// (or a delete) touches its mutex-protected dirty map. Race dedup is the
+// opposite: every DISTINCT race is, by definition, a first-time write, and
+// a buggy program's distinct races tend to cluster in time rather than
+// arrive one at a time, so a burst of new races all serialize on that one
+// dirty-map mutex right when reportRaceV2WithStack most wants to keep
+// moving. Splitting the key space across dedupShardCount independently
+// locked shards lets those bursts proceed in parallel instead.
+type dedupSet struct {
+	shards [dedupShardCount]dedupShard
+}
+
+// newDedupSet returns an empty dedupSet ready for use.
+func newDedupSet() *dedupSet {
+	ds := &dedupSet{}
+	for i := range ds.shards {
+		ds.shards[i].entries = make(map[string]struct{})
+	}
+	return ds
+}
+
+// shardFor picks key's shard by hashing it with FNV-1a (the same hash
+// family cmd/racedetector/instrument uses for symbol ids) and masking down
+// to dedupShardCount - stable across calls, so the same key always lands
+// in the same shard regardless of which goroutine looks it up.
+func (ds *dedupSet) shardFor(key string) *dedupShard {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return &ds.shards[h.Sum64()&(dedupShardCount-1)]
+}
+
+// checkAndAdd reports whether key was already present, adding it if not -
+// dedupSet's equivalent of sync.Map.LoadOrStore, but the atomic
+// check-and-set only needs to hold key's own shard's lock rather than
+// contending with every other key in the set.
+func (ds *dedupSet) checkAndAdd(key string) (alreadyPresent bool) {
+	shard := ds.shardFor(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if _, ok := shard.entries[key]; ok {
+		return true
+	}
+	shard.entries[key] = struct{}{}
+	return false
+}
+
+// clear discards every tracked key, called from Detector.Reset() so a race
+// reported before the reset can be reported again afterward.
+func (ds *dedupSet) clear() {
+	for i := range ds.shards {
+		ds.shards[i].mu.Lock()
+		ds.shards[i].entries = make(map[string]struct{})
+		ds.shards[i].mu.Unlock()
+	}
+}
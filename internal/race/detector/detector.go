@@ -1,18 +1,30 @@
 package detector
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/kolkov/racedetector/internal/race/epoch"
 	"github.com/kolkov/racedetector/internal/race/goroutine"
+	"github.com/kolkov/racedetector/internal/race/logging"
 	"github.com/kolkov/racedetector/internal/race/shadowmem"
 	"github.com/kolkov/racedetector/internal/race/syncshadow"
 )
 
+// log is detector's internal diagnostic logger (synth-3622), silent unless
+// RACEDETECTOR_DEBUG enables it - see internal/race/logging.
+var log = logging.New("detector")
+
+// errProfilingDisabled is returned by WriteOverheadProfile when the
+// detector was created without DetectorOptions.ProfilingEnabled.
+var errProfilingDisabled = errors.New("detector: profiling not enabled (set DetectorOptions.ProfilingEnabled)")
+
 // DetectorOptions configures the race detector behavior (v0.3.0).
 //
 // Use NewDetectorWithOptions() to create a detector with custom options.
@@ -52,6 +64,230 @@ type DetectorOptions struct {
 	// - Rate=1000: Check 1 in 1000 accesses (~90% overhead reduction)
 	// Default: 1 (no sampling).
 	SampleRate uint64
+
+	// WarmupDuration, when > 0 and SamplingEnabled is true, checks every
+	// access (as if SamplingEnabled were false) until this much time has
+	// passed since the detector was created, then switches to SampleRate
+	// (synth-3639). Initialization races - two setup paths racing to
+	// populate the same shared state - are both the most common kind of
+	// race and the most likely to be missed by sampling, since they only
+	// happen once, early, rather than recurring throughout a program's run.
+	// Default: 0 (SampleRate applies immediately).
+	WarmupDuration time.Duration
+
+	// OverheadTargetPercent, when > 0, see SamplerConfig.OverheadTargetPercent
+	// - puts the sampling rate under feedback control targeting this
+	// overhead percentage instead of a fixed SampleRate (synth-3640).
+	// Enables sampling even if SamplingEnabled is false, starting from
+	// SampleRate (or full detection if that's also unset) and adjusting
+	// from there once the first measurement window completes.
+	OverheadTargetPercent float64
+
+	// HistorySize configures how many recent accesses per shadow cell are
+	// retained for race reports (v0.4.0), mirroring TSan's history_size knob.
+	// When a race is detected, the report can then show several prior
+	// accesses to the same cell instead of only the single most recent one,
+	// reducing cases where the true racing partner was already overwritten
+	// by an unrelated access before the race was detected.
+	// - 0 (default): History tracking disabled, zero overhead.
+	// - N > 0: Retain up to N accesses per cell (internally capped at 8).
+	HistorySize int
+
+	// ProfilingEnabled turns on per-call-site overhead profiling (v0.5.0).
+	// When enabled, every OnRead/OnWrite/OnAcquire call records its
+	// wall-clock duration against the call site that triggered it (see
+	// Profiler), so WriteOverheadProfile can report which code paths
+	// dominate the detector's overhead.
+	// Default: false. Profiling captures a full stack trace per call
+	// (~500ns, the same cost class as history tracking's stack capture),
+	// so it is opt-in rather than always-on.
+	ProfilingEnabled bool
+
+	// Symbolize configures how file paths in race report stack traces are
+	// rewritten (v0.5.0), so reports stay stable and readable across
+	// machines - e.g. in CI logs, where the build path differs from a
+	// developer's machine. See SymbolizeOptions.
+	// Default: zero value, which leaves paths unchanged.
+	Symbolize SymbolizeOptions
+
+	// RecordSchedule, when non-nil, logs every OnRead/OnWrite event to this
+	// writer as it happens (v0.5.0), so a flaky race can be captured once
+	// and reproduced on demand with ReplaySchedule below. See
+	// ScheduleRecorder.
+	// Default: nil (no recording, zero overhead).
+	RecordSchedule io.Writer
+
+	// ReplaySchedule, when non-empty, re-drives the interleaving a prior
+	// ScheduleRecorder captured: each OnRead/OnWrite blocks until the trace
+	// says it's that goroutine's turn (v0.5.0). See ScheduleGate and
+	// LoadSchedule for reading a trace file back into this slice.
+	// Default: nil (no replay).
+	ReplaySchedule []ScheduleEvent
+
+	// StressEnabled turns on randomized scheduling perturbation at every
+	// OnRead/OnWrite (v0.5.0): a runtime.Gosched or brief sleep is injected
+	// with probability stressYieldProbability, seeded by StressSeed, so
+	// interleavings that are rare under the normal scheduler show up more
+	// often across repeated runs. See StressScheduler and the
+	// `racedetector test -stress` flag, which drives a program through many
+	// runs with different seeds and reports the seed of any run that found
+	// a race.
+	// Default: false (no perturbation, zero overhead).
+	StressEnabled bool
+
+	// StressSeed seeds the StressScheduler's perturbation sequence when
+	// StressEnabled is true, so a specific run (and whatever race it
+	// surfaced) can be reproduced exactly by reusing the same seed.
+	// Default: 0.
+	StressSeed int64
+
+	// HappensBeforeGraphEnabled turns on full happens-before graph
+	// recording (v0.5.0): every OnRead/OnWrite/OnAcquire/OnRelease/
+	// OnReleaseMerge call is logged as a graph node with a snapshot of the
+	// acting goroutine's vector clock, so WriteHappensBeforeGraph can
+	// export the whole graph for post-mortem analysis of why a race was or
+	// wasn't reported. See HBGraphRecorder.
+	// Default: false (no recording, zero overhead).
+	HappensBeforeGraphEnabled bool
+
+	// MaxShadowBytes caps the detector's estimated shadow memory footprint
+	// (synth-3578). Once GetOrCreate would push the estimate over this
+	// cap, the least-recently-touched cell sampled from the same shard is
+	// evicted to make room, and ShadowMemory.Evictions() is incremented -
+	// so the detector can keep running inside a memory-constrained
+	// container without OOM-killing the instrumented program, at the cost
+	// of losing detection coverage for whichever addresses get evicted.
+	// Default: 0 (unbounded, backward compatible).
+	MaxShadowBytes uint64
+
+	// MaxSyncShadowBytes caps the detector's estimated sync-shadow memory
+	// footprint (synth-3620) - the SyncVar entries tracking mutexes,
+	// channels, WaitGroups, and the like, keyed by sync-primitive address
+	// (see syncshadow.SyncShadow). These were previously never reclaimed,
+	// so a program that creates many short-lived channels or mutexes
+	// (each a distinct address, never reused) grows this map without
+	// bound. Once GetOrCreate would push the estimate over this cap, the
+	// least-recently-touched entry sampled from the map is reclaimed to
+	// make room, and SyncShadow.Evictions() is incremented - at the cost
+	// of losing detection coverage for whichever sync primitive gets
+	// reclaimed. See SyncVarCount for the live-entry accounting this
+	// backs.
+	// Default: 0 (unbounded, backward compatible).
+	MaxSyncShadowBytes uint64
+
+	// MMapShadowEnabled turns on a direct-mapped, mmap-backed epoch shadow
+	// (synth-3579) covering [MMapShadowBase, MMapShadowBase+MMapShadowSpan)
+	// as a fast-path mirror in front of the normal shadow memory: an
+	// OnWrite/OnRead for an address in that range whose stored epoch
+	// already matches the current one returns immediately via array
+	// arithmetic, skipping the per-goroutine cache and ShadowMemory's
+	// sharded sync.Map entirely. It can only ever short-circuit where the
+	// existing same-epoch checks already would, so it never changes what
+	// races are detected - only how fast the already-race-free case is.
+	// Requires linux/amd64 or linux/arm64; on any other platform, or if
+	// the underlying mmap(2) call fails, this is silently ignored and the
+	// detector behaves as if it were false. See shadowmem.MMapEpochShadow.
+	// Default: false.
+	MMapShadowEnabled bool
+
+	// MMapShadowBase is the first address covered by the mmap shadow when
+	// MMapShadowEnabled is true, typically the start of a heap or stack
+	// region the caller knows is hot. Default: 0.
+	MMapShadowBase uintptr
+
+	// MMapShadowSpan is the number of bytes covered by the mmap shadow
+	// when MMapShadowEnabled is true, rounded up to a multiple of 8.
+	// Default: 0, which combined with MMapShadowEnabled reserves no
+	// coverage - callers must set this to a non-zero value to see any
+	// effect.
+	MMapShadowSpan uintptr
+
+	// RaceQueueBlockOnFull selects raceQueue's backpressure policy
+	// (synth-3588) for the rare case where the reporter goroutine can't
+	// keep up with a flood of distinct simultaneous races and raceQueue
+	// (see racequeue.go) fills up.
+	//   - false (default): drop the report and count it in raceQueue's
+	//     dropped counter - detection and RacesDetected already happened
+	//     before enqueueRace was reached, so only the human-readable
+	//     report is lost. Bounded overhead, appropriate for production.
+	//   - true: OnWrite/OnRead's caller blocks (via runtime.Gosched, not a
+	//     lock) until the reporter goroutine frees a slot. Guarantees every
+	//     race gets a report, at the cost of an instrumented goroutine
+	//     stalling behind a slow reporter - appropriate for tests and
+	//     offline analysis (AnalyzeTrace, CompareToOracle) that need a
+	//     complete report set more than they need throughput.
+	RaceQueueBlockOnFull bool
+
+	// SingleThreaded tells the detector it is running on a target that is
+	// cooperatively scheduled onto a single OS thread - GOOS=js or
+	// GOOS=wasip1 today (synth-3615) - where goroutines never truly execute
+	// in parallel but still interleave at scheduling points (channel ops,
+	// blocking calls, explicit Gosched), so happens-before tracking is
+	// still required for correctness. This is not a detection shortcut:
+	// every OnRead/OnWrite still goes through the same epoch/vector-clock
+	// comparison as on any other target, because a real race - two
+	// unsynchronized accesses in an order the program didn't intend - is
+	// just as reachable through cooperative interleaving as through actual
+	// parallelism.
+	//
+	// What it does change: RaceQueueBlockOnFull's spin-wait for a free
+	// queue slot assumes the reporter goroutine is running concurrently on
+	// another OS thread and will drain the queue while the caller waits.
+	// On a single OS thread that assumption doesn't hold the same way -
+	// blocking to wait on a goroutine that can only run once the blocking
+	// caller yields is a needless risk to take for a report that can just
+	// as safely be dropped and counted. When SingleThreaded is true, the
+	// detector uses the drop-and-count policy regardless of what
+	// RaceQueueBlockOnFull was set to.
+	//
+	// NewDetectorWithOptions does not set this from runtime.GOOS - the
+	// caller (typically api.Init, reading RACEDETECTOR_SINGLE_THREADED or
+	// detecting GOOS itself) decides, so a detector embedded directly via
+	// this package works the same on every platform unless asked otherwise.
+	// Default: false.
+	SingleThreaded bool
+
+	// MuteAfterReport poisons a shadow cell's address the first time a race
+	// is reported on it (synth-3636): OnWrite/OnRead then return
+	// immediately for that address on every later access, skipping the
+	// FastTrack happens-before check (and the SmartTrack ownership fast
+	// path) entirely rather than merely deduplicating the report the way
+	// reportedRaces already does. Deduplication still stops the same
+	// stderr spam on its own, but it doesn't save the detection work
+	// itself - a known racy hot variable accessed millions of times still
+	// pays for a full check on every one of those accesses. This trades
+	// that cost away, at the price of never detecting a second, different
+	// race at the same address (e.g. one introduced by a later fix to the
+	// first) for the rest of the run.
+	// Default: false (every access is always checked, matching
+	// pre-synth-3636 behavior).
+	MuteAfterReport bool
+
+	// Export configures the remote report sink (synth-3602): when
+	// Export.URL is non-empty, every newly reported race is also POSTed
+	// there as JSON or an OTLP log record, so a fleet running the detector
+	// in canary mode can centralize findings instead of only ever seeing
+	// them in each instance's own stderr. See ExportOptions.
+	// Default: zero value, which leaves exporting disabled.
+	Export ExportOptions
+
+	// Report configures how much of a race's stack trace is printed and
+	// which frames are filtered out (synth-3605), so reports stay concise
+	// and point directly at user code instead of scrolling through runtime
+	// plumbing and compiler-generated wrappers. See ReportOptions.
+	// Default: zero value, which matches pre-synth-3605 behavior exactly.
+	Report ReportOptions
+
+	// OnReport, when non-nil, is called synchronously with each newly
+	// detected race, in addition to (not instead of) the stderr report,
+	// exporter, and tracer (synth-3608). This is the extension point the
+	// public race.Detector façade uses to let an embedding tool - a
+	// dynamic-analysis tool, interpreter, or simulator driving its own
+	// OnRead/OnWrite/sync-hook calls instead of going through Init/Fini -
+	// react to a race directly instead of parsing stderr or polling
+	// RacesDetected.
+	// Default: nil (no callback).
+	OnReport func(report *RaceReport)
 }
 
 // PromotionStats tracks adaptive representation statistics (Phase 3).
@@ -71,6 +307,50 @@ type PromotionStats struct {
 	PromotedVars  uint64 // Current number of promoted variables.
 }
 
+// statsCounters holds PromotionStats as per-field atomic counters (v0.4.0).
+//
+// OnRead/OnWrite previously took d.mu on every single access just to bump a
+// stats counter, serializing all goroutines through one lock regardless of
+// how many CPUs were actually doing race detection work. Each field here is
+// updated independently with a plain atomic add, so concurrent goroutines
+// touching different (or the same) counters never block on each other.
+// GetPromotionStats() aggregates the counters into a PromotionStats snapshot
+// on demand; that aggregation is not atomic across fields, but stats are
+// informational (used for tuning/monitoring), not correctness-critical.
+type statsCounters struct {
+	totalReads    atomic.Uint64
+	totalWrites   atomic.Uint64
+	promotions    atomic.Uint64
+	demotions     atomic.Uint64
+	fastPathReads atomic.Uint64
+	slowPathReads atomic.Uint64
+	promotedVars  atomic.Int64 // Signed: Promotions increment it, Demotions decrement it.
+}
+
+// snapshot returns a PromotionStats copy of the current counter values.
+func (sc *statsCounters) snapshot() PromotionStats {
+	return PromotionStats{
+		TotalReads:    sc.totalReads.Load(),
+		TotalWrites:   sc.totalWrites.Load(),
+		Promotions:    sc.promotions.Load(),
+		Demotions:     sc.demotions.Load(),
+		FastPathReads: sc.fastPathReads.Load(),
+		SlowPathReads: sc.slowPathReads.Load(),
+		PromotedVars:  uint64(sc.promotedVars.Load()),
+	}
+}
+
+// reset zeroes all counters. Not safe for concurrent use with OnRead/OnWrite.
+func (sc *statsCounters) reset() {
+	sc.totalReads.Store(0)
+	sc.totalWrites.Store(0)
+	sc.promotions.Store(0)
+	sc.demotions.Store(0)
+	sc.fastPathReads.Store(0)
+	sc.slowPathReads.Store(0)
+	sc.promotedVars.Store(0)
+}
+
 // Detector implements the core FastTrack race detection algorithm.
 //
 // It maintains global state including shadow memory (tracking access history
@@ -97,32 +377,249 @@ type Detector struct {
 	// This is nil when sampling is disabled for zero overhead.
 	sampler *Sampler
 
-	// racesDetected counts the total number of races found.
-	// This is used for testing and reporting purposes.
-	racesDetected int
+	// profiler attributes OnRead/OnWrite/OnAcquire wall-clock time to call
+	// sites (v0.5.0). Nil when profiling is disabled for zero overhead.
+	profiler *Profiler
+
+	// symbolizer rewrites file paths in race report stack traces (v0.5.0).
+	// Nil when DetectorOptions.Symbolize is the zero value, in which case
+	// reports fall back to the unrewritten paths runtime.CallersFrames
+	// returns.
+	symbolizer *Symbolizer
+
+	// reportOpts controls stack depth and frame filtering when formatting a
+	// race report (synth-3605). Always valid (not a pointer): its zero
+	// value reproduces the fixed filtering formatStackTrace applied before
+	// synth-3605, so an unconfigured Detector's output is unchanged.
+	reportOpts ReportOptions
+
+	// recorder logs the OnRead/OnWrite interleaving for later replay
+	// (v0.5.0). Nil when DetectorOptions.RecordSchedule is nil.
+	recorder *ScheduleRecorder
+
+	// scheduleGate re-drives a previously recorded interleaving (v0.5.0).
+	// Nil when DetectorOptions.ReplaySchedule is empty.
+	scheduleGate *ScheduleGate
+
+	// stress injects randomized scheduling perturbations at OnRead/OnWrite
+	// (v0.5.0) to surface rare interleavings during repeated test runs. Nil
+	// when DetectorOptions.StressEnabled is false.
+	stress *StressScheduler
+
+	// hbGraph records the full happens-before event graph for post-mortem
+	// export (v0.5.0). Nil when DetectorOptions.HappensBeforeGraphEnabled
+	// is false.
+	hbGraph *HBGraphRecorder
+
+	// tracer emits runtime/trace user regions/tasks for Acquire/Release
+	// critical sections and detected races (v0.5.0). Unlike the other
+	// opt-in recorders above, this is always non-nil - each method is a
+	// no-op unless the caller has separately started a runtime/trace
+	// capture (trace.IsEnabled()), so there's no DetectorOptions field to
+	// gate it. See traceTracker.
+	tracer *traceTracker
+
+	// exporter POSTs each newly reported race to a remote collector
+	// (synth-3602). Nil when DetectorOptions.Export.URL is empty.
+	exporter *reportExporter
+
+	// onReport is an embedder's callback for each newly reported race
+	// (synth-3608, DetectorOptions.OnReport). Nil when not configured.
+	onReport func(report *RaceReport)
+
+	// racesDetected counts the total number of unique races found
+	// (synth-3634: atomic.Int64, not an int guarded by mu). Every race
+	// funnels through the single reporter goroutine that drains raceQueue,
+	// so this was never actually contended the way OnWrite/OnRead's
+	// nosplit hot path is - but RacesDetected() is a cheap, frequently
+	// polled getter (dashboards, tests), and there is no reason to make it
+	// wait behind d.mu while a report burst is busy printing and exporting.
+	racesDetected atomic.Int64
 
 	// reportedRaces tracks which races have already been reported.
-	// Key format: "{type}:{addr}:{gid1}:{gid2}" (sorted goroutine IDs).
+	// Key format: "{type}:{hashA}:{hashB}" (sorted stack hashes, v0.5.0 -
+	// see generateDeduplicationKey).
 	// This prevents duplicate reports for the same race location.
-	// Added in Phase 5 Task 5.3.
-	reportedRaces sync.Map
-
-	// stats tracks adaptive representation statistics (Phase 3).
-	stats PromotionStats
+	//
+	// A sharded set (synth-3634) rather than sync.Map: sync.Map is tuned
+	// for a read-mostly, stable key set, but dedup is the opposite -
+	// every first occurrence of a race is a write, and a buggy program's
+	// distinct races tend to cluster in time, all landing on sync.Map's
+	// single mutex-protected dirty map at once. Splitting the key space
+	// across independent shards lets that burst proceed in parallel. See
+	// dedup.go.
+	reportedRaces *dedupSet
+
+	// symbolAddrs maps an address most recently written through
+	// OnWriteSym to the symbol id passed alongside it (synth-3630, see
+	// symbols.go). A struct field's address is stable across every write
+	// to that same field, so "whichever id was written here most
+	// recently" is already the right lookup for both sides of a race in
+	// the overwhelming majority of cases - the same field, raced on by
+	// two goroutines, writes the same address with the same id both
+	// times. Cleared on Reset() since, unlike the global id->name table
+	// registerSymbolNames holds, this is per-run state tied to a
+	// particular address's current occupant.
+	symbolAddrs sync.Map
+
+	// allocSites indexes live heap allocations by address range, recorded
+	// by OnMalloc, so a race report on an address inside one can print
+	// where that object was allocated (synth-3632, see allocsite.go).
+	// Cleared on Reset() for the same reason symbolAddrs is: an address
+	// reused after a reset belongs to whatever allocated there afterwards,
+	// not to the allocation this index remembers from before it.
+	allocSites allocIndex
+
+	// recentReports is a bounded ring buffer of the most recently reported
+	// races (v0.5.0), retained so a debug endpoint can show recent activity
+	// without re-parsing stderr. Protected by recentReportsMu rather than mu
+	// since it's read far more often (every debug-endpoint scrape) than
+	// racesDetected, and the two don't need to be consistent with each other.
+	recentReportsMu sync.Mutex
+	recentReports   []*RaceReport
+
+	// siteCounts tallies how many unique races (already deduplicated by
+	// call-stack fingerprint - see reportedRaces) have been attributed to
+	// each racing site pair (synth-3607), keyed by raceSiteKey and
+	// reported at Fini via TopSites. Protected by mu, alongside the other
+	// per-race bookkeeping in reportRaceV2WithStack.
+	siteCounts map[string]*siteCount
+
+	// printedForSite counts how many full reports have actually been
+	// printed to stderr for each site pair (synth-3607), separately from
+	// siteCounts above: with DetectorOptions.Report.MaxReportsPerSite set,
+	// a site keeps accumulating in siteCounts after its cap is reached,
+	// but reportRaceV2WithStack stops printing its stack traces. Protected
+	// by mu.
+	printedForSite map[string]int
+
+	// totalPrinted counts how many full reports have actually been printed
+	// to stderr in total, across every site pair combined (synth-3635) -
+	// what DetectorOptions.Report.MaxReports is checked against. Protected
+	// by mu, alongside printedForSite.
+	totalPrinted int
+
+	// suppressedReports counts how many reports MaxReports has held back
+	// from stderr (synth-3635), for Detector.SuppressedReports and the
+	// Fini summary's "suppressed N further races" line. Atomic rather than
+	// mu-guarded, matching racesDetected: a caller polling
+	// SuppressedReports shouldn't wait behind d.mu while a report burst is
+	// busy printing.
+	suppressedReports atomic.Int64
+
+	// testNames maps a goroutine's TID to the name of the test currently
+	// running on it (synth-3600), e.g. "TestFoo/subtest". Populated by
+	// SetGoroutineTestName (called from api.TestCleanup) and cleared by
+	// ClearGoroutineTestName once that test's t.Cleanup runs. A TID absent
+	// from this map means "not currently running inside a known test" -
+	// most goroutines in a non-test program, and reportRaceV2WithStack
+	// treats a miss as "omit the test name" rather than an error.
+	//
+	// Deliberately not cleared by Reset(): a race found by
+	// `-reset-between-tests` right as one test ends and the next begins
+	// must still resolve to whichever test actually owns the racing
+	// goroutine at report time, and Reset() runs on the test binary's main
+	// goroutine while other tests' goroutines - including t.Parallel ones -
+	// may still be registered here.
+	testNamesMu sync.Mutex
+	testNames   map[uint16]string
+
+	// stats tracks adaptive representation statistics (Phase 3) as per-field
+	// atomic counters (v0.4.0), so OnRead/OnWrite never take d.mu to update
+	// them. See statsCounters for rationale.
+	stats statsCounters
 
 	// operationCount tracks total operations for periodic overflow checks (v0.2.0 Task 5).
 	// Incremented on every OnWrite/OnRead call. When it reaches overflowCheckInterval,
 	// we check for TID/clock overflows and report warnings if needed.
 	operationCount uint64
 
-	// mu protects racesDetected counter and stats updates.
+	// historySize is the configured access-history ring buffer length
+	// (v0.4.0, DetectorOptions.HistorySize). 0 means history tracking is
+	// disabled, which is the default and costs nothing on the hot path.
+	historySize int
+
+	// singleThreaded records DetectorOptions.SingleThreaded (synth-3615)
+	// for SingleThreaded's getter. The only actual behavioral effect is
+	// already baked into raceQueue.blockOnFull at construction time; this
+	// field exists so callers (and tests) can observe what was configured
+	// without reaching into raceQueue.
+	singleThreaded bool
+
+	// muteAfterReport records DetectorOptions.MuteAfterReport (synth-3636).
+	// When true, OnWrite/OnRead poison a shadow cell's VarState the first
+	// time a race is reported on its address, and check IsPoisoned before
+	// doing any FastTrack work on every later access to that address. See
+	// shadowmem.VarState.Poison.
+	muteAfterReport bool
+
+	// mu protects the per-race bookkeeping reportRaceV2WithStack does after
+	// deduplication - site counting, printedForSite, and serializing
+	// stderr output - now that racesDetected is its own atomic counter and
+	// no longer needs it (synth-3634). Stats updates use statsCounters'
+	// own atomics instead (v0.4.0) and never needed this lock either.
 	mu sync.Mutex
+
+	// generation counts how many times Reset has run (synth-3577). Bumped
+	// as the very first step of Reset, before anything is cleared, so any
+	// goroutine's per-context shadow cell cache (see
+	// goroutine.RaceContext.CachedShadowCell) filled under an older
+	// generation misses on its next access instead of continuing to read
+	// and write a VarState that Reset is about to (or has already) evicted
+	// from shadowMemory. This is what lets `racedetector test` call Reset
+	// between packages while background goroutines from a leaked test
+	// might still be running: those goroutines' in-flight operations are
+	// not interrupted, but every operation that starts after Reset begins
+	// is guaranteed to look the address up fresh rather than silently
+	// operating on an orphaned cell forever.
+	generation atomic.Uint64
+
+	// mmapShadow is the opt-in direct-mapped epoch fast path (synth-3579).
+	// Nil when DetectorOptions.MMapShadowEnabled is false, the platform
+	// doesn't support it, or the underlying mmap(2) call failed - in every
+	// case OnWrite/OnRead's mmap fast-path checks are simply skipped and
+	// the detector behaves exactly as it did before this field existed.
+	mmapShadow *shadowmem.MMapEpochShadow
+
+	// raceQueue is the lock-free ring buffer OnWrite/OnRead's nosplit hot
+	// path enqueues a detected race into instead of building and printing
+	// a RaceReport inline (synth-3587). Always non-nil - unlike the opt-in
+	// fields above, this is unconditional infrastructure, not a
+	// DetectorOptions toggle. See racequeue.go.
+	raceQueue *raceQueue
+
+	// reporterOnce starts the reporter goroutine that drains raceQueue on
+	// the first race a detector actually sees (synth-3587), so a program
+	// that never races never pays for an idle goroutine. See
+	// startReporter/runReporter.
+	reporterOnce sync.Once
+
+	// reporterDone and reporterStopped coordinate reporter goroutine
+	// shutdown from Close() (synth-3587): closing reporterDone tells
+	// runReporter to drain whatever's left and exit; it then closes
+	// reporterStopped so Close() knows it's safe to return. Both are nil
+	// until startReporter's first call allocates them.
+	reporterDone    chan struct{}
+	reporterStopped chan struct{}
+
+	// reportedCount tracks how many raceRecords the reporter goroutine has
+	// finished turning into a RaceReport (synth-3587). Compared against
+	// raceQueue.enqueued by WaitForPendingReports to give callers - tests,
+	// mainly - a synchronization point for state that reporting now updates
+	// asynchronously instead of inline in OnWrite/OnRead.
+	reportedCount atomic.Uint64
 }
 
 const (
 	// overflowCheckInterval defines how often to check for TID/clock overflows.
 	// Checking every 10,000 operations provides early warning with minimal overhead (<0.1%).
 	overflowCheckInterval = 10000
+
+	// maxRecentReports bounds the recentReports ring buffer (v0.5.0). A
+	// long-running service that races often shouldn't have its debug
+	// endpoint grow unbounded memory - the most recent handful is what
+	// matters for spotting active problems.
+	maxRecentReports = 20
 )
 
 // NewDetector creates and initializes a new race detector instance.
@@ -172,22 +669,132 @@ func NewDetector() *Detector {
 //	})
 func NewDetectorWithOptions(opts DetectorOptions) *Detector {
 	d := &Detector{
-		shadowMemory: shadowmem.NewShadowMemory(),
-		syncShadow:   syncshadow.NewSyncShadow(),
+		shadowMemory:    shadowmem.NewShadowMemoryWithCap(opts.MaxShadowBytes),
+		syncShadow:      syncshadow.NewSyncShadowWithCap(opts.MaxSyncShadowBytes),
+		historySize:     opts.HistorySize,
+		tracer:          newTraceTracker(),
+		raceQueue:       newRaceQueue(opts.RaceQueueBlockOnFull && !opts.SingleThreaded),
+		singleThreaded:  opts.SingleThreaded,
+		muteAfterReport: opts.MuteAfterReport,
+		reportedRaces:   newDedupSet(),
 	}
 
 	// Initialize sampler only if sampling is enabled (v0.3.0 P0).
 	// When nil, ShouldSample check is skipped entirely (zero overhead).
-	if opts.SamplingEnabled {
+	if opts.SamplingEnabled || opts.OverheadTargetPercent > 0 {
 		d.sampler = NewSampler(SamplerConfig{
-			Enabled: true,
-			Rate:    opts.SampleRate,
+			Enabled:               true,
+			Rate:                  opts.SampleRate,
+			WarmupDuration:        opts.WarmupDuration,
+			OverheadTargetPercent: opts.OverheadTargetPercent,
 		})
 	}
 
+	// Initialize profiler only if profiling is enabled, mirroring the
+	// sampler's opt-in pattern above.
+	if opts.ProfilingEnabled {
+		d.profiler = NewProfiler()
+	}
+
+	// Initialize the symbolizer only if path rewriting was configured,
+	// mirroring the sampler/profiler opt-in pattern above.
+	if opts.Symbolize != (SymbolizeOptions{}) {
+		d.symbolizer = NewSymbolizer(opts.Symbolize)
+	}
+
+	// reportOpts has no "disabled" state to opt into - its zero value is
+	// already the correct default (see ReportOptions), so it's just copied
+	// unconditionally rather than following the pointer opt-in pattern
+	// above.
+	d.reportOpts = opts.Report
+
+	// Initialize the schedule recorder/gate only if record-and-replay was
+	// requested, mirroring the sampler/profiler/symbolizer opt-in pattern
+	// above. A detector is never both recording and replaying at once in
+	// practice, but nothing stops it - recording would simply capture the
+	// replayed interleaving verbatim.
+	if opts.RecordSchedule != nil {
+		d.recorder = NewScheduleRecorder(opts.RecordSchedule)
+	}
+	if len(opts.ReplaySchedule) > 0 {
+		d.scheduleGate = NewScheduleGate(opts.ReplaySchedule)
+	}
+
+	// Initialize the stress scheduler only if perturbation was requested,
+	// mirroring the sampler/profiler/symbolizer/recorder opt-in pattern
+	// above.
+	if opts.StressEnabled {
+		d.stress = NewStressScheduler(opts.StressSeed)
+	}
+
+	// Initialize the happens-before graph recorder only if requested,
+	// mirroring the sampler/profiler/symbolizer/recorder/stress opt-in
+	// pattern above.
+	if opts.HappensBeforeGraphEnabled {
+		d.hbGraph = NewHBGraphRecorder()
+	}
+
+	// Initialize the mmap-backed epoch shadow only if requested (synth-3579),
+	// mirroring the sampler/profiler/symbolizer/recorder/stress/hbGraph
+	// opt-in pattern above. Unlike those, this can fail (unsupported
+	// platform or mmap(2) exhaustion) - on failure d.mmapShadow is simply
+	// left nil, matching MMapShadowEnabled's documented "silently ignored"
+	// contract rather than surfacing an error NewDetectorWithOptions has
+	// no way to return.
+	if opts.MMapShadowEnabled {
+		if mmapShadow, err := shadowmem.NewMMapEpochShadow(opts.MMapShadowBase, opts.MMapShadowSpan); err == nil {
+			d.mmapShadow = mmapShadow
+		}
+	}
+
+	// Initialize the remote report exporter only if a URL was configured
+	// (synth-3602), mirroring the sampler/profiler/symbolizer/recorder/
+	// stress/hbGraph opt-in pattern above.
+	if opts.Export.URL != "" {
+		d.exporter = newReportExporter(opts.Export)
+	}
+
+	// onReport has no initialization to do beyond the copy itself - like
+	// reportOpts above, a nil func is already the correct "disabled" state,
+	// so there's nothing to opt into.
+	d.onReport = opts.OnReport
+
+	log.Info("detector created: sampling=%v profiling=%v singleThreaded=%v maxShadowBytes=%d maxSyncShadowBytes=%d",
+		opts.SamplingEnabled, opts.ProfilingEnabled, opts.SingleThreaded, opts.MaxShadowBytes, opts.MaxSyncShadowBytes)
+
 	return d
 }
 
+// StressSeed returns the seed the detector's StressScheduler was created
+// with, or 0 if DetectorOptions.StressEnabled was false. Intended for a
+// caller (see `racedetector test -stress`) that needs to report the seed of
+// a run in which a race was found, so it can be reproduced.
+func (d *Detector) StressSeed() int64 {
+	return d.stress.Seed()
+}
+
+// WriteHappensBeforeGraph dumps the recorded happens-before event graph in
+// Graphviz DOT format (v0.5.0), so it can be rendered with `dot` or
+// inspected by hand. Returns an error if DetectorOptions.
+// HappensBeforeGraphEnabled was not set.
+func (d *Detector) WriteHappensBeforeGraph(w io.Writer) error {
+	if d.hbGraph == nil {
+		return errHBGraphDisabled
+	}
+	return d.hbGraph.WriteDOT(w)
+}
+
+// WriteOverheadProfile dumps the accumulated per-call-site overhead profile
+// in the standard pprof protocol buffer format (v0.5.0), so it can be
+// inspected with `go tool pprof`. Returns an error if profiling was not
+// enabled via DetectorOptions.ProfilingEnabled.
+func (d *Detector) WriteOverheadProfile(w io.Writer) error {
+	if d.profiler == nil {
+		return errProfilingDisabled
+	}
+	return d.profiler.Dump(w)
+}
+
 // checkOverflowPeriodically increments the operation counter and periodically
 // checks for TID/clock overflows (v0.2.0 Task 5).
 //
@@ -328,10 +935,65 @@ func captureCallerPC() uintptr {
 //go:nosplit
 //nolint:gocognit,nestif,gocyclo,cyclop // Complex race detection logic requires nested conditionals
 func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
-	// Step 0: Sampling check (v0.3.0 P0).
-	// If sampling is enabled and this access is not sampled, skip detection.
-	// This provides 50-90% overhead reduction with 70-90%+ detection rate.
-	if d.sampler != nil && !d.sampler.ShouldSample() {
+	// Step -3: Self-check invariants (synth-3621), no-op unless built with
+	// -tags racedetector_selfcheck. See selfcheck.go.
+	selfCheckContext(ctx)
+
+	// Step -2: Record-and-replay (v0.5.0), opt-in via
+	// DetectorOptions.RecordSchedule / ReplaySchedule. Runs before
+	// everything else so recording captures the true call order and replay
+	// blocks the write until the recorded trace says it's this goroutine's
+	// turn. See ScheduleRecorder and ScheduleGate.
+	if d.recorder != nil {
+		d.recorder.recordEvent(ScheduleEventWrite, addr, ctx.TID)
+	}
+	if d.scheduleGate != nil {
+		d.scheduleGate.Wait(ctx.TID)
+	}
+
+	// Step -1.5: Stress scheduling (v0.5.0), opt-in via
+	// DetectorOptions.StressEnabled. Runs after record-and-replay so a
+	// replayed run's forced ordering isn't itself perturbed.
+	if d.stress != nil {
+		d.stress.Maybe()
+	}
+
+	// Step -1.25: Happens-before graph recording (v0.5.0), opt-in via
+	// DetectorOptions.HappensBeforeGraphEnabled. See HBGraphRecorder.
+	if d.hbGraph != nil {
+		d.hbGraph.record(HBEventWrite, addr, ctx)
+	}
+
+	// Step -1: Overhead profiling (v0.5.0), opt-in via
+	// DetectorOptions.ProfilingEnabled. Measures the whole call regardless
+	// of which step below returns early, so the profile reflects real
+	// per-call-site cost.
+	if d.profiler != nil {
+		start := time.Now()
+		defer func() { d.profiler.RecordSample("write", time.Since(start), 2) }()
+	}
+
+	// Step -0.5: Overhead-targeted sampling control (synth-3640), opt-in
+	// via DetectorOptions.OverheadTargetPercent. Times the whole call, like
+	// the profiler step above, so RecordHookDuration's overhead estimate
+	// reflects real per-call cost rather than only the "sampled" branch.
+	if d.sampler != nil && d.sampler.controlled() {
+		start := time.Now()
+		defer func() { d.sampler.RecordHookDuration(time.Since(start)) }()
+	}
+
+	// Step 0: Sampling check (v0.3.0 P0), now per-call-site with adaptive
+	// feedback (v0.4.0). If sampling is enabled and this access is not
+	// sampled, skip detection. ShouldSampleAt keys on both the caller's PC
+	// (so rarely executed sites are always checked and only hot loops are
+	// down-sampled, replacing the old global counter) and addr (so sites
+	// recently flagged by a race report - see reportRaceV2 ->
+	// Sampler.MarkHotSite - stay at 100% until the boost window expires).
+	// IsFullDetectionForced (synth-3641) bypasses the sampler entirely for
+	// a goroutine tagged via EnableFullDetectionForGoroutine, so a request
+	// singled out for debugging is never skipped regardless of the
+	// program-wide rate.
+	if d.sampler != nil && !ctx.IsFullDetectionForced() && !d.sampler.ShouldSampleAt(captureCallerPC(), addr) {
 		return
 	}
 
@@ -339,14 +1001,59 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 	// Check every 10K operations for TID/clock overflows.
 	d.checkOverflowPeriodically()
 
+	// Step 0.2: mmap-backed same-epoch fast path (synth-3579), opt-in via
+	// DetectorOptions.MMapShadowEnabled. Mirrors Step 3's vs.GetW().Same
+	// check below, but via a direct array lookup instead of the
+	// per-goroutine cache + ShadowMemory.GetOrCreate below it - safe
+	// because it only ever short-circuits where Step 3 would too (the
+	// mirrored epoch, kept in sync by storeMMapShadow at every vs.SetW
+	// call site, already equals this exact write).
+	if d.mmapShadow != nil && d.mmapShadow.Contains(addr) && d.mmapShadow.LoadEpoch(addr).Same(ctx.GetEpoch()) {
+		return
+	}
+
 	// Step 1: Get or create shadow cell for this address.
-	// GetOrCreate is thread-safe and may allocate on first access.
-	vs := d.shadowMemory.GetOrCreate(addr)
+	// Per-goroutine cache check first (v0.4.0): skips ShadowMemory's sharded
+	// sync.Map entirely on a hit, which is the common case for repeated
+	// accesses to the same hot variable from the same goroutine. Falls back
+	// to GetOrCreate (thread-safe, may allocate on first access) on a miss.
+	// Keyed by the current generation (synth-3577) so a cache entry filled
+	// before a concurrent Reset() can't be reused after it.
+	gen := d.generation.Load()
+	vs := ctx.CachedShadowCell(addr, gen)
+	if vs == nil {
+		vs = d.shadowMemory.GetOrCreate(addr)
+		ctx.CacheShadowCell(addr, vs, gen)
+	}
+
+	// Step 1.05: Access-pattern tracking (synth-3642), for
+	// Detector.TopAddresses. Counted before the poisoned check below so a
+	// muted hot variable - the exact kind of address a user would want to
+	// find and suppress - still shows up as frequently checked.
+	vs.IncrementCheckCount()
+
+	// Step 1.1: Poisoned check (synth-3636), opt-in via
+	// DetectorOptions.MuteAfterReport. A cell stays poisoned once a race
+	// has been reported on its address, so a known racy hot variable that
+	// would otherwise keep hitting the full FastTrack check below on every
+	// access stops paying that cost.
+	if d.muteAfterReport && vs.IsPoisoned() {
+		return
+	}
 
 	// Step 2: Get current epoch (TID, Clock) for this goroutine.
 	currentEpoch := ctx.GetEpoch()
 	currentTID := int64(ctx.TID)
 
+	// Step 2.1: Record access history if enabled (v0.4.0 "history_size").
+	// Recorded unconditionally of which fast path below is taken, so the
+	// ring buffer reflects every write attempt, not just the ones that hit
+	// the full FastTrack race check. No-op (single int comparison) when
+	// history tracking is disabled, which is the default.
+	if d.historySize > 0 {
+		vs.RecordHistory(shadowmem.AccessRecord{Epoch: currentEpoch, PC: captureCallerPC(), IsWrite: true}, d.historySize)
+	}
+
 	// Step 3: [FT WRITE SAME EPOCH] Fast path optimization.
 	// If we're writing to the same location in the same epoch, no race possible.
 	// This handles 71% of writes according to FastTrack paper.
@@ -361,7 +1068,7 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 	}
 
 	// Step 4: [SMARTTRACK OWNERSHIP] Check ownership state.
-	exclusiveWriter := vs.GetExclusiveWriter()
+	exclusiveWriter, ownerToken := vs.SnapshotOwner()
 
 	// SmartTrack fast paths:
 	if exclusiveWriter == currentTID && exclusiveWriter != 0 {
@@ -376,23 +1083,34 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 			_, currentClock := currentEpoch.Decode()
 			if int64(prevTID) == currentTID && prevClock <= currentClock {
 				// Normal case: same owner, monotonic clock.
-				// FAST PATH (skip ALL HB checks!)
-				// Now using lock-free atomic store for W field.
-				vs.SetW(currentEpoch)
-				vs.IncrementWriteCount()
-				// Lazy stack capture (v0.3.0 Performance).
-				pc := captureCallerPC()
-				vs.SetWritePC(pc)
-				ctx.IncrementClock()
-				return
+				// FAST PATH (skip ALL HB checks!) - but only if ownership
+				// hasn't moved since the snapshot above. A concurrent second
+				// writer could have promoted this cell to shared between
+				// SnapshotOwner and here; committing W without re-validating
+				// would then skip the happens-before check that promotion was
+				// meant to force (synth-3633). ValidateOwner fails cheaply
+				// (a single CAS) and sends us to the full FastTrack path
+				// below, which re-reads W and exclusiveWriter fresh.
+				if vs.ValidateOwner(ownerToken) {
+					// Now using lock-free atomic store for W field.
+					vs.SetW(currentEpoch)
+					d.storeMMapShadow(addr, currentEpoch)
+					vs.IncrementWriteCount()
+					// Lazy stack capture (v0.3.0 Performance).
+					pc := captureCallerPC()
+					vs.SetWritePC(pc)
+					ctx.IncrementClock()
+					return
+				}
 			}
 			// Time-travel detected: prev write at later clock than current write.
 			// This indicates either clock rollback (bug) or actual race.
 			// Fall through to full FastTrack check.
-		} else {
-			// No previous write - FAST PATH.
+		} else if vs.ValidateOwner(ownerToken) {
+			// No previous write - FAST PATH (ownership re-validated, see above).
 			// Now using lock-free atomic store for W field.
 			vs.SetW(currentEpoch)
+			d.storeMMapShadow(addr, currentEpoch)
 			vs.IncrementWriteCount()
 			// Lazy stack capture (v0.3.0 Performance).
 			pc := captureCallerPC()
@@ -414,6 +1132,7 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 				// No previous read - safe to return early.
 				// Now using lock-free atomic store for W field.
 				vs.SetW(currentEpoch)
+				d.storeMMapShadow(addr, currentEpoch)
 				vs.IncrementWriteCount()
 				// Lazy stack capture (v0.3.0 Performance).
 				pc := captureCallerPC()
@@ -446,7 +1165,7 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 	// Now using lock-free atomic load for W field.
 	prevW := vs.GetW()
 	if !d.happensBeforeWrite(prevW, ctx) {
-		d.reportRaceV2("write-write", addr, vs, prevW, currentEpoch)
+		d.enqueueRace("write-write", addr, vs, prevW, currentEpoch)
 		return // Stop on first race to avoid cascade of reports
 	}
 
@@ -455,7 +1174,7 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 		// FAST PATH: Check single reader epoch.
 		readEpoch := vs.GetReadEpoch()
 		if readEpoch != 0 && !d.happensBeforeRead(readEpoch, ctx) {
-			d.reportRaceV2("read-write", addr, vs, readEpoch, currentEpoch)
+			d.enqueueRace("read-write", addr, vs, readEpoch, currentEpoch)
 			return // Stop on first race
 		}
 	} else {
@@ -465,7 +1184,7 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 			// Report race with first conflicting read (use epoch representation for reporting).
 			// For simplicity, we report a synthetic epoch from the VectorClock.
 			// TODO: Improve race reporting to show all conflicting reads in future version.
-			d.reportRaceV2("read-write", addr, vs, epoch.Epoch(0), currentEpoch)
+			d.enqueueRace("read-write", addr, vs, epoch.Epoch(0), currentEpoch)
 			return // Stop on first race
 		}
 	}
@@ -474,6 +1193,7 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 	// Record that this write occurred at currentEpoch.
 	// Now using lock-free atomic store for W field.
 	vs.SetW(currentEpoch)
+	d.storeMMapShadow(addr, currentEpoch)
 	vs.IncrementWriteCount()
 
 	// Step 7.1: Lazy stack capture (v0.3.0 Performance).
@@ -489,17 +1209,14 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 	wasPromoted := vs.IsPromoted()
 	vs.Demote()
 	if wasPromoted {
-		// Track demotion statistics.
-		d.mu.Lock()
-		d.stats.Demotions++
-		d.stats.PromotedVars--
-		d.mu.Unlock()
+		// Track demotion statistics (lock-free, v0.4.0).
+		d.stats.demotions.Add(1)
+		d.stats.promotedVars.Add(-1)
+		vs.IncrementDemotionCount() // Per-address (synth-3642).
 	}
 
-	// Track write statistics.
-	d.mu.Lock()
-	d.stats.TotalWrites++
-	d.mu.Unlock()
+	// Track write statistics (lock-free, v0.4.0).
+	d.stats.totalWrites.Add(1)
 
 	// Step 9: Increment logical clock to advance time.
 	// This must be done AFTER updating shadow memory to maintain
@@ -507,6 +1224,61 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 	ctx.IncrementClock()
 }
 
+// OnWriteSym is OnWrite with a symbol id attached, so a race reported
+// against addr can be printed with a human-readable name - e.g. "field
+// Config.Timeout" - instead of a bare hex address (synth-3630). It's
+// emitted by compiler instrumentation in place of OnWrite for a write
+// whose left-hand side is a struct field the instrumenter could resolve a
+// "Type.Field" name for via go/types - see
+// cmd/racedetector/instrument/symbols.go and RegisterSymbol below.
+//
+// symID is recorded against addr before delegating to OnWrite, so it's
+// already in place by the time OnWrite's own race check (which runs
+// against this exact call, not a later one) might report a race. See
+// symbols.go for the id->name table this feeds and its caveats.
+//
+//go:nosplit
+func (d *Detector) OnWriteSym(addr uintptr, symID uint64, ctx *goroutine.RaceContext) {
+	d.symbolAddrs.Store(addr, symID)
+	d.OnWrite(addr, ctx)
+}
+
+// OnWriteBatch checks a batch of write accesses collected from a single
+// instrumented statement (or a caller that has otherwise already grouped
+// them) in one call (synth-3598).
+//
+// The sampling gate - the one per-call decision OnWrite makes before doing
+// any real work - is evaluated ONCE for the whole batch, keyed on the call
+// site's PC and the batch's first address, instead of once per address:
+// down-sampled call sites now skip every address in the batch with a
+// single ShouldSampleAt check instead of len(addrs) of them. Each address
+// that survives the gate still goes through the full FastTrack check via
+// OnWrite, so this doesn't change detection accuracy, only how many times
+// the gate itself runs.
+//
+// Parameters:
+//   - addrs: Memory addresses written in this batch, in program order
+//   - ctx: RaceContext for the calling goroutine
+//
+// Thread Safety: Safe for concurrent calls from multiple goroutines.
+func (d *Detector) OnWriteBatch(addrs []uintptr, ctx *goroutine.RaceContext) {
+	if len(addrs) == 0 {
+		return
+	}
+	if len(addrs) == 1 {
+		d.OnWrite(addrs[0], ctx)
+		return
+	}
+
+	if d.sampler != nil && !ctx.IsFullDetectionForced() && !d.sampler.ShouldSampleAt(captureCallerPC(), addrs[0]) {
+		return
+	}
+
+	for _, addr := range addrs {
+		d.OnWrite(addr, ctx)
+	}
+}
+
 // OnRead handles read access to memory at the given address.
 //
 // This is the CRITICAL HOT PATH function - it is called on EVERY read access
@@ -551,10 +1323,58 @@ func (d *Detector) OnWrite(addr uintptr, ctx *goroutine.RaceContext) {
 //
 //go:nosplit
 func (d *Detector) OnRead(addr uintptr, ctx *goroutine.RaceContext) {
-	// Step 0: Sampling check (v0.3.0 P0).
-	// If sampling is enabled and this access is not sampled, skip detection.
-	// This provides 50-90% overhead reduction with 70-90%+ detection rate.
-	if d.sampler != nil && !d.sampler.ShouldSample() {
+	// Step -3: Self-check invariants (synth-3621). See OnWrite's identical
+	// step for rationale.
+	selfCheckContext(ctx)
+
+	// Step -2: Record-and-replay (v0.5.0). See OnWrite's identical step for
+	// rationale.
+	if d.recorder != nil {
+		d.recorder.recordEvent(ScheduleEventRead, addr, ctx.TID)
+	}
+	if d.scheduleGate != nil {
+		d.scheduleGate.Wait(ctx.TID)
+	}
+
+	// Step -1.5: Stress scheduling (v0.5.0). See OnWrite's identical step
+	// for rationale.
+	if d.stress != nil {
+		d.stress.Maybe()
+	}
+
+	// Step -1.25: Happens-before graph recording (v0.5.0). See OnWrite's
+	// identical step for rationale.
+	if d.hbGraph != nil {
+		d.hbGraph.record(HBEventRead, addr, ctx)
+	}
+
+	// Step -1: Overhead profiling (v0.5.0), opt-in via
+	// DetectorOptions.ProfilingEnabled. See OnWrite's identical step for
+	// rationale.
+	if d.profiler != nil {
+		start := time.Now()
+		defer func() { d.profiler.RecordSample("read", time.Since(start), 2) }()
+	}
+
+	// Step -0.5: Overhead-targeted sampling control (synth-3640). See
+	// OnWrite's identical step for rationale.
+	if d.sampler != nil && d.sampler.controlled() {
+		start := time.Now()
+		defer func() { d.sampler.RecordHookDuration(time.Since(start)) }()
+	}
+
+	// Step 0: Sampling check (v0.3.0 P0), now per-call-site with adaptive
+	// feedback (v0.4.0). If sampling is enabled and this access is not
+	// sampled, skip detection. ShouldSampleAt keys on both the caller's PC
+	// (so rarely executed sites are always checked and only hot loops are
+	// down-sampled, replacing the old global counter) and addr (so sites
+	// recently flagged by a race report - see reportRaceV2 ->
+	// Sampler.MarkHotSite - stay at 100% until the boost window expires).
+	// IsFullDetectionForced (synth-3641) bypasses the sampler entirely for
+	// a goroutine tagged via EnableFullDetectionForGoroutine, so a request
+	// singled out for debugging is never skipped regardless of the
+	// program-wide rate.
+	if d.sampler != nil && !ctx.IsFullDetectionForced() && !d.sampler.ShouldSampleAt(captureCallerPC(), addr) {
 		return
 	}
 
@@ -563,23 +1383,58 @@ func (d *Detector) OnRead(addr uintptr, ctx *goroutine.RaceContext) {
 	d.checkOverflowPeriodically()
 
 	// Step 1: Get or create shadow cell for this address.
-	// GetOrCreate is thread-safe and may allocate on first access.
-	vs := d.shadowMemory.GetOrCreate(addr)
+	// Per-goroutine cache check first (v0.4.0): skips ShadowMemory's sharded
+	// sync.Map entirely on a hit, which is the common case for repeated
+	// accesses to the same hot variable from the same goroutine. Falls back
+	// to GetOrCreate (thread-safe, may allocate on first access) on a miss.
+	// Keyed by the current generation (synth-3577) so a cache entry filled
+	// before a concurrent Reset() can't be reused after it.
+	gen := d.generation.Load()
+	vs := ctx.CachedShadowCell(addr, gen)
+	if vs == nil {
+		vs = d.shadowMemory.GetOrCreate(addr)
+		ctx.CacheShadowCell(addr, vs, gen)
+	}
+
+	// Step 1.05: Access-pattern tracking (synth-3642). See the equivalent
+	// step in OnWrite for rationale.
+	vs.IncrementCheckCount()
+
+	// Step 1.1: Poisoned check (synth-3636). See the equivalent step in
+	// OnWrite for rationale.
+	if d.muteAfterReport && vs.IsPoisoned() {
+		return
+	}
 
 	// Step 2: Get current epoch (TID, Clock) for this goroutine.
 	currentEpoch := ctx.GetEpoch()
 	currentTID := int64(ctx.TID)
 
+	// Step 2.1: Record access history if enabled (v0.4.0 "history_size").
+	// See the equivalent step in OnWrite for rationale.
+	if d.historySize > 0 {
+		vs.RecordHistory(shadowmem.AccessRecord{Epoch: currentEpoch, PC: captureCallerPC(), IsWrite: false}, d.historySize)
+	}
+
 	// Step 3: [SMARTTRACK OWNERSHIP] Fast path for owned variables.
 	// If the reader is the exclusive writer, skip expensive HB check.
 	// This is the common case for thread-local or single-writer variables.
-	exclusiveWriter := vs.GetExclusiveWriter()
+	exclusiveWriter, ownerToken := vs.SnapshotOwner()
 	if exclusiveWriter == currentTID && exclusiveWriter > 0 {
 		// Reading own writes - FAST PATH (skip HB check!)
-		// This is safe because a thread's writes always happen-before its own reads.
-		vs.SetReadEpoch(currentEpoch)
-		ctx.IncrementClock()
-		return
+		// This is safe because a thread's writes always happen-before its own
+		// reads - but only if ownership hasn't moved since the snapshot
+		// above. A concurrent second writer could have promoted this cell to
+		// shared between SnapshotOwner and here, in which case the write it
+		// is about to make (or just made) never happened-before this read
+		// and skipping the check would hide the race (synth-3633).
+		// ValidateOwner sends us to the full happens-before check below on
+		// any concurrent transition.
+		if vs.ValidateOwner(ownerToken) {
+			vs.SetReadEpoch(currentEpoch)
+			ctx.IncrementClock()
+			return
+		}
 	}
 
 	// Step 4: Check read-write race.
@@ -588,7 +1443,7 @@ func (d *Detector) OnRead(addr uintptr, ctx *goroutine.RaceContext) {
 	// Now using lock-free atomic load for W field.
 	prevW := vs.GetW()
 	if prevW != 0 && !d.happensBeforeWrite(prevW, ctx) {
-		d.reportRaceV2("write-read", addr, vs, prevW, currentEpoch)
+		d.enqueueRace("write-read", addr, vs, prevW, currentEpoch)
 		return // Stop on first race to avoid cascade of reports
 	}
 
@@ -596,10 +1451,10 @@ func (d *Detector) OnRead(addr uintptr, ctx *goroutine.RaceContext) {
 	//nolint:nestif // FastTrack adaptive algorithm requires nested conditions for performance
 	if !vs.IsPromoted() {
 		// FAST PATH: Single reader (common case, 90%+ of reads).
-		d.mu.Lock()
-		d.stats.TotalReads++
-		d.stats.FastPathReads++
-		d.mu.Unlock()
+		// Lock-free stats update (v0.4.0): no d.mu, so concurrent readers
+		// never serialize on this bookkeeping.
+		d.stats.totalReads.Add(1)
+		d.stats.fastPathReads.Add(1)
 
 		// [FT READ SAME EPOCH] Fast path optimization.
 		// If we're reading from the same location in the same epoch, no race possible.
@@ -631,10 +1486,9 @@ func (d *Detector) OnRead(addr uintptr, ctx *goroutine.RaceContext) {
 
 			// CONCURRENT READS DETECTED - PROMOTE!
 			vs.PromoteToReadClock(ctx.C)
-			d.mu.Lock()
-			d.stats.Promotions++
-			d.stats.PromotedVars++
-			d.mu.Unlock()
+			d.stats.promotions.Add(1)
+			d.stats.promotedVars.Add(1)
+			vs.IncrementPromotionCount() // Per-address (synth-3642).
 			ctx.IncrementClock()
 			return
 		}
@@ -646,10 +1500,9 @@ func (d *Detector) OnRead(addr uintptr, ctx *goroutine.RaceContext) {
 	}
 
 	// SLOW PATH: Multiple readers (already promoted, 0.1% of reads).
-	d.mu.Lock()
-	d.stats.TotalReads++
-	d.stats.SlowPathReads++
-	d.mu.Unlock()
+	// Lock-free stats update (v0.4.0), same as the fast path above.
+	d.stats.totalReads.Add(1)
+	d.stats.slowPathReads.Add(1)
 
 	vs.GetReadClock().Join(ctx.C)
 
@@ -749,7 +1602,7 @@ func (d *Detector) reportRace(raceType string, addr uintptr, prevEpoch, currEpoc
 	defer d.mu.Unlock()
 
 	// Increment race counter for statistics.
-	d.racesDetected++
+	d.racesDetected.Add(1)
 
 	// Print race report to stderr.
 	// Using fmt.Fprintf for formatted output (not on hot path).
@@ -767,14 +1620,157 @@ func (d *Detector) reportRace(raceType string, addr uintptr, prevEpoch, currEpoc
 // This is used for testing and reporting purposes. It provides a simple
 // count of how many races were found during execution.
 //
-// Thread Safety: Safe for concurrent calls (protected by mutex).
+// Thread Safety: Safe for concurrent calls (atomic load, synth-3634 - no
+// longer waits behind mu for a report burst to finish printing/exporting).
 //
 // Returns:
 //   - int: Total number of races detected
 func (d *Detector) RacesDetected() int {
+	return int(d.racesDetected.Load())
+}
+
+// SuppressedReports returns how many full reports DetectorOptions.
+// Report.MaxReports has held back from stderr so far (synth-3635) - races
+// that were still detected and counted by RacesDetected/TopSites, but
+// whose stack trace was skipped because the global report cap was already
+// reached. Always 0 when MaxReports is unset (the default).
+//
+// Thread Safety: Safe for concurrent calls (atomic load).
+func (d *Detector) SuppressedReports() int {
+	return int(d.suppressedReports.Load())
+}
+
+// SetOnReport replaces d's report callback (synth-3609), so a caller can
+// register or change DetectorOptions.OnReport on an already-running
+// Detector - e.g. the process-wide singleton api.OnReport wraps, which
+// exists before a caller's own init() functions get a chance to run.
+// Pass nil to disable the callback.
+//
+// Thread Safety: Safe for concurrent calls, and safe to call while races
+// are being reported - guarded by the same lock reportRaceV2WithStack
+// holds while invoking the callback.
+func (d *Detector) SetOnReport(fn func(report *RaceReport)) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	return d.racesDetected
+	d.onReport = fn
+}
+
+// SetGoroutineTestName records that tid is currently running the test
+// named name (synth-3600), so a race report naming that goroutine can
+// include which test found it.
+//
+// Called by api.TestCleanup with the calling goroutine's own TID -
+// since Go's testing package always runs each test function (including
+// every t.Parallel subtest) on its own goroutine via `go tRunner(t, fn)`,
+// this gives every test a distinct logical detector scope for free: no
+// new scope data structure is needed, only a name for the scope the
+// goroutine-per-test architecture already provides.
+//
+// Thread Safety: Safe for concurrent calls from different goroutines
+// (e.g. multiple t.Parallel tests registering at once).
+func (d *Detector) SetGoroutineTestName(tid uint16, name string) {
+	d.testNamesMu.Lock()
+	defer d.testNamesMu.Unlock()
+	if d.testNames == nil {
+		d.testNames = make(map[uint16]string)
+	}
+	d.testNames[tid] = name
+}
+
+// ClearGoroutineTestName removes tid's test-name association, once that
+// test has finished (synth-3600). A TID can be reused by a later,
+// unrelated goroutine (see the TID pool), so leaving a stale entry behind
+// would mislabel whatever runs on tid next.
+func (d *Detector) ClearGoroutineTestName(tid uint16) {
+	d.testNamesMu.Lock()
+	defer d.testNamesMu.Unlock()
+	delete(d.testNames, tid)
+}
+
+// TestNameForTID returns the test name registered for tid, or "" if none
+// is currently registered.
+//
+// Exported so api.racegostart can propagate a spawning test's name to the
+// child goroutines it starts (synth-3600), not just the test's own
+// goroutine - see getCurrentContext's GoStart-inheritance path.
+func (d *Detector) TestNameForTID(tid uint16) string {
+	d.testNamesMu.Lock()
+	defer d.testNamesMu.Unlock()
+	return d.testNames[tid]
+}
+
+// Generation returns the number of times Reset has run on this detector
+// (synth-3577).
+//
+// This is mainly useful for tests that want to assert a Reset actually
+// happened (e.g. across a package boundary in `racedetector test`) without
+// depending on shadow memory having been observably cleared, which a
+// concurrently-running background goroutine could otherwise repopulate.
+//
+// Thread Safety: Safe for concurrent calls (atomic load).
+func (d *Detector) Generation() uint64 {
+	return d.generation.Load()
+}
+
+// storeMMapShadow mirrors e into the mmap-backed epoch shadow for addr when
+// DetectorOptions.MMapShadowEnabled covers it (synth-3579), keeping OnWrite's
+// Step 0.2 fast-path check in sync with every vs.SetW(currentEpoch) call
+// site below it. No-op when mmapShadow is nil or addr falls outside its
+// covered range.
+//
+//go:nosplit
+func (d *Detector) storeMMapShadow(addr uintptr, e epoch.Epoch) {
+	if d.mmapShadow != nil && d.mmapShadow.Contains(addr) {
+		d.mmapShadow.StoreEpoch(addr, e)
+	}
+}
+
+// MMapShadowActive reports whether this detector's mmap-backed epoch
+// fast path (synth-3579, DetectorOptions.MMapShadowEnabled) is actually in
+// effect. False whenever MMapShadowEnabled was unset, the platform doesn't
+// support it, or the underlying mmap(2) call failed - useful for tests and
+// monitoring to distinguish "opted out" from "opted in but silently fell
+// back".
+func (d *Detector) MMapShadowActive() bool {
+	return d.mmapShadow != nil
+}
+
+// SingleThreaded reports whether this detector was constructed with
+// DetectorOptions.SingleThreaded set (synth-3615) - directly, via a
+// platform default (GOOS=js/wasip1), or via RACEDETECTOR_SINGLE_THREADED.
+func (d *Detector) SingleThreaded() bool {
+	return d.singleThreaded
+}
+
+// Close releases resources held by the detector that the Go garbage
+// collector cannot reclaim on its own: the mmap-backed epoch shadow
+// (synth-3579), when DetectorOptions.MMapShadowEnabled was set, and the
+// reporter goroutine draining raceQueue (synth-3587), if a race ever
+// started one. Safe to call even if neither was ever active. The detector
+// must not be used for OnWrite/OnRead after Close returns.
+func (d *Detector) Close() error {
+	d.stopReporter()
+	d.FlushExporter()
+
+	if d.mmapShadow == nil {
+		return nil
+	}
+	return d.mmapShadow.Close()
+}
+
+// FlushExporter blocks until every race handed to the remote report
+// exporter (synth-3602, DetectorOptions.Export) has been sent or
+// ExportOptions.Timeout elapses, whichever comes first. No-op if exporting
+// isn't enabled.
+//
+// Called from Close, and separately from api.Fini() - which doesn't
+// otherwise call Close - so a race reported just before process exit still
+// makes it to the collector instead of being silently dropped with the
+// rest of the process.
+func (d *Detector) FlushExporter() {
+	if d.exporter != nil {
+		d.exporter.close()
+	}
 }
 
 // OnAcquire handles mutex lock operations (Phase 4 Task 4.1).
@@ -782,12 +1778,32 @@ func (d *Detector) RacesDetected() int {
 // This establishes a happens-before edge from the previous Unlock to this Lock.
 // The acquiring thread merges the mutex's release clock into its own clock.
 //
-// Algorithm: FastTrack [FT ACQUIRE]
+// For a RWMutex, this also doubles as the write-lock side: a Lock cannot
+// proceed until every outstanding RLock/RUnlock has completed (real RWMutex
+// mutual exclusion), so it additionally joins readReleaseClock, the
+// accumulated clock of every reader since the last writer (synth-3570). See
+// OnRLock/OnRUnlock for the reader side, which - unlike this method -
+// doesn't join readReleaseClock, since concurrent reads never race with
+// each other and don't need to happen-before one another.
+//
+// Algorithm: FastTrack [FT ACQUIRE] + SmartTrack single-owner fast path
+// (synth-3619)
 //  1. Get lock's SyncVar from sync shadow memory
-//  2. If lock has release clock: ctx.C.Join(syncVar.releaseClock)
-//  3. ctx.IncrementClock()
+//  2. If lock has release clock AND this goroutine is not its sole owner:
+//     ctx.JoinClock(syncVar.releaseClock)
+//  3. If lock has a read release clock: ctx.JoinClock(syncVar.readReleaseClock)
+//  4. ctx.IncrementClock()
+//
+// This implements: Ct := Ct ⊔ Lm ⊔ Rm (thread clock joins lock clock and the
+// accumulated reader clock).
 //
-// This implements: Ct := Ct ⊔ Lm (thread clock joins lock clock).
+// SmartTrack Optimization (synth-3619): mirrors
+// shadowmem.VarState.exclusiveWriter. Most mutexes are only ever Locked and
+// Unlocked by one goroutine, so re-Locking one's own last Unlock is a
+// mathematically guaranteed no-op Join - safe to skip outright. The moment a
+// different goroutine's Acquire is observed, SyncVar.IsSoleOwner demotes
+// ownership to shared for good and every future Acquire takes the full Join
+// path again, exactly like exclusiveWriter's own promotion to -1.
 //
 // Parameters:
 //   - addr: Address of the mutex being locked
@@ -805,20 +1821,54 @@ func (d *Detector) RacesDetected() int {
 //
 //go:nosplit
 func (d *Detector) OnAcquire(addr uintptr, ctx *goroutine.RaceContext) {
+	// Step -1: Self-check invariants (synth-3621). See OnWrite's identical
+	// step for rationale.
+	selfCheckContext(ctx)
+
+	// Step 0: Overhead profiling (v0.5.0), opt-in via
+	// DetectorOptions.ProfilingEnabled. See OnWrite's identical step for
+	// rationale.
+	if d.profiler != nil {
+		start := time.Now()
+		defer func() { d.profiler.RecordSample("acquire", time.Since(start), 2) }()
+	}
+
 	// Step 1: Get or create SyncVar for this mutex address.
 	syncVar := d.syncShadow.GetOrCreate(addr)
 
 	// Step 2: If lock has a release clock, join it with current thread's clock.
 	// This establishes happens-before from the previous Unlock.
+	//
+	// SmartTrack ownership fast path (synth-3619): if every Release of this
+	// lock has come from this exact goroutine so far, the Join below is a
+	// guaranteed no-op - skip it. See this method's doc comment.
 	releaseClock := syncVar.GetReleaseClock()
-	if releaseClock != nil {
+	if releaseClock != nil && !syncVar.IsSoleOwner(ctx.TID, ctx.GetEpoch()) {
 		// Ct := Ct ⊔ Lm (thread clock joins lock clock).
-		ctx.C.Join(releaseClock)
+		ctx.JoinClock(releaseClock)
+	}
+
+	// Step 2b: If lock is a RWMutex with readers since the last writer, join
+	// their accumulated clock too - see this method's doc comment.
+	readReleaseClock := syncVar.GetReadReleaseClock()
+	if readReleaseClock != nil {
+		ctx.JoinClock(readReleaseClock)
 	}
 
 	// Step 3: Increment logical clock to advance time.
 	// This must be done AFTER joining to maintain happens-before invariant.
 	ctx.IncrementClock()
+
+	// Step 4: Happens-before graph recording (v0.5.0), opt-in via
+	// DetectorOptions.HappensBeforeGraphEnabled. Recorded last so the
+	// snapshotted clock reflects this Acquire's join. See HBGraphRecorder.
+	if d.hbGraph != nil {
+		d.hbGraph.record(HBEventAcquire, addr, ctx)
+	}
+
+	// Step 5: runtime/trace region start (v0.5.0). See OnRelease's
+	// matching step and traceTracker for rationale.
+	d.tracer.acquire(addr, ctx)
 }
 
 // OnRelease handles mutex unlock operations (Phase 4 Task 4.1).
@@ -828,8 +1878,9 @@ func (d *Detector) OnAcquire(addr uintptr, ctx *goroutine.RaceContext) {
 //
 // Algorithm: FastTrack [FT RELEASE]
 //  1. Get lock's SyncVar
-//  2. Set syncVar.releaseClock = ctx.C (copy current thread's clock)
-//  3. ctx.IncrementClock()
+//  2. ctx.IncrementClock()
+//  3. Set syncVar.releaseClock = ctx.C, copy-on-write (synth-3618)
+//  4. Update single-owner tracking for OnAcquire's fast path (synth-3619)
 //
 // This implements: Lm := Ct (lock clock = thread clock).
 //
@@ -839,7 +1890,9 @@ func (d *Detector) OnAcquire(addr uintptr, ctx *goroutine.RaceContext) {
 //
 // Thread Safety: Safe for concurrent calls from multiple goroutines.
 //
-// Performance Target: <300ns per call (VectorClock copy overhead acceptable).
+// Performance Target: O(1) in steady state (synth-3618) - SetReleaseClock
+// shares ctx.C by reference instead of copying it; the O(MaxThreads) copy
+// only happens later, lazily, if ctx ever mutates its clock again.
 //
 // Example:
 //
@@ -849,17 +1902,46 @@ func (d *Detector) OnAcquire(addr uintptr, ctx *goroutine.RaceContext) {
 //
 //go:nosplit
 func (d *Detector) OnRelease(addr uintptr, ctx *goroutine.RaceContext) {
+	// Step -1: Self-check invariants (synth-3621). See OnWrite's identical
+	// step for rationale.
+	selfCheckContext(ctx)
+
 	// Step 1: Get or create SyncVar for this mutex address.
 	syncVar := d.syncShadow.GetOrCreate(addr)
 
-	// Step 2: Set lock's release clock to current thread's clock.
+	// Step 2: Increment logical clock to advance time for this Release
+	// event BEFORE capturing it below (synth-3618) - the clock
+	// SetReleaseClock shares with the mutex must already include this
+	// tick, since sharing marks it copy-on-write and no longer safe to
+	// mutate in place.
+	ctx.IncrementClock()
+
+	// Step 3: Share the current thread's clock as the lock's release clock.
 	// This captures the happens-before relationship for future Acquires.
 	// Lm := Ct (lock clock = thread clock).
 	syncVar.SetReleaseClock(ctx.C)
 
-	// Step 3: Increment logical clock to advance time.
-	// This must be done AFTER updating release clock to maintain happens-before.
-	ctx.IncrementClock()
+	// Step 3a: Self-check that the release clock just captured never
+	// exceeds this goroutine's own clock (synth-3621) - a violation here
+	// means SetReleaseClock or the clock sharing it relies on is broken.
+	selfCheckRelease(syncVar, ctx)
+
+	// Step 3b: Update single-owner tracking for OnAcquire's SmartTrack fast
+	// path (synth-3619). Independent of SetReleaseClock above - this only
+	// ever affects whether a future OnAcquire can skip its Join, never
+	// whether releaseClock itself stays correct.
+	syncVar.RecordRelease(ctx.TID, ctx.GetEpoch())
+
+	// Step 4: Happens-before graph recording (v0.5.0). See OnAcquire's
+	// identical step for rationale.
+	if d.hbGraph != nil {
+		d.hbGraph.record(HBEventRelease, addr, ctx)
+	}
+
+	// Step 5: runtime/trace region end (v0.5.0). Closes the region
+	// OnAcquire started for this (goroutine, addr) pair, so the critical
+	// section's hold time shows up in `go tool trace`. See traceTracker.
+	d.tracer.release(addr, ctx)
 }
 
 // OnReleaseMerge handles RWMutex write unlock operations (Phase 4 Task 4.1).
@@ -911,10 +1993,174 @@ func (d *Detector) OnReleaseMerge(addr uintptr, ctx *goroutine.RaceContext) {
 
 	// Step 3: Increment logical clock to advance time.
 	ctx.IncrementClock()
+
+	// Step 4: Happens-before graph recording (v0.5.0). See OnAcquire's
+	// identical step for rationale.
+	if d.hbGraph != nil {
+		d.hbGraph.record(HBEventReleaseMerge, addr, ctx)
+	}
+
+	// Step 5: runtime/trace region end (v0.5.0). RUnlock ends the region
+	// its RLock started, same as OnRelease. See traceTracker.
+	d.tracer.release(addr, ctx)
+}
+
+// OnRLock handles RWMutex read-lock operations (synth-3570).
+//
+// Unlike OnAcquire (used for RWMutex.Lock and plain sync.Mutex.Lock), RLock
+// only needs to happen-after the last writer: concurrent readers never need
+// to happen-before each other, since concurrent reads are never themselves a
+// race. Before this method existed, RLock reused OnAcquire directly against
+// the same releaseClock that RUnlock merges into, chaining every reader to
+// every other reader - an unnecessary happens-before edge that, like any
+// extra edge in a vector-clock algorithm, can mask a genuine, unrelated race
+// between those two goroutines.
+//
+// Algorithm: FastTrack RWMutex reader acquire
+//  1. Get lock's SyncVar from sync shadow memory
+//  2. If lock has a (writer) release clock: ctx.JoinClock(syncVar.releaseClock)
+//  3. ctx.IncrementClock()
+//
+// This implements: Ct := Ct ⊔ Lm (thread clock joins the last writer's
+// clock only, not other readers').
+//
+// Parameters:
+//   - addr: Address of the RWMutex being read-locked
+//   - ctx: Current goroutine's RaceContext
+//
+// Thread Safety: Safe for concurrent calls from multiple goroutines.
+//
+// Performance Target: <500ns per call (VectorClock join overhead acceptable).
+//
+// Example:
+//
+//	mu.RLock()  // Compiler inserts: raceracquire (OnRLock)
+//	// OnRLock merges the last writer's clock, not other readers'
+//	y = x       // Read happens-after the last Unlock
+//
+//go:nosplit
+func (d *Detector) OnRLock(addr uintptr, ctx *goroutine.RaceContext) {
+	// Step 0: Overhead profiling (v0.5.0). See OnWrite's identical step for
+	// rationale.
+	if d.profiler != nil {
+		start := time.Now()
+		defer func() { d.profiler.RecordSample("rlock", time.Since(start), 2) }()
+	}
+
+	// Step 1: Get or create SyncVar for this mutex address.
+	syncVar := d.syncShadow.GetOrCreate(addr)
+
+	// Step 2: If lock has a writer release clock, join it with current
+	// thread's clock. This establishes happens-before from the last Unlock.
+	releaseClock := syncVar.GetReleaseClock()
+	if releaseClock != nil {
+		ctx.JoinClock(releaseClock)
+	}
+
+	// Step 3: Increment logical clock to advance time.
+	ctx.IncrementClock()
+
+	// Step 4: Happens-before graph recording (v0.5.0). See OnAcquire's
+	// identical step for rationale.
+	if d.hbGraph != nil {
+		d.hbGraph.record(HBEventRLock, addr, ctx)
+	}
+
+	// Step 5: runtime/trace region start (v0.5.0). See OnRUnlock's matching
+	// step and traceTracker for rationale.
+	d.tracer.acquire(addr, ctx)
+}
+
+// OnRUnlock handles RWMutex read-unlock operations (synth-3570).
+//
+// This merges the reader's clock into readReleaseClock, the accumulator a
+// subsequent Lock (OnAcquire) joins in full - since a write lock cannot
+// proceed until every outstanding reader has released, it must happen-after
+// all of them, not just whichever RUnlock happened to run last.
+//
+// Algorithm: FastTrack RWMutex reader release
+//  1. Get lock's SyncVar
+//  2. syncVar.readReleaseClock = syncVar.readReleaseClock ⊔ ctx.C (merge)
+//  3. ctx.IncrementClock()
+//
+// This implements: Rm := Rm ⊔ Ct (accumulated reader clock merges with this
+// reader's clock).
+//
+// Parameters:
+//   - addr: Address of the RWMutex being read-unlocked
+//   - ctx: Current goroutine's RaceContext
+//
+// Thread Safety: Safe for concurrent calls from multiple goroutines.
+//
+// Performance Target: <500ns per call (VectorClock merge overhead acceptable).
+//
+// Example (RWMutex scenario):
+//
+//	// Reader 1
+//	mu.RLock()   // OnRLock
+//	y = x        // Read
+//	mu.RUnlock() // OnRUnlock (merges Reader 1's clock into readReleaseClock)
+//
+//	// Reader 2 (concurrent with Reader 1, no happens-before between them)
+//	mu.RLock()   // OnRLock
+//	z = x        // Read
+//	mu.RUnlock() // OnRUnlock (merges Reader 2's clock into readReleaseClock)
+//
+//	// Writer
+//	mu.Lock()    // OnAcquire (sees union of Reader 1 and Reader 2 clocks)
+//	x = 42       // Write happens-after both readers
+//
+//go:nosplit
+func (d *Detector) OnRUnlock(addr uintptr, ctx *goroutine.RaceContext) {
+	// Step 1: Get or create SyncVar for this mutex address.
+	syncVar := d.syncShadow.GetOrCreate(addr)
+
+	// Step 2: Merge current thread's clock into the accumulated reader
+	// release clock. Rm := Rm ⊔ Ct.
+	syncVar.MergeReadReleaseClock(ctx.C)
+
+	// Step 3: Increment logical clock to advance time.
+	ctx.IncrementClock()
+
+	// Step 4: Happens-before graph recording (v0.5.0). See OnAcquire's
+	// identical step for rationale.
+	if d.hbGraph != nil {
+		d.hbGraph.record(HBEventRUnlock, addr, ctx)
+	}
+
+	// Step 5: runtime/trace region end (v0.5.0). Closes the region OnRLock
+	// started for this (goroutine, addr) pair. See traceTracker.
+	d.tracer.release(addr, ctx)
 }
 
 // === Channel Synchronization Methods (Phase 4 Task 4.2) ===
 
+// OnChannelMake is called when a channel is created via make(chan T, N).
+//
+// This records the channel's buffer capacity, which OnChannelSendAfter and
+// OnChannelRecvAfter use to switch from the single-clock unbuffered model to
+// the per-message send/recv ring needed for buffered channels (the n-th
+// receive happens-before the (n+C)-th send completes). Calling this is
+// optional: a channel that never has OnChannelMake called for it (e.g. one
+// created before instrumentation could observe it) is simply treated as
+// unbuffered, which remains conservative.
+//
+// Parameters:
+//   - ch: Address of the channel being created
+//   - capacity: The channel's buffer capacity (from cap(ch)); 0 or a
+//     negative value leaves the channel on the unbuffered path
+//   - ctx: Current goroutine's RaceContext
+//
+// Performance Target: <100ns (single field write, no VectorClock work).
+//
+//go:nosplit
+func (d *Detector) OnChannelMake(ch uintptr, capacity int, ctx *goroutine.RaceContext) {
+	_ = ctx
+
+	syncVar := d.syncShadow.GetOrCreate(ch)
+	syncVar.SetChannelCapacity(capacity)
+}
+
 // OnChannelSendBefore is called BEFORE a channel send operation.
 //
 // For MVP, this is a no-op placeholder. In future phases, this could be used
@@ -938,6 +2184,11 @@ func (d *Detector) OnChannelSendBefore(ch uintptr, ctx *goroutine.RaceContext) {
 // This establishes a happens-before edge from the sender to future receivers.
 // The sender's clock is captured into the channel's sendClock.
 //
+// For an unbuffered channel, SetChannelSendClock also merges the channel's
+// most recent recvClock into ctx.C before capturing it: an unbuffered send
+// is a rendezvous, so the waiting receiver's prior work happens-before this
+// send returns too (the reverse of the usual send-before-receive edge).
+//
 // Algorithm: FastTrack [FT CHANNEL SEND]
 //  1. Get channel's SyncVar from sync shadow memory
 //  2. Capture sender's clock: ch.sendClock := ctx.C (copy)
@@ -945,6 +2196,8 @@ func (d *Detector) OnChannelSendBefore(ch uintptr, ctx *goroutine.RaceContext) {
 //
 // This implements the happens-before relationship:
 //   - Send happens-before Receive (for unbuffered and buffered channels)
+//   - For unbuffered channels, Receive also happens-before the matching
+//     Send's return (rendezvous)
 //
 // Parameters:
 //   - ch: Address of the channel being sent to
@@ -998,12 +2251,16 @@ func (d *Detector) OnChannelRecvBefore(ch uintptr, ctx *goroutine.RaceContext) {
 //
 // Algorithm: FastTrack [FT CHANNEL RECV]
 //  1. Get channel's SyncVar from sync shadow memory
-//  2. If channel has sendClock: ctx.C.Join(ch.sendClock)
-//  3. If channel is closed: ctx.C.Join(ch.closeClock)
+//  2. If channel has sendClock: ctx.JoinClock(ch.sendClock)
+//  3. If channel is closed: ctx.JoinClock(ch.closeClock)
 //  4. ctx.IncrementClock()
+//  5. Capture ctx.C into ch.recvClock (for the unbuffered rendezvous
+//     reverse edge - see SetChannelSendClock)
 //
 // This implements the happens-before relationship:
 //   - Sender's work happens-before Receiver's subsequent work
+//   - For unbuffered channels, this Receive also happens-before the next
+//     Send's return (rendezvous)
 //
 // Parameters:
 //   - ch: Address of the channel being received from
@@ -1024,12 +2281,16 @@ func (d *Detector) OnChannelRecvAfter(ch uintptr, ctx *goroutine.RaceContext) {
 	// Step 1: Get or create SyncVar for this channel address.
 	syncVar := d.syncShadow.GetOrCreate(ch)
 
-	// Step 2: If channel has a send clock, join it with receiver's clock.
+	// Step 2: If channel has a matching send clock, join it with receiver's
+	// clock. For an unbuffered channel this is simply the last send; for a
+	// buffered channel (see OnChannelMake) it's the FIFO-matching send for
+	// this receive, so a fast receiver draining a full buffer doesn't
+	// incorrectly join whichever sender happened to run last.
 	// This establishes happens-before from the sender.
-	sendClock := syncVar.GetChannelSendClock()
+	sendClock := syncVar.GetChannelRecvJoinClock()
 	if sendClock != nil {
 		// Ct := Ct ⊔ Csend (receiver clock joins sender clock).
-		ctx.C.Join(sendClock)
+		ctx.JoinClock(sendClock)
 	}
 
 	// Step 3: If channel is closed, join with close clock.
@@ -1037,17 +2298,20 @@ func (d *Detector) OnChannelRecvAfter(ch uintptr, ctx *goroutine.RaceContext) {
 	if syncVar.IsChannelClosed() {
 		closeClock := syncVar.GetChannelCloseClock()
 		if closeClock != nil {
-			ctx.C.Join(closeClock)
+			ctx.JoinClock(closeClock)
 		}
 	}
 
-	// Step 4: Optionally capture receiver's clock (for future bidirectional sync).
-	// MVP: Store recvClock but don't use it yet.
-	syncVar.SetChannelRecvClock(ctx.C)
-
-	// Step 5: Increment logical clock to advance time.
+	// Step 4: Increment logical clock to advance time.
 	// This must be done AFTER joining to maintain happens-before invariant.
 	ctx.IncrementClock()
+
+	// Step 5: Capture receiver's clock, used by SetChannelSendClock to
+	// implement the unbuffered rendezvous reverse edge (a later send on this
+	// channel happens-after this receive). Captured AFTER IncrementClock so
+	// it also covers work the receiver does immediately upon taking the
+	// value, up to (and including) this receive's own logical step.
+	syncVar.SetChannelRecvClock(ctx.C)
 }
 
 // OnChannelClose is called when a channel is closed via close(ch).
@@ -1254,7 +2518,7 @@ func (d *Detector) OnWaitGroupWaitAfter(wg uintptr, ctx *goroutine.RaceContext)
 	// Step 3: Merge doneClock into waiter's clock (happens-before).
 	// If doneClock is nil, no Done() calls have occurred yet (unusual but valid).
 	if doneClock != nil {
-		ctx.C.Join(doneClock)
+		ctx.JoinClock(doneClock)
 	}
 
 	// Step 4: Increment logical clock to advance time.
@@ -1262,6 +2526,37 @@ func (d *Detector) OnWaitGroupWaitAfter(wg uintptr, ctx *goroutine.RaceContext)
 	ctx.IncrementClock()
 }
 
+// ClearGoroutineStack forgets every shadow cell in [stackLo, stackHi)
+// (synth-3580).
+//
+// Call this when a goroutine terminates, passing the stack bounds recorded
+// via goroutine.RaceContext.SetStackBounds. Goroutine stacks are reused: the
+// Go runtime is free to hand a terminated goroutine's stack memory to an
+// unrelated new goroutine, whose locals then live at the same addresses. If
+// the retired goroutine's shadow cells for that range are still tracked,
+// the new goroutine's first access looks like it happens-after (or
+// same-epoch as) an access that has nothing to do with it, either
+// suppressing a real race or - more visibly - reporting one that never
+// happened. Clearing the range on exit means the new goroutine's first
+// access there is treated as a genuinely fresh address, same as any other
+// address that was never touched before.
+//
+// A zero-value (0, 0) range (the default when SetStackBounds was never
+// called) is a no-op, since there is currently no compiler instrumentation
+// hook that supplies real stack bounds automatically.
+//
+// Thread Safety: Safe for concurrent calls, same as ShadowMemory.ClearRange
+// - a concurrent OnRead/OnWrite for an address in the range may still
+// recreate a cell there immediately after it's cleared if a genuinely new
+// (unrelated) goroutine is already running at that address, which is
+// exactly the intended behavior.
+func (d *Detector) ClearGoroutineStack(stackLo, stackHi uintptr) {
+	if stackLo == 0 && stackHi == 0 {
+		return
+	}
+	d.shadowMemory.ClearRange(stackLo, stackHi)
+}
+
 // Reset resets the detector state for testing.
 //
 // This clears:
@@ -1271,14 +2566,35 @@ func (d *Detector) OnWaitGroupWaitAfter(wg uintptr, ctx *goroutine.RaceContext)
 //   - Reported races deduplication map (Phase 5)
 //   - Promotion statistics
 //
-// Thread Safety: NOT safe for concurrent access.
-// The caller must ensure no other goroutines are using the detector.
+// Thread Safety: Clearing shadowMemory and syncShadow themselves is still
+// NOT safe to run concurrently with in-flight OnRead/OnWrite/OnAcquire
+// calls from other goroutines - those calls may observe a torn map mid-Reset.
+// What Reset() does guarantee (synth-3577) is that it bumps the detector's
+// generation counter before touching anything else, so any goroutine whose
+// operation starts after that point - even one racing with the rest of
+// Reset() - misses its per-context shadow cell cache and looks addr up
+// fresh, rather than silently continuing to use a VarState that Reset() is
+// evicting. That's what makes `racedetector test` resetting between
+// packages while a leaked background goroutine from the previous package
+// is still running safe against permanently orphaned cells; it does not
+// make Reset() itself linearizable with concurrent detection.
 //
 // This is primarily used in test setup/teardown.
 func (d *Detector) Reset() {
+	// Flush every race enqueued by a call that happened-before this one
+	// (synth-3587) before clearing racesDetected/reportedRaces below - the
+	// reporter goroutine finishing that work afterwards would otherwise
+	// increment state this Reset just zeroed.
+	d.WaitForPendingReports()
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	// Bump the generation first (synth-3577), before anything below is
+	// cleared - see the Thread Safety note above.
+	d.generation.Add(1)
+	log.Debug("detector reset (generation now %d, %d races detected before reset)", d.generation.Load(), d.racesDetected.Load())
+
 	// Clear shadow memory.
 	d.shadowMemory.Reset()
 
@@ -1286,17 +2602,101 @@ func (d *Detector) Reset() {
 	d.syncShadow.Reset()
 
 	// Reset race counter.
-	d.racesDetected = 0
+	d.racesDetected.Store(0)
+
+	// Clear reported races (Phase 5 Task 5.3 / synth-3634).
+	d.reportedRaces.clear()
 
-	// Clear reported races map (Phase 5 Task 5.3).
-	// Range over all keys and delete them.
-	d.reportedRaces.Range(func(key, _ interface{}) bool {
-		d.reportedRaces.Delete(key)
-		return true // Continue iteration
+	// Clear address->symbol associations (synth-3630) - stale addresses
+	// from before this Reset must not be attributed to whatever new
+	// variable happens to land at the same address afterwards.
+	d.symbolAddrs.Range(func(key, _ interface{}) bool {
+		d.symbolAddrs.Delete(key)
+		return true
 	})
 
+	// Clear the allocation-site index (synth-3632) - same rationale as
+	// symbolAddrs above.
+	d.allocSites.clear()
+
 	// Reset promotion statistics.
-	d.stats = PromotionStats{}
+	d.stats.reset()
+
+	// Clear recent race reports (v0.5.0).
+	d.recentReportsMu.Lock()
+	d.recentReports = nil
+	d.recentReportsMu.Unlock()
+
+	// Clear per-site race counts and print counts (synth-3607) - reporting
+	// bookkeeping like reportedRaces/recentReports above, not core
+	// detection state.
+	d.siteCounts = nil
+	d.printedForSite = nil
+	d.totalPrinted = 0
+
+	// Reset the global report cap's suppression tally (synth-3635),
+	// alongside racesDetected above.
+	d.suppressedReports.Store(0)
+}
+
+// Snapshot captures the detector's shadow memory, sync shadow, and race
+// counter, for a later Restore (synth-3576).
+//
+// This is the finer-grained alternative to Reset() a test framework needs
+// to checkpoint state before a subtest and roll back after, rather than
+// discarding everything: a subtest can run, get diagnosed by whatever it
+// left in the detector, and then have exactly that state undone without
+// also erasing races found by tests that ran before it.
+//
+// Snapshot deliberately does not capture reportedRaces (the report
+// deduplication map), recentReports, or stats: those are reporting/metrics
+// bookkeeping, not race-detection state, and a subtest re-triggering a race
+// its checkpoint already reported once is still meaningful information
+// worth deduplicating against, not something Restore should un-suppress.
+//
+// Thread Safety: NOT safe for concurrent access. The caller must ensure no
+// other goroutines are using the detector while snapshotting, same
+// convention as Reset().
+func (d *Detector) Snapshot() *Snapshot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return &Snapshot{
+		shadowMemory:  d.shadowMemory.Snapshot(),
+		syncShadow:    d.syncShadow.Snapshot(),
+		racesDetected: int(d.racesDetected.Load()),
+	}
+}
+
+// Restore replaces the detector's shadow memory, sync shadow, and race
+// counter with a deep copy of snap, as previously returned by Snapshot
+// (synth-3576).
+//
+// Cloning snap's entries (rather than adopting them directly) means the
+// same snapshot can be restored from more than once, e.g. to reset between
+// several subtests that all build on the same fixture.
+//
+// Thread Safety: NOT safe for concurrent access. The caller must ensure no
+// other goroutines are using the detector during Restore(), same
+// convention as Reset().
+func (d *Detector) Restore(snap *Snapshot) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.shadowMemory.RestoreFrom(snap.shadowMemory)
+	d.syncShadow.RestoreFrom(snap.syncShadow)
+	d.racesDetected.Store(int64(snap.racesDetected))
+}
+
+// Snapshot is an opaque checkpoint of detector state captured by
+// Detector.Snapshot, to be handed to Detector.Restore (synth-3576).
+//
+// The zero Snapshot is not meaningful - always obtain one from
+// Detector.Snapshot().
+type Snapshot struct {
+	shadowMemory  map[uintptr]*shadowmem.VarState
+	syncShadow    map[uintptr]*syncshadow.SyncVar
+	racesDetected int
 }
 
 // GetPromotionStats returns a copy of the current promotion statistics.
@@ -1306,10 +2706,15 @@ func (d *Detector) Reset() {
 //   - Promotion rate: Promotions / TotalReads (expect <1%)
 //   - Promoted variables: PromotedVars (should be small)
 //
-// Thread Safety: Safe for concurrent calls (protected by mutex).
+// Thread Safety: Safe for concurrent calls. Aggregates each counter with an
+// atomic load (v0.4.0) rather than a mutex, so calling this while OnRead/
+// OnWrite are running on other goroutines never blocks them. The resulting
+// snapshot is not a consistent point-in-time view across all seven counters
+// (they're loaded independently), which is acceptable for monitoring/tuning
+// use but should not be relied on for exact cross-field invariants.
 //
 // Returns:
-//   - PromotionStats: Copy of current statistics
+//   - PromotionStats: Snapshot of current statistics
 //
 // Example usage:
 //
@@ -1317,9 +2722,44 @@ func (d *Detector) Reset() {
 //	fastPathRate := float64(stats.FastPathReads) / float64(stats.TotalReads) * 100
 //	fmt.Printf("Fast path rate: %.2f%%\n", fastPathRate)
 func (d *Detector) GetPromotionStats() PromotionStats {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	return d.stats
+	return d.stats.snapshot()
+}
+
+// ShadowCellCount returns the number of shadow memory cells currently
+// tracked (v0.5.0), i.e. the number of distinct addresses the detector has
+// seen an access to since the last Reset.
+//
+// This is a monitoring accessor intended for periodic reporting (expvar,
+// Prometheus), not the hot path - see shadowmem.ShadowMemory.Count.
+//
+// Thread Safety: Safe for concurrent calls.
+func (d *Detector) ShadowCellCount() int {
+	return d.shadowMemory.Count()
+}
+
+// TopShadowConsumers returns up to n shadow cells with the highest write
+// counts (v0.5.0), for a debug endpoint that wants to show which addresses
+// dominate shadow-memory traffic. See shadowmem.ShadowMemory.TopConsumers.
+//
+// Thread Safety: Safe for concurrent calls.
+func (d *Detector) TopShadowConsumers(n int) []shadowmem.Consumer {
+	return d.shadowMemory.TopConsumers(n)
+}
+
+// SyncVarCount returns the number of SyncVar entries currently tracked
+// (synth-3620), i.e. the number of distinct sync-primitive addresses
+// (mutexes, channels, WaitGroups, etc.) the detector has seen an operation
+// on since the last Reset.
+//
+// This is a monitoring accessor intended for periodic reporting (expvar,
+// Prometheus), not the hot path - see syncshadow.SyncShadow.Count. Combined
+// with MaxSyncShadowBytes, it lets a long-running program confirm that
+// short-lived sync primitives are actually being reclaimed instead of
+// leaking for the life of the process.
+//
+// Thread Safety: Safe for concurrent calls.
+func (d *Detector) SyncVarCount() int {
+	return d.syncShadow.Count()
 }
 
 // IsSamplingEnabled returns true if sampling is enabled (v0.3.0).
@@ -1356,3 +2796,16 @@ func (d *Detector) GetSampleRate() uint64 {
 	}
 	return d.sampler.GetEffectiveRate()
 }
+
+// IsWarmingUp returns true if DetectorOptions.WarmupDuration is still
+// forcing every access to be checked regardless of SampleRate (synth-3639).
+//
+// Always false when sampling is disabled (there's nothing to warm up out
+// of) - use this to distinguish "sampling at full rate because we're still
+// warming up" from "sampling at full rate because SampleRate <= 1" when
+// reporting effective overhead.
+//
+// Thread Safety: Safe for concurrent calls.
+func (d *Detector) IsWarmingUp() bool {
+	return d.sampler != nil && d.sampler.IsWarmingUp()
+}
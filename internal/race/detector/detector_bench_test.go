@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"sync/atomic"
 	"testing"
 
 	"github.com/kolkov/racedetector/internal/race/epoch"
@@ -445,6 +446,33 @@ func BenchmarkParallelReadWrite(b *testing.B) {
 	})
 }
 
+// BenchmarkParallelOnRead_SharedAddress benchmarks concurrent reads of the
+// SAME address from distinct goroutines (v0.4.0).
+//
+// Unlike BenchmarkParallelOnRead (each goroutine touches its own address
+// space), this forces every OnRead call to hit the promoted/shared-reader
+// path and bump the same stats counters repeatedly. It exists to measure
+// the scalability win from replacing d.mu with per-field atomics
+// (statsCounters): before that change, every goroutine here serialized on
+// one mutex just to update PromotionStats, independent of the actual
+// race-detection work. Run with -cpu=1,2,4,8 to see scaling.
+func BenchmarkParallelOnRead_SharedAddress(b *testing.B) {
+	d := NewDetector()
+	addr := uintptr(0x400000)
+
+	var nextTID atomic.Uint32
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	b.RunParallel(func(pb *testing.PB) {
+		tid := uint16(nextTID.Add(1))
+		ctx := goroutine.Alloc(tid)
+		for pb.Next() {
+			d.OnRead(addr, ctx)
+		}
+	})
+}
+
 // BenchmarkOnReadOnWrite_Comparison directly compares OnRead vs OnWrite performance.
 //
 // This helps verify that OnRead is as fast or faster than OnWrite.
@@ -22,8 +22,8 @@ func TestNewDetector(t *testing.T) {
 		t.Error("shadowMemory not initialized")
 	}
 
-	if d.racesDetected != 0 {
-		t.Errorf("racesDetected = %d, want 0", d.racesDetected)
+	if d.racesDetected.Load() != 0 {
+		t.Errorf("racesDetected = %d, want 0", d.racesDetected.Load())
 	}
 }
 
@@ -125,6 +125,11 @@ func TestOnWrite_WriteWriteRace(t *testing.T) {
 	// Second write should detect write-write race.
 	d.OnWrite(addr, ctx)
 
+	// Reporting - including the stderr write below asserts on - happens on
+	// a separate goroutine (synth-3587); wait for it to catch up before
+	// restoring stderr.
+	d.WaitForPendingReports()
+
 	// Restore stderr.
 	w.Close()
 	os.Stderr = oldStderr
@@ -184,6 +189,11 @@ func TestOnWrite_ReadWriteRace(t *testing.T) {
 	// Write should detect read-write race.
 	d.OnWrite(addr, ctx)
 
+	// Reporting - including the stderr write below asserts on - happens on
+	// a separate goroutine (synth-3587); wait for it to catch up before
+	// restoring stderr.
+	d.WaitForPendingReports()
+
 	// Restore stderr.
 	w.Close()
 	os.Stderr = oldStderr
@@ -232,6 +242,54 @@ func TestOnWrite_NoRaceWithHappensBefore(t *testing.T) {
 	}
 }
 
+// TestOnWrite_MuteAfterReport_PoisonsAddressAfterFirstRace verifies that
+// with DetectorOptions.MuteAfterReport set, a second conflicting write to
+// an address that already reported a race is no longer detected - the
+// address is poisoned rather than merely deduplicated (synth-3636).
+func TestOnWrite_MuteAfterReport_PoisonsAddressAfterFirstRace(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{MuteAfterReport: true})
+	ctx := goroutine.Alloc(1)
+	addr := uintptr(0x8000)
+
+	// First write, from TID 1.
+	ctx.C.Set(1, 10)
+	ctx.Epoch = epoch.NewEpoch(1, 10)
+	d.OnWrite(addr, ctx)
+
+	// Force a write-write race the same way TestOnWrite_WriteWriteRace
+	// does: back-date the shadow cell's W, then write again from an
+	// earlier logical time.
+	vs := d.shadowMemory.Get(addr)
+	if vs == nil {
+		t.Fatal("Shadow cell not created")
+	}
+	vs.SetW(epoch.NewEpoch(1, 20))
+	ctx.C.Set(1, 5)
+	ctx.Epoch = epoch.NewEpoch(1, 5)
+	d.OnWrite(addr, ctx)
+	d.WaitForPendingReports()
+
+	if d.RacesDetected() != 1 {
+		t.Fatalf("Expected the first race to be detected, got %d", d.RacesDetected())
+	}
+	if !vs.IsPoisoned() {
+		t.Fatal("Expected the address to be poisoned after its race was reported")
+	}
+
+	// A second, independently racy write - back-date W again the same way -
+	// must not be detected: the poisoned check short-circuits before the
+	// happens-before comparison ever runs.
+	vs.SetW(epoch.NewEpoch(1, 40))
+	ctx.C.Set(1, 30)
+	ctx.Epoch = epoch.NewEpoch(1, 30)
+	d.OnWrite(addr, ctx)
+	d.WaitForPendingReports()
+
+	if d.RacesDetected() != 1 {
+		t.Errorf("Expected no further races on a poisoned address, got %d total", d.RacesDetected())
+	}
+}
+
 // TestOnWrite_MultipleAddresses tests that writes to different addresses
 // are tracked independently.
 func TestOnWrite_MultipleAddresses(t *testing.T) {
@@ -266,6 +324,91 @@ func TestOnWrite_MultipleAddresses(t *testing.T) {
 	}
 }
 
+// TestOnWriteBatch_NoFalsePositive verifies a batch of writes to distinct
+// addresses from a single goroutine reports no races (synth-3598).
+func TestOnWriteBatch_NoFalsePositive(t *testing.T) {
+	d := NewDetector()
+	ctx := goroutine.Alloc(1)
+	addrs := []uintptr{0x6100, 0x6200, 0x6300}
+
+	d.OnWriteBatch(addrs, ctx)
+
+	if d.RacesDetected() != 0 {
+		t.Errorf("Batch write to distinct addresses reported races")
+	}
+
+	for _, addr := range addrs {
+		if d.shadowMemory.Get(addr) == nil {
+			t.Errorf("Shadow cell not created for address 0x%x", addr)
+		}
+	}
+}
+
+// TestOnWriteBatch_EmptyBatch verifies an empty batch is a no-op.
+func TestOnWriteBatch_EmptyBatch(t *testing.T) {
+	d := NewDetector()
+	ctx := goroutine.Alloc(1)
+
+	d.OnWriteBatch(nil, ctx)
+
+	if d.RacesDetected() != 0 {
+		t.Errorf("Empty batch reported races")
+	}
+}
+
+// TestOnWriteBatch_SingleAddressDelegatesToOnWrite verifies a single-address
+// batch behaves exactly like a direct OnWrite call.
+func TestOnWriteBatch_SingleAddressDelegatesToOnWrite(t *testing.T) {
+	d := NewDetector()
+	ctx := goroutine.Alloc(1)
+	addr := uintptr(0x6400)
+	epochBefore := ctx.GetEpoch()
+
+	d.OnWriteBatch([]uintptr{addr}, ctx)
+
+	vs := d.shadowMemory.Get(addr)
+	if vs == nil {
+		t.Fatal("Shadow cell not created for single-address batch")
+	}
+	if !vs.GetW().Same(epochBefore) {
+		t.Error("Single-address batch did not record the write epoch")
+	}
+}
+
+// TestOnWriteBatch_DetectsRace verifies a write inside a batch still races
+// against a conflicting write from another goroutine - batching only
+// amortizes the sampling gate, it must not suppress detection.
+func TestOnWriteBatch_DetectsRace(t *testing.T) {
+	d := NewDetector()
+	ctx := goroutine.Alloc(1)
+	addr := uintptr(0x6500)
+
+	// First write at epoch (1, 10).
+	ctx.C.Set(1, 10)
+	ctx.Epoch = epoch.NewEpoch(1, 10)
+	d.OnWriteBatch([]uintptr{addr}, ctx)
+
+	vs := d.shadowMemory.Get(addr)
+	if vs == nil {
+		t.Fatal("Shadow cell not created")
+	}
+
+	// Set previous write to epoch (1, 20) - a "future" write.
+	vs.SetW(epoch.NewEpoch(1, 20))
+
+	// Reset context to earlier time (1, 5) to create a race condition.
+	ctx.C.Set(1, 5)
+	ctx.Epoch = epoch.NewEpoch(1, 5)
+
+	// Second write, as part of a batch, should still detect the race.
+	d.OnWriteBatch([]uintptr{uintptr(0x6600), addr}, ctx)
+	d.WaitForPendingReports()
+
+	if d.RacesDetected() != 1 {
+		t.Errorf("Write-write race inside a batch not detected, got %d races", d.RacesDetected())
+	}
+}
+
 // TestOnWrite_UpdatesShadowMemory tests that OnWrite correctly updates
 // the shadow memory write epoch.
 func TestOnWrite_UpdatesShadowMemory(t *testing.T) {
@@ -344,6 +487,7 @@ func TestRacesDetected(t *testing.T) {
 	os.Stderr, _ = os.Open(os.DevNull)
 
 	d.OnWrite(addr, ctx)
+	d.WaitForPendingReports()
 
 	os.Stderr = oldStderr
 
@@ -372,6 +516,7 @@ func TestReset(t *testing.T) {
 	oldStderr := os.Stderr
 	os.Stderr, _ = os.Open(os.DevNull)
 	d.OnWrite(addr, ctx)
+	d.WaitForPendingReports()
 	os.Stderr = oldStderr
 
 	// Verify state before reset.
@@ -644,6 +789,11 @@ func TestOnRead_WriteReadRace(t *testing.T) {
 	// Read should detect write-read race.
 	d.OnRead(addr, ctx)
 
+	// Reporting - including the stderr write below asserts on - happens on
+	// a separate goroutine (synth-3587); wait for it to catch up before
+	// restoring stderr.
+	d.WaitForPendingReports()
+
 	// Restore stderr.
 	w.Close()
 	os.Stderr = oldStderr
@@ -1014,3 +1164,179 @@ func TestConcurrentReadsAndWrites(_ *testing.T) {
 
 	// Test passes if no panics occurred.
 }
+
+// TestDetector_ShadowCellCount verifies ShadowCellCount reports the number
+// of distinct addresses the detector has tracked accesses for.
+func TestDetector_ShadowCellCount(t *testing.T) {
+	d := NewDetector()
+	ctx := createTestContext(1)
+
+	if got := d.ShadowCellCount(); got != 0 {
+		t.Fatalf("ShadowCellCount() = %d on fresh detector, want 0", got)
+	}
+
+	d.OnWrite(0x50000, ctx)
+	d.OnWrite(0x50008, ctx)
+	d.OnRead(0x50000, ctx) // Same cell as the first write, shouldn't double-count.
+
+	if got := d.ShadowCellCount(); got != 2 {
+		t.Errorf("ShadowCellCount() = %d, want 2", got)
+	}
+}
+
+// TestDetector_TopShadowConsumers verifies TopShadowConsumers ranks
+// addresses by write count, delegating to the shadow memory.
+func TestDetector_TopShadowConsumers(t *testing.T) {
+	d := NewDetector()
+	ctx := createTestContext(1)
+
+	d.OnWrite(0x60000, ctx)
+	d.OnWrite(0x60008, ctx)
+	d.OnWrite(0x60008, ctx)
+
+	got := d.TopShadowConsumers(10)
+	if len(got) != 2 {
+		t.Fatalf("TopShadowConsumers() returned %d entries, want 2", len(got))
+	}
+	if got[0].Addr != 0x60008 || got[0].WriteCount != 2 {
+		t.Errorf("TopShadowConsumers()[0] = %+v, want {Addr: 0x60008, WriteCount: 2}", got[0])
+	}
+}
+
+// TestDetector_TopAddresses verifies TopAddresses ranks addresses by total
+// access count (not just writes, unlike TopShadowConsumers), reports
+// promotion/demotion counts, and resolves Owner from a registered symbol
+// (synth-3642).
+func TestDetector_TopAddresses(t *testing.T) {
+	d := NewDetector()
+	ctx := createTestContext(1)
+
+	// 0x61008 is checked four times (two writes, two reads); 0x61000 only
+	// once, so it must rank second.
+	d.OnWrite(0x61000, ctx)
+	RegisterSymbol(0x9001, "Stats.Counter", "field")
+	d.OnWriteSym(0x61008, 0x9001, ctx)
+	d.OnWrite(0x61008, ctx)
+	d.OnRead(0x61008, ctx)
+	d.OnRead(0x61008, ctx)
+
+	got := d.TopAddresses(10)
+	if len(got) != 2 {
+		t.Fatalf("TopAddresses() returned %d entries, want 2", len(got))
+	}
+	if got[0].Addr != 0x61008 || got[0].CheckCount != 4 {
+		t.Errorf("TopAddresses()[0] = %+v, want {Addr: 0x61008, CheckCount: 4}", got[0])
+	}
+	if got[0].Owner != "Stats.Counter" {
+		t.Errorf("TopAddresses()[0].Owner = %q, want %q", got[0].Owner, "Stats.Counter")
+	}
+	if got[0].OwnerFunc == "" {
+		t.Error("TopAddresses()[0].OwnerFunc = \"\", want the calling test function resolved from the write PC")
+	}
+	if got[1].Addr != 0x61000 || got[1].CheckCount != 1 {
+		t.Errorf("TopAddresses()[1] = %+v, want {Addr: 0x61000, CheckCount: 1}", got[1])
+	}
+	if got[1].Owner != "" {
+		t.Errorf("TopAddresses()[1].Owner = %q, want \"\" (never written through OnWriteSym)", got[1].Owner)
+	}
+}
+
+// TestDetector_TopAddresses_PromotionsAndDemotions verifies TopAddresses
+// surfaces a cell's promotion/demotion counts, which OnRead/OnWrite
+// maintain directly on the VarState (synth-3642).
+func TestDetector_TopAddresses_PromotionsAndDemotions(t *testing.T) {
+	d := NewDetector()
+	addr := uintptr(0x62000)
+
+	// Exercise the counters directly on the shadow cell, the same way
+	// other detector tests seed state that would otherwise require a
+	// full concurrent promotion/demotion scenario to reach (see
+	// TestOnWrite_SameEpochFastPath and friends above for the pattern).
+	vs := d.shadowMemory.GetOrCreate(addr)
+	vs.IncrementCheckCount()
+	vs.IncrementPromotionCount()
+	vs.IncrementPromotionCount()
+	vs.IncrementDemotionCount()
+
+	got := d.TopAddresses(10)
+	if len(got) != 1 {
+		t.Fatalf("TopAddresses() returned %d entries, want 1", len(got))
+	}
+	if got[0].Promotions != 2 {
+		t.Errorf("TopAddresses()[0].Promotions = %d, want 2", got[0].Promotions)
+	}
+	if got[0].Demotions != 1 {
+		t.Errorf("TopAddresses()[0].Demotions = %d, want 1", got[0].Demotions)
+	}
+}
+
+// TestDetector_SyncVarCount verifies SyncVarCount reports the number of
+// distinct sync-primitive addresses the detector has tracked operations for
+// (synth-3620).
+func TestDetector_SyncVarCount(t *testing.T) {
+	d := NewDetector()
+	ctx := createTestContext(1)
+
+	if got := d.SyncVarCount(); got != 0 {
+		t.Fatalf("SyncVarCount() = %d on fresh detector, want 0", got)
+	}
+
+	d.OnAcquire(0x70000, ctx)
+	d.OnRelease(0x70000, ctx)
+	d.OnAcquire(0x70008, ctx)
+	d.OnRelease(0x70008, ctx)
+	d.OnAcquire(0x70000, ctx) // Same mutex as the first pair, shouldn't double-count.
+	d.OnRelease(0x70000, ctx)
+
+	if got := d.SyncVarCount(); got != 2 {
+		t.Errorf("SyncVarCount() = %d, want 2", got)
+	}
+}
+
+// TestNewDetectorWithOptions_MaxSyncShadowBytesReclaimsColdMutexes verifies
+// that MaxSyncShadowBytes bounds SyncVarCount instead of letting it grow
+// without limit as new mutex addresses are used (synth-3620).
+func TestNewDetectorWithOptions_MaxSyncShadowBytesReclaimsColdMutexes(t *testing.T) {
+	// A cap of a few hundred bytes is well under one SyncVar per address
+	// (~1KB once it accumulates state), so 32 distinct mutex addresses
+	// should force reclamation well before all of them are tracked at once.
+	d := NewDetectorWithOptions(DetectorOptions{MaxSyncShadowBytes: 256})
+	ctx := createTestContext(1)
+
+	for i := uintptr(0); i < 32; i++ {
+		addr := 0x80000 + i*8
+		d.OnAcquire(addr, ctx)
+		d.OnRelease(addr, ctx)
+	}
+
+	if got := d.SyncVarCount(); got >= 32 {
+		t.Errorf("SyncVarCount() = %d, want fewer than 32 (cap enforced)", got)
+	}
+}
+
+// TestNewDetectorWithOptions_SingleThreadedOverridesBlockOnFull verifies
+// that SingleThreaded forces raceQueue's drop-and-count policy even when
+// RaceQueueBlockOnFull was also requested (synth-3615): the two are
+// contradictory intents (wait for a slot vs. never risk blocking a
+// cooperatively-scheduled caller), and SingleThreaded wins.
+func TestNewDetectorWithOptions_SingleThreadedOverridesBlockOnFull(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{
+		RaceQueueBlockOnFull: true,
+		SingleThreaded:       true,
+	})
+
+	if d.raceQueue.blockOnFull {
+		t.Error("raceQueue.blockOnFull = true with SingleThreaded set, want false")
+	}
+}
+
+// TestNewDetectorWithOptions_BlockOnFullWithoutSingleThreaded verifies
+// RaceQueueBlockOnFull still takes effect on its own, unaffected by the
+// SingleThreaded field's zero value (synth-3615).
+func TestNewDetectorWithOptions_BlockOnFullWithoutSingleThreaded(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{RaceQueueBlockOnFull: true})
+
+	if !d.raceQueue.blockOnFull {
+		t.Error("raceQueue.blockOnFull = false with only RaceQueueBlockOnFull set, want true")
+	}
+}
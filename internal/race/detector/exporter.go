@@ -0,0 +1,362 @@
+package detector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExportFormat selects the wire format ExportOptions.URL is POSTed with.
+type ExportFormat string
+
+const (
+	// ExportFormatJSON POSTs a flat JSON object describing the race - the
+	// default, and the simplest to consume from a one-off collector.
+	ExportFormatJSON ExportFormat = "json"
+
+	// ExportFormatOTLP POSTs an OTLP/HTTP JSON logs payload (one log
+	// record per race), so the report can go straight into an existing
+	// OpenTelemetry collector alongside a fleet's other telemetry instead
+	// of needing a bespoke ingester for racedetector's own JSON shape.
+	ExportFormatOTLP ExportFormat = "otlp"
+)
+
+// ExportOptions configures the remote report sink (synth-3602): a
+// best-effort HTTP POST of every newly reported race, so a fleet running
+// the detector in canary mode can centralize findings instead of only ever
+// seeing them in each instance's own stderr.
+//
+// Default: zero value, which leaves exporting disabled - see
+// reportExporter, which is only created when URL is non-empty.
+type ExportOptions struct {
+	// URL is the HTTP endpoint each race report is POSTed to. Empty
+	// disables exporting entirely.
+	URL string
+
+	// Format selects the request body's shape. Empty is treated as
+	// ExportFormatJSON.
+	Format ExportFormat
+
+	// Headers are added to every export request, e.g. for an
+	// "Authorization" bearer token an ingest endpoint requires.
+	Headers map[string]string
+
+	// Timeout bounds both a single export request and the drain Close
+	// performs on shutdown. Zero means defaultExportTimeout.
+	Timeout time.Duration
+}
+
+// defaultExportTimeout is used when ExportOptions.Timeout is zero.
+const defaultExportTimeout = 5 * time.Second
+
+// exportQueueCapacity bounds how many reports reportExporter buffers
+// waiting for a free sender slot before it starts dropping them. Sized
+// generously relative to maxRecentReports (the in-process ring buffer this
+// mirrors) since a burst of the same race repeating across goroutines is
+// already collapsed by reportRaceV2WithStack's deduplication before it
+// ever reaches the exporter.
+const exportQueueCapacity = 256
+
+// reportExporter POSTs each newly detected race to ExportOptions.URL on a
+// dedicated goroutine, so a slow or unreachable collector never adds
+// latency to the detector's own hot path or blocks d.mu (see
+// reportRaceV2WithStack, which only ever does a non-blocking enqueue).
+//
+// This mirrors the racequeue.go async-reporter pattern (synth-3587):
+// producer enqueues, a single background goroutine drains and does the
+// slow part, and a bounded queue sheds load under overload rather than
+// applying backpressure to callers that can't afford to block.
+type reportExporter struct {
+	opts   ExportOptions
+	client *http.Client
+	queue  chan *RaceReport
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newReportExporter starts the background sender goroutine for opts.
+// Callers must have already checked opts.URL != "" - it accepts the
+// caller's opts.Timeout as its own HTTP client timeout.
+func newReportExporter(opts ExportOptions) *reportExporter {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultExportTimeout
+	}
+	if opts.Format == "" {
+		opts.Format = ExportFormatJSON
+	}
+
+	e := &reportExporter{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  make(chan *RaceReport, exportQueueCapacity),
+		done:   make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// enqueue hands report to the sender goroutine without blocking. If the
+// queue is full - the collector is slower than races are arriving - the
+// report is dropped and noted on stderr rather than stalling the caller,
+// which is holding Detector.mu (see reportRaceV2WithStack).
+func (e *reportExporter) enqueue(report *RaceReport) {
+	select {
+	case e.queue <- report:
+	default:
+		fmt.Fprintf(os.Stderr, "racedetector: export queue full, dropping race report %s\n", report.DeduplicationKey)
+	}
+}
+
+// run drains e.queue until close is called, then drains whatever's left
+// once more before returning, so a report enqueued just before shutdown
+// still gets sent.
+func (e *reportExporter) run() {
+	defer e.wg.Done()
+	for {
+		select {
+		case report := <-e.queue:
+			e.send(report)
+		case <-e.done:
+			for {
+				select {
+				case report := <-e.queue:
+					e.send(report)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send POSTs report to opts.URL, logging (but not retrying) any failure -
+// this is a best-effort observability sink, not a delivery guarantee, the
+// same tradeoff writeReportFile/writeRacesFile make for their side
+// channels.
+func (e *reportExporter) send(report *RaceReport) {
+	body, err := e.encode(report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "racedetector: encoding race report for export: %v\n", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "racedetector: building export request: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "racedetector: exporting race report: %v\n", err)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Fprintf(os.Stderr, "racedetector: export endpoint %s returned %s\n", e.opts.URL, resp.Status)
+	}
+}
+
+// encode marshals report according to opts.Format.
+func (e *reportExporter) encode(report *RaceReport) ([]byte, error) {
+	if e.opts.Format == ExportFormatOTLP {
+		return json.Marshal(otlpLogsPayload(report))
+	}
+	return json.Marshal(jsonExportPayloadFrom(report))
+}
+
+// close stops accepting the drain loop's steady-state wait and blocks
+// until the queue is empty or opts.Timeout elapses, whichever comes
+// first - the same "flush guarantee, bounded" shape as
+// Detector.WaitForPendingReports, except here the thing being waited on is
+// network I/O rather than a local goroutine, so an unbounded wait isn't
+// acceptable.
+func (e *reportExporter) close() {
+	close(e.done)
+	drained := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(e.opts.Timeout):
+	}
+}
+
+// raceTypeFromKey recovers the race type ("write-write", "read-write",
+// "write-read") encoded at the front of a DeduplicationKey - see
+// generateDeduplicationKey - so export payloads can surface it as its own
+// field instead of forcing a consumer to parse it back out of the key.
+func raceTypeFromKey(key string) string {
+	if idx := strings.IndexByte(key, ':'); idx >= 0 {
+		return key[:idx]
+	}
+	return key
+}
+
+// accessExportInfo is one access's exported shape, shared by both
+// ExportFormatJSON and the OTLP attributes below.
+type accessExportInfo struct {
+	Type        string `json:"type"`
+	Addr        string `json:"addr"`
+	GoroutineID uint32 `json:"goroutine_id"`
+	TestName    string `json:"test_name,omitempty"`
+	SymbolName  string `json:"symbol_name,omitempty"`
+	SymbolKind  string `json:"symbol_kind,omitempty"`
+}
+
+func exportAccess(a AccessInfo) accessExportInfo {
+	return accessExportInfo{
+		Type:        a.Type.String(),
+		Addr:        fmt.Sprintf("0x%x", a.Addr),
+		GoroutineID: a.GoroutineID,
+		TestName:    a.TestName,
+		SymbolName:  a.SymbolName,
+		SymbolKind:  a.SymbolKind,
+	}
+}
+
+// jsonExportPayload is the ExportFormatJSON request body: the report's
+// structured fields plus its fully formatted text, so a consumer that
+// only wants to log the race verbatim doesn't need to reconstruct
+// Format's output from the structured fields itself.
+type jsonExportPayload struct {
+	DeduplicationKey string           `json:"deduplication_key"`
+	RaceType         string           `json:"race_type"`
+	Kind             RaceKind         `json:"kind"`
+	Severity         Severity         `json:"severity"`
+	Current          accessExportInfo `json:"current"`
+	Previous         accessExportInfo `json:"previous"`
+	Report           string           `json:"report"`
+}
+
+func jsonExportPayloadFrom(report *RaceReport) jsonExportPayload {
+	classification := Classify(report)
+	return jsonExportPayload{
+		DeduplicationKey: report.DeduplicationKey,
+		RaceType:         raceTypeFromKey(report.DeduplicationKey),
+		Kind:             classification.Kind,
+		Severity:         classification.Severity,
+		Current:          exportAccess(report.Current),
+		Previous:         exportAccess(report.Previous),
+		Report:           report.String(),
+	}
+}
+
+// OTLP/HTTP JSON logs shapes (https://opentelemetry.io/docs/specs/otlp/),
+// hand-rolled rather than pulling in the OTLP SDK: racedetector emits at
+// most one log record per unique race, so the SDK's batching/retry/gRPC
+// machinery would be pure overhead for what's a handful of fields.
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpLogRecord struct {
+	SeverityText string         `json:"severityText"`
+	Body         otlpAnyValue   `json:"body"`
+	Attributes   []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+func otlpAttr(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: value}}
+}
+
+func otlpIntAttr(key string, value uint32) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{IntValue: fmt.Sprintf("%d", value)}}
+}
+
+// otlpSeverityText maps a Classify severity to the OTLP log severity text
+// closest to it, so a collector's default severity-based routing/alerting
+// already does something sensible without a consumer-side mapping.
+func otlpSeverityText(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "ERROR"
+	case SeverityHigh:
+		return "WARN"
+	case SeverityLow:
+		return "INFO"
+	default:
+		return "WARN"
+	}
+}
+
+func otlpLogsPayload(report *RaceReport) otlpLogsRequest {
+	classification := Classify(report)
+	record := otlpLogRecord{
+		SeverityText: otlpSeverityText(classification.Severity),
+		Body:         otlpAnyValue{StringValue: report.String()},
+		Attributes: []otlpKeyValue{
+			otlpAttr("race.deduplication_key", report.DeduplicationKey),
+			otlpAttr("race.type", raceTypeFromKey(report.DeduplicationKey)),
+			otlpAttr("race.kind", string(classification.Kind)),
+			otlpAttr("race.severity", string(classification.Severity)),
+			otlpAttr("race.current.type", report.Current.Type.String()),
+			otlpIntAttr("race.current.goroutine_id", report.Current.GoroutineID),
+			otlpAttr("race.previous.type", report.Previous.Type.String()),
+			otlpIntAttr("race.previous.goroutine_id", report.Previous.GoroutineID),
+		},
+	}
+	if report.Current.TestName != "" {
+		record.Attributes = append(record.Attributes, otlpAttr("race.current.test_name", report.Current.TestName))
+	}
+	if report.Previous.TestName != "" {
+		record.Attributes = append(record.Attributes, otlpAttr("race.previous.test_name", report.Previous.TestName))
+	}
+	if report.Current.SymbolName != "" {
+		record.Attributes = append(record.Attributes, otlpAttr("race.symbol_name", report.Current.SymbolName))
+		record.Attributes = append(record.Attributes, otlpAttr("race.symbol_kind", report.Current.SymbolKind))
+	}
+
+	return otlpLogsRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{otlpAttr("service.name", "racedetector")},
+			},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: "github.com/kolkov/racedetector"},
+				LogRecords: []otlpLogRecord{record},
+			}},
+		}},
+	}
+}
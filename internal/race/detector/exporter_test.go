@@ -0,0 +1,147 @@
+package detector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestReport builds a minimal RaceReport suitable for exporter tests,
+// without needing a live shadow-memory conflict to produce one.
+func newTestReport() *RaceReport {
+	return &RaceReport{
+		DeduplicationKey: "write-write:000000000000abcd:000000000000ef01",
+		Current: AccessInfo{
+			Type:        AccessWrite,
+			Addr:        0x1000,
+			GoroutineID: 2,
+			TestName:    "TestCurrent",
+		},
+		Previous: AccessInfo{
+			Type:        AccessWrite,
+			Addr:        0x1000,
+			GoroutineID: 1,
+		},
+	}
+}
+
+// TestReportExporter_JSON_PostsExpectedFields verifies the default JSON
+// format POSTs the report's structured fields and formatted text.
+func TestReportExporter_JSON_PostsExpectedFields(t *testing.T) {
+	var mu sync.Mutex
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		buf := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(buf)
+		gotBody = buf
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	e := newReportExporter(ExportOptions{URL: srv.URL})
+	e.enqueue(newTestReport())
+	e.close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var payload jsonExportPayload
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("unmarshal export body: %v (body=%s)", err, gotBody)
+	}
+	if payload.RaceType != "write-write" {
+		t.Errorf("RaceType = %q, want write-write", payload.RaceType)
+	}
+	if payload.Current.GoroutineID != 2 || payload.Current.TestName != "TestCurrent" {
+		t.Errorf("Current = %+v, want goroutine 2 with TestName TestCurrent", payload.Current)
+	}
+	if payload.Report == "" {
+		t.Errorf("Report field is empty, want formatted report text")
+	}
+}
+
+// TestReportExporter_OTLP_PostsLogRecord verifies the OTLP format wraps
+// the report as a single log record with race attributes.
+func TestReportExporter_OTLP_PostsLogRecord(t *testing.T) {
+	var mu sync.Mutex
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		buf := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(buf)
+		gotBody = buf
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	e := newReportExporter(ExportOptions{URL: srv.URL, Format: ExportFormatOTLP})
+	e.enqueue(newTestReport())
+	e.close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var payload otlpLogsRequest
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("unmarshal export body: %v (body=%s)", err, gotBody)
+	}
+	if len(payload.ResourceLogs) != 1 || len(payload.ResourceLogs[0].ScopeLogs) != 1 ||
+		len(payload.ResourceLogs[0].ScopeLogs[0].LogRecords) != 1 {
+		t.Fatalf("unexpected OTLP shape: %+v", payload)
+	}
+	record := payload.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record.Body.StringValue == "" {
+		t.Errorf("log record body is empty, want formatted report text")
+	}
+	foundType := false
+	for _, attr := range record.Attributes {
+		if attr.Key == "race.type" && attr.Value.StringValue == "write-write" {
+			foundType = true
+		}
+	}
+	if !foundType {
+		t.Errorf("attributes = %+v, want a race.type=write-write attribute", record.Attributes)
+	}
+}
+
+// TestReportExporter_QueueFull_DropsWithoutBlocking verifies enqueue never
+// blocks the caller even when the sender can't keep up - a slow collector
+// must not add latency to the detector's own hot path.
+func TestReportExporter_QueueFull_DropsWithoutBlocking(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	e := newReportExporter(ExportOptions{URL: srv.URL, Timeout: 2 * time.Second})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < exportQueueCapacity+10; i++ {
+			e.enqueue(newTestReport())
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("enqueue blocked instead of dropping once the queue filled up")
+	}
+}
+
+// TestRaceTypeFromKey verifies the race type prefix is recovered from a
+// DeduplicationKey.
+func TestRaceTypeFromKey(t *testing.T) {
+	got := raceTypeFromKey("read-write:1:2")
+	if got != "read-write" {
+		t.Errorf("raceTypeFromKey() = %q, want read-write", got)
+	}
+}
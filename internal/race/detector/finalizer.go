@@ -0,0 +1,112 @@
+package detector
+
+import (
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// === Finalizer Synchronization Methods (synth-3572) ===
+//
+// The Go runtime guarantees that a finalizer registered via
+// runtime.SetFinalizer(obj, f) only runs after obj becomes unreachable to
+// the mutator. That guarantee is itself a happens-before edge - everything
+// the mutator did to obj before it let go of the last reference is safe for
+// the finalizer to observe - but the detector has no way to see it without
+// an explicit hook: OnFinalizerSet captures the registering goroutine's
+// clock, and OnFinalizerRun (called on whatever goroutine the runtime
+// chooses to run the finalizer on) joins that clock before the finalizer
+// body executes.
+//
+// This intentionally does not try to track runtime.KeepAlive: KeepAlive is
+// a compiler/runtime intrinsic that only affects how long the GC considers
+// an object reachable, so it doesn't introduce a happens-before edge of its
+// own - it just delays when the guarantee above kicks in. Code that relies
+// on the ordering already calls SetFinalizer only once it's done mutating
+// the object (often after a KeepAlive call), so the SetFinalizer call site's
+// clock is the right thing to capture.
+
+// OnFinalizerSet handles runtime.SetFinalizer(obj, finalizer) registration.
+//
+// This captures the registering goroutine's clock, which OnFinalizerRun
+// later joins into the finalizer goroutine's clock before the finalizer body
+// runs - see the package-level rationale above.
+//
+// Algorithm:
+//  1. Get or create SyncVar for this object's address
+//  2. Capture registering thread's clock: syncVar.registerClock := ctx.C (copy)
+//  3. ctx.IncrementClock()
+//
+// Parameters:
+//   - obj: Address of the object the finalizer is being registered on
+//   - ctx: Current goroutine's RaceContext
+//
+// Thread Safety: Safe for concurrent calls from multiple goroutines.
+//
+// Performance Target: <300ns (VectorClock copy overhead acceptable).
+//
+// Example:
+//
+//	runtime.SetFinalizer(obj, finalizerFunc)  // Compiler/runtime inserts: racefinalizerset(obj)
+//	// OnFinalizerSet captures the registering goroutine's clock
+//
+//go:nosplit
+func (d *Detector) OnFinalizerSet(obj uintptr, ctx *goroutine.RaceContext) {
+	// Step 1: Get or create SyncVar for this object's address.
+	syncVar := d.syncShadow.GetOrCreate(obj)
+
+	// Step 2: Capture registering thread's clock into the object's
+	// FinalizerState. This makes the registering goroutine's logical time
+	// visible to the finalizer once it runs.
+	syncVar.SetFinalizerRegisterClock(ctx.C)
+
+	// Step 3: Increment logical clock to advance time.
+	// This must be done AFTER capturing the clock to maintain happens-before.
+	ctx.IncrementClock()
+}
+
+// OnFinalizerRun handles the runtime invoking a finalizer for obj.
+//
+// This establishes a happens-before edge from the SetFinalizer call site to
+// the finalizer's first access of obj, on whatever goroutine the runtime
+// chose to run the finalizer on.
+//
+// Algorithm:
+//  1. Get object's SyncVar from sync shadow memory
+//  2. If object has a finalizer register clock: ctx.JoinClock(syncVar.registerClock)
+//  3. ctx.IncrementClock()
+//
+// This implements: Ct := Ct ⊔ Fm (finalizer goroutine's clock joins the
+// registration clock).
+//
+// Parameters:
+//   - obj: Address of the object whose finalizer is running
+//   - ctx: Finalizer goroutine's RaceContext
+//
+// Thread Safety: Safe for concurrent calls from multiple goroutines.
+//
+// Performance Target: <500ns (VectorClock join overhead acceptable).
+//
+// Example:
+//
+//	// Mutator
+//	obj.data = 42
+//	runtime.SetFinalizer(obj, finalizerFunc)  // OnFinalizerSet captures clock
+//
+//	// Runtime, once obj is unreachable, on its own goroutine
+//	finalizerFunc(obj)  // Compiler/runtime inserts: racefinalizerrun(obj)
+//	// OnFinalizerRun merges the registration clock: reading obj.data is safe
+//
+//go:nosplit
+func (d *Detector) OnFinalizerRun(obj uintptr, ctx *goroutine.RaceContext) {
+	// Step 1: Get or create SyncVar for this object's address.
+	syncVar := d.syncShadow.GetOrCreate(obj)
+
+	// Step 2: If a finalizer was registered, join its registration clock
+	// with the finalizer goroutine's clock.
+	registerClock := syncVar.GetFinalizerRegisterClock()
+	if registerClock != nil {
+		ctx.JoinClock(registerClock)
+	}
+
+	// Step 3: Increment logical clock to advance time.
+	ctx.IncrementClock()
+}
@@ -0,0 +1,88 @@
+package detector
+
+import "testing"
+
+// TestGenerateFuzzOps_DeterministicForSameSeed verifies GenerateFuzzOps is
+// a pure function of its arguments (synth-3582) - required for go test
+// -fuzz to be able to replay and shrink a failing case.
+func TestGenerateFuzzOps_DeterministicForSameSeed(t *testing.T) {
+	a := GenerateFuzzOps(42, 100, 4, 8)
+	b := GenerateFuzzOps(42, 100, 4, 8)
+
+	if len(a) != len(b) {
+		t.Fatalf("len(a) = %d, len(b) = %d, want equal", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("ops[%d] = %+v, want %+v (same seed must reproduce the same sequence)", i, b[i], a[i])
+		}
+	}
+}
+
+// TestCompareToOracle_RaceOnUnsynchronizedWrites verifies both the
+// Detector and fuzzOracle agree a real, unsynchronized write-write
+// conflict is a race.
+func TestCompareToOracle_RaceOnUnsynchronizedWrites(t *testing.T) {
+	ops := []FuzzOp{
+		{Kind: FuzzOpWrite, Thread: 0, Addr: 0x100},
+		{Kind: FuzzOpWrite, Thread: 1, Addr: 0x100},
+	}
+
+	detectorRaces, oracleRaces, diverged := CompareToOracle(ops)
+	if diverged {
+		t.Fatalf("diverged: detector=%d oracle=%d, want equal", detectorRaces, oracleRaces)
+	}
+	if detectorRaces == 0 {
+		t.Error("detectorRaces = 0, want at least 1 (unsynchronized concurrent writes)")
+	}
+}
+
+// TestCompareToOracle_NoRaceWhenLockProtected verifies both sides agree
+// that lock-protected accesses never race.
+func TestCompareToOracle_NoRaceWhenLockProtected(t *testing.T) {
+	const mu = uintptr(0x200)
+	const x = uintptr(0x300)
+	ops := []FuzzOp{
+		{Kind: FuzzOpAcquire, Thread: 0, Addr: mu},
+		{Kind: FuzzOpWrite, Thread: 0, Addr: x},
+		{Kind: FuzzOpRelease, Thread: 0, Addr: mu},
+		{Kind: FuzzOpAcquire, Thread: 1, Addr: mu},
+		{Kind: FuzzOpWrite, Thread: 1, Addr: x},
+		{Kind: FuzzOpRelease, Thread: 1, Addr: mu},
+	}
+
+	detectorRaces, oracleRaces, diverged := CompareToOracle(ops)
+	if diverged {
+		t.Fatalf("diverged: detector=%d oracle=%d, want equal", detectorRaces, oracleRaces)
+	}
+	if detectorRaces != 0 {
+		t.Errorf("detectorRaces = %d, want 0 (all accesses are lock-protected)", detectorRaces)
+	}
+}
+
+// FuzzDetectorAgainstOracle is the synth-3582 differential-fuzzing entry
+// point: for each generated seed, it builds a random op sequence and
+// checks the production Detector's race count against fuzzOracle's. Any
+// divergence go test -fuzz finds is a soundness bug in one of the epoch
+// fast paths (see this file's package-level comment in fuzzharness.go).
+func FuzzDetectorAgainstOracle(f *testing.F) {
+	f.Add(int64(1), 5, 1, 2)
+	f.Add(int64(2), 10, 2, 1)
+	f.Add(int64(50), 30, 2, 2)
+
+	f.Fuzz(func(t *testing.T, seed int64, numOps, numThreads, numAddrs int) {
+		if numOps < 0 || numOps > 2000 {
+			t.Skip("op count out of the range this harness is meant to explore")
+		}
+		if numThreads < 1 || numThreads > 16 || numAddrs < 1 || numAddrs > 16 {
+			t.Skip("thread/address count out of the range this harness is meant to explore")
+		}
+
+		ops := GenerateFuzzOps(seed, numOps, numThreads, numAddrs)
+		detectorRaces, oracleRaces, diverged := CompareToOracle(ops)
+		if diverged {
+			t.Fatalf("detector and oracle disagree: detector=%d oracle=%d (seed=%d numOps=%d numThreads=%d numAddrs=%d)",
+				detectorRaces, oracleRaces, seed, numOps, numThreads, numAddrs)
+		}
+	})
+}
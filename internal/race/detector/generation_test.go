@@ -0,0 +1,66 @@
+package detector
+
+import (
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// TestGeneration_StartsAtZero verifies a freshly created detector reports
+// generation 0 (never reset).
+func TestGeneration_StartsAtZero(t *testing.T) {
+	d := NewDetector()
+	if got := d.Generation(); got != 0 {
+		t.Errorf("Generation() = %d, want 0", got)
+	}
+}
+
+// TestGeneration_IncrementsOnReset verifies each Reset call advances the
+// generation counter by exactly one (synth-3577).
+func TestGeneration_IncrementsOnReset(t *testing.T) {
+	d := NewDetector()
+
+	d.Reset()
+	if got := d.Generation(); got != 1 {
+		t.Errorf("Generation() after first Reset = %d, want 1", got)
+	}
+
+	d.Reset()
+	if got := d.Generation(); got != 2 {
+		t.Errorf("Generation() after second Reset = %d, want 2", got)
+	}
+}
+
+// TestReset_InvalidatesStaleShadowCellCache verifies that a goroutine's
+// per-context shadow cell cache, filled before Reset, is not reused after
+// Reset - it must miss and fetch a fresh cell from the (now-cleared)
+// ShadowMemory instead of continuing to reference the evicted one
+// (synth-3577).
+func TestReset_InvalidatesStaleShadowCellCache(t *testing.T) {
+	d := NewDetector()
+	ctx := goroutine.Alloc(1)
+	addr := uintptr(0x9300)
+
+	d.OnWrite(addr, ctx)
+	staleCell := ctx.CachedShadowCell(addr, d.Generation())
+	if staleCell == nil {
+		t.Fatal("shadow cell not cached before Reset")
+	}
+
+	d.Reset()
+
+	if got := ctx.CachedShadowCell(addr, d.Generation()); got != nil {
+		t.Errorf("CachedShadowCell(addr, currentGen) = %p after Reset, want nil (cache entry belongs to a superseded generation)", got)
+	}
+
+	// A subsequent OnWrite must not resurrect the evicted cell - it should
+	// look addr up fresh in the (now-empty) ShadowMemory instead.
+	d.OnWrite(addr, ctx)
+	freshCell := ctx.CachedShadowCell(addr, d.Generation())
+	if freshCell == nil {
+		t.Fatal("shadow cell not cached after post-Reset OnWrite")
+	}
+	if freshCell == staleCell {
+		t.Error("post-Reset OnWrite reused the pre-Reset shadow cell instead of creating a fresh one")
+	}
+}
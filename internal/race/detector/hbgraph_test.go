@@ -0,0 +1,133 @@
+package detector
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// TestHBGraphRecorder_NilIsNoOp verifies record is safe to call on a nil
+// *HBGraphRecorder, matching Detector.hbGraph's default.
+func TestHBGraphRecorder_NilIsNoOp(t *testing.T) {
+	var r *HBGraphRecorder
+	r.record(HBEventWrite, 0x1000, goroutine.Alloc(0)) // must not panic
+}
+
+// TestHBGraphRecorder_ProgramOrderEdges verifies consecutive events on the
+// same goroutine are linked by a program-order edge.
+func TestHBGraphRecorder_ProgramOrderEdges(t *testing.T) {
+	r := NewHBGraphRecorder()
+	ctx := goroutine.Alloc(1)
+
+	r.record(HBEventWrite, 0x1000, ctx)
+	r.record(HBEventRead, 0x2000, ctx)
+
+	if len(r.nodes) != 2 {
+		t.Fatalf("len(nodes) = %d, want 2", len(r.nodes))
+	}
+	if len(r.edges) != 1 {
+		t.Fatalf("len(edges) = %d, want 1", len(r.edges))
+	}
+	if r.edges[0].from != r.nodes[0].seq || r.edges[0].to != r.nodes[1].seq || r.edges[0].sync {
+		t.Errorf("edges[0] = %+v, want a program-order edge from node 0 to node 1", r.edges[0])
+	}
+}
+
+// TestHBGraphRecorder_SyncEdgeFromReleaseToAcquire verifies a Release on an
+// address is linked by a sync edge to the next Acquire of that same
+// address, even across different goroutines.
+func TestHBGraphRecorder_SyncEdgeFromReleaseToAcquire(t *testing.T) {
+	r := NewHBGraphRecorder()
+	releaser := goroutine.Alloc(1)
+	acquirer := goroutine.Alloc(2)
+
+	const mu = uintptr(0xdead)
+	r.record(HBEventRelease, mu, releaser)
+	r.record(HBEventAcquire, mu, acquirer)
+
+	var syncEdges int
+	for _, e := range r.edges {
+		if e.sync {
+			syncEdges++
+			if e.from != r.nodes[0].seq || e.to != r.nodes[1].seq {
+				t.Errorf("sync edge = %+v, want from node 0 to node 1", e)
+			}
+		}
+	}
+	if syncEdges != 1 {
+		t.Errorf("found %d sync edges, want 1", syncEdges)
+	}
+}
+
+// TestHBGraphRecorder_WriteDOT verifies WriteDOT emits a well-formed
+// digraph containing every recorded node and edge.
+func TestHBGraphRecorder_WriteDOT(t *testing.T) {
+	r := NewHBGraphRecorder()
+	ctx := goroutine.Alloc(1)
+	r.record(HBEventWrite, 0x1000, ctx)
+	r.record(HBEventRead, 0x1000, ctx)
+
+	var buf bytes.Buffer
+	if err := r.WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT() error = %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "digraph happens_before {") {
+		t.Errorf("WriteDOT() output doesn't start with the digraph header: %q", out)
+	}
+	if !strings.Contains(out, "n1 [label=") || !strings.Contains(out, "n2 [label=") {
+		t.Errorf("WriteDOT() output missing expected node declarations: %q", out)
+	}
+	if !strings.Contains(out, "n1 -> n2;") {
+		t.Errorf("WriteDOT() output missing the program-order edge: %q", out)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(out), "}") {
+		t.Errorf("WriteDOT() output doesn't end with the closing brace: %q", out)
+	}
+}
+
+// TestDetector_HBGraphUnsetByDefault verifies a Detector with
+// DetectorOptions.HappensBeforeGraphEnabled left false doesn't allocate an
+// HBGraphRecorder (zero overhead, mirroring the sampler/profiler/symbolizer
+// opt-in pattern), and that WriteHappensBeforeGraph reports the error.
+func TestDetector_HBGraphUnsetByDefault(t *testing.T) {
+	d := NewDetector()
+	if d.hbGraph != nil {
+		t.Error("hbGraph != nil for a Detector created without DetectorOptions.HappensBeforeGraphEnabled")
+	}
+	if err := d.WriteHappensBeforeGraph(&bytes.Buffer{}); err != errHBGraphDisabled {
+		t.Errorf("WriteHappensBeforeGraph() error = %v, want errHBGraphDisabled", err)
+	}
+}
+
+// TestDetector_HBGraphEnabled_CapturesAllInstrumentedOps verifies
+// DetectorOptions.HappensBeforeGraphEnabled causes OnWrite, OnRead,
+// OnAcquire, OnRelease, and OnReleaseMerge to all add graph nodes.
+func TestDetector_HBGraphEnabled_CapturesAllInstrumentedOps(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{HappensBeforeGraphEnabled: true})
+	if d.hbGraph == nil {
+		t.Fatal("hbGraph = nil, want a configured HBGraphRecorder")
+	}
+
+	ctx := goroutine.Alloc(1)
+	d.OnWrite(0x1000, ctx)
+	d.OnRead(0x1000, ctx)
+	d.OnAcquire(0x2000, ctx)
+	d.OnRelease(0x2000, ctx)
+	d.OnReleaseMerge(0x3000, ctx)
+
+	if len(d.hbGraph.nodes) != 5 {
+		t.Fatalf("len(hbGraph.nodes) = %d, want 5", len(d.hbGraph.nodes))
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteHappensBeforeGraph(&buf); err != nil {
+		t.Fatalf("WriteHappensBeforeGraph() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "digraph happens_before") {
+		t.Errorf("WriteHappensBeforeGraph() output missing digraph header: %q", buf.String())
+	}
+}
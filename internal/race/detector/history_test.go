@@ -0,0 +1,143 @@
+package detector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/epoch"
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+	"github.com/kolkov/racedetector/internal/race/shadowmem"
+)
+
+// TestNewDetectorWithOptions_HistorySizeDisabledByDefault verifies that
+// plain NewDetector() (and DetectorOptions{}) leave history tracking off,
+// so OnWrite/OnRead never populate the shadow cell's history ring buffer.
+func TestNewDetectorWithOptions_HistorySizeDisabledByDefault(t *testing.T) {
+	d := NewDetector()
+	ctx := goroutine.Alloc(1)
+	addr := uintptr(0x9000)
+
+	d.OnWrite(addr, ctx)
+	d.OnWrite(addr, ctx)
+
+	vs := d.shadowMemory.Get(addr)
+	if vs == nil {
+		t.Fatal("shadow cell not created")
+	}
+	if got := vs.GetHistory(); got != nil {
+		t.Errorf("GetHistory() = %v, want nil when HistorySize is 0 (default)", got)
+	}
+}
+
+// TestNewDetectorWithOptions_HistorySizeRecordsAccesses verifies that
+// configuring HistorySize causes OnWrite/OnRead to populate the shadow
+// cell's access history.
+func TestNewDetectorWithOptions_HistorySizeRecordsAccesses(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{HistorySize: 4})
+	ctx := goroutine.Alloc(1)
+	addr := uintptr(0x9100)
+
+	d.OnWrite(addr, ctx)
+	d.OnRead(addr, ctx)
+
+	vs := d.shadowMemory.Get(addr)
+	if vs == nil {
+		t.Fatal("shadow cell not created")
+	}
+
+	history := vs.GetHistory()
+	if len(history) != 2 {
+		t.Fatalf("len(GetHistory()) = %d, want 2", len(history))
+	}
+	if !history[0].IsWrite {
+		t.Error("history[0].IsWrite = false, want true (the OnWrite call)")
+	}
+	if history[1].IsWrite {
+		t.Error("history[1].IsWrite = true, want false (the OnRead call)")
+	}
+}
+
+// TestOnWrite_PopulatesPerGoroutineShadowCellCache verifies that OnWrite
+// caches the shadow cell on the calling goroutine's RaceContext (v0.4.0),
+// so a repeated access to the same address hits the context's own cache
+// instead of ShadowMemory.
+func TestOnWrite_PopulatesPerGoroutineShadowCellCache(t *testing.T) {
+	d := NewDetector()
+	ctx := goroutine.Alloc(1)
+	addr := uintptr(0x9200)
+
+	d.OnWrite(addr, ctx)
+
+	want := d.shadowMemory.Get(addr)
+	if want == nil {
+		t.Fatal("shadow cell not created")
+	}
+	if got := ctx.CachedShadowCell(addr, d.Generation()); got != want {
+		t.Errorf("ctx.CachedShadowCell(addr) = %p, want %p (the cell ShadowMemory created)", got, want)
+	}
+}
+
+// TestNewRaceReportWithStacks_PopulatesHistory verifies that when the
+// shadow cell carries recorded history, NewRaceReportWithStacks surfaces
+// entries other than Current/Previous in RaceReport.History.
+func TestNewRaceReportWithStacks_PopulatesHistory(t *testing.T) {
+	vs := shadowmem.NewVarState()
+
+	prevEpoch := epoch.NewEpoch(1, 10)
+	currEpoch := epoch.NewEpoch(2, 20)
+	thirdEpoch := epoch.NewEpoch(3, 15)
+
+	vs.RecordHistory(shadowmem.AccessRecord{Epoch: prevEpoch, IsWrite: true}, 4)
+	vs.RecordHistory(shadowmem.AccessRecord{Epoch: thirdEpoch, IsWrite: false}, 4)
+
+	report := NewRaceReportWithStacks(RaceTypeWriteWrite, 0x1234, vs, prevEpoch, currEpoch)
+
+	if len(report.History) != 1 {
+		t.Fatalf("len(report.History) = %d, want 1 (thirdEpoch only; prevEpoch overlaps Previous)", len(report.History))
+	}
+	if report.History[0].GoroutineID != 3 {
+		t.Errorf("report.History[0].GoroutineID = %d, want 3", report.History[0].GoroutineID)
+	}
+	if report.History[0].Type != AccessRead {
+		t.Errorf("report.History[0].Type = %v, want AccessRead", report.History[0].Type)
+	}
+}
+
+// TestNewRaceReportWithStacks_NoHistoryWhenDisabled verifies that when the
+// shadow cell has no recorded history (the default), RaceReport.History is
+// empty and Format() does not print the "Other recent accesses" section.
+func TestNewRaceReportWithStacks_NoHistoryWhenDisabled(t *testing.T) {
+	vs := shadowmem.NewVarState()
+	prevEpoch := epoch.NewEpoch(1, 10)
+	currEpoch := epoch.NewEpoch(2, 20)
+
+	report := NewRaceReportWithStacks(RaceTypeWriteWrite, 0x1234, vs, prevEpoch, currEpoch)
+	if len(report.History) != 0 {
+		t.Fatalf("len(report.History) = %d, want 0", len(report.History))
+	}
+
+	formatted := report.String()
+	if strings.Contains(formatted, "Other recent accesses") {
+		t.Error("Format() printed history section with no recorded history")
+	}
+}
+
+// TestRaceReport_Format_WithHistory verifies the "Other recent accesses"
+// section is rendered when History is populated.
+func TestRaceReport_Format_WithHistory(t *testing.T) {
+	report := &RaceReport{
+		Current:  AccessInfo{Type: AccessWrite, GoroutineID: 2, Epoch: epoch.NewEpoch(2, 20)},
+		Previous: AccessInfo{Type: AccessWrite, GoroutineID: 1, Epoch: epoch.NewEpoch(1, 10)},
+		History: []AccessInfo{
+			{Type: AccessRead, GoroutineID: 3, Epoch: epoch.NewEpoch(3, 15)},
+		},
+	}
+
+	formatted := report.String()
+	if !strings.Contains(formatted, "Other recent accesses to this address:") {
+		t.Error("Format() did not print the history section")
+	}
+	if !strings.Contains(formatted, "goroutine 3") {
+		t.Error("Format() did not mention the historical goroutine ID")
+	}
+}
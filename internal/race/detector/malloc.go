@@ -0,0 +1,57 @@
+package detector
+
+import "github.com/kolkov/racedetector/internal/race/goroutine"
+
+// === Heap Allocation Support (synth-3581) ===
+//
+// Go's allocator routinely hands the same physical memory to a series of
+// unrelated objects over a program's lifetime: a freed slice's backing
+// array, the previous occupant of an address a &T{} composite literal now
+// lives at, a sync.Pool's Get() result. ShadowMemory has no way to know an
+// allocation happened - it only sees addresses come and go through
+// OnRead/OnWrite - so without an explicit hook, a freshly allocated
+// object's first access can be compared against a shadow cell an entirely
+// different, already-dead object left behind at the same address. That
+// stale epoch can produce a false race report (the new object "races" with
+// something it never shared memory with) or a false absence of one (the
+// stale epoch happens to make the new access look happens-before something
+// it never synchronized with). OnMalloc closes that gap the same way
+// Detector.ClearGoroutineStack (synth-3580) does for reused goroutine
+// stacks: clear the range before anything can observe the leftover state.
+
+// OnMalloc clears shadow cells covering [addr, addr+size), the address
+// range of a just-completed allocation, and records the allocation itself
+// - its size, its caller's stack, and the allocating goroutine - so a
+// later race report on an address inside that range can print where the
+// object came from (synth-3632, see allocsite.go).
+//
+// Call this immediately after make/new/&T{} returns, before the new
+// object's address is visible to any other goroutine, so its first access
+// is always treated as genuinely fresh memory rather than compared against
+// whatever previously lived there - see the package-level rationale above.
+//
+// A zero size is a no-op: it clears no shadow cells (same as before
+// synth-3632) and, since a zero-size allocation covers no addresses a race
+// could ever land inside, records nothing either.
+//
+// Thread Safety: Safe for concurrent calls, same as
+// ShadowMemory.ClearRange - the caller must still ensure the allocation
+// itself isn't observable by another goroutine until OnMalloc returns,
+// exactly as it must for any other newly allocated, not-yet-published
+// object.
+//
+// Performance: O(live shadow cells) in the worst case, same as
+// ShadowMemory.ClearRange, plus one stack capture and one allocIndex
+// insert - acceptable per-allocation, but not a candidate for a hot loop
+// of tiny allocations.
+func (d *Detector) OnMalloc(addr, size uintptr, ctx *goroutine.RaceContext) {
+	if size == 0 {
+		return
+	}
+	d.shadowMemory.ClearRange(addr, addr+size)
+
+	// Skip 3 frames: captureStackTrace, OnMalloc, racemalloc - landing on
+	// racemalloc's caller, the actual allocation site.
+	pcs := captureStackTrace(3)
+	d.allocSites.record(addr, size, pcs, uint32(ctx.TID))
+}
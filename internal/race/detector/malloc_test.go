@@ -0,0 +1,70 @@
+package detector
+
+import (
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// TestOnMalloc_ClearsCellsInRange verifies a stale shadow cell within the
+// allocated range is removed, while one outside it survives (synth-3581).
+func TestOnMalloc_ClearsCellsInRange(t *testing.T) {
+	d := NewDetector()
+
+	inRange := uintptr(0x5000)
+	outOfRange := uintptr(0x6000)
+	d.shadowMemory.GetOrCreate(inRange)
+	d.shadowMemory.GetOrCreate(outOfRange)
+
+	d.OnMalloc(0x5000, 8, goroutine.Alloc(1))
+
+	if d.shadowMemory.Get(inRange) != nil {
+		t.Error("shadow cell for the allocated address survived OnMalloc")
+	}
+	if d.shadowMemory.Get(outOfRange) == nil {
+		t.Error("shadow cell for an unrelated address was removed by OnMalloc")
+	}
+}
+
+// TestOnMalloc_ZeroSizeIsNoOp verifies a zero-size allocation clears
+// nothing (synth-3581).
+func TestOnMalloc_ZeroSizeIsNoOp(t *testing.T) {
+	d := NewDetector()
+	addr := uintptr(0x7000)
+	d.shadowMemory.GetOrCreate(addr)
+
+	d.OnMalloc(addr, 0, goroutine.Alloc(1))
+
+	if d.shadowMemory.Get(addr) == nil {
+		t.Error("shadow cell removed by a zero-size OnMalloc call, want preserved")
+	}
+}
+
+// TestOnWrite_StaleEpochClearedByReallocation verifies the intended
+// end-to-end effect: a stale shadow cell left by a freed object doesn't
+// survive OnMalloc to confuse the first write to a new object reusing the
+// same address (synth-3581).
+func TestOnWrite_StaleEpochClearedByReallocation(t *testing.T) {
+	d := NewDetector()
+	addr := uintptr(0x8008)
+
+	freed := goroutine.Alloc(1)
+	d.OnWrite(addr, freed)
+	if d.shadowMemory.Get(addr) == nil {
+		t.Fatal("shadow cell not created by the freed object's write")
+	}
+
+	// The allocator hands addr's memory to a brand new object.
+	d.OnMalloc(addr, 8, goroutine.Alloc(2))
+
+	if d.shadowMemory.Get(addr) != nil {
+		t.Fatal("shadow cell survived OnMalloc, test setup invalid")
+	}
+
+	fresh := goroutine.Alloc(2)
+	d.OnWrite(addr, fresh)
+
+	if d.RacesDetected() != 0 {
+		t.Errorf("RacesDetected() = %d, want 0 (fresh object's first access to a reallocated address)", d.RacesDetected())
+	}
+}
@@ -0,0 +1,49 @@
+package detector
+
+import (
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// AnalyzeTrace replays a previously recorded access trace (see
+// ScheduleRecorder and LoadSchedule) through a fresh Detector sequentially,
+// in the exact Seq order it was recorded in - the offline analyzer half of
+// `racedetector analyze` (v0.5.0).
+//
+// FastTrack's vector clock algorithm only cares about the order accesses
+// are observed in, not when they happened in wall-clock time, so replaying
+// a trace sequentially through the same OnRead/OnWrite methods a live
+// detector uses is sufficient - there's no separate offline detection
+// algorithm to write, only a different source of events. This also means a
+// trace can be analyzed with DetectorOptions too expensive for a live
+// program's hot path (a large HistorySize, for example), since there's no
+// production overhead to protect once the trace has already been captured.
+//
+// Each distinct ScheduleEvent.Goroutine gets its own *goroutine.RaceContext,
+// allocated on first use and reused for the rest of the trace, so replayed
+// accesses accumulate per-goroutine vector clock state exactly like the
+// original run did.
+func AnalyzeTrace(events []ScheduleEvent, opts DetectorOptions) *Detector {
+	d := NewDetectorWithOptions(opts)
+	contexts := make(map[uint16]*goroutine.RaceContext)
+
+	for _, event := range events {
+		ctx, ok := contexts[event.Goroutine]
+		if !ok {
+			ctx = goroutine.Alloc(event.Goroutine)
+			contexts[event.Goroutine] = ctx
+		}
+
+		switch event.Kind {
+		case ScheduleEventWrite:
+			d.OnWrite(event.Addr, ctx)
+		case ScheduleEventRead:
+			d.OnRead(event.Addr, ctx)
+		}
+	}
+
+	// Race reporting happens on a separate goroutine (synth-3587); a caller
+	// of this offline, batch-oriented API expects the returned Detector to
+	// already reflect every race in the trace, not catch up moments later.
+	d.WaitForPendingReports()
+	return d
+}
@@ -0,0 +1,81 @@
+package detector
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestNewProfiler_UniqueNames verifies multiple Profilers can coexist in the
+// same process without panicking on a duplicate pprof profile name.
+func TestNewProfiler_UniqueNames(t *testing.T) {
+	p1 := NewProfiler()
+	p2 := NewProfiler()
+
+	if p1 == p2 {
+		t.Fatal("NewProfiler() returned the same instance twice")
+	}
+}
+
+// TestProfiler_RecordSample_WriteToProducesOutput verifies a recorded sample
+// shows up in the pprof-formatted dump.
+func TestProfiler_RecordSample_WriteToProducesOutput(t *testing.T) {
+	p := NewProfiler()
+	p.RecordSample("write", 42*time.Nanosecond, 0)
+
+	var buf bytes.Buffer
+	if err := p.Dump(&buf); err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+
+	if buf.Len() == 0 {
+		t.Error("Dump() produced an empty profile after RecordSample")
+	}
+}
+
+// TestProfiler_RecordSample_DuplicateDurationsDontPanic verifies recording
+// many samples of the same kind and duration from the same call site
+// doesn't panic pprof.Profile.Add's duplicate-value check - see
+// overheadSample.seq.
+func TestProfiler_RecordSample_DuplicateDurationsDontPanic(t *testing.T) {
+	p := NewProfiler()
+	for i := 0; i < 1000; i++ {
+		p.RecordSample("write", 42*time.Nanosecond, 0)
+	}
+
+	var buf bytes.Buffer
+	if err := p.Dump(&buf); err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+}
+
+// TestDetector_WriteOverheadProfile_DisabledByDefault verifies detectors
+// created without ProfilingEnabled refuse to dump a profile.
+func TestDetector_WriteOverheadProfile_DisabledByDefault(t *testing.T) {
+	d := NewDetector()
+
+	var buf bytes.Buffer
+	if err := d.WriteOverheadProfile(&buf); err == nil {
+		t.Error("WriteOverheadProfile() error = nil, want an error when profiling is disabled")
+	}
+}
+
+// TestDetector_WriteOverheadProfile_AttributesOnWriteAndOnRead verifies that
+// enabling profiling causes OnWrite/OnRead calls to show up in the dumped
+// profile.
+func TestDetector_WriteOverheadProfile_AttributesOnWriteAndOnRead(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{ProfilingEnabled: true})
+	ctx := createTestContext(1)
+
+	d.OnWrite(0x1000, ctx)
+	d.OnRead(0x1000, ctx)
+
+	var buf bytes.Buffer
+	if err := d.WriteOverheadProfile(&buf); err != nil {
+		t.Fatalf("WriteOverheadProfile() error = %v", err)
+	}
+
+	if buf.Len() == 0 {
+		t.Error("WriteOverheadProfile() produced an empty profile after OnWrite/OnRead")
+	}
+}
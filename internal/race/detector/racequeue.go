@@ -0,0 +1,307 @@
+package detector
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"github.com/kolkov/racedetector/internal/race/epoch"
+)
+
+// raceQueueCapacity bounds the number of detected-but-not-yet-reported
+// races raceQueue can hold at once (synth-3587). Must be a power of two -
+// push/pop use a bitmask instead of a modulo to stay nosplit-friendly.
+//
+// Races are rare (a healthy program detects zero), so this only needs to
+// absorb a reporting goroutine's worst-case scheduling delay, not sustained
+// throughput. 256 is generous slack for that; a queue this small overflowing
+// means hundreds of distinct races are in flight at once, at which point
+// dropping a few enqueue notices (see raceQueue.push) costs nothing real -
+// the ones that already got through are more than enough to tell the
+// caller their program is broken.
+const raceQueueCapacity = 256
+
+// raceRecord is everything OnWrite/OnRead's nosplit hot path needs to save
+// about a detected race so a separate reporter goroutine can turn it into a
+// full RaceReport later (synth-3587). Every field is a fixed-size value -
+// no slice, no map - so raceQueue.push can copy one into a pre-allocated
+// slot without an allocation.
+//
+// pcs/pcsLen hold the current access's captured call stack. This can't be
+// deferred to the reporter goroutine the way the rest of report generation
+// is: by the time that goroutine runs, the racing goroutine may have
+// returned from OnWrite/OnRead and unwound the very frames the report needs
+// to show, so the capture has to happen synchronously, on the racing
+// goroutine, before enqueueRace returns. See enqueueRace.
+type raceRecord struct {
+	raceType  string
+	addr      uintptr
+	vs        interface{}
+	prevEpoch epoch.Epoch
+	currEpoch epoch.Epoch
+	pcs       [maxStackDepth]uintptr
+	pcsLen    int
+}
+
+// raceQueueSlot is one cell of raceQueue's backing array: a record plus the
+// sequence number Dmitry Vyukov's bounded MPMC queue algorithm (below) uses
+// to coordinate producers and the consumer without a lock.
+type raceQueueSlot struct {
+	sequence atomic.Uint64
+	record   raceRecord
+}
+
+// raceQueue is a fixed-capacity, lock-free, multi-producer/single-consumer
+// ring buffer of raceRecord (synth-3587), sized to let OnWrite/OnRead's
+// nosplit hot path hand a detected race off to a reporter goroutine without
+// taking a lock or allocating.
+//
+// This is Vyukov's bounded MPMC queue algorithm (only the multi-producer
+// side is exercised here - one reporter goroutine drains it - but the
+// dequeue side is written generically rather than assuming that, since
+// nothing about push depends on there being exactly one consumer). Each
+// slot's sequence number tracks which "lap" around the ring it's ready for,
+// so a producer that loses a race to claim a slot to another producer
+// simply retries instead of taking a lock.
+//
+// Thread Safety: push is safe for concurrent calls from any number of
+// goroutines. pop is written to be safe for concurrent callers too, though
+// the detector only ever runs one.
+type raceQueue struct {
+	slots [raceQueueCapacity]raceQueueSlot
+	mask  uint64
+
+	enqueuePos atomic.Uint64
+	dequeuePos atomic.Uint64
+
+	// enqueued counts every record that made it into the queue, successful
+	// pushes only (synth-3587). Compared against Detector.reportedCount by
+	// WaitForPendingReports to tell "reporting in flight" from "reporting
+	// caught up", without either side taking a lock.
+	enqueued atomic.Uint64
+
+	// dropped counts races whose enqueue lost to a full queue under the
+	// drop policy (synth-3587/synth-3588). The race itself was still
+	// detected and counted in Detector.racesDetected by the time push is
+	// called - this only tracks how many detections never made it to a
+	// RaceReport because the reporter goroutine couldn't keep up.
+	dropped atomic.Uint64
+
+	// blockOnFull selects push's behavior when the queue is full
+	// (synth-3588, DetectorOptions.RaceQueueBlockOnFull): spin until a
+	// slot frees up instead of dropping the record. See push.
+	blockOnFull bool
+}
+
+// newRaceQueue returns an empty raceQueue ready for use. blockOnFull sets
+// push's full-queue policy - see raceQueue.blockOnFull.
+func newRaceQueue(blockOnFull bool) *raceQueue {
+	q := &raceQueue{mask: raceQueueCapacity - 1, blockOnFull: blockOnFull}
+	for i := range q.slots {
+		q.slots[i].sequence.Store(uint64(i))
+	}
+	return q
+}
+
+// push enqueues rec. When the queue is full, behavior depends on
+// blockOnFull (synth-3588): by default push returns false without
+// blocking (see raceQueue.dropped); with blockOnFull set, it instead
+// spins via runtime.Gosched until the reporter goroutine frees a slot, and
+// always returns true. No allocation, no lock either way, and even the
+// blocking policy never takes a lock waiting for the consumer - safe to
+// call from code that must stay off the hot-path allocator, even though
+// this function itself isn't //go:nosplit (its raceRecord-sized stack
+// frame would blow OnWrite/OnRead's nosplit chain budget; see
+// enqueueRace, which is the actual nosplit boundary).
+func (q *raceQueue) push(rec raceRecord) bool {
+	pos := q.enqueuePos.Load()
+	for {
+		slot := &q.slots[pos&q.mask]
+		seq := slot.sequence.Load()
+		diff := int64(seq) - int64(pos)
+		if diff == 0 {
+			// Slot is free for this lap - try to claim it.
+			if q.enqueuePos.CompareAndSwap(pos, pos+1) {
+				slot.record = rec
+				// Bump enqueued before publishing the slot via sequence.Store
+				// below: WaitForPendingReports relies on this count already
+				// reflecting a record by the time pop can possibly observe
+				// it, or a fast reporter goroutine could process the record
+				// and update reportedCount before this count catches up,
+				// making the two look falsely equal.
+				q.enqueued.Add(1)
+				slot.sequence.Store(pos + 1)
+				return true
+			}
+			pos = q.enqueuePos.Load()
+			continue
+		}
+		if diff < 0 {
+			// The consumer hasn't caught up to even the previous lap:
+			// the queue is full.
+			if q.blockOnFull {
+				runtime.Gosched()
+				continue
+			}
+			q.dropped.Add(1)
+			return false
+		}
+		// Another producer already claimed this slot; re-read and retry
+		// against whatever the enqueue position is now.
+		pos = q.enqueuePos.Load()
+	}
+}
+
+// pop dequeues the oldest record, returning (rec, false) if the queue is
+// currently empty.
+func (q *raceQueue) pop() (raceRecord, bool) {
+	pos := q.dequeuePos.Load()
+	for {
+		slot := &q.slots[pos&q.mask]
+		seq := slot.sequence.Load()
+		diff := int64(seq) - int64(pos+1)
+		if diff == 0 {
+			if q.dequeuePos.CompareAndSwap(pos, pos+1) {
+				rec := slot.record
+				slot.sequence.Store(pos + q.mask + 1)
+				return rec, true
+			}
+			pos = q.dequeuePos.Load()
+			continue
+		}
+		if diff < 0 {
+			return raceRecord{}, false
+		}
+		pos = q.dequeuePos.Load()
+	}
+}
+
+// enqueueRace is OnWrite/OnRead's replacement for calling d.reportRaceV2
+// directly (synth-3587): it captures just enough state - including the
+// current access's call stack, which must happen now, on the racing
+// goroutine, while those frames still exist - into a raceRecord and pushes
+// it onto d.raceQueue, then makes sure the reporter goroutine is running.
+// The actual report construction (which allocates: RaceReport, formatted
+// output, the reportedRaces dedup entry) happens later, off this call's
+// stack, in runReporter.
+//
+// If d.raceQueue is full, the race is silently dropped from the report
+// stream (see raceQueue.dropped) - detection already happened before this
+// was reached; only the human-readable report is at risk, and only under a
+// flood of distinct simultaneous races no single report would have helped
+// diagnose anyway.
+//
+// Deliberately not //go:nosplit, unlike OnWrite/OnRead: a raceRecord is
+// large enough (its embedded [32]uintptr stack buffer dominates) that
+// forcing this whole call into OnWrite/OnRead's nosplit stack-frame budget
+// would blow the compiler's 792-byte nosplit chain limit. That's fine -
+// nosplit only needs to hold for OnWrite/OnRead themselves; a normal
+// function they call (this one) is free to grow the stack the ordinary
+// way. Detected races are rare enough that paying for a stack-growth check
+// on this path is a non-issue - the property this function actually needs
+// to preserve is "don't allocate on the heap", which push and the fixed
+// pcs array below both hold to regardless of the nosplit annotation.
+func (d *Detector) enqueueRace(raceType string, addr uintptr, vs interface{}, prevEpoch, currEpoch epoch.Epoch) {
+	var rec raceRecord
+	rec.raceType = raceType
+	rec.addr = addr
+	rec.vs = vs
+	rec.prevEpoch = prevEpoch
+	rec.currEpoch = currEpoch
+
+	// Skip 2 frames: captureStackTraceInto, enqueueRace - the same
+	// "first frame is the detector's own caller" depth reportRaceV2's
+	// direct capture has always used (see reportRaceV2).
+	rec.pcsLen = captureStackTraceInto(2, rec.pcs[:])
+
+	d.raceQueue.push(rec)
+	d.startReporter()
+}
+
+// startReporter lazily starts d's reporter goroutine on the first detected
+// race, so a program that never races never pays for an idle goroutine
+// (synth-3587). Safe to call repeatedly and concurrently; only the first
+// call does anything, via d.reporterOnce.
+func (d *Detector) startReporter() {
+	d.reporterOnce.Do(func() {
+		d.reporterDone = make(chan struct{})
+		d.reporterStopped = make(chan struct{})
+		go d.runReporter()
+	})
+}
+
+// runReporter drains d.raceQueue and turns each raceRecord into a full,
+// formatted RaceReport via reportRaceV2WithStack, off the racing
+// goroutine's nosplit hot path (synth-3587). Runs until d.reporterDone is
+// closed by Close(), then drains whatever is left in the queue one last
+// time before exiting, so a race detected just before shutdown still gets
+// reported.
+func (d *Detector) runReporter() {
+	defer close(d.reporterStopped)
+
+	for {
+		drained := d.drainRaceQueue()
+		select {
+		case <-d.reporterDone:
+			d.drainRaceQueue()
+			return
+		default:
+		}
+		if !drained {
+			// Nothing to do right now - yield instead of busy-spinning.
+			// Races are rare enough that a dedicated wakeup channel would
+			// be pure plumbing for no measurable benefit; Gosched keeps
+			// this goroutine cheap while idle, which is the common case.
+			runtime.Gosched()
+		}
+	}
+}
+
+// drainRaceQueue pops and reports every record currently in d.raceQueue,
+// returning true if it processed at least one.
+func (d *Detector) drainRaceQueue() bool {
+	drained := false
+	for {
+		rec, ok := d.raceQueue.pop()
+		if !ok {
+			return drained
+		}
+		drained = true
+		d.reportRaceV2WithStack(rec.raceType, rec.addr, rec.vs, rec.prevEpoch, rec.currEpoch, rec.pcs[:rec.pcsLen])
+		d.reportedCount.Add(1)
+	}
+}
+
+// WaitForPendingReports blocks until every race enqueued so far has been
+// fully turned into a RaceReport by the reporter goroutine (synth-3587):
+// deduplicated, counted in RacesDetected, and - if new - printed.
+//
+// OnWrite/OnRead's detection itself is synchronous, but reporting is not
+// anymore (see enqueueRace) - a caller that checks RacesDetected, captured
+// stderr output, or sampler hot-site state right after a call that might
+// have raced needs a synchronization point instead of assuming reporting
+// already happened. This is that point. A program that never inspects
+// those never needs to call it.
+//
+// Thread Safety: Safe for concurrent calls. Only waits for races enqueued
+// before this call returns to a caller that itself only calls
+// OnWrite/OnRead sequentially with its own calls to WaitForPendingReports -
+// concurrent OnWrite/OnRead calls from other goroutines may still be
+// enqueuing after this call observes "caught up".
+func (d *Detector) WaitForPendingReports() {
+	for d.raceQueue.enqueued.Load() != d.reportedCount.Load() {
+		runtime.Gosched()
+	}
+}
+
+// stopReporter signals the reporter goroutine to drain and exit, and waits
+// for it to do so. A no-op if the reporter was never started (the detector
+// never saw a race). Called from Close(), whose contract already forbids
+// concurrent OnWrite/OnRead calls that could race with this nil check by
+// starting the reporter for the first time.
+func (d *Detector) stopReporter() {
+	if d.reporterDone == nil {
+		return
+	}
+	close(d.reporterDone)
+	<-d.reporterStopped
+}
@@ -0,0 +1,54 @@
+package detector
+
+import "testing"
+
+// TestRaceQueue_DropsOnFullByDefault verifies push's default backpressure
+// policy (synth-3588): once the ring is full, further pushes return false
+// and count against dropped instead of blocking the caller.
+func TestRaceQueue_DropsOnFullByDefault(t *testing.T) {
+	q := newRaceQueue(false)
+
+	for i := 0; i < raceQueueCapacity; i++ {
+		if !q.push(raceRecord{addr: uintptr(i)}) {
+			t.Fatalf("push(%d) = false, want true (queue not yet full)", i)
+		}
+	}
+
+	if q.push(raceRecord{addr: 0xFFFF}) {
+		t.Fatal("push() on a full queue = true, want false under the default drop policy")
+	}
+	if got := q.dropped.Load(); got != 1 {
+		t.Errorf("dropped = %d, want 1", got)
+	}
+}
+
+// TestRaceQueue_BlockOnFullWaitsForSpace verifies the opt-in backpressure
+// policy (synth-3588, DetectorOptions.RaceQueueBlockOnFull) never drops a
+// record: a push against a full queue blocks until pop frees a slot,
+// rather than returning false.
+func TestRaceQueue_BlockOnFullWaitsForSpace(t *testing.T) {
+	q := newRaceQueue(true)
+
+	for i := 0; i < raceQueueCapacity; i++ {
+		if !q.push(raceRecord{addr: uintptr(i)}) {
+			t.Fatalf("push(%d) = false, want true (queue not yet full)", i)
+		}
+	}
+
+	done := make(chan bool, 1)
+	go func() {
+		done <- q.push(raceRecord{addr: 0xFFFF})
+	}()
+
+	// Free exactly one slot; the blocked push above should then succeed.
+	if _, ok := q.pop(); !ok {
+		t.Fatal("pop() = false on a full queue, want true")
+	}
+
+	if ok := <-done; !ok {
+		t.Error("push() on a full queue under blockOnFull = false, want true")
+	}
+	if got := q.dropped.Load(); got != 0 {
+		t.Errorf("dropped = %d, want 0 under the block policy", got)
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/kolkov/racedetector/internal/race/epoch"
+	"github.com/kolkov/racedetector/internal/race/shadowmem"
 	"github.com/kolkov/racedetector/internal/race/stackdepot"
 )
 
@@ -75,6 +78,33 @@ type AccessInfo struct {
 	// Captured at the time of the access using runtime.Callers().
 	// Phase 5 Task 5.2: Added for stack trace support.
 	StackTrace []uintptr
+
+	// TestName is the name of the test currently registered against
+	// GoroutineID at the time this report was formatted, e.g.
+	// "TestFoo/subtest" (synth-3600). Best-effort, not a point-in-time
+	// capture: races are reported asynchronously (see drainRaceQueue), so
+	// for the Previous access in particular, this is empty whenever that
+	// access's own test has already finished and cleared its registration
+	// by the time the conflicting access is discovered - not just when the
+	// race happened outside of `racedetector test` altogether. The Current
+	// access's name is reliable, since it's looked up while that access's
+	// goroutine (and therefore its test, if any) is still running.
+	TestName string
+
+	// SymbolName is the human-readable name of the symbol at Addr - a
+	// struct field's "Type.Field" (synth-3630) or a package-level
+	// variable's "pkg.VarName" (synth-3631) - if the instrumenter could
+	// resolve one and the write went through OnWriteSym rather than plain
+	// OnWrite. Empty for every access that isn't a symbolized write on such
+	// an address - the large majority today, since only writes are
+	// instrumented this way (see symbols.go).
+	SymbolName string
+
+	// SymbolKind labels what SymbolName names - "field" or "global" - so a
+	// report can print "race on field Config.Timeout" or "race on global
+	// main.counter" with the right word (synth-3631). Meaningless when
+	// SymbolName is empty.
+	SymbolKind string
 }
 
 // RaceReport represents a detected data race between two accesses.
@@ -93,45 +123,66 @@ type RaceReport struct {
 	Previous AccessInfo
 
 	// DeduplicationKey uniquely identifies this race location.
-	// Format: "{type}:{addr}:{gid1}:{gid2}" where gid1 < gid2.
+	// Format: "{type}:{hashA}:{hashB}" where hashA <= hashB are stackdepot
+	// hashes of the two racing accesses' stacks (v0.5.0; originally keyed
+	// on address+goroutine-IDs in Phase 5 Task 5.3 - see
+	// generateDeduplicationKey for why that was replaced).
 	// This is used to prevent duplicate reports for the same race.
-	// Added in Phase 5 Task 5.3.
 	DeduplicationKey string
+
+	// History holds other recent accesses to the same address, oldest first,
+	// excluding Current and Previous. Empty unless the detector was created
+	// with DetectorOptions.HistorySize > 0 (v0.4.0 "history_size" support).
+	//
+	// This helps when the true racing partner isn't Previous - e.g. a third
+	// goroutine wrote to the cell between the actual race and detection,
+	// overwriting what Previous would otherwise have captured.
+	History []AccessInfo
+
+	// AllocSize is the size in bytes of the heap object Current.Addr falls
+	// inside, if OnMalloc ever recorded an allocation covering it
+	// (synth-3632). Zero if the address isn't inside any tracked
+	// allocation - a global, a stack variable, or an object allocated
+	// before this run's allocSites index was populated.
+	AllocSize uintptr
+
+	// AllocStack holds the call stack captured at the allocation site
+	// AllocSize describes. Empty exactly when AllocSize is zero.
+	AllocStack []uintptr
+
+	// AllocGoroutineID is the id of the goroutine that performed the
+	// allocation AllocStack describes. Meaningless when AllocStack is
+	// empty.
+	AllocGoroutineID uint32
 }
 
-// generateDeduplicationKey generates a unique key for a race location.
+// generateDeduplicationKey generates a unique key for a race location,
+// fingerprinted by the two call stacks involved rather than the address
+// and goroutine IDs (v0.5.0).
 //
-// The key format is: "{type}:{addr}:{gid1}:{gid2}" where:
-//   - type: Race type string (RaceTypeWriteWrite, RaceTypeReadWrite, RaceTypeWriteRead)
-//   - addr: Memory address in hexadecimal (0x format)
-//   - gid1, gid2: Goroutine IDs sorted numerically (smaller first)
+// The original {type,addr,gid1,gid2} key had two failure modes in
+// practice: it silently merged distinct logical races that happened to
+// reuse the same heap address and goroutine IDs across a run, and it
+// reported the SAME logical race over and over when ASLR/heap layout put
+// it at a different address each run. A bug's call stacks don't move
+// between runs, so hashing them via the stackdepot gives a stable
+// fingerprint for "is this the same bug" instead.
 //
-// This ensures that a race between goroutines A and B at address X always
-// generates the same key regardless of which goroutine detected it first.
+// The key format is: "{type}:{hashA}:{hashB}" where hashA and hashB are
+// the two stack hashes sorted numerically (smaller first), so a race
+// between accesses A and B produces the same key regardless of which one
+// is "current" and which is "previous".
 //
 // Parameters:
 //   - raceType: Type of race (RaceTypeWriteWrite, RaceTypeReadWrite, RaceTypeWriteRead)
-//   - addr: Memory address where race occurred
-//   - gid1, gid2: Goroutine IDs involved in the race
+//   - stackHashA, stackHashB: stackdepot hashes of the two racing accesses' stacks
 //
 // Returns a string suitable for use as a map key.
-//
-// Phase 5 Task 5.3: Deduplication key generation.
-//
-// Example:
-//
-//	key := generateDeduplicationKey(RaceTypeWriteWrite, 0x1234, 5, 3)
-//	// Returns: "write-write:0x1234:3:5" (goroutine IDs sorted)
-func generateDeduplicationKey(raceType string, addr uintptr, gid1, gid2 uint32) string {
-	// Sort goroutine IDs to ensure consistent key ordering.
-	// This makes race (G1 vs G2) and race (G2 vs G1) generate the same key.
-	minGID := min(gid1, gid2)
-	maxGID := max(gid1, gid2)
+func generateDeduplicationKey(raceType string, stackHashA, stackHashB uint64) string {
+	minHash := min(stackHashA, stackHashB)
+	maxHash := max(stackHashA, stackHashB)
 
-	// Format: "type:addr:gid1:gid2"
-	// Using fmt.Sprintf for clarity and maintainability.
-	// This is not on the hot path (only called when race detected).
-	return fmt.Sprintf("%s:0x%x:%d:%d", raceType, addr, minGID, maxGID)
+	return fmt.Sprintf("%s:%016x:%016x", raceType, minHash, maxHash)
 }
 
 // captureStackTrace captures the current call stack.
@@ -152,34 +203,145 @@ func captureStackTrace(skip int) []uintptr {
 	return pcs[:n]
 }
 
-// formatStackTrace formats a stack trace for display in race reports.
-//
-// This function converts program counters (PCs) into a formatted string
-// matching Go's official race detector output:
-//
-//	main.reader()
-//	    /path/to/file.go:15 +0x3b
-//	main.worker()
-//	    /path/to/file.go:25 +0x5c
-//
-// Parameters:
-//   - pcs: Program counters from runtime.Callers()
+// captureStackTraceInto is captureStackTrace without the allocation: it
+// fills a caller-provided, already-sized buffer instead of making a new
+// one, so it can run on a //go:nosplit hot path (synth-3587's enqueueRace)
+// without violating "must not allocate". See captureStackTrace for the
+// skip parameter's meaning; the two otherwise behave identically.
+func captureStackTraceInto(skip int, pcs []uintptr) int {
+	return runtime.Callers(skip, pcs)
+}
+
+// ReportOptions configures how a race report's stack traces are rendered
+// (synth-3605): how many frames are shown and which are filtered out, so a
+// report can be made to point directly at user code instead of scrolling
+// through racedetector's own instrumentation plumbing and Go's
+// compiler-generated wrappers.
 //
-// Returns a formatted string ready for inclusion in race reports.
+// The zero value reproduces formatStackTrace's fixed filtering from before
+// synth-3605 exactly: every captured frame is shown (up to maxStackDepth),
+// runtime and detector-internal frames are skipped, and nothing else is
+// filtered - so leaving ReportOptions unset changes no existing output.
 //
+// racedetector deliberately doesn't read the official Go race detector's
+// GORACE environment variable for this: GORACE's keys (history_size,
+// halt_on_error, etc.) are TSan's, not this detector's, and reusing that
+// name for unrelated options would misleadingly imply compatibility with
+// them. See internal/race/api.Init for the RACEDETECTOR_* env vars that
+// configure this instead.
+type ReportOptions struct {
+	// MaxFrames caps how many frames are printed per stack after the
+	// filtering below is applied, so a deep call chain can't push the
+	// actual race site off the top of a terminal or CI log. 0 (default)
+	// means unlimited: print every frame that survives filtering.
+	MaxFrames int
+
+	// SkipInternalFrames additionally filters out this module's own
+	// frames outside the detector package itself - e.g. race.RaceRead and
+	// internal/race/api.raceread, the instrumentation wrappers every
+	// rewritten read/write passes through - so a report's first visible
+	// frame is the user code that triggered the access, not racedetector's
+	// own plumbing. detector-internal frames (OnWrite, OnRead, ...) and
+	// runtime frames are always filtered regardless of this setting; it
+	// only extends that filtering to cover the rest of the module.
+	// Default: false (unchanged from pre-synth-3605 behavior).
+	SkipInternalFrames bool
+
+	// CollapseWrappers filters out compiler-generated wrapper frames that
+	// carry no information for debugging a race: method-value wrappers
+	// (function names ending in "-fm") and the wrapper functions the Go
+	// compiler emits around range-over-func loop bodies (names containing
+	// ".gowrap"). Default: false (unchanged from pre-synth-3605 behavior).
+	CollapseWrappers bool
+
+	// MaxReportsPerSite caps how many full reports are printed to stderr
+	// for the same racing site pair (synth-3607) - see raceSiteKey - before
+	// further occurrences are suppressed from stderr. Suppressed
+	// occurrences are still counted: Detector.TopSites and the Fini
+	// summary reflect every occurrence regardless of this cap, only the
+	// noisy per-occurrence stack trace print is affected. 0 (default)
+	// means unlimited: print every occurrence, matching pre-synth-3607
+	// behavior.
+	MaxReportsPerSite int
+
+	// MaxReports caps how many full reports are printed to stderr in
+	// total, across every site pair combined (synth-3635) - the same idea
+	// as MaxReportsPerSite, just summed globally instead of per site. This
+	// is what keeps a badly racy program from dumping gigabytes of stack
+	// traces before anyone notices: once the cap is hit, every further
+	// distinct race is still detected and still counted (RacesDetected,
+	// TopSites), it just stops growing stderr. Detector.SuppressedReports
+	// reports how many were held back this way, and the Fini summary
+	// prints it as a "suppressed N further races" line. 0 (default) means
+	// unlimited: print every distinct race, matching pre-synth-3635
+	// behavior.
+	MaxReports int
+}
+
+// isModuleInternalFrame reports whether fn belongs to this module's own
+// instrumentation plumbing (synth-3605's SkipInternalFrames), as opposed to
+// user code that happens to import it. detector-internal frames are
+// already filtered unconditionally by formatStackTrace's own frame list
+// below; this additionally covers the race and internal/race/api packages'
+// wrapper functions, which sit on every instrumented access's stack.
+func isModuleInternalFrame(fn string) bool {
+	return strings.HasPrefix(fn, "github.com/kolkov/racedetector/race.") ||
+		strings.HasPrefix(fn, "github.com/kolkov/racedetector/internal/race/api.")
+}
+
+// isCompilerGeneratedWrapper reports whether fn is a wrapper frame the Go
+// compiler emits rather than code the developer wrote (synth-3605's
+// CollapseWrappers): a method-value wrapper ("T.Method-fm") or a
+// range-over-func loop body wrapper (containing ".gowrap").
+func isCompilerGeneratedWrapper(fn string) bool {
+	return strings.HasSuffix(fn, "-fm") || strings.Contains(fn, ".gowrap")
+}
+
+// formatTestName returns " (test <name>)" for a non-empty test name, or ""
+// if name is empty - suffixed directly onto a report's "by goroutine %d"
+// line so a race found under `racedetector test` reads e.g. "by goroutine
+// 7 (test TestFoo/subtest):" (synth-3600), and reports outside of tests
+// are unaffected.
+func formatTestName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (test %s)", name)
+}
+
+// formatSymbolName returns " (<kind> <name>)" for a non-empty symbol name,
+// or "" if name is empty - printed right after an access's address so a
+// report reads e.g. "Write at 0x00c0000180a0 (field Config.Timeout) by
+// goroutine 7:" (synth-3630) or "... (global main.counter) ..."
+// (synth-3631) instead of only the bare address. kind is meaningless when
+// name is empty.
+func formatSymbolName(name, kind string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s %s)", kind, name)
+}
+
 // Phase 5 Task 5.2: Stack trace formatting implementation.
-func formatStackTrace(pcs []uintptr) string {
+// synth-3605: opts controls the additional frame filtering and the
+// printed-frame cap described on ReportOptions.
+func formatStackTrace(pcs []uintptr, sym *Symbolizer, opts ReportOptions) string {
 	if len(pcs) == 0 {
 		return "  (no stack trace available)\n"
 	}
 
 	frames := runtime.CallersFrames(pcs)
 	var buf strings.Builder
+	printed := 0
 
 	for {
 		frame, more := frames.Next()
 
 		// Skip runtime internal frames and detector internal frames
+		// (always filtered, regardless of opts - these never help debug a
+		// race, unlike the module-internal/wrapper frames below, which are
+		// opt-in since some users want to see the full instrumentation
+		// path).
 		if strings.HasPrefix(frame.Function, "runtime.") ||
 			strings.HasPrefix(frame.Function, "internal/") ||
 			strings.Contains(frame.Function, "/race/detector.(*Detector).OnWrite") ||
@@ -187,13 +349,19 @@ func formatStackTrace(pcs []uintptr) string {
 			strings.Contains(frame.Function, "/race/detector.(*Detector).OnAcquire") ||
 			strings.Contains(frame.Function, "/race/detector.(*Detector).OnRelease") ||
 			strings.Contains(frame.Function, "/race/detector.(*Detector).OnChannel") ||
-			strings.Contains(frame.Function, "/race/detector.(*Detector).OnWaitGroup") {
+			strings.Contains(frame.Function, "/race/detector.(*Detector).OnWaitGroup") ||
+			(opts.SkipInternalFrames && isModuleInternalFrame(frame.Function)) ||
+			(opts.CollapseWrappers && isCompilerGeneratedWrapper(frame.Function)) {
 			if !more {
 				break
 			}
 			continue
 		}
 
+		if opts.MaxFrames > 0 && printed >= opts.MaxFrames {
+			break
+		}
+
 		// Format: function name with parentheses
 		buf.WriteString("  ")
 		buf.WriteString(frame.Function)
@@ -201,7 +369,7 @@ func formatStackTrace(pcs []uintptr) string {
 
 		// Format: file path and line number with offset
 		buf.WriteString("      ")
-		buf.WriteString(frame.File)
+		buf.WriteString(sym.SymbolizeFile(frame.File))
 		buf.WriteString(":")
 		buf.WriteString(fmt.Sprintf("%d", frame.Line))
 
@@ -209,6 +377,7 @@ func formatStackTrace(pcs []uintptr) string {
 		// Note: This is approximate, actual offset calculation is complex
 		buf.WriteString(fmt.Sprintf(" +0x%x", frame.PC&0xfff))
 		buf.WriteString("\n")
+		printed++
 
 		if !more {
 			break
@@ -245,17 +414,35 @@ func formatStackTrace(pcs []uintptr) string {
 //
 // v0.2.0 Task 6: Complete race reports with both stacks.
 // v0.3.0 Performance: Lazy stack capture using stored PC values.
+func NewRaceReportWithStacks(raceType string, addr uintptr, vsInterface interface{}, prevEpoch, currEpoch epoch.Epoch) *RaceReport {
+	// Capture stack trace for current access.
+	// Skip 2 frames: captureStackTrace, NewRaceReportWithStacks.
+	return newRaceReport(nil, raceType, addr, vsInterface, prevEpoch, currEpoch, captureStackTrace(2))
+}
+
+// newRaceReport is the shared core of NewRaceReportWithStacks, factored out
+// so the current access's stack trace can be supplied by the caller instead
+// of always being captured on the spot (synth-3587).
+//
+// This matters once race reporting moves off the goroutine that actually
+// raced: OnWrite/OnRead's nosplit hot path now only enqueues a raceRecord
+// (see racequeue.go) for a separate reporter goroutine to process, and by
+// the time that goroutine calls this function the racing goroutine's stack
+// may have already unwound past the frames that matter. currentStack must
+// therefore be captured synchronously, on the racing goroutine itself, at
+// enqueue time - see enqueueRace - and threaded through here rather than
+// re-captured from whichever goroutine happens to be building the report.
+//
+// Parameters:
+//   - currentStack: PCs for the current access, captured on the racing
+//     goroutine before it returned from OnWrite/OnRead.
 //
 //nolint:gocognit // Complex but necessary logic for race report generation
-func NewRaceReportWithStacks(raceType string, addr uintptr, vsInterface interface{}, prevEpoch, currEpoch epoch.Epoch) *RaceReport {
+func newRaceReport(d *Detector, raceType string, addr uintptr, vsInterface interface{}, prevEpoch, currEpoch epoch.Epoch, currentStack []uintptr) *RaceReport {
 	// Extract goroutine IDs from epochs.
 	currTID, _ := currEpoch.Decode()
 	prevTID, _ := prevEpoch.Decode()
 
-	// Capture stack trace for current access.
-	// Skip 3 frames: captureStackTrace, NewRaceReportWithStacks, reportRaceV2
-	currentStack := captureStackTrace(3)
-
 	// Retrieve previous access stack from VarState.
 	var previousStack []uintptr
 
@@ -313,21 +500,72 @@ func NewRaceReportWithStacks(raceType string, addr uintptr, vsInterface interfac
 		}
 	}
 
+	// Symbol name lookup (synth-3630, synth-3631): both accesses share the
+	// same addr, and symbolInfoForAddr only ever reflects the most recent
+	// OnWriteSym write there - see symbols.go for why that's still the
+	// right answer for both sides of a race in the common case.
+	var symbolName, symbolKind string
+	if d != nil {
+		symbolName, symbolKind = d.symbolInfoForAddr(addr)
+	}
+
 	report := &RaceReport{
 		Current: AccessInfo{
 			Addr:        addr,
 			GoroutineID: uint32(currTID),
 			Epoch:       currEpoch,
 			StackTrace:  currentStack,
+			SymbolName:  symbolName,
+			SymbolKind:  symbolKind,
 		},
 		Previous: AccessInfo{
 			Addr:        addr,
 			GoroutineID: uint32(prevTID),
 			Epoch:       prevEpoch,
 			StackTrace:  previousStack, // ✅ Now has previous stack!
+			SymbolName:  symbolName,
+			SymbolKind:  symbolKind,
 		},
 	}
 
+	// Allocation-site attribution (synth-3632): if addr falls inside an
+	// object OnMalloc recorded, note where that object came from so
+	// FormatWithOptions can print it alongside the two racing accesses.
+	if d != nil {
+		if site, ok := d.allocSiteForAddr(addr); ok {
+			report.AllocSize = site.size
+			report.AllocStack = site.pcs
+			report.AllocGoroutineID = site.gid
+		}
+	}
+
+	// History (v0.4.0 "history_size" support): if the detector was configured
+	// with HistorySize > 0, surface the other recent accesses to this cell.
+	// We use an interface{} type assertion (rather than a concrete *VarState
+	// parameter) for the same reason pcGetter does above - vsInterface comes
+	// in as interface{} to keep this file decoupled from the call site.
+	type historyGetter interface {
+		GetHistory() []shadowmem.AccessRecord
+	}
+	if hg, ok := vsInterface.(historyGetter); ok {
+		for _, rec := range hg.GetHistory() {
+			if rec.Epoch == currEpoch || rec.Epoch == prevEpoch {
+				continue // already represented by Current/Previous
+			}
+			tid, _ := rec.Epoch.Decode()
+			accessType := AccessRead
+			if rec.IsWrite {
+				accessType = AccessWrite
+			}
+			report.History = append(report.History, AccessInfo{
+				Type:        accessType,
+				Addr:        addr,
+				GoroutineID: uint32(tid),
+				Epoch:       rec.Epoch,
+			})
+		}
+	}
+
 	// Determine access types based on race type string.
 	switch raceType {
 	case RaceTypeWriteWrite:
@@ -345,12 +583,11 @@ func NewRaceReportWithStacks(raceType string, addr uintptr, vsInterface interfac
 		report.Previous.Type = AccessWrite
 	}
 
-	// Generate deduplication key (Phase 5 Task 5.3).
+	// Generate deduplication key from the two stacks involved (v0.5.0).
 	report.DeduplicationKey = generateDeduplicationKey(
 		raceType,
-		addr,
-		uint32(prevTID),
-		uint32(currTID),
+		stackdepot.HashPCs(currentStack),
+		stackdepot.HashPCs(previousStack),
 	)
 
 	return report
@@ -419,13 +656,13 @@ func NewRaceReport(raceType string, addr uintptr, prevEpoch, currEpoch epoch.Epo
 		report.Previous.Type = AccessWrite
 	}
 
-	// Generate deduplication key (Phase 5 Task 5.3).
-	// This uniquely identifies the race location to prevent duplicate reports.
+	// Generate deduplication key from the two stacks involved (v0.5.0).
+	// Previous access has no stack here (see StackTrace: nil above), so it
+	// hashes to the depot's "empty stack" fingerprint.
 	report.DeduplicationKey = generateDeduplicationKey(
 		raceType,
-		addr,
-		uint32(prevTID),
-		uint32(currTID),
+		stackdepot.HashPCs(currentStack),
+		stackdepot.HashPCs(nil),
 	)
 
 	return report
@@ -453,18 +690,36 @@ func NewRaceReport(raceType string, addr uintptr, prevEpoch, currEpoch epoch.Epo
 //
 // The report is written to the provided io.Writer (typically os.Stderr).
 //
-//nolint:errcheck // Error handling omitted for stderr output formatting
+// Format leaves stack trace file paths unchanged; use FormatWithSymbolizer
+// to rewrite them (v0.5.0).
 func (r *RaceReport) Format(w io.Writer) {
+	r.FormatWithSymbolizer(w, nil)
+}
+
+// FormatWithSymbolizer is Format, but rewrites each stack frame's file
+// path through sym first (v0.5.0) - see Symbolizer. A nil sym behaves
+// exactly like Format. Uses the zero-value ReportOptions; see
+// FormatWithOptions to also configure stack depth and frame filtering
+// (synth-3605).
+func (r *RaceReport) FormatWithSymbolizer(w io.Writer, sym *Symbolizer) {
+	r.FormatWithOptions(w, sym, ReportOptions{})
+}
+
+// FormatWithOptions is FormatWithSymbolizer, additionally applying opts to
+// each stack trace's rendering (synth-3605) - see ReportOptions.
+//
+//nolint:errcheck // Error handling omitted for stderr output formatting
+func (r *RaceReport) FormatWithOptions(w io.Writer, sym *Symbolizer, opts ReportOptions) {
 	fmt.Fprintf(w, "==================\n")
 	fmt.Fprintf(w, "WARNING: DATA RACE\n")
 
 	// Current access (the one that triggered detection).
-	fmt.Fprintf(w, "%s at 0x%016x by goroutine %d:\n",
-		r.Current.Type, r.Current.Addr, r.Current.GoroutineID)
+	fmt.Fprintf(w, "%s at 0x%016x%s by goroutine %d%s:\n",
+		r.Current.Type, r.Current.Addr, formatSymbolName(r.Current.SymbolName, r.Current.SymbolKind), r.Current.GoroutineID, formatTestName(r.Current.TestName))
 
 	// Format stack trace for current access.
 	if len(r.Current.StackTrace) > 0 {
-		fmt.Fprint(w, formatStackTrace(r.Current.StackTrace))
+		fmt.Fprint(w, formatStackTrace(r.Current.StackTrace, sym, opts))
 	} else {
 		fmt.Fprintf(w, "  (no stack trace captured)\n")
 	}
@@ -474,12 +729,12 @@ func (r *RaceReport) Format(w io.Writer) {
 	fmt.Fprintf(w, "\n")
 
 	// Previous conflicting access.
-	fmt.Fprintf(w, "Previous %s at 0x%016x by goroutine %d:\n",
-		r.Previous.Type, r.Previous.Addr, r.Previous.GoroutineID)
+	fmt.Fprintf(w, "Previous %s at 0x%016x%s by goroutine %d%s:\n",
+		r.Previous.Type, r.Previous.Addr, formatSymbolName(r.Previous.SymbolName, r.Previous.SymbolKind), r.Previous.GoroutineID, formatTestName(r.Previous.TestName))
 
 	// Format stack trace for previous access (if available).
 	if len(r.Previous.StackTrace) > 0 {
-		fmt.Fprint(w, formatStackTrace(r.Previous.StackTrace))
+		fmt.Fprint(w, formatStackTrace(r.Previous.StackTrace, sym, opts))
 	} else {
 		// Previous access stack trace not available (would require
 		// storing stack traces in shadow memory).
@@ -489,6 +744,25 @@ func (r *RaceReport) Format(w io.Writer) {
 
 	fmt.Fprintf(w, "  [epoch: %s]\n", r.Previous.Epoch.String())
 
+	// Other recent accesses (v0.4.0 "history_size" support). Only present
+	// when the detector was configured with HistorySize > 0.
+	if len(r.History) > 0 {
+		fmt.Fprintf(w, "\nOther recent accesses to this address:\n")
+		for _, access := range r.History {
+			fmt.Fprintf(w, "  %s by goroutine %d [epoch: %s]\n",
+				access.Type, access.GoroutineID, access.Epoch.String())
+		}
+	}
+
+	// Allocation site (synth-3632), like the official race detector's
+	// "allocated by goroutine N at:" block - present only when the racing
+	// address fell inside an object OnMalloc recorded.
+	if len(r.AllocStack) > 0 {
+		fmt.Fprintf(w, "\n0x%016x is %d-byte block allocated by goroutine %d at:\n",
+			r.Current.Addr, r.AllocSize, r.AllocGoroutineID)
+		fmt.Fprint(w, formatStackTrace(r.AllocStack, sym, opts))
+	}
+
 	fmt.Fprintf(w, "==================\n")
 }
 
@@ -509,13 +783,17 @@ func (r *RaceReport) String() string {
 // This replaces the MVP reportRace() function with a more structured approach
 // that matches Go's official race detector output format.
 //
-// Deduplication Strategy (Phase 5 Task 5.3):
-// - Generate a unique key for each race location: "{type}:{addr}:{gid1}:{gid2}"
-// - Check if this key has been reported before (using sync.Map)
-// - If yes: silently skip reporting (return early)
-// - If no: report the race and mark this key as reported
+// Deduplication Strategy (v0.5.0, originally Phase 5 Task 5.3):
+//   - Generate a unique key for each race location from the two stack hashes
+//     involved: "{type}:{hashA}:{hashB}" (see generateDeduplicationKey)
+//   - Check if this key has been reported before (using sync.Map)
+//   - If yes: silently skip reporting (return early)
+//   - If no: report the race and mark this key as reported
 //
-// This prevents spam from the same race occurring multiple times during execution.
+// This prevents spam from the same race occurring multiple times during
+// execution, and - unlike the original address+goroutine-ID key - also
+// across runs where ASLR/heap layout puts the same logical race at a
+// different address each time.
 //
 // Stack Traces (v0.2.0 Task 6):
 // - Retrieves previous access stack from VarState
@@ -536,14 +814,31 @@ func (r *RaceReport) String() string {
 // Phase 5 Task 5.3: ✅ Deduplication to prevent duplicate reports
 // v0.2.0 Task 6: ✅ Complete race reports with both stacks.
 func (d *Detector) reportRaceV2(raceType string, addr uintptr, vs interface{}, prevEpoch, currEpoch epoch.Epoch) {
+	// Skip 2 frames: captureStackTrace, reportRaceV2 - see
+	// reportRaceV2WithStack for why the capture and the reporting logic are
+	// split into two functions.
+	d.reportRaceV2WithStack(raceType, addr, vs, prevEpoch, currEpoch, captureStackTrace(2))
+}
+
+// reportRaceV2WithStack is reportRaceV2's implementation, parameterized on
+// the current access's already-captured stack (synth-3587).
+//
+// reportRaceV2 captures the stack on whichever goroutine calls it, which is
+// only correct when that's the goroutine that actually raced. That stopped
+// being true once OnWrite/OnRead's nosplit hot path started enqueuing races
+// for a separate reporter goroutine to process instead of reporting them
+// inline (see racequeue.go): enqueueRace captures the stack on the racing
+// goroutine itself, before it returns, and threads it through to here so
+// the reporter goroutine's own call stack never leaks into a race report.
+func (d *Detector) reportRaceV2WithStack(raceType string, addr uintptr, vs interface{}, prevEpoch, currEpoch epoch.Epoch, currentStack []uintptr) {
 	// Create structured race report (this generates the deduplication key).
-	report := NewRaceReportWithStacks(raceType, addr, vs, prevEpoch, currEpoch)
+	report := newRaceReport(d, raceType, addr, vs, prevEpoch, currEpoch, currentStack)
 
 	// Phase 5 Task 5.3: Check if this race has already been reported.
-	// Use LoadOrStore for atomic check-and-set operation.
-	// If the key already exists, LoadOrStore returns (value, true).
-	// If the key is new, it stores the value and returns (value, false).
-	_, alreadyReported := d.reportedRaces.LoadOrStore(report.DeduplicationKey, struct{}{})
+	// checkAndAdd is dedupSet's LoadOrStore equivalent (synth-3634): an
+	// atomic check-and-set scoped to whichever shard DeduplicationKey
+	// hashes to.
+	alreadyReported := d.reportedRaces.checkAndAdd(report.DeduplicationKey)
 	if alreadyReported {
 		// This race has already been reported - skip it silently.
 		// We don't increment the race counter for duplicates.
@@ -551,14 +846,248 @@ func (d *Detector) reportRaceV2(raceType string, addr uintptr, vs interface{}, p
 	}
 
 	// This is a new race - report it!
+
+	// Attribute each access to whichever test (if any) is currently
+	// registered against its goroutine (synth-3600), so the printed report
+	// can say which test found the race, not just which anonymous
+	// goroutine ID did. This is a live lookup at report time, not a
+	// capture at access time, so Previous.TestName can come back empty
+	// even for a genuine test race - see AccessInfo.TestName.
+	report.Current.TestName = d.TestNameForTID(uint16(report.Current.GoroutineID))
+	report.Previous.TestName = d.TestNameForTID(uint16(report.Previous.GoroutineID))
+
+	// Adaptive sampling feedback (v0.4.0): now that this address is known to
+	// race, boost its sampling rate to 100% for a while so we don't miss the
+	// next occurrence just because the global sampling rate skipped it.
+	if d.sampler != nil {
+		d.sampler.MarkHotSite(addr)
+	}
+
+	// Per-address muting (synth-3636), opt-in via DetectorOptions.
+	// MuteAfterReport: now that this address is known to race, poison its
+	// shadow cell so OnWrite/OnRead stop paying for the full FastTrack
+	// check on it. Only the shadowmem.VarState path supports this - vs is
+	// nil (or a syncshadow.SyncVar, for a sync-primitive race) in the
+	// call paths that don't apply here.
+	if d.muteAfterReport {
+		if varState, ok := vs.(*shadowmem.VarState); ok {
+			varState.Poison()
+		}
+	}
+
 	// Lock to prevent interleaved output from multiple goroutines.
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	// Increment race counter for statistics.
-	// Only count unique races (deduplication is applied).
-	d.racesDetected++
+	// Only count unique races (deduplication is applied). Atomic, not
+	// mu-guarded (synth-3634) - see racesDetected's field doc.
+	d.racesDetected.Add(1)
+
+	// Tally this race against its site pair (synth-3607), for Fini's
+	// ranked summary and MaxReportsPerSite below - counted regardless of
+	// whether printing ends up suppressed, so the summary always reflects
+	// every occurrence.
+	d.recordSiteCount(report)
+
+	// Print the full report to stderr, unless MaxReportsPerSite caps how
+	// many of this site pair's occurrences get a full stack trace printed
+	// (synth-3607), or MaxReports caps how many get printed in total
+	// across every site combined (synth-3635) - the site is still counted
+	// above either way.
+	siteKey, _ := raceSiteKey(report, d.symbolizer)
+	underSiteCap := d.reportOpts.MaxReportsPerSite <= 0 || d.printedForSite[siteKey] < d.reportOpts.MaxReportsPerSite
+	underGlobalCap := d.reportOpts.MaxReports <= 0 || d.totalPrinted < d.reportOpts.MaxReports
+	if underSiteCap && underGlobalCap {
+		report.FormatWithOptions(os.Stderr, d.symbolizer, d.reportOpts)
+		if d.printedForSite == nil {
+			d.printedForSite = make(map[string]int)
+		}
+		d.printedForSite[siteKey]++
+		d.totalPrinted++
+	} else if !underGlobalCap {
+		// Only tally suppressions actually caused by MaxReports - one held
+		// back by the pre-existing, separate MaxReportsPerSite cap was
+		// never going to count against MaxReports in the first place.
+		d.suppressedReports.Add(1)
+	}
+
+	// Retain it for the debug endpoint (v0.5.0).
+	d.recordRecentReport(report)
+
+	// Emit a runtime/trace user task for this race (v0.5.0), if a trace is
+	// running. See traceTracker.
+	d.tracer.race(report)
+
+	// Hand off to the remote report exporter (synth-3602), if configured.
+	// enqueue only ever does a non-blocking channel send, so this can't
+	// stall d.mu on network I/O the way calling send() directly here
+	// would.
+	if d.exporter != nil {
+		d.exporter.enqueue(report)
+	}
+
+	// Hand off to an embedder's report callback (synth-3608), if
+	// configured - see DetectorOptions.OnReport and race.DetectorOptions.
+	if d.onReport != nil {
+		d.onReport(report)
+	}
+}
+
+// raceSite returns "file:line" for the innermost frame of pcs that
+// survives formatStackTrace's baseline filtering (runtime and
+// detector-internal frames), symbolized through sym exactly like a full
+// report - see formatStackTrace. Returns "(unknown)" if pcs is empty or
+// every frame was filtered.
+func raceSite(pcs []uintptr, sym *Symbolizer) string {
+	if len(pcs) == 0 {
+		return "(unknown)"
+	}
 
-	// Format and print to stderr.
-	report.Format(os.Stderr)
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+
+		if strings.HasPrefix(frame.Function, "runtime.") ||
+			strings.HasPrefix(frame.Function, "internal/") ||
+			strings.Contains(frame.Function, "/race/detector.(*Detector).OnWrite") ||
+			strings.Contains(frame.Function, "/race/detector.(*Detector).OnRead") ||
+			strings.Contains(frame.Function, "/race/detector.(*Detector).OnAcquire") ||
+			strings.Contains(frame.Function, "/race/detector.(*Detector).OnRelease") ||
+			strings.Contains(frame.Function, "/race/detector.(*Detector).OnChannel") ||
+			strings.Contains(frame.Function, "/race/detector.(*Detector).OnWaitGroup") {
+			if !more {
+				break
+			}
+			continue
+		}
+
+		return fmt.Sprintf("%s:%d", sym.SymbolizeFile(frame.File), frame.Line)
+	}
+
+	return "(unknown)"
+}
+
+// raceSiteKey identifies report's racing site pair (synth-3607) for
+// per-site aggregation - see Detector.TopSites - as the two racing
+// accesses' innermost surviving stack frames (via raceSite above), sorted
+// so a race between sites A and B produces the same key regardless of
+// which access is Current and which is Previous, mirroring
+// generateDeduplicationKey's own sorting of its two stack hashes.
+//
+// display is the same pair for the Fini summary table, using each site's
+// base filename only (e.g. "cache.go:88 <-> worker.go:41"): a summary
+// table is meant to be scanned at a glance across many sites, unlike a
+// full report's stack traces, which keep full paths for going straight
+// from a terminal to an editor.
+func raceSiteKey(report *RaceReport, sym *Symbolizer) (key, display string) {
+	a := raceSite(report.Current.StackTrace, sym)
+	b := raceSite(report.Previous.StackTrace, sym)
+	if b < a {
+		a, b = b, a
+	}
+	return a + " <-> " + b, filepath.Base(a) + " <-> " + filepath.Base(b)
+}
+
+// siteCount is one entry accumulated in Detector.siteCounts.
+type siteCount struct {
+	display string
+	count   int
+}
+
+// SiteCount is one row of Detector.TopSites' ranked summary.
+type SiteCount struct {
+	// Site is the racing site pair's display form, e.g.
+	// "cache.go:88 <-> worker.go:41" (synth-3607) - see raceSiteKey.
+	Site string
+	// Count is how many unique races (already deduplicated by call-stack
+	// fingerprint) have been attributed to this site pair.
+	Count int
+}
+
+// recordSiteCount increments the tally for report's site pair, called once
+// per newly reported (non-duplicate) race from reportRaceV2WithStack.
+//
+// Thread Safety: caller must hold d.mu.
+func (d *Detector) recordSiteCount(report *RaceReport) {
+	key, display := raceSiteKey(report, d.symbolizer)
+
+	if d.siteCounts == nil {
+		d.siteCounts = make(map[string]*siteCount)
+	}
+	sc, ok := d.siteCounts[key]
+	if !ok {
+		sc = &siteCount{display: display}
+		d.siteCounts[key] = sc
+	}
+	sc.count++
+}
+
+// TopSites returns the n racing site pairs with the most reported races
+// (synth-3607), ranked highest count first, ties broken by Site name for
+// determinism. n <= 0 returns every site, unranked-bounded.
+//
+// Thread Safety: Safe for concurrent calls.
+func (d *Detector) TopSites(n int) []SiteCount {
+	d.mu.Lock()
+	sites := make([]SiteCount, 0, len(d.siteCounts))
+	for _, sc := range d.siteCounts {
+		sites = append(sites, SiteCount{Site: sc.display, Count: sc.count})
+	}
+	d.mu.Unlock()
+
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Count != sites[j].Count {
+			return sites[i].Count > sites[j].Count
+		}
+		return sites[i].Site < sites[j].Site
+	})
+
+	if n > 0 && len(sites) > n {
+		sites = sites[:n]
+	}
+	return sites
+}
+
+// recordRecentReport appends report to the bounded recentReports ring
+// buffer, dropping the oldest entry once maxRecentReports is reached.
+func (d *Detector) recordRecentReport(report *RaceReport) {
+	d.recentReportsMu.Lock()
+	defer d.recentReportsMu.Unlock()
+
+	d.recentReports = append(d.recentReports, report)
+	if len(d.recentReports) > maxRecentReports {
+		d.recentReports = d.recentReports[len(d.recentReports)-maxRecentReports:]
+	}
+}
+
+// RecentReports returns a snapshot of the most recently reported races
+// (v0.5.0), oldest first, bounded to the last maxRecentReports. Intended
+// for a debug endpoint, not for correctness-critical logic.
+//
+// Thread Safety: Safe for concurrent calls.
+func (d *Detector) RecentReports() []*RaceReport {
+	d.recentReportsMu.Lock()
+	defer d.recentReportsMu.Unlock()
+
+	reports := make([]*RaceReport, len(d.recentReports))
+	copy(reports, d.recentReports)
+	return reports
+}
+
+// FormatRecentReports writes each of RecentReports' entries to w, formatted
+// with the Detector's own symbolizer (v0.5.0) and separated by a NUL byte
+// so a caller can split them back into individual reports without parsing
+// the human-oriented "====" banner each report uses internally.
+//
+// Intended for the race-reports side channel (see
+// internal/race/api.writeRacesFile), not for human-readable output - use
+// WriteDebugState or a RaceReport's own Format method for that.
+func (d *Detector) FormatRecentReports(w io.Writer) {
+	for i, report := range d.RecentReports() {
+		if i > 0 {
+			_, _ = w.Write([]byte{0})
+		}
+		report.FormatWithOptions(w, d.symbolizer, d.reportOpts)
+	}
 }
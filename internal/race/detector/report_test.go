@@ -2,10 +2,13 @@ package detector
 
 import (
 	"bytes"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/kolkov/racedetector/internal/race/epoch"
+	"github.com/kolkov/racedetector/internal/race/goroutine"
 )
 
 // TestAccessType_String tests the String() method of AccessType.
@@ -219,6 +222,245 @@ func TestRaceReport_String(t *testing.T) {
 	}
 }
 
+// TestRaceReport_FormatWithSymbolizer verifies stack trace file paths are
+// rewritten through the provided Symbolizer (v0.5.0).
+func TestRaceReport_FormatWithSymbolizer(t *testing.T) {
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("runtime.Caller(0) failed to report this file's path")
+	}
+	thisDir := filepath.Dir(thisFile)
+
+	addr := uintptr(0x1000)
+	prevEpoch := epoch.NewEpoch(1, 5)
+	currEpoch := epoch.NewEpoch(2, 10)
+	report := NewRaceReport("write-write", addr, prevEpoch, currEpoch)
+
+	sym := NewSymbolizer(SymbolizeOptions{StripPathPrefix: thisDir})
+
+	var buf bytes.Buffer
+	report.FormatWithSymbolizer(&buf, sym)
+	output := buf.String()
+
+	if strings.Contains(output, thisDir) {
+		t.Errorf("FormatWithSymbolizer() output still contains stripped prefix %q:\n%s", thisDir, output)
+	}
+	if !strings.Contains(output, "report_test.go") {
+		t.Errorf("FormatWithSymbolizer() output missing the stack frame file entirely:\n%s", output)
+	}
+}
+
+// TestFormatWithOptions_ZeroValueMatchesFormatWithSymbolizer verifies the
+// zero-value ReportOptions{} reproduces FormatWithSymbolizer's output
+// exactly (synth-3605), so leaving ReportOptions unset changes no existing
+// output.
+func TestFormatWithOptions_ZeroValueMatchesFormatWithSymbolizer(t *testing.T) {
+	addr := uintptr(0x1000)
+	prevEpoch := epoch.NewEpoch(1, 5)
+	currEpoch := epoch.NewEpoch(2, 10)
+	report := NewRaceReport("write-write", addr, prevEpoch, currEpoch)
+
+	var want, got bytes.Buffer
+	report.FormatWithSymbolizer(&want, nil)
+	report.FormatWithOptions(&got, nil, ReportOptions{})
+
+	if want.String() != got.String() {
+		t.Errorf("FormatWithOptions(zero value) differs from FormatWithSymbolizer:\nwant:\n%s\ngot:\n%s", want.String(), got.String())
+	}
+}
+
+// TestFormatWithOptions_MaxFrames verifies MaxFrames caps the number of
+// frames printed per stack trace (synth-3605).
+func TestFormatWithOptions_MaxFrames(t *testing.T) {
+	addr := uintptr(0x1000)
+	prevEpoch := epoch.NewEpoch(1, 5)
+	currEpoch := epoch.NewEpoch(2, 10)
+	report := NewRaceReport("write-write", addr, prevEpoch, currEpoch)
+
+	var unlimited bytes.Buffer
+	report.FormatWithOptions(&unlimited, nil, ReportOptions{})
+	unlimitedFrames := strings.Count(unlimited.String(), "()\n")
+	if unlimitedFrames < 2 {
+		t.Fatalf("need at least 2 frames in the unfiltered trace to test capping, got %d", unlimitedFrames)
+	}
+
+	var capped bytes.Buffer
+	report.FormatWithOptions(&capped, nil, ReportOptions{MaxFrames: 1})
+	cappedFrames := strings.Count(capped.String(), "()\n")
+	if cappedFrames != 1 {
+		t.Errorf("MaxFrames: 1 printed %d frames, want 1", cappedFrames)
+	}
+}
+
+// TestFormatWithOptions_SkipInternalFrames verifies SkipInternalFrames
+// filters out this module's own instrumentation frames (synth-3605).
+func TestFormatWithOptions_SkipInternalFrames(t *testing.T) {
+	pcs := captureStackTrace(0) // include this test's own frame
+	sym := NewSymbolizer(SymbolizeOptions{})
+
+	plain := formatStackTrace(pcs, sym, ReportOptions{})
+	if !strings.Contains(plain, "report_test.go") {
+		t.Fatalf("expected this test's own frame in the unfiltered trace:\n%s", plain)
+	}
+
+	filtered := formatStackTrace(pcs, sym, ReportOptions{SkipInternalFrames: true})
+	if !strings.Contains(filtered, "report_test.go") {
+		t.Errorf("SkipInternalFrames unexpectedly filtered out test code, not just module-internal frames:\n%s", filtered)
+	}
+}
+
+// TestDetector_TopSites verifies races are aggregated by racing site pair
+// and ranked highest count first (synth-3607).
+func TestDetector_TopSites(t *testing.T) {
+	d := NewDetector()
+
+	// Two distinct addresses/goroutine pairs, both captured from this same
+	// test function, so both races share the same site pair and should
+	// aggregate into a single TopSites entry with count 2.
+	d.reportRaceV2("write-write", 0x1000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+	d.reportRaceV2("write-write", 0x2000, nil, epoch.NewEpoch(3, 15), epoch.NewEpoch(4, 20))
+
+	sites := d.TopSites(10)
+	if len(sites) != 1 {
+		t.Fatalf("TopSites() returned %d sites, want 1: %+v", len(sites), sites)
+	}
+	if sites[0].Count != 2 {
+		t.Errorf("sites[0].Count = %d, want 2", sites[0].Count)
+	}
+	if sites[0].Site == "" {
+		t.Error("sites[0].Site is empty")
+	}
+}
+
+// TestDetector_MaxReportsPerSite verifies MaxReportsPerSite caps how many
+// full reports print for the same site pair, without affecting TopSites'
+// count of every occurrence (synth-3607).
+func TestDetector_MaxReportsPerSite(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{
+		Report: ReportOptions{MaxReportsPerSite: 1},
+	})
+
+	d.reportRaceV2("write-write", 0x1000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+	d.reportRaceV2("write-write", 0x2000, nil, epoch.NewEpoch(3, 15), epoch.NewEpoch(4, 20))
+	d.reportRaceV2("write-write", 0x3000, nil, epoch.NewEpoch(5, 25), epoch.NewEpoch(6, 30))
+
+	sites := d.TopSites(10)
+	if len(sites) != 1 || sites[0].Count != 3 {
+		t.Fatalf("TopSites() = %+v, want a single site with count 3", sites)
+	}
+	if d.RacesDetected() != 3 {
+		t.Errorf("RacesDetected() = %d, want 3 (MaxReportsPerSite must not affect counting)", d.RacesDetected())
+	}
+	if len(d.printedForSite) != 1 {
+		t.Fatalf("printedForSite has %d entries, want 1", len(d.printedForSite))
+	}
+	for _, printed := range d.printedForSite {
+		if printed != 1 {
+			t.Errorf("printedForSite count = %d, want 1 (capped by MaxReportsPerSite)", printed)
+		}
+	}
+}
+
+// TestDetector_MaxReports verifies MaxReports caps how many full reports
+// print in total, across every site combined, without affecting
+// RacesDetected/TopSites' count of every occurrence (synth-3635).
+func TestDetector_MaxReports(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{
+		Report: ReportOptions{MaxReports: 2},
+	})
+
+	d.reportRaceV2("write-write", 0x1000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+	d.reportRaceV2("write-write", 0x2000, nil, epoch.NewEpoch(3, 15), epoch.NewEpoch(4, 20))
+	d.reportRaceV2("write-write", 0x3000, nil, epoch.NewEpoch(5, 25), epoch.NewEpoch(6, 30))
+
+	if d.RacesDetected() != 3 {
+		t.Errorf("RacesDetected() = %d, want 3 (MaxReports must not affect counting)", d.RacesDetected())
+	}
+	if sites := d.TopSites(10); len(sites) != 1 || sites[0].Count != 3 {
+		t.Errorf("TopSites() = %+v, want a single site with count 3 (MaxReports must not affect TopSites)", sites)
+	}
+	if d.totalPrinted != 2 {
+		t.Errorf("totalPrinted = %d, want 2 (capped by MaxReports)", d.totalPrinted)
+	}
+	if got := d.SuppressedReports(); got != 1 {
+		t.Errorf("SuppressedReports() = %d, want 1", got)
+	}
+}
+
+// TestDetector_MaxReports_UnlimitedByDefault verifies the zero value keeps
+// printing every distinct race, matching pre-synth-3635 behavior.
+func TestDetector_MaxReports_UnlimitedByDefault(t *testing.T) {
+	d := NewDetector()
+
+	// Each call sits on its own source line so its captured stack (and
+	// hence its deduplication key) differs from the others - a shared call
+	// site would otherwise collapse them into a single deduplicated race,
+	// same as TestDetector_TopSites/TestDetector_MaxReportsPerSite above.
+	d.reportRaceV2("write-write", 0x1000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+	d.reportRaceV2("write-write", 0x2000, nil, epoch.NewEpoch(3, 15), epoch.NewEpoch(4, 20))
+	d.reportRaceV2("write-write", 0x3000, nil, epoch.NewEpoch(5, 25), epoch.NewEpoch(6, 30))
+	d.reportRaceV2("write-write", 0x4000, nil, epoch.NewEpoch(7, 35), epoch.NewEpoch(8, 40))
+	d.reportRaceV2("write-write", 0x5000, nil, epoch.NewEpoch(9, 45), epoch.NewEpoch(10, 50))
+
+	if d.totalPrinted != 5 {
+		t.Errorf("totalPrinted = %d, want 5 (MaxReports unset must not suppress anything)", d.totalPrinted)
+	}
+	if got := d.SuppressedReports(); got != 0 {
+		t.Errorf("SuppressedReports() = %d, want 0", got)
+	}
+}
+
+// TestRaceSiteKey_OrderIndependent verifies swapping Current and Previous
+// produces the same key and display (synth-3607), matching
+// generateDeduplicationKey's own order-independence.
+func TestRaceSiteKey_OrderIndependent(t *testing.T) {
+	pcsA := captureStackTrace(0)
+	pcsB := captureStackTrace(0)
+
+	r1 := &RaceReport{Current: AccessInfo{StackTrace: pcsA}, Previous: AccessInfo{StackTrace: pcsB}}
+	r2 := &RaceReport{Current: AccessInfo{StackTrace: pcsB}, Previous: AccessInfo{StackTrace: pcsA}}
+
+	key1, display1 := raceSiteKey(r1, nil)
+	key2, display2 := raceSiteKey(r2, nil)
+	if key1 != key2 {
+		t.Errorf("raceSiteKey key = %q vs %q, want equal regardless of Current/Previous order", key1, key2)
+	}
+	if display1 != display2 {
+		t.Errorf("raceSiteKey display = %q vs %q, want equal regardless of Current/Previous order", display1, display2)
+	}
+}
+
+// TestIsModuleInternalFrame and TestIsCompilerGeneratedWrapper exercise the
+// two helper predicates directly (synth-3605), since the module's own
+// wrapper/compiler-generated frames don't appear on this test's own stack.
+func TestIsModuleInternalFrame(t *testing.T) {
+	cases := map[string]bool{
+		"github.com/kolkov/racedetector/race.RaceRead":                        true,
+		"github.com/kolkov/racedetector/internal/race/api.raceread":           true,
+		"github.com/kolkov/racedetector/internal/race/detector.NewRaceReport": false,
+		"main.main": false,
+	}
+	for fn, want := range cases {
+		if got := isModuleInternalFrame(fn); got != want {
+			t.Errorf("isModuleInternalFrame(%q) = %v, want %v", fn, got, want)
+		}
+	}
+}
+
+func TestIsCompilerGeneratedWrapper(t *testing.T) {
+	cases := map[string]bool{
+		"main.(*T).Method-fm":       true,
+		"main.main.func1.gowrap1":   true,
+		"main.main":                 false,
+		"github.com/foo/bar.Handle": false,
+	}
+	for fn, want := range cases {
+		if got := isCompilerGeneratedWrapper(fn); got != want {
+			t.Errorf("isCompilerGeneratedWrapper(%q) = %v, want %v", fn, got, want)
+		}
+	}
+}
+
 // TestDetector_reportRaceV2 tests the new structured race reporting.
 func TestDetector_reportRaceV2(t *testing.T) {
 	d := NewDetector()
@@ -255,6 +497,292 @@ func TestDetector_reportRaceV2(t *testing.T) {
 	}
 }
 
+// TestDetector_reportRaceV2_AttributesTestNames verifies a race report
+// includes the test names registered for each racing goroutine's TID
+// (synth-3600), and that the printed report names both tests.
+func TestDetector_reportRaceV2_AttributesTestNames(t *testing.T) {
+	d := NewDetector()
+
+	prevEpoch := epoch.NewEpoch(1, 5)  // tid=1
+	currEpoch := epoch.NewEpoch(2, 10) // tid=2
+
+	d.SetGoroutineTestName(1, "TestA")
+	d.SetGoroutineTestName(2, "TestB/subtest")
+
+	d.reportRaceV2("write-write", 0x1000, nil, prevEpoch, currEpoch)
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	report := reports[0]
+	if report.Current.TestName != "TestB/subtest" {
+		t.Errorf("Current.TestName = %q, want %q", report.Current.TestName, "TestB/subtest")
+	}
+	if report.Previous.TestName != "TestA" {
+		t.Errorf("Previous.TestName = %q, want %q", report.Previous.TestName, "TestA")
+	}
+
+	formatted := report.String()
+	if !strings.Contains(formatted, "(test TestB/subtest)") {
+		t.Errorf("Expected formatted report to name the current test, got:\n%s", formatted)
+	}
+	if !strings.Contains(formatted, "(test TestA)") {
+		t.Errorf("Expected formatted report to name the previous test, got:\n%s", formatted)
+	}
+}
+
+// TestDetector_reportRaceV2_NoTestNameOmitsAnnotation verifies a racing
+// goroutine with no registered test name prints without a "(test ...)"
+// suffix, matching pre-synth-3600 output.
+func TestDetector_reportRaceV2_NoTestNameOmitsAnnotation(t *testing.T) {
+	d := NewDetector()
+
+	d.reportRaceV2("write-write", 0x2000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	if strings.Contains(reports[0].String(), "(test ") {
+		t.Errorf("Expected no test-name annotation, got:\n%s", reports[0].String())
+	}
+}
+
+// TestDetector_reportRaceV2_AttributesSymbolName verifies a race on an
+// address last written through OnWriteSym reports the registered field
+// name, and that the formatted report names it (synth-3630).
+func TestDetector_reportRaceV2_AttributesSymbolName(t *testing.T) {
+	d := NewDetector()
+
+	RegisterSymbol(0xabc, "Config.Timeout", "field")
+	d.OnWriteSym(0x4000, 0xabc, goroutine.Alloc(1))
+
+	d.reportRaceV2("write-write", 0x4000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	report := reports[0]
+	if report.Current.SymbolName != "Config.Timeout" {
+		t.Errorf("Current.SymbolName = %q, want %q", report.Current.SymbolName, "Config.Timeout")
+	}
+	if report.Previous.SymbolName != "Config.Timeout" {
+		t.Errorf("Previous.SymbolName = %q, want %q", report.Previous.SymbolName, "Config.Timeout")
+	}
+
+	formatted := report.String()
+	if !strings.Contains(formatted, "(field Config.Timeout)") {
+		t.Errorf("Expected formatted report to name the field, got:\n%s", formatted)
+	}
+}
+
+// TestDetector_reportRaceV2_NoSymbolNameOmitsAnnotation verifies a race on
+// an address that was never written through OnWriteSym prints without a
+// "(field ...)" suffix, matching pre-synth-3630 output.
+func TestDetector_reportRaceV2_NoSymbolNameOmitsAnnotation(t *testing.T) {
+	d := NewDetector()
+
+	d.reportRaceV2("write-write", 0x5000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	if strings.Contains(reports[0].String(), "(field ") {
+		t.Errorf("Expected no field-name annotation, got:\n%s", reports[0].String())
+	}
+}
+
+// TestDetector_Reset_ClearsSymbolAddrs verifies Reset() forgets which
+// symbol id was last written to an address, so a stale field name from
+// before the reset isn't attributed to whatever new variable happens to
+// land at the same address afterwards (synth-3630).
+func TestDetector_Reset_ClearsSymbolAddrs(t *testing.T) {
+	d := NewDetector()
+
+	RegisterSymbol(0xdef, "Counter.Value", "field")
+	d.OnWriteSym(0x6000, 0xdef, goroutine.Alloc(1))
+
+	d.Reset()
+
+	d.reportRaceV2("write-write", 0x6000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	if reports[0].Current.SymbolName != "" {
+		t.Errorf("Expected Reset to clear the address->symbol association, got %q", reports[0].Current.SymbolName)
+	}
+}
+
+// TestDetector_reportRaceV2_AttributesAllocationSite verifies a race on an
+// address inside a tracked allocation includes an "allocated by goroutine"
+// block naming the object's size and allocating goroutine (synth-3632).
+func TestDetector_reportRaceV2_AttributesAllocationSite(t *testing.T) {
+	d := NewDetector()
+
+	d.OnMalloc(0x9000, 16, goroutine.Alloc(3))
+	d.reportRaceV2("write-write", 0x9000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	report := reports[0]
+	if report.AllocSize != 16 {
+		t.Errorf("AllocSize = %d, want 16", report.AllocSize)
+	}
+	if report.AllocGoroutineID != 3 {
+		t.Errorf("AllocGoroutineID = %d, want 3", report.AllocGoroutineID)
+	}
+	if len(report.AllocStack) == 0 {
+		t.Errorf("Expected a non-empty AllocStack")
+	}
+
+	formatted := report.String()
+	if !strings.Contains(formatted, "is 16-byte block allocated by goroutine 3 at:") {
+		t.Errorf("Expected formatted report to name the allocation site, got:\n%s", formatted)
+	}
+}
+
+// TestDetector_reportRaceV2_NoAllocationOmitsBlock verifies a race on an
+// address OnMalloc never recorded prints no allocation-site block
+// (synth-3632).
+func TestDetector_reportRaceV2_NoAllocationOmitsBlock(t *testing.T) {
+	d := NewDetector()
+
+	d.reportRaceV2("write-write", 0xa000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	if strings.Contains(reports[0].String(), "byte block allocated by goroutine") {
+		t.Errorf("Expected no allocation-site block, got:\n%s", reports[0].String())
+	}
+}
+
+// TestDetector_Reset_ClearsAllocSites verifies Reset() forgets tracked
+// allocations, so a race after reset on a reused address isn't attributed
+// to the allocation that occupied it before the reset (synth-3632).
+func TestDetector_Reset_ClearsAllocSites(t *testing.T) {
+	d := NewDetector()
+
+	d.OnMalloc(0xb000, 32, goroutine.Alloc(1))
+	d.Reset()
+
+	d.reportRaceV2("write-write", 0xb000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	if reports[0].AllocSize != 0 {
+		t.Errorf("Expected Reset to clear the allocation index, got AllocSize=%d", reports[0].AllocSize)
+	}
+}
+
+// TestDetector_ClearGoroutineTestName verifies a cleared TID's races no
+// longer carry the old test name.
+func TestDetector_ClearGoroutineTestName(t *testing.T) {
+	d := NewDetector()
+
+	d.SetGoroutineTestName(2, "TestStale")
+	d.ClearGoroutineTestName(2)
+
+	d.reportRaceV2("write-write", 0x3000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	reports := d.RecentReports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 recent report, got %d", len(reports))
+	}
+	if reports[0].Current.TestName != "" {
+		t.Errorf("Expected cleared TID to have no test name, got %q", reports[0].Current.TestName)
+	}
+}
+
+// TestDetector_RecentReports_RecordsInOrder verifies RecentReports reflects
+// reports in the order reportRaceV2 recorded them.
+func TestDetector_RecentReports_RecordsInOrder(t *testing.T) {
+	d := NewDetector()
+
+	d.reportRaceV2("write-write", 0x1000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+	d.reportRaceV2("read-write", 0x2000, nil, epoch.NewEpoch(3, 15), epoch.NewEpoch(4, 20))
+
+	reports := d.RecentReports()
+	if len(reports) != 2 {
+		t.Fatalf("RecentReports() returned %d reports, want 2", len(reports))
+	}
+	if reports[0].Current.Addr != 0x1000 || reports[1].Current.Addr != 0x2000 {
+		t.Errorf("RecentReports() = %+v, want addresses in recording order", reports)
+	}
+}
+
+// TestDetector_RecentReports_BoundedByMax verifies the ring buffer drops the
+// oldest reports once maxRecentReports is exceeded.
+func TestDetector_RecentReports_BoundedByMax(t *testing.T) {
+	d := NewDetector()
+
+	// Call recordRecentReport directly rather than going through
+	// reportRaceV2: the ring buffer itself doesn't dedupe, and exercising
+	// it this way keeps the test independent of stack-based deduplication
+	// (which would otherwise treat every iteration below as the same race,
+	// since they'd all share one call site).
+	for i := 0; i < maxRecentReports+5; i++ {
+		report := NewRaceReport("write-write", uintptr(i), epoch.NewEpoch(1, uint64(i)), epoch.NewEpoch(2, uint64(i)+1))
+		d.recordRecentReport(report)
+	}
+
+	reports := d.RecentReports()
+	if len(reports) != maxRecentReports {
+		t.Fatalf("RecentReports() returned %d reports, want %d", len(reports), maxRecentReports)
+	}
+	if reports[0].Current.Addr != 5 {
+		t.Errorf("RecentReports()[0].Current.Addr = %d, want 5 (oldest 5 dropped)", reports[0].Current.Addr)
+	}
+	if reports[len(reports)-1].Current.Addr != uintptr(maxRecentReports+4) {
+		t.Errorf("RecentReports()[last].Current.Addr = %d, want %d", reports[len(reports)-1].Current.Addr, maxRecentReports+4)
+	}
+}
+
+// TestDetector_RecentReports_ClearedByReset verifies Reset drops recorded
+// reports along with the race counter.
+func TestDetector_RecentReports_ClearedByReset(t *testing.T) {
+	d := NewDetector()
+	d.reportRaceV2("write-write", 0x1000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+
+	d.Reset()
+
+	if got := d.RecentReports(); len(got) != 0 {
+		t.Errorf("RecentReports() after Reset() = %v, want empty", got)
+	}
+}
+
+// TestDetector_FormatRecentReports_NulSeparated verifies
+// FormatRecentReports writes one formatted report per RecentReports entry,
+// separated by a NUL byte so a caller can split them back apart.
+func TestDetector_FormatRecentReports_NulSeparated(t *testing.T) {
+	d := NewDetector()
+	d.reportRaceV2("write-write", 0x1000, nil, epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10))
+	d.reportRaceV2("read-write", 0x2000, nil, epoch.NewEpoch(3, 15), epoch.NewEpoch(4, 20))
+
+	var buf bytes.Buffer
+	d.FormatRecentReports(&buf)
+
+	parts := strings.Split(buf.String(), "\x00")
+	if len(parts) != 2 {
+		t.Fatalf("FormatRecentReports() produced %d NUL-separated parts, want 2:\n%s", len(parts), buf.String())
+	}
+	for i, part := range parts {
+		if !strings.Contains(part, "WARNING: DATA RACE") {
+			t.Errorf("part %d missing formatted report banner:\n%s", i, part)
+		}
+	}
+}
+
 // BenchmarkNewRaceReport benchmarks race report creation.
 func BenchmarkNewRaceReport(b *testing.B) {
 	addr := uintptr(0x12345678)
@@ -313,7 +841,7 @@ func BenchmarkFormatStackTrace(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = formatStackTrace(pcs)
+		_ = formatStackTrace(pcs, nil, ReportOptions{})
 	}
 }
 
@@ -339,56 +867,50 @@ func TestGenerateDeduplicationKey(t *testing.T) {
 	tests := []struct {
 		name     string
 		raceType string
-		addr     uintptr
-		gid1     uint32
-		gid2     uint32
+		hashA    uint64
+		hashB    uint64
 		wantKey  string
 	}{
 		{
-			name:     "write-write race with sorted IDs",
+			name:     "write-write race with sorted hashes",
 			raceType: "write-write",
-			addr:     0x1234,
-			gid1:     3,
-			gid2:     5,
-			wantKey:  "write-write:0x1234:3:5",
+			hashA:    0x3,
+			hashB:    0x5,
+			wantKey:  "write-write:0000000000000003:0000000000000005",
 		},
 		{
-			name:     "write-write race with unsorted IDs (should sort)",
+			name:     "write-write race with unsorted hashes (should sort)",
 			raceType: "write-write",
-			addr:     0x1234,
-			gid1:     5,
-			gid2:     3,
-			wantKey:  "write-write:0x1234:3:5", // IDs sorted
+			hashA:    0x5,
+			hashB:    0x3,
+			wantKey:  "write-write:0000000000000003:0000000000000005", // Hashes sorted
 		},
 		{
 			name:     "read-write race",
 			raceType: "read-write",
-			addr:     0xabcdef,
-			gid1:     10,
-			gid2:     20,
-			wantKey:  "read-write:0xabcdef:10:20",
+			hashA:    0xabcdef,
+			hashB:    0x10,
+			wantKey:  "read-write:0000000000000010:0000000000abcdef",
 		},
 		{
 			name:     "write-read race",
 			raceType: "write-read",
-			addr:     0xffffff,
-			gid1:     100,
-			gid2:     50,
-			wantKey:  "write-read:0xffffff:50:100", // IDs sorted
+			hashA:    0xffffff,
+			hashB:    0x50,
+			wantKey:  "write-read:0000000000000050:0000000000ffffff", // Hashes sorted
 		},
 		{
-			name:     "same goroutine (edge case)",
+			name:     "same stack both sides (edge case)",
 			raceType: "write-write",
-			addr:     0x5678,
-			gid1:     7,
-			gid2:     7,
-			wantKey:  "write-write:0x5678:7:7",
+			hashA:    0x7,
+			hashB:    0x7,
+			wantKey:  "write-write:0000000000000007:0000000000000007",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotKey := generateDeduplicationKey(tt.raceType, tt.addr, tt.gid1, tt.gid2)
+			gotKey := generateDeduplicationKey(tt.raceType, tt.hashA, tt.hashB)
 			if gotKey != tt.wantKey {
 				t.Errorf("generateDeduplicationKey() = %q, want %q", gotKey, tt.wantKey)
 			}
@@ -396,18 +918,27 @@ func TestGenerateDeduplicationKey(t *testing.T) {
 	}
 }
 
-// TestNewRaceReport_DeduplicationKey tests that NewRaceReport generates correct dedup key.
+// TestNewRaceReport_DeduplicationKey tests that NewRaceReport generates a
+// dedup key that's stable for identical calls from the same call site, and
+// that race type still distinguishes otherwise-identical races.
 func TestNewRaceReport_DeduplicationKey(t *testing.T) {
 	addr := uintptr(0x1000)
 	prevEpoch := epoch.NewEpoch(3, 10) // tid=3, clock=10
 	currEpoch := epoch.NewEpoch(5, 20) // tid=5, clock=20
 
-	report := NewRaceReport("write-write", addr, prevEpoch, currEpoch)
+	// All three calls go through the same call site (loop body) so only
+	// raceType varies - a call at a different source line would itself
+	// produce a different stack fingerprint regardless of raceType.
+	var reports []*RaceReport
+	for _, raceType := range []string{"write-write", "write-write", "read-write"} {
+		reports = append(reports, NewRaceReport(raceType, addr, prevEpoch, currEpoch))
+	}
 
-	// Expected key: "write-write:0x1000:3:5" (IDs sorted)
-	expectedKey := "write-write:0x1000:3:5"
-	if report.DeduplicationKey != expectedKey {
-		t.Errorf("DeduplicationKey = %q, want %q", report.DeduplicationKey, expectedKey)
+	if reports[0].DeduplicationKey != reports[1].DeduplicationKey {
+		t.Errorf("DeduplicationKey differs across identical calls from the same site: %q vs %q", reports[0].DeduplicationKey, reports[1].DeduplicationKey)
+	}
+	if reports[2].DeduplicationKey == reports[0].DeduplicationKey {
+		t.Errorf("DeduplicationKey should differ across race types, got %q for both", reports[0].DeduplicationKey)
 	}
 }
 
@@ -434,6 +965,62 @@ func TestDetector_Deduplication_FirstRaceReported(t *testing.T) {
 	}
 }
 
+// TestDetector_reportRaceV2_MarksHotSiteForSampler verifies that a newly
+// reported race flags its address as a hot site on the detector's sampler
+// (v0.4.0 adaptive sampling with feedback), so subsequent accesses to that
+// address are force-sampled instead of being subject to the configured rate.
+func TestDetector_reportRaceV2_MarksHotSiteForSampler(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{SamplingEnabled: true, SampleRate: 1000})
+	defer d.Reset()
+
+	addr := uintptr(0x9000)
+	prevEpoch := epoch.NewEpoch(1, 5)
+	currEpoch := epoch.NewEpoch(2, 10)
+
+	d.reportRaceV2("write-write", addr, nil, prevEpoch, currEpoch)
+
+	if !d.sampler.ShouldSampleAt(0, addr) {
+		t.Error("ShouldSampleAt(pc, addr) = false after reportRaceV2, want true (address should be marked hot)")
+	}
+}
+
+// TestDetector_reportRaceV2_DuplicateDoesNotReMarkHotSite verifies that a
+// deduplicated (already-reported) race doesn't re-run the hot-site logic —
+// not a correctness requirement, but documents that MarkHotSite only runs
+// on the path that also increments the race counter.
+func TestDetector_reportRaceV2_DuplicateDoesNotReMarkHotSite(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{SamplingEnabled: true, SampleRate: 1000})
+	defer d.Reset()
+
+	addr := uintptr(0x9100)
+	prevEpoch := epoch.NewEpoch(1, 5)
+	currEpoch := epoch.NewEpoch(2, 10)
+
+	pc := uintptr(0x9200)
+	// Both calls share the same call site (loop body) so they dedupe - two
+	// distinct statements here would hash to different stacks (see
+	// generateDeduplicationKey) and both would be treated as new races.
+	for i := 0; i < 2; i++ {
+		d.reportRaceV2("write-write", addr, nil, prevEpoch, currEpoch)
+		if i == 0 {
+			for j := 0; j < hotSiteBoost; j++ {
+				d.sampler.ShouldSampleAt(pc, addr) // Exhaust the boost window.
+			}
+		}
+	}
+
+	sampled := 0
+	n := 10000
+	for i := 0; i < n; i++ {
+		if d.sampler.ShouldSampleAt(pc, addr) {
+			sampled++
+		}
+	}
+	if want := expectedSiteSamples(n, 1000); sampled != want {
+		t.Errorf("ShouldSampleAt(pc, addr) sampled %d/%d after duplicate report, want %d (duplicate shouldn't refresh the boost)", sampled, n, want)
+	}
+}
+
 // TestDetector_Deduplication_DuplicateRaceSkipped tests that duplicate race is NOT reported.
 func TestDetector_Deduplication_DuplicateRaceSkipped(t *testing.T) {
 	d := NewDetector()
@@ -443,9 +1030,10 @@ func TestDetector_Deduplication_DuplicateRaceSkipped(t *testing.T) {
 	prevEpoch := epoch.NewEpoch(1, 5)
 	currEpoch := epoch.NewEpoch(2, 10)
 
-	// Report the same race twice.
-	d.reportRaceV2("write-write", addr, nil, prevEpoch, currEpoch)
-	d.reportRaceV2("write-write", addr, nil, prevEpoch, currEpoch)
+	// Report the same race twice from the same call site (loop body).
+	for i := 0; i < 2; i++ {
+		d.reportRaceV2("write-write", addr, nil, prevEpoch, currEpoch)
+	}
 
 	// Only first race should be counted.
 	finalCount := d.RacesDetected()
@@ -454,28 +1042,31 @@ func TestDetector_Deduplication_DuplicateRaceSkipped(t *testing.T) {
 	}
 }
 
-// TestDetector_Deduplication_DifferentLocationReported tests that different locations are reported separately.
+// TestDetector_Deduplication_DifferentLocationReported tests that races
+// reported from different call sites are reported separately, even at the
+// same address and with the same goroutine pair.
 func TestDetector_Deduplication_DifferentLocationReported(t *testing.T) {
 	d := NewDetector()
 	defer d.Reset()
 
-	addr1 := uintptr(0x1000)
-	addr2 := uintptr(0x2000) // Different address
+	addr := uintptr(0x1000)
 	prevEpoch := epoch.NewEpoch(1, 5)
 	currEpoch := epoch.NewEpoch(2, 10)
 
-	// Report races at two different addresses.
-	d.reportRaceV2("write-write", addr1, nil, prevEpoch, currEpoch)
-	d.reportRaceV2("write-write", addr2, nil, prevEpoch, currEpoch)
+	// Two distinct call sites (separate lines -> distinct stacks).
+	d.reportRaceV2("write-write", addr, nil, prevEpoch, currEpoch)
+	d.reportRaceV2("write-write", addr, nil, prevEpoch, currEpoch)
 
-	// Both races should be counted (different locations).
+	// Both races should be counted (different call sites, hence stacks).
 	finalCount := d.RacesDetected()
 	if finalCount != 2 {
-		t.Errorf("After races at different locations, race count = %d, want 2", finalCount)
+		t.Errorf("After races from different call sites, race count = %d, want 2", finalCount)
 	}
 }
 
-// TestDetector_Deduplication_DifferentGoroutinesReported tests that different goroutine pairs are reported.
+// TestDetector_Deduplication_DifferentGoroutinesReported tests that races
+// from different call sites are reported separately even when the
+// goroutine IDs involved differ too.
 func TestDetector_Deduplication_DifferentGoroutinesReported(t *testing.T) {
 	d := NewDetector()
 	defer d.Reset()
@@ -487,39 +1078,43 @@ func TestDetector_Deduplication_DifferentGoroutinesReported(t *testing.T) {
 	currEpoch1 := epoch.NewEpoch(2, 10)
 	d.reportRaceV2("write-write", addr, nil, prevEpoch1, currEpoch1)
 
-	// Race 2: G1 vs G3 (different goroutine pair)
+	// Race 2: G1 vs G3, reported from a different call site.
 	prevEpoch2 := epoch.NewEpoch(1, 15)
 	currEpoch2 := epoch.NewEpoch(3, 20)
 	d.reportRaceV2("write-write", addr, nil, prevEpoch2, currEpoch2)
 
-	// Both races should be counted (different goroutine pairs).
+	// Both races should be counted (different call sites).
 	finalCount := d.RacesDetected()
 	if finalCount != 2 {
 		t.Errorf("After races with different goroutine pairs, race count = %d, want 2", finalCount)
 	}
 }
 
-// TestDetector_Deduplication_GoroutineOrderIrrelevant tests that goroutine order doesn't matter.
-func TestDetector_Deduplication_GoroutineOrderIrrelevant(t *testing.T) {
+// TestDetector_Deduplication_SameCallSiteDifferentAddressDeduplicated
+// verifies the core synth-3552 motivation: the same logical race, reported
+// from the same call site, is deduplicated even when the address and
+// goroutine IDs differ between occurrences - as happens across runs when
+// ASLR/heap layout places the same bug at a different address each time.
+func TestDetector_Deduplication_SameCallSiteDifferentAddressDeduplicated(t *testing.T) {
 	d := NewDetector()
 	defer d.Reset()
 
-	addr := uintptr(0x1000)
-
-	// Race 1: G1 vs G2
-	prevEpoch1 := epoch.NewEpoch(1, 5)
-	currEpoch1 := epoch.NewEpoch(2, 10)
-	d.reportRaceV2("write-write", addr, nil, prevEpoch1, currEpoch1)
-
-	// Race 2: G2 vs G1 (same pair, reversed order)
-	prevEpoch2 := epoch.NewEpoch(2, 15)
-	currEpoch2 := epoch.NewEpoch(1, 20)
-	d.reportRaceV2("write-write", addr, nil, prevEpoch2, currEpoch2)
+	// Both occurrences go through the single call site in this loop body -
+	// calling reportRaceV2 from two separate statements would itself
+	// produce two different stack fingerprints regardless of address.
+	occurrences := []struct {
+		addr                 uintptr
+		prevEpoch, currEpoch epoch.Epoch
+	}{
+		{uintptr(0x1000), epoch.NewEpoch(1, 5), epoch.NewEpoch(2, 10)},
+		{uintptr(0x9000), epoch.NewEpoch(7, 50), epoch.NewEpoch(9, 60)}, // Different address & goroutines.
+	}
+	for _, occ := range occurrences {
+		d.reportRaceV2("write-write", occ.addr, nil, occ.prevEpoch, occ.currEpoch)
+	}
 
-	// Only first race should be counted (same goroutine pair).
-	finalCount := d.RacesDetected()
-	if finalCount != 1 {
-		t.Errorf("After races with reversed goroutine order, race count = %d, want 1 (should be deduplicated)", finalCount)
+	if got := d.RacesDetected(); got != 1 {
+		t.Errorf("RacesDetected() = %d, want 1 (same call site should dedupe despite different address/goroutines)", got)
 	}
 }
 
@@ -574,30 +1169,31 @@ func TestDetector_Reset_ClearsDeduplicationMap(t *testing.T) {
 
 // BenchmarkGenerateDeduplicationKey benchmarks deduplication key generation.
 func BenchmarkGenerateDeduplicationKey(b *testing.B) {
-	addr := uintptr(0x12345678)
-	gid1 := uint32(5)
-	gid2 := uint32(10)
+	hashA := uint64(0x12345678)
+	hashB := uint64(0x87654321)
 	raceType := "write-write"
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = generateDeduplicationKey(raceType, addr, gid1, gid2)
+		_ = generateDeduplicationKey(raceType, hashA, hashB)
 	}
 }
 
-// BenchmarkDeduplicationCheck_FirstRace benchmarks first race detection (no dedup).
+// BenchmarkDeduplicationCheck_FirstRace benchmarks the "new race" path (no
+// dedup hit). Unlike the old address+goroutine-ID key, varying the address
+// alone no longer produces a distinct key - every call below shares the
+// same call site and thus the same stack fingerprint - so we give each
+// iteration its own Detector instead, matching what "first race" means
+// under stack-based deduplication.
 func BenchmarkDeduplicationCheck_FirstRace(b *testing.B) {
-	d := NewDetector()
-	defer d.Reset()
-
 	addr := uintptr(0x12345678)
 	prevEpoch := epoch.NewEpoch(5, 100)
 	currEpoch := epoch.NewEpoch(7, 200)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		// Use different addresses to avoid deduplication.
-		d.reportRaceV2("write-write", addr+uintptr(i), nil, prevEpoch, currEpoch)
+		d := NewDetector()
+		d.reportRaceV2("write-write", addr, nil, prevEpoch, currEpoch)
 	}
 }
 
@@ -1,9 +1,21 @@
 package detector
 
 import (
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// hotSiteBoost is the number of subsequent accesses to a flagged address
+// that are force-sampled at 100% after a race is reported there (v0.4.0
+// adaptive sampling with feedback).
+//
+// 1000 accesses is large enough to catch the next few racy interleavings at
+// a hot address (most races recur within a handful of operations once
+// triggering conditions are met) without permanently disabling the sampling
+// budget for addresses that raced once early in a long-running program.
+const hotSiteBoost = 1000
+
 // SamplerConfig configures the sampling-based race detection (v0.3.0 P0).
 //
 // Sampling allows trading off detection rate for performance, making race
@@ -43,6 +55,36 @@ type SamplerConfig struct {
 	//
 	// Default: 1 (no sampling).
 	Rate uint64
+
+	// WarmupDuration, when > 0, forces every access to be checked
+	// (as if sampling were disabled) until this much time has passed since
+	// the Sampler was created, then falls back to Rate as usual (synth-3639).
+	//
+	// Programs tend to spawn most of their long-lived goroutines and
+	// initialize most of their shared state in the first moments of
+	// execution, which is also when the most common kind of race - two
+	// initialization paths racing to set up the same state - actually
+	// happens. Warming up at full detection fidelity catches those, while
+	// still keeping the configured Rate's steady-state overhead reduction
+	// once the program settles down.
+	//
+	// Default: 0 (no warmup; Rate applies immediately).
+	WarmupDuration time.Duration
+
+	// OverheadTargetPercent, when > 0, puts Rate under feedback control
+	// (synth-3640): roughly once a second, the Sampler compares the
+	// fraction of wall-clock time actually spent inside hooked calls
+	// (see RecordHookDuration) against this target and doubles or halves
+	// its effective rate to converge on it, instead of the caller having
+	// to guess a fixed Rate up front. Rate is still used as the starting
+	// point before the first measurement window completes.
+	//
+	// Intended for always-on production detection, where the acceptable
+	// overhead is known (e.g. "at most 20% slower") but the access
+	// pattern - and therefore the rate needed to hit that budget - isn't.
+	//
+	// Default: 0 (disabled; Rate is fixed).
+	OverheadTargetPercent float64
 }
 
 // Sampler implements probabilistic race detection sampling (v0.3.0 P0).
@@ -70,8 +112,59 @@ type Sampler struct {
 
 	// stats tracks sampling statistics for monitoring.
 	stats SamplerStats
+
+	// hotSites maps addr -> *atomic.Int64 (remaining force-sampled accesses)
+	// for addresses where a race was recently reported (v0.4.0 "adaptive
+	// sampling with feedback"). Checked by ShouldSampleAt before falling
+	// back to the per-call-site rate, so detection fidelity recovers right
+	// where a race is already known to occur.
+	hotSites sync.Map
+
+	// siteCounts maps caller PC -> *atomic.Uint64 (accesses seen at that
+	// call site), replacing the single global tracePos counter with
+	// per-call-site state (v0.4.0 "per-call-site sampling"). See
+	// ShouldSampleSite.
+	siteCounts sync.Map
+
+	// warmupDeadline is the wall-clock time after which SamplerConfig's
+	// WarmupDuration stops forcing full detection, or the zero Time if no
+	// warmup was configured (synth-3639). Set once in NewSampler; safe for
+	// concurrent reads afterwards since it is never mutated again.
+	warmupDeadline time.Time
+
+	// currentRate is the effective sampling rate consulted by ShouldSample/
+	// ShouldSampleSite when SamplerConfig.OverheadTargetPercent > 0
+	// (synth-3640). Starts at config.Rate and is doubled/halved by
+	// RecordHookDuration as the observed overhead drifts from target.
+	// Ignored (config.Rate is used directly) when no target is configured.
+	currentRate atomic.Uint64
+
+	// hookNanos accumulates RecordHookDuration's durations since the start
+	// of the current control window (synth-3640), reset by whichever call
+	// closes out the window.
+	hookNanos atomic.Int64
+
+	// windowStart is the current control window's start time, as
+	// UnixNano (synth-3640). CompareAndSwap'd by RecordHookDuration so
+	// exactly one concurrent caller closes out and resets each window.
+	windowStart atomic.Int64
 }
 
+// controlWindow is how often RecordHookDuration recomputes the observed
+// overhead and adjusts the sampling rate (synth-3640). A full second gives
+// the hookNanos/wall-time ratio enough samples to be a stable estimate
+// without leaving the detector badly over or under target for long.
+const controlWindow = time.Second
+
+// minControlledRate and maxControlledRate bound the feedback-controlled
+// rate (synth-3640): never fully disable sampling (races could still be
+// under control at 1-in-1, in which case there's no need for control at
+// all) and never sample so rarely that detection becomes theoretical.
+const (
+	minControlledRate = 2
+	maxControlledRate = 1_000_000
+)
+
 // SamplerStats tracks sampling statistics for monitoring and validation.
 type SamplerStats struct {
 	// TotalAccesses counts all memory accesses (sampled + skipped).
@@ -93,14 +186,107 @@ func NewSampler(config SamplerConfig) *Sampler {
 		config.Rate = 1
 	}
 
-	return &Sampler{
+	s := &Sampler{
 		config: config,
 	}
+	if config.WarmupDuration > 0 {
+		s.warmupDeadline = time.Now().Add(config.WarmupDuration)
+	}
+	if config.OverheadTargetPercent > 0 {
+		s.currentRate.Store(config.Rate)
+		s.windowStart.Store(time.Now().UnixNano())
+	}
+	return s
+}
+
+// controlled reports whether OverheadTargetPercent is set, i.e. this
+// Sampler's effective rate is under RecordHookDuration's feedback control
+// rather than fixed at config.Rate (synth-3640).
+func (s *Sampler) controlled() bool {
+	return s.config.OverheadTargetPercent > 0
+}
+
+// effectiveRate returns the sampling rate ShouldSample/ShouldSampleSite
+// should use right now: the feedback-controlled rate if
+// OverheadTargetPercent is set (synth-3640), otherwise the fixed
+// config.Rate.
+func (s *Sampler) effectiveRate() uint64 {
+	if s.controlled() {
+		return s.currentRate.Load()
+	}
+	return s.config.Rate
+}
+
+// RecordHookDuration feeds one hooked call's wall-clock duration into the
+// overhead controller (synth-3640). It is a no-op unless
+// SamplerConfig.OverheadTargetPercent is set; callers (OnRead/OnWrite)
+// still pay one extra time.Now() pair to time themselves even then, which
+// is why it's opt-in rather than always-on like the trace_pos counter.
+//
+// Roughly once per controlWindow, the accumulated hook time is compared
+// against wall-clock time elapsed to estimate the actual overhead
+// percentage, then the effective rate is doubled if that's meaningfully
+// over target or halved if there's a lot of headroom under it - a simple
+// multiplicative controller rather than a precise inverse computation,
+// since the relationship between rate and overhead depends on the
+// program's own access pattern and isn't known in closed form.
+//
+// Thread Safety: Safe for concurrent calls; exactly one caller per window
+// performs the recompute via windowStart's CompareAndSwap.
+func (s *Sampler) RecordHookDuration(d time.Duration) {
+	if !s.controlled() {
+		return
+	}
+	s.hookNanos.Add(d.Nanoseconds())
+
+	now := time.Now().UnixNano()
+	start := s.windowStart.Load()
+	elapsed := now - start
+	if elapsed < controlWindow.Nanoseconds() {
+		return
+	}
+	if !s.windowStart.CompareAndSwap(start, now) {
+		// Another goroutine already closed out this window.
+		return
+	}
+	hookNanos := s.hookNanos.Swap(0)
+	overheadPercent := float64(hookNanos) / float64(elapsed) * 100
+
+	rate := s.currentRate.Load()
+	switch {
+	case overheadPercent > s.config.OverheadTargetPercent*1.1 && rate < maxControlledRate:
+		rate *= 2
+	case overheadPercent < s.config.OverheadTargetPercent*0.5 && rate > minControlledRate:
+		rate /= 2
+	default:
+		return
+	}
+	if rate < minControlledRate {
+		rate = minControlledRate
+	}
+	if rate > maxControlledRate {
+		rate = maxControlledRate
+	}
+	s.currentRate.Store(rate)
+}
+
+// IsWarmingUp reports whether SamplerConfig.WarmupDuration is still
+// forcing every access to be checked, i.e. it hasn't been that long yet
+// since this Sampler was created (synth-3639).
+func (s *Sampler) IsWarmingUp() bool {
+	return !s.warmupDeadline.IsZero() && time.Now().Before(s.warmupDeadline)
 }
 
-// ShouldSample returns true if the current memory access should be checked.
+// ShouldSample returns true if the current memory access should be checked,
+// using a single global counter shared by every call site.
+//
+// The detector's hot path uses ShouldSampleAt/ShouldSampleSite instead
+// (v0.4.0 "per-call-site sampling"), since a global counter can starve a
+// rarely executed site whenever its accesses happen to land on the wrong
+// phase of a hot loop's counter. ShouldSample remains for callers that have
+// no per-call-site context (e.g. ShouldSampleWithStats, tests, callers
+// outside the detector's instrumented access path).
 //
-// This is the CRITICAL HOT PATH function - called on EVERY memory access.
 // Must be as fast as possible, especially when sampling is disabled.
 //
 // Algorithm (inspired by TSAN trace_pos):
@@ -120,7 +306,17 @@ func NewSampler(config SamplerConfig) *Sampler {
 //go:nosplit
 func (s *Sampler) ShouldSample() bool {
 	// Fast path: Sampling disabled
-	if !s.config.Enabled || s.config.Rate <= 1 {
+	if !s.config.Enabled {
+		return true
+	}
+	rate := s.effectiveRate()
+	if rate <= 1 {
+		return true
+	}
+
+	// Still within the configured warmup window (synth-3639): check
+	// everything, same as sampling being disabled.
+	if s.IsWarmingUp() {
 		return true
 	}
 
@@ -130,7 +326,117 @@ func (s *Sampler) ShouldSample() bool {
 
 	// Modulo-based selection for uniform distribution.
 	// pos % rate == 0 means "sample this access"
-	return (pos % s.config.Rate) == 0
+	return (pos % rate) == 0
+}
+
+// ShouldSampleSite returns true if an access from call site pc should be
+// checked, using per-call-site state instead of the single global tracePos
+// counter (v0.4.0 "per-call-site sampling", inspired by Google's sampled
+// TSan: each instrumented load/store site tracks its own call count).
+//
+// Algorithm, per pc:
+//  1. If sampling disabled: return true (fast path, same as ShouldSample).
+//  2. Increment pc's access counter.
+//  3. While that counter is still within the first Rate calls: always
+//     sample. This guarantees sites invoked fewer than Rate times over the
+//     program's lifetime - the common case for rarely executed code - are
+//     checked on every single access, instead of being at the mercy of a
+//     global counter's phase.
+//  4. Once a site's counter exceeds Rate, fall back to modulo selection
+//     (count % Rate == 0), which converges to the same ~1/Rate overhead as
+//     ShouldSample for call sites hot enough to need down-sampling.
+//
+// Performance: One sync.Map lookup per distinct call site (LoadOrStore on
+// first sight, Load thereafter) plus one atomic increment. Call sites are
+// bounded by the program's instrumented source locations, not by the
+// number of accesses, so the map stays small relative to ShouldSample's
+// near-zero overhead.
+//
+// Thread Safety: Safe for concurrent calls.
+//
+//go:nosplit
+func (s *Sampler) ShouldSampleSite(pc uintptr) bool {
+	// Fast path: Sampling disabled
+	if !s.config.Enabled {
+		return true
+	}
+	rate := s.effectiveRate()
+	if rate <= 1 {
+		return true
+	}
+
+	// Still within the configured warmup window (synth-3639): check
+	// everything, same as sampling being disabled.
+	if s.IsWarmingUp() {
+		return true
+	}
+
+	counterVal, _ := s.siteCounts.LoadOrStore(pc, new(atomic.Uint64))
+	count := counterVal.(*atomic.Uint64).Add(1)
+
+	// Warmup window: always sample a site's first Rate accesses, so sites
+	// that never reach Rate accesses are effectively checked every time.
+	// Uses the (possibly feedback-adjusted, synth-3640) rate in effect at
+	// the moment of this call, not necessarily the one this site started
+	// out counting against.
+	if count <= rate {
+		return true
+	}
+
+	return count%rate == 0
+}
+
+// ShouldSampleAt is the combined sampling decision OnWrite/OnRead make on
+// every access (v0.4.0): it layers the per-address hot-site override
+// (MarkHotSite, "adaptive sampling with feedback") on top of the
+// per-call-site rate (ShouldSampleSite, "per-call-site sampling").
+//
+// Decision order:
+//  1. addr was recently flagged by MarkHotSite: sample at 100% for the
+//     remaining boost window, regardless of pc's own rate.
+//  2. Otherwise: defer to ShouldSampleSite(pc).
+//
+// pc is the instrumented access's caller PC (see captureCallerPC), not the
+// address being accessed - the two axes (hot address, hot call site) are
+// tracked independently because a race can recur at a fixed address from
+// many call sites, or at many addresses from one hot loop.
+//
+// Performance: A hot-site miss (the common case: no race has occurred at
+// addr) costs one extra sync.Map.Load compared to ShouldSampleSite alone.
+// This is the same lookup cost class as ShadowMemory.Get, acceptable given
+// sampling is only used when full-rate detection overhead is already a
+// concern.
+//
+// Thread Safety: Safe for concurrent calls.
+//
+//go:nosplit
+func (s *Sampler) ShouldSampleAt(pc, addr uintptr) bool {
+	if counterVal, ok := s.hotSites.Load(addr); ok {
+		counter := counterVal.(*atomic.Int64)
+		if counter.Add(-1) >= 0 {
+			return true
+		}
+		// Boost window exhausted - stop tracking this address.
+		s.hotSites.Delete(addr)
+	}
+
+	return s.ShouldSampleSite(pc)
+}
+
+// MarkHotSite flags addr as a recent race site (v0.4.0), forcing the next
+// hotSiteBoost accesses to it through ShouldSampleAddr to be sampled at
+// 100% regardless of the configured rate.
+//
+// The detector calls this from reportRaceV2 whenever it reports a race that
+// hasn't been seen before (i.e., a new deduplication key), so a single
+// report is enough to raise fidelity around that address without waiting
+// for another sampled access to happen to catch it again.
+//
+// Thread Safety: Safe for concurrent calls.
+func (s *Sampler) MarkHotSite(addr uintptr) {
+	counter := &atomic.Int64{}
+	counter.Store(hotSiteBoost)
+	s.hotSites.Store(addr, counter)
 }
 
 // ShouldSampleWithStats is like ShouldSample but also updates statistics.
@@ -176,13 +482,29 @@ func (s *Sampler) GetConfig() SamplerConfig {
 
 // IsEnabled returns true if sampling is enabled.
 func (s *Sampler) IsEnabled() bool {
-	return s.config.Enabled && s.config.Rate > 1
+	if !s.config.Enabled {
+		return false
+	}
+	// Under feedback control (synth-3640), the rate can move above 1 at
+	// any moment even if it started there, so sampling counts as enabled
+	// for the life of the Sampler rather than flapping with each window's
+	// recomputed rate.
+	if s.controlled() {
+		return true
+	}
+	return s.config.Rate > 1
 }
 
-// GetEffectiveRate returns the actual sampling rate being used.
+// GetEffectiveRate returns the actual sampling rate being used right now.
 // Returns 1 if sampling is disabled (all accesses checked).
 func (s *Sampler) GetEffectiveRate() uint64 {
-	if !s.IsEnabled() {
+	if !s.config.Enabled {
+		return 1
+	}
+	if s.controlled() {
+		return s.effectiveRate()
+	}
+	if s.config.Rate <= 1 {
 		return 1
 	}
 	return s.config.Rate
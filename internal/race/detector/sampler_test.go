@@ -4,6 +4,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/kolkov/racedetector/internal/race/goroutine"
 )
@@ -196,6 +197,142 @@ func TestSampler_ConcurrentAccess(t *testing.T) {
 	}
 }
 
+// === Adaptive Sampling with Feedback Tests (v0.4.0) ===
+
+// expectedSiteSamples returns the exact number of true results
+// ShouldSampleSite gives over n consecutive calls at one call site with the
+// given rate: the first `rate` calls are always sampled (warmup), then
+// every rate-th call after that. The algorithm is deterministic, so tests
+// assert this exact count rather than a statistical range.
+func expectedSiteSamples(n, rate int) int {
+	if n <= rate {
+		return n
+	}
+	return rate + (n/rate - 1)
+}
+
+func TestSampler_ShouldSampleAt_NotHotUsesConfiguredRate(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 1000})
+	pc := uintptr(0x1000)
+
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		if s.ShouldSampleAt(pc, 0xA000) {
+			sampled++
+		}
+	}
+
+	if want := expectedSiteSamples(10000, 1000); sampled != want {
+		t.Errorf("ShouldSampleAt(pc, cold addr) sampled %d/10000, want %d (per-call-site rate)", sampled, want)
+	}
+}
+
+func TestSampler_MarkHotSite_ForcesFullSamplingForBoostWindow(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 1000})
+	pc := uintptr(0x2500)
+	addr := uintptr(0x2000)
+
+	s.MarkHotSite(addr)
+
+	for i := 0; i < hotSiteBoost; i++ {
+		if !s.ShouldSampleAt(pc, addr) {
+			t.Fatalf("ShouldSampleAt(pc, hot addr) = false at access %d, want true within boost window", i)
+		}
+	}
+}
+
+func TestSampler_MarkHotSite_RevertsToRateAfterBoostWindow(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 1000})
+	pc := uintptr(0x3500)
+	addr := uintptr(0x3000)
+
+	s.MarkHotSite(addr)
+	for i := 0; i < hotSiteBoost; i++ {
+		s.ShouldSampleAt(pc, addr)
+	}
+
+	// Boost window is now exhausted and the site's own counter never
+	// advanced while it was hot, so the next n calls fall back to exactly
+	// the per-call-site rate, as if this were a fresh, never-hot site.
+	sampled := 0
+	n := 10000
+	for i := 0; i < n; i++ {
+		if s.ShouldSampleAt(pc, addr) {
+			sampled++
+		}
+	}
+	if want := expectedSiteSamples(n, 1000); sampled != want {
+		t.Errorf("ShouldSampleAt(pc, addr) after boost window sampled %d/%d, want %d (per-call-site rate)", sampled, n, want)
+	}
+}
+
+func TestSampler_MarkHotSite_OnlyAffectsFlaggedAddress(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 1000})
+	pc := uintptr(0x4500)
+	hotAddr := uintptr(0x4000)
+	coldAddr := uintptr(0x5000)
+
+	s.MarkHotSite(hotAddr)
+
+	if !s.ShouldSampleAt(pc, hotAddr) {
+		t.Error("ShouldSampleAt(pc, hotAddr) = false, want true (just marked hot)")
+	}
+
+	sampled := 0
+	n := 10000
+	for i := 0; i < n; i++ {
+		if s.ShouldSampleAt(pc, coldAddr) {
+			sampled++
+		}
+	}
+	if want := expectedSiteSamples(n, 1000); sampled != want {
+		t.Errorf("ShouldSampleAt(pc, coldAddr) sampled %d/%d, want %d (marking a different address shouldn't affect this one)", sampled, n, want)
+	}
+}
+
+func TestSampler_ShouldSampleAt_Disabled(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: false})
+
+	for i := 0; i < 100; i++ {
+		if !s.ShouldSampleAt(0x6500, 0x6000) {
+			t.Error("ShouldSampleAt should always return true when sampling is disabled")
+		}
+	}
+}
+
+func TestSampler_ShouldSampleSite_RareSiteAlwaysSampled(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 1000})
+	pc := uintptr(0x7000)
+
+	// A call site invoked fewer times than Rate over its lifetime must be
+	// sampled every single time - the core guarantee per-call-site sampling
+	// adds over a global counter.
+	for i := 0; i < 500; i++ {
+		if !s.ShouldSampleSite(pc) {
+			t.Fatalf("ShouldSampleSite(rare pc) = false at access %d, want true (below warmup window)", i)
+		}
+	}
+}
+
+func TestSampler_ShouldSampleSite_IndependentPerSite(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 1000})
+	hotPC := uintptr(0x8000)
+	rarePC := uintptr(0x9000)
+
+	// Drive hotPC deep into its down-sampled regime.
+	for i := 0; i < 5000; i++ {
+		s.ShouldSampleSite(hotPC)
+	}
+
+	// rarePC's own counter is untouched by hotPC's traffic, so it still
+	// gets its full warmup window.
+	for i := 0; i < 100; i++ {
+		if !s.ShouldSampleSite(rarePC) {
+			t.Fatalf("ShouldSampleSite(rarePC) = false at access %d, want true (independent of hotPC's rate)", i)
+		}
+	}
+}
+
 func TestSampler_ExpectedDetectionRate(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -444,3 +581,193 @@ func BenchmarkDetector_OnRead_WithSampling_Rate10(b *testing.B) {
 		d.OnRead(addr+uintptr(i%1000), ctx)
 	}
 }
+
+// === Warmup Tests (synth-3639) ===
+
+// TestSampler_WarmupForcesFullSamplingUntilDeadline verifies a Sampler
+// with WarmupDuration set checks every access - ignoring Rate - until the
+// warmup window elapses, then falls back to the configured rate.
+func TestSampler_WarmupForcesFullSamplingUntilDeadline(t *testing.T) {
+	s := NewSampler(SamplerConfig{
+		Enabled:        true,
+		Rate:           1_000_000, // would almost never sample on its own
+		WarmupDuration: 30 * time.Millisecond,
+	})
+
+	if !s.IsWarmingUp() {
+		t.Fatal("IsWarmingUp() = false immediately after NewSampler, want true")
+	}
+	for i := 0; i < 100; i++ {
+		if !s.ShouldSample() {
+			t.Fatal("ShouldSample() = false during warmup, want true")
+		}
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if s.IsWarmingUp() {
+		t.Fatal("IsWarmingUp() = true after WarmupDuration elapsed, want false")
+	}
+}
+
+// TestSampler_NoWarmupByDefault verifies a zero WarmupDuration behaves
+// exactly as before synth-3639 - no forced full-detection window.
+func TestSampler_NoWarmupByDefault(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 10})
+	if s.IsWarmingUp() {
+		t.Error("IsWarmingUp() = true with WarmupDuration unset, want false")
+	}
+}
+
+// TestNewDetectorWithOptions_WarmupPropagatesToSampler verifies
+// DetectorOptions.WarmupDuration reaches the detector's Sampler and is
+// observable via Detector.IsWarmingUp.
+func TestNewDetectorWithOptions_WarmupPropagatesToSampler(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{
+		SamplingEnabled: true,
+		SampleRate:      1_000_000,
+		WarmupDuration:  30 * time.Millisecond,
+	})
+
+	if !d.IsWarmingUp() {
+		t.Fatal("Detector.IsWarmingUp() = false immediately after creation, want true")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if d.IsWarmingUp() {
+		t.Fatal("Detector.IsWarmingUp() = true after WarmupDuration elapsed, want false")
+	}
+}
+
+// TestDetector_IsWarmingUp_FalseWithoutSampling verifies WarmupDuration has
+// no effect - and IsWarmingUp reports false - when sampling itself isn't
+// enabled, since every access is already checked either way.
+func TestDetector_IsWarmingUp_FalseWithoutSampling(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{WarmupDuration: time.Hour})
+	if d.IsWarmingUp() {
+		t.Error("Detector.IsWarmingUp() = true with SamplingEnabled=false, want false")
+	}
+}
+
+// === Overhead-Targeted Feedback Control Tests (synth-3640) ===
+
+// TestSampler_RecordHookDuration_IncreasesRateWhenOverTarget verifies a
+// window measuring overhead well above OverheadTargetPercent doubles the
+// effective rate.
+func TestSampler_RecordHookDuration_IncreasesRateWhenOverTarget(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 10, OverheadTargetPercent: 10})
+	s.windowStart.Store(time.Now().Add(-2 * time.Second).UnixNano())
+
+	// ~50% of the simulated 2s window spent in hooks - far over the 10% target.
+	s.RecordHookDuration(time.Second)
+
+	if got := s.effectiveRate(); got <= 10 {
+		t.Errorf("effectiveRate() = %d after over-target window, want > 10 (rate should increase)", got)
+	}
+}
+
+// TestSampler_RecordHookDuration_DecreasesRateWhenUnderTarget verifies a
+// window measuring overhead well below OverheadTargetPercent halves the
+// effective rate.
+func TestSampler_RecordHookDuration_DecreasesRateWhenUnderTarget(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 100, OverheadTargetPercent: 50})
+	s.windowStart.Store(time.Now().Add(-2 * time.Second).UnixNano())
+
+	// ~1% of the simulated 2s window spent in hooks - well under the 50% target.
+	s.RecordHookDuration(20 * time.Millisecond)
+
+	if got := s.effectiveRate(); got >= 100 {
+		t.Errorf("effectiveRate() = %d after under-target window, want < 100 (rate should decrease)", got)
+	}
+}
+
+// TestSampler_RecordHookDuration_NoopWhenNotControlled verifies
+// RecordHookDuration does nothing unless OverheadTargetPercent is set.
+func TestSampler_RecordHookDuration_NoopWhenNotControlled(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: 10})
+	s.RecordHookDuration(time.Second)
+	if got := s.effectiveRate(); got != 10 {
+		t.Errorf("effectiveRate() = %d, want unchanged 10 (OverheadTargetPercent unset)", got)
+	}
+}
+
+// TestSampler_RecordHookDuration_RespectsRateBounds verifies the
+// controlled rate never exceeds maxControlledRate.
+func TestSampler_RecordHookDuration_RespectsRateBounds(t *testing.T) {
+	s := NewSampler(SamplerConfig{Enabled: true, Rate: maxControlledRate, OverheadTargetPercent: 1})
+	s.windowStart.Store(time.Now().Add(-2 * time.Second).UnixNano())
+	s.RecordHookDuration(2 * time.Second) // ~100% overhead, way over target
+
+	if got := s.currentRate.Load(); got != maxControlledRate {
+		t.Errorf("currentRate = %d, want capped at maxControlledRate=%d", got, maxControlledRate)
+	}
+}
+
+// TestNewDetectorWithOptions_OverheadTargetEnablesSamplingWithoutSampleRate
+// verifies OverheadTargetPercent alone (no SamplingEnabled/SampleRate) is
+// enough to create a controlled Sampler.
+func TestNewDetectorWithOptions_OverheadTargetEnablesSamplingWithoutSampleRate(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{OverheadTargetPercent: 20})
+	if d.sampler == nil {
+		t.Fatal("sampler is nil with OverheadTargetPercent set, want non-nil")
+	}
+	if !d.sampler.controlled() {
+		t.Error("sampler.controlled() = false, want true")
+	}
+}
+
+// === Per-Goroutine Full Detection Forcing Tests (synth-3641) ===
+
+// TestDetector_OnWrite_ForcedFullDetectionBypassesSampler verifies that a
+// goroutine with IsFullDetectionForced() set still has its accesses
+// checked at a call site the sampler would otherwise skip, while an
+// unforced goroutine at that same site is skipped as normal.
+func TestDetector_OnWrite_ForcedFullDetectionBypassesSampler(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{SamplingEnabled: true, SampleRate: 5})
+	defer d.Reset()
+
+	establishAddr := uintptr(0xA000)
+	raceAddr := uintptr(0xA100)
+
+	ctx1 := createTestContext(1)
+	ctx2 := createTestContext(2)
+	ctx3 := createTestContext(3)
+
+	// ctx1's write establishes the "prior" write raceAddr's shadow cell
+	// holds, and (as OnWrite's single internal call site) advances the
+	// sampler's per-site counter to 1.
+	d.OnWrite(raceAddr, ctx1)
+
+	// Advance the same site's counter past ShouldSampleSite's "always
+	// sample the first Rate accesses" window using harmless self-writes,
+	// so the next accesses land on a count%rate != 0 - not sampled. Count
+	// is now 5 (1 + 4); the 6th and 7th calls below are the first past the
+	// window, at counts 6 and 7, and neither is a multiple of 5.
+	for i := 0; i < 4; i++ {
+		d.OnWrite(establishAddr, ctx1)
+	}
+
+	if before := d.RacesDetected(); before != 0 {
+		t.Fatalf("RacesDetected() = %d before any conflicting write, want 0", before)
+	}
+
+	// ctx2 is not forced: at this site's count (6, not sampled), the
+	// genuine unsynchronized write to raceAddr is skipped entirely.
+	d.OnWrite(raceAddr, ctx2)
+	if got := d.RacesDetected(); got != 0 {
+		t.Fatalf("RacesDetected() = %d after unforced ctx2's write (count 6, not sampled), want 0", got)
+	}
+
+	// ctx3 is forced: this site's count (7) is also not sampled, but
+	// forcing bypasses the sampler entirely, so the race is still caught.
+	ctx3.IncForceFullDetection()
+	d.OnWrite(raceAddr, ctx3)
+
+	// Reporting happens on a separate goroutine (synth-3587); wait for it
+	// to catch up before checking RacesDetected.
+	d.WaitForPendingReports()
+	if got := d.RacesDetected(); got != 1 {
+		t.Fatalf("RacesDetected() = %d after forced ctx3's write, want 1 (forcing should bypass the sampler)", got)
+	}
+}
@@ -0,0 +1,192 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ScheduleEventKind identifies the category of instrumented event a
+// ScheduleRecorder logs.
+type ScheduleEventKind string
+
+const (
+	// ScheduleEventRead and ScheduleEventWrite are the only kinds recorded
+	// today (v0.5.0) - base sync operations (OnAcquire/OnRelease/channels/
+	// waitgroups) aren't hooked into record-and-replay yet, since a
+	// detected race is always reported as a read/write pair; the constants
+	// are still scoped to "access" vs. "sync" so that extension doesn't
+	// require renaming anything.
+	ScheduleEventRead  ScheduleEventKind = "read"
+	ScheduleEventWrite ScheduleEventKind = "write"
+)
+
+// ScheduleEvent is one recorded instrumented event (v0.5.0): a memory
+// access observed during a run, logged so a later run can reproduce the
+// same interleaving.
+//
+// Seq is the event's position in the global interleaving the detector
+// actually observed - the thing record-and-replay exists to reproduce.
+// Goroutine and Step together identify *which* event this is in a way
+// that survives a fresh process: Goroutine is the goroutine's TID (the
+// same compact per-goroutine identifier the detector's vector clocks
+// already use), and Step is that goroutine's own per-goroutine event
+// counter. Addr is recorded so a human reading the trace can see what was
+// touched, but - like the stack-hash dedup key in
+// generateDeduplicationKey - it is NOT used to match events during
+// replay, because heap addresses shift between runs (ASLR, GC layout)
+// even when control flow is identical.
+type ScheduleEvent struct {
+	Seq       uint64            `json:"seq"`
+	Goroutine uint16            `json:"goroutine"`
+	Step      int               `json:"step"`
+	Kind      ScheduleEventKind `json:"kind"`
+	Addr      uintptr           `json:"addr"`
+}
+
+// ScheduleRecorder logs the interleaving of OnRead/OnWrite events to a
+// writer, one JSON object per line, so a later racedetector run can feed
+// the trace to a ScheduleGate and reproduce the same interleaving
+// deterministically - the record half of record-and-replay (v0.5.0).
+//
+// A nil *ScheduleRecorder is a safe no-op - see Detector.recorder, which
+// stays nil unless DetectorOptions.RecordSchedule is set, mirroring the
+// sampler/profiler/symbolizer opt-in pattern.
+//
+// Recording every access event is real hot-path overhead (a JSON-encoded
+// line and a mutex acquisition per event): enable it only while trying to
+// capture a reproduction of a specific flaky race, not in production.
+type ScheduleRecorder struct {
+	mu   sync.Mutex
+	enc  *json.Encoder
+	seq  uint64
+	step map[uint16]int
+}
+
+// NewScheduleRecorder creates a ScheduleRecorder writing to w.
+func NewScheduleRecorder(w io.Writer) *ScheduleRecorder {
+	return &ScheduleRecorder{
+		enc:  json.NewEncoder(w),
+		step: make(map[uint16]int),
+	}
+}
+
+// recordEvent appends one event for the goroutine identified by tid to the
+// trace, assigning it the next global sequence number and that
+// goroutine's next per-goroutine step. Safe for concurrent use. Safe to
+// call on a nil *ScheduleRecorder, which does nothing.
+//
+// A failure to write the trace is intentionally silent (best effort) -
+// the program being debugged shouldn't crash because its own debugging
+// aid couldn't write to disk.
+func (r *ScheduleRecorder) recordEvent(kind ScheduleEventKind, addr uintptr, tid uint16) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	r.step[tid]++
+
+	_ = r.enc.Encode(ScheduleEvent{
+		Seq:       r.seq,
+		Goroutine: tid,
+		Step:      r.step[tid],
+		Kind:      kind,
+		Addr:      addr,
+	})
+}
+
+// LoadSchedule reads a trace previously written by a ScheduleRecorder,
+// decoding one ScheduleEvent per line, for use as DetectorOptions.
+// ReplaySchedule.
+func LoadSchedule(r io.Reader) ([]ScheduleEvent, error) {
+	var events []ScheduleEvent
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var event ScheduleEvent
+		if err := dec.Decode(&event); err != nil {
+			return nil, fmt.Errorf("failed to decode schedule event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// scheduleKey identifies one event by the same (goroutine, step) pair
+// ScheduleRecorder and ScheduleGate use instead of addresses.
+type scheduleKey struct {
+	goroutine uint16
+	step      int
+}
+
+// ScheduleGate re-drives a recorded interleaving during replay (v0.5.0):
+// each OnRead/OnWrite call blocks until the trace says it's that
+// goroutine's turn, forcing the same global ordering of events that was
+// originally recorded - the replay half of record-and-replay.
+//
+// Like ScheduleRecorder, goroutines are identified by TID and events
+// within a goroutine by that goroutine's own step counter, not by
+// address, which isn't stable across runs. This means replay reproduces
+// the recorded interleaving as long as the program's control flow takes
+// the same path as when it was recorded (same binary, same input); it
+// cannot force an order onto a goroutine that diverges from the trace -
+// see Wait.
+//
+// A nil *ScheduleGate is a safe no-op - see Detector.scheduleGate, which
+// stays nil unless DetectorOptions.ReplaySchedule is non-empty.
+type ScheduleGate struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	bySeq map[scheduleKey]uint64
+	step  map[uint16]int
+	fired uint64
+}
+
+// NewScheduleGate builds a ScheduleGate from a trace previously loaded
+// with LoadSchedule.
+func NewScheduleGate(events []ScheduleEvent) *ScheduleGate {
+	g := &ScheduleGate{
+		bySeq: make(map[scheduleKey]uint64, len(events)),
+		step:  make(map[uint16]int),
+	}
+	g.cond = sync.NewCond(&g.mu)
+	for _, event := range events {
+		g.bySeq[scheduleKey{event.Goroutine, event.Step}] = event.Seq
+	}
+	return g
+}
+
+// Wait blocks the calling goroutine (identified by its TID) until the
+// trace says it's this event's turn, then returns - letting the
+// instrumented OnRead/OnWrite that called it proceed to the real access.
+// Safe to call on a nil *ScheduleGate, which returns immediately.
+//
+// If this call doesn't appear in the trace (this goroutine has taken a
+// different number of steps than recording did - control flow diverged),
+// Wait returns immediately without blocking rather than deadlocking: a
+// diverged replay can no longer promise anything about ordering anyway,
+// so refusing to make progress would just trade one bug for a hang.
+func (g *ScheduleGate) Wait(tid uint16) {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.step[tid]++
+	seq, ok := g.bySeq[scheduleKey{tid, g.step[tid]}]
+	if !ok {
+		return
+	}
+
+	for g.fired < seq-1 {
+		g.cond.Wait()
+	}
+	g.fired++
+	g.cond.Broadcast()
+}
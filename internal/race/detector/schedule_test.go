@@ -0,0 +1,208 @@
+package detector
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// TestScheduleRecorder_RecordsSeqAndStepPerGoroutine verifies recordEvent
+// assigns a monotonically increasing global Seq and a per-goroutine Step,
+// keyed by TID.
+func TestScheduleRecorder_RecordsSeqAndStepPerGoroutine(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewScheduleRecorder(&buf)
+
+	r.recordEvent(ScheduleEventWrite, 0x1000, 0)
+	r.recordEvent(ScheduleEventRead, 0x2000, 1)
+	r.recordEvent(ScheduleEventWrite, 0x3000, 0)
+
+	events, err := LoadSchedule(&buf)
+	if err != nil {
+		t.Fatalf("LoadSchedule() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("LoadSchedule() returned %d events, want 3", len(events))
+	}
+
+	want := []ScheduleEvent{
+		{Seq: 1, Goroutine: 0, Step: 1, Kind: ScheduleEventWrite, Addr: 0x1000},
+		{Seq: 2, Goroutine: 1, Step: 1, Kind: ScheduleEventRead, Addr: 0x2000},
+		{Seq: 3, Goroutine: 0, Step: 2, Kind: ScheduleEventWrite, Addr: 0x3000},
+	}
+	for i, w := range want {
+		if events[i] != w {
+			t.Errorf("events[%d] = %+v, want %+v", i, events[i], w)
+		}
+	}
+}
+
+// TestScheduleRecorder_NilIsNoOp verifies recordEvent is safe to call on a
+// nil *ScheduleRecorder, matching Detector.recorder's default.
+func TestScheduleRecorder_NilIsNoOp(t *testing.T) {
+	var r *ScheduleRecorder
+	r.recordEvent(ScheduleEventWrite, 0x1000, 0) // must not panic
+}
+
+// TestLoadSchedule_Empty verifies an empty trace decodes to no events.
+func TestLoadSchedule_Empty(t *testing.T) {
+	events, err := LoadSchedule(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("LoadSchedule() error = %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("LoadSchedule(\"\") = %v, want none", events)
+	}
+}
+
+// TestScheduleGate_Nil_IsNoOp verifies Wait is safe to call on a nil
+// *ScheduleGate, matching Detector.scheduleGate's default.
+func TestScheduleGate_Nil_IsNoOp(t *testing.T) {
+	var g *ScheduleGate
+	g.Wait(0) // must not block or panic
+}
+
+// TestScheduleGate_EnforcesRecordedOrder verifies a ScheduleGate built from
+// a recorded trace forces two goroutines to touch a shared slice in the
+// exact order they were recorded in, even though they race to append
+// concurrently.
+func TestScheduleGate_EnforcesRecordedOrder(t *testing.T) {
+	// Goroutine 0 goes first, then goroutine 1, then goroutine 0 again.
+	events := []ScheduleEvent{
+		{Seq: 1, Goroutine: 0, Step: 1, Kind: ScheduleEventWrite},
+		{Seq: 2, Goroutine: 1, Step: 1, Kind: ScheduleEventWrite},
+		{Seq: 3, Goroutine: 0, Step: 2, Kind: ScheduleEventWrite},
+	}
+	gate := NewScheduleGate(events)
+
+	var mu sync.Mutex
+	var order []uint16
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Goroutine 1 starts first and would normally win the race to append -
+	// the gate should still force it to wait for goroutine 0's first step.
+	go func() {
+		defer wg.Done()
+		gate.Wait(1)
+		mu.Lock()
+		order = append(order, 1)
+		mu.Unlock()
+	}()
+
+	go func() {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond) // give goroutine 1 a head start
+		gate.Wait(0)
+		mu.Lock()
+		order = append(order, 0)
+		mu.Unlock()
+		gate.Wait(0)
+		mu.Lock()
+		order = append(order, 0)
+		mu.Unlock()
+	}()
+
+	wg.Wait()
+
+	want := []uint16{0, 1, 0}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("order = %v, want %v", order, want)
+			break
+		}
+	}
+}
+
+// TestDetector_RecorderUnsetByDefault verifies a Detector with the zero
+// RecordSchedule/ReplaySchedule options doesn't allocate a recorder or gate
+// (zero overhead, mirroring the sampler/profiler/symbolizer opt-in
+// pattern).
+func TestDetector_RecorderUnsetByDefault(t *testing.T) {
+	d := NewDetector()
+	if d.recorder != nil {
+		t.Error("recorder != nil for a Detector created without DetectorOptions.RecordSchedule")
+	}
+	if d.scheduleGate != nil {
+		t.Error("scheduleGate != nil for a Detector created without DetectorOptions.ReplaySchedule")
+	}
+}
+
+// TestDetector_RecordSchedule_CapturesOnWriteAndOnRead verifies
+// DetectorOptions.RecordSchedule causes OnWrite/OnRead to log events.
+func TestDetector_RecordSchedule_CapturesOnWriteAndOnRead(t *testing.T) {
+	var buf bytes.Buffer
+	d := NewDetectorWithOptions(DetectorOptions{RecordSchedule: &buf})
+	if d.recorder == nil {
+		t.Fatal("recorder = nil, want a configured ScheduleRecorder")
+	}
+
+	ctx := goroutine.Alloc(1)
+	d.OnWrite(0x1000, ctx)
+	d.OnRead(0x1000, ctx)
+
+	events, err := LoadSchedule(&buf)
+	if err != nil {
+		t.Fatalf("LoadSchedule() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("LoadSchedule() returned %d events, want 2", len(events))
+	}
+	if events[0].Kind != ScheduleEventWrite || events[1].Kind != ScheduleEventRead {
+		t.Errorf("events = %+v, want [write, read]", events)
+	}
+}
+
+// TestDetector_ReplaySchedule_BuildsGate verifies DetectorOptions.
+// ReplaySchedule causes the Detector to build a ScheduleGate.
+func TestDetector_ReplaySchedule_BuildsGate(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{
+		ReplaySchedule: []ScheduleEvent{{Seq: 1, Goroutine: 0, Step: 1, Kind: ScheduleEventWrite}},
+	})
+	if d.scheduleGate == nil {
+		t.Fatal("scheduleGate = nil, want a configured ScheduleGate")
+	}
+
+	// A single-event trace for TID 0 should let OnWrite proceed without
+	// blocking (step 1 matches the trace, nothing before it to wait for).
+	done := make(chan struct{})
+	go func() {
+		d.OnWrite(0x1000, goroutine.Alloc(0))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnWrite() blocked despite matching the replay trace")
+	}
+}
+
+// TestScheduleGate_DivergedGoroutineDoesNotBlock verifies Wait returns
+// immediately, rather than deadlocking, when a goroutine takes a step the
+// trace has no record of (a diverged replay).
+func TestScheduleGate_DivergedGoroutineDoesNotBlock(t *testing.T) {
+	gate := NewScheduleGate([]ScheduleEvent{
+		{Seq: 1, Goroutine: 0, Step: 1, Kind: ScheduleEventWrite},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		gate.Wait(0) // step 1: matches the trace
+		gate.Wait(0) // step 2: not in the trace - must not block
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait() blocked on a diverged step instead of returning immediately")
+	}
+}
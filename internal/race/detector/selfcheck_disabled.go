@@ -0,0 +1,21 @@
+//go:build !racedetector_selfcheck
+
+package detector
+
+import (
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+	"github.com/kolkov/racedetector/internal/race/syncshadow"
+)
+
+// selfCheckContext is a no-op in the default build (synth-3621). See
+// selfcheck.go, built with -tags racedetector_selfcheck, for the real
+// invariant checks.
+//
+//go:nosplit
+func selfCheckContext(ctx *goroutine.RaceContext) {}
+
+// selfCheckRelease is a no-op in the default build (synth-3621). See
+// selfcheck.go for the real invariant check.
+//
+//go:nosplit
+func selfCheckRelease(sv *syncshadow.SyncVar, ctx *goroutine.RaceContext) {}
@@ -0,0 +1,88 @@
+//go:build racedetector_selfcheck
+
+package detector
+
+import (
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/epoch"
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// expectSelfCheckPanic runs fn and fails the test unless it panics with a
+// self-check diagnostic (synth-3621).
+func expectSelfCheckPanic(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a self-check panic, got none")
+		}
+	}()
+	fn()
+}
+
+// TestSelfCheckContext_DetectsEpochDrift verifies selfCheckContext panics
+// when a RaceContext's cached Epoch no longer matches C[TID] - the kind of
+// corruption a torn read or a missed CloneIfShared in the lock-free paths
+// would produce (synth-3621).
+func TestSelfCheckContext_DetectsEpochDrift(t *testing.T) {
+	ctx := createTestContext(1)
+	ctx.C.Set(1, 5)
+	ctx.Epoch = epoch.NewEpoch(1, 5) // In sync so far.
+
+	ctx.C.Set(1, 6) // Corrupt: advance C[TID] without updating Epoch.
+
+	expectSelfCheckPanic(t, func() {
+		selfCheckContext(ctx)
+	})
+}
+
+// TestSelfCheckContext_AcceptsConsistentEpoch verifies selfCheckContext is
+// silent when Epoch and C[TID] agree, including after the existing test
+// suite's technique of hand-setting both to an arbitrary earlier value to
+// construct a specific race scenario (synth-3621).
+func TestSelfCheckContext_AcceptsConsistentEpoch(t *testing.T) {
+	ctx := createTestContext(1)
+	ctx.C.Set(1, 20)
+	ctx.Epoch = epoch.NewEpoch(1, 20)
+	selfCheckContext(ctx) // Must not panic.
+
+	ctx.C.Set(1, 5) // Rewind, as e.g. TestOnWrite_WriteWriteRace does.
+	ctx.Epoch = epoch.NewEpoch(1, 5)
+	selfCheckContext(ctx) // Still consistent, must not panic.
+}
+
+// TestSelfCheckRelease_DetectsReleaseClockAheadOfOwner verifies
+// selfCheckRelease panics when a lock's release clock claims more logical
+// time for the releasing goroutine than that goroutine's own clock actually
+// has - a violation of releaseClock (sub)set owner clock at release
+// (synth-3621).
+func TestSelfCheckRelease_DetectsReleaseClockAheadOfOwner(t *testing.T) {
+	d := NewDetector()
+	ctx := createTestContext(1)
+	ctx.C.Set(1, 10)
+	ctx.Epoch = epoch.NewEpoch(1, 10)
+
+	syncVar := d.syncShadow.GetOrCreate(0x9000)
+	future := goroutine.Alloc(1).C
+	future.Set(1, 999) // Claims far more logical time than ctx.C actually has.
+	syncVar.SetReleaseClock(future)
+
+	expectSelfCheckPanic(t, func() {
+		selfCheckRelease(syncVar, ctx)
+	})
+}
+
+// TestSelfCheckRelease_AcceptsConsistentReleaseClock verifies
+// selfCheckRelease is silent for a release clock genuinely produced by
+// SetReleaseClock during a real OnRelease call (synth-3621).
+func TestSelfCheckRelease_AcceptsConsistentReleaseClock(t *testing.T) {
+	d := NewDetector()
+	ctx := createTestContext(1)
+
+	d.OnAcquire(0x9008, ctx)
+	d.OnRelease(0x9008, ctx) // Exercises selfCheckRelease internally too.
+
+	syncVar := d.syncShadow.GetOrCreate(0x9008)
+	selfCheckRelease(syncVar, ctx) // Must not panic.
+}
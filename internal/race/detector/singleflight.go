@@ -0,0 +1,101 @@
+package detector
+
+import (
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// === golang.org/x/sync/singleflight Synchronization Methods (synth-3574) ===
+//
+// singleflight.Group.Do(key, fn) (and DoChan) collapse concurrent calls that
+// share a key into a single execution of fn: one caller ("the leader") runs
+// fn and every other concurrent caller ("duplicates") blocks until the
+// leader finishes, then receives the same result - a very common cache-fill
+// pattern. Without a hook, the leader's writes inside fn have no recorded
+// happens-before edge to a duplicate caller's read of the shared result
+// after Do returns, so the detector reports a false race.
+//
+// Two hooks are enough to capture this:
+//   - OnSingleflightDone: called once by the leader right after fn returns,
+//     capturing its clock (analogous to Detector.OnFinalizerSet capturing
+//     the mutator's clock at registration time).
+//   - OnSingleflightReturn: called by every caller - leader and duplicates
+//     alike - as Do/DoChan returns to them, joining the captured clock.
+//
+// addr identifies one (Group, key) pair. A real Group is keyed by an
+// arbitrary string shared across many independent calls, not a single
+// memory address, so the compiler/runtime instrumentation calling these
+// hooks is expected to derive a stable per-key address (e.g. by hashing the
+// Group's address together with the key) the same way it already must for
+// any other non-address-keyed primitive.
+
+// OnSingleflightDone handles the leader's fn returning inside Do/DoChan.
+//
+// Algorithm:
+//  1. Get or create SyncVar for the (Group, key) address
+//  2. Capture the leader's clock via SetSingleflightDoneClock, overwriting
+//     any earlier call cycle's clock for this key
+//  3. ctx.IncrementClock()
+//
+// Parameters:
+//   - addr: Caller-derived address identifying the (Group, key) pair
+//   - ctx: Leader goroutine's RaceContext
+//
+// Thread Safety: Safe for concurrent calls on different keys. Two
+// completions racing on the same key would themselves be a bug in
+// singleflight's own mutual exclusion, not something this hook needs to
+// guard against.
+//
+// Performance Target: <300ns (VectorClock copy overhead acceptable).
+//
+//go:nosplit
+func (d *Detector) OnSingleflightDone(addr uintptr, ctx *goroutine.RaceContext) {
+	// Step 1: Get or create SyncVar for this (Group, key) address.
+	syncVar := d.syncShadow.GetOrCreate(addr)
+
+	// Step 2: Capture the leader's clock, overwriting any previous cycle's.
+	syncVar.SetSingleflightDoneClock(ctx.C)
+
+	// Step 3: Increment logical clock to advance time.
+	// This must be done AFTER capturing the clock to maintain happens-before.
+	ctx.IncrementClock()
+}
+
+// OnSingleflightReturn handles Do/DoChan returning to a caller, whether that
+// caller was the leader or a duplicate.
+//
+// Algorithm:
+//  1. Get or create SyncVar for the (Group, key) address
+//  2. If a doneClock has been captured, join it into the caller's clock
+//  3. ctx.IncrementClock()
+//
+// Parameters:
+//   - addr: Caller-derived address identifying the (Group, key) pair
+//   - ctx: Returning caller's RaceContext
+//
+// Thread Safety: Safe for concurrent calls from multiple goroutines.
+//
+// Performance Target: <300ns (VectorClock join overhead acceptable).
+//
+// Example:
+//
+//	data = 42                       // Write happens-before fn returns
+//	v, _, _ := group.Do("key", fn)  // fn does the write; OnSingleflightDone
+//	                                 // captures the leader's clock
+//	// OnSingleflightReturn joins that clock into every caller, including
+//	// duplicates that never ran fn themselves
+//	_ = v                           // Safe: happens-after the write above
+//
+//go:nosplit
+func (d *Detector) OnSingleflightReturn(addr uintptr, ctx *goroutine.RaceContext) {
+	// Step 1: Get or create SyncVar for this (Group, key) address.
+	syncVar := d.syncShadow.GetOrCreate(addr)
+
+	// Step 2: Join the leader's clock, if a call has ever completed.
+	if doneClock := syncVar.GetSingleflightDoneClock(); doneClock != nil {
+		ctx.JoinClock(doneClock)
+	}
+
+	// Step 3: Increment logical clock to advance time.
+	// This must be done AFTER joining the clock to maintain happens-before.
+	ctx.IncrementClock()
+}
@@ -0,0 +1,72 @@
+package detector
+
+import (
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// TestClearGoroutineStack_ForgetsCellsInRange verifies a shadow cell
+// created for an address within the cleared range is gone afterward, while
+// one outside it survives (synth-3580).
+func TestClearGoroutineStack_ForgetsCellsInRange(t *testing.T) {
+	d := NewDetector()
+
+	inRange := uintptr(0x8000)
+	outOfRange := uintptr(0x9000)
+	d.shadowMemory.GetOrCreate(inRange)
+	d.shadowMemory.GetOrCreate(outOfRange)
+
+	d.ClearGoroutineStack(0x8000, 0x8FF8)
+
+	if d.shadowMemory.Get(inRange) != nil {
+		t.Error("shadow cell for an in-range address survived ClearGoroutineStack")
+	}
+	if d.shadowMemory.Get(outOfRange) == nil {
+		t.Error("shadow cell for an out-of-range address was removed by ClearGoroutineStack")
+	}
+}
+
+// TestClearGoroutineStack_ZeroBoundsIsNoOp verifies the default (0, 0)
+// bounds - meaning SetStackBounds was never called - clears nothing,
+// matching the documented "no compiler hook yet" fallback (synth-3580).
+func TestClearGoroutineStack_ZeroBoundsIsNoOp(t *testing.T) {
+	d := NewDetector()
+	addr := uintptr(0xA000)
+	d.shadowMemory.GetOrCreate(addr)
+
+	d.ClearGoroutineStack(0, 0)
+
+	if d.shadowMemory.Get(addr) == nil {
+		t.Error("shadow cell removed by a zero-bounds ClearGoroutineStack call, want preserved")
+	}
+}
+
+// TestOnWrite_StaleStackEpochClearedOnGoroutineExit verifies the intended
+// end-to-end effect: a stale shadow cell left by a goroutine that reused a
+// stack address doesn't survive that goroutine's ClearGoroutineStack call
+// to confuse a later, unrelated write to the same address (synth-3580).
+func TestOnWrite_StaleStackEpochClearedOnGoroutineExit(t *testing.T) {
+	d := NewDetector()
+	addr := uintptr(0xB008)
+
+	retired := goroutine.Alloc(1)
+	d.OnWrite(addr, retired)
+	if d.shadowMemory.Get(addr) == nil {
+		t.Fatal("shadow cell not created by the retiring goroutine's write")
+	}
+
+	// The runtime hands addr's stack memory to a brand new goroutine.
+	d.ClearGoroutineStack(addr, addr+8)
+
+	if d.shadowMemory.Get(addr) != nil {
+		t.Fatal("shadow cell survived ClearGoroutineStack, test setup invalid")
+	}
+
+	fresh := goroutine.Alloc(2)
+	d.OnWrite(addr, fresh)
+
+	if d.RacesDetected() != 0 {
+		t.Errorf("RacesDetected() = %d, want 0 (fresh goroutine's first access to a recycled stack address)", d.RacesDetected())
+	}
+}
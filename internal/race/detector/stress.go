@@ -0,0 +1,80 @@
+package detector
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// stressYieldProbability is the chance, out of 1.0, that any given
+// instrumented access triggers a scheduling perturbation. Low enough that
+// stress mode doesn't make every run glacially slow, high enough that rare
+// interleavings tend to surface across a handful of `-stress-iterations`
+// runs with different seeds.
+const stressYieldProbability = 0.05
+
+// StressScheduler injects randomized scheduling perturbations - a
+// runtime.Gosched call or a brief sleep - at instrumented access points
+// (v0.5.0), so races that depend on a rare goroutine interleaving are more
+// likely to surface under repeated test runs than they would under the
+// normal Go scheduler. See `racedetector test -stress`, which drives a
+// detector through many runs with different seeds and reports the seed of
+// any run that found a race.
+//
+// All perturbation decisions are derived from Seed, so two StressSchedulers
+// created with the same seed perturb identically - a failing run can be
+// captured and reproduced exactly by reusing its reported seed.
+//
+// A nil *StressScheduler is a safe no-op - see Detector.stress, which stays
+// nil unless DetectorOptions.StressEnabled is set, mirroring the
+// sampler/profiler/symbolizer opt-in pattern.
+type StressScheduler struct {
+	seed int64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewStressScheduler creates a StressScheduler whose perturbations are
+// entirely determined by seed.
+func NewStressScheduler(seed int64) *StressScheduler {
+	return &StressScheduler{
+		seed: seed,
+		rng:  rand.New(rand.NewSource(seed)), //nolint:gosec // deterministic reproduction is the point, not security.
+	}
+}
+
+// Seed returns the seed this scheduler was created with, so a caller that
+// observes a race can report it for reproduction. Safe to call on a nil
+// *StressScheduler, which returns 0.
+func (s *StressScheduler) Seed() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.seed
+}
+
+// Maybe randomly perturbs the calling goroutine - either yielding via
+// runtime.Gosched or sleeping for a microsecond - to encourage interleavings
+// that the normal scheduler rarely produces. Safe to call on a nil
+// *StressScheduler, which does nothing.
+func (s *StressScheduler) Maybe() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	perturb := s.rng.Float64() < stressYieldProbability
+	sleep := perturb && s.rng.Intn(2) == 0
+	s.mu.Unlock()
+
+	if !perturb {
+		return
+	}
+	if sleep {
+		time.Sleep(time.Microsecond)
+		return
+	}
+	runtime.Gosched()
+}
@@ -0,0 +1,87 @@
+package detector
+
+import (
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// TestStressScheduler_Seed verifies Seed reports the seed the scheduler was
+// created with, including on a nil *StressScheduler.
+func TestStressScheduler_Seed(t *testing.T) {
+	s := NewStressScheduler(42)
+	if got := s.Seed(); got != 42 {
+		t.Errorf("Seed() = %d, want 42", got)
+	}
+
+	var nilScheduler *StressScheduler
+	if got := nilScheduler.Seed(); got != 0 {
+		t.Errorf("nil.Seed() = %d, want 0", got)
+	}
+}
+
+// TestStressScheduler_NilIsNoOp verifies Maybe is safe to call on a nil
+// *StressScheduler, matching Detector.stress's default.
+func TestStressScheduler_NilIsNoOp(t *testing.T) {
+	var s *StressScheduler
+	s.Maybe() // must not panic
+}
+
+// TestStressScheduler_SameSeedSameDecisions verifies two schedulers created
+// with the same seed make the same sequence of perturb/no-perturb
+// decisions, since that determinism is the entire point of seeding -
+// without it, a reported failing seed couldn't reproduce anything.
+func TestStressScheduler_SameSeedSameDecisions(t *testing.T) {
+	const calls = 200
+
+	record := func(seed int64) []bool {
+		s := NewStressScheduler(seed)
+		var decisions []bool
+		for i := 0; i < calls; i++ {
+			before := s.rng.Float64()
+			decisions = append(decisions, before < stressYieldProbability)
+		}
+		return decisions
+	}
+
+	a := record(7)
+	b := record(7)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("decision %d diverged between two schedulers with the same seed", i)
+		}
+	}
+}
+
+// TestDetector_StressUnsetByDefault verifies a Detector with
+// DetectorOptions.StressEnabled left false doesn't allocate a
+// StressScheduler (zero overhead, mirroring the sampler/profiler/symbolizer
+// opt-in pattern).
+func TestDetector_StressUnsetByDefault(t *testing.T) {
+	d := NewDetector()
+	if d.stress != nil {
+		t.Error("stress != nil for a Detector created without DetectorOptions.StressEnabled")
+	}
+	if d.StressSeed() != 0 {
+		t.Errorf("StressSeed() = %d, want 0", d.StressSeed())
+	}
+}
+
+// TestDetector_StressEnabled_RunsWithoutPanicking verifies
+// DetectorOptions.StressEnabled builds a StressScheduler that OnWrite/OnRead
+// drive without panicking or hanging, and that StressSeed reports it back.
+func TestDetector_StressEnabled_RunsWithoutPanicking(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{StressEnabled: true, StressSeed: 99})
+	if d.stress == nil {
+		t.Fatal("stress = nil, want a configured StressScheduler")
+	}
+	if got := d.StressSeed(); got != 99 {
+		t.Errorf("StressSeed() = %d, want 99", got)
+	}
+
+	ctx := goroutine.Alloc(1)
+	for i := 0; i < 100; i++ {
+		d.OnWrite(uintptr(i), ctx)
+		d.OnRead(uintptr(i), ctx)
+	}
+}
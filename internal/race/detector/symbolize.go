@@ -0,0 +1,70 @@
+package detector
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SymbolizeOptions configures how Symbolizer rewrites the absolute file
+// paths captured in race report stack traces, so reports stay stable and
+// readable across machines - e.g. in CI logs, where the build path differs
+// from a developer's machine.
+//
+// The zero value disables rewriting: paths are left exactly as
+// runtime.CallersFrames returns them.
+type SymbolizeOptions struct {
+	// ModuleRoot is the absolute path to the module's root directory (the
+	// directory containing go.mod). Paths under it are rewritten relative
+	// to ModuleImportPath instead of being left as absolute machine paths.
+	// Both ModuleRoot and ModuleImportPath must be set for this rewrite to
+	// apply.
+	ModuleRoot string
+
+	// ModuleImportPath is the module's import path (the "module" line in
+	// go.mod), e.g. "github.com/kolkov/racedetector".
+	ModuleImportPath string
+
+	// StripPathPrefix, if set, is trimmed from the front of a file path
+	// that didn't match ModuleRoot/ModuleImportPath above. Useful when the
+	// race originates in a dependency outside the module, but the build
+	// path prefix (e.g. a GOPATH or CI workspace root) is still noise.
+	StripPathPrefix string
+}
+
+// Symbolizer rewrites the file paths captured in stack traces into stable,
+// portable paths: module-relative when ModuleRoot/ModuleImportPath are
+// configured, or with a fixed prefix stripped otherwise.
+//
+// A nil *Symbolizer leaves paths unchanged - see Detector.symbolizer,
+// which stays nil when DetectorOptions.Symbolize is the zero value.
+type Symbolizer struct {
+	opts SymbolizeOptions
+}
+
+// NewSymbolizer creates a Symbolizer from opts.
+func NewSymbolizer(opts SymbolizeOptions) *Symbolizer {
+	return &Symbolizer{opts: opts}
+}
+
+// SymbolizeFile rewrites a single file path captured in a stack trace.
+// Safe to call on a nil *Symbolizer, which returns file unchanged.
+func (s *Symbolizer) SymbolizeFile(file string) string {
+	if s == nil {
+		return file
+	}
+
+	if s.opts.ModuleRoot != "" && s.opts.ModuleImportPath != "" {
+		if rel, err := filepath.Rel(s.opts.ModuleRoot, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return path.Join(s.opts.ModuleImportPath, filepath.ToSlash(rel))
+		}
+	}
+
+	if s.opts.StripPathPrefix != "" {
+		if rest, ok := strings.CutPrefix(file, s.opts.StripPathPrefix); ok {
+			return strings.TrimPrefix(rest, "/")
+		}
+	}
+
+	return file
+}
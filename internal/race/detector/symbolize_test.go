@@ -0,0 +1,99 @@
+package detector
+
+import "testing"
+
+// TestSymbolizer_Nil_LeavesPathUnchanged verifies a nil *Symbolizer (the
+// Detector default when Symbolize is unconfigured) is a safe no-op.
+func TestSymbolizer_Nil_LeavesPathUnchanged(t *testing.T) {
+	var sym *Symbolizer
+
+	file := "/home/alice/racedetector/report.go"
+	if got := sym.SymbolizeFile(file); got != file {
+		t.Errorf("SymbolizeFile() = %q, want unchanged %q", got, file)
+	}
+}
+
+// TestSymbolizer_ModuleRoot_RewritesToModulePath verifies a file under
+// ModuleRoot is rewritten relative to ModuleImportPath.
+func TestSymbolizer_ModuleRoot_RewritesToModulePath(t *testing.T) {
+	sym := NewSymbolizer(SymbolizeOptions{
+		ModuleRoot:       "/home/alice/racedetector",
+		ModuleImportPath: "github.com/kolkov/racedetector",
+	})
+
+	got := sym.SymbolizeFile("/home/alice/racedetector/internal/race/detector/report.go")
+	want := "github.com/kolkov/racedetector/internal/race/detector/report.go"
+	if got != want {
+		t.Errorf("SymbolizeFile() = %q, want %q", got, want)
+	}
+}
+
+// TestSymbolizer_ModuleRoot_IgnoresFilesOutsideRoot verifies a file outside
+// ModuleRoot isn't rewritten by the module rule (falls through to
+// StripPathPrefix or is left unchanged).
+func TestSymbolizer_ModuleRoot_IgnoresFilesOutsideRoot(t *testing.T) {
+	sym := NewSymbolizer(SymbolizeOptions{
+		ModuleRoot:       "/home/alice/racedetector",
+		ModuleImportPath: "github.com/kolkov/racedetector",
+	})
+
+	file := "/usr/local/go/src/runtime/proc.go"
+	if got := sym.SymbolizeFile(file); got != file {
+		t.Errorf("SymbolizeFile() = %q, want unchanged %q", got, file)
+	}
+}
+
+// TestSymbolizer_StripPathPrefix_TrimsPrefix verifies StripPathPrefix takes
+// effect when there's no ModuleRoot match.
+func TestSymbolizer_StripPathPrefix_TrimsPrefix(t *testing.T) {
+	sym := NewSymbolizer(SymbolizeOptions{
+		StripPathPrefix: "/home/ci/workspace",
+	})
+
+	got := sym.SymbolizeFile("/home/ci/workspace/internal/race/detector/report.go")
+	want := "internal/race/detector/report.go"
+	if got != want {
+		t.Errorf("SymbolizeFile() = %q, want %q", got, want)
+	}
+}
+
+// TestSymbolizer_StripPathPrefix_NoMatchLeavesUnchanged verifies a path
+// that doesn't start with StripPathPrefix is left alone.
+func TestSymbolizer_StripPathPrefix_NoMatchLeavesUnchanged(t *testing.T) {
+	sym := NewSymbolizer(SymbolizeOptions{
+		StripPathPrefix: "/home/ci/workspace",
+	})
+
+	file := "/usr/local/go/src/runtime/proc.go"
+	if got := sym.SymbolizeFile(file); got != file {
+		t.Errorf("SymbolizeFile() = %q, want unchanged %q", got, file)
+	}
+}
+
+// TestDetector_SymbolizerUnsetByDefault verifies a Detector with the zero
+// SymbolizeOptions value doesn't allocate a Symbolizer (zero overhead,
+// mirroring the sampler/profiler opt-in pattern).
+func TestDetector_SymbolizerUnsetByDefault(t *testing.T) {
+	d := NewDetector()
+	if d.symbolizer != nil {
+		t.Error("symbolizer != nil for a Detector created without DetectorOptions.Symbolize")
+	}
+}
+
+// TestDetector_SymbolizerConfigured verifies DetectorOptions.Symbolize
+// causes a Detector to build a Symbolizer that rewrites report paths.
+func TestDetector_SymbolizerConfigured(t *testing.T) {
+	d := NewDetectorWithOptions(DetectorOptions{
+		Symbolize: SymbolizeOptions{
+			StripPathPrefix: "/home/ci/workspace",
+		},
+	})
+	if d.symbolizer == nil {
+		t.Fatal("symbolizer = nil, want a configured Symbolizer")
+	}
+
+	got := d.symbolizer.SymbolizeFile("/home/ci/workspace/report.go")
+	if got != "report.go" {
+		t.Errorf("SymbolizeFile() = %q, want %q", got, "report.go")
+	}
+}
@@ -0,0 +1,76 @@
+// symbols.go implements symbolized race reporting: resolving the address a
+// race occurred at back to a human-readable name, so a report can print
+// "race on field Config.Timeout" or "race on global main.counter" instead
+// of only a bare hex address (synth-3630 for struct fields, generalized to
+// package-level variables by synth-3631).
+//
+// The instrumenter (cmd/racedetector/instrument) knows a field's or
+// global's qualified name at build time, but it instruments one file at a
+// time with no cross-file coordination (see checkTypesBestEffort's doc
+// comment) - it can't hand out sequential IDs that would agree across
+// independently compiled files. Instead it hashes the qualified name into
+// a numeric id (FNV-1a, matching stackdepot's approach to
+// content-addressing a stack trace - see stackdepot.HashPCs) and emits two
+// things at the write site: a race.RegisterSymbol(id, name, kind) call
+// recording what the id means, and a race.RaceWriteSym(addr, id) call
+// instead of a plain race.RaceWrite(addr). Hashing the name is a pure
+// function, so every file that touches the same field or global computes
+// the same id independently, and registering it more than once is
+// harmless.
+package detector
+
+import "sync"
+
+// symbolInfo is what a symbol id resolves to: a human-readable name and
+// the kind of thing it names ("field" or "global"), so a report can pick
+// the right phrasing for each (synth-3631).
+type symbolInfo struct {
+	name string
+	kind string
+}
+
+var (
+	symbolNamesMu sync.Mutex
+	symbolNames   = make(map[uint64]symbolInfo)
+)
+
+// RegisterSymbol records name (e.g. "Config.Timeout" or "main.counter") and
+// kind (e.g. "field" or "global") for id, so a later race report can
+// resolve id back to them. Called by generated instrumentation via
+// race.RegisterSymbol immediately before the race.RaceWriteSym call it
+// documents.
+//
+// Idempotent: registering the same id twice - expected, since every write
+// to the same field or global re-registers it - just overwrites the map
+// entry with the same value.
+//
+// Unlike symbolAddrs (see Detector.OnWriteSym), this table is never
+// cleared by Reset(): it holds static, compile-time metadata about what a
+// hash means, not per-run state about what currently occupies an address.
+func RegisterSymbol(id uint64, name, kind string) {
+	symbolNamesMu.Lock()
+	symbolNames[id] = symbolInfo{name: name, kind: kind}
+	symbolNamesMu.Unlock()
+}
+
+// symbolInfoForID returns the name and kind registered for id, or ("", "")
+// if id was never registered - e.g. the write was a plain OnWrite, not
+// OnWriteSym.
+func symbolInfoForID(id uint64) (name, kind string) {
+	symbolNamesMu.Lock()
+	info := symbolNames[id]
+	symbolNamesMu.Unlock()
+	return info.name, info.kind
+}
+
+// symbolInfoForAddr returns the human-readable name and kind of the symbol
+// last written to addr through OnWriteSym, or ("", "") if addr has never
+// been written that way (a plain write, or a symbol the instrumenter
+// couldn't resolve a name for).
+func (d *Detector) symbolInfoForAddr(addr uintptr) (name, kind string) {
+	idVal, ok := d.symbolAddrs.Load(addr)
+	if !ok {
+		return "", ""
+	}
+	return symbolInfoForID(idVal.(uint64))
+}
@@ -0,0 +1,89 @@
+package detector
+
+import (
+	"runtime"
+
+	"github.com/kolkov/racedetector/internal/race/shadowmem"
+)
+
+// AddressStats describes one shadow memory cell's access pattern, as
+// returned by Detector.TopAddresses (synth-3642). It's shadowmem.Consumer
+// plus the detector-level context (Owner, OwnerFunc) that only the
+// symbol/report machinery in this package can resolve.
+type AddressStats struct {
+	// Addr is the memory address this cell tracks.
+	Addr uintptr
+
+	// CheckCount is the number of OnRead/OnWrite calls that reached this
+	// cell, regardless of outcome.
+	CheckCount uint64
+
+	// Promotions is the number of times this cell promoted to VectorClock
+	// (concurrent readers detected).
+	Promotions uint32
+
+	// Demotions is the number of times this cell demoted back to the fast
+	// path (a write following a promoted read state).
+	Demotions uint32
+
+	// Owner is the best-effort qualified name of the variable at Addr -
+	// "Config.Timeout" for a struct field or "main.counter" for a
+	// package-level global, exactly as a race report on Addr would print
+	// it (see symbolInfoForAddr) - or "" if Addr was never written through
+	// OnWriteSym.
+	Owner string
+
+	// OwnerFunc is the function that most recently wrote or read Addr,
+	// resolved from whichever of the cell's write/read program counters
+	// was captured last, or "" if that PC can't be symbolized.
+	OwnerFunc string
+}
+
+// TopAddresses returns the n most frequently checked shadow memory cells,
+// ranked by total OnRead/OnWrite calls, for finding which variables
+// dominate detector overhead and might deserve suppression
+// (IgnoreWritesBegin/End) or a redesign around a different synchronization
+// primitive - see shadowmem.ShadowMemory.TopAddresses for the ranking
+// itself.
+//
+// Thread Safety: Safe for concurrent calls, same as TopShadowConsumers.
+func (d *Detector) TopAddresses(n int) []AddressStats {
+	consumers := d.shadowMemory.TopAddresses(n)
+	stats := make([]AddressStats, len(consumers))
+	for i, c := range consumers {
+		ownerName, _ := d.symbolInfoForAddr(c.Addr)
+		stats[i] = AddressStats{
+			Addr:       c.Addr,
+			CheckCount: c.CheckCount,
+			Promotions: c.Promotions,
+			Demotions:  c.Demotions,
+			Owner:      ownerName,
+			OwnerFunc:  funcNameForPC(lastAccessPC(c)),
+		}
+	}
+	return stats
+}
+
+// lastAccessPC returns whichever of c's write/read program counters was
+// captured, preferring the write PC: a cell with a nonzero write PC was
+// written at least once, and OnWrite always clears read state (see
+// shadowmem.VarState.Demote), making the write PC the more recent access
+// whenever both are present.
+func lastAccessPC(c shadowmem.Consumer) uintptr {
+	if c.WritePC != 0 {
+		return c.WritePC
+	}
+	return c.ReadPC
+}
+
+// funcNameForPC resolves pc to its enclosing function's qualified name
+// ("main.(*Server).handle"), the same way formatStackTrace resolves each
+// frame of a race report's stack trace, or "" if pc is zero or the runtime
+// can't symbolize it.
+func funcNameForPC(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame.Function
+}
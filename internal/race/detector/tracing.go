@@ -0,0 +1,98 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"runtime/trace"
+	"sync"
+
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// traceTracker emits runtime/trace user regions for Acquire/Release
+// critical sections and user tasks for detected races (v0.5.0), so a
+// program that is already capturing a `go tool trace` execution trace
+// shows races and lock hold times right alongside goroutine scheduling,
+// instead of only in the detector's own stderr/report output.
+//
+// Every method checks trace.IsEnabled() before doing any work, so this
+// integration costs a single bool read on OnAcquire/OnRelease/
+// OnReleaseMerge/reportRaceV2 when no trace is running - unlike the
+// sampler/profiler/hbGraph opt-in pattern elsewhere in this file, the
+// on/off switch here is runtime/trace's own state rather than a
+// DetectorOptions field, since there's nothing useful for this feature to
+// do until the caller has started a trace anyway.
+type traceTracker struct {
+	mu      sync.Mutex
+	regions map[traceRegionKey]*trace.Region
+}
+
+// traceRegionKey identifies one in-flight critical section: the
+// goroutine holding it and the lock address. Regions must start and end
+// in the same goroutine, so a lock held by goroutine A can't be closed
+// out by goroutine B releasing the same address (e.g. a channel used as
+// a lock).
+type traceRegionKey struct {
+	goroutine uint16
+	addr      uintptr
+}
+
+// newTraceTracker creates an empty traceTracker.
+func newTraceTracker() *traceTracker {
+	return &traceTracker{regions: make(map[traceRegionKey]*trace.Region)}
+}
+
+// acquire starts a trace region for the critical section beginning at
+// this Lock/RLock, keyed by (goroutine, addr) so the matching release
+// can find and end it. No-op unless a trace is running.
+func (t *traceTracker) acquire(addr uintptr, ctx *goroutine.RaceContext) {
+	if !trace.IsEnabled() {
+		return
+	}
+
+	region := trace.StartRegion(context.Background(), fmt.Sprintf("race.lock 0x%x", addr))
+
+	key := traceRegionKey{goroutine: ctx.TID, addr: addr}
+	t.mu.Lock()
+	t.regions[key] = region
+	t.mu.Unlock()
+}
+
+// release ends the trace region started by the matching acquire, if any.
+// No-op unless a trace is running, or if no region was started for this
+// (goroutine, addr) pair - e.g. the trace was started after the Lock.
+func (t *traceTracker) release(addr uintptr, ctx *goroutine.RaceContext) {
+	if !trace.IsEnabled() {
+		return
+	}
+
+	key := traceRegionKey{goroutine: ctx.TID, addr: addr}
+	t.mu.Lock()
+	region := t.regions[key]
+	delete(t.regions, key)
+	t.mu.Unlock()
+
+	if region != nil {
+		region.End()
+	}
+}
+
+// race emits a user task for a newly reported race, with a log entry
+// describing the two conflicting accesses. The task is momentary (it
+// starts and ends immediately) since a race is detected at a single
+// point in time rather than spanning an interval - this still surfaces
+// it as a distinct, filterable entry in `go tool trace`'s "User Tasks"
+// view. No-op unless a trace is running.
+func (t *traceTracker) race(report *RaceReport) {
+	if !trace.IsEnabled() {
+		return
+	}
+
+	ctx, task := trace.NewTask(context.Background(), "race")
+	trace.Logf(ctx, "race", "%s: goroutine %d (%s) vs goroutine %d (%s) at 0x%x",
+		report.DeduplicationKey,
+		report.Current.GoroutineID, report.Current.Type,
+		report.Previous.GoroutineID, report.Previous.Type,
+		report.Current.Addr)
+	task.End()
+}
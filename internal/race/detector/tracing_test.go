@@ -0,0 +1,65 @@
+package detector
+
+import (
+	"bytes"
+	"runtime/trace"
+	"testing"
+)
+
+// TestTraceTracker_NoopWithoutTrace verifies acquire/release/race are
+// no-ops (in particular, they don't panic on an unmatched release) when
+// no runtime/trace capture is running.
+func TestTraceTracker_NoopWithoutTrace(t *testing.T) {
+	tr := newTraceTracker()
+	ctx := createTestContext(1)
+
+	tr.acquire(0x1000, ctx)
+	tr.release(0x1000, ctx)
+	tr.release(0x2000, ctx) // unmatched: must not panic
+}
+
+// TestTraceTracker_AcquireReleasePairsRegion verifies a release ends the
+// region its matching acquire started, and that an unmatched release for
+// a different address is silently ignored, while a trace is running.
+func TestTraceTracker_AcquireReleasePairsRegion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatalf("trace.Start() error = %v", err)
+	}
+	defer trace.Stop()
+
+	tr := newTraceTracker()
+	ctx := createTestContext(1)
+
+	tr.acquire(0x1000, ctx)
+	tr.release(0x2000, ctx) // unmatched address: must not panic or affect 0x1000's region
+	tr.release(0x1000, ctx)
+
+	if len(tr.regions) != 0 {
+		t.Errorf("regions map has %d entries after release, want 0", len(tr.regions))
+	}
+}
+
+// TestDetector_OnAcquireOnRelease_EmitTraceRegions is an end-to-end check
+// that OnAcquire/OnRelease/OnReleaseMerge drive the detector's tracer
+// without panicking while a trace is running.
+func TestDetector_OnAcquireOnRelease_EmitTraceRegions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatalf("trace.Start() error = %v", err)
+	}
+	defer trace.Stop()
+
+	d := NewDetector()
+	ctx := createTestContext(1)
+
+	d.OnAcquire(0x1000, ctx)
+	d.OnRelease(0x1000, ctx)
+
+	d.OnAcquire(0x2000, ctx)
+	d.OnReleaseMerge(0x2000, ctx)
+
+	if len(d.tracer.regions) != 0 {
+		t.Errorf("detector tracer has %d open regions after matched acquire/release, want 0", len(d.tracer.regions))
+	}
+}
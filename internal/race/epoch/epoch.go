@@ -2,7 +2,8 @@
 //
 // Epoch represents a single thread's logical time as a compact 64-bit value:
 // - Top 16 bits: Thread ID (0-65,535)
-// - Bottom 48 bits: Clock value (0-281 trillion)
+// - Next 8 bits: TID generation (0-255)
+// - Bottom 40 bits: Clock value (0-1.1 trillion)
 //
 // This encoding enables O(1) happens-before checks which are the foundation
 // of FastTrack's performance (96%+ operations use epoch-only fast path).
@@ -12,6 +13,19 @@
 // - Tight loops can exceed 16M operations in seconds (24-bit clock overflowed).
 // - Go 1.25+ has excellent 64-bit performance on all platforms.
 // - Memory cost: 4 bytes → 8 bytes per variable (acceptable for reliability).
+//
+// Generation field (synth-3612): the TID pool in internal/race/api recycles
+// TIDs from a fixed-size free list, so a goroutine's TID is not a stable
+// identity across the program's lifetime - a live goroutine can be handed
+// the same TID a long-dead one used earlier. Without a generation tag, a
+// third goroutine that once synced with the dead one (and so still carries
+// a high clock value for that TID in its own VectorClock) would wrongly
+// treat the new goroutine's fresh, unrelated accesses as "happened before",
+// a false negative. Bumping the generation on every free/reuse of a TID
+// (see BumpGeneration) and packing it into both Epoch and
+// vectorclock.VectorClock (see VectorClock.SetGeneration) ensures a stale
+// generation's clock value can never dominate a fresh generation's - see
+// HappensBefore.
 package epoch
 
 import (
@@ -20,14 +34,17 @@ import (
 	"github.com/kolkov/racedetector/internal/race/vectorclock"
 )
 
-// Epoch is a 64-bit logical timestamp encoding both thread ID and clock value.
-// Layout: [TID:16][Clock:48]
+// Epoch is a 64-bit logical timestamp encoding thread ID, TID generation,
+// and clock value.
+// Layout: [TID:16][Generation:8][Clock:40]
 //
-// Example: 0x0005000000001234 represents TID=5, Clock=0x1234 (4660 decimal).
+// Example: 0x0005 00 000001234 represents TID=5, Generation=0, Clock=0x1234
+// (4660 decimal).
 //
 // Limits:
 //   - Max TID: 65,535 (16-bit) - supports up to 65K concurrent goroutines.
-//   - Max Clock: 281,474,976,710,655 (48-bit) - 281 trillion operations.
+//   - Max Generation: 255 (8-bit) - see BumpGeneration.
+//   - Max Clock: 1,099,511,627,775 (40-bit) - 1.1 trillion operations.
 type Epoch uint64
 
 const (
@@ -36,18 +53,34 @@ const (
 	// This covers 99%+ of real-world programs; v0.4 will add dynamic TID mapping.
 	TIDBits = 16
 
+	// GenerationBits is the number of bits allocated for TID generation
+	// (synth-3612). 8 bits = 256 reuses of a given TID before the generation
+	// tag wraps and a stale reference could theoretically alias again - the
+	// same bounded-risk trade-off this package already makes for TID and
+	// clock overflow (see MaxTIDWarning/MaxClockWarning), carved out of
+	// ClockBits rather than added on top so Epoch stays 64 bits.
+	GenerationBits = 8
+
 	// ClockBits is the number of bits allocated for clock value.
-	// 48 bits = 281,474,976,710,655 operations max (vs 16M in MVP).
-	// This is practically unlimited for any real program.
-	ClockBits = 48
+	// 40 bits = 1,099,511,627,775 operations max (vs 16M in MVP, 281 trillion
+	// before GenerationBits was carved out of it in synth-3612).
+	// This is still practically unlimited for any real program.
+	ClockBits = 64 - TIDBits - GenerationBits
 
-	// ClockMask is the bitmask for extracting clock value (0x0000FFFFFFFFFFFF).
+	// ClockMask is the bitmask for extracting clock value (0x000000FFFFFFFFFF).
 	ClockMask = (1 << ClockBits) - 1
 
+	// GenerationMask is the bitmask for extracting the generation field
+	// after shifting it into the low byte (synth-3612).
+	GenerationMask = (1 << GenerationBits) - 1
+
 	// MaxTID is the maximum thread ID value (65,535).
 	MaxTID = uint32((1 << TIDBits) - 1)
 
-	// MaxClock is the maximum clock value (281,474,976,710,655).
+	// MaxGeneration is the maximum TID generation value (255) (synth-3612).
+	MaxGeneration = uint8((1 << GenerationBits) - 1)
+
+	// MaxClock is the maximum clock value (1,099,511,627,775).
 	MaxClock = uint64((1 << ClockBits) - 1)
 
 	// MaxTIDWarning is the threshold for warning about TID approaching overflow (90% of max).
@@ -55,7 +88,7 @@ const (
 	MaxTIDWarning = uint32((1 << TIDBits) * 9 / 10)
 
 	// MaxClockWarning is the threshold for warning about clock approaching overflow (90% of max).
-	// 253,327,479,039,589 operations (90% of 281 trillion).
+	// 989,560,464,998 operations (90% of 1.1 trillion).
 	MaxClockWarning = uint64((1 << ClockBits) * 9 / 10)
 )
 
@@ -75,11 +108,62 @@ var (
 	// clockNearOverflow is set to 1 when clock reaches 90% threshold.
 	// Use atomic operations to access this flag.
 	clockNearOverflow uint32
+
+	// tidGenerations tracks the current generation for every possible TID
+	// (synth-3612), indexed by TID. All start at 0 (never recycled). One
+	// atomic.Uint32 per TID (rather than a smaller type) so BumpGeneration's
+	// Add is lock-free; only the low 8 bits are ever meaningful, see
+	// CurrentGeneration.
+	tidGenerations [int(MaxTID) + 1]atomic.Uint32
 )
 
-// NewEpoch creates an epoch from thread ID and clock value.
+// BumpGeneration advances tid's generation and returns the new value
+// (synth-3612). Called by the TID pool (internal/race/api.freeTID) exactly
+// once when a TID is returned to the free list, so the next goroutine that
+// allocates this TID gets a generation distinct from every goroutine that
+// held it before - see the package doc comment for why that closes the
+// stale-VectorClock-entry false negative.
+//
+// The generation is stored in only 8 bits (see GenerationBits); wrapping
+// past 255 reuses of the same TID re-admits the aliasing risk this exists
+// to close, the same bounded trade-off CheckOverflows already documents for
+// TID/clock exhaustion.
+//
+// Thread Safety: Safe for concurrent calls.
+func BumpGeneration(tid uint16) uint8 {
+	return uint8(tidGenerations[tid].Add(1))
+}
+
+// CurrentGeneration returns tid's current generation (synth-3612): 0 if tid
+// has never been freed back to the TID pool, or the number of times it has
+// been recycled since, truncated to 8 bits. Called by goroutine.Alloc and
+// goroutine.AllocWithParentClock when initializing a new RaceContext for a
+// freshly (re)allocated TID.
+//
+// Thread Safety: Safe for concurrent calls.
+func CurrentGeneration(tid uint16) uint8 {
+	return uint8(tidGenerations[tid].Load())
+}
+
+// NewEpoch creates an epoch from thread ID and clock value, at generation 0.
 //
-// The TID is stored in the top 16 bits, clock in the bottom 48 bits.
+// Generation 0 is correct for any TID that has never been returned to and
+// reallocated from the TID pool (internal/race/api), which is true of every
+// caller outside that package - the fixed-TID examples, fuzz harness, and
+// offline analysis tooling all use this constructor. The TID pool itself
+// uses NewEpochWithGeneration so a recycled TID's epochs carry its current
+// generation; see BumpGeneration.
+//
+//go:nosplit
+func NewEpoch(tid uint16, clock uint64) Epoch {
+	return NewEpochWithGeneration(tid, 0, clock)
+}
+
+// NewEpochWithGeneration creates an epoch from thread ID, TID generation, and
+// clock value (synth-3612).
+//
+// The TID is stored in the top 16 bits, generation in the next 8 bits, clock
+// in the bottom 40 bits.
 //
 // Overflow detection (v0.2.0 Task 5):
 // - If TID > MaxTID: Sets tidOverflowDetected flag and clamps to MaxTID.
@@ -89,7 +173,7 @@ var (
 // Clamping prevents wrap-around which causes false negatives (worse than false positives).
 //
 //go:nosplit
-func NewEpoch(tid uint16, clock uint64) Epoch {
+func NewEpochWithGeneration(tid uint16, generation uint8, clock uint64) Epoch {
 	// Convert tid to uint32 for comparison with MaxTID constant.
 	tid32 := uint32(tid)
 
@@ -116,7 +200,7 @@ func NewEpoch(tid uint16, clock uint64) Epoch {
 		atomic.StoreUint32(&clockNearOverflow, 1)
 	}
 
-	return Epoch(uint64(tid)<<ClockBits | (clock & ClockMask))
+	return Epoch(uint64(tid)<<(GenerationBits+ClockBits) | uint64(generation)<<ClockBits | (clock & ClockMask))
 }
 
 // Decode extracts the thread ID and clock value from an epoch.
@@ -126,17 +210,31 @@ func NewEpoch(tid uint16, clock uint64) Epoch {
 //go:nosplit
 func (e Epoch) Decode() (tid uint16, clock uint64) {
 	//nolint:gosec // G115: Intentional truncation to extract top 16 bits as TID.
-	tid = uint16(e >> ClockBits)
+	tid = uint16(e >> (GenerationBits + ClockBits))
 	clock = uint64(e) & ClockMask
 	return
 }
 
+// Generation extracts the TID generation from an epoch (synth-3612). See
+// BumpGeneration for what a generation change means.
+//
+//go:nosplit
+func (e Epoch) Generation() uint8 {
+	return uint8((e >> ClockBits) & GenerationMask)
+}
+
 // HappensBefore checks if this epoch happened before a vector clock.
 //
 // This is the CRITICAL O(1) operation that makes FastTrack fast!
 // Called millions of times, must be zero-allocation, inline-candidate.
 //
-// Returns true if epoch's clock <= vc[epoch's TID].
+// Returns true if e's generation matches vc's recorded generation for e's
+// TID, and e's clock <= vc[e's TID]. A generation mismatch (synth-3612)
+// means vc's clock value for this TID belongs to a different goroutine that
+// once held the same (recycled) TID - not comparable to e at all, so it
+// cannot happen-before regardless of the raw clock values, and this
+// correctly falls through to the detector's full happens-before/report path
+// instead of silently short-circuiting on stale data.
 //
 // Note: VectorClock stores uint32 clocks per thread, but Epoch uses uint64 global clock.
 // The comparison is safe since per-thread clocks rarely exceed 32-bit range.
@@ -144,6 +242,9 @@ func (e Epoch) Decode() (tid uint16, clock uint64) {
 //go:nosplit
 func (e Epoch) HappensBefore(vc *vectorclock.VectorClock) bool {
 	tid, clock := e.Decode()
+	if e.Generation() != vc.GetGeneration(tid) {
+		return false
+	}
 	return clock <= uint64(vc.Get(tid))
 }
 
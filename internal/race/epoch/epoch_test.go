@@ -45,22 +45,22 @@ func TestNewEpoch(t *testing.T) {
 			wantEpoch: 0xFFFF000000000000,
 		},
 		{
-			name:      "max clock (48-bit)",
+			name:      "max clock (40-bit)",
 			tid:       0,
-			clock:     0x0000FFFFFFFFFFFF,
-			wantEpoch: 0x0000FFFFFFFFFFFF,
+			clock:     MaxClock,
+			wantEpoch: MaxClock,
 		},
 		{
 			name:      "max tid and max clock",
 			tid:       65535,
-			clock:     0x0000FFFFFFFFFFFF,
-			wantEpoch: 0xFFFFFFFFFFFFFFFF,
+			clock:     MaxClock,
+			wantEpoch: uint64(65535)<<(GenerationBits+ClockBits) | MaxClock,
 		},
 		{
-			name:      "clock overflow (truncation)",
+			name:      "clock overflow (clamped)",
 			tid:       1,
-			clock:     0xFFFFFFFFFFFFFFFF, // Beyond 48 bits
-			wantEpoch: 0x0001FFFFFFFFFFFF, // Should truncate to 48 bits
+			clock:     0xFFFFFFFFFFFFFFFF, // Beyond ClockBits (40)
+			wantEpoch: uint64(1)<<(GenerationBits+ClockBits) | MaxClock, // Clamped to MaxClock
 		},
 		{
 			name:      "large tid (1000 goroutines)",
@@ -126,16 +126,16 @@ func TestEpochDecode(t *testing.T) {
 			wantClock: 0,
 		},
 		{
-			name:      "max clock (48-bit)",
+			name:      "max clock (40-bit)",
 			epoch:     0x0000FFFFFFFFFFFF,
 			wantTID:   0,
-			wantClock: 0x0000FFFFFFFFFFFF,
+			wantClock: ClockMask,
 		},
 		{
 			name:      "max epoch",
 			epoch:     0xFFFFFFFFFFFFFFFF,
 			wantTID:   65535,
-			wantClock: 0x0000FFFFFFFFFFFF,
+			wantClock: ClockMask,
 		},
 	}
 
@@ -163,7 +163,7 @@ func TestEpochRoundTrip(t *testing.T) {
 		{0, 0},
 		{1, 100},
 		{42, 0x123456},
-		{65535, 0x0000FFFFFFFFFFFF},
+		{65535, MaxClock},
 		{32768, 0x800000000},
 		{1000, 1000000000}, // 1000 goroutines, 1 billion operations
 	}
@@ -300,6 +300,36 @@ func TestEpochHappensBefore(t *testing.T) {
 	}
 }
 
+// TestEpochHappensBeforeGenerationMismatch tests the synth-3612 fix: a
+// stale VectorClock entry recorded for an earlier generation of a recycled
+// TID must never satisfy HappensBefore for a fresh generation's epoch, even
+// when the raw clock comparison alone would have said yes.
+func TestEpochHappensBeforeGenerationMismatch(t *testing.T) {
+	// A dead goroutine held TID 7 at generation 0 and reached clock 500;
+	// some third goroutine's VectorClock still remembers that (e.g. via a
+	// channel handoff), long after TID 7 was freed and reused.
+	vc := vectorclock.New()
+	vc.Set(7, 500)
+	vc.SetGeneration(7, 0)
+
+	// TID 7 has since been recycled to a brand new goroutine, now at
+	// generation 1, only two accesses into its life.
+	freshEpoch := NewEpochWithGeneration(7, 1, 2)
+
+	if freshEpoch.HappensBefore(vc) {
+		t.Error("HappensBefore = true across a generation mismatch, want false " +
+			"(stale generation-0 clock=500 must not dominate generation-1 clock=2)")
+	}
+
+	// Once vc catches up (e.g. by joining with the new goroutine directly),
+	// the generations agree again and the ordinary clock comparison applies.
+	vc.SetGeneration(7, 1)
+	vc.Set(7, 10)
+	if !freshEpoch.HappensBefore(vc) {
+		t.Error("HappensBefore = false once generations match and clock <=, want true")
+	}
+}
+
 // TestEpochSame tests the same-epoch optimization check.
 func TestEpochSame(t *testing.T) {
 	tests := []struct {
@@ -334,8 +364,8 @@ func TestEpochSame(t *testing.T) {
 		},
 		{
 			name: "max epochs identical",
-			e1:   NewEpoch(65535, 0x0000FFFFFFFFFFFF),
-			e2:   NewEpoch(65535, 0x0000FFFFFFFFFFFF),
+			e1:   NewEpoch(65535, MaxClock),
+			e2:   NewEpoch(65535, MaxClock),
 			want: true,
 		},
 		{
@@ -433,9 +463,9 @@ func TestEpochString(t *testing.T) {
 			want:  "100@65535",
 		},
 		{
-			name:  "max clock (48-bit)",
-			epoch: NewEpoch(1, 0x0000FFFFFFFFFFFF),
-			want:  "281474976710655@1",
+			name:  "max clock (40-bit)",
+			epoch: NewEpoch(1, MaxClock),
+			want:  itoa64(MaxClock) + "@1",
 		},
 		{
 			name:  "large tid (1000 goroutines)",
@@ -579,7 +609,7 @@ func TestOverflowConstants(t *testing.T) {
 	}
 
 	// Verify MaxClock calculation.
-	expectedMaxClock := uint64((1 << ClockBits) - 1) // 281,474,976,710,655
+	expectedMaxClock := uint64((1 << ClockBits) - 1) // 1,099,511,627,775
 	if MaxClock != expectedMaxClock {
 		t.Errorf("MaxClock = %d, want %d", MaxClock, expectedMaxClock)
 	}
@@ -591,7 +621,7 @@ func TestOverflowConstants(t *testing.T) {
 	}
 
 	// Verify MaxClockWarning is 90% of MaxClock.
-	expectedMaxClockWarning := uint64((1 << ClockBits) * 9 / 10) // 253,327,479,039,589
+	expectedMaxClockWarning := uint64((1 << ClockBits) * 9 / 10) // 989,560,464,998
 	if MaxClockWarning != expectedMaxClockWarning {
 		t.Errorf("MaxClockWarning = %d, want %d (90%% of MaxClock)", MaxClockWarning, expectedMaxClockWarning)
 	}
@@ -604,3 +634,68 @@ func TestOverflowConstants(t *testing.T) {
 		t.Errorf("MaxClockWarning (%d) should be less than MaxClock (%d)", MaxClockWarning, MaxClock)
 	}
 }
+
+// TestBumpGeneration verifies the TID generation registry (synth-3612): a
+// fresh TID starts at generation 0, and each BumpGeneration call advances it
+// by exactly one, independently of every other TID.
+func TestBumpGeneration(t *testing.T) {
+	// Use TIDs unlikely to be touched by other tests in this package.
+	const tidA, tidB = 40000, 40001
+
+	if got := CurrentGeneration(tidA); got != 0 {
+		t.Fatalf("CurrentGeneration(%d) before any bump = %d, want 0", tidA, got)
+	}
+
+	if got := BumpGeneration(tidA); got != 1 {
+		t.Errorf("BumpGeneration(%d) first call = %d, want 1", tidA, got)
+	}
+	if got := BumpGeneration(tidA); got != 2 {
+		t.Errorf("BumpGeneration(%d) second call = %d, want 2", tidA, got)
+	}
+	if got := CurrentGeneration(tidA); got != 2 {
+		t.Errorf("CurrentGeneration(%d) = %d, want 2", tidA, got)
+	}
+
+	// tidB must be unaffected by tidA's bumps.
+	if got := CurrentGeneration(tidB); got != 0 {
+		t.Errorf("CurrentGeneration(%d) = %d, want 0 (independent of tidA)", tidB, got)
+	}
+}
+
+// TestNewEpochWithGenerationRoundTrip verifies Epoch packs and unpacks TID,
+// generation, and clock independently (synth-3612).
+func TestNewEpochWithGenerationRoundTrip(t *testing.T) {
+	tests := []struct {
+		tid   uint16
+		gen   uint8
+		clock uint64
+	}{
+		{0, 0, 0},
+		{5, 3, 100},
+		{65535, MaxGeneration, MaxClock},
+		{1000, 128, 500000},
+	}
+
+	for _, tt := range tests {
+		e := NewEpochWithGeneration(tt.tid, tt.gen, tt.clock)
+
+		gotTID, gotClock := e.Decode()
+		if gotTID != tt.tid {
+			t.Errorf("NewEpochWithGeneration(%d, %d, %d).Decode() tid = %d, want %d",
+				tt.tid, tt.gen, tt.clock, gotTID, tt.tid)
+		}
+		if gotClock != tt.clock&ClockMask {
+			t.Errorf("NewEpochWithGeneration(%d, %d, %d).Decode() clock = %d, want %d",
+				tt.tid, tt.gen, tt.clock, gotClock, tt.clock&ClockMask)
+		}
+		if gotGen := e.Generation(); gotGen != tt.gen {
+			t.Errorf("NewEpochWithGeneration(%d, %d, %d).Generation() = %d, want %d",
+				tt.tid, tt.gen, tt.clock, gotGen, tt.gen)
+		}
+	}
+
+	// NewEpoch must default to generation 0.
+	if gen := NewEpoch(5, 100).Generation(); gen != 0 {
+		t.Errorf("NewEpoch(...).Generation() = %d, want 0", gen)
+	}
+}
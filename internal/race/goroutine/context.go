@@ -2,9 +2,39 @@ package goroutine
 
 import (
 	"github.com/kolkov/racedetector/internal/race/epoch"
+	"github.com/kolkov/racedetector/internal/race/shadowmem"
 	"github.com/kolkov/racedetector/internal/race/vectorclock"
 )
 
+// shadowCellCacheSize is the number of direct-mapped slots in each
+// RaceContext's per-goroutine shadow cell cache (v0.4.0).
+//
+// 256 entries covers the common case of a goroutine repeatedly touching a
+// small working set of hot variables without growing RaceContext too much
+// (256 * 16 bytes = 4KB per goroutine). Power-of-2 sized so slot selection
+// is a cheap bit-mask, matching ShadowMemory's own shard selection strategy.
+const shadowCellCacheSize = 256
+
+// shadowCellCacheEntry is a single slot in a RaceContext's shadow cell
+// cache: the address it was last filled for, the VarState found there, and
+// the detector generation it was filled under (synth-3577). addr == 0
+// represents an empty slot (real memory addresses are never 0).
+type shadowCellCacheEntry struct {
+	addr uintptr
+	vs   *shadowmem.VarState
+	gen  uint64
+}
+
+// shadowCellCacheIndex selects the direct-mapped slot for addr.
+//
+// Uses the same (addr >> 3) & (size-1) strategy as ShadowMemory.getShard:
+// divides by 8 (assumes 8-byte alignment) then masks to the slot count.
+//
+//go:nosplit
+func shadowCellCacheIndex(addr uintptr) uintptr {
+	return (addr >> 3) & (shadowCellCacheSize - 1)
+}
+
 // RaceContext represents the race detection state for a single goroutine.
 //
 // Each goroutine has its own RaceContext tracking logical time and happens-before
@@ -19,7 +49,10 @@ import (
 //   - C: Full vector clock [65536]uint32 tracking all threads
 //   - Epoch: Cached value of C[TID] as compact 64-bit epoch
 //
-// Invariant: Epoch must ALWAYS equal epoch.NewEpoch(TID, C[TID]).
+// Invariant: Epoch must ALWAYS equal
+// epoch.NewEpochWithGeneration(TID, Epoch.Generation(), C[TID]) - i.e. its
+// TID and clock always match C[TID], and its generation is fixed for this
+// RaceContext's whole lifetime (synth-3612; see epoch.CurrentGeneration).
 // This invariant is maintained by IncrementClock() which atomically updates both.
 type RaceContext struct {
 	// TID is the thread/goroutine identifier (0-65535).
@@ -29,6 +62,11 @@ type RaceContext struct {
 	// C is the full vector clock tracking logical time for all threads.
 	// C[i] represents the logical time for thread i.
 	// This is used for happens-before checks when epoch fast-path fails.
+	//
+	// C may be shared by reference with a SyncVar's release clock after an
+	// Unlock (synth-3618, see VectorClock.MarkShared) - never mutate it
+	// directly. Always go through IncrementClock or JoinClock, which swap
+	// in a private copy first if it's currently shared.
 	C *vectorclock.VectorClock
 
 	// Epoch is the cached epoch for this goroutine: Epoch == C[TID].
@@ -38,6 +76,48 @@ type RaceContext struct {
 	// CRITICAL: This field is on the hot path for every memory access!
 	// Must be kept in sync with C[TID] at all times.
 	Epoch epoch.Epoch
+
+	// cellCache is a direct-mapped per-goroutine cache of addr -> *VarState
+	// (v0.4.0). Repeated accesses to the same hot variables hit this cache
+	// and skip ShadowMemory's sharded sync.Map entirely, targeting a <10ns
+	// hit path that sync.Map cannot reach under contention. Owned exclusively
+	// by this goroutine, so reads and writes to it need no synchronization.
+	cellCache [shadowCellCacheSize]shadowCellCacheEntry
+
+	// ignoreDepth counts nested Disable/Enable regions for this goroutine
+	// (v0.4.0 "manual enable/disable by code region", see IncIgnore). Zero
+	// means race detection is active; OnWrite/OnRead skip this goroutine
+	// entirely while it's greater than zero.
+	ignoreDepth int32
+
+	// ignoreReadsDepth and ignoreWritesDepth count nested
+	// IgnoreReadsBegin/End and IgnoreWritesBegin/End regions (v0.4.0), the
+	// finer-grained counterparts of ignoreDepth that silence only one
+	// access kind - mirroring TSan's __tsan_ignore_reads_begin/end and
+	// __tsan_ignore_writes_begin/end. A goroutine that is ignoring reads can
+	// still have its writes checked (and vice versa), unlike ignoreDepth
+	// which silences both.
+	ignoreReadsDepth  int32
+	ignoreWritesDepth int32
+
+	// forceFullDepth counts nested EnableFullDetectionForGoroutine/
+	// DisableFullDetectionForGoroutine regions (synth-3641), the inverse of
+	// ignoreDepth: while greater than zero, this goroutine's accesses are
+	// always checked even if the detector's sampler would otherwise skip
+	// them. Depth-based for the same reason as ignoreDepth - so a nested
+	// call from library code composes correctly. GoStart propagates it to
+	// child goroutines like it already does the vector clock, so a tagged
+	// request's whole call tree runs at full detection; see racegostart
+	// and IsFullDetectionForced.
+	forceFullDepth int32
+
+	// stackLo and stackHi bound this goroutine's stack, [stackLo, stackHi)
+	// (synth-3580). Zero for both (the default) means unknown - set only
+	// when the caller has an actual stack range to report, since the Go
+	// runtime doesn't currently plumb this through compiler
+	// instrumentation. See SetStackBounds and StackBounds.
+	stackLo uintptr
+	stackHi uintptr
 }
 
 // Alloc creates and initializes a new RaceContext for the given thread ID.
@@ -71,8 +151,17 @@ func Alloc(tid uint16) *RaceContext {
 	// Initialize epoch cache to TID@1 (clock 1 for new goroutine).
 	// CRITICAL: Clock must start at 1, not 0, to detect unsynchronized races.
 	// Clock 0 means "never happened" in HappensBefore check (0 <= 0 is TRUE).
+	//
+	// gen is tid's current TID-pool generation (synth-3612, 0 if tid has
+	// never been recycled) - tagging both the vector clock's own slot and
+	// the cached Epoch with it ensures a goroutine that once synced with a
+	// prior occupant of this TID can never mistake this goroutine's fresh
+	// accesses for ones that already happened-before it. See
+	// epoch.CurrentGeneration and vectorclock.VectorClock.SetGeneration.
+	gen := epoch.CurrentGeneration(tid)
 	ctx.C.Set(tid, 1) // Set initial clock in VectorClock
-	ctx.Epoch = epoch.NewEpoch(tid, 1)
+	ctx.C.SetGeneration(tid, gen)
+	ctx.Epoch = epoch.NewEpochWithGeneration(tid, gen, 1)
 	return ctx
 }
 
@@ -99,12 +188,55 @@ func Alloc(tid uint16) *RaceContext {
 //	ctx.IncrementClock()
 //	// ctx.C[5] = 3, ctx.Epoch = 3@5
 func (rc *RaceContext) IncrementClock() {
+	// Step 0: rc.C may be a copy-on-write snapshot some SyncVar is holding
+	// as a release clock (synth-3618, see ownClock) - swap in a private
+	// copy first if so, so the increment below can never change what that
+	// snapshot looks like to whoever else is holding it.
+	rc.ownClock()
+
+	before := rc.C.Get(rc.TID)
+
 	// Step 1: Increment the vector clock for this thread.
 	rc.C.Increment(rc.TID)
 
-	// Step 2: Update the cached epoch to match C[TID].
-	// This maintains the invariant: Epoch == epoch.NewEpoch(TID, C[TID]).
-	rc.Epoch = epoch.NewEpoch(rc.TID, uint64(rc.C.Get(rc.TID)))
+	// Step 2: Update the cached epoch to match C[TID], preserving TID's
+	// generation (synth-3612) - it never changes for the lifetime of this
+	// RaceContext, only when the TID is later freed and reallocated to a
+	// different goroutine.
+	// This maintains the invariant: Epoch == epoch.NewEpochWithGeneration(TID, generation, C[TID]).
+	rc.Epoch = epoch.NewEpochWithGeneration(rc.TID, rc.Epoch.Generation(), uint64(rc.C.Get(rc.TID)))
+
+	// Self-check (synth-3621): no-op unless built with -tags
+	// racedetector_selfcheck. See selfcheck.go.
+	selfCheckClockAdvanced("IncrementClock", rc.TID, before, rc.C.Get(rc.TID))
+}
+
+// ownClock ensures rc.C is safe to mutate in place, replacing it with a
+// private CloneIfShared() copy if some SyncVar is currently holding it as a
+// frozen release-clock snapshot (synth-3618, see VectorClock.MarkShared).
+// IncrementClock and JoinClock - the two operations that mutate rc.C - both
+// call this first; read-only accessors like GetEpoch never need to.
+func (rc *RaceContext) ownClock() {
+	rc.C = rc.C.CloneIfShared()
+}
+
+// JoinClock merges other into this goroutine's vector clock (Ct := Ct ⊔
+// other), establishing a happens-before edge from whatever produced other -
+// a lock's release clock, a channel's send clock, a WaitGroup's accumulated
+// Done clock, and so on - to this goroutine's future accesses.
+//
+// Like IncrementClock, this calls ownClock first (synth-3618): rc.C may
+// currently be shared with a SyncVar if this goroutine released a lock since
+// its last mutation, and Join mutates its receiver in place, which would
+// otherwise corrupt that lock's frozen release-clock snapshot.
+func (rc *RaceContext) JoinClock(other *vectorclock.VectorClock) {
+	rc.ownClock()
+	before := rc.C.Get(rc.TID)
+	rc.C.Join(other)
+
+	// Self-check (synth-3621): no-op unless built with -tags
+	// racedetector_selfcheck. See selfcheck.go.
+	selfCheckClockAdvanced("JoinClock", rc.TID, before, rc.C.Get(rc.TID))
 }
 
 // GetEpoch returns the cached epoch for this goroutine.
@@ -176,10 +308,200 @@ func AllocWithParentClock(tid uint16, parentClock *vectorclock.VectorClock) *Rac
 	// Step 2: Initialize child's own clock component.
 	// CRITICAL: Must start at 1, not 0, to detect unsynchronized races.
 	// Clock 0 means "never happened" in HappensBefore check (0 <= 0 is TRUE).
+	//
+	// gen is tid's current TID-pool generation (synth-3612). Set after
+	// CopyFrom so it overwrites whatever generation parentClock may have
+	// recorded for this same TID slot (e.g. inherited from some earlier,
+	// unrelated goroutine parent once synced with) with the generation this
+	// child's TID actually has right now.
+	gen := epoch.CurrentGeneration(tid)
 	ctx.C.Set(tid, 1)
+	ctx.C.SetGeneration(tid, gen)
 
 	// Step 3: Initialize cached epoch.
-	ctx.Epoch = epoch.NewEpoch(tid, 1)
+	ctx.Epoch = epoch.NewEpochWithGeneration(tid, gen, 1)
 
 	return ctx
 }
+
+// CachedShadowCell returns the VarState this goroutine last cached for addr
+// under detector generation gen, or nil on a cache miss (never accessed,
+// the slot was since overwritten by a different address that hashed to the
+// same slot, or the entry was filled under a since-superseded generation).
+//
+// The gen check exists so a Detector.Reset() call that happens while other
+// goroutines are still running is safe against stale cache reuse
+// (synth-3577): once Reset() bumps the detector's generation counter, every
+// goroutine's next access naturally misses its cache instead of continuing
+// to read and write a VarState that Reset() has already evicted from
+// ShadowMemory, which would otherwise let that goroutine's future races on
+// addr go undetected forever. Callers pass the detector's current
+// generation, so no cross-package dependency on Detector is needed here.
+//
+// This is the HOT PATH lookup for the per-goroutine shadow cell cache
+// (v0.4.0): callers should try this before falling back to
+// ShadowMemory.GetOrCreate, which requires a sync.Map lookup.
+//
+// Thread Safety: Safe without synchronization - cellCache is only ever
+// read/written by the goroutine that owns this RaceContext.
+//
+// Performance Target: <10ns (array index + pointer/int compares, no atomics).
+//
+//go:nosplit
+func (rc *RaceContext) CachedShadowCell(addr uintptr, gen uint64) *shadowmem.VarState {
+	entry := &rc.cellCache[shadowCellCacheIndex(addr)]
+	if entry.addr == addr && entry.gen == gen {
+		return entry.vs
+	}
+	return nil
+}
+
+// CacheShadowCell records vs as the VarState for addr under detector
+// generation gen in this goroutine's cache, evicting whatever was
+// previously cached in that slot (if the slot held a different address, it
+// simply hashed to the same index). See CachedShadowCell for why gen is
+// tracked (synth-3577).
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+//
+// Performance Target: <5ns (array index + three field stores).
+//
+//go:nosplit
+func (rc *RaceContext) CacheShadowCell(addr uintptr, vs *shadowmem.VarState, gen uint64) {
+	entry := &rc.cellCache[shadowCellCacheIndex(addr)]
+	entry.addr = addr
+	entry.vs = vs
+	entry.gen = gen
+}
+
+// IncIgnore increases this goroutine's ignore depth by one (v0.4.0 "manual
+// enable/disable by code region"), so OnWrite/OnRead calls made by this
+// goroutine are skipped until a matching DecIgnore brings the depth back to
+// zero. Depth-based rather than boolean so nested Disable/Enable pairs -
+// e.g. a library call made from inside caller-disabled code - compose
+// correctly instead of the inner Enable re-enabling detection too early.
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+func (rc *RaceContext) IncIgnore() {
+	rc.ignoreDepth++
+}
+
+// DecIgnore decreases this goroutine's ignore depth by one. Calling
+// DecIgnore when the depth is already zero is a no-op rather than going
+// negative, matching runtime.RaceDisable's tolerance of unbalanced calls.
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+func (rc *RaceContext) DecIgnore() {
+	if rc.ignoreDepth > 0 {
+		rc.ignoreDepth--
+	}
+}
+
+// IsIgnored reports whether this goroutine is currently inside a
+// Disable/Enable region (ignoreDepth > 0). OnWrite/OnRead check this before
+// doing any detection work for the calling goroutine.
+//
+//go:nosplit
+func (rc *RaceContext) IsIgnored() bool {
+	return rc.ignoreDepth > 0
+}
+
+// IncIgnoreReads / DecIgnoreReads / IsReadsIgnored are the read-only
+// counterpart of IncIgnore/DecIgnore/IsIgnored (v0.4.0), backing
+// IgnoreReadsBegin/End: only reads from this goroutine are skipped, writes
+// are still checked. See IncIgnore for the nesting/unbalanced-call contract,
+// which applies identically here.
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+func (rc *RaceContext) IncIgnoreReads() {
+	rc.ignoreReadsDepth++
+}
+
+func (rc *RaceContext) DecIgnoreReads() {
+	if rc.ignoreReadsDepth > 0 {
+		rc.ignoreReadsDepth--
+	}
+}
+
+//go:nosplit
+func (rc *RaceContext) IsReadsIgnored() bool {
+	return rc.ignoreReadsDepth > 0 || rc.ignoreDepth > 0
+}
+
+// IncIgnoreWrites / DecIgnoreWrites / IsWritesIgnored are the write-only
+// counterpart of IncIgnore/DecIgnore/IsIgnored (v0.4.0), backing
+// IgnoreWritesBegin/End: only writes from this goroutine are skipped, reads
+// are still checked. See IncIgnore for the nesting/unbalanced-call contract,
+// which applies identically here.
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+func (rc *RaceContext) IncIgnoreWrites() {
+	rc.ignoreWritesDepth++
+}
+
+func (rc *RaceContext) DecIgnoreWrites() {
+	if rc.ignoreWritesDepth > 0 {
+		rc.ignoreWritesDepth--
+	}
+}
+
+//go:nosplit
+func (rc *RaceContext) IsWritesIgnored() bool {
+	return rc.ignoreWritesDepth > 0 || rc.ignoreDepth > 0
+}
+
+// IncForceFullDetection increases this goroutine's force-full-detection
+// depth by one (synth-3641), so the sampler is bypassed for every access
+// this goroutine makes until a matching DecForceFullDetection brings the
+// depth back to zero. See forceFullDepth for why this is depth-based
+// rather than boolean.
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+func (rc *RaceContext) IncForceFullDetection() {
+	rc.forceFullDepth++
+}
+
+// DecForceFullDetection decreases this goroutine's force-full-detection
+// depth by one. Calling it when the depth is already zero is a no-op,
+// matching DecIgnore's tolerance of unbalanced calls.
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+func (rc *RaceContext) DecForceFullDetection() {
+	if rc.forceFullDepth > 0 {
+		rc.forceFullDepth--
+	}
+}
+
+// IsFullDetectionForced reports whether this goroutine is currently inside
+// an EnableFullDetectionForGoroutine/DisableFullDetectionForGoroutine
+// region (forceFullDepth > 0). OnWrite/OnRead check this before consulting
+// the sampler, so a tagged goroutine's accesses are never skipped.
+//
+//go:nosplit
+func (rc *RaceContext) IsFullDetectionForced() bool {
+	return rc.forceFullDepth > 0
+}
+
+// SetStackBounds records this goroutine's stack range, [lo, hi) (synth-3580).
+//
+// This is used at goroutine exit so the terminating goroutine's shadow
+// cells can be cleared before the Go runtime is free to hand the same
+// stack addresses to an unrelated future goroutine - see
+// detector.Detector.ClearGoroutineStack. There is currently no compiler
+// instrumentation hook that supplies these bounds automatically (the
+// runtime tracks them internally but doesn't export them), so this is a
+// no-op until a caller with access to the real bounds calls it explicitly.
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+func (rc *RaceContext) SetStackBounds(lo, hi uintptr) {
+	rc.stackLo = lo
+	rc.stackHi = hi
+}
+
+// StackBounds returns the stack range last recorded by SetStackBounds, or
+// (0, 0) if it was never called for this goroutine.
+//
+// Thread Safety: Safe without synchronization - see CachedShadowCell.
+func (rc *RaceContext) StackBounds() (lo, hi uintptr) {
+	return rc.stackLo, rc.stackHi
+}
@@ -406,6 +406,64 @@ func TestEpochClockOverflow(t *testing.T) {
 	}
 }
 
+// TestOwnClockCopyOnWrite verifies IncrementClock and JoinClock swap in a
+// private copy of C before mutating it if C is currently shared (synth-3618),
+// so a SyncVar holding rc.C as a release clock never sees it change after the
+// fact.
+func TestOwnClockCopyOnWrite(t *testing.T) {
+	t.Run("IncrementClock clones a shared clock before mutating", func(t *testing.T) {
+		ctx := Alloc(1)
+		ctx.IncrementClock()
+
+		shared := ctx.C
+		shared.MarkShared()
+
+		ctx.IncrementClock()
+
+		if ctx.C == shared {
+			t.Fatal("IncrementClock mutated a shared VectorClock in place instead of cloning it")
+		}
+		if got := shared.Get(1); got != 2 {
+			t.Errorf("shared snapshot C[1] = %d, want 2 (must be unaffected by the later increment)", got)
+		}
+		if got := ctx.C.Get(1); got != 3 {
+			t.Errorf("ctx.C[1] = %d, want 3", got)
+		}
+	})
+
+	t.Run("JoinClock clones a shared clock before mutating", func(t *testing.T) {
+		ctx := Alloc(1)
+		other := Alloc(2)
+		other.IncrementClock()
+
+		shared := ctx.C
+		shared.MarkShared()
+
+		ctx.JoinClock(other.C)
+
+		if ctx.C == shared {
+			t.Fatal("JoinClock mutated a shared VectorClock in place instead of cloning it")
+		}
+		if got := shared.Get(2); got != 0 {
+			t.Errorf("shared snapshot C[2] = %d, want 0 (must be unaffected by the later join)", got)
+		}
+		if got := ctx.C.Get(2); got != 2 {
+			t.Errorf("ctx.C[2] = %d, want 2 (joined from other)", got)
+		}
+	})
+
+	t.Run("IncrementClock does not clone an unshared clock", func(t *testing.T) {
+		ctx := Alloc(1)
+		before := ctx.C
+
+		ctx.IncrementClock()
+
+		if ctx.C != before {
+			t.Error("IncrementClock cloned an unshared VectorClock unnecessarily")
+		}
+	})
+}
+
 // ========== BENCHMARKS ==========
 
 // BenchmarkGetEpoch benchmarks the critical hot-path GetEpoch() operation.
@@ -0,0 +1,69 @@
+package goroutine
+
+import "testing"
+
+// TestIsFullDetectionForced_FalseByDefault verifies a freshly allocated
+// RaceContext has full detection not forced (sampling, if any, applies
+// normally).
+func TestIsFullDetectionForced_FalseByDefault(t *testing.T) {
+	ctx := Alloc(1)
+
+	if ctx.IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = true on a fresh context, want false")
+	}
+}
+
+// TestIncForceFullDetection_ForcesContext verifies a single
+// IncForceFullDetection call puts the context into the forced state.
+func TestIncForceFullDetection_ForcesContext(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.IncForceFullDetection()
+
+	if !ctx.IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = false after IncForceFullDetection(), want true")
+	}
+}
+
+// TestIncDecForceFullDetection_Nests verifies nested Enable/Disable
+// regions compose: forcing only stops once every IncForceFullDetection has
+// a matching DecForceFullDetection.
+func TestIncDecForceFullDetection_Nests(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.IncForceFullDetection()
+	ctx.IncForceFullDetection()
+	if !ctx.IsFullDetectionForced() {
+		t.Fatal("IsFullDetectionForced() = false after two IncForceFullDetection() calls, want true")
+	}
+
+	ctx.DecForceFullDetection()
+	if !ctx.IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = false after one DecForceFullDetection(), want true (still one level deep)")
+	}
+
+	ctx.DecForceFullDetection()
+	if ctx.IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = true after matching DecForceFullDetection() calls, want false")
+	}
+}
+
+// TestDecForceFullDetection_UnbalancedIsNoOp verifies a
+// DecForceFullDetection with no matching IncForceFullDetection doesn't
+// underflow into a false "forced" state.
+func TestDecForceFullDetection_UnbalancedIsNoOp(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.DecForceFullDetection()
+	ctx.DecForceFullDetection()
+
+	if ctx.IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = true after unbalanced DecForceFullDetection() calls, want false")
+	}
+
+	// A subsequent IncForceFullDetection should still work normally.
+	ctx.IncForceFullDetection()
+	if !ctx.IsFullDetectionForced() {
+		t.Error("IsFullDetectionForced() = false after IncForceFullDetection() following unbalanced DecForceFullDetection(), want true")
+	}
+}
@@ -0,0 +1,123 @@
+package goroutine
+
+import "testing"
+
+// TestIsIgnored_FalseByDefault verifies a freshly allocated RaceContext has
+// detection enabled.
+func TestIsIgnored_FalseByDefault(t *testing.T) {
+	ctx := Alloc(1)
+
+	if ctx.IsIgnored() {
+		t.Error("IsIgnored() = true on a fresh context, want false")
+	}
+}
+
+// TestIncIgnore_MakesContextIgnored verifies a single IncIgnore call puts
+// the context into the ignored state.
+func TestIncIgnore_MakesContextIgnored(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.IncIgnore()
+
+	if !ctx.IsIgnored() {
+		t.Error("IsIgnored() = false after IncIgnore(), want true")
+	}
+}
+
+// TestIncIgnore_DecIgnore_Nests verifies nested Disable/Enable regions
+// compose: detection only resumes once every IncIgnore has a matching
+// DecIgnore.
+func TestIncIgnore_DecIgnore_Nests(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.IncIgnore()
+	ctx.IncIgnore()
+	if !ctx.IsIgnored() {
+		t.Fatal("IsIgnored() = false after two IncIgnore() calls, want true")
+	}
+
+	ctx.DecIgnore()
+	if !ctx.IsIgnored() {
+		t.Error("IsIgnored() = false after one DecIgnore(), want true (still one level deep)")
+	}
+
+	ctx.DecIgnore()
+	if ctx.IsIgnored() {
+		t.Error("IsIgnored() = true after matching DecIgnore() calls, want false")
+	}
+}
+
+// TestDecIgnore_UnbalancedIsNoOp verifies a DecIgnore with no matching
+// IncIgnore doesn't underflow into a false "ignored" state.
+func TestDecIgnore_UnbalancedIsNoOp(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.DecIgnore()
+	ctx.DecIgnore()
+
+	if ctx.IsIgnored() {
+		t.Error("IsIgnored() = true after unbalanced DecIgnore() calls, want false")
+	}
+
+	// A subsequent IncIgnore should still work normally.
+	ctx.IncIgnore()
+	if !ctx.IsIgnored() {
+		t.Error("IsIgnored() = false after IncIgnore() following unbalanced DecIgnore(), want true")
+	}
+}
+
+// TestIgnoreReads_OnlyAffectsReads verifies IncIgnoreReads marks reads
+// ignored while leaving writes checked.
+func TestIgnoreReads_OnlyAffectsReads(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.IncIgnoreReads()
+
+	if !ctx.IsReadsIgnored() {
+		t.Error("IsReadsIgnored() = false after IncIgnoreReads(), want true")
+	}
+	if ctx.IsWritesIgnored() {
+		t.Error("IsWritesIgnored() = true after IncIgnoreReads(), want false (writes unaffected)")
+	}
+
+	ctx.DecIgnoreReads()
+	if ctx.IsReadsIgnored() {
+		t.Error("IsReadsIgnored() = true after matching DecIgnoreReads(), want false")
+	}
+}
+
+// TestIgnoreWrites_OnlyAffectsWrites verifies IncIgnoreWrites marks writes
+// ignored while leaving reads checked.
+func TestIgnoreWrites_OnlyAffectsWrites(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.IncIgnoreWrites()
+
+	if !ctx.IsWritesIgnored() {
+		t.Error("IsWritesIgnored() = false after IncIgnoreWrites(), want true")
+	}
+	if ctx.IsReadsIgnored() {
+		t.Error("IsReadsIgnored() = true after IncIgnoreWrites(), want false (reads unaffected)")
+	}
+
+	ctx.DecIgnoreWrites()
+	if ctx.IsWritesIgnored() {
+		t.Error("IsWritesIgnored() = true after matching DecIgnoreWrites(), want false")
+	}
+}
+
+// TestIgnore_FullIgnoreAffectsBothReadsAndWrites verifies that IncIgnore
+// (the coarser Disable/Enable region) is reflected by both IsReadsIgnored
+// and IsWritesIgnored, since DisableCurrentGoroutine silences everything.
+func TestIgnore_FullIgnoreAffectsBothReadsAndWrites(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.IncIgnore()
+
+	if !ctx.IsReadsIgnored() {
+		t.Error("IsReadsIgnored() = false while IncIgnore() is active, want true")
+	}
+	if !ctx.IsWritesIgnored() {
+		t.Error("IsWritesIgnored() = false while IncIgnore() is active, want true")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build racedetector_selfcheck
+
+package goroutine
+
+import "fmt"
+
+// selfCheckClockAdvanced validates the "monotonic clocks" invariant self-check
+// mode enforces (synth-3621, -tags racedetector_selfcheck): IncrementClock
+// and JoinClock must never leave TID's own clock component lower than it was
+// before the call. Checked against each call's own pre/post state rather
+// than against history recorded elsewhere, since detector tests routinely
+// hand-set a RaceContext's clock to an arbitrary earlier value to construct
+// specific race scenarios - a legitimate test technique this must not flag.
+func selfCheckClockAdvanced(op string, tid uint16, before, after uint32) {
+	if after < before {
+		panic(fmt.Sprintf(
+			"racedetector: self-check failed: %s moved TID=%d's own clock backwards: %d -> %d",
+			op, tid, before, after))
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !racedetector_selfcheck
+
+package goroutine
+
+// selfCheckClockAdvanced is a no-op in the default build (synth-3621). See
+// selfcheck.go, built with -tags racedetector_selfcheck, for the real check.
+//
+//go:nosplit
+func selfCheckClockAdvanced(op string, tid uint16, before, after uint32) {}
@@ -0,0 +1,28 @@
+//go:build racedetector_selfcheck
+
+package goroutine
+
+import "testing"
+
+// TestSelfCheckClockAdvanced_PanicsOnRegression verifies selfCheckClockAdvanced
+// panics when a clock-mutating call's post-state is lower than its
+// pre-state - something IncrementClock and JoinClock never produce by
+// construction, but exactly the corruption self-check mode exists to catch
+// if that construction is ever broken (synth-3621).
+func TestSelfCheckClockAdvanced_PanicsOnRegression(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a self-check panic, got none")
+		}
+	}()
+	selfCheckClockAdvanced("IncrementClock", 1, 10, 5)
+}
+
+// TestSelfCheckClockAdvanced_AcceptsAdvanceOrHold verifies
+// selfCheckClockAdvanced is silent whenever the clock advanced or held
+// steady - JoinClock legitimately leaves TID's own component unchanged when
+// the joined clock has nothing new for it (synth-3621).
+func TestSelfCheckClockAdvanced_AcceptsAdvanceOrHold(t *testing.T) {
+	selfCheckClockAdvanced("IncrementClock", 1, 5, 6)
+	selfCheckClockAdvanced("JoinClock", 1, 5, 5)
+}
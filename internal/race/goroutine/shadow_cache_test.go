@@ -0,0 +1,108 @@
+package goroutine
+
+import (
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/shadowmem"
+)
+
+// TestCachedShadowCell_MissByDefault verifies that a freshly allocated
+// RaceContext has an empty cache.
+func TestCachedShadowCell_MissByDefault(t *testing.T) {
+	ctx := Alloc(1)
+
+	if got := ctx.CachedShadowCell(0x1000, 0); got != nil {
+		t.Errorf("CachedShadowCell() = %v, want nil on a fresh context", got)
+	}
+}
+
+// TestCacheShadowCell_HitReturnsCachedValue verifies that a value stored via
+// CacheShadowCell is returned by a subsequent CachedShadowCell for the same
+// address.
+func TestCacheShadowCell_HitReturnsCachedValue(t *testing.T) {
+	ctx := Alloc(1)
+	vs := shadowmem.NewVarState()
+	addr := uintptr(0x2000)
+
+	ctx.CacheShadowCell(addr, vs, 0)
+
+	got := ctx.CachedShadowCell(addr, 0)
+	if got != vs {
+		t.Errorf("CachedShadowCell() = %p, want %p", got, vs)
+	}
+}
+
+// TestCacheShadowCell_DifferentAddressMisses verifies that caching one
+// address doesn't produce a false hit for an unrelated address that maps to
+// a different slot.
+func TestCacheShadowCell_DifferentAddressMisses(t *testing.T) {
+	ctx := Alloc(1)
+	vs := shadowmem.NewVarState()
+
+	ctx.CacheShadowCell(uintptr(0x3000), vs, 0)
+
+	if got := ctx.CachedShadowCell(uintptr(0x4000), 0); got != nil {
+		t.Errorf("CachedShadowCell(0x4000) = %v, want nil (never cached)", got)
+	}
+}
+
+// TestCacheShadowCell_SameSlotEvictsPreviousAddress verifies that two
+// addresses hashing to the same direct-mapped slot correctly evict each
+// other rather than silently returning stale data for the wrong address.
+func TestCacheShadowCell_SameSlotEvictsPreviousAddress(t *testing.T) {
+	ctx := Alloc(1)
+	vs1 := shadowmem.NewVarState()
+	vs2 := shadowmem.NewVarState()
+
+	// Addresses shadowCellCacheSize*8 bytes apart hash to the same slot
+	// (shadowCellCacheIndex masks out exactly those bits).
+	addr1 := uintptr(0x1000)
+	addr2 := addr1 + shadowCellCacheSize*8
+
+	ctx.CacheShadowCell(addr1, vs1, 0)
+	if got := ctx.CachedShadowCell(addr1, 0); got != vs1 {
+		t.Fatalf("CachedShadowCell(addr1) = %p, want %p before eviction", got, vs1)
+	}
+
+	ctx.CacheShadowCell(addr2, vs2, 0)
+	if got := ctx.CachedShadowCell(addr2, 0); got != vs2 {
+		t.Errorf("CachedShadowCell(addr2) = %p, want %p", got, vs2)
+	}
+	if got := ctx.CachedShadowCell(addr1, 0); got != nil {
+		t.Errorf("CachedShadowCell(addr1) = %p, want nil after addr2 evicted the shared slot", got)
+	}
+}
+
+// TestCachedShadowCell_GenerationMismatchMisses verifies that an entry
+// cached under one detector generation is not returned when looked up under
+// a different generation, even for the same address (synth-3577). This is
+// what lets a concurrent Detector.Reset() invalidate every goroutine's
+// cache without touching cellCache directly.
+func TestCachedShadowCell_GenerationMismatchMisses(t *testing.T) {
+	ctx := Alloc(1)
+	vs := shadowmem.NewVarState()
+	addr := uintptr(0x2000)
+
+	ctx.CacheShadowCell(addr, vs, 1)
+
+	if got := ctx.CachedShadowCell(addr, 1); got != vs {
+		t.Fatalf("CachedShadowCell(addr, 1) = %p, want %p before generation bump", got, vs)
+	}
+	if got := ctx.CachedShadowCell(addr, 2); got != nil {
+		t.Errorf("CachedShadowCell(addr, 2) = %p, want nil after generation bump", got)
+	}
+}
+
+// TestShadowCellCacheIndex_PowerOfTwoMask sanity-checks the slot selection
+// formula mirrors ShadowMemory's shard selection strategy.
+func TestShadowCellCacheIndex_PowerOfTwoMask(t *testing.T) {
+	if got := shadowCellCacheIndex(0); got != 0 {
+		t.Errorf("shadowCellCacheIndex(0) = %d, want 0", got)
+	}
+	if got := shadowCellCacheIndex(shadowCellCacheSize * 8); got != 0 {
+		t.Errorf("shadowCellCacheIndex(size*8) = %d, want 0 (wraps around)", got)
+	}
+	if got := shadowCellCacheIndex(8); got != 1 {
+		t.Errorf("shadowCellCacheIndex(8) = %d, want 1", got)
+	}
+}
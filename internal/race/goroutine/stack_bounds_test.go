@@ -0,0 +1,27 @@
+package goroutine
+
+import "testing"
+
+// TestStackBounds_ZeroByDefault verifies a freshly allocated RaceContext
+// reports unknown stack bounds until SetStackBounds is called (synth-3580).
+func TestStackBounds_ZeroByDefault(t *testing.T) {
+	ctx := Alloc(1)
+
+	lo, hi := ctx.StackBounds()
+	if lo != 0 || hi != 0 {
+		t.Errorf("StackBounds() = (%#x, %#x) on a fresh context, want (0, 0)", lo, hi)
+	}
+}
+
+// TestSetStackBounds_RoundTrips verifies StackBounds returns exactly what
+// was last passed to SetStackBounds.
+func TestSetStackBounds_RoundTrips(t *testing.T) {
+	ctx := Alloc(1)
+
+	ctx.SetStackBounds(0x1000, 0x2000)
+
+	lo, hi := ctx.StackBounds()
+	if lo != 0x1000 || hi != 0x2000 {
+		t.Errorf("StackBounds() = (%#x, %#x), want (0x1000, 0x2000)", lo, hi)
+	}
+}
@@ -0,0 +1,202 @@
+// Package logging provides a small, leveled internal logger for
+// racedetector's own diagnostics (synth-3622).
+//
+// Every internal subsystem that used to fmt.Fprintf(os.Stderr, ...) its own
+// ad-hoc debug traces should instead get a *Logger via New(subsystem) and
+// call its Debug/Info/Warn/Error methods. Output is off by default and
+// controlled entirely by the RACEDETECTOR_DEBUG environment variable, so a
+// user filing an issue can be asked to re-run with, say,
+// RACEDETECTOR_DEBUG=shadowmem=trace,detector=debug and attach what comes
+// out on stderr, without the maintainers needing to ship a special debug
+// build.
+//
+// # RACEDETECTOR_DEBUG syntax
+//
+// A comma-separated list of subsystem=level pairs, e.g.:
+//
+//	RACEDETECTOR_DEBUG=shadowmem=debug,detector=trace
+//
+// A bare level with no "subsystem=" prefix sets the default level applied
+// to every subsystem that isn't otherwise listed:
+//
+//	RACEDETECTOR_DEBUG=warn
+//
+// Unset or empty disables logging entirely (LevelOff), matching every other
+// RACEDETECTOR_* environment variable's opt-in convention (see
+// RACEDETECTOR_SAMPLE_RATE, RACEDETECTOR_STRESS, and friends in
+// internal/race/api/race.go).
+//
+// Thread Safety: New and every Logger method are safe for concurrent use.
+// The environment variable is parsed once, lazily, on first use.
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Level is a logging verbosity threshold. Levels are ordered from least to
+// most verbose; a Logger emits a message only if its configured Level is at
+// least as verbose as the message's own level.
+type Level int
+
+const (
+	// LevelOff disables logging entirely - the default for every subsystem
+	// unless RACEDETECTOR_DEBUG says otherwise.
+	LevelOff Level = iota
+	// LevelError is for conditions that make racedetector's own output
+	// unreliable (not races found in the user's program - those are always
+	// printed, regardless of this package).
+	LevelError
+	// LevelWarn is for unexpected-but-recoverable internal conditions.
+	LevelWarn
+	// LevelInfo is for high-level lifecycle events (subsystem init/reset,
+	// mode selection).
+	LevelInfo
+	// LevelDebug is for the kind of per-operation detail useful when
+	// diagnosing a specific bug report.
+	LevelDebug
+	// LevelTrace is for the highest-volume detail (near hot-path
+	// frequency); expect this to noticeably slow the instrumented program.
+	LevelTrace
+)
+
+// String returns the level's canonical lowercase name, matching what
+// RACEDETECTOR_DEBUG accepts.
+func (l Level) String() string {
+	switch l {
+	case LevelOff:
+		return "off"
+	case LevelError:
+		return "error"
+	case LevelWarn:
+		return "warn"
+	case LevelInfo:
+		return "info"
+	case LevelDebug:
+		return "debug"
+	case LevelTrace:
+		return "trace"
+	default:
+		return "unknown"
+	}
+}
+
+// parseLevel parses one of the Level.String() names, case-insensitively.
+// The empty string and unrecognized names both fall back to LevelOff, so a
+// typo in RACEDETECTOR_DEBUG silently disables logging for that entry
+// rather than panicking or erroring at process startup.
+func parseLevel(s string) Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "error":
+		return LevelError
+	case "warn", "warning":
+		return LevelWarn
+	case "info":
+		return LevelInfo
+	case "debug":
+		return LevelDebug
+	case "trace":
+		return LevelTrace
+	default:
+		return LevelOff
+	}
+}
+
+// config is the parsed form of RACEDETECTOR_DEBUG: a default level plus any
+// per-subsystem overrides.
+type config struct {
+	defaultLevel Level
+	subsystems   map[string]Level
+}
+
+var (
+	configOnce   sync.Once
+	parsedConfig config
+)
+
+// loadConfig parses RACEDETECTOR_DEBUG exactly once per process.
+func loadConfig() config {
+	configOnce.Do(func() {
+		parsedConfig = parseConfig(os.Getenv("RACEDETECTOR_DEBUG"))
+	})
+	return parsedConfig
+}
+
+// parseConfig implements the syntax documented on the package - split out
+// from loadConfig so it's testable without mutating the process environment.
+func parseConfig(raw string) config {
+	cfg := config{subsystems: make(map[string]Level)}
+	if raw == "" {
+		return cfg
+	}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		subsystem, level, hasSubsystem := strings.Cut(entry, "=")
+		if hasSubsystem {
+			cfg.subsystems[strings.ToLower(strings.TrimSpace(subsystem))] = parseLevel(level)
+		} else {
+			cfg.defaultLevel = parseLevel(subsystem)
+		}
+	}
+	return cfg
+}
+
+// Logger emits leveled diagnostics for one named subsystem to stderr.
+//
+// Obtain one via New; the zero Logger is not usable.
+type Logger struct {
+	subsystem string
+	level     Level
+}
+
+// New returns a Logger for subsystem (e.g. "shadowmem", "detector", "api",
+// "instrument"), with its verbosity taken from RACEDETECTOR_DEBUG.
+//
+// Example:
+//
+//	var log = logging.New("shadowmem")
+//	...
+//	log.Debug("evicted shard %d entry %#x (over %d byte budget)", shard, addr, maxBytes)
+func New(subsystem string) *Logger {
+	cfg := loadConfig()
+	level, ok := cfg.subsystems[strings.ToLower(subsystem)]
+	if !ok {
+		level = cfg.defaultLevel
+	}
+	return &Logger{subsystem: subsystem, level: level}
+}
+
+// Enabled reports whether a message at level would actually be emitted,
+// letting a caller skip building an expensive log argument (e.g. a
+// snapshot dump) when nothing will read it.
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && level != LevelOff && level <= l.level
+}
+
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[racedetector:%s:%s] %s\n", l.subsystem, level, fmt.Sprintf(format, args...))
+}
+
+// Error logs at LevelError.
+func (l *Logger) Error(format string, args ...interface{}) { l.log(LevelError, format, args...) }
+
+// Warn logs at LevelWarn.
+func (l *Logger) Warn(format string, args ...interface{}) { l.log(LevelWarn, format, args...) }
+
+// Info logs at LevelInfo.
+func (l *Logger) Info(format string, args ...interface{}) { l.log(LevelInfo, format, args...) }
+
+// Debug logs at LevelDebug.
+func (l *Logger) Debug(format string, args ...interface{}) { l.log(LevelDebug, format, args...) }
+
+// Trace logs at LevelTrace.
+func (l *Logger) Trace(format string, args ...interface{}) { l.log(LevelTrace, format, args...) }
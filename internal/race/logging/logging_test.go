@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Level
+	}{
+		{"", LevelOff},
+		{"off", LevelOff},
+		{"garbage", LevelOff},
+		{"error", LevelError},
+		{"WARN", LevelWarn},
+		{"warning", LevelWarn},
+		{"Info", LevelInfo},
+		{"debug", LevelDebug},
+		{"trace", LevelTrace},
+		{"  trace  ", LevelTrace},
+	}
+	for _, tt := range tests {
+		if got := parseLevel(tt.in); got != tt.want {
+			t.Errorf("parseLevel(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseConfig_Empty(t *testing.T) {
+	cfg := parseConfig("")
+	if cfg.defaultLevel != LevelOff {
+		t.Errorf("defaultLevel = %v, want LevelOff", cfg.defaultLevel)
+	}
+	if len(cfg.subsystems) != 0 {
+		t.Errorf("subsystems = %v, want empty", cfg.subsystems)
+	}
+}
+
+func TestParseConfig_BareLevelSetsDefault(t *testing.T) {
+	cfg := parseConfig("debug")
+	if cfg.defaultLevel != LevelDebug {
+		t.Errorf("defaultLevel = %v, want LevelDebug", cfg.defaultLevel)
+	}
+}
+
+func TestParseConfig_PerSubsystemOverrides(t *testing.T) {
+	cfg := parseConfig("warn,shadowmem=trace,detector=debug")
+	if cfg.defaultLevel != LevelWarn {
+		t.Errorf("defaultLevel = %v, want LevelWarn", cfg.defaultLevel)
+	}
+	if cfg.subsystems["shadowmem"] != LevelTrace {
+		t.Errorf("shadowmem = %v, want LevelTrace", cfg.subsystems["shadowmem"])
+	}
+	if cfg.subsystems["detector"] != LevelDebug {
+		t.Errorf("detector = %v, want LevelDebug", cfg.subsystems["detector"])
+	}
+	if _, ok := cfg.subsystems["api"]; ok {
+		t.Error("api should have no override, want it to fall back to defaultLevel")
+	}
+}
+
+func TestLogger_EnabledRespectsLevel(t *testing.T) {
+	l := &Logger{subsystem: "shadowmem", level: LevelWarn}
+
+	if !l.Enabled(LevelError) || !l.Enabled(LevelWarn) {
+		t.Error("Error/Warn should be enabled at level Warn")
+	}
+	if l.Enabled(LevelInfo) || l.Enabled(LevelDebug) || l.Enabled(LevelTrace) {
+		t.Error("Info/Debug/Trace should be disabled at level Warn")
+	}
+}
+
+func TestLogger_OffLoggerEmitsNothing(t *testing.T) {
+	l := &Logger{subsystem: "api", level: LevelOff}
+	if l.Enabled(LevelError) {
+		t.Error("LevelOff logger must not enable even LevelError")
+	}
+}
+
+func TestLogger_LogWritesToStderrWhenEnabled(t *testing.T) {
+	l := &Logger{subsystem: "detector", level: LevelDebug}
+
+	old := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+	l.Debug("evicted %d entries over %d byte budget", 3, 256)
+	w.Close()
+	os.Stderr = old
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	got := buf.String()
+
+	if !strings.Contains(got, "[racedetector:detector:debug]") {
+		t.Errorf("output missing subsystem/level prefix, got %q", got)
+	}
+	if !strings.Contains(got, "evicted 3 entries over 256 byte budget") {
+		t.Errorf("output missing formatted message, got %q", got)
+	}
+}
+
+func TestLogger_LogSilentWhenAboveConfiguredLevel(t *testing.T) {
+	l := &Logger{subsystem: "detector", level: LevelWarn}
+
+	old := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+	l.Debug("should not appear")
+	w.Close()
+	os.Stderr = old
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	if got := buf.String(); got != "" {
+		t.Errorf("expected no output, got %q", got)
+	}
+}
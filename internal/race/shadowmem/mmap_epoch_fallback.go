@@ -0,0 +1,43 @@
+//go:build !linux || !(amd64 || arm64)
+
+// Fallback mmap-backed epoch shadow for unsupported platforms (synth-3579).
+//
+// mmap_epoch_linux.go's direct-mapped shadow relies on syscall.Mmap/Munmap
+// and raw pointer arithmetic over the mapping, which this repo only
+// verifies on linux/amd64 and linux/arm64 - the same scope restriction the
+// goid_* fast-path files already apply for their assembly implementation.
+// Everywhere else, NewMMapEpochShadow reports ErrMMapUnsupported so callers
+// fall back to the normal ShadowMemory path, exactly as if
+// DetectorOptions.MMapShadowEnabled had never been set.
+package shadowmem
+
+import (
+	"errors"
+
+	"github.com/kolkov/racedetector/internal/race/epoch"
+)
+
+// ErrMMapUnsupported is returned by NewMMapEpochShadow on this platform.
+var ErrMMapUnsupported = errors.New("shadowmem: mmap-backed shadow memory is not supported on this platform")
+
+// MMapEpochShadow is the fallback stub for platforms without a native mmap
+// implementation. Its methods are never reachable in practice, since
+// NewMMapEpochShadow always fails first - see the package doc above.
+type MMapEpochShadow struct{}
+
+// NewMMapEpochShadow always fails on this platform; see ErrMMapUnsupported.
+func NewMMapEpochShadow(base uintptr, span uintptr) (*MMapEpochShadow, error) {
+	return nil, ErrMMapUnsupported
+}
+
+// Contains always returns false on this platform.
+func (m *MMapEpochShadow) Contains(addr uintptr) bool { return false }
+
+// LoadEpoch always returns the zero Epoch on this platform.
+func (m *MMapEpochShadow) LoadEpoch(addr uintptr) epoch.Epoch { return 0 }
+
+// StoreEpoch is a no-op on this platform.
+func (m *MMapEpochShadow) StoreEpoch(addr uintptr, e epoch.Epoch) {}
+
+// Close is a no-op on this platform.
+func (m *MMapEpochShadow) Close() error { return nil }
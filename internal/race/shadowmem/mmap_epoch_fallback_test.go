@@ -0,0 +1,13 @@
+//go:build !linux || !(amd64 || arm64)
+
+package shadowmem
+
+import "testing"
+
+// TestNewMMapEpochShadow_UnsupportedPlatform verifies the fallback always
+// reports ErrMMapUnsupported instead of pretending to succeed.
+func TestNewMMapEpochShadow_UnsupportedPlatform(t *testing.T) {
+	if _, err := NewMMapEpochShadow(0x1000, 4096); err != ErrMMapUnsupported {
+		t.Errorf("NewMMapEpochShadow() error = %v, want ErrMMapUnsupported", err)
+	}
+}
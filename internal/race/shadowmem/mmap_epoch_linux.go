@@ -0,0 +1,142 @@
+//go:build linux && (amd64 || arm64)
+
+// Package shadowmem: mmap-backed direct-mapped epoch shadow (synth-3579).
+//
+// MMapEpochShadow reserves a single contiguous block of memory via the
+// mmap(2) syscall and maps every 8-byte-aligned address in a caller-chosen
+// range straight onto a slot in it via arithmetic ((addr-base)>>3), instead
+// of going through ShadowMemory's sharded sync.Map. This is the same
+// technique ThreadSanitizer's C++ shadow memory uses for its shadow cells,
+// and is the single biggest lever for closing the gap to TSan-class
+// performance: no hashing, no bucket traversal, no per-lookup allocation
+// risk - just a multiply-free array index.
+//
+// Scope: each slot holds one raw epoch.Epoch (8 bytes, no pointers), not a
+// full VarState. VarState's promotion machinery (read-sharing, ownership
+// tracking, history) needs heap-allocated, GC-visible state (readClock,
+// stack hashes, etc.) that must never live in this syscall-backed memory -
+// the Go garbage collector cannot see or trace pointers stored there, so a
+// *VarState stashed here could be collected out from under a live
+// reference. MMapEpochShadow is therefore a narrower building block:
+// Detector uses it as a same-epoch fast-path mirror (see OnWrite/OnRead),
+// falling back to the full ShadowMemory for anything the epoch-only
+// representation can't answer, exactly the way VarState's own "same epoch"
+// check already does today - this just makes the common case of that check
+// skip the per-goroutine cache and sync.Map lookup entirely.
+package shadowmem
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"github.com/kolkov/racedetector/internal/race/epoch"
+)
+
+// ErrMMapUnsupported is returned by NewMMapEpochShadow on platforms without
+// a native implementation. On linux/amd64 and linux/arm64 (this file) it is
+// never returned; see mmap_epoch_fallback.go for the platforms where it is.
+var ErrMMapUnsupported = errors.New("shadowmem: mmap-backed shadow memory is not supported on this platform")
+
+// mmapEpochSlotSize is the number of bytes reserved per 8-byte-aligned
+// address: one atomically accessed epoch.Epoch (stored as uint64).
+const mmapEpochSlotSize = 8
+
+// MMapEpochShadow is a direct-mapped, mmap-backed epoch table covering a
+// fixed [base, base+span) address range at 8-byte granularity (synth-3579).
+//
+// Thread Safety: LoadEpoch/StoreEpoch are safe for concurrent calls (atomic
+// operations on the underlying mmap'd memory). Close must not be called
+// concurrently with either.
+type MMapEpochShadow struct {
+	base uintptr
+	span uintptr
+	mem  []byte // len(mem) == (span>>3)*mmapEpochSlotSize, backed by mmap(2).
+}
+
+// NewMMapEpochShadow reserves an mmap'd region covering the address range
+// [base, base+span) at 8-byte granularity.
+//
+// base and span are typically chosen to cover a specific heap or stack
+// region the caller knows is hot (see the package doc), not the entire
+// address space - reserving shadow memory for the full 48-bit virtual
+// address space up front would itself require terabytes of address space,
+// which is not the intended usage even though the OS would lazily back
+// only the pages actually touched.
+//
+// span is rounded up to a multiple of 8 if it isn't already. Returns an
+// error if span is 0 or the underlying mmap(2) call fails (e.g. address
+// space exhaustion).
+//
+// The returned MMapEpochShadow must be released with Close when no longer
+// needed, since the reserved mapping is OS-backed memory the Go garbage
+// collector does not know how to reclaim.
+func NewMMapEpochShadow(base uintptr, span uintptr) (*MMapEpochShadow, error) {
+	if span == 0 {
+		return nil, errors.New("shadowmem: NewMMapEpochShadow span must be > 0")
+	}
+	span = (span + 7) &^ 7 // Round up to a multiple of 8.
+
+	slots := span >> 3
+	size := slots * mmapEpochSlotSize
+
+	mem, err := syscall.Mmap(-1, 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("shadowmem: mmap %d bytes: %w", size, err)
+	}
+
+	return &MMapEpochShadow{base: base, span: span, mem: mem}, nil
+}
+
+// Contains reports whether addr falls within this shadow's covered range.
+//
+//go:nosplit
+func (m *MMapEpochShadow) Contains(addr uintptr) bool {
+	return addr >= m.base && addr < m.base+m.span
+}
+
+// slotPtr returns a pointer to the 8-byte slot for addr, which must
+// satisfy Contains(addr).
+//
+//go:nosplit
+func (m *MMapEpochShadow) slotPtr(addr uintptr) *uint64 {
+	idx := (addr - m.base) >> 3
+	return (*uint64)(unsafe.Pointer(&m.mem[idx*mmapEpochSlotSize]))
+}
+
+// LoadEpoch returns the epoch last stored for addr via StoreEpoch, or the
+// zero Epoch if never stored. addr must satisfy Contains(addr); callers
+// are expected to check Contains before calling, the same convention
+// ShadowMemory.getShard's callers already follow for shard selection.
+//
+// Thread Safety: Lock-free (atomic load).
+// Performance: ~2-5ns (array arithmetic + atomic load), the same class as
+// VarState.GetW - no sync.Map lookup, no per-goroutine cache miss handling.
+//
+//go:nosplit
+func (m *MMapEpochShadow) LoadEpoch(addr uintptr) epoch.Epoch {
+	return epoch.Epoch(atomic.LoadUint64(m.slotPtr(addr)))
+}
+
+// StoreEpoch records e as the epoch for addr, overwriting whatever was
+// there. addr must satisfy Contains(addr).
+//
+// Thread Safety: Lock-free (atomic store).
+// Performance: ~2-5ns, same class as VarState.SetW.
+//
+//go:nosplit
+func (m *MMapEpochShadow) StoreEpoch(addr uintptr, e epoch.Epoch) {
+	atomic.StoreUint64(m.slotPtr(addr), uint64(e))
+}
+
+// Close releases the mmap'd region. m must not be used afterward.
+func (m *MMapEpochShadow) Close() error {
+	if m.mem == nil {
+		return nil
+	}
+	err := syscall.Munmap(m.mem)
+	m.mem = nil
+	return err
+}
@@ -0,0 +1,126 @@
+//go:build linux && (amd64 || arm64)
+
+package shadowmem
+
+import (
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/epoch"
+)
+
+// TestMMapEpochShadow_LoadStoreRoundTrips verifies a stored epoch is
+// returned by a later load at the same address.
+func TestMMapEpochShadow_LoadStoreRoundTrips(t *testing.T) {
+	m, err := NewMMapEpochShadow(0x1000, 4096)
+	if err != nil {
+		t.Fatalf("NewMMapEpochShadow() error = %v", err)
+	}
+	defer m.Close()
+
+	addr := uintptr(0x1008)
+	want := epoch.NewEpoch(3, 42)
+	m.StoreEpoch(addr, want)
+
+	if got := m.LoadEpoch(addr); got != want {
+		t.Errorf("LoadEpoch() = %v, want %v", got, want)
+	}
+}
+
+// TestMMapEpochShadow_UnstoredSlotIsZero verifies a slot that was never
+// written returns the zero Epoch, matching VarState's zero-value semantics.
+func TestMMapEpochShadow_UnstoredSlotIsZero(t *testing.T) {
+	m, err := NewMMapEpochShadow(0x2000, 4096)
+	if err != nil {
+		t.Fatalf("NewMMapEpochShadow() error = %v", err)
+	}
+	defer m.Close()
+
+	if got := m.LoadEpoch(0x2000); got != 0 {
+		t.Errorf("LoadEpoch(never-stored) = %v, want 0", got)
+	}
+}
+
+// TestMMapEpochShadow_DistinctAddressesDontAlias verifies two different
+// 8-byte-aligned addresses within range map to independent slots.
+func TestMMapEpochShadow_DistinctAddressesDontAlias(t *testing.T) {
+	m, err := NewMMapEpochShadow(0x3000, 4096)
+	if err != nil {
+		t.Fatalf("NewMMapEpochShadow() error = %v", err)
+	}
+	defer m.Close()
+
+	addr1, addr2 := uintptr(0x3000), uintptr(0x3008)
+	e1, e2 := epoch.NewEpoch(1, 10), epoch.NewEpoch(2, 20)
+
+	m.StoreEpoch(addr1, e1)
+	m.StoreEpoch(addr2, e2)
+
+	if got := m.LoadEpoch(addr1); got != e1 {
+		t.Errorf("LoadEpoch(addr1) = %v, want %v", got, e1)
+	}
+	if got := m.LoadEpoch(addr2); got != e2 {
+		t.Errorf("LoadEpoch(addr2) = %v, want %v", got, e2)
+	}
+}
+
+// TestMMapEpochShadow_Contains verifies range membership at both edges.
+func TestMMapEpochShadow_Contains(t *testing.T) {
+	m, err := NewMMapEpochShadow(0x4000, 4096)
+	if err != nil {
+		t.Fatalf("NewMMapEpochShadow() error = %v", err)
+	}
+	defer m.Close()
+
+	cases := []struct {
+		addr uintptr
+		want bool
+	}{
+		{0x3FF8, false}, // Just below base.
+		{0x4000, true},  // Base itself.
+		{0x4FF8, true},  // Last slot in range.
+		{0x5000, false}, // base + span, exclusive.
+	}
+	for _, c := range cases {
+		if got := m.Contains(c.addr); got != c.want {
+			t.Errorf("Contains(%#x) = %v, want %v", c.addr, got, c.want)
+		}
+	}
+}
+
+// TestNewMMapEpochShadow_RoundsSpanUpToMultipleOf8 verifies a span that
+// isn't 8-byte aligned still covers the requested range rather than
+// truncating it.
+func TestNewMMapEpochShadow_RoundsSpanUpToMultipleOf8(t *testing.T) {
+	m, err := NewMMapEpochShadow(0x6000, 10) // Rounds up to 16.
+	if err != nil {
+		t.Fatalf("NewMMapEpochShadow() error = %v", err)
+	}
+	defer m.Close()
+
+	if !m.Contains(0x6008) {
+		t.Error("Contains(base+8) = false, want true after rounding span up to 16")
+	}
+}
+
+// TestNewMMapEpochShadow_ZeroSpanErrors verifies a zero span is rejected
+// rather than silently reserving an empty mapping.
+func TestNewMMapEpochShadow_ZeroSpanErrors(t *testing.T) {
+	if _, err := NewMMapEpochShadow(0x7000, 0); err == nil {
+		t.Error("NewMMapEpochShadow(base, 0) error = nil, want an error")
+	}
+}
+
+// TestMMapEpochShadow_CloseIsIdempotent verifies calling Close twice
+// doesn't panic (mirrors os.File.Close's contract).
+func TestMMapEpochShadow_CloseIsIdempotent(t *testing.T) {
+	m, err := NewMMapEpochShadow(0x8000, 4096)
+	if err != nil {
+		t.Fatalf("NewMMapEpochShadow() error = %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("first Close() error = %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("second Close() error = %v, want nil (idempotent)", err)
+	}
+}
@@ -0,0 +1,165 @@
+//go:build !racedetector_small
+
+package shadowmem
+
+import "testing"
+
+// TestNewShadowMemoryWithCap_ZeroIsUnbounded verifies a zero cap behaves
+// like NewShadowMemory: no eviction, regardless of how many cells are
+// created (synth-3578).
+func TestNewShadowMemoryWithCap_ZeroIsUnbounded(t *testing.T) {
+	sm := NewShadowMemoryWithCap(0)
+
+	for i := uintptr(0); i < 100; i++ {
+		sm.GetOrCreate(i * 8)
+	}
+
+	if got := sm.Count(); got != 100 {
+		t.Errorf("Count() = %d, want 100 (no eviction under an unbounded cap)", got)
+	}
+	if got := sm.Evictions(); got != 0 {
+		t.Errorf("Evictions() = %d, want 0", got)
+	}
+}
+
+// TestShadowMemoryWithCap_EvictsOnceOverBudget verifies that once the
+// estimated footprint would exceed maxBytes, GetOrCreate evicts a cell
+// instead of growing past the cap, and records the eviction (synth-3578).
+func TestShadowMemoryWithCap_EvictsOnceOverBudget(t *testing.T) {
+	const capCells = 4
+	sm := NewShadowMemoryWithCap(capCells * estimatedVarStateBytes)
+
+	// Eviction only samples within the shard the newly inserted cell lands
+	// in, but evictIfOverBudget's over-budget check compares against the
+	// cellCount shared across all shards, so spreading these across
+	// whichever shards they naturally land on still exercises the cap.
+	for i := uintptr(0); i < capCells*4; i++ {
+		sm.GetOrCreate(i * 8)
+	}
+
+	if got := sm.Count(); got > capCells {
+		t.Errorf("Count() = %d, want at most %d (cap enforced)", got, capCells)
+	}
+	if got := sm.Evictions(); got == 0 {
+		t.Error("Evictions() = 0, want > 0 after inserting well past the cap")
+	}
+}
+
+// TestShadowMemoryWithCap_EvictsLeastRecentlyTouched verifies eviction
+// prefers a cell that hasn't been touched recently over one that was just
+// accessed, when both are candidates in the same shard (synth-3578).
+func TestShadowMemoryWithCap_EvictsLeastRecentlyTouched(t *testing.T) {
+	// n addresses that all hash to the same shard: getShard selects shard
+	// (addr>>3)&shardMask, so stepping by ShardCount*8 keeps addr>>3's low
+	// bits (and therefore the shard) constant while still producing
+	// distinct addresses.
+	const n = evictionSampleSize
+	addrs := make([]uintptr, n)
+	for i := range addrs {
+		addrs[i] = uintptr(i*ShardCount) * 8
+	}
+
+	sm := NewShadowMemoryWithCap((n - 1) * estimatedVarStateBytes)
+	for _, a := range addrs[:n-1] {
+		sm.GetOrCreate(a)
+	}
+
+	// Re-touch every cell except addrs[0], so it's the only one left with
+	// the oldest recency stamp when the next insert forces an eviction.
+	for _, a := range addrs[1 : n-1] {
+		sm.GetOrCreate(a)
+	}
+
+	// This insert pushes the estimate over budget and should evict addrs[0].
+	sm.GetOrCreate(addrs[n-1])
+
+	if sm.Get(addrs[0]) != nil {
+		t.Error("Get(addrs[0]) != nil, want the least-recently-touched cell evicted")
+	}
+	for _, a := range addrs[1:] {
+		if sm.Get(a) == nil {
+			t.Errorf("Get(%#x) = nil, want the recently-touched cell preserved", a)
+		}
+	}
+}
+
+// TestShadowMemoryWithCap_HotAddressSurvivesNaturalDistribution verifies
+// that under a natural (sequential, non-strided) address distribution
+// spread across many shards, a hot address - touched on every insertion -
+// survives sustained eviction pressure while a cold address that's never
+// touched again does not.
+//
+// This is a regression test for a bug where evictIfOverBudget sampled
+// candidates only from the shard the just-inserted cell landed in, and
+// never excluded that cell from candidacy. Once ShardCount scaled past the
+// live-address count, single-entry shards were common, so the cell
+// GetOrCreate had just created was frequently the only candidate and got
+// evicted immediately - the opposite of "evict cold, keep hot"
+// (synth-3578). TestShadowMemoryWithCap_EvictsLeastRecentlyTouched above
+// doesn't catch this because it strides addresses by ShardCount*8 to force
+// every candidate into the same shard by construction.
+func TestShadowMemoryWithCap_HotAddressSurvivesNaturalDistribution(t *testing.T) {
+	const capCells = 16
+	sm := NewShadowMemoryWithCap(capCells * estimatedVarStateBytes)
+
+	hot := uintptr(8)
+	sm.GetOrCreate(hot)
+
+	cold := uintptr(16)
+	sm.GetOrCreate(cold)
+
+	// Sequential, naturally-distributed addresses - no artificial striding
+	// to keep everything in one shard. Insert far more than the cap to
+	// force sustained eviction pressure, re-touching hot after every insert
+	// so it's never the least-recently-touched candidate.
+	for i := uintptr(2); i < capCells*20; i++ {
+		sm.GetOrCreate(i * 8)
+		sm.GetOrCreate(hot)
+	}
+
+	if sm.Get(hot) == nil {
+		t.Error("Get(hot) = nil, want the repeatedly-touched hot address to survive eviction")
+	}
+	if sm.Get(cold) != nil {
+		t.Error("Get(cold) != nil, want the never-retouched cold address evicted under sustained eviction pressure")
+	}
+	if got := sm.Evictions(); got == 0 {
+		t.Fatal("Evictions() = 0, want > 0 to make this test meaningful")
+	}
+}
+
+// TestShadowMemoryWithCap_ResetClearsEvictionState verifies Reset zeroes
+// both the cell count and the eviction metric, so a detector reused across
+// tests doesn't inherit a stale over-budget signal (synth-3578).
+func TestShadowMemoryWithCap_ResetClearsEvictionState(t *testing.T) {
+	sm := NewShadowMemoryWithCap(estimatedVarStateBytes)
+	for i := uintptr(0); i < 8; i++ {
+		sm.GetOrCreate(i * 8)
+	}
+	if sm.Evictions() == 0 {
+		t.Fatal("Evictions() = 0 before Reset, want > 0 to make this test meaningful")
+	}
+
+	sm.Reset()
+
+	if got := sm.Evictions(); got != 0 {
+		t.Errorf("Evictions() after Reset = %d, want 0", got)
+	}
+	if got := sm.EstimatedBytes(); got != 0 {
+		t.Errorf("EstimatedBytes() after Reset = %d, want 0", got)
+	}
+}
+
+// TestShadowMemoryEstimatedBytes_TracksCellCount verifies EstimatedBytes
+// scales linearly with the number of live cells (synth-3578).
+func TestShadowMemoryEstimatedBytes_TracksCellCount(t *testing.T) {
+	sm := NewShadowMemory()
+	for i := uintptr(0); i < 10; i++ {
+		sm.GetOrCreate(i * 8)
+	}
+
+	want := uint64(10) * estimatedVarStateBytes
+	if got := sm.EstimatedBytes(); got != want {
+		t.Errorf("EstimatedBytes() = %d, want %d", got, want)
+	}
+}
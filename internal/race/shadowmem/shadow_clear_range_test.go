@@ -0,0 +1,72 @@
+//go:build !racedetector_small
+
+package shadowmem
+
+import "testing"
+
+// TestShadowMemoryClearRange_RemovesCellsInRange verifies addresses inside
+// [lo, hi) are forgotten while addresses outside it survive (synth-3580).
+func TestShadowMemoryClearRange_RemovesCellsInRange(t *testing.T) {
+	sm := NewShadowMemory()
+	inRange := []uintptr{0x1000, 0x1008, 0x1FF8}
+	outOfRange := []uintptr{0xFF8, 0x2000}
+
+	for _, a := range append(append([]uintptr{}, inRange...), outOfRange...) {
+		sm.GetOrCreate(a)
+	}
+
+	sm.ClearRange(0x1000, 0x2000)
+
+	for _, a := range inRange {
+		if sm.Get(a) != nil {
+			t.Errorf("Get(%#x) != nil after ClearRange, want cleared", a)
+		}
+	}
+	for _, a := range outOfRange {
+		if sm.Get(a) == nil {
+			t.Errorf("Get(%#x) = nil after ClearRange, want preserved (outside range)", a)
+		}
+	}
+}
+
+// TestShadowMemoryClearRange_UpdatesCellCount verifies Count reflects the
+// cells removed by ClearRange, so a subsequent MaxShadowBytes eviction
+// budget isn't computed against phantom cells (synth-3580).
+func TestShadowMemoryClearRange_UpdatesCellCount(t *testing.T) {
+	sm := NewShadowMemory()
+	for i := uintptr(0); i < 10; i++ {
+		sm.GetOrCreate(i * 8)
+	}
+
+	sm.ClearRange(0, 5*8)
+
+	if got := sm.Count(); got != 5 {
+		t.Errorf("Count() after ClearRange = %d, want 5", got)
+	}
+}
+
+// TestShadowMemoryClearRange_EmptyRangeIsNoOp verifies a degenerate range
+// (lo >= hi) clears nothing rather than panicking or clearing everything.
+func TestShadowMemoryClearRange_EmptyRangeIsNoOp(t *testing.T) {
+	sm := NewShadowMemory()
+	sm.GetOrCreate(0x3000)
+
+	sm.ClearRange(0x3000, 0x3000)
+
+	if sm.Get(0x3000) == nil {
+		t.Error("Get(0x3000) = nil after a degenerate (lo == hi) ClearRange, want preserved")
+	}
+}
+
+// TestShadowMemoryClearRange_NoMatchesIsNoOp verifies clearing a range with
+// no tracked cells doesn't disturb unrelated cells.
+func TestShadowMemoryClearRange_NoMatchesIsNoOp(t *testing.T) {
+	sm := NewShadowMemory()
+	sm.GetOrCreate(0x4000)
+
+	sm.ClearRange(0x9000, 0xA000)
+
+	if sm.Get(0x4000) == nil {
+		t.Error("Get(0x4000) = nil after clearing an unrelated range, want preserved")
+	}
+}
@@ -0,0 +1,35 @@
+package shadowmem
+
+// Consumer describes one shadow cell's access activity, for ranking the
+// hottest tracked addresses (v0.5.0 debug endpoint support; CheckCount,
+// Promotions and Demotions added by synth-3642 for access-pattern export).
+// Shared by both the default (shadow_map.go) and racedetector_small
+// (shadow_small.go) ShadowMemory implementations, since TopConsumers' and
+// TopAddresses' return type must be the same regardless of which build tag
+// is active.
+type Consumer struct {
+	Addr       uintptr
+	WriteCount uint32
+
+	// CheckCount is the number of OnRead/OnWrite calls that reached this
+	// cell, regardless of outcome - unlike WriteCount, this also counts
+	// reads, making it the right ranking for "which addresses dominate
+	// detector overhead" rather than only "which addresses are written
+	// most" (synth-3642).
+	CheckCount uint64
+
+	// Promotions is the number of times this cell promoted to VectorClock
+	// (concurrent readers detected).
+	Promotions uint32
+
+	// Demotions is the number of times this cell demoted back to the fast
+	// path (a write following a promoted read state).
+	Demotions uint32
+
+	// WritePC and ReadPC are the cell's last-write and last-read caller
+	// program counters (synth-3642), carried through so a caller can
+	// resolve "which function accessed this address" without a second,
+	// separately-synchronized lookup back into shadow memory.
+	WritePC uintptr
+	ReadPC  uintptr
+}
@@ -1,17 +1,73 @@
+//go:build !racedetector_small
+
+// Package shadowmem's default ShadowMemory implementation. Built whenever
+// the racedetector_small build tag is not set; see shadow_small.go for the
+// fixed-table alternative that tag selects instead (synth-3616).
+
 package shadowmem
 
-import "sync"
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/kolkov/racedetector/internal/race/logging"
+)
+
+// log is shadowmem's internal diagnostic logger (synth-3622), silent unless
+// RACEDETECTOR_DEBUG enables it - see internal/race/logging.
+var log = logging.New("shadowmem")
+
+// minShardCount is the floor on ShardCount regardless of GOMAXPROCS, so
+// single-core or low-GOMAXPROCS runs (common in CI containers) still get
+// enough shards to keep sequential struct-field addresses spread out.
+const minShardCount = 64
+
+// shardsPerP is the number of shards allocated per GOMAXPROCS (v0.4.0).
+//
+// Scaling shard count with GOMAXPROCS (rather than using a fixed count)
+// keeps the expected number of goroutines contending on any one shard
+// roughly constant as the machine grows: with P cores, up to P goroutines
+// can be truly concurrent, and 4 shards per P keeps collision probability
+// low even when several goroutines land on the same shard's hash bucket.
+const shardsPerP = 4
 
 // ShardCount is the number of shards in the sharded shadow memory.
-// 256 shards provides good balance between:
-//   - Reduced contention (each shard handles ~1/256 of addresses)
-//   - Low memory overhead (256 * 64 bytes padding = 16KB total)
-//   - Fast shard selection (8 bits of address, no division needed)
 //
-// Performance Impact: With 256 shards, probability of contention on
-// the same shard is 1/256 for random addresses, which significantly
-// reduces lock contention in multi-goroutine programs.
-const ShardCount = 256
+// Computed once at package init as the next power of two >= GOMAXPROCS*4
+// (floored at minShardCount), rather than a fixed constant. This matters
+// because each shard is itself a sync.Map: sync.Map's read/dirty-map
+// promotion bookkeeping is per-instance, so a fixed shard count sized for
+// a typical machine leaves large many-core machines with too few shards
+// (more goroutines piling onto each shard's promotion path) and leaves
+// small machines paying for shards they'll never meaningfully contend on.
+//
+// Power-of-two sizing keeps shard selection a cheap bit-mask (see getShard)
+// instead of a division/modulo.
+var ShardCount = computeShardCount()
+
+// shardMask is ShardCount-1, precomputed so getShard never recomputes it.
+var shardMask = uintptr(ShardCount - 1)
+
+// computeShardCount derives ShardCount from the runtime's GOMAXPROCS.
+func computeShardCount() int {
+	n := runtime.GOMAXPROCS(0) * shardsPerP
+	if n < minShardCount {
+		n = minShardCount
+	}
+	return nextPowerOfTwo(n)
+}
+
+// nextPowerOfTwo returns the smallest power of two >= n (n must be > 0).
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
 
 // shard represents a single partition of the shadow memory.
 // Each shard has its own sync.Map to reduce contention.
@@ -37,42 +93,99 @@ type shard struct {
 // the last write and read epochs for that location. This is the foundation
 // of the FastTrack race detection algorithm.
 //
-// Implementation: Sharded sync.Map design (Phase 2 optimization).
+// Implementation: Sharded sync.Map design (Phase 2 optimization), with
+// shard count scaled to GOMAXPROCS (v0.4.0, see ShardCount).
 //
 // Architecture:
-//   - 256 shards, each containing its own sync.Map
+//   - ShardCount shards, each containing its own sync.Map
 //   - Addresses are distributed across shards using low-order bits
 //   - Each shard is cache-line aligned to prevent false sharing
 //
 // Sharding Strategy:
-//   - Shard selection: (addr >> 3) & (ShardCount - 1)
-//   - Uses bits 3-10 of address (assumes 8-byte alignment)
+//   - Shard selection: (addr >> 3) & shardMask
+//   - Uses the low bits of address (assumes 8-byte alignment)
 //   - Fast bit-masking operation (no division/modulo needed)
 //   - Even distribution for both sequential and random addresses
 //
 // Performance Characteristics:
 //   - Load (cache hit): ~5-10ns (same as sync.Map)
 //   - Load (cache miss): ~20-50ns (same as sync.Map)
-//   - Concurrent contention: Reduced by ~256x (each shard independent)
-//   - Memory overhead: +16KB for padding (negligible for race detector)
+//   - Concurrent contention: Reduced by ~ShardCount (each shard independent)
+//   - Memory overhead: 64 bytes per shard (negligible for race detector)
 //
 // Benefits vs Single sync.Map:
 //   - Multi-goroutine: 10-20% faster (reduced contention)
 //   - Single-goroutine: Same performance (sharding overhead negligible)
-//   - Scalability: Near-linear scaling up to 256 cores
+//   - Scalability: Shard count grows with GOMAXPROCS, so contention per
+//     shard stays low instead of being fixed for whatever core count the
+//     constant happened to be tuned for.
 //
 // Thread Safety: All operations are thread-safe. Each shard's sync.Map
 // handles concurrent access internally without requiring external locks.
 type ShadowMemory struct {
-	shards [ShardCount]shard // Sharded maps for reduced contention
+	shards []shard // Sharded maps for reduced contention, length == ShardCount
+
+	// maxBytes is the configured memory cap (synth-3578,
+	// DetectorOptions.MaxShadowBytes), or 0 for unbounded (the default).
+	// Set once at construction (see NewShadowMemoryWithCap) and never
+	// mutated afterward, so it's safe to read without synchronization.
+	maxBytes uint64
+
+	// cellCount approximates the number of live cells across all shards.
+	// Maintained with plain atomic adds on insert/evict rather than an
+	// exact Count() call (which would require an O(n) Range on every
+	// GetOrCreate), so budget checks stay cheap enough for the hot path.
+	cellCount atomic.Int64
+
+	// accessCounter is a monotonically increasing logical clock, advanced
+	// once per GetOrCreate call and stamped onto the resolved cell's
+	// VarState.lastTouch. It backs the approximate LRU eviction ordering -
+	// see VarState.Touch.
+	accessCounter atomic.Uint64
+
+	// evictions counts cells evicted to stay under maxBytes (synth-3578).
+	// Exposed via Evictions() so callers running inside a memory-constrained
+	// container can alert when the cap is actually being hit, rather than
+	// just silently losing detection coverage for evicted addresses.
+	evictions atomic.Uint64
 }
 
+// estimatedVarStateBytes approximates the resident size of one shadow cell
+// for MaxShadowBytes accounting (synth-3578). It's deliberately the
+// unpromoted (fast path) size, not the ~1KB promoted size described in
+// VarState's doc comment: promotion is comparatively rare (SmartTrack
+// expects 90%+ unpromoted), and undercounting promoted cells means the cap
+// is enforced a little late rather than evicting unnecessarily aggressively
+// on workloads that rarely promote.
+const estimatedVarStateBytes = uint64(unsafe.Sizeof(VarState{}))
+
+// evictionSampleSize bounds how many candidate entries evictIfOverBudget
+// inspects across evictionScanShards shards before picking one to evict
+// (synth-3578). Scanning a fixed small sample - rather than the whole
+// ShadowMemory - keeps eviction O(1) relative to the number of tracked
+// addresses, at the cost of only approximating "least recently touched"
+// within that sample instead of finding the true global minimum.
+const evictionSampleSize = 8
+
+// evictionScanShards bounds how many shards evictIfOverBudget will roam
+// into looking for evictionSampleSize candidates (synth-3578 review fix).
+// Sampling only the just-inserted cell's own shard was the original
+// design, but that cell is always excluded from candidacy (it was just
+// touched - evicting it would undo the very insert that triggered this
+// call), so a shard holding only that one live entry - common at realistic
+// live-address counts with ShardCount scaling into the hundreds - had no
+// other candidate to evict and evicted the just-inserted cell anyway.
+// Roaming into a handful of neighboring shards gives eviction a real
+// population to sample from even when the triggering shard is otherwise
+// empty.
+const evictionScanShards = 8
+
 // getShard returns the shard for the given address.
 //
 // Sharding Strategy:
-//   - Uses (addr >> 3) & (ShardCount - 1) for fast shard selection
+//   - Uses (addr >> 3) & shardMask for fast shard selection
 //   - Divides by 8 (>> 3) assuming 8-byte aligned addresses
-//   - Masks with ShardCount - 1 (= 255) to get shard index 0-255
+//   - Masks with shardMask (ShardCount - 1) to get shard index
 //   - Bit-masking is faster than modulo for power-of-2 shard counts
 //
 // Address Distribution:
@@ -80,7 +193,7 @@ type ShadowMemory struct {
 //   - Random addresses: Uniform distribution across shards
 //   - Struct fields: Different fields map to different shards
 //
-// Example:
+// Example (ShardCount = 256):
 //   - addr = 0x1000 (4096) → shard = (4096 >> 3) & 255 = 512 & 255 = 0
 //   - addr = 0x1008 (4104) → shard = (4104 >> 3) & 255 = 513 & 255 = 1
 //   - addr = 0x2FF8 (12280) → shard = (12280 >> 3) & 255 = 1535 & 255 = 255
@@ -92,8 +205,8 @@ type ShadowMemory struct {
 //go:inline
 func (sm *ShadowMemory) getShard(addr uintptr) *sync.Map {
 	// Shard index = (addr / 8) % ShardCount
-	// Optimized: (addr >> 3) & (ShardCount - 1) for power-of-2 ShardCount
-	shardIdx := (addr >> 3) & (ShardCount - 1)
+	// Optimized: (addr >> 3) & shardMask for power-of-2 ShardCount
+	shardIdx := (addr >> 3) & shardMask
 	return &sm.shards[shardIdx].cells
 }
 
@@ -102,9 +215,10 @@ func (sm *ShadowMemory) getShard(addr uintptr) *sync.Map {
 // The returned ShadowMemory is ready to use and safe for concurrent access
 // by multiple goroutines.
 //
-// Implementation Note: The shards array is zero-initialized by Go's runtime,
-// so all 256 sync.Map instances are ready to use immediately without
-// explicit initialization.
+// Implementation Note: The shards slice is allocated with length ShardCount
+// (derived from GOMAXPROCS at package init, see computeShardCount) and is
+// zero-initialized by Go's runtime, so every shard's sync.Map is ready to
+// use immediately without explicit initialization.
 //
 // Example:
 //
@@ -112,7 +226,24 @@ func (sm *ShadowMemory) getShard(addr uintptr) *sync.Map {
 //	vs := sm.GetOrCreate(0x1234)  // Get or allocate shadow cell
 //	vs.W = epoch.NewEpoch(1, 10)  // Record write access
 func NewShadowMemory() *ShadowMemory {
-	return &ShadowMemory{}
+	return &ShadowMemory{shards: make([]shard, ShardCount)}
+}
+
+// NewShadowMemoryWithCap creates an empty shadow memory map that evicts
+// least-recently-touched cells once its estimated footprint would exceed
+// maxBytes (synth-3578, DetectorOptions.MaxShadowBytes).
+//
+// maxBytes == 0 means unbounded - equivalent to NewShadowMemory(). This is
+// what lets the detector keep running inside a memory-constrained container
+// instead of growing shadow memory without limit until the process OOMs:
+// once the cap is hit, tracking an address that hasn't been touched in a
+// while is evicted to make room for a newer one, trading detection of races
+// on cold addresses for bounded memory use.
+//
+// See GetOrCreate for where the cap is enforced and Evictions for the
+// resulting metric.
+func NewShadowMemoryWithCap(maxBytes uint64) *ShadowMemory {
+	return &ShadowMemory{shards: make([]shard, ShardCount), maxBytes: maxBytes}
 }
 
 // GetOrCreate retrieves the VarState for the given address, creating it if needed.
@@ -151,15 +282,113 @@ func (sm *ShadowMemory) GetOrCreate(addr uintptr) *VarState {
 
 	// Fast path: Try to load existing cell from the shard.
 	if val, ok := shard.Load(addr); ok {
-		return val.(*VarState)
+		vs := val.(*VarState)
+		vs.Touch(sm.accessCounter.Add(1))
+		return vs
 	}
 
 	// Slow path: Allocate new cell and store atomically.
 	// LoadOrStore ensures only one VarState is created even if multiple
 	// goroutines race to create the cell for this address.
 	vs := NewVarState()
-	actual, _ := shard.LoadOrStore(addr, vs)
-	return actual.(*VarState)
+	actual, loaded := shard.LoadOrStore(addr, vs)
+	result := actual.(*VarState)
+	result.Touch(sm.accessCounter.Add(1))
+
+	// synth-3578: only a genuinely new cell grows cellCount and can push it
+	// over maxBytes - a LoadOrStore that lost the race to a concurrent
+	// caller (loaded == true) just resolved to an existing cell above.
+	if !loaded {
+		sm.cellCount.Add(1)
+		sm.evictIfOverBudget(addr)
+	}
+	return result
+}
+
+// scanForEvictionCandidate samples up to evictionSampleSize cells across up
+// to shardsToScan shards, starting at addr's own shard and wrapping via
+// shardMask, looking for the least-recently-touched cell. addr itself is
+// never a candidate (synth-3578) - see evictIfOverBudget's doc comment.
+func (sm *ShadowMemory) scanForEvictionCandidate(addr uintptr, shardsToScan uintptr) (oldestAddr uintptr, oldestTouch uint64, found bool) {
+	sampled := 0
+	startShard := (addr >> 3) & shardMask
+	for i := uintptr(0); i < shardsToScan && sampled < evictionSampleSize; i++ {
+		cells := &sm.shards[(startShard+i)&shardMask].cells
+		cells.Range(func(key, value interface{}) bool {
+			k := key.(uintptr)
+			if k == addr {
+				return true
+			}
+			if touch := value.(*VarState).LastTouch(); !found || touch < oldestTouch {
+				oldestAddr, oldestTouch, found = k, touch, true
+			}
+			sampled++
+			return sampled < evictionSampleSize
+		})
+	}
+	return
+}
+
+// evictIfOverBudget evicts one least-recently-touched cell sampled from up
+// to evictionScanShards shards near addr's own if sm's estimated footprint
+// exceeds maxBytes (synth-3578). No-op when maxBytes is 0 (unbounded, the
+// default).
+//
+// addr - the address GetOrCreate just resolved a cell for - is always
+// excluded from candidacy: it was touched a moment ago by this very call,
+// so evicting it would immediately undo the insert that triggered this
+// eviction and wipe a hot variable's tracked state instead of a cold one
+// (see evictionScanShards' doc comment for the bug this fixes).
+func (sm *ShadowMemory) evictIfOverBudget(addr uintptr) {
+	if sm.maxBytes == 0 {
+		return
+	}
+	if uint64(sm.cellCount.Load())*estimatedVarStateBytes <= sm.maxBytes {
+		return
+	}
+
+	oldestAddr, oldestTouch, found := sm.scanForEvictionCandidate(addr, evictionScanShards)
+	if !found {
+		// Fallback: the windowed scan above is a fast-path optimization that
+		// can legitimately come up empty when very few cells are scattered
+		// across many shards (e.g. a small MaxShadowBytes budget with
+		// ShardCount in the hundreds). Scan every shard rather than give up
+		// and leave sm over budget, or fall back to evicting addr itself -
+		// see the function doc comment for why that would defeat the point.
+		oldestAddr, oldestTouch, found = sm.scanForEvictionCandidate(addr, uintptr(ShardCount))
+	}
+	if !found {
+		return
+	}
+
+	sm.getShard(oldestAddr).Delete(oldestAddr)
+	sm.cellCount.Add(-1)
+	sm.evictions.Add(1)
+	log.Debug("evicted shadow cell %#x (last touched at %d, %d total evictions) - over %d byte budget",
+		oldestAddr, oldestTouch, sm.evictions.Load(), sm.maxBytes)
+}
+
+// Evictions returns the number of shadow cells evicted so far to stay
+// under a configured MaxShadowBytes cap (synth-3578). Always 0 when the
+// detector was created without a cap.
+//
+// This is a monitoring accessor, not called from the hot path.
+//
+// Thread Safety: Safe for concurrent calls (atomic load).
+func (sm *ShadowMemory) Evictions() uint64 {
+	return sm.evictions.Load()
+}
+
+// EstimatedBytes returns sm's approximate memory footprint: the number of
+// live cells times estimatedVarStateBytes (synth-3578). This is what
+// evictIfOverBudget compares against maxBytes - it undercounts promoted
+// cells (see estimatedVarStateBytes) and is not updated atomically with
+// cellCount's individual increments/decrements, so treat it as an
+// approximation suitable for monitoring, not an exact accounting.
+//
+// Thread Safety: Safe for concurrent calls (atomic load).
+func (sm *ShadowMemory) EstimatedBytes() uint64 {
+	return uint64(sm.cellCount.Load()) * estimatedVarStateBytes
 }
 
 // Get retrieves the VarState for the given address if it exists.
@@ -211,12 +440,13 @@ func (sm *ShadowMemory) Get(addr uintptr) *VarState {
 // The caller must ensure no other goroutines are accessing the ShadowMemory
 // during Reset() (typically used only in test setup/teardown).
 //
-// Implementation Note: We reset each of the 256 shards independently.
+// Implementation Note: We reset each of the ShardCount shards independently.
 // sync.Map doesn't provide a Clear() method in Go 1.21, so we replace
 // each shard's sync.Map with a new instance. This allows the garbage
 // collector to reclaim the old maps.
 //
-// Performance: O(ShardCount) = O(256) time complexity (constant).
+// Performance: O(ShardCount) time complexity (constant for a given process,
+// since ShardCount is fixed at package init).
 // Each shard reset is just a pointer assignment.
 // The old maps will be garbage collected when no references remain.
 //
@@ -226,8 +456,196 @@ func (sm *ShadowMemory) Get(addr uintptr) *VarState {
 //	vs := sm.Get(0x1234)  // Returns nil - address forgotten
 func (sm *ShadowMemory) Reset() {
 	// Reset each shard independently.
-	// This iterates over all 256 shards and replaces their sync.Map instances.
 	for i := range sm.shards {
 		sm.shards[i].cells = sync.Map{}
 	}
+
+	// synth-3578: cellCount must track the shards it approximates, or
+	// evictIfOverBudget would keep comparing against a stale, too-high
+	// count and evict cells that were never re-created after Reset.
+	// evictions is a lifetime counter of this ShadowMemory's own
+	// instance (mirrors statsCounters.reset() being called from
+	// Detector.Reset()), so it's cleared along with everything else.
+	sm.cellCount.Store(0)
+	sm.evictions.Store(0)
+}
+
+// Count returns the total number of shadow cells currently tracked across
+// all shards.
+//
+// This is a monitoring/metrics accessor (v0.5.0) - it is not called from
+// OnRead/OnWrite's hot path, so its O(n) sync.Map.Range cost over every
+// tracked address is acceptable for periodic reporting (e.g. expvar,
+// Prometheus scrapes) but would be far too slow per-access.
+//
+// Thread Safety: Safe for concurrent calls, though the result may be
+// stale by the time it's read if other goroutines are concurrently
+// allocating or resetting cells.
+func (sm *ShadowMemory) Count() int {
+	count := 0
+	for i := range sm.shards {
+		sm.shards[i].cells.Range(func(_, _ interface{}) bool {
+			count++
+			return true
+		})
+	}
+	return count
+}
+
+// ClearRange forgets every shadow cell whose address falls in [lo, hi)
+// (synth-3580).
+//
+// This exists for goroutine stack recycling: once a goroutine terminates,
+// the Go runtime is free to hand its stack's address range to an unrelated
+// future goroutine, whose locals would otherwise inherit the retired
+// goroutine's shadow epochs at those same addresses and be reported as
+// racing with accesses that happened before the stack was ever reused. See
+// Detector.ClearGoroutineStack, which calls this with the terminating
+// goroutine's tracked stack bounds.
+//
+// Like Count and TopConsumers, this is O(n) over every tracked address
+// (sync.Map has no range-indexed lookup, so there's no cheaper way to find
+// which cells fall in an arbitrary [lo, hi)), which is acceptable for a
+// per-goroutine-exit call but would be far too slow on the OnRead/OnWrite
+// hot path.
+//
+// Thread Safety: Safe for concurrent calls, same as Get/GetOrCreate -
+// concurrent accesses to addresses inside the range may still observe a
+// stale cell if they race with this call, exactly as any other concurrent
+// Delete/Range interleaving on a sync.Map would.
+func (sm *ShadowMemory) ClearRange(lo, hi uintptr) {
+	if lo >= hi {
+		return
+	}
+	for i := range sm.shards {
+		shard := &sm.shards[i].cells
+		shard.Range(func(key, _ interface{}) bool {
+			addr := key.(uintptr)
+			if addr >= lo && addr < hi {
+				shard.Delete(key)
+				sm.cellCount.Add(-1)
+			}
+			return true
+		})
+	}
+}
+
+// Snapshot returns a deep copy of every tracked shadow cell, keyed by
+// address (synth-3576).
+//
+// The returned map is independent of the live ShadowMemory: mutating a
+// VarState afterward (live or in the snapshot) never affects the other.
+// This backs Detector.Snapshot, letting a test framework checkpoint shadow
+// memory before a subtest and hand it to RestoreFrom afterward, instead of
+// the all-or-nothing Reset().
+//
+// Thread Safety: NOT safe for concurrent access, same convention as
+// Reset() - the caller must ensure no other goroutines are accessing the
+// ShadowMemory while snapshotting.
+func (sm *ShadowMemory) Snapshot() map[uintptr]*VarState {
+	snapshot := make(map[uintptr]*VarState)
+	for i := range sm.shards {
+		sm.shards[i].cells.Range(func(key, value interface{}) bool {
+			snapshot[key.(uintptr)] = value.(*VarState).Clone()
+			return true
+		})
+	}
+	return snapshot
+}
+
+// RestoreFrom replaces all shadow memory cells with a deep copy of
+// snapshot, as previously returned by Snapshot (synth-3576).
+//
+// Cloning snapshot's entries (rather than adopting them directly) means the
+// same snapshot can be restored from more than once without later restores
+// sharing state with earlier ones.
+//
+// Thread Safety: NOT safe for concurrent access, same convention as
+// Reset() - the caller must ensure no other goroutines are accessing the
+// ShadowMemory during RestoreFrom().
+func (sm *ShadowMemory) RestoreFrom(snapshot map[uintptr]*VarState) {
+	sm.Reset()
+	for addr, vs := range snapshot {
+		shard := sm.getShard(addr)
+		shard.Store(addr, vs.Clone())
+	}
+	// synth-3578: Reset() zeroed cellCount, and the Store calls above don't
+	// go through GetOrCreate's increment, so cellCount needs to be brought
+	// back in line with what was actually restored - otherwise
+	// evictIfOverBudget would think shadow memory is empty right after a
+	// Restore that in fact left it well over maxBytes.
+	sm.cellCount.Store(int64(len(snapshot)))
+}
+
+// TopConsumers returns up to n shadow cells with the highest write counts,
+// sorted descending, for surfacing which addresses dominate shadow memory
+// traffic in a long-running program.
+//
+// Like Count, this ranges over every shard and is meant for periodic
+// debug/monitoring reporting, not the hot path.
+//
+// Thread Safety: Safe for concurrent calls; the result may be stale by the
+// time it's read.
+func (sm *ShadowMemory) TopConsumers(n int) []Consumer {
+	var consumers []Consumer
+	for i := range sm.shards {
+		sm.shards[i].cells.Range(func(key, value interface{}) bool {
+			vs := value.(*VarState)
+			if count := vs.GetWriteCount(); count > 0 {
+				consumers = append(consumers, Consumer{Addr: key.(uintptr), WriteCount: count})
+			}
+			return true
+		})
+	}
+
+	sort.Slice(consumers, func(i, j int) bool {
+		return consumers[i].WriteCount > consumers[j].WriteCount
+	})
+
+	if n >= 0 && len(consumers) > n {
+		consumers = consumers[:n]
+	}
+	return consumers
+}
+
+// TopAddresses returns up to n shadow cells with the highest total
+// OnRead/OnWrite check counts, sorted descending, alongside each cell's
+// promotion/demotion counts (synth-3642). Unlike TopConsumers, which ranks
+// by write traffic alone, this ranks by every access - the metric that
+// actually tracks detector overhead, since a read-heavy hot variable costs
+// just as much per check as a write-heavy one.
+//
+// Like TopConsumers, this ranges over every shard and is meant for
+// periodic debug/monitoring reporting, not the hot path.
+//
+// Thread Safety: Safe for concurrent calls; the result may be stale by the
+// time it's read.
+func (sm *ShadowMemory) TopAddresses(n int) []Consumer {
+	var consumers []Consumer
+	for i := range sm.shards {
+		sm.shards[i].cells.Range(func(key, value interface{}) bool {
+			vs := value.(*VarState)
+			if count := vs.GetCheckCount(); count > 0 {
+				consumers = append(consumers, Consumer{
+					Addr:       key.(uintptr),
+					WriteCount: vs.GetWriteCount(),
+					CheckCount: count,
+					Promotions: vs.GetPromotionCount(),
+					Demotions:  vs.GetDemotionCount(),
+					WritePC:    vs.GetWritePC(),
+					ReadPC:     vs.GetReadPC(),
+				})
+			}
+			return true
+		})
+	}
+
+	sort.Slice(consumers, func(i, j int) bool {
+		return consumers[i].CheckCount > consumers[j].CheckCount
+	})
+
+	if n >= 0 && len(consumers) > n {
+		consumers = consumers[:n]
+	}
+	return consumers
 }
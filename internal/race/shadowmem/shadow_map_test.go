@@ -1,3 +1,5 @@
+//go:build !racedetector_small
+
 package shadowmem
 
 import (
@@ -162,6 +164,43 @@ func TestShadowMemoryGet_AfterGetOrCreate(t *testing.T) {
 	t.Logf("Get(0x%x) correctly found cell after GetOrCreate()", addr)
 }
 
+// TestNextPowerOfTwo verifies the power-of-two rounding used to size ShardCount.
+func TestNextPowerOfTwo(t *testing.T) {
+	cases := []struct {
+		n    int
+		want int
+	}{
+		{1, 1},
+		{2, 2},
+		{3, 4},
+		{63, 64},
+		{64, 64},
+		{65, 128},
+		{200, 256},
+	}
+	for _, c := range cases {
+		if got := nextPowerOfTwo(c.n); got != c.want {
+			t.Errorf("nextPowerOfTwo(%d) = %d, want %d", c.n, got, c.want)
+		}
+	}
+}
+
+// TestShardCount_IsPowerOfTwoAtLeastMin verifies the package-level ShardCount
+// (computed from GOMAXPROCS at init) satisfies the invariants getShard's
+// bit-mask selection relies on: a power of two, and no smaller than
+// minShardCount regardless of how low GOMAXPROCS is in this environment.
+func TestShardCount_IsPowerOfTwoAtLeastMin(t *testing.T) {
+	if ShardCount < minShardCount {
+		t.Errorf("ShardCount = %d, want >= minShardCount (%d)", ShardCount, minShardCount)
+	}
+	if ShardCount&(ShardCount-1) != 0 {
+		t.Errorf("ShardCount = %d, want a power of two", ShardCount)
+	}
+	if shardMask != uintptr(ShardCount-1) {
+		t.Errorf("shardMask = %d, want %d", shardMask, ShardCount-1)
+	}
+}
+
 // TestShadowMemoryReset verifies Reset clears all cells.
 func TestShadowMemoryReset(t *testing.T) {
 	sm := NewShadowMemory()
@@ -580,6 +619,147 @@ func BenchmarkShadowMemory_HighContention(b *testing.B) {
 	})
 }
 
+// TestShadowMemoryCount verifies Count reflects the number of distinct
+// addresses tracked, across shards.
+func TestShadowMemoryCount(t *testing.T) {
+	sm := NewShadowMemory()
+
+	if got := sm.Count(); got != 0 {
+		t.Fatalf("Count() = %d on empty ShadowMemory, want 0", got)
+	}
+
+	addresses := []uintptr{0x1111, 0x2222, 0x3333, 0x4444}
+	for _, addr := range addresses {
+		sm.GetOrCreate(addr)
+	}
+
+	if got := sm.Count(); got != len(addresses) {
+		t.Errorf("Count() = %d, want %d", got, len(addresses))
+	}
+
+	// Re-creating an existing address must not double-count it.
+	sm.GetOrCreate(addresses[0])
+	if got := sm.Count(); got != len(addresses) {
+		t.Errorf("Count() after re-GetOrCreate = %d, want %d (no duplicate)", got, len(addresses))
+	}
+}
+
+// TestShadowMemoryCount_AfterReset verifies Count returns to zero once Reset
+// clears all shards.
+func TestShadowMemoryCount_AfterReset(t *testing.T) {
+	sm := NewShadowMemory()
+	sm.GetOrCreate(0x5555)
+	sm.GetOrCreate(0x6666)
+
+	sm.Reset()
+
+	if got := sm.Count(); got != 0 {
+		t.Errorf("Count() after Reset() = %d, want 0", got)
+	}
+}
+
+// TestShadowMemoryTopConsumers_EmptyWhenNoWrites verifies addresses with no
+// recorded writes are excluded rather than reported with a zero count.
+func TestShadowMemoryTopConsumers_EmptyWhenNoWrites(t *testing.T) {
+	sm := NewShadowMemory()
+	sm.GetOrCreate(0x1000)
+	sm.GetOrCreate(0x2000)
+
+	if got := sm.TopConsumers(10); len(got) != 0 {
+		t.Errorf("TopConsumers() = %v, want empty (no writes recorded)", got)
+	}
+}
+
+// TestShadowMemoryTopConsumers_RankedByWriteCount verifies consumers are
+// sorted descending by write count.
+func TestShadowMemoryTopConsumers_RankedByWriteCount(t *testing.T) {
+	sm := NewShadowMemory()
+
+	addrs := []uintptr{0x1000, 0x2000, 0x3000}
+	counts := []int{1, 5, 3}
+	for i, addr := range addrs {
+		vs := sm.GetOrCreate(addr)
+		for j := 0; j < counts[i]; j++ {
+			vs.IncrementWriteCount()
+		}
+	}
+
+	got := sm.TopConsumers(10)
+	if len(got) != 3 {
+		t.Fatalf("TopConsumers() returned %d entries, want 3", len(got))
+	}
+
+	want := []uintptr{0x2000, 0x3000, 0x1000} // write counts 5, 3, 1
+	for i, c := range got {
+		if c.Addr != want[i] {
+			t.Errorf("TopConsumers()[%d].Addr = %#x, want %#x", i, c.Addr, want[i])
+		}
+	}
+	if got[0].WriteCount != 5 || got[1].WriteCount != 3 || got[2].WriteCount != 1 {
+		t.Errorf("TopConsumers() write counts = %+v, want [5 3 1]", got)
+	}
+}
+
+// TestShadowMemoryTopConsumers_Truncates verifies only the top n entries are
+// returned when there are more consumers than requested.
+func TestShadowMemoryTopConsumers_Truncates(t *testing.T) {
+	sm := NewShadowMemory()
+
+	for i := 0; i < 5; i++ {
+		vs := sm.GetOrCreate(uintptr(0x1000 + i*8))
+		vs.IncrementWriteCount()
+	}
+
+	if got := sm.TopConsumers(2); len(got) != 2 {
+		t.Errorf("TopConsumers(2) returned %d entries, want 2", len(got))
+	}
+}
+
+// TestShadowMemorySnapshot_RestoreFrom_RoundTrips verifies a cell present
+// at Snapshot time is restored with the same write epoch, even after the
+// live shadow memory diverges in between (synth-3576).
+func TestShadowMemorySnapshot_RestoreFrom_RoundTrips(t *testing.T) {
+	sm := NewShadowMemory()
+	addr := uintptr(0x1000)
+
+	vs := sm.GetOrCreate(addr)
+	vs.SetW(epoch.NewEpoch(1, 5))
+
+	snap := sm.Snapshot()
+
+	// Diverge the live shadow memory after snapshotting.
+	vs.SetW(epoch.NewEpoch(1, 99))
+	sm.GetOrCreate(uintptr(0x2000))
+
+	sm.RestoreFrom(snap)
+
+	restored := sm.Get(addr)
+	if restored == nil {
+		t.Fatal("Get() after RestoreFrom = nil, want restored cell")
+	}
+	if got := restored.GetW(); got != epoch.NewEpoch(1, 5) {
+		t.Errorf("GetW() after RestoreFrom = %v, want epoch(1,5)", got)
+	}
+	if sm.Get(uintptr(0x2000)) != nil {
+		t.Error("Get(0x2000) after RestoreFrom = non-nil, want nil (not in snapshot)")
+	}
+}
+
+// TestShadowMemorySnapshot_IsIndependentCopy verifies mutating a snapshot's
+// VarState does not affect the live shadow memory it was taken from.
+func TestShadowMemorySnapshot_IsIndependentCopy(t *testing.T) {
+	sm := NewShadowMemory()
+	addr := uintptr(0x1000)
+	sm.GetOrCreate(addr).SetW(epoch.NewEpoch(1, 5))
+
+	snap := sm.Snapshot()
+	snap[addr].SetW(epoch.NewEpoch(1, 100))
+
+	if got := sm.Get(addr).GetW(); got != epoch.NewEpoch(1, 5) {
+		t.Errorf("live GetW() = %v after mutating snapshot, want unchanged epoch(1,5)", got)
+	}
+}
+
 // BenchmarkShadowMemory_Reset benchmarks Reset performance.
 // This is not on hot path but good to measure.
 func BenchmarkShadowMemory_Reset(b *testing.B) {
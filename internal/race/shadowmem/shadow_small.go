@@ -0,0 +1,358 @@
+//go:build racedetector_small
+
+// Package shadowmem's constrained-target ShadowMemory implementation. Built
+// under the racedetector_small build tag (synth-3616), for targets where
+// CGO TSan is impossible and the default sharded sync.Map implementation
+// (shadow_map.go) is unaffordable: memory-limited embedded/TinyGo-class
+// targets that need a bounded, predictable footprint instead of a map that
+// grows with the number of distinct addresses ever touched.
+//
+// Build with:
+//
+//	go build -tags racedetector_small ./...
+package shadowmem
+
+import (
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// estimatedVarStateBytes mirrors shadow_map.go's constant of the same name
+// (unavailable here - that file is excluded by racedetector_small) for
+// EstimatedBytes' footprint calculation.
+const estimatedVarStateBytes = uint64(unsafe.Sizeof(VarState{}))
+
+// smallShadowCapacity is the fixed number of shadow cells this build can
+// track at once. Sized well below CASBasedShadow's 65536-slot table (which
+// targets throughput on a full-size host, not a bounded footprint) so the
+// backing array - smallShadowCapacity * unsafe.Sizeof(VarState{}) - stays
+// well under a megabyte on the small platforms this tag targets.
+//
+// There's no cap-tuning constructor: unlike NewShadowMemoryWithCap's
+// maxBytes (an operator-facing budget over an otherwise-unbounded map),
+// this is a compile-time constant sizing a fixed array, the same way
+// CASBasedShadow's 65536 is a constant rather than a parameter.
+const smallShadowCapacity = 1024
+
+// ShadowMemory is the racedetector_small build's shadow memory: a single
+// fixed-size array of cells guarded by one mutex, reusing CASBasedShadow's
+// linear-probing slot layout and fastHash (see shadow_cas.go) rather than
+// introducing a second hashing scheme.
+//
+// Unlike the default build's ShadowMemory (sharded sync.Map, unbounded
+// unless MaxShadowBytes is set) or CASBasedShadow (lock-free CAS, fixed but
+// generously-sized for a full host), this implementation deliberately
+// trades away both sharding and lock-freedom for the simplest possible
+// correct design: a single plain sync.Mutex protecting a single fixed
+// array. On a target with one or few cores, sharding buys nothing, and a
+// mutex compiles down to primitives every Go port (including TinyGo)
+// supports, whereas sync.Map's internal atomic.Pointer-heavy dirty/read map
+// promotion is exactly the kind of general-purpose machinery a constrained
+// target can't afford to carry.
+//
+// Because the array is fixed-size, GetOrCreate always evicts something once
+// the table is full - there is no maxBytes to stay under, the array itself
+// is the budget. Eviction picks the least-recently-touched cell out of a
+// small linear-probe run (mirroring evictionSampleSize in shadow_map.go),
+// using VarState.LastTouch/Touch exactly as the default build does.
+//
+// Thread Safety: All operations are safe for concurrent access.
+type ShadowMemory struct {
+	mu       sync.Mutex
+	cells    [smallShadowCapacity]smallCell
+	count    int
+	evicted  uint64
+	accessNo uint64
+}
+
+// smallCell is one slot in ShadowMemory's fixed array. occupied disambiguates
+// an empty slot from one whose addr happens to be the zero value.
+type smallCell struct {
+	addr     uintptr
+	state    *VarState
+	occupied bool
+}
+
+// probeLimit bounds how many slots GetOrCreate/Get will linear-probe past a
+// slot's natural hash position before giving up (a full table's worst case),
+// and how many slots the eviction scan inspects when picking a victim -
+// mirrors CASBasedShadow's own 8-probe bound and shadow_map.go's
+// evictionSampleSize, scaled down for smallShadowCapacity.
+const probeLimit = 8
+
+// NewShadowMemory creates an empty, ready-to-use ShadowMemory.
+func NewShadowMemory() *ShadowMemory {
+	return &ShadowMemory{}
+}
+
+// NewShadowMemoryWithCap exists so callers written against the default
+// build's constructor compile unchanged under racedetector_small.
+// maxBytes is ignored: this build's budget is smallShadowCapacity, a
+// compile-time constant, not a runtime-configurable byte cap.
+func NewShadowMemoryWithCap(maxBytes uint64) *ShadowMemory {
+	return &ShadowMemory{}
+}
+
+// slot returns the array index addr's cell naturally hashes to.
+func slot(addr uintptr) int {
+	return int(fastHash(addr)) % smallShadowCapacity
+}
+
+// GetOrCreate retrieves the VarState for addr, creating it (evicting the
+// least-recently-touched cell in the probe run if the table is full) if
+// needed.
+//
+// Thread Safety: safe for concurrent calls.
+func (sm *ShadowMemory) GetOrCreate(addr uintptr) *VarState {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	start := slot(addr)
+	for i := 0; i < probeLimit; i++ {
+		idx := (start + i) % smallShadowCapacity
+		c := &sm.cells[idx]
+		if c.occupied && c.addr == addr {
+			sm.accessNo++
+			c.state.Touch(sm.accessNo)
+			return c.state
+		}
+		if !c.occupied {
+			sm.accessNo++
+			c.addr = addr
+			c.state = NewVarState()
+			c.state.Touch(sm.accessNo)
+			c.occupied = true
+			sm.count++
+			return c.state
+		}
+	}
+
+	// Probe run is full: evict the least-recently-touched cell in it to
+	// make room, same trade-off evictIfOverBudget makes in shadow_map.go.
+	victim := start
+	oldest := sm.cells[start].state.LastTouch()
+	for i := 1; i < probeLimit; i++ {
+		idx := (start + i) % smallShadowCapacity
+		if touch := sm.cells[idx].state.LastTouch(); touch < oldest {
+			victim, oldest = idx, touch
+		}
+	}
+
+	c := &sm.cells[victim]
+	c.addr = addr
+	c.state = NewVarState()
+	sm.accessNo++
+	c.state.Touch(sm.accessNo)
+	sm.evicted++
+	return c.state
+}
+
+// Get retrieves the VarState for addr if it exists, without creating one.
+//
+// Thread Safety: safe for concurrent calls.
+func (sm *ShadowMemory) Get(addr uintptr) *VarState {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	start := slot(addr)
+	for i := 0; i < probeLimit; i++ {
+		c := &sm.cells[(start+i)%smallShadowCapacity]
+		if c.occupied && c.addr == addr {
+			return c.state
+		}
+	}
+	return nil
+}
+
+// Reset clears every tracked cell.
+//
+// Thread Safety: NOT safe for concurrent access during Reset, same
+// convention as the default build.
+func (sm *ShadowMemory) Reset() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.cells = [smallShadowCapacity]smallCell{}
+	sm.count = 0
+	sm.evicted = 0
+}
+
+// Count returns the number of cells currently tracked.
+//
+// Thread Safety: safe for concurrent calls.
+func (sm *ShadowMemory) Count() int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.count
+}
+
+// ClearRange forgets every tracked cell whose address falls in [lo, hi),
+// same contract as the default build's ClearRange (synth-3580).
+//
+// Thread Safety: NOT safe for concurrent access during ClearRange, same
+// convention as Reset.
+func (sm *ShadowMemory) ClearRange(lo, hi uintptr) {
+	if lo >= hi {
+		return
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for i := range sm.cells {
+		c := &sm.cells[i]
+		if c.occupied && c.addr >= lo && c.addr < hi {
+			*c = smallCell{}
+			sm.count--
+		}
+	}
+}
+
+// Snapshot returns a deep copy of every tracked cell, keyed by address.
+//
+// Thread Safety: NOT safe for concurrent access, same convention as the
+// default build's Snapshot.
+func (sm *ShadowMemory) Snapshot() map[uintptr]*VarState {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	snapshot := make(map[uintptr]*VarState, sm.count)
+	for i := range sm.cells {
+		c := &sm.cells[i]
+		if c.occupied {
+			snapshot[c.addr] = c.state.Clone()
+		}
+	}
+	return snapshot
+}
+
+// RestoreFrom replaces all cells with a deep copy of snapshot, as previously
+// returned by Snapshot. Entries that collide past a full probe run are
+// evicted exactly as a GetOrCreate insertion under table pressure would be,
+// rather than silently overwriting an unrelated address.
+//
+// Thread Safety: NOT safe for concurrent access, same convention as Reset.
+func (sm *ShadowMemory) RestoreFrom(snapshot map[uintptr]*VarState) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.cells = [smallShadowCapacity]smallCell{}
+	sm.count = 0
+	sm.evicted = 0
+
+	for addr, vs := range snapshot {
+		sm.putLocked(addr, vs.Clone())
+	}
+}
+
+// putLocked inserts state at addr, evicting the least-recently-touched cell
+// in the probe run if it's full. Callers must hold sm.mu.
+func (sm *ShadowMemory) putLocked(addr uintptr, state *VarState) {
+	start := slot(addr)
+	for i := 0; i < probeLimit; i++ {
+		idx := (start + i) % smallShadowCapacity
+		if !sm.cells[idx].occupied {
+			sm.cells[idx] = smallCell{addr: addr, state: state, occupied: true}
+			sm.count++
+			return
+		}
+	}
+
+	victim := start
+	oldest := sm.cells[start].state.LastTouch()
+	for i := 1; i < probeLimit; i++ {
+		idx := (start + i) % smallShadowCapacity
+		if touch := sm.cells[idx].state.LastTouch(); touch < oldest {
+			victim, oldest = idx, touch
+		}
+	}
+	sm.cells[victim] = smallCell{addr: addr, state: state, occupied: true}
+	sm.evicted++
+}
+
+// TopConsumers returns up to n tracked cells with the highest write counts,
+// sorted descending.
+//
+// Thread Safety: safe for concurrent calls; the result may be stale by the
+// time it's read.
+func (sm *ShadowMemory) TopConsumers(n int) []Consumer {
+	sm.mu.Lock()
+	var consumers []Consumer
+	for i := range sm.cells {
+		c := &sm.cells[i]
+		if c.occupied {
+			if count := c.state.GetWriteCount(); count > 0 {
+				consumers = append(consumers, Consumer{Addr: c.addr, WriteCount: count})
+			}
+		}
+	}
+	sm.mu.Unlock()
+
+	sort.Slice(consumers, func(i, j int) bool {
+		return consumers[i].WriteCount > consumers[j].WriteCount
+	})
+
+	if n >= 0 && len(consumers) > n {
+		consumers = consumers[:n]
+	}
+	return consumers
+}
+
+// TopAddresses returns up to n tracked cells with the highest total
+// OnRead/OnWrite check counts, sorted descending, alongside each cell's
+// promotion/demotion counts (synth-3642). See the default build's
+// TopAddresses (shadow_map.go) for why this ranks by check count rather
+// than write count.
+//
+// Thread Safety: safe for concurrent calls; the result may be stale by the
+// time it's read.
+func (sm *ShadowMemory) TopAddresses(n int) []Consumer {
+	sm.mu.Lock()
+	var consumers []Consumer
+	for i := range sm.cells {
+		c := &sm.cells[i]
+		if c.occupied {
+			if count := c.state.GetCheckCount(); count > 0 {
+				consumers = append(consumers, Consumer{
+					Addr:       c.addr,
+					WriteCount: c.state.GetWriteCount(),
+					CheckCount: count,
+					Promotions: c.state.GetPromotionCount(),
+					Demotions:  c.state.GetDemotionCount(),
+					WritePC:    c.state.GetWritePC(),
+					ReadPC:     c.state.GetReadPC(),
+				})
+			}
+		}
+	}
+	sm.mu.Unlock()
+
+	sort.Slice(consumers, func(i, j int) bool {
+		return consumers[i].CheckCount > consumers[j].CheckCount
+	})
+
+	if n >= 0 && len(consumers) > n {
+		consumers = consumers[:n]
+	}
+	return consumers
+}
+
+// EstimatedBytes returns the fixed footprint of the smallShadowCapacity
+// array - unlike the default build's EstimatedBytes, this doesn't scale
+// with the number of live cells, because the array is allocated at its full
+// size up front rather than growing with use.
+//
+// Thread Safety: safe for concurrent calls (reads only constants).
+func (sm *ShadowMemory) EstimatedBytes() uint64 {
+	return smallShadowCapacity * estimatedVarStateBytes
+}
+
+// Evictions returns the number of cells evicted so far to make room in a
+// full probe run.
+//
+// Thread Safety: safe for concurrent calls.
+func (sm *ShadowMemory) Evictions() uint64 {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.evicted
+}
@@ -0,0 +1,151 @@
+//go:build racedetector_small
+
+package shadowmem
+
+import "testing"
+
+// TestShadowMemorySmall_GetOrCreate_ReturnsSameInstance verifies repeated
+// GetOrCreate calls for the same address return the same VarState
+// (synth-3616), the same "get or create" contract the default build's
+// ShadowMemory promises.
+func TestShadowMemorySmall_GetOrCreate_ReturnsSameInstance(t *testing.T) {
+	sm := NewShadowMemory()
+
+	vs1 := sm.GetOrCreate(0x1000)
+	vs2 := sm.GetOrCreate(0x1000)
+
+	if vs1 != vs2 {
+		t.Errorf("GetOrCreate(0x1000) returned different instances: %p vs %p", vs1, vs2)
+	}
+}
+
+// TestShadowMemorySmall_Get_MissingReturnsNil verifies Get doesn't create a
+// cell for an address never passed to GetOrCreate.
+func TestShadowMemorySmall_Get_MissingReturnsNil(t *testing.T) {
+	sm := NewShadowMemory()
+
+	if vs := sm.Get(0x2000); vs != nil {
+		t.Errorf("Get(0x2000) = %v, want nil for an address never created", vs)
+	}
+}
+
+// TestShadowMemorySmall_EvictsUnderPressure verifies that once every slot in
+// a probe run is occupied, GetOrCreate evicts rather than growing past the
+// fixed smallShadowCapacity table (synth-3616) - the core guarantee that
+// makes this build's footprint bounded and predictable.
+func TestShadowMemorySmall_EvictsUnderPressure(t *testing.T) {
+	sm := NewShadowMemory()
+
+	// Insert far more addresses than smallShadowCapacity so evictions are
+	// forced regardless of hash distribution.
+	const n = smallShadowCapacity * 4
+	for i := uintptr(0); i < n; i++ {
+		sm.GetOrCreate(i * 8)
+	}
+
+	if got := sm.Count(); got > smallShadowCapacity {
+		t.Errorf("Count() = %d, want at most %d (fixed table capacity)", got, smallShadowCapacity)
+	}
+	if got := sm.Evictions(); got == 0 {
+		t.Error("Evictions() = 0, want > 0 after inserting well past capacity")
+	}
+}
+
+// TestShadowMemorySmall_Reset verifies Reset forgets every tracked cell and
+// zeroes the eviction counter.
+func TestShadowMemorySmall_Reset(t *testing.T) {
+	sm := NewShadowMemory()
+	sm.GetOrCreate(0x3000)
+
+	sm.Reset()
+
+	if sm.Get(0x3000) != nil {
+		t.Error("Get(0x3000) != nil after Reset, want forgotten")
+	}
+	if got := sm.Count(); got != 0 {
+		t.Errorf("Count() after Reset = %d, want 0", got)
+	}
+}
+
+// TestShadowMemorySmall_ClearRange verifies addresses inside [lo, hi) are
+// forgotten while addresses outside it survive, matching the default
+// build's ClearRange contract (synth-3580).
+func TestShadowMemorySmall_ClearRange(t *testing.T) {
+	sm := NewShadowMemory()
+	sm.GetOrCreate(0x1000)
+	sm.GetOrCreate(0x1FF8)
+	sm.GetOrCreate(0x2000)
+
+	sm.ClearRange(0x1000, 0x2000)
+
+	if sm.Get(0x1000) != nil || sm.Get(0x1FF8) != nil {
+		t.Error("ClearRange did not forget addresses inside the range")
+	}
+	if sm.Get(0x2000) == nil {
+		t.Error("ClearRange forgot an address outside the range")
+	}
+}
+
+// TestShadowMemorySmall_SnapshotRestore verifies a Snapshot/RestoreFrom
+// round trip preserves tracked addresses and their write epochs.
+func TestShadowMemorySmall_SnapshotRestore(t *testing.T) {
+	sm := NewShadowMemory()
+	vs := sm.GetOrCreate(0x4000)
+	vs.IncrementWriteCount()
+
+	snap := sm.Snapshot()
+
+	sm.Reset()
+	if sm.Count() != 0 {
+		t.Fatal("Count() != 0 after Reset")
+	}
+
+	sm.RestoreFrom(snap)
+
+	restored := sm.Get(0x4000)
+	if restored == nil {
+		t.Fatal("Get(0x4000) = nil after RestoreFrom, want restored cell")
+	}
+	if restored.GetWriteCount() != 1 {
+		t.Errorf("restored WriteCount = %d, want 1", restored.GetWriteCount())
+	}
+}
+
+// TestShadowMemorySmall_TopConsumers verifies cells are ranked by write
+// count, descending.
+func TestShadowMemorySmall_TopConsumers(t *testing.T) {
+	sm := NewShadowMemory()
+
+	hot := sm.GetOrCreate(0x5000)
+	hot.IncrementWriteCount()
+	hot.IncrementWriteCount()
+	hot.IncrementWriteCount()
+
+	cold := sm.GetOrCreate(0x6000)
+	cold.IncrementWriteCount()
+
+	top := sm.TopConsumers(1)
+	if len(top) != 1 {
+		t.Fatalf("TopConsumers(1) returned %d entries, want 1", len(top))
+	}
+	if top[0].Addr != 0x5000 {
+		t.Errorf("TopConsumers(1)[0].Addr = %#x, want 0x5000 (highest write count)", top[0].Addr)
+	}
+}
+
+// TestShadowMemorySmall_EstimatedBytes_IsFixed verifies EstimatedBytes
+// reports the fixed table footprint regardless of how many cells are live,
+// unlike the default build's usage-scaled estimate (synth-3616).
+func TestShadowMemorySmall_EstimatedBytes_IsFixed(t *testing.T) {
+	sm := NewShadowMemory()
+	before := sm.EstimatedBytes()
+
+	sm.GetOrCreate(0x7000)
+
+	if got := sm.EstimatedBytes(); got != before {
+		t.Errorf("EstimatedBytes() = %d after one insert, want unchanged %d (fixed-size table)", got, before)
+	}
+	if before != smallShadowCapacity*estimatedVarStateBytes {
+		t.Errorf("EstimatedBytes() = %d, want %d (smallShadowCapacity * estimatedVarStateBytes)", before, smallShadowCapacity*estimatedVarStateBytes)
+	}
+}
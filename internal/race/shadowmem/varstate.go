@@ -23,6 +23,13 @@ const (
 	// promotedMarker indicates the VarState has been promoted to VectorClock.
 	// readerCount == promotedMarker means readClock is active.
 	promotedMarker uint8 = 255
+
+	// maxHistoryEntries is the ring buffer capacity for recent-access history
+	// (v0.4.0 "history_size" support, mirroring TSan's knob of the same name).
+	// The detector's configured HistorySize is clamped to this value. 8 slots
+	// is enough to usually retain the true racing partner even when several
+	// unrelated accesses land on the same cell between the race and its report.
+	maxHistoryEntries = 8
 )
 
 // VarState stores the access state for a single variable using adaptive representation.
@@ -58,6 +65,20 @@ const (
 //   - Lazy PC capture: + 8 bytes (writePC atomic) + 8 bytes (readPC atomic) = 101 bytes
 //   - Total fast path: ~104 bytes per variable (with padding)
 //   - Promoted path: 104 bytes + 1024 bytes (VectorClock allocation) = 1128 bytes
+//   - History ring buffer (v0.4.0): + 128 bytes (history[8]AccessRecord) + 3 bytes
+//     (historyCount/historyNext/historyLimit) = ~312 bytes total (with padding).
+//     Only populated when the detector is configured with HistorySize > 0;
+//     otherwise it sits zeroed, costing only the extra allocation size.
+//   - Shadow memory cap (synth-3578): + 8 bytes (lastTouch atomic) = 320
+//     bytes total. Always present - it's what ShadowMemory's approximate
+//     LRU eviction ranks cells by when DetectorOptions.MaxShadowBytes > 0.
+//   - Per-address muting (synth-3636): + 1 byte (poisoned atomic.Bool),
+//     padded to 8 = 328 bytes total.
+//   - Access-pattern stats (synth-3642): + 8 bytes (checkCount atomic)
+//     + 4 bytes (promotionCount atomic) + 4 bytes (demotionCount atomic)
+//     = 344 bytes total. Always present, same reasoning as lastTouch -
+//     Detector.TopAddresses needs every cell counted, not just the ones
+//     a caller opted into tracking.
 //
 // v0.3.0 ENHANCED READ-SHARED OPTIMIZATION (P1):
 // Trade-off: 88 bytes per variable (was 56 bytes) BUT avoids 1KB VectorClock allocation
@@ -72,10 +93,56 @@ const (
 type VarState struct {
 	// Lock-free hot-path fields (atomic operations, no mutex needed):
 	// These fields are accessed on EVERY memory access, so lock-free is critical.
-	W               atomic.Uint64  // Last write epoch (always present). Stores epoch.Epoch as uint64.
-	exclusiveWriter atomic.Int64   // TID of sole writer, -1 if shared, 0 if uninitialized.
-	writePC         atomic.Uintptr // PC (program counter) of last write caller (8 bytes).
-	readPC          atomic.Uintptr // PC (program counter) of last read caller (8 bytes).
+	W atomic.Uint64 // Last write epoch (always present). Stores epoch.Epoch as uint64.
+
+	// ownerWord packs the SmartTrack exclusive-writer state (see
+	// "Ownership states" above) together with a version counter that
+	// increments on every transition (synth-3633). GetExclusiveWriter/
+	// SetExclusiveWriter/CompareAndSwapExclusiveWriter present the same
+	// int64 TID-or-sentinel interface earlier versions of this field did;
+	// the version half is only visible through SnapshotOwner/ValidateOwner,
+	// which the detector's SmartTrack fast paths use to detect a concurrent
+	// ownership change (e.g. promotion to shared by a second writer)
+	// between reading ownerWord and later committing W - a window that,
+	// left unguarded, could commit a write without the happens-before
+	// check the promotion should have forced it through. See
+	// SnapshotOwner's doc comment.
+	ownerWord atomic.Uint64
+
+	writePC atomic.Uintptr // PC (program counter) of last write caller (8 bytes).
+	readPC  atomic.Uintptr // PC (program counter) of last read caller (8 bytes).
+
+	// poisoned marks this cell as muted after a reported race (synth-3636,
+	// DetectorOptions.MuteAfterReport): once true, OnWrite/OnRead return
+	// immediately after the poisoned check, skipping the FastTrack
+	// happens-before comparison (and, for the owned case, the SmartTrack
+	// fast path) entirely for every later access to this address. See
+	// Poison/IsPoisoned.
+	poisoned atomic.Bool
+
+	// checkCount tallies every OnRead/OnWrite call that reaches this cell,
+	// regardless of whether it promotes, demotes, or hits the fast path
+	// (synth-3642, Detector.TopAddresses). Lock-free since it increments on
+	// literally every access, same reasoning as the other hot-path fields
+	// above.
+	checkCount atomic.Uint64
+
+	// promotionCount and demotionCount mirror the detector-wide promotions/
+	// demotions counters in statsCollector, but per address (synth-3642),
+	// so TopAddresses can point at the specific hot variables driving
+	// promotion churn instead of only the aggregate rate.
+	promotionCount atomic.Uint32
+	demotionCount  atomic.Uint32
+
+	// lastTouch is a logical (not wall-clock) recency stamp, set from
+	// ShadowMemory's monotonic access counter whenever GetOrCreate resolves
+	// this cell (synth-3578, DetectorOptions.MaxShadowBytes). It backs the
+	// approximate LRU eviction ShadowMemory uses to stay under its
+	// configured memory cap - approximate because a goroutine that keeps
+	// hitting its own per-context shadow cell cache (see
+	// goroutine.RaceContext.CachedShadowCell) never calls back into
+	// GetOrCreate, so a genuinely hot cell can still look stale here.
+	lastTouch atomic.Uint64
 
 	// Mutex-protected fields (complex operations):
 	// These are accessed less frequently or require complex multi-field updates.
@@ -104,6 +171,29 @@ type VarState struct {
 	// Enables complete race reports showing both current and previous stacks.
 	writeStackHash uint64 // Hash of stack trace for last write (8 bytes).
 	readStackHash  uint64 // Hash of stack trace for last read (8 bytes, only set when read-shared).
+
+	// Access history ring buffer (v0.4.0 "history_size" support):
+	// Records the last few accesses to this cell so a race report can show
+	// several prior conflicting accesses instead of only the single most
+	// recent W/R epoch. Disabled by default (historyLimit == 0): RecordHistory
+	// is then a no-op and these fields stay zeroed, so the feature costs
+	// nothing unless the detector is explicitly configured with HistorySize > 0.
+	history      [maxHistoryEntries]AccessRecord // Ring buffer of recent accesses.
+	historyCount uint8                           // Number of valid entries (0..historyLimit).
+	historyNext  uint8                           // Index the next RecordHistory call writes to.
+	historyLimit uint8                           // Effective ring length, set on first RecordHistory call.
+}
+
+// AccessRecord captures a single historical memory access for the
+// history ring buffer (v0.4.0 "history_size" support).
+type AccessRecord struct {
+	// Epoch is the logical timestamp (TID + clock) of the access.
+	Epoch epoch.Epoch
+	// PC is the caller's program counter, captured the same way the hot-path
+	// lazy stack capture does (see SetWritePC/SetReadPC).
+	PC uintptr
+	// IsWrite distinguishes a write access from a read access.
+	IsWrite bool
 }
 
 // NewVarState creates a new zero-initialized variable state.
@@ -135,9 +225,13 @@ func NewVarState() *VarState {
 func (vs *VarState) Reset() {
 	// Reset lock-free fields using atomic stores.
 	vs.W.Store(0)
-	vs.exclusiveWriter.Store(0)
+	vs.ownerWord.Store(0)
 	vs.writePC.Store(0)
 	vs.readPC.Store(0)
+	vs.poisoned.Store(false)
+	vs.checkCount.Store(0)
+	vs.promotionCount.Store(0)
+	vs.demotionCount.Store(0)
 
 	// Reset mutex-protected fields.
 	vs.mu.Lock()
@@ -154,6 +248,12 @@ func (vs *VarState) Reset() {
 	vs.writeCount = 0
 	vs.writeStackHash = 0
 	vs.readStackHash = 0
+	for i := range vs.history {
+		vs.history[i] = AccessRecord{}
+	}
+	vs.historyCount = 0
+	vs.historyNext = 0
+	vs.historyLimit = 0
 	vs.mu.Unlock()
 }
 
@@ -454,6 +554,21 @@ func (vs *VarState) CompareAndSwapW(oldVal, newVal epoch.Epoch) bool {
 
 // === SmartTrack Ownership Tracking Methods (v0.2.0 Task 3) ===
 
+// packOwner and unpackOwner convert between the exclusive-writer state
+// (state) - the same TID-or-sentinel values GetExclusiveWriter has always
+// returned - and the packed uint64 stored in ownerWord: state in the low
+// 32 bits, a transition counter in the high 32 bits (synth-3633).
+//
+//go:nosplit
+func packOwner(state int32, version uint32) uint64 {
+	return uint64(version)<<32 | uint64(uint32(state))
+}
+
+//go:nosplit
+func unpackOwner(word uint64) (state int32, version uint32) {
+	return int32(uint32(word)), uint32(word >> 32)
+}
+
 // IsOwned returns true if the variable has an exclusive writer (owned state).
 //
 // Ownership states:
@@ -468,7 +583,8 @@ func (vs *VarState) CompareAndSwapW(oldVal, newVal epoch.Epoch) bool {
 //
 //go:nosplit
 func (vs *VarState) IsOwned() bool {
-	return vs.exclusiveWriter.Load() >= 0
+	state, _ := unpackOwner(vs.ownerWord.Load())
+	return state >= 0
 }
 
 // GetExclusiveWriter returns the TID of the exclusive writer, or -1 if shared.
@@ -483,7 +599,8 @@ func (vs *VarState) IsOwned() bool {
 //
 //go:nosplit
 func (vs *VarState) GetExclusiveWriter() int64 {
-	return vs.exclusiveWriter.Load()
+	state, _ := unpackOwner(vs.ownerWord.Load())
+	return int64(state)
 }
 
 // SetExclusiveWriter sets the exclusive writer TID.
@@ -492,12 +609,23 @@ func (vs *VarState) GetExclusiveWriter() int64 {
 //   - First write: Claim ownership (tid >= 0)
 //   - Second writer detected: Promote to shared (tid = -1)
 //
-// Thread Safety: Lock-free (atomic store).
-// Performance: ~2-5ns (atomic store).
+// Every call bumps ownerWord's version half, even when the state itself
+// happens not to change, so a SnapshotOwner/ValidateOwner pair taken around
+// it always sees the transition (synth-3633).
+//
+// Thread Safety: Lock-free (CAS retry loop over the packed word).
+// Performance: ~5-10ns (uncontended atomic CAS).
 //
 //go:nosplit
 func (vs *VarState) SetExclusiveWriter(tid int64) {
-	vs.exclusiveWriter.Store(tid)
+	for {
+		old := vs.ownerWord.Load()
+		_, version := unpackOwner(old)
+		newWord := packOwner(int32(tid), version+1)
+		if vs.ownerWord.CompareAndSwap(old, newWord) {
+			return
+		}
+	}
 }
 
 // CompareAndSwapExclusiveWriter atomically compares and swaps the exclusive writer.
@@ -514,12 +642,169 @@ func (vs *VarState) SetExclusiveWriter(tid int64) {
 //   - true if swap succeeded (current value was 'oldVal')
 //   - false if swap failed (current value was not 'oldVal')
 //
-// Thread Safety: Lock-free (atomic CAS).
-// Performance: ~5-10ns (atomic CAS).
+// Like SetExclusiveWriter, a successful swap bumps ownerWord's version half
+// (synth-3633).
+//
+// Thread Safety: Lock-free (CAS retry loop over the packed word).
+// Performance: ~5-10ns (uncontended atomic CAS).
 //
 //go:nosplit
 func (vs *VarState) CompareAndSwapExclusiveWriter(oldVal, newVal int64) bool {
-	return vs.exclusiveWriter.CompareAndSwap(oldVal, newVal)
+	for {
+		old := vs.ownerWord.Load()
+		state, version := unpackOwner(old)
+		if int64(state) != oldVal {
+			return false
+		}
+		newWord := packOwner(int32(newVal), version+1)
+		if vs.ownerWord.CompareAndSwap(old, newWord) {
+			return true
+		}
+	}
+}
+
+// SnapshotOwner atomically reads the current exclusive-writer state together
+// with an opaque token a later ValidateOwner call can use to confirm that
+// state hasn't changed in the meantime (synth-3633).
+//
+// SmartTrack's fast paths (see detector.OnWrite/OnRead) read the exclusive
+// writer once to decide whether they may skip the full happens-before
+// check, then - several steps later - commit an epoch. Between those two
+// points a second goroutine can concurrently claim or promote ownership via
+// SetExclusiveWriter/CompareAndSwapExclusiveWriter, which the earlier plain
+// GetExclusiveWriter read has no way to notice. SnapshotOwner + ValidateOwner
+// close that window: the fast path re-validates its snapshot immediately
+// before committing, and falls back to the full FastTrack path if
+// validation fails.
+//
+// Thread Safety: Lock-free (atomic load).
+// Performance: ~2-5ns (atomic load).
+//
+//go:nosplit
+func (vs *VarState) SnapshotOwner() (tid int64, token uint64) {
+	word := vs.ownerWord.Load()
+	state, _ := unpackOwner(word)
+	return int64(state), word
+}
+
+// ValidateOwner reports whether the exclusive-writer state is still exactly
+// what it was when token was captured by SnapshotOwner - i.e. no other
+// goroutine has claimed, released, or promoted ownership since (synth-3633).
+//
+// It is implemented as a CAS of ownerWord onto itself: if the word hasn't
+// changed, the CAS is a no-op and trivially succeeds; if SetExclusiveWriter
+// or CompareAndSwapExclusiveWriter ran in between, the version half moved
+// and the CAS fails. This gives SmartTrack's fast paths a genuine atomic
+// read-modify-write over ownership state without needing a 128-bit CAS to
+// also fold in W's full 64-bit epoch - W and ownerWord remain independently
+// atomic fields, and callers that need both consistent (as the fast paths
+// do) re-validate ownerWord right before touching W rather than relying on
+// having read them together.
+//
+// Thread Safety: Lock-free (atomic CAS).
+// Performance: ~5-10ns (uncontended atomic CAS).
+//
+//go:nosplit
+func (vs *VarState) ValidateOwner(token uint64) bool {
+	return vs.ownerWord.CompareAndSwap(token, token)
+}
+
+// Poison marks this cell as poisoned (synth-3636), so IsPoisoned starts
+// reporting true for it. Called by the detector once a race has been
+// reported on this cell's address, when DetectorOptions.MuteAfterReport is
+// set - a known-racy hot variable that would otherwise keep hitting the
+// full FastTrack check on every access, for a race the program has already
+// been told about, stops paying that cost. Idempotent and one-way: nothing
+// short of Reset() clears it.
+//
+// Thread Safety: Lock-free (atomic store).
+// Performance: ~2-5ns (atomic store).
+//
+//go:nosplit
+func (vs *VarState) Poison() {
+	vs.poisoned.Store(true)
+}
+
+// IsPoisoned reports whether Poison has been called on this cell
+// (synth-3636). OnWrite/OnRead check this before doing any happens-before
+// work, so a poisoned cell costs one atomic load per access instead of the
+// full FastTrack check.
+//
+// Thread Safety: Lock-free (atomic load).
+// Performance: ~2-5ns (atomic load).
+//
+//go:nosplit
+func (vs *VarState) IsPoisoned() bool {
+	return vs.poisoned.Load()
+}
+
+// IncrementCheckCount records one more OnRead/OnWrite access to this cell
+// (synth-3642). Called once per access regardless of outcome, so
+// GetCheckCount reflects true access frequency, not just races or writes.
+//
+// Thread Safety: Lock-free (atomic add).
+// Performance: ~2-5ns (atomic add).
+//
+//go:nosplit
+func (vs *VarState) IncrementCheckCount() {
+	vs.checkCount.Add(1)
+}
+
+// GetCheckCount returns the total number of OnRead/OnWrite accesses to
+// this cell since the last Reset (synth-3642).
+//
+// Thread Safety: Lock-free (atomic load).
+// Performance: ~2-5ns (atomic load).
+//
+//go:nosplit
+func (vs *VarState) GetCheckCount() uint64 {
+	return vs.checkCount.Load()
+}
+
+// IncrementPromotionCount records that this cell just promoted to
+// VectorClock (synth-3642), mirroring the detector-wide promotions counter
+// but scoped to this address.
+//
+// Thread Safety: Lock-free (atomic add).
+// Performance: ~2-5ns (atomic add).
+//
+//go:nosplit
+func (vs *VarState) IncrementPromotionCount() {
+	vs.promotionCount.Add(1)
+}
+
+// GetPromotionCount returns the number of times this cell has promoted to
+// VectorClock since the last Reset (synth-3642).
+//
+// Thread Safety: Lock-free (atomic load).
+// Performance: ~2-5ns (atomic load).
+//
+//go:nosplit
+func (vs *VarState) GetPromotionCount() uint32 {
+	return vs.promotionCount.Load()
+}
+
+// IncrementDemotionCount records that this cell just demoted back to the
+// fast path (synth-3642), mirroring the detector-wide demotions counter
+// but scoped to this address.
+//
+// Thread Safety: Lock-free (atomic add).
+// Performance: ~2-5ns (atomic add).
+//
+//go:nosplit
+func (vs *VarState) IncrementDemotionCount() {
+	vs.demotionCount.Add(1)
+}
+
+// GetDemotionCount returns the number of times this cell has demoted back
+// to the fast path since the last Reset (synth-3642).
+//
+// Thread Safety: Lock-free (atomic load).
+// Performance: ~2-5ns (atomic load).
+//
+//go:nosplit
+func (vs *VarState) GetDemotionCount() uint32 {
+	return vs.demotionCount.Load()
 }
 
 // IncrementWriteCount increments the write counter.
@@ -731,3 +1016,146 @@ func (vs *VarState) SetReadPC(pc uintptr) {
 func (vs *VarState) GetReadPC() uintptr {
 	return vs.readPC.Load()
 }
+
+// === Access History Ring Buffer (v0.4.0 "history_size" support) ===
+
+// RecordHistory appends rec to the ring buffer, evicting the oldest entry
+// once limit entries have been recorded.
+//
+// limit is the detector's configured HistorySize, clamped here to
+// maxHistoryEntries. Callers should only invoke this when history tracking
+// is enabled (limit > 0) - checking that before calling RecordHistory avoids
+// paying the mutex cost on every access when the feature is off (the default).
+//
+// Parameters:
+//   - rec: The access to record
+//   - limit: Desired ring length (clamped to maxHistoryEntries)
+//
+// Thread Safety: Protected by mutex.
+// Performance: ~10-20ns (mutex + array write), only paid when enabled.
+func (vs *VarState) RecordHistory(rec AccessRecord, limit int) {
+	if limit <= 0 {
+		return
+	}
+	if limit > maxHistoryEntries {
+		limit = maxHistoryEntries
+	}
+
+	vs.mu.Lock()
+	vs.historyLimit = uint8(limit)
+	vs.history[vs.historyNext] = rec
+	vs.historyNext = (vs.historyNext + 1) % uint8(limit)
+	if int(vs.historyCount) < limit {
+		vs.historyCount++
+	}
+	vs.mu.Unlock()
+}
+
+// GetHistory returns a copy of the recorded access history, oldest first.
+//
+// Returns nil if no history has been recorded - either history tracking is
+// disabled (the default), or this cell hasn't been accessed since RecordHistory
+// was first enabled for it.
+//
+// This is used during race reporting, not on the hot path.
+//
+// Thread Safety: Protected by mutex.
+func (vs *VarState) GetHistory() []AccessRecord {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	if vs.historyCount == 0 {
+		return nil
+	}
+
+	limit := int(vs.historyLimit)
+	count := int(vs.historyCount)
+
+	// Before the ring has wrapped (count < limit), the oldest entry is
+	// always at index 0. Once full, historyNext points at the slot that's
+	// about to be overwritten next - which is exactly the oldest entry.
+	start := 0
+	if count == limit {
+		start = int(vs.historyNext)
+	}
+
+	result := make([]AccessRecord, count)
+	for i := 0; i < count; i++ {
+		result[i] = vs.history[(start+i)%limit]
+	}
+	return result
+}
+
+// === Shadow Memory Cap Support (synth-3578) ===
+
+// Touch records stamp as this cell's recency stamp, evicting whatever value
+// was previously there.
+//
+// stamp comes from ShadowMemory's monotonic access counter, not a wall-clock
+// time - only the relative ordering between cells matters for picking an
+// eviction candidate, and a plain counter avoids the cost of a time.Now()
+// call on every GetOrCreate.
+//
+// Thread Safety: Lock-free (atomic store).
+// Performance: ~2-5ns (atomic store), same class as SetW.
+//
+//go:nosplit
+func (vs *VarState) Touch(stamp uint64) {
+	vs.lastTouch.Store(stamp)
+}
+
+// LastTouch returns the recency stamp last recorded by Touch, or 0 if the
+// cell has never been touched.
+//
+//go:nosplit
+func (vs *VarState) LastTouch() uint64 {
+	return vs.lastTouch.Load()
+}
+
+// Clone returns a deep copy of vs, independent of the original: mutating
+// the clone's promoted readClock (or the original's) never affects the
+// other (synth-3576).
+//
+// This backs ShadowMemory.Snapshot/RestoreFrom, which need an isolated
+// copy of every VarState so a later Restore doesn't hand back state an
+// in-progress access on the live cell is still mutating.
+//
+// Thread Safety: Safe for concurrent calls against vs (takes vs.mu for the
+// mutex-protected fields, same as every other accessor), but the returned
+// clone itself is unshared and needs no further synchronization until it
+// is published somewhere concurrent access is possible.
+func (vs *VarState) Clone() *VarState {
+	clone := &VarState{}
+
+	// Lock-free hot-path fields: read via their own atomic loads.
+	clone.W.Store(vs.W.Load())
+	clone.ownerWord.Store(vs.ownerWord.Load())
+	clone.writePC.Store(vs.writePC.Load())
+	clone.readPC.Store(vs.readPC.Load())
+	clone.lastTouch.Store(vs.lastTouch.Load())
+	clone.poisoned.Store(vs.poisoned.Load())
+	clone.checkCount.Store(vs.checkCount.Load())
+	clone.promotionCount.Store(vs.promotionCount.Load())
+	clone.demotionCount.Store(vs.demotionCount.Load())
+
+	// Mutex-protected fields: read all of them under one critical section
+	// rather than one accessor call each, so the clone is a consistent
+	// snapshot instead of a mix of before/after values from concurrent
+	// mutation between separate calls.
+	vs.mu.Lock()
+	clone.readEpochs = vs.readEpochs
+	clone.readerCount = vs.readerCount
+	if vs.readClock != nil {
+		clone.readClock = vs.readClock.Clone()
+	}
+	clone.writeCount = vs.writeCount
+	clone.writeStackHash = vs.writeStackHash
+	clone.readStackHash = vs.readStackHash
+	clone.history = vs.history
+	clone.historyCount = vs.historyCount
+	clone.historyNext = vs.historyNext
+	clone.historyLimit = vs.historyLimit
+	vs.mu.Unlock()
+
+	return clone
+}
@@ -0,0 +1,112 @@
+package shadowmem
+
+import (
+	"testing"
+
+	"github.com/kolkov/racedetector/internal/race/epoch"
+)
+
+// TestRecordHistory_Disabled verifies that RecordHistory is a no-op when
+// limit <= 0, matching the default (history tracking disabled) behavior.
+func TestRecordHistory_Disabled(t *testing.T) {
+	vs := NewVarState()
+	vs.RecordHistory(AccessRecord{Epoch: epoch.NewEpoch(1, 1)}, 0)
+
+	if got := vs.GetHistory(); got != nil {
+		t.Errorf("GetHistory() = %v, want nil when history tracking is disabled", got)
+	}
+}
+
+// TestRecordHistory_OrderedBeforeWrap verifies that entries are returned
+// oldest-first while the ring buffer hasn't filled up yet.
+func TestRecordHistory_OrderedBeforeWrap(t *testing.T) {
+	vs := NewVarState()
+	const limit = 4
+
+	for i := 1; i <= 3; i++ {
+		vs.RecordHistory(AccessRecord{Epoch: epoch.NewEpoch(1, uint64(i))}, limit)
+	}
+
+	history := vs.GetHistory()
+	if len(history) != 3 {
+		t.Fatalf("len(GetHistory()) = %d, want 3", len(history))
+	}
+	for i, rec := range history {
+		_, clock := rec.Epoch.Decode()
+		if clock != uint64(i+1) {
+			t.Errorf("history[%d] clock = %d, want %d", i, clock, i+1)
+		}
+	}
+}
+
+// TestRecordHistory_EvictsOldestOnWrap verifies that once the ring buffer
+// is full, the oldest entry is evicted to make room for the newest one.
+func TestRecordHistory_EvictsOldestOnWrap(t *testing.T) {
+	vs := NewVarState()
+	const limit = 3
+
+	// Record 5 accesses with clocks 1..5 into a ring of capacity 3.
+	for i := 1; i <= 5; i++ {
+		vs.RecordHistory(AccessRecord{Epoch: epoch.NewEpoch(1, uint64(i))}, limit)
+	}
+
+	history := vs.GetHistory()
+	if len(history) != limit {
+		t.Fatalf("len(GetHistory()) = %d, want %d", len(history), limit)
+	}
+
+	wantClocks := []uint64{3, 4, 5}
+	for i, rec := range history {
+		_, clock := rec.Epoch.Decode()
+		if clock != wantClocks[i] {
+			t.Errorf("history[%d] clock = %d, want %d", i, clock, wantClocks[i])
+		}
+	}
+}
+
+// TestRecordHistory_LimitClampedToMax verifies that a limit larger than
+// maxHistoryEntries is clamped rather than overflowing the ring buffer.
+func TestRecordHistory_LimitClampedToMax(t *testing.T) {
+	vs := NewVarState()
+
+	for i := 1; i <= maxHistoryEntries+5; i++ {
+		vs.RecordHistory(AccessRecord{Epoch: epoch.NewEpoch(1, uint64(i))}, maxHistoryEntries+100)
+	}
+
+	history := vs.GetHistory()
+	if len(history) != maxHistoryEntries {
+		t.Fatalf("len(GetHistory()) = %d, want %d (clamped to maxHistoryEntries)", len(history), maxHistoryEntries)
+	}
+}
+
+// TestRecordHistory_TracksWriteFlag verifies that IsWrite survives a
+// round-trip through the ring buffer.
+func TestRecordHistory_TracksWriteFlag(t *testing.T) {
+	vs := NewVarState()
+	vs.RecordHistory(AccessRecord{Epoch: epoch.NewEpoch(1, 1), IsWrite: true}, 4)
+	vs.RecordHistory(AccessRecord{Epoch: epoch.NewEpoch(2, 2), IsWrite: false}, 4)
+
+	history := vs.GetHistory()
+	if len(history) != 2 {
+		t.Fatalf("len(GetHistory()) = %d, want 2", len(history))
+	}
+	if !history[0].IsWrite {
+		t.Error("history[0].IsWrite = false, want true")
+	}
+	if history[1].IsWrite {
+		t.Error("history[1].IsWrite = true, want false")
+	}
+}
+
+// TestVarStateReset_ClearsHistory verifies that Reset() clears recorded
+// history along with the rest of the VarState.
+func TestVarStateReset_ClearsHistory(t *testing.T) {
+	vs := NewVarState()
+	vs.RecordHistory(AccessRecord{Epoch: epoch.NewEpoch(1, 1)}, 4)
+
+	vs.Reset()
+
+	if got := vs.GetHistory(); got != nil {
+		t.Errorf("GetHistory() after Reset() = %v, want nil", got)
+	}
+}
@@ -270,6 +270,113 @@ func BenchmarkVarState_SetExclusiveWriter(b *testing.B) {
 	}
 }
 
+// TestVarState_ValidateOwner_SucceedsWhenUnchanged verifies a token from
+// SnapshotOwner still validates when nothing has touched ownership since
+// (synth-3633).
+func TestVarState_ValidateOwner_SucceedsWhenUnchanged(t *testing.T) {
+	vs := NewVarState()
+	vs.SetExclusiveWriter(1)
+
+	tid, token := vs.SnapshotOwner()
+	if tid != 1 {
+		t.Fatalf("Expected snapshot TID = 1, got %d", tid)
+	}
+	if !vs.ValidateOwner(token) {
+		t.Error("Expected ValidateOwner to succeed when ownership hasn't changed")
+	}
+}
+
+// TestVarState_ValidateOwner_FailsAfterTransition verifies a token captured
+// before a SetExclusiveWriter/CompareAndSwapExclusiveWriter call no longer
+// validates afterward, even when the observable TID ends up the same
+// (synth-3633: SmartTrack's fast paths rely on this to detect a concurrent
+// ownership change they raced with, not just a changed TID).
+func TestVarState_ValidateOwner_FailsAfterTransition(t *testing.T) {
+	vs := NewVarState()
+	vs.SetExclusiveWriter(1)
+
+	_, token := vs.SnapshotOwner()
+
+	// Second writer detected, promoted to shared, then reclaimed by TID 1 -
+	// the TID observed by a fresh SnapshotOwner is back to where it started,
+	// but the token from before the promotion must still be stale.
+	vs.SetExclusiveWriter(-1)
+	vs.SetExclusiveWriter(1)
+
+	if vs.ValidateOwner(token) {
+		t.Error("Expected ValidateOwner to fail after an intervening ownership transition")
+	}
+
+	tid, freshToken := vs.SnapshotOwner()
+	if tid != 1 {
+		t.Fatalf("Expected TID = 1 after reclaim, got %d", tid)
+	}
+	if !vs.ValidateOwner(freshToken) {
+		t.Error("Expected ValidateOwner to succeed against a freshly captured token")
+	}
+}
+
+// TestVarState_ValidateOwner_FailsAfterPromotionCAS verifies that
+// CompareAndSwapExclusiveWriter's promotion path - the one OnWrite's
+// second-writer branch uses - also invalidates outstanding tokens, exactly
+// like the plain SetExclusiveWriter path (synth-3633).
+func TestVarState_ValidateOwner_FailsAfterPromotionCAS(t *testing.T) {
+	vs := NewVarState()
+	vs.SetExclusiveWriter(1)
+
+	_, token := vs.SnapshotOwner()
+
+	if !vs.CompareAndSwapExclusiveWriter(1, -1) {
+		t.Fatal("Expected the promotion CAS to succeed given TID matches")
+	}
+
+	if vs.ValidateOwner(token) {
+		t.Error("Expected ValidateOwner to fail once a CAS promoted ownership away")
+	}
+}
+
+// TestVarState_Poison verifies IsPoisoned reports true only after Poison
+// has been called (synth-3636).
+func TestVarState_Poison(t *testing.T) {
+	vs := NewVarState()
+
+	if vs.IsPoisoned() {
+		t.Fatal("Expected a fresh VarState to not be poisoned")
+	}
+
+	vs.Poison()
+
+	if !vs.IsPoisoned() {
+		t.Error("Expected IsPoisoned to be true after Poison()")
+	}
+}
+
+// TestVarState_Reset_ClearsPoison verifies Reset() un-poisons a cell, so a
+// freed and reused address starts detection fresh (synth-3636).
+func TestVarState_Reset_ClearsPoison(t *testing.T) {
+	vs := NewVarState()
+	vs.Poison()
+
+	vs.Reset()
+
+	if vs.IsPoisoned() {
+		t.Error("Expected Reset() to clear the poisoned flag")
+	}
+}
+
+// TestVarState_Clone_PreservesPoison verifies Clone() carries the poisoned
+// flag over, consistent with how it carries every other field (synth-3636).
+func TestVarState_Clone_PreservesPoison(t *testing.T) {
+	vs := NewVarState()
+	vs.Poison()
+
+	clone := vs.Clone()
+
+	if !clone.IsPoisoned() {
+		t.Error("Expected Clone() to preserve the poisoned flag")
+	}
+}
+
 // BenchmarkVarState_IncrementWriteCount benchmarks write counter performance.
 func BenchmarkVarState_IncrementWriteCount(b *testing.B) {
 	vs := NewVarState()
@@ -6,13 +6,16 @@
 // Design (ThreadSanitizer v2 approach):
 //   - Fixed-size stack traces (8 frames, 64 bytes per stack)
 //   - Hash-based deduplication (FNV-1a hash)
-//   - Global sync.Map storage (thread-safe)
+//   - Bounded storage with LRU eviction (synth-3603), so a long-running
+//     process with a large or ever-growing set of distinct call sites can't
+//     grow the depot without limit
 //   - Memory overhead: 64 bytes per unique stack + 8 bytes hash per VarState
 //
 // Performance:
-//   - CaptureStack: ~500ns (includes runtime.Callers + hashing)
-//   - GetStack: ~50ns (sync.Map.Load)
-//   - Memory: ~64KB for 1000 unique stacks
+//   - CaptureStack: ~500ns on a cold call site (runtime.Callers + hashing +
+//     depot insert), ~20ns on a hot one (per-call-site cache hit, synth-3603)
+//   - GetStack: ~80ns (map lookup under depotMu)
+//   - Memory: bounded by Capacity (default DefaultCapacity), ~96 bytes/stack
 //
 // Usage:
 //
@@ -28,11 +31,13 @@
 package stackdepot
 
 import (
+	"container/list"
 	"fmt"
 	"hash/fnv"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
@@ -51,14 +56,117 @@ type StackTrace struct {
 	PC [MaxFrames]uintptr // Program counters (64 bytes).
 }
 
-// stackDepot is the global deduplication store for stack traces.
+// DefaultCapacity is the depot's default maximum number of unique stacks
+// (synth-3603). At ~96 bytes/stack (see Stats), this bounds the depot to
+// roughly 400KB - generous for the working set of distinct call sites a
+// real program hits, while still capping memory for long-running processes
+// that would otherwise intern call sites forever (e.g. one driven by
+// data-dependent code paths that grow over the process lifetime).
+const DefaultCapacity = 4096
+
+// depotEntry is a stack stored in the depot, wrapped so it can live in the
+// LRU list.
+type depotEntry struct {
+	hash  uint64
+	trace *StackTrace
+}
+
+// stackDepot is the global deduplication store for stack traces, bounded to
+// depotCapacity entries with least-recently-used eviction (synth-3603).
 //
 // Key: uint64 hash (FNV-1a of program counters)
-// Value: *StackTrace (pointer to fixed-size trace)
+// Value: *list.Element wrapping *depotEntry, so a hit can be moved to the
+// front of depotOrder without a second map operation.
+//
+// depotOrder tracks recency: Front() is most recently used, Back() is the
+// next eviction candidate. Both are only ever touched under depotMu.
+//
+// Thread Safety: All access goes through depotMu - a map keyed by content
+// hash and an LRU list can't be kept consistent with sync.Map's lock-free
+// reads the way the unbounded version could, so this trades that fast-path
+// for capacity control.
+var (
+	depotMu       sync.Mutex
+	stackDepot    = make(map[uint64]*list.Element, DefaultCapacity)
+	depotOrder    = list.New()
+	depotCapacity = DefaultCapacity
+)
+
+// SetCapacity changes the maximum number of unique stacks the depot retains
+// (synth-3603), evicting least-recently-used entries immediately if the new
+// capacity is smaller than the current stack count. n <= 0 is treated as
+// DefaultCapacity rather than "unlimited" - an unbounded depot is exactly
+// the growth problem this exists to prevent.
 //
-// Thread Safety: sync.Map provides lock-free reads, lock-based writes.
-// Memory: Grows unbounded (future: add LRU eviction if needed).
-var stackDepot sync.Map // uint64 (hash) → *StackTrace
+// Thread Safety: Safe for concurrent calls.
+func SetCapacity(n int) {
+	if n <= 0 {
+		n = DefaultCapacity
+	}
+
+	depotMu.Lock()
+	defer depotMu.Unlock()
+
+	depotCapacity = n
+	for len(stackDepot) > depotCapacity {
+		evictLRULocked()
+	}
+}
+
+// evictLRULocked removes the least-recently-used stack from the depot.
+// Caller must hold depotMu and must have already verified the depot is
+// non-empty.
+func evictLRULocked() {
+	oldest := depotOrder.Back()
+	if oldest == nil {
+		return
+	}
+	entry := oldest.Value.(*depotEntry)
+	delete(stackDepot, entry.hash)
+	depotOrder.Remove(oldest)
+}
+
+// siteCacheSize is the number of direct-mapped slots in the per-call-site
+// stack cache (synth-3603).
+//
+// Keyed by the caller's innermost PC rather than by goroutine identity:
+// stackdepot has no notion of "current goroutine" (that lives in the
+// goroutine package, one layer up, which would make depending on it here a
+// backwards import), and in practice a hot call site is overwhelmingly
+// called from the same enclosing call chain every time - the same
+// assumption detector.Sampler.ShouldSampleSite already relies on for
+// per-call-site sampling. A full [MaxFrames]uintptr comparison against the
+// cached entry (not just the PC) guards against the rare case where that
+// assumption doesn't hold: a mismatch is treated as a cache miss and falls
+// back to the normal capture-and-hash path, so a collision only costs
+// performance, never correctness.
+//
+// 256 is a direct-mapped size in the same family as goroutine's
+// shadowCellCacheSize (this package has no equivalent per-goroutine
+// residency to size against, so it reuses that constant's order of
+// magnitude rather than inventing a new one).
+const siteCacheSize = 256
+
+// siteCacheEntry is one slot's contents: the full PC array it was last
+// filled for, and that stack's already-computed hash.
+type siteCacheEntry struct {
+	pcs  [MaxFrames]uintptr
+	hash uint64
+}
+
+// siteCache is the fast-path cache CaptureStack checks before hashing and
+// touching the depot. Lock-free: each slot is an atomic.Pointer swapped in
+// whole, so a reader never observes a torn pcs/hash pair.
+var siteCache [siteCacheSize]atomic.Pointer[siteCacheEntry]
+
+// siteCacheIndex selects the direct-mapped slot for the caller's PC.
+//
+// >>4 discards the low bits (instruction alignment means they never vary
+// between distinct call sites), matching the shift used by other PC-keyed
+// lookups in this codebase (see detector.Sampler's siteCounts key).
+func siteCacheIndex(pc uintptr) uintptr {
+	return (pc >> 4) & (siteCacheSize - 1)
+}
 
 // CaptureStack captures the current stack trace and returns its hash.
 //
@@ -69,8 +177,9 @@ var stackDepot sync.Map // uint64 (hash) → *StackTrace
 //   - On every write (to record write stack in VarState)
 //   - On every read to read-shared variables (to record read stack)
 //
-// Performance: ~500ns (includes runtime.Callers + hashing + sync.Map.Store).
-// Deduplication: If same stack already exists, only hash computation cost (~100ns).
+// Performance: ~20ns on a per-call-site cache hit (synth-3603, see
+// siteCache), ~500ns on a cold call site (runtime.Callers + hashing + depot
+// insert, which may itself evict the depot's least-recently-used stack).
 //
 // Returns:
 //   - uint64 hash: Unique identifier for this stack (0 if no stack available)
@@ -90,24 +199,54 @@ func CaptureStack() uint64 {
 		return 0
 	}
 
+	// Fast path (synth-3603): if this exact stack was the last one captured
+	// at this call site, skip both the FNV hash and the depot lookup.
+	slot := &siteCache[siteCacheIndex(pcs[0])]
+	if cached := slot.Load(); cached != nil && cached.pcs == pcs {
+		return cached.hash
+	}
+
 	// Compute hash for deduplication.
 	// FNV-1a is fast (~50ns for 8 frames) and has good distribution.
 	hash := hashStack(pcs[:n])
 
-	// Check if stack already in depot (deduplication).
-	// If yes, we don't need to allocate a new StackTrace.
-	if _, exists := stackDepot.Load(hash); exists {
-		return hash // Already stored, return existing hash.
+	depotMu.Lock()
+	if elem, exists := stackDepot[hash]; exists {
+		// Already stored - bump recency and reuse the existing StackTrace.
+		depotOrder.MoveToFront(elem)
+	} else {
+		trace := &StackTrace{PC: pcs}
+		elem := depotOrder.PushFront(&depotEntry{hash: hash, trace: trace})
+		stackDepot[hash] = elem
+		if len(stackDepot) > depotCapacity {
+			evictLRULocked()
+		}
 	}
+	depotMu.Unlock()
 
-	// Store new stack in depot.
-	// This allocates a new StackTrace (64 bytes).
-	trace := &StackTrace{PC: pcs}
-	stackDepot.Store(hash, trace)
-
+	slot.Store(&siteCacheEntry{pcs: pcs, hash: hash})
 	return hash
 }
 
+// CaptureStackSampled is CaptureStack's sampling-aware entry point
+// (synth-3603): when sampled is false it skips capture entirely and
+// returns 0, the same "no stack available" sentinel CaptureStack itself
+// returns. Callers that already know an access was skipped by the
+// detector's sampler (see detector.Sampler.ShouldSample) should use this
+// instead of unconditionally calling CaptureStack, so a sampled-out access
+// doesn't still pay stack-capture cost for a report that will never exist.
+//
+// Performance: ~0.5ns when sampled is false (single branch), identical to
+// CaptureStack otherwise.
+//
+// Thread Safety: Safe for concurrent calls from multiple goroutines.
+func CaptureStackSampled(sampled bool) uint64 {
+	if !sampled {
+		return 0
+	}
+	return CaptureStack()
+}
+
 // GetStack retrieves a stack trace by hash.
 //
 // This function is called during race reporting to format stack traces.
@@ -128,13 +267,28 @@ func GetStack(hash uint64) *StackTrace {
 		return nil
 	}
 
-	val, ok := stackDepot.Load(hash)
+	depotMu.Lock()
+	defer depotMu.Unlock()
+
+	elem, ok := stackDepot[hash]
 	if !ok {
-		// Hash not found (shouldn't happen in practice).
+		// Hash not found: never captured, or evicted under capacity
+		// pressure (synth-3603) since it was last looked up.
 		return nil
 	}
 
-	return val.(*StackTrace)
+	// A lookup counts as use for LRU purposes, same as a fresh capture.
+	depotOrder.MoveToFront(elem)
+	return elem.Value.(*depotEntry).trace
+}
+
+// HashPCs computes the same FNV-1a fingerprint CaptureStack uses, for
+// callers that already have a []uintptr (e.g. race report deduplication)
+// and want a stable hash without storing the stack in the depot.
+//
+// Thread Safety: Pure function, no shared state.
+func HashPCs(pcs []uintptr) uint64 {
+	return hashStack(pcs)
 }
 
 // hashStack computes FNV-1a hash of program counters.
@@ -230,13 +384,25 @@ func (st *StackTrace) FormatStack() string {
 
 // Reset clears the stack depot (for testing).
 //
+// This also resets the depot back to DefaultCapacity and clears the
+// per-call-site cache (synth-3603), so a test that called SetCapacity
+// doesn't leak its capacity into the next test.
+//
 // This is useful for tests that need a clean slate.
 // Should NOT be called in production code.
 //
 // Thread Safety: NOT safe for concurrent calls.
 // Only use this in single-threaded test setup/teardown.
 func Reset() {
-	stackDepot = sync.Map{}
+	depotMu.Lock()
+	stackDepot = make(map[uint64]*list.Element, DefaultCapacity)
+	depotOrder = list.New()
+	depotCapacity = DefaultCapacity
+	depotMu.Unlock()
+
+	for i := range siteCache {
+		siteCache[i].Store(nil)
+	}
 }
 
 // Stats returns statistics about the stack depot.
@@ -247,19 +413,18 @@ func Reset() {
 //   - uniqueStacks: Number of unique stacks stored
 //   - totalMemory: Approximate memory usage in bytes
 //
-// Performance: O(N) - must iterate all entries in sync.Map.
-// Do not call this on hot path.
+// Performance: O(1) - depotMu guards a plain map, so len() is immediate
+// (unlike the sync.Map.Range this used before synth-3603's LRU rework).
+// Still avoid calling this on the hot path: it takes depotMu.
 //
-// Thread Safety: Safe for concurrent calls, but count may be approximate
-// if other goroutines are adding stacks concurrently.
+// Thread Safety: Safe for concurrent calls.
 func Stats() (uniqueStacks int, totalMemory int64) {
-	stackDepot.Range(func(_, _ interface{}) bool {
-		uniqueStacks++
-		return true
-	})
+	depotMu.Lock()
+	uniqueStacks = len(stackDepot)
+	depotMu.Unlock()
 
 	// Each StackTrace is 64 bytes (8 frames × 8 bytes).
-	// Plus overhead: ~32 bytes per sync.Map entry (hash + pointer + metadata).
+	// Plus overhead: ~32 bytes per depot entry (hash + list element + map bucket).
 	const bytesPerStack = 64 + 32
 	totalMemory = int64(uniqueStacks) * bytesPerStack
 
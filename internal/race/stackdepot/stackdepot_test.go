@@ -290,6 +290,105 @@ func TestStats(t *testing.T) {
 	}
 }
 
+// TestCaptureStackSampledSkipsCapture verifies CaptureStackSampled(false)
+// returns the zero sentinel without touching the depot at all.
+func TestCaptureStackSampledSkipsCapture(t *testing.T) {
+	Reset()
+
+	hash := CaptureStackSampled(false)
+	if hash != 0 {
+		t.Errorf("CaptureStackSampled(false) = %#x, want 0", hash)
+	}
+
+	uniqueStacks, _ := Stats()
+	if uniqueStacks != 0 {
+		t.Errorf("expected no stacks captured, got %d", uniqueStacks)
+	}
+}
+
+// TestCaptureStackSampledTrueMatchesCaptureStack verifies
+// CaptureStackSampled(true) behaves exactly like CaptureStack.
+func TestCaptureStackSampledTrueMatchesCaptureStack(t *testing.T) {
+	Reset()
+
+	hash := CaptureStackSampled(true)
+	if hash == 0 {
+		t.Fatal("CaptureStackSampled(true) returned zero hash")
+	}
+	if GetStack(hash) == nil {
+		t.Fatal("GetStack returned nil for a hash from CaptureStackSampled(true)")
+	}
+}
+
+// TestSetCapacityEvictsLRU verifies that lowering the depot's capacity
+// below its current stack count evicts the least-recently-used entries
+// first (synth-3603).
+func TestSetCapacityEvictsLRU(t *testing.T) {
+	Reset()
+	SetCapacity(2)
+	defer SetCapacity(DefaultCapacity)
+
+	// Three distinct call sites, captured in order: oldest first.
+	hashOld := captureFromSiteA()
+	hashMid := captureFromSiteB()
+	hashNew := captureFromSiteC()
+
+	if hashOld == 0 || hashMid == 0 || hashNew == 0 {
+		t.Fatal("CaptureStack returned zero hash")
+	}
+
+	uniqueStacks, _ := Stats()
+	if uniqueStacks != 2 {
+		t.Fatalf("expected capacity to cap the depot at 2 stacks, got %d", uniqueStacks)
+	}
+
+	// The oldest (least-recently-used) entry should have been evicted.
+	if GetStack(hashOld) != nil {
+		t.Error("expected the least-recently-used stack to be evicted, but it's still present")
+	}
+	if GetStack(hashMid) == nil {
+		t.Error("expected the second-oldest stack to survive eviction")
+	}
+	if GetStack(hashNew) == nil {
+		t.Error("expected the most recently captured stack to survive eviction")
+	}
+}
+
+// TestSetCapacityRecencyBumpOnGetStack verifies that GetStack counts as a
+// use for LRU purposes, so re-reading an old entry protects it from the
+// next eviction.
+func TestSetCapacityRecencyBumpOnGetStack(t *testing.T) {
+	Reset()
+	SetCapacity(2)
+	defer SetCapacity(DefaultCapacity)
+
+	hashA := captureFromSiteA()
+	hashB := captureFromSiteB()
+
+	// Touch A again via GetStack, making B the least-recently-used entry.
+	if GetStack(hashA) == nil {
+		t.Fatal("GetStack returned nil for hashA")
+	}
+
+	hashC := captureFromSiteC() // Pushes the depot over capacity.
+
+	if GetStack(hashA) == nil {
+		t.Error("expected hashA to survive eviction after being touched by GetStack")
+	}
+	if GetStack(hashB) != nil {
+		t.Error("expected hashB to be evicted as the least-recently-used entry")
+	}
+	if GetStack(hashC) == nil {
+		t.Error("expected the just-captured hashC to survive eviction")
+	}
+}
+
+// captureFromSiteA/B/C are distinct call sites for LRU eviction tests,
+// mirroring captureFromSite1/2's role in TestHashStackDifferentStacks.
+func captureFromSiteA() uint64 { return CaptureStack() }
+func captureFromSiteB() uint64 { return CaptureStack() }
+func captureFromSiteC() uint64 { return CaptureStack() }
+
 // BenchmarkCaptureStack benchmarks stack capture performance.
 func BenchmarkCaptureStack(b *testing.B) {
 	Reset()
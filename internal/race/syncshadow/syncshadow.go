@@ -2,8 +2,28 @@ package syncshadow
 
 import (
 	"sync"
+	"sync/atomic"
+	"unsafe"
 )
 
+// estimatedSyncVarBytes approximates the resident size of one SyncVar for
+// MaxSyncShadowBytes accounting (synth-3620), mirroring
+// shadowmem.estimatedVarStateBytes. This is the base SyncVar struct size,
+// not the larger footprint a mutex with channel/WaitGroup/finalizer/
+// singleflight sub-state attached would actually use - undercounting those
+// means the cap is enforced a little late rather than reclaiming
+// unnecessarily aggressively on workloads that rarely use those extensions.
+const estimatedSyncVarBytes = uint64(unsafe.Sizeof(SyncVar{}))
+
+// syncShadowEvictionSampleSize bounds how many entries evictIfOverBudget
+// inspects before picking a candidate (synth-3620), mirroring
+// shadowmem.evictionSampleSize. SyncShadow uses a single sync.Map rather
+// than shardmem's sharded design, so the sample is drawn from the whole map
+// instead of one shard - still O(1) relative to the number of tracked sync
+// primitives, at the cost of only approximating "least recently touched"
+// within that sample instead of finding the true global minimum.
+const syncShadowEvictionSampleSize = 8
+
 // SyncShadow manages shadow memory for synchronization primitives.
 //
 // This maps each sync primitive address (uintptr) to its SyncVar, which
@@ -12,7 +32,11 @@ import (
 // Implementation:
 //   - Uses sync.Map for lock-free concurrent access
 //   - SyncVar allocated on first access to a mutex address
-//   - Never freed (mutexes typically live for program lifetime)
+//   - Unbounded by default (mutexes typically live for program lifetime);
+//     NewSyncShadowWithCap bounds memory use by reclaiming
+//     least-recently-touched entries once a configured cap is exceeded
+//     (synth-3620), for programs that create many short-lived channels or
+//     mutexes instead of a fixed set that lives for the program's lifetime.
 //
 // Memory Model:
 //   - Key: uintptr (address of sync.Mutex, sync.RWMutex, etc.)
@@ -26,7 +50,7 @@ import (
 //	mutexAddr := uintptr(unsafe.Pointer(&mu))
 //	sv := shadow.GetOrCreate(mutexAddr)
 //	sv.SetReleaseClock(ctx.C)  // On Unlock
-//	ctx.C.Join(sv.GetReleaseClock())  // On Lock
+//	ctx.JoinClock(sv.GetReleaseClock())  // On Lock
 type SyncShadow struct {
 	// vars maps sync primitive addresses to their SyncVar instances.
 	// Key: uintptr (address of sync primitive)
@@ -38,6 +62,32 @@ type SyncShadow struct {
 	//
 	// sync.Map is optimized for this "stable keys" pattern.
 	vars sync.Map
+
+	// maxBytes is the configured memory cap (synth-3620,
+	// DetectorOptions.MaxSyncShadowBytes), or 0 for unbounded (the
+	// default). Set once at construction (see NewSyncShadowWithCap) and
+	// never mutated afterward, so it's safe to read without
+	// synchronization.
+	maxBytes uint64
+
+	// entryCount approximates the number of live SyncVar entries.
+	// Maintained with plain atomic adds on insert/evict rather than an
+	// exact Count() call (which would require an O(n) Range on every
+	// GetOrCreate), so budget checks stay cheap enough for the hot path.
+	entryCount atomic.Int64
+
+	// accessCounter is a monotonically increasing logical clock, advanced
+	// once per GetOrCreate call and stamped onto the resolved SyncVar's
+	// lastTouch. It backs the approximate LRU reclamation ordering - see
+	// SyncVar.Touch.
+	accessCounter atomic.Uint64
+
+	// evictions counts entries reclaimed to stay under maxBytes
+	// (synth-3620). Exposed via Evictions() so callers running inside a
+	// memory-constrained container can alert when the cap is actually
+	// being hit, rather than just silently losing detection coverage for
+	// reclaimed sync primitives.
+	evictions atomic.Uint64
 }
 
 // NewSyncShadow creates and initializes a new SyncShadow instance.
@@ -54,6 +104,26 @@ func NewSyncShadow() *SyncShadow {
 	return &SyncShadow{}
 }
 
+// NewSyncShadowWithCap creates an empty SyncShadow that reclaims
+// least-recently-touched entries once its estimated footprint would exceed
+// maxBytes (synth-3620, DetectorOptions.MaxSyncShadowBytes), mirroring
+// shadowmem.NewShadowMemoryWithCap.
+//
+// maxBytes == 0 means unbounded - equivalent to NewSyncShadow(). This
+// bounds the leak a long-running program that creates many short-lived
+// mutexes or channels would otherwise cause: each SyncVar (~1KB once its
+// release clock is populated) previously lived until the whole SyncShadow
+// was Reset, so a program that never reuses an address grows shadow memory
+// without bound. Once the cap is hit, an entry that hasn't been touched in
+// a while is reclaimed to make room for a newer one, trading detection of
+// races on cold, likely-dead sync primitives for bounded memory use.
+//
+// See GetOrCreate for where the cap is enforced, Evictions for the
+// resulting metric, and Count for the live-entry accounting this backs.
+func NewSyncShadowWithCap(maxBytes uint64) *SyncShadow {
+	return &SyncShadow{maxBytes: maxBytes}
+}
+
 // GetOrCreate returns the SyncVar for the given address, creating it if needed.
 //
 // This is the primary entry point for accessing sync variable state.
@@ -83,15 +153,106 @@ func NewSyncShadow() *SyncShadow {
 func (s *SyncShadow) GetOrCreate(addr uintptr) *SyncVar {
 	// Try to load existing SyncVar (fast path).
 	if val, ok := s.vars.Load(addr); ok {
-		return val.(*SyncVar)
+		sv := val.(*SyncVar)
+		sv.Touch(s.accessCounter.Add(1))
+		return sv
 	}
 
 	// Slow path: Create new SyncVar for this address.
 	// Multiple goroutines may race here, but LoadOrStore ensures
 	// only one SyncVar is actually used.
 	newVar := &SyncVar{}
-	val, _ := s.vars.LoadOrStore(addr, newVar)
-	return val.(*SyncVar)
+	actual, loaded := s.vars.LoadOrStore(addr, newVar)
+	result := actual.(*SyncVar)
+	result.Touch(s.accessCounter.Add(1))
+
+	// synth-3620: only a genuinely new entry grows entryCount and can push
+	// it over maxBytes - a LoadOrStore that lost the race to a concurrent
+	// caller (loaded == true) just resolved to an existing entry above.
+	if !loaded {
+		s.entryCount.Add(1)
+		s.evictIfOverBudget()
+	}
+	return result
+}
+
+// evictIfOverBudget reclaims one least-recently-touched entry sampled from
+// the map if s's estimated footprint exceeds maxBytes (synth-3620). No-op
+// when maxBytes is 0 (unbounded, the default).
+func (s *SyncShadow) evictIfOverBudget() {
+	if s.maxBytes == 0 {
+		return
+	}
+	if uint64(s.entryCount.Load())*estimatedSyncVarBytes <= s.maxBytes {
+		return
+	}
+
+	var oldestAddr uintptr
+	var oldestTouch uint64
+	found := false
+	sampled := 0
+	s.vars.Range(func(key, value interface{}) bool {
+		if touch := value.(*SyncVar).LastTouch(); !found || touch < oldestTouch {
+			oldestAddr, oldestTouch, found = key.(uintptr), touch, true
+		}
+		sampled++
+		return sampled < syncShadowEvictionSampleSize
+	})
+	if !found {
+		return
+	}
+
+	s.vars.Delete(oldestAddr)
+	s.entryCount.Add(-1)
+	s.evictions.Add(1)
+}
+
+// Evictions returns the number of SyncVar entries reclaimed so far to stay
+// under a configured MaxSyncShadowBytes cap (synth-3620). Always 0 when the
+// detector was created without a cap.
+//
+// This is a monitoring accessor, not called from the hot path.
+//
+// Thread Safety: Safe for concurrent calls (atomic load).
+func (s *SyncShadow) Evictions() uint64 {
+	return s.evictions.Load()
+}
+
+// EstimatedBytes returns s's approximate memory footprint: the number of
+// live entries times estimatedSyncVarBytes (synth-3620). This is what
+// evictIfOverBudget compares against maxBytes - it undercounts entries with
+// channel/WaitGroup/finalizer/singleflight sub-state attached (see
+// estimatedSyncVarBytes) and is not updated atomically with entryCount's
+// individual increments/decrements, so treat it as an approximation
+// suitable for monitoring, not an exact accounting.
+//
+// Thread Safety: Safe for concurrent calls (atomic load).
+func (s *SyncShadow) EstimatedBytes() uint64 {
+	return uint64(s.entryCount.Load()) * estimatedSyncVarBytes
+}
+
+// Count returns the number of SyncVar entries currently tracked (synth-3620).
+//
+// This is the accounting API for the live-entry leak this ticket describes:
+// a program creating many short-lived channels or mutexes can watch this
+// value to confirm reclamation (see NewSyncShadowWithCap) is actually
+// keeping it bounded, rather than growing without limit for the life of the
+// process.
+//
+// Like shadowmem.ShadowMemory.Count, this ranges over the whole map and is
+// meant for periodic debug/monitoring reporting (expvar, Prometheus
+// scrapes), not the hot path.
+//
+// Thread Safety: Safe for concurrent calls, though the result may be stale
+// by the time it's read if other goroutines are concurrently allocating or
+// resetting entries.
+func (s *SyncShadow) Count() int {
+	count := 0
+	s.vars.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	return count
 }
 
 // Reset clears all sync variable state.
@@ -112,4 +273,56 @@ func (s *SyncShadow) Reset() {
 	// Create a new sync.Map to clear all entries.
 	// This is more efficient than Range + Delete for large maps.
 	s.vars = sync.Map{}
+
+	// synth-3620: entryCount must track the map it approximates, or
+	// evictIfOverBudget would keep comparing against a stale, too-high
+	// count and reclaim entries that were never re-created after Reset.
+	// evictions is a lifetime counter of this SyncShadow's own instance
+	// (mirrors shadowmem.ShadowMemory.Reset), so it's cleared too.
+	s.entryCount.Store(0)
+	s.evictions.Store(0)
+}
+
+// Snapshot returns a deep copy of every tracked SyncVar, keyed by address
+// (synth-3576).
+//
+// The returned map is independent of the live SyncShadow: mutating a
+// SyncVar afterward (live or in the snapshot) never affects the other.
+// This backs Detector.Snapshot, letting a test framework checkpoint sync
+// primitive state before a subtest and hand it to RestoreFrom afterward,
+// instead of the all-or-nothing Reset().
+//
+// Thread Safety: NOT safe for concurrent access, same convention as
+// Reset() - the caller must ensure no other goroutines are using the
+// shadow memory while snapshotting.
+func (s *SyncShadow) Snapshot() map[uintptr]*SyncVar {
+	snapshot := make(map[uintptr]*SyncVar)
+	s.vars.Range(func(key, value interface{}) bool {
+		snapshot[key.(uintptr)] = value.(*SyncVar).Clone()
+		return true
+	})
+	return snapshot
+}
+
+// RestoreFrom replaces all sync variable state with a deep copy of
+// snapshot, as previously returned by Snapshot (synth-3576).
+//
+// Cloning snapshot's entries (rather than adopting them directly) means the
+// same snapshot can be restored from more than once without later restores
+// sharing clocks with earlier ones.
+//
+// Thread Safety: NOT safe for concurrent access, same convention as
+// Reset() - the caller must ensure no other goroutines are using the
+// shadow memory during RestoreFrom().
+func (s *SyncShadow) RestoreFrom(snapshot map[uintptr]*SyncVar) {
+	s.Reset()
+	for addr, sv := range snapshot {
+		s.vars.Store(addr, sv.Clone())
+	}
+	// synth-3620: Reset() zeroed entryCount, and the Store calls above
+	// don't go through GetOrCreate's increment, so entryCount needs to be
+	// brought back in line with what was actually restored - otherwise
+	// evictIfOverBudget would think the shadow is empty right after a
+	// Restore that in fact left it well over maxBytes.
+	s.entryCount.Store(int64(len(snapshot)))
 }
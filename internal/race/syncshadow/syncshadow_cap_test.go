@@ -0,0 +1,111 @@
+package syncshadow
+
+import "testing"
+
+// TestNewSyncShadowWithCap_ZeroIsUnbounded verifies a zero cap behaves like
+// NewSyncShadow: no reclamation, regardless of how many entries are created
+// (synth-3620).
+func TestNewSyncShadowWithCap_ZeroIsUnbounded(t *testing.T) {
+	s := NewSyncShadowWithCap(0)
+
+	for i := uintptr(0); i < 100; i++ {
+		s.GetOrCreate(i * 8)
+	}
+
+	if got := s.Count(); got != 100 {
+		t.Errorf("Count() = %d, want 100 (no reclamation under an unbounded cap)", got)
+	}
+	if got := s.Evictions(); got != 0 {
+		t.Errorf("Evictions() = %d, want 0", got)
+	}
+}
+
+// TestSyncShadowWithCap_EvictsOnceOverBudget verifies that once the
+// estimated footprint would exceed maxBytes, GetOrCreate reclaims an entry
+// instead of growing past the cap, and records the reclamation (synth-3620).
+func TestSyncShadowWithCap_EvictsOnceOverBudget(t *testing.T) {
+	const capEntries = 4
+	s := NewSyncShadowWithCap(capEntries * estimatedSyncVarBytes)
+
+	for i := uintptr(0); i < capEntries*4; i++ {
+		s.GetOrCreate(i * 8)
+	}
+
+	if got := s.Count(); got > capEntries {
+		t.Errorf("Count() = %d, want at most %d (cap enforced)", got, capEntries)
+	}
+	if got := s.Evictions(); got == 0 {
+		t.Error("Evictions() = 0, want > 0 after inserting well past the cap")
+	}
+}
+
+// TestSyncShadowWithCap_EvictsLeastRecentlyTouched verifies reclamation
+// prefers an entry that hasn't been touched recently over one that was just
+// accessed (synth-3620).
+func TestSyncShadowWithCap_EvictsLeastRecentlyTouched(t *testing.T) {
+	const n = syncShadowEvictionSampleSize
+	addrs := make([]uintptr, n)
+	for i := range addrs {
+		addrs[i] = uintptr(i) * 8
+	}
+
+	s := NewSyncShadowWithCap((n - 1) * estimatedSyncVarBytes)
+	for _, a := range addrs[:n-1] {
+		s.GetOrCreate(a)
+	}
+
+	// Re-touch every entry except addrs[0], so it's the only one left with
+	// the oldest recency stamp when the next insert forces a reclamation.
+	for _, a := range addrs[1 : n-1] {
+		s.GetOrCreate(a)
+	}
+
+	// This insert pushes the estimate over budget and should reclaim addrs[0].
+	s.GetOrCreate(addrs[n-1])
+
+	snapshot := s.Snapshot()
+	if _, ok := snapshot[addrs[0]]; ok {
+		t.Error("addrs[0] survived, want the least-recently-touched entry reclaimed")
+	}
+	for _, a := range addrs[1:] {
+		if _, ok := snapshot[a]; !ok {
+			t.Errorf("addr %#x missing, want the recently-touched entry preserved", a)
+		}
+	}
+}
+
+// TestSyncShadowWithCap_ResetClearsEvictionState verifies Reset zeroes both
+// the entry count and the eviction metric, so a detector reused across
+// tests doesn't inherit a stale over-budget signal (synth-3620).
+func TestSyncShadowWithCap_ResetClearsEvictionState(t *testing.T) {
+	s := NewSyncShadowWithCap(estimatedSyncVarBytes)
+	for i := uintptr(0); i < 8; i++ {
+		s.GetOrCreate(i * 8)
+	}
+	if s.Evictions() == 0 {
+		t.Fatal("Evictions() = 0 before Reset, want > 0 to make this test meaningful")
+	}
+
+	s.Reset()
+
+	if got := s.Evictions(); got != 0 {
+		t.Errorf("Evictions() after Reset = %d, want 0", got)
+	}
+	if got := s.EstimatedBytes(); got != 0 {
+		t.Errorf("EstimatedBytes() after Reset = %d, want 0", got)
+	}
+}
+
+// TestSyncShadowEstimatedBytes_TracksEntryCount verifies EstimatedBytes
+// scales linearly with the number of live entries (synth-3620).
+func TestSyncShadowEstimatedBytes_TracksEntryCount(t *testing.T) {
+	s := NewSyncShadow()
+	for i := uintptr(0); i < 10; i++ {
+		s.GetOrCreate(i * 8)
+	}
+
+	want := uint64(10) * estimatedSyncVarBytes
+	if got := s.EstimatedBytes(); got != want {
+		t.Errorf("EstimatedBytes() = %d, want %d", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package syncshadow
 import (
 	"testing"
 
+	"github.com/kolkov/racedetector/internal/race/epoch"
 	"github.com/kolkov/racedetector/internal/race/vectorclock"
 )
 
@@ -129,7 +130,9 @@ func TestSyncVar_GetReleaseClock_Nil(t *testing.T) {
 	}
 }
 
-// TestSyncVar_SetReleaseClock_First verifies first SetReleaseClock allocates.
+// TestSyncVar_SetReleaseClock_First verifies SetReleaseClock stores the
+// clock by reference and marks it shared (synth-3618), rather than copying
+// it - the copy is deferred until whoever owns vc mutates it again.
 func TestSyncVar_SetReleaseClock_First(t *testing.T) {
 	sv := &SyncVar{}
 
@@ -138,16 +141,15 @@ func TestSyncVar_SetReleaseClock_First(t *testing.T) {
 	vc.Set(0, 10)
 	vc.Set(1, 20)
 
-	// First SetReleaseClock should allocate and copy.
 	sv.SetReleaseClock(vc)
 
 	// Verify releaseClock is now non-nil.
 	releaseClock := sv.GetReleaseClock()
 	if releaseClock == nil {
-		t.Fatal("SetReleaseClock did not allocate releaseClock")
+		t.Fatal("SetReleaseClock did not set releaseClock")
 	}
 
-	// Verify values were copied correctly.
+	// Verify values are visible through the stored clock.
 	if releaseClock.Get(0) != 10 {
 		t.Errorf("Expected clock[0]=10, got %d", releaseClock.Get(0))
 	}
@@ -155,13 +157,24 @@ func TestSyncVar_SetReleaseClock_First(t *testing.T) {
 		t.Errorf("Expected clock[1]=20, got %d", releaseClock.Get(1))
 	}
 
-	// Verify it's a copy, not a reference.
-	if releaseClock == vc {
-		t.Error("SetReleaseClock did not copy, it's a reference")
+	// Verify it's the same instance, shared by reference (synth-3618) - not
+	// a defensive copy - so a bare pointer store is enough to make this O(1).
+	if releaseClock != vc {
+		t.Error("SetReleaseClock copied instead of sharing by reference")
+	}
+
+	// Sharing must mark vc so a subsequent mutation copies-on-write instead
+	// of corrupting this frozen snapshot.
+	if got := vc.CloneIfShared(); got == vc {
+		t.Error("SetReleaseClock did not mark the clock as shared")
 	}
 }
 
-// TestSyncVar_SetReleaseClock_Update verifies subsequent SetReleaseClock updates in place.
+// TestSyncVar_SetReleaseClock_Update verifies subsequent SetReleaseClock
+// calls replace the stored reference (synth-3618) rather than copying into
+// the previous instance in place - the old and new instances may be
+// completely independent VectorClocks (e.g. the caller's clock was cloned
+// via CloneIfShared between releases), so there's nothing to "update".
 func TestSyncVar_SetReleaseClock_Update(t *testing.T) {
 	sv := &SyncVar{}
 
@@ -178,12 +191,19 @@ func TestSyncVar_SetReleaseClock_Update(t *testing.T) {
 	sv.SetReleaseClock(vc2)
 	secondClock := sv.GetReleaseClock()
 
-	// Verify same VectorClock instance (updated in place, no new allocation).
-	if firstClock != secondClock {
-		t.Error("SetReleaseClock allocated new clock instead of updating in place")
+	// Verify the stored clock now points at the second instance.
+	if secondClock != vc2 {
+		t.Error("SetReleaseClock did not switch to the newly shared clock")
+	}
+
+	// The first instance is untouched - a subsequent mutation to it (which
+	// would have been a bug even before synth-3618: releaseClock had already
+	// moved on) doesn't retroactively change what was reported here.
+	if firstClock.Get(0) != 10 {
+		t.Errorf("Expected the first shared clock to be left alone at clock[0]=10, got %d", firstClock.Get(0))
 	}
 
-	// Verify values were updated.
+	// Verify values are visible through the newly stored clock.
 	if secondClock.Get(0) != 20 {
 		t.Errorf("Expected clock[0]=20, got %d", secondClock.Get(0))
 	}
@@ -192,6 +212,105 @@ func TestSyncVar_SetReleaseClock_Update(t *testing.T) {
 	}
 }
 
+// TestSyncVar_IsSoleOwner_UninitializedIsSafeButNotSole verifies a SyncVar
+// that has never been Released reports no fast path (synth-3619) - matching
+// GetReleaseClock() == nil, which is the caller's own guard for this case in
+// practice.
+func TestSyncVar_IsSoleOwner_UninitializedIsSafeButNotSole(t *testing.T) {
+	sv := &SyncVar{}
+
+	if sv.IsSoleOwner(1, epoch.NewEpoch(1, 1)) {
+		t.Error("IsSoleOwner reported the fast path before any Release had occurred")
+	}
+}
+
+// TestSyncVar_IsSoleOwner_SameGoroutineSkipsJoin verifies that repeated
+// Release/Acquire cycles by the same goroutine keep reporting the fast path
+// (synth-3619, mirrors VarState.exclusiveWriter).
+func TestSyncVar_IsSoleOwner_SameGoroutineSkipsJoin(t *testing.T) {
+	sv := &SyncVar{}
+
+	sv.RecordRelease(5, epoch.NewEpoch(5, 1))
+	if !sv.IsSoleOwner(5, epoch.NewEpoch(5, 2)) {
+		t.Error("Expected the sole releaser to still qualify for the fast path")
+	}
+
+	// A second Release/Acquire cycle by the same goroutine, at a later
+	// clock, must still qualify.
+	sv.RecordRelease(5, epoch.NewEpoch(5, 3))
+	if !sv.IsSoleOwner(5, epoch.NewEpoch(5, 4)) {
+		t.Error("Expected repeated same-goroutine cycles to keep the fast path")
+	}
+}
+
+// TestSyncVar_IsSoleOwner_CrossThreadDemotesPermanently verifies a
+// different goroutine's Acquire demotes ownership to shared for good
+// (synth-3619) - even if the original owner later comes back.
+func TestSyncVar_IsSoleOwner_CrossThreadDemotesPermanently(t *testing.T) {
+	sv := &SyncVar{}
+
+	sv.RecordRelease(5, epoch.NewEpoch(5, 1))
+
+	// A different goroutine (TID 9) acquires - must not get the fast path,
+	// and must permanently retire it.
+	if sv.IsSoleOwner(9, epoch.NewEpoch(9, 1)) {
+		t.Error("Expected a cross-thread Acquire to be reported as not sole-owned")
+	}
+
+	// The original owner (TID 5) reacquiring afterward must not get the
+	// fast path back either.
+	sv.RecordRelease(9, epoch.NewEpoch(9, 2))
+	if sv.IsSoleOwner(5, epoch.NewEpoch(5, 5)) {
+		t.Error("Expected demotion to shared to persist even for the original owner")
+	}
+}
+
+// TestSyncVar_IsSoleOwner_TIDZeroNeverGetsFastPath verifies the same
+// "TID 0 doubles as uninitialized" limitation VarState.exclusiveWriter has
+// (synth-3619): a lock solely Released by TID 0 never qualifies for the
+// fast path, since owner == 0 can't be told apart from "no Release yet".
+// This must never cause a missed happens-before edge - only a missed
+// optimization - so IsSoleOwner must return false, not true, here.
+func TestSyncVar_IsSoleOwner_TIDZeroNeverGetsFastPath(t *testing.T) {
+	sv := &SyncVar{}
+
+	sv.RecordRelease(0, epoch.NewEpoch(0, 1))
+	if sv.IsSoleOwner(0, epoch.NewEpoch(0, 2)) {
+		t.Error("Expected TID 0 to never qualify for the single-owner fast path")
+	}
+}
+
+// TestSyncVar_IsSoleOwner_TimeTravelFallsBackToFullJoin verifies that if
+// ownerEpoch's clock is somehow later than the acquiring goroutine's own
+// current clock, IsSoleOwner distrusts the fast path (synth-3619, mirrors
+// OnWrite's identical monotonicity guard against exclusiveWriter).
+func TestSyncVar_IsSoleOwner_TimeTravelFallsBackToFullJoin(t *testing.T) {
+	sv := &SyncVar{}
+
+	sv.RecordRelease(5, epoch.NewEpoch(5, 10))
+	if sv.IsSoleOwner(5, epoch.NewEpoch(5, 3)) {
+		t.Error("Expected a clock regression to fall back to the full Join path")
+	}
+}
+
+// TestSyncVar_Clone_CopiesOwnerTracking verifies Clone copies owner and
+// ownerEpoch (synth-3619), consistent with every other SyncVar field.
+func TestSyncVar_Clone_CopiesOwnerTracking(t *testing.T) {
+	sv := &SyncVar{}
+	sv.RecordRelease(5, epoch.NewEpoch(5, 1))
+
+	clone := sv.Clone()
+	if !clone.IsSoleOwner(5, epoch.NewEpoch(5, 2)) {
+		t.Error("Expected Clone to preserve single-owner tracking")
+	}
+
+	// Mutating the clone's ownership must not affect the original.
+	clone.IsSoleOwner(9, epoch.NewEpoch(9, 1))
+	if !sv.IsSoleOwner(5, epoch.NewEpoch(5, 3)) {
+		t.Error("Expected the clone's demotion to be independent of the original")
+	}
+}
+
 // TestSyncVar_MergeReleaseClock_First verifies first MergeReleaseClock allocates.
 func TestSyncVar_MergeReleaseClock_First(t *testing.T) {
 	sv := &SyncVar{}
@@ -289,6 +408,51 @@ func TestSyncVar_MergeReleaseClock_RWMutexScenario(t *testing.T) {
 	}
 }
 
+// TestSyncVar_GetReadReleaseClock_Nil verifies nil is returned before any
+// RUnlock has occurred (synth-3570).
+func TestSyncVar_GetReadReleaseClock_Nil(t *testing.T) {
+	sv := &SyncVar{}
+	if got := sv.GetReadReleaseClock(); got != nil {
+		t.Errorf("Expected nil GetReadReleaseClock(), got %v", got)
+	}
+}
+
+// TestSyncVar_MergeReadReleaseClock_Accumulates verifies that
+// MergeReadReleaseClock is kept separate from MergeReleaseClock/
+// SetReleaseClock and accumulates across multiple readers (synth-3570).
+func TestSyncVar_MergeReadReleaseClock_Accumulates(t *testing.T) {
+	sv := &SyncVar{}
+
+	// Writer unlocks first (Lock/Unlock side, untouched by RUnlock).
+	writerClock := vectorclock.New()
+	writerClock.Set(2, 5)
+	sv.SetReleaseClock(writerClock)
+
+	// Reader 1 (TID=0) RUnlocks at clock=10.
+	reader1Clock := vectorclock.New()
+	reader1Clock.Set(0, 10)
+	sv.MergeReadReleaseClock(reader1Clock)
+
+	// Reader 2 (TID=1) RUnlocks at clock=15.
+	reader2Clock := vectorclock.New()
+	reader2Clock.Set(1, 15)
+	sv.MergeReadReleaseClock(reader2Clock)
+
+	// The writer's release clock must be untouched by RUnlock merges.
+	if got := sv.GetReleaseClock().Get(2); got != 5 {
+		t.Errorf("Expected releaseClock[2]=5 (writer, untouched), got %d", got)
+	}
+
+	// readReleaseClock accumulates both readers.
+	readReleaseClock := sv.GetReadReleaseClock()
+	if got := readReleaseClock.Get(0); got != 10 {
+		t.Errorf("Expected readReleaseClock[0]=10 (Reader 1), got %d", got)
+	}
+	if got := readReleaseClock.Get(1); got != 15 {
+		t.Errorf("Expected readReleaseClock[1]=15 (Reader 2), got %d", got)
+	}
+}
+
 // === Channel State Tests (Phase 4 Task 4.2) ===
 
 // TestSyncVar_GetOrCreateChannel verifies lazy channel state creation.
@@ -477,6 +641,149 @@ func TestSyncVar_ChannelState_Independent(t *testing.T) {
 	}
 }
 
+// TestSyncVar_ChannelCapacity_Unbuffered verifies that a channel with no
+// recorded capacity (capacity 0) still FIFO-matches sends to receives via
+// the unbounded sendQueue fallback, rather than collapsing to "whichever
+// send ran last" (synth-3569 - the capacity may simply never have been
+// recorded, even though the channel is genuinely buffered).
+func TestSyncVar_ChannelCapacity_Unbuffered(t *testing.T) {
+	sv := &SyncVar{}
+	sv.SetChannelCapacity(0) // explicit no-op
+
+	vc1 := vectorclock.New()
+	vc1.Set(0, 1)
+	sv.SetChannelSendClock(vc1)
+
+	vc2 := vectorclock.New()
+	vc2.Set(0, 2)
+	sv.SetChannelSendClock(vc2)
+
+	// Two sends happened, no receive yet: the 1st receive must match the
+	// 1st send, not "the last one".
+	if got := sv.GetChannelRecvJoinClock(); got.Get(0) != 1 {
+		t.Errorf("GetChannelRecvJoinClock() = clock[0]=%d, want 1 (1st send, FIFO)", got.Get(0))
+	}
+
+	sv.SetChannelRecvClock(vectorclock.New())
+
+	// The 2nd receive must match the 2nd send.
+	if got := sv.GetChannelRecvJoinClock(); got.Get(0) != 2 {
+		t.Errorf("GetChannelRecvJoinClock() = clock[0]=%d, want 2 (2nd send, FIFO)", got.Get(0))
+	}
+}
+
+// TestSyncVar_UnbufferedChannel_ReverseEdge verifies that an unbuffered send
+// merges the channel's most recent recvClock into the sender's clock - the
+// receiver already waiting at the rendezvous happens-before this send
+// returns too (synth-3568).
+func TestSyncVar_UnbufferedChannel_ReverseEdge(t *testing.T) {
+	sv := &SyncVar{}
+
+	// First send: no prior receive, so nothing to merge.
+	send1 := vectorclock.New()
+	send1.Set(0, 1)
+	sv.SetChannelSendClock(send1)
+	if send1.Get(1) != 0 {
+		t.Errorf("1st send's clock[1] = %d, want 0 (no prior receive to merge)", send1.Get(1))
+	}
+
+	// Receiver takes the value and does more work.
+	recv := vectorclock.New()
+	recv.Set(1, 7)
+	sv.SetChannelRecvClock(recv)
+
+	// Second send should observe the receiver's clock (rendezvous).
+	send2 := vectorclock.New()
+	send2.Set(0, 2)
+	sv.SetChannelSendClock(send2)
+	if send2.Get(1) != 7 {
+		t.Errorf("2nd send's clock[1] = %d, want 7 (merged from the waiting receiver)", send2.Get(1))
+	}
+}
+
+// TestSyncVar_BufferedChannel_NoReverseEdge verifies that the unbuffered
+// rendezvous reverse edge does NOT apply to buffered channels, which use
+// their own backpressure model instead (see
+// TestSyncVar_ChannelCapacity_Backpressure).
+func TestSyncVar_BufferedChannel_NoReverseEdge(t *testing.T) {
+	sv := &SyncVar{}
+	sv.SetChannelCapacity(4) // plenty of room, no backpressure in play
+
+	recv := vectorclock.New()
+	recv.Set(1, 7)
+	sv.SetChannelRecvClock(recv)
+
+	send := vectorclock.New()
+	send.Set(0, 1)
+	sv.SetChannelSendClock(send)
+	if send.Get(1) != 0 {
+		t.Errorf("buffered send's clock[1] = %d, want 0 (no rendezvous reverse edge)", send.Get(1))
+	}
+}
+
+// TestSyncVar_ChannelCapacity_FIFOMatching verifies that a buffered channel
+// matches each receive to the send that produced its value, not to whatever
+// send happened to run most recently - the bug synth-3567 fixes.
+func TestSyncVar_ChannelCapacity_FIFOMatching(t *testing.T) {
+	sv := &SyncVar{}
+	sv.SetChannelCapacity(2) // buffered, capacity 2
+
+	// Two sends race ahead of the receiver (both fit in the buffer).
+	vc1 := vectorclock.New()
+	vc1.Set(0, 1)
+	sv.SetChannelSendClock(vc1) // 1st send
+
+	vc2 := vectorclock.New()
+	vc2.Set(1, 1)
+	sv.SetChannelSendClock(vc2) // 2nd send, unrelated goroutine
+
+	// The 1st receive must join the 1st send's clock, not the 2nd's.
+	first := sv.GetChannelRecvJoinClock()
+	if first == nil {
+		t.Fatal("GetChannelRecvJoinClock() = nil for 1st receive, want 1st send's clock")
+	}
+	if first.Get(0) != 1 || first.Get(1) != 0 {
+		t.Errorf("1st receive joined clock{0:%d,1:%d}, want clock{0:1,1:0} (1st send)", first.Get(0), first.Get(1))
+	}
+	recvClock1 := vectorclock.New()
+	sv.SetChannelRecvClock(recvClock1) // 1st receive completes
+
+	// The 2nd receive must join the 2nd send's clock.
+	second := sv.GetChannelRecvJoinClock()
+	if second == nil {
+		t.Fatal("GetChannelRecvJoinClock() = nil for 2nd receive, want 2nd send's clock")
+	}
+	if second.Get(0) != 0 || second.Get(1) != 1 {
+		t.Errorf("2nd receive joined clock{0:%d,1:%d}, want clock{0:0,1:1} (2nd send)", second.Get(0), second.Get(1))
+	}
+}
+
+// TestSyncVar_ChannelCapacity_Backpressure verifies that a send reusing a
+// full buffer's slot joins the clock of the receive that freed it - the
+// n-th receive happens-before the (n+C)-th send completes.
+func TestSyncVar_ChannelCapacity_Backpressure(t *testing.T) {
+	sv := &SyncVar{}
+	sv.SetChannelCapacity(1) // buffered, capacity 1
+
+	send1 := vectorclock.New()
+	send1.Set(0, 1)
+	sv.SetChannelSendClock(send1) // fills the single slot
+
+	recv1 := vectorclock.New()
+	recv1.Set(1, 5)
+	sv.SetChannelRecvClock(recv1) // drains it, freeing the slot
+
+	// The 2nd send (index 1 == capacity) reuses that slot; it must observe
+	// the receive that freed it.
+	send2 := vectorclock.New()
+	send2.Set(0, 2)
+	sv.SetChannelSendClock(send2)
+
+	if send2.Get(1) != 5 {
+		t.Errorf("2nd send's clock[1] = %d, want 5 (joined from the freeing receive)", send2.Get(1))
+	}
+}
+
 // === WaitGroup Tests (Phase 4 Task 4.3) ===
 
 // TestSyncVar_GetOrCreateWaitGroup verifies lazy WaitGroup state allocation.
@@ -692,3 +999,187 @@ func TestSyncVar_WaitGroupCounterAndClock(t *testing.T) {
 
 	// Counter=0 means Wait() can return, and waiter will merge doneClock.
 }
+
+// === Finalizer State Tests (synth-3572) ===
+
+// TestSyncVar_GetFinalizerRegisterClock_Nil verifies nil is returned before
+// any SetFinalizer registration has occurred.
+func TestSyncVar_GetFinalizerRegisterClock_Nil(t *testing.T) {
+	sv := &SyncVar{}
+	if got := sv.GetFinalizerRegisterClock(); got != nil {
+		t.Errorf("Expected nil GetFinalizerRegisterClock(), got %v", got)
+	}
+}
+
+// TestSyncVar_SetFinalizerRegisterClock_First verifies the clock is captured
+// (copied, not aliased) on the first SetFinalizer call.
+func TestSyncVar_SetFinalizerRegisterClock_First(t *testing.T) {
+	sv := &SyncVar{}
+
+	clock := vectorclock.New()
+	clock.Set(0, 5)
+	sv.SetFinalizerRegisterClock(clock)
+
+	got := sv.GetFinalizerRegisterClock()
+	if got.Get(0) != 5 {
+		t.Errorf("Expected registerClock[0]=5, got %d", got.Get(0))
+	}
+
+	// Mutating the original clock afterward must not affect the captured one.
+	clock.Set(0, 100)
+	if got.Get(0) != 5 {
+		t.Errorf("Expected registerClock[0] to remain 5 after mutating original, got %d", got.Get(0))
+	}
+}
+
+// TestSyncVar_SetFinalizerRegisterClock_ReRegistration verifies a second
+// SetFinalizer call replaces the first clock, matching the runtime's own
+// last-registration-wins semantics.
+func TestSyncVar_SetFinalizerRegisterClock_ReRegistration(t *testing.T) {
+	sv := &SyncVar{}
+
+	first := vectorclock.New()
+	first.Set(0, 5)
+	sv.SetFinalizerRegisterClock(first)
+
+	second := vectorclock.New()
+	second.Set(0, 10)
+	sv.SetFinalizerRegisterClock(second)
+
+	if got := sv.GetFinalizerRegisterClock().Get(0); got != 10 {
+		t.Errorf("Expected registerClock[0]=10 (re-registration replaces), got %d", got)
+	}
+}
+
+// === Singleflight State Tests (synth-3574) ===
+
+// TestSyncVar_GetSingleflightDoneClock_Nil verifies nil is returned before
+// any call cycle has completed for this key.
+func TestSyncVar_GetSingleflightDoneClock_Nil(t *testing.T) {
+	sv := &SyncVar{}
+	if got := sv.GetSingleflightDoneClock(); got != nil {
+		t.Errorf("Expected nil GetSingleflightDoneClock(), got %v", got)
+	}
+}
+
+// TestSyncVar_SetSingleflightDoneClock_First verifies the clock is captured
+// (copied, not aliased) on the first completed call cycle.
+func TestSyncVar_SetSingleflightDoneClock_First(t *testing.T) {
+	sv := &SyncVar{}
+
+	clock := vectorclock.New()
+	clock.Set(0, 5)
+	sv.SetSingleflightDoneClock(clock)
+
+	got := sv.GetSingleflightDoneClock()
+	if got.Get(0) != 5 {
+		t.Errorf("Expected doneClock[0]=5, got %d", got.Get(0))
+	}
+
+	// Mutating the original clock afterward must not affect the captured one.
+	clock.Set(0, 100)
+	if got.Get(0) != 5 {
+		t.Errorf("Expected doneClock[0] to remain 5 after mutating original, got %d", got.Get(0))
+	}
+}
+
+// TestSyncVar_SetSingleflightDoneClock_NewCycle verifies a second call cycle
+// replaces the first clock, matching singleflight's own semantics of only
+// ever handing out the latest result.
+func TestSyncVar_SetSingleflightDoneClock_NewCycle(t *testing.T) {
+	sv := &SyncVar{}
+
+	first := vectorclock.New()
+	first.Set(0, 5)
+	sv.SetSingleflightDoneClock(first)
+
+	second := vectorclock.New()
+	second.Set(0, 10)
+	sv.SetSingleflightDoneClock(second)
+
+	if got := sv.GetSingleflightDoneClock().Get(0); got != 10 {
+		t.Errorf("Expected doneClock[0]=10 (new cycle replaces), got %d", got)
+	}
+}
+
+// === Checkpoint/Restore Tests (synth-3576) ===
+
+// TestSyncVar_Clone_IsIndependentCopy verifies mutating the original's
+// releaseClock after Clone does not affect the clone.
+func TestSyncVar_Clone_IsIndependentCopy(t *testing.T) {
+	sv := &SyncVar{}
+	clock := vectorclock.New()
+	clock.Set(0, 5)
+	sv.SetReleaseClock(clock)
+
+	clone := sv.Clone()
+	sv.SetReleaseClock(func() *vectorclock.VectorClock {
+		c := vectorclock.New()
+		c.Set(0, 999)
+		return c
+	}())
+
+	if got := clone.GetReleaseClock().Get(0); got != 5 {
+		t.Errorf("clone.GetReleaseClock().Get(0) after mutating original = %d, want unchanged 5", got)
+	}
+}
+
+// TestSyncVar_Clone_CopiesSubStates verifies Clone deep-copies every
+// lazily-allocated sub-state (channel, waitGroup, finalizer, singleflight).
+func TestSyncVar_Clone_CopiesSubStates(t *testing.T) {
+	sv := &SyncVar{}
+
+	clock := vectorclock.New()
+	clock.Set(0, 1)
+	sv.SetChannelSendClock(clock)
+	sv.WaitGroupAdd(1)
+	sv.SetFinalizerRegisterClock(clock)
+	sv.SetSingleflightDoneClock(clock)
+
+	clone := sv.Clone()
+
+	if clone.GetChannelSendClock() == nil {
+		t.Error("clone.GetChannelSendClock() = nil, want copied channel state")
+	}
+	if got := clone.GetWaitGroupCounter(); got != 1 {
+		t.Errorf("clone.GetWaitGroupCounter() = %d, want 1", got)
+	}
+	if clone.GetFinalizerRegisterClock() == nil {
+		t.Error("clone.GetFinalizerRegisterClock() = nil, want copied finalizer state")
+	}
+	if clone.GetSingleflightDoneClock() == nil {
+		t.Error("clone.GetSingleflightDoneClock() = nil, want copied singleflight state")
+	}
+}
+
+// TestSyncShadow_Snapshot_RestoreFrom_RoundTrips verifies an address
+// present at Snapshot time is restored with the same release clock, even
+// after the live SyncShadow diverges in between.
+func TestSyncShadow_Snapshot_RestoreFrom_RoundTrips(t *testing.T) {
+	shadow := NewSyncShadow()
+	addr := uintptr(0x1234)
+
+	clock := vectorclock.New()
+	clock.Set(0, 5)
+	shadow.GetOrCreate(addr).SetReleaseClock(clock)
+
+	snap := shadow.Snapshot()
+
+	// Diverge the live SyncShadow after snapshotting.
+	other := vectorclock.New()
+	other.Set(0, 999)
+	shadow.GetOrCreate(addr).SetReleaseClock(other)
+	shadow.GetOrCreate(uintptr(0x5678))
+
+	shadow.RestoreFrom(snap)
+
+	restored := shadow.GetOrCreate(addr).GetReleaseClock()
+	if got := restored.Get(0); got != 5 {
+		t.Errorf("GetReleaseClock().Get(0) after RestoreFrom = %d, want 5", got)
+	}
+
+	fresh := shadow.GetOrCreate(uintptr(0x5678))
+	if fresh.GetReleaseClock() != nil {
+		t.Error("0x5678 SyncVar after RestoreFrom has a releaseClock, want nil (not in snapshot)")
+	}
+}
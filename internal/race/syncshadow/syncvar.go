@@ -1,6 +1,7 @@
 package syncshadow
 
 import (
+	"github.com/kolkov/racedetector/internal/race/epoch"
 	"github.com/kolkov/racedetector/internal/race/vectorclock"
 )
 
@@ -76,15 +77,30 @@ type WaitGroupState struct {
 //   - Buffered channel: kth Receive happens-before (k+C)th Send completes
 //   - Channel close: close(ch) happens-before all receives that observe closure
 //
-// For MVP (Task 4.2), we treat all channels as unbuffered for simplicity.
-// This is conservative - it won't produce false negatives (missed races),
-// but may be slightly less permissive than the full memory model.
+// For unbuffered channels (capacity == 0, the default), we keep the original
+// MVP (Task 4.2) model: a single sendClock/recvClock pair updated on every
+// operation. This is conservative - it won't produce false negatives (missed
+// races) - but is unsound for buffered channels, where "join with the most
+// recent send" can pair a receive with the wrong message: if two sends race
+// ahead of a slow receiver, the receiver observes only the last sender's
+// clock, silently dropping the happens-before edge from whichever sender's
+// value it actually got.
+//
+// For buffered channels (capacity > 0, set via SetChannelCapacity), sendRing
+// and recvRing record one clock per in-flight message, indexed by sequence
+// number modulo capacity, so each receive joins the clock of the send that
+// actually produced its value (FIFO order), and each send that would reuse a
+// still-occupied buffer slot joins the clock of the receive that freed it
+// (the n-th receive happens-before the (n+C)-th send completes).
 //
 // Layout:
-//   - sendClock: VectorClock from the last send operation
-//   - recvClock: VectorClock from the last receive operation
+//   - sendClock: VectorClock from the last send operation (unbuffered path)
+//   - recvClock: VectorClock from the last receive operation (unbuffered path)
 //   - closeClock: VectorClock from channel close (nil if not closed)
 //   - isClosed: Flag indicating if channel is closed
+//   - capacity: Buffer capacity (0 means unbuffered or not yet recorded)
+//   - sendRing, recvRing: Per-message clocks for buffered channels
+//   - sendSeq, recvSeq: Total sends/receives observed so far
 //
 // Operations:
 //   - OnSendAfter: Captures sender's clock (sendClock := sender.C)
@@ -92,7 +108,9 @@ type WaitGroupState struct {
 //   - OnClose: Captures close clock, sets isClosed flag
 //
 // Memory:
-//   - Size: ~3KB (3 VectorClocks x 1KB each) + 1 byte flag
+//   - Unbuffered: ~3KB (3 VectorClocks x 1KB each) + 1 byte flag
+//   - Buffered: additionally, 2 * capacity VectorClock pointers (~8 bytes
+//     each) plus up to 2 * capacity VectorClocks (~1KB each) once populated
 //   - Allocated lazily on first channel operation
 //
 // Lifecycle:
@@ -113,14 +131,18 @@ type ChannelState struct {
 	//
 	// On Send, the sender's clock is captured into sendClock.
 	// On Receive, the receiver merges sendClock into its own clock.
+	//
+	// For buffered channels (capacity > 0), this still tracks the most
+	// recent send, but GetChannelRecvJoinClock prefers sendRing so each
+	// receive joins the clock of the send that actually produced its value.
 	sendClock *vectorclock.VectorClock
 
 	// recvClock is the vector clock from the last receive operation.
 	// nil means no receive has occurred yet.
 	//
-	// For bidirectional synchronization (unbuffered channels), recvClock
-	// can be merged back into sender's clock if needed.
-	// MVP: Not used for now, reserved for future bidirectional sync.
+	// For unbuffered channels, SetChannelSendClock merges recvClock back
+	// into the next sender's clock (the rendezvous reverse edge - a
+	// receiver happens-before the send it unblocks).
 	recvClock *vectorclock.VectorClock
 
 	// closeClock is the vector clock when the channel was closed.
@@ -136,6 +158,37 @@ type ChannelState struct {
 	// After close, receives are allowed (until channel is drained),
 	// but sends will panic. We track this for correctness.
 	isClosed bool
+
+	// capacity is the channel's buffer capacity, as recorded by
+	// SetChannelCapacity. 0 means unbuffered, or that the capacity was never
+	// recorded (e.g. SetChannelCapacity/OnChannelMake was never called for
+	// this channel) - in which case sendQueue is used instead of sendRing.
+	capacity int
+
+	// sendRing holds one clock per in-flight message, indexed by
+	// sendSeq % capacity. Only allocated when capacity > 0.
+	sendRing []*vectorclock.VectorClock
+
+	// recvRing holds one clock per completed receive, indexed by
+	// recvSeq % capacity. Only allocated when capacity > 0. Used to
+	// implement backpressure: the n-th receive happens-before the (n+C)-th
+	// send completes.
+	recvRing []*vectorclock.VectorClock
+
+	// sendQueue holds one clock per send, in FIFO order, appended to on
+	// every send and indexed (never popped) by recvSeq. Used in place of
+	// sendRing when capacity is unknown/unbuffered (capacity == 0): without
+	// a known buffer size we can't bound a ring, but we still don't want to
+	// collapse multiple racing producers into "whichever sent last" - see
+	// GetChannelRecvJoinClock. Grows unboundedly for the lifetime of the
+	// channel, same as the other Never-freed sync state in this file.
+	sendQueue []*vectorclock.VectorClock
+
+	// sendSeq is the total number of sends observed so far (0-indexed).
+	sendSeq int64
+
+	// recvSeq is the total number of receives observed so far (0-indexed).
+	recvSeq int64
 }
 
 // SyncVar tracks happens-before relationships for a synchronization primitive.
@@ -149,7 +202,8 @@ type ChannelState struct {
 //
 // Operations:
 //   - Acquire: Thread merges releaseClock into its own clock
-//   - Release: Thread copies its clock into releaseClock
+//   - Release: Thread shares its clock with releaseClock, copy-on-write
+//     (synth-3618, see SetReleaseClock)
 //   - ReleaseMerge: Thread merges its clock into releaseClock (for RWMutex)
 //
 // Memory:
@@ -166,9 +220,9 @@ type ChannelState struct {
 //
 //	sv := &SyncVar{}
 //	// First unlock: sv.releaseClock = nil
-//	sv.SetReleaseClock(threadClock)  // Allocates and copies
+//	sv.SetReleaseClock(threadClock)  // Stores threadClock by reference
 //	// Next lock: threadClock.Join(sv.releaseClock)
-//	sv.SetReleaseClock(threadClock)  // Updates existing clock
+//	sv.SetReleaseClock(threadClock)  // Still O(1) if threadClock hasn't mutated
 type SyncVar struct {
 	// releaseClock is the vector clock from the last Release operation.
 	// nil means no Release has occurred yet (uninitialized mutex).
@@ -176,7 +230,9 @@ type SyncVar struct {
 	// On Acquire (Lock), threads merge this into their own clock to establish
 	// happens-before from the previous Unlock.
 	//
-	// On Release (Unlock), this is updated to the current thread's clock.
+	// On Release (Unlock), this is set to the current thread's clock by
+	// reference, copy-on-write (synth-3618, see SetReleaseClock) - it may
+	// be the very same *VectorClock the releasing RaceContext still owns.
 	releaseClock *vectorclock.VectorClock
 
 	// channel tracks happens-before relationships for channel operations.
@@ -192,6 +248,77 @@ type SyncVar struct {
 	// Allocated lazily on first WaitGroup operation (Add/Done/Wait).
 	// Phase 4 Task 4.3: WaitGroup synchronization support.
 	waitGroup *WaitGroupState
+
+	// finalizer tracks happens-before relationships for runtime.SetFinalizer
+	// registrations on this object. nil means no finalizer has ever been
+	// registered for this address.
+	//
+	// Allocated lazily on first SetFinalizer call. synth-3572.
+	finalizer *FinalizerState
+
+	// singleflight tracks happens-before relationships for a
+	// golang.org/x/sync/singleflight.Group call, keyed by a caller-derived
+	// address identifying one (Group, key) pair. nil means no Do/DoChan call
+	// has ever completed for that pair.
+	//
+	// Allocated lazily on the first completed call. synth-3574.
+	singleflight *SingleflightState
+
+	// readReleaseClock accumulates vector clocks from RUnlock (RWMutex
+	// reader release) operations, kept separate from releaseClock (which
+	// tracks only Lock/Unlock, the writer side).
+	//
+	// Standard RWMutex happens-before rules (synth-3570): concurrent readers
+	// never need to happen-before each other, since concurrent reads are
+	// never themselves a race, so RLock only joins releaseClock (the last
+	// writer). A subsequent Lock, however, cannot proceed until every
+	// outstanding RLock/RUnlock has completed (real RWMutex mutual
+	// exclusion), so it must join both releaseClock and readReleaseClock.
+	//
+	// Before this field existed, RLock/RUnlock reused OnAcquire and
+	// MergeReleaseClock against the single releaseClock that Lock/Unlock
+	// also use, which chained every reader to every other reader in
+	// whatever order their RUnlock happened to run. Vector-clock algorithms
+	// have no way to "unlearn" a happens-before edge once added, so that
+	// unnecessary reader-reader edge could silently absorb a genuinely
+	// unrelated race between those two goroutines into false synchronization.
+	readReleaseClock *vectorclock.VectorClock
+
+	// owner and ownerEpoch implement a SmartTrack-style single-owner fast
+	// path for OnAcquire (synth-3619), mirroring
+	// shadowmem.VarState.exclusiveWriter: most mutexes are, in practice,
+	// only ever Locked and Unlocked by one goroutine, so maintaining a full
+	// releaseClock for OnAcquire to Join is wasted work - a goroutine
+	// re-Locking its own last Unlock is trivially already happens-after it.
+	//
+	//   - owner == 0: uninitialized, no Release observed yet
+	//   - owner  > 0: TID of the sole goroutine that has Released this lock
+	//   - owner == -1: shared - a different goroutine has Acquired since,
+	//     so the fast path is retired for good (mirrors exclusiveWriter's
+	//     own permanent promotion to -1 once a second writer appears)
+	//
+	// Unlike exclusiveWriter, this never gates whether releaseClock itself
+	// gets maintained - SetReleaseClock always runs on every Release,
+	// exactly as before synth-3619. owner only controls whether
+	// OnAcquire's Join(releaseClock) call can be skipped; no
+	// happens-before information is ever discarded by promotion to -1.
+	owner int64
+
+	// ownerEpoch is the epoch captured at the most recent Release while
+	// owner is still a single TID. Not required for IsSoleOwner's
+	// correctness on its own - a goroutine re-Locking its own last Unlock
+	// is safe regardless of clock values - but kept as a monotonicity
+	// self-check mirroring OnWrite's identical guard against
+	// exclusiveWriter, in case a corruption bug ever makes owner's TID
+	// match without ownerEpoch's clock having actually advanced.
+	ownerEpoch epoch.Epoch
+
+	// lastTouch is a logical (not wall-clock) recency stamp, set from
+	// SyncShadow's own access counter on every GetOrCreate (synth-3620).
+	// It backs the approximate LRU reclamation SyncShadow performs once a
+	// configured MaxSyncShadowBytes cap is exceeded, mirroring
+	// VarState.lastTouch's identical role for shadow memory cells.
+	lastTouch uint64
 }
 
 // GetReleaseClock returns the release clock for this sync variable.
@@ -215,17 +342,25 @@ func (sv *SyncVar) GetReleaseClock() *vectorclock.VectorClock {
 // SetReleaseClock sets the release clock for this sync variable.
 //
 // This is called during Release (Unlock) to capture the current thread's
-// vector clock. The clock is copied (not referenced) to avoid aliasing issues.
-//
-// If releaseClock is nil (first Release), a new VectorClock is allocated.
-// Otherwise, the existing clock is updated in place to avoid allocations.
+// vector clock. clock is stored BY REFERENCE and marked copy-on-write
+// (synth-3618, see VectorClock.MarkShared) rather than copied: the caller
+// (OnRelease) must have already advanced clock past this Release event
+// (its ctx.IncrementClock()) before calling this, since the releasing
+// goroutine's next mutation of that same VectorClock - its own next
+// IncrementClock or JoinClock - will transparently swap it onto a private
+// copy rather than touching this frozen snapshot. In steady state (no
+// mutation between calls, the common case for an uncontended mutex) this
+// makes Release O(1): a pointer store instead of an O(MaxThreads) copy.
 //
 // Parameters:
-//   - clock: The vector clock to copy (must not be nil)
+//   - clock: The vector clock to share (must not be nil)
 //
 // Performance:
-//   - First call: Allocates VectorClock (~1KB) and copies
-//   - Subsequent calls: Updates in place (no allocations)
+//   - O(1): stores clock by reference and marks it shared, no copy.
+//   - The O(MaxThreads) copy this used to pay here happens later instead,
+//     lazily, only if and when the releasing goroutine mutates its clock
+//     again (CloneIfShared) - and not at all if it never does before the
+//     next Release.
 //
 // Thread Safety: NOT thread-safe on its own. The caller must ensure
 // synchronization.
@@ -234,18 +369,94 @@ func (sv *SyncVar) GetReleaseClock() *vectorclock.VectorClock {
 //
 //	sv := &SyncVar{}
 //	ctx := goroutine.Alloc(0)
-//	sv.SetReleaseClock(ctx.C)  // First call: allocates + copies
 //	ctx.IncrementClock()
-//	sv.SetReleaseClock(ctx.C)  // Second call: updates in place
+//	sv.SetReleaseClock(ctx.C)  // O(1): stores ctx.C by reference
 func (sv *SyncVar) SetReleaseClock(clock *vectorclock.VectorClock) {
-	if sv.releaseClock == nil {
-		// First Release: Allocate a new VectorClock and copy.
-		sv.releaseClock = clock.Clone()
-	} else {
-		// Subsequent Release: Update in place to avoid allocations.
-		// v0.3.0: Use CopyFrom for sparse-aware copying.
-		sv.releaseClock.CopyFrom(clock)
+	clock.MarkShared()
+	sv.releaseClock = clock
+}
+
+// RecordRelease updates single-owner tracking for this sync variable
+// (synth-3619, mirrors VarState.exclusiveWriter): the first Release claims
+// sole ownership, a Release from that same goroutine keeps it, and a
+// Release from any other goroutine - which can only happen after that
+// goroutine's own Acquire already demoted ownership via IsSoleOwner -
+// leaves it permanently shared.
+//
+// This is independent of SetReleaseClock and does not change what it does;
+// call both on every Release, in either order.
+//
+// owner == 0 doubles as "uninitialized" here (see IsSoleOwner), so a lock
+// solely Released by TID 0 records that fact but never actually benefits
+// from the fast path - a harmless, VarState-mirrored limitation, not a
+// correctness issue: IsSoleOwner falls back to the full Join whenever it
+// can't tell the two apart.
+//
+// Parameters:
+//   - tid: The releasing goroutine's thread ID
+//   - ep: The releasing goroutine's epoch at the time of this Release,
+//     cached purely for IsSoleOwner's monotonicity self-check
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization. In practice, this is naturally serialized by the real
+// mutex being tracked: only the goroutine currently holding the lock can
+// call Release on it.
+func (sv *SyncVar) RecordRelease(tid uint16, ep epoch.Epoch) {
+	switch {
+	case sv.owner == 0:
+		sv.owner = int64(tid)
+	case sv.owner != int64(tid):
+		sv.owner = -1
 	}
+	sv.ownerEpoch = ep
+}
+
+// IsSoleOwner reports whether tid is the only goroutine that has ever
+// Released this lock, enabling OnAcquire's SmartTrack-style fast path
+// (synth-3619, mirrors VarState.exclusiveWriter): re-Locking one's own last
+// Unlock is trivially already happens-after it, so joining releaseClock
+// into the acquirer's clock would be a guaranteed no-op.
+//
+// currentEpoch is the acquiring goroutine's own current epoch, used for a
+// monotonicity self-check against ownerEpoch (mirrors OnWrite's identical
+// guard against exclusiveWriter): since owner only matches when tid is the
+// same goroutine that performed the last Release, its clock must not have
+// gone backwards since then. A violation falls back to the full Join path
+// rather than trust the fast path - it should never happen outside a
+// corruption bug in the lock-free paths, but this costs nothing to check.
+//
+// As a side effect, the first time a different tid is observed here,
+// ownership is permanently demoted to shared: from then on IsSoleOwner
+// always returns false for this SyncVar, even if the original owner comes
+// back, exactly matching how exclusiveWriter's own promotion to -1 never
+// re-elevates.
+//
+// Like exclusiveWriter, owner == 0 doubles as both "uninitialized" and a
+// legitimate TID 0 - the two can't be told apart - so a lock whose sole
+// releaser happens to be TID 0 never qualifies for the fast path (mirrors
+// OnWrite's identical "exclusiveWriter != 0" guard).
+//
+// Parameters:
+//   - tid: The acquiring goroutine's thread ID
+//   - currentEpoch: The acquiring goroutine's current epoch
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization. In practice, this is naturally serialized by the real
+// mutex being tracked: a goroutine's Acquire can only run after the
+// previous holder's Release, so there is no concurrent access to owner to
+// race with.
+func (sv *SyncVar) IsSoleOwner(tid uint16, currentEpoch epoch.Epoch) bool {
+	if sv.owner != 0 && sv.owner == int64(tid) {
+		_, prevClock := sv.ownerEpoch.Decode()
+		_, currentClock := currentEpoch.Decode()
+		return prevClock <= currentClock
+	}
+	if sv.owner != 0 && sv.owner != int64(tid) {
+		// A different goroutine than the recorded sole releaser is
+		// acquiring - demote to shared for good.
+		sv.owner = -1
+	}
+	return false
 }
 
 // MergeReleaseClock merges a clock into the release clock (for RWMutex).
@@ -287,6 +498,42 @@ func (sv *SyncVar) MergeReleaseClock(clock *vectorclock.VectorClock) {
 	}
 }
 
+// GetReadReleaseClock returns the accumulated reader release clock for this
+// sync variable (synth-3570).
+//
+// Returns nil if no RUnlock has occurred yet. The caller should check for
+// nil before using the clock.
+//
+// Thread Safety: NOT thread-safe on its own. The caller (SyncShadow) must
+// ensure synchronization via sync.Map or other mechanisms.
+func (sv *SyncVar) GetReadReleaseClock() *vectorclock.VectorClock {
+	return sv.readReleaseClock
+}
+
+// MergeReadReleaseClock merges a clock into the accumulated reader release
+// clock (synth-3570).
+//
+// This is called on RUnlock, where multiple readers may have overlapping
+// critical sections: unlike releaseClock (Lock/Unlock, exclusive), each
+// RUnlock merges (joins) into readReleaseClock rather than overwriting it,
+// so a later Lock sees the union of every reader since the last writer.
+//
+// If readReleaseClock is nil (first RUnlock), the clock is copied.
+// Otherwise, the join operation (element-wise max) is performed in place.
+//
+// Parameters:
+//   - clock: The vector clock to merge (must not be nil)
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization.
+func (sv *SyncVar) MergeReadReleaseClock(clock *vectorclock.VectorClock) {
+	if sv.readReleaseClock == nil {
+		sv.readReleaseClock = clock.Clone()
+	} else {
+		sv.readReleaseClock.Join(clock)
+	}
+}
+
 // === Channel State Management (Phase 4 Task 4.2) ===
 
 // GetOrCreateChannel returns the ChannelState for this SyncVar, creating it if needed.
@@ -327,11 +574,60 @@ func (sv *SyncVar) GetChannel() *ChannelState {
 	return sv.channel
 }
 
+// SetChannelCapacity records a channel's buffer capacity, enabling the
+// per-message send/recv ring described on ChannelState.
+//
+// This should be called once, when the channel is created (e.g. on
+// make(chan T, N)). It is idempotent: only the first call with a positive
+// capacity has any effect, matching the lazy, allocate-once style of the
+// rest of this file. Calling it with capacity <= 0 is a no-op, leaving the
+// channel on the unbuffered (single sendClock/recvClock) path.
+//
+// Parameters:
+//   - capacity: The channel's buffer capacity (from cap(ch))
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization.
+func (sv *SyncVar) SetChannelCapacity(capacity int) {
+	chState := sv.GetOrCreateChannel()
+	if chState.capacity != 0 || capacity <= 0 {
+		return
+	}
+	chState.capacity = capacity
+	chState.sendRing = make([]*vectorclock.VectorClock, capacity)
+	chState.recvRing = make([]*vectorclock.VectorClock, capacity)
+}
+
 // SetChannelSendClock captures the sender's clock on channel send.
 //
 // This is called after a channel send completes. The sender's clock is
 // copied into the channel's sendClock for the receiver to merge.
 //
+// For buffered channels (SetChannelCapacity was called with a positive
+// capacity), the clock is also recorded into sendRing at sendSeq % capacity,
+// so GetChannelRecvJoinClock can later pair it with the matching receive
+// instead of whatever the most recent send happens to be. Before recording,
+// if this send would reuse a buffer slot (sendSeq >= capacity), clock is
+// joined with the recvRing entry for the receive that freed that slot - the
+// n-th receive happens-before the (n+C)-th send completes. Since clock is
+// typically the sender's own *vectorclock.VectorClock (e.g. ctx.C), this
+// join is visible to the sender's subsequent operations too.
+//
+// When capacity is 0 - either a genuinely unbuffered channel, or a buffered
+// one whose capacity was never recorded via SetChannelCapacity - two things
+// happen instead of the ring-based bookkeeping above:
+//
+//   - Rendezvous reverse edge: an unbuffered send hands its value directly
+//     to a receiver that's already waiting, so the receiver's prior work
+//     also happens-before this send returns. clock is joined with the
+//     channel's most recent recvClock, if any, removing false positives in
+//     rendezvous-style handoff patterns (e.g. ping-pong send/recv loops).
+//   - FIFO queue: clock is also appended to sendQueue, so - just like
+//     sendRing - a receiver joins the clock of the send that actually
+//     produced its value (see GetChannelRecvJoinClock) rather than
+//     whichever sender happened to run last, even though the capacity that
+//     would size a ring isn't known.
+//
 // Parameters:
 //   - clock: The sender's vector clock (must not be nil)
 //
@@ -348,6 +644,36 @@ func (sv *SyncVar) GetChannel() *ChannelState {
 //	sv.SetChannelSendClock(senderCtx.C)  // Capture sender's clock
 func (sv *SyncVar) SetChannelSendClock(clock *vectorclock.VectorClock) {
 	chState := sv.GetOrCreateChannel()
+
+	if chState.capacity > 0 {
+		if backIdx := chState.sendSeq - int64(chState.capacity); backIdx >= 0 {
+			if freed := chState.recvRing[backIdx%int64(chState.capacity)]; freed != nil {
+				clock.Join(freed)
+			}
+		}
+
+		slot := chState.sendSeq % int64(chState.capacity)
+		if chState.sendRing[slot] == nil {
+			chState.sendRing[slot] = clock.Clone()
+		} else {
+			chState.sendRing[slot].CopyFrom(clock)
+		}
+	} else {
+		if chState.recvClock != nil {
+			// Unbuffered rendezvous: the receiver on the other end of this
+			// handoff has already arrived, so its clock happens-before this
+			// send returns too.
+			clock.Join(chState.recvClock)
+		}
+
+		// Capacity unknown: fall back to an unbounded FIFO queue instead of
+		// collapsing every send into a single "most recent" clock, so
+		// multiple producers racing ahead of a slow receiver are still
+		// matched exactly - see GetChannelRecvJoinClock.
+		chState.sendQueue = append(chState.sendQueue, clock.Clone())
+	}
+	chState.sendSeq++
+
 	if chState.sendClock == nil {
 		// First send: Allocate and copy.
 		chState.sendClock = clock.Clone()
@@ -371,11 +697,45 @@ func (sv *SyncVar) GetChannelSendClock() *vectorclock.VectorClock {
 	return sv.channel.sendClock
 }
 
+// GetChannelRecvJoinClock returns the clock a receive should join with: the
+// clock of the send that FIFO-matches this receive, so a receiver observing
+// the k-th value joins the k-th sender's clock rather than whichever sender
+// happened to run last. For a channel with a known capacity, the match comes
+// from sendRing (sendRing at recvSeq % capacity); otherwise it comes from
+// the unbounded sendQueue (sendQueue[recvSeq]).
+//
+// Returns nil if there is no send for this receive to join (channel not yet
+// used for sending, or no send has reached this receive's sequence number
+// yet).
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization.
+func (sv *SyncVar) GetChannelRecvJoinClock() *vectorclock.VectorClock {
+	if sv.channel == nil {
+		return nil
+	}
+	chState := sv.channel
+	if chState.capacity > 0 {
+		if chState.recvSeq < chState.sendSeq {
+			return chState.sendRing[chState.recvSeq%int64(chState.capacity)]
+		}
+		return nil
+	}
+	if chState.recvSeq < int64(len(chState.sendQueue)) {
+		return chState.sendQueue[chState.recvSeq]
+	}
+	return chState.sendClock
+}
+
 // SetChannelRecvClock captures the receiver's clock on channel receive.
 //
 // This is called after a channel receive completes. The receiver's clock is
 // copied into the channel's recvClock for potential bidirectional sync.
 //
+// For buffered channels, the clock is also recorded into recvRing at
+// recvSeq % capacity, so a later send that reuses this receive's buffer slot
+// can join it (see SetChannelSendClock's backpressure handling).
+//
 // Parameters:
 //   - clock: The receiver's vector clock (must not be nil)
 //
@@ -387,6 +747,17 @@ func (sv *SyncVar) GetChannelSendClock() *vectorclock.VectorClock {
 // synchronization.
 func (sv *SyncVar) SetChannelRecvClock(clock *vectorclock.VectorClock) {
 	chState := sv.GetOrCreateChannel()
+
+	if chState.capacity > 0 {
+		slot := chState.recvSeq % int64(chState.capacity)
+		if chState.recvRing[slot] == nil {
+			chState.recvRing[slot] = clock.Clone()
+		} else {
+			chState.recvRing[slot].CopyFrom(clock)
+		}
+	}
+	chState.recvSeq++
+
 	if chState.recvClock == nil {
 		// First recv: Allocate and copy.
 		chState.recvClock = clock.Clone()
@@ -544,7 +915,7 @@ func (sv *SyncVar) WaitGroupAdd(delta int) {
 //	// Child goroutine 2
 //	sv.MergeWaitGroupDoneClock(child2Ctx.C)  // Second Done: merge
 //	// Parent waits
-//	parentCtx.C.Join(sv.GetWaitGroupDoneClock())  // Gets union of both children
+//	parentCtx.JoinClock(sv.GetWaitGroupDoneClock())  // Gets union of both children
 func (sv *SyncVar) MergeWaitGroupDoneClock(clock *vectorclock.VectorClock) {
 	wgState := sv.GetOrCreateWaitGroup()
 	if wgState.doneClock == nil {
@@ -590,3 +961,278 @@ func (sv *SyncVar) GetWaitGroupCounter() int32 {
 	}
 	return sv.waitGroup.counter
 }
+
+// === Finalizer State Management (synth-3572) ===
+
+// FinalizerState tracks happens-before relationships for runtime.SetFinalizer
+// registrations on a single object.
+//
+// The Go runtime guarantees that a finalizer set via SetFinalizer(obj, f) is
+// only invoked after obj becomes unreachable to the mutator, so every write
+// the mutator made to obj (or to anything reachable only through it) before
+// that point is safe for the finalizer to observe. Without a hook capturing
+// this, the detector has no way to know about that runtime-enforced ordering
+// and reports a race between the finalizer's first touch of the object and
+// the mutator's last write to it, even though the object being unreachable
+// is exactly what made that access safe.
+//
+// This models the registering goroutine's clock at SetFinalizer time as an
+// approximation of "the mutator's last relevant write": in practice, code
+// that relies on this ordering keeps the object reachable (often via
+// runtime.KeepAlive) until it's done mutating it and only then lets it
+// become finalizable, so the SetFinalizer call site's clock is a reasonable
+// upper bound on the mutator's last touch. runtime.KeepAlive itself needs no
+// hook - it's a compiler/runtime intrinsic that only affects *when* GC may
+// consider the object unreachable, not a new happens-before edge.
+//
+// Layout:
+//   - registerClock: VectorClock captured at the most recent SetFinalizer call
+//
+// Lifecycle:
+//   - Created on first SetFinalizer call for this object's address
+//   - Re-registering (a second SetFinalizer call, which the runtime treats
+//     as replacing the object's finalizer) overwrites registerClock
+type FinalizerState struct {
+	// registerClock is the vector clock captured at the most recent
+	// SetFinalizer call for this object. nil means no finalizer is
+	// currently registered.
+	registerClock *vectorclock.VectorClock
+}
+
+// GetOrCreateFinalizer returns the FinalizerState for this SyncVar, creating
+// it if needed.
+//
+// Thread Safety: NOT thread-safe on its own. The caller (SyncShadow) must
+// ensure synchronization via sync.Map.
+func (sv *SyncVar) GetOrCreateFinalizer() *FinalizerState {
+	if sv.finalizer == nil {
+		sv.finalizer = &FinalizerState{}
+	}
+	return sv.finalizer
+}
+
+// SetFinalizerRegisterClock captures the registering thread's clock into the
+// object's FinalizerState, called on SetFinalizer(obj, f).
+//
+// If registerClock is nil (first registration), a new VectorClock is
+// allocated. Otherwise, the existing clock is updated in place - a second
+// SetFinalizer call replaces the first, matching the runtime's own
+// last-registration-wins semantics.
+//
+// Parameters:
+//   - clock: The vector clock to copy (must not be nil)
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization.
+func (sv *SyncVar) SetFinalizerRegisterClock(clock *vectorclock.VectorClock) {
+	finalizerState := sv.GetOrCreateFinalizer()
+	if finalizerState.registerClock == nil {
+		finalizerState.registerClock = clock.Clone()
+	} else {
+		finalizerState.registerClock.CopyFrom(clock)
+	}
+}
+
+// GetFinalizerRegisterClock returns the clock captured at the most recent
+// SetFinalizer call for this object.
+//
+// Returns nil if no finalizer has ever been registered.
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization.
+func (sv *SyncVar) GetFinalizerRegisterClock() *vectorclock.VectorClock {
+	if sv.finalizer == nil {
+		return nil
+	}
+	return sv.finalizer.registerClock
+}
+
+// === Singleflight State Management (synth-3574) ===
+
+// SingleflightState tracks happens-before relationships for one
+// (singleflight.Group, key) pair.
+//
+// singleflight.Group.Do(key, fn) collapses concurrent calls that share a key
+// into a single execution of fn: one caller becomes the "leader" and runs
+// fn, while every other concurrent caller ("duplicates") blocks and is
+// handed the leader's result once fn returns. Without a hook, the detector
+// sees only the leader's writes inside fn and every caller's read of the
+// shared result after Do returns, with no edge between them - exactly the
+// cache-fill false positive this ticket describes.
+//
+// This models the leader's post-fn clock as the single source every caller
+// (leader included) joins on return, the same "one writer, many readers of
+// one snapshot" shape as releaseClock, not the accumulating shape of
+// waitGroup or channel. A key is only ever executed by one leader at a time
+// (singleflight's own mutual exclusion guarantees that), and each new call
+// cycle for the same key produces a fresh leader, so overwriting doneClock
+// on each completion (rather than merging into it) matches the runtime's
+// own semantics: only the most recent call's result is ever handed out.
+//
+// Layout:
+//   - doneClock: VectorClock captured when the leader's fn returns
+//
+// Lifecycle:
+//   - Created on the first completed Do/DoChan call for this (Group, key)
+//   - Each subsequent call cycle overwrites doneClock with its own leader's
+type SingleflightState struct {
+	// doneClock is the vector clock captured when the most recent leader
+	// call for this key finished executing fn. nil means no call has
+	// completed yet for this (Group, key) pair.
+	doneClock *vectorclock.VectorClock
+}
+
+// GetOrCreateSingleflight returns the SingleflightState for this SyncVar,
+// creating it if needed.
+//
+// Thread Safety: NOT thread-safe on its own. The caller (SyncShadow) must
+// ensure synchronization via sync.Map.
+func (sv *SyncVar) GetOrCreateSingleflight() *SingleflightState {
+	if sv.singleflight == nil {
+		sv.singleflight = &SingleflightState{}
+	}
+	return sv.singleflight
+}
+
+// SetSingleflightDoneClock captures the leader's clock into this key's
+// SingleflightState, called once the leader's fn returns.
+//
+// If doneClock is nil (first completed call), a new VectorClock is
+// allocated. Otherwise, the existing clock is updated in place - each call
+// cycle's completion replaces the previous one's, since only the latest
+// result is ever handed to callers.
+//
+// Parameters:
+//   - clock: The vector clock to copy (must not be nil)
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization.
+func (sv *SyncVar) SetSingleflightDoneClock(clock *vectorclock.VectorClock) {
+	singleflightState := sv.GetOrCreateSingleflight()
+	if singleflightState.doneClock == nil {
+		singleflightState.doneClock = clock.Clone()
+	} else {
+		singleflightState.doneClock.CopyFrom(clock)
+	}
+}
+
+// GetSingleflightDoneClock returns the clock captured when the most recent
+// leader call for this key finished executing fn.
+//
+// Returns nil if no call has completed yet for this (Group, key) pair.
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization.
+func (sv *SyncVar) GetSingleflightDoneClock() *vectorclock.VectorClock {
+	if sv.singleflight == nil {
+		return nil
+	}
+	return sv.singleflight.doneClock
+}
+
+// === Reclamation Support (synth-3620) ===
+
+// Touch records stamp as this SyncVar's recency stamp, evicting whatever
+// value Touch last recorded (synth-3620), mirroring VarState.Touch. Called
+// from SyncShadow.GetOrCreate on every access, so the most recently accessed
+// entries always carry the highest stamp.
+//
+// Thread Safety: NOT thread-safe on its own. The caller (SyncShadow) must
+// ensure synchronization.
+func (sv *SyncVar) Touch(stamp uint64) {
+	sv.lastTouch = stamp
+}
+
+// LastTouch returns the recency stamp last recorded by Touch, or 0 if the
+// SyncVar has never been touched (synth-3620).
+//
+// Thread Safety: NOT thread-safe on its own. The caller must ensure
+// synchronization.
+func (sv *SyncVar) LastTouch() uint64 {
+	return sv.lastTouch
+}
+
+// === Checkpoint/Restore Support (synth-3576) ===
+
+// cloneClock returns a deep copy of clock, or nil if clock is nil.
+//
+// Shared by Clone's fields below so every nil-checked *vectorclock.VectorClock
+// field in SyncVar and its sub-states is copied the same way.
+func cloneClock(clock *vectorclock.VectorClock) *vectorclock.VectorClock {
+	if clock == nil {
+		return nil
+	}
+	return clock.Clone()
+}
+
+// Clone returns a deep copy of sv, independent of the original: mutating
+// the clone's clocks (or the original's) never affects the other.
+//
+// This backs Detector.Snapshot/Restore, which need an isolated copy of
+// every SyncVar so a later Restore doesn't hand back state an in-progress
+// operation on the live SyncVar is still mutating, and so restoring twice
+// from the same snapshot doesn't share clocks between the two restores.
+//
+// Thread Safety: NOT thread-safe on its own, same convention as every other
+// SyncVar method - the caller (SyncShadow/Detector) must ensure no
+// concurrent mutation of sv while cloning.
+func (sv *SyncVar) Clone() *SyncVar {
+	clone := &SyncVar{
+		releaseClock:     cloneClock(sv.releaseClock),
+		readReleaseClock: cloneClock(sv.readReleaseClock),
+		owner:            sv.owner,
+		ownerEpoch:       sv.ownerEpoch,
+		lastTouch:        sv.lastTouch,
+	}
+
+	if sv.channel != nil {
+		clone.channel = &ChannelState{
+			sendClock:  cloneClock(sv.channel.sendClock),
+			recvClock:  cloneClock(sv.channel.recvClock),
+			closeClock: cloneClock(sv.channel.closeClock),
+			isClosed:   sv.channel.isClosed,
+			capacity:   sv.channel.capacity,
+			sendSeq:    sv.channel.sendSeq,
+			recvSeq:    sv.channel.recvSeq,
+		}
+		if sv.channel.sendRing != nil {
+			clone.channel.sendRing = make([]*vectorclock.VectorClock, len(sv.channel.sendRing))
+			for i, c := range sv.channel.sendRing {
+				clone.channel.sendRing[i] = cloneClock(c)
+			}
+		}
+		if sv.channel.recvRing != nil {
+			clone.channel.recvRing = make([]*vectorclock.VectorClock, len(sv.channel.recvRing))
+			for i, c := range sv.channel.recvRing {
+				clone.channel.recvRing[i] = cloneClock(c)
+			}
+		}
+		if sv.channel.sendQueue != nil {
+			clone.channel.sendQueue = make([]*vectorclock.VectorClock, len(sv.channel.sendQueue))
+			for i, c := range sv.channel.sendQueue {
+				clone.channel.sendQueue[i] = cloneClock(c)
+			}
+		}
+	}
+
+	if sv.waitGroup != nil {
+		clone.waitGroup = &WaitGroupState{
+			doneClock: cloneClock(sv.waitGroup.doneClock),
+			counter:   sv.waitGroup.counter,
+		}
+	}
+
+	if sv.finalizer != nil {
+		clone.finalizer = &FinalizerState{
+			registerClock: cloneClock(sv.finalizer.registerClock),
+		}
+	}
+
+	if sv.singleflight != nil {
+		clone.singleflight = &SingleflightState{
+			doneClock: cloneClock(sv.singleflight.doneClock),
+		}
+	}
+
+	return clone
+}
@@ -0,0 +1,53 @@
+// +build amd64
+
+// Vectorized Join/LessOrEqual helpers for amd64 (synth-3617).
+//
+// Join and LessOrEqual are the two operations FastTrack's sync-heavy
+// workloads spend the most time in (see vectorclock.go), and their
+// generation-aware scalar loops process one uint32 at a time. When neither
+// operand has ever recorded a non-zero generation (see hasGeneration in
+// vectorclock.go), the per-element generation check is dead weight: the
+// comparison collapses to a plain point-wise max/<=, which SSE2 - present
+// on every amd64 target Go supports, so no runtime feature detection is
+// needed - can do eight lanes (two XMM registers) per loop iteration.
+package vectorclock
+
+// vecMaxU32Asm and vecAllLessOrEqualU32Asm are implemented in
+// simd_amd64.s. n must be a multiple of 8.
+
+//go:noescape
+func vecMaxU32Asm(dst, src *uint32, n int)
+
+//go:noescape
+func vecAllLessOrEqualU32Asm(a, b *uint32, n int) bool
+
+// vecMaxU32 sets dst[i] = max(dst[i], src[i]) for every i, processing eight
+// lanes at a time via SSE2 with a scalar Go loop for the len(dst)%8 tail.
+func vecMaxU32(dst, src []uint32) {
+	n := len(dst)
+	main := n &^ 7
+	if main > 0 {
+		vecMaxU32Asm(&dst[0], &src[0], main)
+	}
+	for i := main; i < n; i++ {
+		if src[i] > dst[i] {
+			dst[i] = src[i]
+		}
+	}
+}
+
+// vecAllLessOrEqualU32 reports whether a[i] <= b[i] for every i, processing
+// eight lanes at a time via SSE2 with a scalar Go loop for the len(a)%8 tail.
+func vecAllLessOrEqualU32(a, b []uint32) bool {
+	n := len(a)
+	main := n &^ 7
+	if main > 0 && !vecAllLessOrEqualU32Asm(&a[0], &b[0], main) {
+		return false
+	}
+	for i := main; i < n; i++ {
+		if a[i] > b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+// +build arm64
+
+// Vectorized Join/LessOrEqual helpers for arm64 (synth-3617). See
+// simd_amd64.go's doc comment for the fast-path rationale; this file is
+// the NEON counterpart, using instructions guaranteed present on every
+// arm64 target Go supports (no runtime feature detection needed).
+package vectorclock
+
+// vecMaxU32Asm and vecAllLessOrEqualU32Asm are implemented in
+// simd_arm64.s. n must be a multiple of 4.
+
+//go:noescape
+func vecMaxU32Asm(dst, src *uint32, n int)
+
+//go:noescape
+func vecAllLessOrEqualU32Asm(a, b *uint32, n int) bool
+
+// vecMaxU32 sets dst[i] = max(dst[i], src[i]) for every i, processing four
+// lanes at a time via NEON with a scalar Go loop for the len(dst)%4 tail.
+func vecMaxU32(dst, src []uint32) {
+	n := len(dst)
+	main := n &^ 3
+	if main > 0 {
+		vecMaxU32Asm(&dst[0], &src[0], main)
+	}
+	for i := main; i < n; i++ {
+		if src[i] > dst[i] {
+			dst[i] = src[i]
+		}
+	}
+}
+
+// vecAllLessOrEqualU32 reports whether a[i] <= b[i] for every i, processing
+// four lanes at a time via NEON with a scalar Go loop for the len(a)%4 tail.
+func vecAllLessOrEqualU32(a, b []uint32) bool {
+	n := len(a)
+	main := n &^ 3
+	if main > 0 && !vecAllLessOrEqualU32Asm(&a[0], &b[0], main) {
+		return false
+	}
+	for i := main; i < n; i++ {
+		if a[i] > b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+// +build !amd64,!arm64
+
+// Pure-Go fallback for Join/LessOrEqual's vectorized fast path (synth-3617),
+// for architectures without a hand-written SIMD implementation (see
+// simd_amd64.go/simd_arm64.go). Behaviorally identical to the vectorized
+// versions, just without the 4-lanes-per-instruction speedup.
+package vectorclock
+
+// vecMaxU32 sets dst[i] = max(dst[i], src[i]) for every i.
+func vecMaxU32(dst, src []uint32) {
+	for i := range dst {
+		if src[i] > dst[i] {
+			dst[i] = src[i]
+		}
+	}
+}
+
+// vecAllLessOrEqualU32 reports whether a[i] <= b[i] for every i.
+func vecAllLessOrEqualU32(a, b []uint32) bool {
+	for i := range a {
+		if a[i] > b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -61,7 +61,9 @@ var vcPool = sync.Pool{
 // Layout: [Thread0, Thread1, ..., Thread65535]
 // Example: {0: 50, 1: 30, 2: 60, ...} means Thread0@50, Thread1@30, Thread2@60.
 //
-// Size: 65,536 × 4 bytes = 256KB (large, but only allocated for read-shared variables).
+// Size: 65,536 × 4 bytes = 256KB, plus 65,536 × 1 byte = 64KB for the
+// generations tag (synth-3612, see the generations field) - large, but only
+// allocated for read-shared variables.
 //
 // v0.3.0 SPARSE-AWARE OPTIMIZATION (P1):
 // Track maxTID to avoid iterating over 65536 elements when most are zero.
@@ -70,6 +72,34 @@ var vcPool = sync.Pool{
 type VectorClock struct {
 	clocks [MaxThreads]uint32 // Clock values per thread.
 	maxTID uint16             // Highest TID with non-zero clock (sparse optimization).
+
+	// generations tags each clocks[i] with the TID-pool generation it was
+	// recorded under (synth-3612), defaulting to 0. Only meaningful once a
+	// TID has been recycled (see epoch.BumpGeneration) - untouched slots and
+	// programs that never recycle TIDs keep the generation-0 default
+	// everywhere, so Join/LessOrEqual behave exactly as before for them.
+	// See SetGeneration and epoch.Epoch.HappensBefore.
+	generations [MaxThreads]uint8
+
+	// hasGeneration is true once SetGeneration has recorded a non-zero
+	// generation somewhere in this VectorClock (synth-3612). Join and
+	// LessOrEqual use it as a cheap pre-check (synth-3617): the
+	// generation-aware per-element comparison is only needed when a TID
+	// has actually been recycled, so when it's false on both operands
+	// Join/LessOrEqual take a vectorized plain-max/plain-<= fast path
+	// instead of the generation-aware scalar loop - see vecMaxU32 and
+	// vecAllLessOrEqualU32.
+	hasGeneration bool
+
+	// shared marks this instance as a copy-on-write snapshot someone else
+	// is holding a reference to - currently only SyncVar's release clock
+	// (synth-3618, see MarkShared). Once true it is never cleared: the only
+	// way for an owner to go back to mutating freely is CloneIfShared,
+	// which hands back a fresh, unshared instance instead. Whoever mutates
+	// a VectorClock in place (RaceContext.IncrementClock, RaceContext.JoinClock)
+	// must check this first, or a Release() capturing "what happened before
+	// the Unlock" would silently keep changing after the fact.
+	shared bool
 }
 
 // New creates a zero-initialized vector clock.
@@ -131,9 +161,21 @@ func NewFromPool() *VectorClock {
 //	vc.Release()  // Return to pool
 //	// vc must NOT be used after this point!
 func (vc *VectorClock) Release() {
-	if vc != nil {
-		vcPool.Put(vc)
+	if vc == nil {
+		return
+	}
+	if vc.shared {
+		// A shared clock (synth-3618, see MarkShared) may still be
+		// referenced by a SyncVar's release clock, which - like the
+		// VarState reference the warning above already covers - can
+		// outlive the RaceContext that owned this instance. Let it be
+		// garbage collected once that last reference goes away instead of
+		// returning it to the pool, where Reset() would eventually hand it
+		// to an unrelated goroutine while a mutex somewhere is still
+		// aliasing it as a frozen snapshot.
+		return
 	}
+	vcPool.Put(vc)
 }
 
 // Reset clears the VectorClock to zero state.
@@ -152,8 +194,11 @@ func (vc *VectorClock) Reset() {
 	// Use uint32 loop counter to avoid uint16 overflow at maxTID=65535.
 	for i := uint32(0); i <= uint32(vc.maxTID); i++ {
 		vc.clocks[i] = 0
+		vc.generations[i] = 0
 	}
 	vc.maxTID = 0
+	vc.hasGeneration = false
+	vc.shared = false
 }
 
 // Clone creates a deep copy of the vector clock.
@@ -165,15 +210,41 @@ func (vc *VectorClock) Reset() {
 //
 // Returns a pointer to the new copy to avoid copying on return.
 func (vc *VectorClock) Clone() *VectorClock {
-	clone := &VectorClock{maxTID: vc.maxTID}
+	clone := &VectorClock{maxTID: vc.maxTID, hasGeneration: vc.hasGeneration}
 	// Only copy up to maxTID+1 elements for efficiency.
 	// Use uint32 loop counter to avoid uint16 overflow at maxTID=65535.
 	for i := uint32(0); i <= uint32(vc.maxTID); i++ {
 		clone.clocks[i] = vc.clocks[i]
+		clone.generations[i] = vc.generations[i]
 	}
 	return clone
 }
 
+// MarkShared marks vc as a copy-on-write snapshot (synth-3618): some other
+// owner now holds a reference to vc and expects its contents to stay frozen,
+// so vc must never be mutated in place again. Called by SyncVar.SetReleaseClock
+// when it hands out a RaceContext's clock by reference instead of copying it.
+//
+// Thread Safety: Not thread-safe, caller must synchronize - matches the rest
+// of VectorClock. SetReleaseClock's caller (OnRelease) already owns the only
+// live reference to vc at the point it calls this, before sharing it.
+func (vc *VectorClock) MarkShared() {
+	vc.shared = true
+}
+
+// CloneIfShared returns vc unchanged if it isn't shared, or a private Clone()
+// of it (unshared) if it is (synth-3618). Any code that owns a VectorClock and
+// is about to mutate it in place - RaceContext.IncrementClock and
+// RaceContext.JoinClock are the only two call sites today - must route
+// through this first and adopt the returned pointer, since a shared
+// VectorClock's contents belong to whoever else holds a reference to it too.
+func (vc *VectorClock) CloneIfShared() *VectorClock {
+	if !vc.shared {
+		return vc
+	}
+	return vc.Clone()
+}
+
 // Join performs point-wise maximum: vc = vc ⊔ other.
 //
 // This is the synchronization operation for happens-before in FastTrack.
@@ -184,6 +255,25 @@ func (vc *VectorClock) Clone() *VectorClock {
 // v0.3.0 SPARSE-AWARE: Only iterates up to max(vc.maxTID, other.maxTID).
 // For typical programs (~100 goroutines), this is 655x faster than iterating 65536 elements.
 //
+// Generation-aware merge (synth-3612): a pointwise max only makes sense
+// between two clock values recorded for the same TID *generation* - see the
+// package-level generations field. For each TID i:
+//   - other's generation is newer: other's (clock, generation) replaces
+//     vc's outright, since vc's value predates that TID being recycled and
+//     must not be blended with the new occupant's.
+//   - generations match: ordinary pointwise max, exactly as before.
+//   - vc's generation is newer: other's value is the stale one, so it is
+//     ignored and vc keeps what it already has.
+//
+// Vectorized fast path (synth-3617): when neither side has ever recorded a
+// non-zero generation (see hasGeneration), the switch below always takes
+// its "generations match" branch, so the whole loop degenerates to a plain
+// point-wise max - exactly what vecMaxU32 computes, several lanes at a time
+// on amd64/arm64 (see simd_amd64.go/simd_arm64.go). This is a pure performance
+// optimization: it produces bit-identical results to the scalar loop for
+// every input where the fast path applies, and falls back to the exact
+// generation-aware scalar loop the instant either side has recycled a TID.
+//
 // Performance: Critical operation, must be fast. Target: < 10ns for sparse clocks.
 //
 //go:nosplit
@@ -194,12 +284,27 @@ func (vc *VectorClock) Join(other *VectorClock) {
 		limit = uint32(other.maxTID)
 	}
 
-	// Point-wise maximum only up to limit.
-	// Use uint32 loop counter to avoid uint16 overflow at maxTID=65535.
-	for i := uint32(0); i <= limit; i++ {
-		if other.clocks[i] > vc.clocks[i] {
-			vc.clocks[i] = other.clocks[i]
+	if !vc.hasGeneration && !other.hasGeneration {
+		vecMaxU32(vc.clocks[:limit+1], other.clocks[:limit+1])
+	} else {
+		// Point-wise maximum only up to limit, generation-aware (synth-3612).
+		// Use uint32 loop counter to avoid uint16 overflow at maxTID=65535.
+		for i := uint32(0); i <= limit; i++ {
+			switch {
+			case other.generations[i] > vc.generations[i]:
+				vc.clocks[i] = other.clocks[i]
+				vc.generations[i] = other.generations[i]
+			case other.generations[i] == vc.generations[i] && other.clocks[i] > vc.clocks[i]:
+				vc.clocks[i] = other.clocks[i]
+			}
 		}
+		// The loop above can copy one of other's non-zero generations into
+		// vc (the first switch case), so vc may come out of this Join with
+		// a non-zero generation even though it never called SetGeneration
+		// itself - keep hasGeneration in sync or the vectorized fast path
+		// above would wrongly skip the generation-aware comparison on a
+		// later Join/LessOrEqual involving this vc.
+		vc.hasGeneration = vc.hasGeneration || other.hasGeneration
 	}
 
 	// Update maxTID if other had higher TIDs.
@@ -219,13 +324,35 @@ func (vc *VectorClock) Join(other *VectorClock) {
 // v0.3.0 SPARSE-AWARE: Only checks up to vc.maxTID (elements beyond are zero).
 // For typical programs (~100 goroutines), this is 655x faster.
 //
+// Generation-aware comparison (synth-3612): if vc and other disagree on
+// which generation they recorded for TID i, their clocks[i] values describe
+// two different goroutines that happened to share a recycled TID - not
+// comparable, so this returns false rather than risk a stale generation's
+// clock silently satisfying (or violating) the relation.
+//
+// Vectorized fast path (synth-3617): when neither side has ever recorded a
+// non-zero generation (see hasGeneration), the generations comparison below
+// always holds, so the check degenerates to a plain point-wise <= - exactly
+// what vecAllLessOrEqualU32 computes, several lanes at a time on amd64/arm64
+// (see simd_amd64.go/simd_arm64.go). Falls back to the exact generation-aware
+// scalar loop the instant either side has recycled a TID.
+//
 // Performance: Critical operation on race check path. Target: < 5ns for sparse clocks.
 //
 //go:nosplit
 func (vc *VectorClock) LessOrEqual(other *VectorClock) bool {
 	// Only need to check up to vc.maxTID (elements beyond are 0, which is always <= other[i]).
+	limit := uint32(vc.maxTID)
+
+	if !vc.hasGeneration && !other.hasGeneration {
+		return vecAllLessOrEqualU32(vc.clocks[:limit+1], other.clocks[:limit+1])
+	}
+
 	// Use uint32 loop counter to avoid uint16 overflow at maxTID=65535.
-	for i := uint32(0); i <= uint32(vc.maxTID); i++ {
+	for i := uint32(0); i <= limit; i++ {
+		if vc.generations[i] != other.generations[i] {
+			return false
+		}
 		if vc.clocks[i] > other.clocks[i] {
 			return false
 		}
@@ -280,6 +407,27 @@ func (vc *VectorClock) Set(tid uint16, clock uint32) {
 	}
 }
 
+// GetGeneration returns the TID-pool generation recorded for thread tid's
+// clock value (synth-3612): 0 for a TID that was never explicitly tagged via
+// SetGeneration, which is the correct default for any program that never
+// recycles TIDs (see epoch.CurrentGeneration).
+func (vc *VectorClock) GetGeneration(tid uint16) uint8 {
+	return vc.generations[tid]
+}
+
+// SetGeneration records which TID-pool generation tid's clock value in this
+// VectorClock belongs to (synth-3612). Called once, alongside Set, when a
+// goroutine's own RaceContext is initialized for a (possibly recycled) TID -
+// see goroutine.Alloc and goroutine.AllocWithParentClock. Kept separate from
+// Set so every other caller (tests, the standalone examples, callers that
+// never see TID recycling) is unaffected and keeps the generation-0 default.
+func (vc *VectorClock) SetGeneration(tid uint16, gen uint8) {
+	vc.generations[tid] = gen
+	if gen != 0 {
+		vc.hasGeneration = true
+	}
+}
+
 // GetMaxTID returns the highest TID with non-zero clock (v0.3.0 sparse optimization).
 //
 // This is useful for debugging and understanding the sparsity of the vector clock.
@@ -295,14 +443,17 @@ func (vc *VectorClock) CopyFrom(other *VectorClock) {
 	// Use uint32 loop counter to avoid uint16 overflow at maxTID=65535.
 	for i := uint32(0); i <= uint32(other.maxTID); i++ {
 		vc.clocks[i] = other.clocks[i]
+		vc.generations[i] = other.generations[i]
 	}
 	// Clear elements beyond other.maxTID if vc had higher maxTID.
 	if vc.maxTID > other.maxTID {
 		for i := uint32(other.maxTID) + 1; i <= uint32(vc.maxTID); i++ {
 			vc.clocks[i] = 0
+			vc.generations[i] = 0
 		}
 	}
 	vc.maxTID = other.maxTID
+	vc.hasGeneration = other.hasGeneration
 }
 
 // String returns a debug representation of the vector clock.
@@ -581,6 +581,162 @@ func TestVectorClockReset(t *testing.T) {
 	})
 }
 
+// TestVectorClockGeneration tests the TID generation tag (synth-3612):
+// GetGeneration/SetGeneration, and how Join and LessOrEqual treat a TID
+// whose two sides disagree on generation.
+func TestVectorClockGeneration(t *testing.T) {
+	t.Run("defaults to 0", func(t *testing.T) {
+		vc := New()
+		if got := vc.GetGeneration(5); got != 0 {
+			t.Errorf("GetGeneration(5) on fresh VectorClock = %d, want 0", got)
+		}
+	})
+
+	t.Run("SetGeneration is independent of Set", func(t *testing.T) {
+		vc := New()
+		vc.Set(5, 100)
+		vc.SetGeneration(5, 3)
+
+		if got := vc.Get(5); got != 100 {
+			t.Errorf("Get(5) after SetGeneration = %d, want 100 (unaffected)", got)
+		}
+		if got := vc.GetGeneration(5); got != 3 {
+			t.Errorf("GetGeneration(5) = %d, want 3", got)
+		}
+	})
+
+	t.Run("Join adopts a newer generation's value outright", func(t *testing.T) {
+		vc := New()
+		vc.Set(7, 500)
+		vc.SetGeneration(7, 0) // stale generation, high clock
+
+		other := New()
+		other.Set(7, 2)
+		other.SetGeneration(7, 1) // newer generation, low clock
+
+		vc.Join(other)
+
+		if got := vc.Get(7); got != 2 {
+			t.Errorf("Get(7) after Join = %d, want 2 (newer generation replaces, not maxes)", got)
+		}
+		if got := vc.GetGeneration(7); got != 1 {
+			t.Errorf("GetGeneration(7) after Join = %d, want 1", got)
+		}
+	})
+
+	t.Run("Join ignores a stale generation's value", func(t *testing.T) {
+		vc := New()
+		vc.Set(7, 2)
+		vc.SetGeneration(7, 1)
+
+		other := New()
+		other.Set(7, 500)
+		other.SetGeneration(7, 0) // stale relative to vc
+
+		vc.Join(other)
+
+		if got := vc.Get(7); got != 2 {
+			t.Errorf("Get(7) after Join = %d, want 2 (stale other value must not win)", got)
+		}
+		if got := vc.GetGeneration(7); got != 1 {
+			t.Errorf("GetGeneration(7) after Join = %d, want 1", got)
+		}
+	})
+
+	t.Run("Join takes pointwise max within the same generation", func(t *testing.T) {
+		vc := New()
+		vc.Set(7, 2)
+		vc.SetGeneration(7, 1)
+
+		other := New()
+		other.Set(7, 9)
+		other.SetGeneration(7, 1)
+
+		vc.Join(other)
+
+		if got := vc.Get(7); got != 9 {
+			t.Errorf("Get(7) after Join = %d, want 9 (ordinary max within same generation)", got)
+		}
+	})
+
+	t.Run("LessOrEqual rejects a generation mismatch", func(t *testing.T) {
+		vc := New()
+		vc.Set(7, 1)
+		vc.SetGeneration(7, 1)
+
+		other := New()
+		other.Set(7, 500)
+		other.SetGeneration(7, 0)
+
+		if vc.LessOrEqual(other) {
+			t.Error("LessOrEqual = true across a generation mismatch, want false " +
+				"even though the raw clock comparison (1 <= 500) alone would say true")
+		}
+	})
+}
+
+// TestVectorClockCopyOnWrite checks MarkShared/CloneIfShared, the primitive
+// SyncVar.SetReleaseClock builds on to share a release clock by reference
+// instead of copying it on every Release (synth-3618).
+func TestVectorClockCopyOnWrite(t *testing.T) {
+	t.Run("CloneIfShared is a no-op when not shared", func(t *testing.T) {
+		vc := New()
+		vc.Set(3, 7)
+
+		if got := vc.CloneIfShared(); got != vc {
+			t.Error("CloneIfShared returned a different instance for an unshared clock")
+		}
+	})
+
+	t.Run("CloneIfShared returns an independent copy once shared", func(t *testing.T) {
+		vc := New()
+		vc.Set(3, 7)
+		vc.MarkShared()
+
+		clone := vc.CloneIfShared()
+		if clone == vc {
+			t.Fatal("CloneIfShared returned the same instance for a shared clock")
+		}
+		if got := clone.Get(3); got != 7 {
+			t.Errorf("Get(3) on the clone = %d, want 7 (must match the shared snapshot)", got)
+		}
+
+		// Mutating the clone must not affect the original shared snapshot -
+		// that's the whole point of copy-on-write.
+		clone.Set(3, 100)
+		if got := vc.Get(3); got != 7 {
+			t.Errorf("Get(3) on the original after mutating the clone = %d, want 7 (unaffected)", got)
+		}
+
+		// The clone itself starts out unshared, so it won't immediately
+		// re-clone on the very next mutation.
+		if got := clone.CloneIfShared(); got != clone {
+			t.Error("a fresh clone from CloneIfShared should not itself be marked shared")
+		}
+	})
+
+	t.Run("Release skips the pool for a shared clock", func(t *testing.T) {
+		// Other tests in this package cycle VectorClocks through vcPool too,
+		// so drain whatever they left behind first - otherwise a leftover
+		// entry could be mistaken for (or mask) the one this test cares about.
+		for i := 0; i < 256; i++ {
+			NewFromPool()
+		}
+
+		vc := NewFromPool()
+		vc.MarkShared()
+		vc.Release()
+
+		// Drain the pool: a correctly-skipped shared clock must never come
+		// back out, since some other owner may still be holding it.
+		for i := 0; i < 256; i++ {
+			if got := NewFromPool(); got == vc {
+				t.Fatal("Release() returned a shared VectorClock to the pool")
+			}
+		}
+	})
+}
+
 // ========== POOLING BENCHMARKS ==========
 
 // BenchmarkVectorClockPooling benchmarks pool vs direct allocation.
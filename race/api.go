@@ -3,7 +3,13 @@
 // See doc.go for detailed documentation and examples.
 package race
 
-import internal "github.com/kolkov/racedetector/internal/race/api"
+import (
+	"context"
+	"io"
+	"runtime/pprof"
+
+	internal "github.com/kolkov/racedetector/internal/race/api"
+)
 
 // Init initializes the race detector runtime.
 //
@@ -96,6 +102,189 @@ func RaceWrite(addr uintptr) {
 	internal.RaceWrite(addr)
 }
 
+// RegisterSymbol records name (e.g. "Config.Timeout" or "main.counter") and
+// kind ("field" or "global") for symID, so a later race report on a write
+// tagged with symID via RaceWriteSym can print that name instead of only
+// the raw address (synth-3630, kind added by synth-3631).
+//
+// This function is automatically inserted by the racedetector tool
+// immediately before the RaceWriteSym call it documents, once per distinct
+// write site. symID is computed at build time as a hash of the qualified
+// name - see cmd/racedetector/instrument/symbols.go - so every instrumented
+// site that touches the same field or global computes the same symID
+// independently, with no cross-file coordination needed.
+//
+// Parameters:
+//   - symID: Symbol id, computed by the instrumenter from name
+//   - name: Human-readable qualified name for symID, e.g. "Type.Field" or
+//     "pkg.VarName"
+//   - kind: What name names - "field" or "global"
+func RegisterSymbol(symID uint64, name, kind string) {
+	internal.RegisterSymbol(symID, name, kind)
+}
+
+// RaceWriteSym records a memory write to a struct field or package-level
+// variable at the given address, tagged with symID so a race report can
+// print its qualified name - e.g. "race on field Config.Timeout" or "race
+// on global main.counter" - instead of only a hex address (synth-3630,
+// generalized to package-level variables by synth-3631).
+//
+// This function is automatically inserted by the racedetector tool in
+// place of RaceWrite when the left-hand side of an assignment is a struct
+// field (e.g. cfg.Timeout = d) whose enclosing type and field name go/types
+// could resolve at build time, or a package-level variable declared in the
+// same file, immediately preceded by the matching RegisterSymbol call for
+// symID.
+//
+// Parameters:
+//   - addr: The memory address being written (use unsafe.Pointer conversion)
+//   - symID: Symbol id previously passed to RegisterSymbol
+//
+// Example (automatic instrumentation):
+//
+//	// Original code:
+//	cfg.Timeout = d
+//
+//	// Instrumented code:
+//	race.RegisterSymbol(0x9f2b1a7c3d4e5601, "Config.Timeout", "field")
+//	race.RaceWriteSym(uintptr(unsafe.Pointer(&cfg.Timeout)), 0x9f2b1a7c3d4e5601)
+//	cfg.Timeout = d
+func RaceWriteSym(addr uintptr, symID uint64) {
+	internal.RaceWriteSym(addr, symID)
+}
+
+// RaceRegionWrite records a batch of write accesses collected from a single
+// lock-protected critical section in one call (synth-3597).
+//
+// This function is automatically inserted by the racedetector tool in place
+// of one RaceWrite call per access, when static lock-region analysis proves
+// every address in addrs was written while holding the same mutex: since
+// only one goroutine can hold that mutex at a time, the accesses can't race
+// with each other, so batching the per-goroutine checks that still guard
+// against a concurrent, unsynchronized goroutine costs one call instead of
+// len(addrs).
+//
+// Parameters:
+//   - addrs: The memory addresses written inside the region, in program order
+//
+// Example (automatic instrumentation):
+//
+//	// Original code:
+//	mu.Lock()
+//	a = 1
+//	b = 2
+//	mu.Unlock()
+//
+//	// Instrumented code:
+//	mu.Lock()
+//	a = 1
+//	b = 2
+//	race.RaceRegionWrite(uintptr(unsafe.Pointer(&a)), uintptr(unsafe.Pointer(&b)))
+//	mu.Unlock()
+func RaceRegionWrite(addrs ...uintptr) {
+	internal.RaceRegionWrite(addrs...)
+}
+
+// RaceBatch records a batch of write accesses made by a single statement in
+// one call (synth-3598).
+//
+// This function is automatically inserted by the racedetector tool in place
+// of one RaceWrite call per address when a single statement touches
+// several addresses at once (e.g. a multi-value assignment). Unlike
+// RaceRegionWrite, which only applies inside a proven lock-protected
+// region, RaceBatch makes no synchronization assumption about the
+// addresses - it exists purely to amortize the per-call context lookup and
+// sampling decision across the batch; each address is still checked for
+// races against every other goroutine exactly as RaceWrite would.
+//
+// Parameters:
+//   - addrs: The memory addresses written by the statement, in program order
+//
+// Example (automatic instrumentation):
+//
+//	// Original code:
+//	a, b = 1, 2
+//
+//	// Instrumented code:
+//	race.RaceBatch(uintptr(unsafe.Pointer(&a)), uintptr(unsafe.Pointer(&b)))
+//	a, b = 1, 2
+func RaceBatch(addrs ...uintptr) {
+	internal.RaceBatch(addrs...)
+}
+
+// SliceHeaderWords is the number of machine words in a Go slice header:
+// data pointer, len, and cap (synth-3628).
+const SliceHeaderWords = internal.SliceHeaderWords
+
+// StringWords is the number of machine words in a Go string header: data
+// pointer and len (synth-3629).
+const StringWords = internal.StringWords
+
+// InterfaceWords is the number of machine words in a Go interface value:
+// the type word and the data word (synth-3629).
+const InterfaceWords = internal.InterfaceWords
+
+// RaceWriteRange records a write to a multi-word value - a slice header
+// (data pointer, len, cap), a string header (data pointer, len), or an
+// interface value (type word, data word) - as words independent shadow
+// memory accesses, one per machine word starting at addr.
+//
+// This function is automatically inserted by the racedetector tool in
+// place of RaceWrite when the left-hand side of an assignment is itself a
+// slice-, string-, or interface-typed variable (e.g. s = append(s, x),
+// name = other, v = anotherValue) rather than one of a slice's elements
+// (s[0] = x) or a struct's field. A multi-word value and whatever its
+// words point at are different memory locations: reassigning the variable
+// races with a concurrent access to that same value, not with an access
+// to data its old or new words happen to reference. This matters most for
+// interfaces, where checking only the first word can miss a "tearing"
+// race - a reader observing the new type word paired with the old data
+// word, or vice versa - because the two words changed at different times
+// as seen from another goroutine.
+//
+// Parameters:
+//   - addr: The address of the value's first word
+//   - words: Number of consecutive machine words the value occupies
+//
+// Example (automatic instrumentation):
+//
+//	// Original code:
+//	s = append(s, x)
+//
+//	// Instrumented code:
+//	race.RaceWriteRange(uintptr(unsafe.Pointer(&s)), race.SliceHeaderWords)
+//	s = append(s, x)
+func RaceWriteRange(addr uintptr, words int) {
+	internal.RaceWriteRange(addr, words)
+}
+
+// RegisterCheckedAnnotation records that a "//racedetector:checked" block
+// at location ("file.go:line") executed at least once (synth-3599).
+//
+// This is automatically inserted by the racedetector tool as the first
+// statement of a checked block - see the package doc comment for the
+// //racedetector:checked annotation itself. Manual calls are typically not
+// needed.
+//
+// Fini() prints every distinct location registered this way, so a
+// reviewer can audit which race-freedom claims a given run actually
+// exercised.
+//
+// Parameters:
+//   - location: Source location of the checked block, e.g. "worker.go:42"
+func RegisterCheckedAnnotation(location string) {
+	internal.RegisterCheckedAnnotation(location)
+}
+
+// CheckedAnnotations returns every distinct "//racedetector:checked"
+// location registered so far, in first-seen order (synth-3599).
+//
+// Mainly useful for tests that want to assert a particular checked block
+// ran, without scraping Fini()'s stderr report.
+func CheckedAnnotations() []string {
+	return internal.CheckedAnnotations()
+}
+
 // RaceAcquire records the acquisition of a synchronization object.
 //
 // This function establishes a happens-before relationship, indicating that
@@ -160,6 +349,188 @@ func RaceRelease(addr uintptr) {
 	internal.RaceRelease(addr)
 }
 
+// DisableCurrentGoroutine disables race detection for the calling goroutine
+// only, unlike a global on/off switch that would affect every goroutine.
+//
+// This lets libraries wrap intentionally racy code (e.g., a lazily
+// initialized cache verified correct by other means) without turning the
+// detector off for the rest of the program while that code runs elsewhere.
+//
+// Calls nest: each DisableCurrentGoroutine call increments this goroutine's
+// ignore depth, and detection only resumes once a matching
+// EnableCurrentGoroutine call brings the depth back to zero. Always pair
+// with defer so the matching call runs even on early return or panic:
+//
+//	race.DisableCurrentGoroutine()
+//	defer race.EnableCurrentGoroutine()
+//	// ... intentionally racy code verified by other means ...
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func DisableCurrentGoroutine() {
+	internal.DisableCurrentGoroutine()
+}
+
+// EnableCurrentGoroutine re-enables race detection for the calling
+// goroutine after a matching DisableCurrentGoroutine call.
+//
+// Calling EnableCurrentGoroutine without a preceding DisableCurrentGoroutine
+// (or more times than it was called) is a no-op rather than an error.
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func EnableCurrentGoroutine() {
+	internal.EnableCurrentGoroutine()
+}
+
+// IgnoreReadsBegin disables race detection for reads made by the calling
+// goroutine only, leaving its writes checked. This is the finer-grained
+// counterpart of DisableCurrentGoroutine: useful when a benign pattern only
+// involves unsynchronized reads (e.g. polling a flag written exactly once
+// under a lock elsewhere) and writes from the same goroutine should still
+// be checked normally.
+//
+// Calls nest; always pair with defer:
+//
+//	race.IgnoreReadsBegin()
+//	defer race.IgnoreReadsEnd()
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func IgnoreReadsBegin() {
+	internal.IgnoreReadsBegin()
+}
+
+// IgnoreReadsEnd re-enables read detection for the calling goroutine after
+// a matching IgnoreReadsBegin call. An unbalanced call is a no-op.
+func IgnoreReadsEnd() {
+	internal.IgnoreReadsEnd()
+}
+
+// IgnoreWritesBegin disables race detection for writes made by the calling
+// goroutine only, leaving its reads checked. This is the finer-grained
+// counterpart of DisableCurrentGoroutine: useful when a benign pattern only
+// involves unsynchronized writes (e.g. a lazily initialized cache where
+// every writer computes and stores the same value) and reads from the same
+// goroutine should still be checked normally.
+//
+// Calls nest; always pair with defer:
+//
+//	race.IgnoreWritesBegin()
+//	defer race.IgnoreWritesEnd()
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func IgnoreWritesBegin() {
+	internal.IgnoreWritesBegin()
+}
+
+// IgnoreWritesEnd re-enables write detection for the calling goroutine
+// after a matching IgnoreWritesBegin call. An unbalanced call is a no-op.
+func IgnoreWritesEnd() {
+	internal.IgnoreWritesEnd()
+}
+
+// EnableFullDetectionForGoroutine forces the calling goroutine's accesses
+// to always be checked, bypassing sampling (SampleRate or
+// OverheadTargetPercent) even when it would otherwise skip most accesses.
+//
+// GoStart propagates this to every goroutine the caller spawns while it's
+// in effect, so tagging a request's entry point covers its whole call
+// tree. See EnableFullDetectionFromContext for driving this from a pprof
+// label instead of calling it directly.
+//
+// Calls nest: each EnableFullDetectionForGoroutine call increments this
+// goroutine's force-full-detection depth, and sampling only resumes once a
+// matching DisableFullDetectionForGoroutine call brings the depth back to
+// zero. Always pair with defer:
+//
+//	race.EnableFullDetectionForGoroutine()
+//	defer race.DisableFullDetectionForGoroutine()
+//
+// Thread Safety: Only affects the calling goroutine and the goroutines it
+// spawns while in effect. Safe to call concurrently from different
+// goroutines.
+func EnableFullDetectionForGoroutine() {
+	internal.EnableFullDetectionForGoroutine()
+}
+
+// DisableFullDetectionForGoroutine re-enables sampling for the calling
+// goroutine after a matching EnableFullDetectionForGoroutine call.
+//
+// Calling DisableFullDetectionForGoroutine without a preceding
+// EnableFullDetectionForGoroutine (or more times than it was called) is a
+// no-op rather than an error.
+//
+// Thread Safety: Only affects the calling goroutine. Safe to call
+// concurrently from different goroutines.
+func DisableFullDetectionForGoroutine() {
+	internal.DisableFullDetectionForGoroutine()
+}
+
+// FullDetectionLabelKey is the runtime/pprof label key
+// WithFullDetectionLabel sets and EnableFullDetectionFromContext reads, so
+// a service can flow the "check this request at full detection" decision
+// through the same context.Context/pprof.Labels plumbing it likely already
+// uses for request tagging, instead of a second bespoke mechanism.
+const FullDetectionLabelKey = "racedetector.full"
+
+// WithFullDetectionLabel returns a copy of ctx carrying the pprof label
+// EnableFullDetectionFromContext looks for, e.g. after deciding a request
+// is worth checking at full detection:
+//
+//	if r.Header.Get("X-Debug-Race") != "" {
+//		ctx = race.WithFullDetectionLabel(ctx)
+//	}
+//
+// This only attaches the label to ctx; it doesn't itself change what gets
+// checked. Call EnableFullDetectionFromContext(ctx) from the goroutine that
+// should act on it - typically once, near the top of the request handler.
+func WithFullDetectionLabel(ctx context.Context) context.Context {
+	return pprof.WithLabels(ctx, pprof.Labels(FullDetectionLabelKey, "1"))
+}
+
+// EnableFullDetectionFromContext calls EnableFullDetectionForGoroutine for
+// the calling goroutine if ctx carries the label WithFullDetectionLabel
+// sets, and is a no-op otherwise. Meant to be called once, near the top of
+// a request handler that has access to the request's context.Context:
+//
+//	func handle(ctx context.Context) {
+//		race.EnableFullDetectionFromContext(ctx)
+//		// ... handler body, and everything it spawns, is now fully
+//		// checked if the caller tagged ctx via WithFullDetectionLabel ...
+//	}
+//
+// Unlike EnableFullDetectionForGoroutine, there is no matching Disable
+// call: this goroutine's own force-full-detection depth is meant to stay
+// forced for the handler's lifetime, and GoStart-based inheritance already
+// keeps it from leaking into goroutines that don't descend from a tagged
+// call tree.
+//
+// Thread Safety: Only affects the calling goroutine and the goroutines it
+// spawns afterward. Safe to call concurrently from different goroutines.
+func EnableFullDetectionFromContext(ctx context.Context) {
+	if v, ok := pprof.Label(ctx, FullDetectionLabelKey); ok && v == "1" {
+		internal.EnableFullDetectionForGoroutine()
+	}
+}
+
+// WriteOverheadProfile dumps a pprof-compatible profile attributing the
+// detector's own wall-clock overhead (time spent in RaceRead/RaceWrite/
+// RaceAcquire) to the instrumented call site that triggered it.
+//
+// Profiling must be enabled before race.Init() via the RACEDETECTOR_PROFILE
+// environment variable; otherwise this returns an error. Inspect the result
+// with the standard toolchain:
+//
+//	f, _ := os.Create("overhead.pprof")
+//	defer f.Close()
+//	race.WriteOverheadProfile(f)
+//	// $ go tool pprof overhead.pprof
+func WriteOverheadProfile(w io.Writer) error {
+	return internal.WriteOverheadProfile(w)
+}
+
 // TODO: Additional API functions will be added when implemented in internal API:
 // - RaceChannelSend(addr uintptr)
 // - RaceChannelRecv(addr uintptr)
@@ -0,0 +1,150 @@
+package race
+
+import (
+	"time"
+
+	internal "github.com/kolkov/racedetector/internal/race/api"
+)
+
+// Options configures the race detector runtime before Init() starts it
+// (v0.5.0), surfacing the internal detector's sampling, history, and
+// stack-symbolization knobs through the supported public API as an
+// alternative to the RACEDETECTOR_* environment variables Init() also
+// recognizes.
+type Options struct {
+	// SampleRate, when > 1, checks only 1 in SampleRate memory accesses,
+	// trading detection rate for performance (~50-90% overhead reduction
+	// at rate=10/100). 0 or 1 disables sampling and checks every access.
+	// Equivalent to RACEDETECTOR_SAMPLE_RATE.
+	SampleRate uint64
+
+	// WarmupDuration, when set alongside SampleRate, checks every access
+	// for this long after Init() before SampleRate takes effect, so
+	// initialization races - the most common kind, and the least likely
+	// to recur for sampling to eventually catch - are always fully
+	// checked while steady-state overhead still stays low. Has no effect
+	// unless SampleRate is also > 1. Equivalent to RACEDETECTOR_WARMUP.
+	WarmupDuration time.Duration
+
+	// OverheadTargetPercent, when set, puts SampleRate under feedback
+	// control targeting this overhead percentage (e.g. 20 for "at most
+	// 20% slower") instead of a fixed rate, roughly once a second
+	// comparing measured detector hook time against wall-clock time and
+	// adjusting. Turns sampling on even if SampleRate is unset, starting
+	// from full detection and backing off from there. Intended for
+	// always-on production detection, where the acceptable overhead is
+	// known but the access pattern isn't. Equivalent to
+	// RACEDETECTOR_OVERHEAD_TARGET_PERCENT.
+	OverheadTargetPercent float64
+
+	// HistorySize configures how many recent accesses per shadow cell are
+	// retained for race reports, so a report can show several prior
+	// accesses instead of only the single most recent one. 0 (default)
+	// disables history tracking.
+	HistorySize int
+
+	// ProfilingEnabled turns on per-call-site overhead profiling, dumped
+	// via WriteOverheadProfile. Equivalent to RACEDETECTOR_PROFILE=1.
+	ProfilingEnabled bool
+
+	// StripPathPrefix, if set, is trimmed from the front of a race
+	// report's stack trace file paths, so reports stay stable across
+	// machines with different build paths. Equivalent to
+	// RACEDETECTOR_STRIP_PREFIX.
+	StripPathPrefix string
+
+	// ModuleRoot and ModuleImportPath, if both set, rewrite stack trace
+	// file paths under ModuleRoot to be relative to ModuleImportPath
+	// instead, e.g. "/home/alice/racedetector/report.go" becomes
+	// "github.com/kolkov/racedetector/report.go". Equivalent to
+	// RACEDETECTOR_MODULE_ROOT and RACEDETECTOR_MODULE_PATH.
+	ModuleRoot       string
+	ModuleImportPath string
+
+	// ExportURL, if set, POSTs every newly reported race to this HTTP
+	// endpoint as it's found, so a fleet running the detector in canary
+	// mode can centralize findings instead of only ever seeing them in
+	// each instance's own stderr. Equivalent to RACEDETECTOR_EXPORT_URL.
+	ExportURL string
+
+	// ExportFormat selects the POSTed body's shape: "json" (default) for
+	// a flat JSON object, or "otlp" for an OTLP/HTTP JSON logs payload
+	// suitable for an OpenTelemetry collector. Has no effect unless
+	// ExportURL is also set. Equivalent to RACEDETECTOR_EXPORT_FORMAT.
+	ExportFormat string
+
+	// ReportMaxFrames caps how many stack frames a race report prints,
+	// after ReportSkipInternalFrames/ReportCollapseWrappers filtering is
+	// applied, so a deep call chain can't push the actual race site off
+	// the top of a terminal or CI log. 0 (default) means unlimited.
+	// Equivalent to RACEDETECTOR_REPORT_MAX_FRAMES.
+	ReportMaxFrames int
+
+	// ReportSkipInternalFrames additionally filters this module's own
+	// instrumentation frames (race.RaceRead, internal/race/api.raceread,
+	// etc.) out of a report's stack traces, so the first visible frame is
+	// the user code that triggered the access. Equivalent to
+	// RACEDETECTOR_REPORT_SKIP_INTERNAL.
+	ReportSkipInternalFrames bool
+
+	// ReportCollapseWrappers filters compiler-generated wrapper frames
+	// (method-value wrappers, range-over-func loop body wrappers) out of
+	// a report's stack traces. Equivalent to
+	// RACEDETECTOR_REPORT_COLLAPSE_WRAPPERS.
+	ReportCollapseWrappers bool
+
+	// ReportMaxReportsPerSite caps how many full reports are printed to
+	// stderr for the same racing site pair before further occurrences are
+	// suppressed from stderr (still counted in the Fini summary). 0
+	// (default) means unlimited. Equivalent to
+	// RACEDETECTOR_REPORT_MAX_PER_SITE.
+	ReportMaxReportsPerSite int
+
+	// SingleThreaded opts into the single-threaded backpressure policy
+	// Init() already assumes by default on GOOS=js/wasip1 - see
+	// detector.DetectorOptions.SingleThreaded. Setting this only ever
+	// turns the policy on; it cannot turn off a platform's true default.
+	// Equivalent to RACEDETECTOR_SINGLE_THREADED=1.
+	SingleThreaded bool
+}
+
+// Configure records detector options for the next Init() call to apply.
+//
+// Configure must be called before Init(); it has no effect on an
+// already-running detector, and Init() itself still re-applies it every
+// time it runs. Where both are set, a RACEDETECTOR_* environment
+// variable takes precedence over the matching Options field, so an
+// operator can still override a program's compiled-in configuration at
+// deploy time without a rebuild.
+//
+// Example:
+//
+//	func main() {
+//		race.Configure(race.Options{SampleRate: 10, HistorySize: 4})
+//		race.Init()
+//		defer race.Fini()
+//		// ... rest of program
+//	}
+//
+// Thread Safety: NOT safe for concurrent calls, and not safe to call
+// concurrently with Init().
+func Configure(opts Options) {
+	internal.Configure(internal.ConfigOptions{
+		SampleRate:            opts.SampleRate,
+		WarmupDuration:        opts.WarmupDuration,
+		OverheadTargetPercent: opts.OverheadTargetPercent,
+		HistorySize:           opts.HistorySize,
+		ProfilingEnabled:      opts.ProfilingEnabled,
+		StripPathPrefix:       opts.StripPathPrefix,
+		ModuleRoot:            opts.ModuleRoot,
+		ModuleImportPath:      opts.ModuleImportPath,
+		ExportURL:             opts.ExportURL,
+		ExportFormat:          opts.ExportFormat,
+
+		ReportMaxFrames:          opts.ReportMaxFrames,
+		ReportSkipInternalFrames: opts.ReportSkipInternalFrames,
+		ReportCollapseWrappers:   opts.ReportCollapseWrappers,
+		ReportMaxReportsPerSite:  opts.ReportMaxReportsPerSite,
+		SingleThreaded:           opts.SingleThreaded,
+	})
+}
@@ -0,0 +1,19 @@
+package race
+
+import (
+	"net/http"
+
+	internal "github.com/kolkov/racedetector/internal/race/api"
+)
+
+// DebugHandler returns an http.Handler showing the detector's live state
+// (v0.5.0): current race count, recent reports, per-goroutine clocks, and
+// top shadow-memory consumers. Useful when running the detector in a
+// staging service for hours, to inspect what's happening without stopping
+// the process:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/debug/race", race.DebugHandler())
+func DebugHandler() http.Handler {
+	return internal.DebugHandler()
+}
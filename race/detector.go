@@ -0,0 +1,253 @@
+package race
+
+import (
+	internal "github.com/kolkov/racedetector/internal/race/api"
+	"github.com/kolkov/racedetector/internal/race/detector"
+	"github.com/kolkov/racedetector/internal/race/goroutine"
+)
+
+// Detector is a standalone race-detection engine (synth-3608), independent
+// of the process-wide detector Init/Fini/RaceRead/RaceWrite manage.
+//
+// The rest of this package assumes the racedetector build tool has
+// instrumented a real Go program's memory accesses and synchronization
+// operations. A dynamic-analysis tool, interpreter, or simulator modeling
+// its own concurrency (green threads, simulated cores, replayed schedules)
+// has no such instrumentation to plug into - it wants the FastTrack engine
+// itself, driven directly from its own event loop. Detector is that engine,
+// reachable without importing internal/race/... .
+//
+// Unlike RaceRead/RaceWrite, which look up the calling goroutine's state
+// automatically, Detector's methods take an explicit *Context identifying
+// which logical thread performed the access - the caller allocates one
+// Context per logical thread it simulates (see Alloc) and threads it
+// through every call that thread makes.
+//
+// Thread Safety: safe for concurrent calls, same as the process-wide
+// detector.
+type Detector struct {
+	d *detector.Detector
+}
+
+// DetectorOptions configures a Detector created by NewDetectorWithOptions
+// (synth-3608). It exposes the subset of the internal detector's options
+// relevant to a hand-driven embedder; see Options for the equivalent used
+// by Configure/Init.
+type DetectorOptions struct {
+	// SampleRate, when > 1, checks only 1 in SampleRate accesses, trading
+	// detection rate for performance. 0 or 1 disables sampling and checks
+	// every access. Equivalent to Options.SampleRate.
+	SampleRate uint64
+
+	// HistorySize configures how many recent accesses per shadow cell are
+	// retained for race reports. 0 (default) disables history tracking.
+	// Equivalent to Options.HistorySize.
+	HistorySize int
+
+	// OnReport, when non-nil, is called synchronously with each newly
+	// detected race (already deduplicated by call-stack fingerprint) -
+	// the "Report callback" an embedder uses to react to a race directly
+	// (fail a fuzz iteration, abort a simulated schedule, record a
+	// finding) instead of parsing stderr or polling RacesDetected.
+	//
+	// Called while the Detector's internal lock is held: it must not call
+	// back into the same Detector, and should copy anything from report
+	// it needs to keep past the call.
+	OnReport func(report *Report)
+}
+
+// Report describes a single detected data race: the two conflicting
+// accesses, their goroutines and stack traces, and the key used to
+// deduplicate repeat occurrences of the same race. Format it with
+// (*Report).FormatWithSymbolizer(w, nil) for a human-readable report; a nil
+// Symbolizer prints raw stack frames without path rewriting.
+type Report = detector.RaceReport
+
+// Context identifies one logical thread's race-detection state to a
+// Detector (synth-3608): its logical clock and happens-before history.
+// Allocate one per logical thread with Detector.Alloc and pass it to every
+// OnRead/OnWrite/sync-hook call that thread makes. A Context must not be
+// used from more than one logical thread concurrently.
+type Context struct {
+	ctx *goroutine.RaceContext
+}
+
+// NewDetector creates a standalone Detector with default options
+// (synth-3608), equivalent to NewDetectorWithOptions(DetectorOptions{}).
+func NewDetector() *Detector {
+	return &Detector{d: detector.NewDetector()}
+}
+
+// NewDetectorWithOptions creates a standalone Detector configured by opts
+// (synth-3608).
+func NewDetectorWithOptions(opts DetectorOptions) *Detector {
+	return &Detector{d: detector.NewDetectorWithOptions(detector.DetectorOptions{
+		SamplingEnabled: opts.SampleRate > 1,
+		SampleRate:      opts.SampleRate,
+		HistorySize:     opts.HistorySize,
+		OnReport:        opts.OnReport,
+	})}
+}
+
+// Alloc creates a Context for a new logical thread identified by tid,
+// starting at logical time 1. tid must be unique among Contexts currently
+// live on d. See goroutine.Alloc.
+func (d *Detector) Alloc(tid uint16) *Context {
+	return &Context{ctx: goroutine.Alloc(tid)}
+}
+
+// OnRead records a read of addr by ctx's logical thread.
+func (d *Detector) OnRead(addr uintptr, ctx *Context) {
+	d.d.OnRead(addr, ctx.ctx)
+}
+
+// OnWrite records a write to addr by ctx's logical thread.
+func (d *Detector) OnWrite(addr uintptr, ctx *Context) {
+	d.d.OnWrite(addr, ctx.ctx)
+}
+
+// OnAcquire records ctx's logical thread acquiring the lock-like resource
+// identified by addr (e.g. a mutex Lock), establishing happens-before
+// ordering against the matching OnRelease.
+func (d *Detector) OnAcquire(addr uintptr, ctx *Context) {
+	d.d.OnAcquire(addr, ctx.ctx)
+}
+
+// OnRelease records ctx's logical thread releasing the lock-like resource
+// identified by addr (e.g. a mutex Unlock).
+func (d *Detector) OnRelease(addr uintptr, ctx *Context) {
+	d.d.OnRelease(addr, ctx.ctx)
+}
+
+// OnReleaseMerge is like OnRelease, but merges the released clock into
+// addr's shadow instead of replacing it, for resources multiple holders can
+// release concurrently (e.g. sync.RWMutex's read side).
+func (d *Detector) OnReleaseMerge(addr uintptr, ctx *Context) {
+	d.d.OnReleaseMerge(addr, ctx.ctx)
+}
+
+// OnRLock records ctx's logical thread taking a shared (read) lock on addr.
+func (d *Detector) OnRLock(addr uintptr, ctx *Context) {
+	d.d.OnRLock(addr, ctx.ctx)
+}
+
+// OnRUnlock records ctx's logical thread releasing a shared (read) lock on
+// addr.
+func (d *Detector) OnRUnlock(addr uintptr, ctx *Context) {
+	d.d.OnRUnlock(addr, ctx.ctx)
+}
+
+// OnChannelMake records ctx's logical thread creating the channel identified
+// by ch with the given buffer capacity.
+func (d *Detector) OnChannelMake(ch uintptr, capacity int, ctx *Context) {
+	d.d.OnChannelMake(ch, capacity, ctx.ctx)
+}
+
+// OnChannelSendBefore records ctx's logical thread about to send on the
+// channel identified by ch.
+func (d *Detector) OnChannelSendBefore(ch uintptr, ctx *Context) {
+	d.d.OnChannelSendBefore(ch, ctx.ctx)
+}
+
+// OnChannelSendAfter records ctx's logical thread having just sent on the
+// channel identified by ch.
+func (d *Detector) OnChannelSendAfter(ch uintptr, ctx *Context) {
+	d.d.OnChannelSendAfter(ch, ctx.ctx)
+}
+
+// OnChannelRecvBefore records ctx's logical thread about to receive on the
+// channel identified by ch.
+func (d *Detector) OnChannelRecvBefore(ch uintptr, ctx *Context) {
+	d.d.OnChannelRecvBefore(ch, ctx.ctx)
+}
+
+// OnChannelRecvAfter records ctx's logical thread having just received on
+// the channel identified by ch, establishing happens-before ordering
+// against the matching OnChannelSendBefore.
+func (d *Detector) OnChannelRecvAfter(ch uintptr, ctx *Context) {
+	d.d.OnChannelRecvAfter(ch, ctx.ctx)
+}
+
+// OnChannelClose records ctx's logical thread closing the channel
+// identified by ch.
+func (d *Detector) OnChannelClose(ch uintptr, ctx *Context) {
+	d.d.OnChannelClose(ch, ctx.ctx)
+}
+
+// OnWaitGroupAdd records ctx's logical thread calling wg.Add(delta) on the
+// WaitGroup identified by wg.
+func (d *Detector) OnWaitGroupAdd(wg uintptr, delta int, ctx *Context) {
+	d.d.OnWaitGroupAdd(wg, delta, ctx.ctx)
+}
+
+// OnWaitGroupDone records ctx's logical thread calling wg.Done() on the
+// WaitGroup identified by wg.
+func (d *Detector) OnWaitGroupDone(wg uintptr, ctx *Context) {
+	d.d.OnWaitGroupDone(wg, ctx.ctx)
+}
+
+// OnWaitGroupWaitBefore records ctx's logical thread about to call
+// wg.Wait() on the WaitGroup identified by wg.
+func (d *Detector) OnWaitGroupWaitBefore(wg uintptr, ctx *Context) {
+	d.d.OnWaitGroupWaitBefore(wg, ctx.ctx)
+}
+
+// OnWaitGroupWaitAfter records ctx's logical thread having just returned
+// from wg.Wait() on the WaitGroup identified by wg, establishing
+// happens-before ordering against every OnWaitGroupDone counted down to
+// zero.
+func (d *Detector) OnWaitGroupWaitAfter(wg uintptr, ctx *Context) {
+	d.d.OnWaitGroupWaitAfter(wg, ctx.ctx)
+}
+
+// WaitForPendingReports blocks until every race enqueued by an OnWrite/
+// OnRead call that has already returned has been reported - including a
+// call to DetectorOptions.OnReport. Race detection and reporting happen on
+// a separate goroutine from OnWrite/OnRead's hot path, so a caller that
+// wants to observe RacesDetected or every OnReport call for accesses it has
+// already made must call this first.
+func (d *Detector) WaitForPendingReports() {
+	d.d.WaitForPendingReports()
+}
+
+// RacesDetected returns the number of unique races d has found so far.
+func (d *Detector) RacesDetected() int {
+	return d.d.RacesDetected()
+}
+
+// Reset clears d's shadow memory, sync shadow, race counter, and
+// deduplication state, as if it were newly created.
+func (d *Detector) Reset() {
+	d.d.Reset()
+}
+
+// Close releases resources (background reporter goroutine, exporter
+// connections) held by d. d must not be used after Close.
+func (d *Detector) Close() error {
+	return d.d.Close()
+}
+
+// OnReport registers fn to be called with a copy of each newly detected
+// race found by the process-wide detector Init/Fini manage (synth-3609),
+// in addition to (not instead of) the stderr report and any Configure'd
+// exporter - so an application can panic in a test, increment a metric,
+// capture an event to an error tracker, or run a programmatic assertion
+// the moment a race is found, instead of only ever seeing it in stderr.
+//
+// OnReport may be called before or after Init(): if called before, fn is
+// picked up by the next Init() call; if called after, it takes effect on
+// the already-running detector immediately. Pass nil to disable a
+// previously registered callback. Unlike Detector.OnReport above (a
+// per-instance construction option), this affects the shared singleton
+// every RaceRead/RaceWrite/etc. call goes through.
+//
+// Thread Safety: Safe for concurrent calls.
+func OnReport(fn func(report Report)) {
+	if fn == nil {
+		internal.OnReport(nil)
+		return
+	}
+	internal.OnReport(func(report *Report) {
+		fn(*report)
+	})
+}
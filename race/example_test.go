@@ -2,7 +2,10 @@ package race_test
 
 import (
 	"fmt"
+	"net/http/httptest"
+	"strings"
 	"sync"
+	"testing"
 	"unsafe"
 
 	"github.com/kolkov/racedetector/race"
@@ -55,6 +58,134 @@ func Example_mutexProtected() {
 	// No race detected
 }
 
+// TestExpectRaceAndExpectNoRace demonstrates the pattern this module's own
+// tests use to assert a closure does or doesn't introduce a data race,
+// exercising both the positive and negative case (synth-3610).
+func TestExpectRaceAndExpectNoRace(t *testing.T) {
+	race.Init()
+	defer race.Fini()
+
+	var counter int
+	addr := uintptr(unsafe.Pointer(&counter))
+	var mu sync.Mutex
+
+	race.ExpectRace(t, func() {
+		done := make(chan struct{})
+		go func() {
+			race.RaceWrite(addr)
+			counter++
+			close(done)
+		}()
+		race.RaceWrite(addr)
+		counter++
+		<-done
+	})
+
+	race.ExpectNoRace(t, func() {
+		race.RaceAcquire(uintptr(unsafe.Pointer(&mu)))
+		mu.Lock()
+		race.RaceWrite(addr)
+		counter++
+		race.RaceRelease(uintptr(unsafe.Pointer(&mu)))
+		mu.Unlock()
+	})
+}
+
+// Example_onReport demonstrates registering a callback that fires whenever
+// the process-wide detector finds a race, instead of only ever seeing it
+// in stderr.
+func Example_onReport() {
+	found := make(chan race.Report, 1)
+	race.OnReport(func(r race.Report) {
+		found <- r
+	})
+	defer race.OnReport(nil)
+
+	race.Init()
+	defer race.Fini()
+
+	var counter int
+	addr := uintptr(unsafe.Pointer(&counter))
+
+	go func() {
+		race.RaceWrite(addr)
+		counter++
+	}()
+	race.RaceWrite(addr)
+	counter++
+
+	report := <-found
+	fmt.Println(report.DeduplicationKey != "")
+
+	// Output:
+	// true
+}
+
+// Example_metricsHandler demonstrates registering the Prometheus metrics
+// endpoint on a mux for monitoring a long-running service.
+func Example_metricsHandler() {
+	race.Init()
+	defer race.Fini()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+
+	race.MetricsHandler().ServeHTTP(rec, req)
+
+	fmt.Println(strings.Contains(rec.Body.String(), "racedetector_races_detected"))
+
+	// Output:
+	// true
+}
+
+// Example_debugHandler demonstrates registering the live debug-state
+// endpoint on a mux for inspecting a long-running service.
+func Example_debugHandler() {
+	race.Init()
+	defer race.Fini()
+
+	req := httptest.NewRequest("GET", "/debug/race", nil)
+	rec := httptest.NewRecorder()
+
+	race.DebugHandler().ServeHTTP(rec, req)
+
+	fmt.Println(strings.Contains(rec.Body.String(), "Race Detector Debug State"))
+
+	// Output:
+	// true
+}
+
+// Example_embeddedDetector demonstrates driving a standalone Detector
+// directly, the way a dynamic-analysis tool or interpreter would - without
+// race.Init/Fini and without any racedetector-instrumented code.
+func Example_embeddedDetector() {
+	var found []string
+	d := race.NewDetectorWithOptions(race.DetectorOptions{
+		OnReport: func(r *race.Report) {
+			found = append(found, r.DeduplicationKey)
+		},
+	})
+	defer d.Close()
+
+	var counter int
+	addr := uintptr(unsafe.Pointer(&counter))
+
+	// Two logical threads racing on addr with no synchronization between
+	// them.
+	t1 := d.Alloc(1)
+	t2 := d.Alloc(2)
+	d.OnWrite(addr, t1)
+	d.OnWrite(addr, t2)
+	d.WaitForPendingReports()
+
+	fmt.Println(d.RacesDetected() > 0)
+	fmt.Println(len(found) > 0)
+
+	// Output:
+	// true
+	// true
+}
+
 // Example_automaticInstrumentation shows how the racedetector tool works.
 func Example_automaticInstrumentation() {
 	// When using: racedetector build myprogram.go
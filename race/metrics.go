@@ -0,0 +1,29 @@
+package race
+
+import (
+	"net/http"
+
+	internal "github.com/kolkov/racedetector/internal/race/api"
+)
+
+// MetricsHandler returns an http.Handler that serves the detector's metrics
+// in Prometheus text exposition format (v0.5.0): races detected, shadow
+// cell count, promoted var count, sampler stats, and TID pool occupancy.
+//
+// The same values are also published to expvar under the "racedetector_"
+// prefix as a side effect of importing this package, so a plain
+// /debug/vars handler (net/http/pprof style) picks them up automatically
+// without registering this handler at all.
+//
+// Register it on a mux to monitor a long-running service:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/metrics", race.MetricsHandler())
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := internal.WritePrometheusMetrics(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package race
+
+import (
+	internal "github.com/kolkov/racedetector/internal/race/api"
+)
+
+// Snapshot is an opaque checkpoint of detector state captured by Snapshot,
+// to be handed to Restore (v0.5.0).
+type Snapshot = internal.FullSnapshot
+
+// TakeSnapshot captures the detector's shadow memory, sync shadow, race
+// counter, and every live goroutine's happens-before clock, for a later
+// Restore (v0.5.0).
+//
+// This is the finer-grained alternative to a full Reset() a test framework
+// can use to checkpoint state before a subtest and roll back after it,
+// instead of losing race-detection state accumulated by tests that ran
+// before it:
+//
+//	func TestSuite(t *testing.T) {
+//		baseline := race.TakeSnapshot()
+//		for _, tc := range cases {
+//			t.Run(tc.name, func(t *testing.T) {
+//				defer race.Restore(baseline)
+//				// ... exercise racy code ...
+//			})
+//		}
+//	}
+//
+// Thread Safety: NOT safe for concurrent access. The caller must ensure no
+// other goroutines are using the detector while snapshotting.
+func TakeSnapshot() *Snapshot {
+	return internal.Snapshot()
+}
+
+// Restore replaces the detector's shadow memory, sync shadow, race
+// counter, and goroutine contexts with a deep copy of snap, as previously
+// returned by TakeSnapshot (v0.5.0). The same snapshot may be restored from
+// more than once.
+//
+// Thread Safety: NOT safe for concurrent access. The caller must ensure no
+// other goroutines are using the detector during Restore().
+func Restore(snap *Snapshot) {
+	internal.Restore(snap)
+}
@@ -0,0 +1,42 @@
+package race
+
+import (
+	internal "github.com/kolkov/racedetector/internal/race/api"
+)
+
+// SyncHookKind identifies which happens-before role a method registered via
+// RegisterSyncHook plays.
+type SyncHookKind = internal.SyncHookKind
+
+const (
+	// SyncHookAcquire marks a method as acquire-like, e.g. sync.Mutex.Lock.
+	SyncHookAcquire = internal.SyncHookAcquire
+
+	// SyncHookRelease marks a method as release-like, e.g. sync.Mutex.Unlock.
+	SyncHookRelease = internal.SyncHookRelease
+)
+
+// RegisterSyncHook declares that typeName.methodName establishes
+// acquire/release happens-before semantics, so the instrumenter treats
+// calls to it exactly like sync.Mutex.Lock/Unlock (v0.5.0).
+//
+// This lets a library author whose own lock/queue type the instrumenter
+// has never heard of avoid the false positives that would otherwise be
+// reported between a Lock-like call and the critical section it guards.
+//
+// Call RegisterSyncHook from an init() function, before the instrumenter
+// processes the package - a registration only affects instrumentation
+// performed after it is recorded:
+//
+//	func init() {
+//		race.RegisterSyncHook("MyMutex", "Lock", race.SyncHookAcquire)
+//		race.RegisterSyncHook("MyMutex", "Unlock", race.SyncHookRelease)
+//	}
+//
+// Returns an error if typeName or methodName is empty, or kind is not one
+// of SyncHookAcquire or SyncHookRelease.
+//
+// Thread Safety: Safe for concurrent calls.
+func RegisterSyncHook(typeName, methodName string, kind SyncHookKind) error {
+	return internal.RegisterSyncHook(typeName, methodName, kind)
+}
@@ -0,0 +1,77 @@
+package race
+
+import (
+	"testing"
+
+	internal "github.com/kolkov/racedetector/internal/race/api"
+)
+
+// TestCleanup scopes race reporting to a single test (v0.5.0), so a race
+// found while one test runs is attributed to that test specifically,
+// instead of only ever surfacing once the whole `go test` binary exits.
+//
+// Call it at the top of a test function:
+//
+//	func TestFoo(t *testing.T) {
+//	    race.TestCleanup(t)
+//	    // ... exercise racy code ...
+//	}
+//
+// TestCleanup registers a t.Cleanup that fails the test with t.Errorf if
+// any new races were detected while it ran. If
+// RACEDETECTOR_RESET_BETWEEN_TESTS=1 is set, it also resets the
+// detector's shadow memory and dedup state after reporting, so a race on
+// an address already reported by an earlier test isn't silently
+// suppressed in a later one - `racedetector test -reset-between-tests`
+// sets this for you. See internal/race/api.TestCleanup for the tradeoffs
+// that flag makes.
+func TestCleanup(t testing.TB) {
+	internal.TestCleanup(t)
+}
+
+// ExpectRace runs fn and fails t if the process-wide detector doesn't
+// report at least one new race while fn runs (synth-3610), replacing the
+// "before := RacesDetected(); fn(); after := RacesDetected(); compare"
+// boilerplate hand-rolled throughout this module's own tests.
+//
+// race.Init() must already be running. Call it inside a subtest (t.Run)
+// or pair it with TestCleanup/Reset in the surrounding test so an earlier
+// test's races can't satisfy this one - ExpectRace only compares the
+// count before and after fn, it doesn't scope by test name.
+//
+//	func TestConcurrentMapWrite(t *testing.T) {
+//	    race.ExpectRace(t, func() {
+//	        // ... exercise code that races ...
+//	    })
+//	}
+func ExpectRace(t testing.TB, fn func()) {
+	t.Helper()
+	before := internal.RacesDetected()
+	fn()
+	after := internal.RacesDetected()
+	if after <= before {
+		t.Errorf("race.ExpectRace: expected a new race, but none was detected (racesDetected stayed at %d)", before)
+	}
+}
+
+// ExpectNoRace runs fn and fails t if the process-wide detector reports any
+// new race while fn runs (synth-3610), the negative counterpart of
+// ExpectRace for asserting that synchronized code stays race-free.
+//
+// race.Init() must already be running. See ExpectRace for the same
+// count-window caveat.
+//
+//	func TestMutexProtectedCounter(t *testing.T) {
+//	    race.ExpectNoRace(t, func() {
+//	        // ... exercise correctly-synchronized code ...
+//	    })
+//	}
+func ExpectNoRace(t testing.TB, fn func()) {
+	t.Helper()
+	before := internal.RacesDetected()
+	fn()
+	after := internal.RacesDetected()
+	if after != before {
+		t.Errorf("race.ExpectNoRace: expected no new race, but %d were detected", after-before)
+	}
+}
@@ -1,5 +1,11 @@
 package race
 
+import (
+	"fmt"
+
+	internal "github.com/kolkov/racedetector/internal/race/api"
+)
+
 // Version information for the Pure-Go Race Detector.
 const (
 	// Version is the current version of the race detector runtime.
@@ -15,6 +21,30 @@ const (
 	VersionPatch = 0
 )
 
+// Subsystems reports which categories of synchronization the running
+// instrumentation actually hooks into happens-before tracking, as opposed
+// to what the detector supports internally (synth-3638). Mutexes is the
+// only one wired up by the racedetector tool's AST instrumentation today;
+// Channels and WaitGroups have detector-side support (see
+// internal/race/detector's OnWaitGroup* hooks) but nothing in
+// cmd/racedetector/instrument emits calls into them yet, and Atomics has
+// no support anywhere. A bug report that only shows GetInfo().Enabled
+// can't tell a maintainer whether a missed race is a real gap or one of
+// these; this can.
+type Subsystems struct {
+	// Mutexes indicates sync.Mutex/RWMutex Lock/Unlock are tracked.
+	Mutexes bool
+
+	// Channels indicates channel send/receive/close are tracked.
+	Channels bool
+
+	// WaitGroups indicates sync.WaitGroup Add/Done/Wait are tracked.
+	WaitGroups bool
+
+	// Atomics indicates sync/atomic operations are tracked.
+	Atomics bool
+}
+
 // Info provides runtime information about the race detector.
 type Info struct {
 	// Version is the runtime version string.
@@ -25,6 +55,24 @@ type Info struct {
 
 	// Enabled indicates whether race detection is active.
 	Enabled bool
+
+	// Subsystems reports which kinds of synchronization the running
+	// instrumentation feeds into happens-before tracking (synth-3638).
+	Subsystems Subsystems
+
+	// SamplingEnabled indicates the detector is checking only a fraction
+	// of memory accesses rather than every one - see SampleRate and
+	// Options.SampleRate (synth-3638).
+	SamplingEnabled bool
+
+	// SampleRate is the sampling denominator in effect: 1 in SampleRate
+	// accesses is checked. 0 or 1 means sampling is off (synth-3638).
+	SampleRate uint64
+
+	// FastGoid indicates this build has the assembly-optimized goroutine
+	// ID extraction wired up rather than the ~1500ns runtime.Stack-parsing
+	// fallback (synth-3638).
+	FastGoid bool
 }
 
 // GetInfo returns information about the race detector runtime.
@@ -37,6 +85,53 @@ func GetInfo() Info {
 	return Info{
 		Version:   Version,
 		Algorithm: "FastTrack (PLDI 2009)",
-		Enabled:   true, // Always enabled when using this package
+		Enabled:   internal.Enabled(),
+		Subsystems: Subsystems{
+			Mutexes:    true,
+			Channels:   false,
+			WaitGroups: false,
+			Atomics:    false,
+		},
+		SamplingEnabled: internal.SamplingEnabled(),
+		SampleRate:      internal.SampleRate(),
+		FastGoid:        internal.FastGoidAvailable(),
+	}
+}
+
+// String returns a one-line, bug-report-friendly summary of info, e.g.
+//
+//	"race detector 0.1.0 (FastTrack (PLDI 2009)), enabled, subsystems=[mutexes], sampling=off, fastGoid=true"
+func (info Info) String() string {
+	named := []struct {
+		name string
+		on   bool
+	}{
+		{"mutexes", info.Subsystems.Mutexes},
+		{"channels", info.Subsystems.Channels},
+		{"waitgroups", info.Subsystems.WaitGroups},
+		{"atomics", info.Subsystems.Atomics},
+	}
+	var subs []byte
+	for _, n := range named {
+		if !n.on {
+			continue
+		}
+		if len(subs) > 0 {
+			subs = append(subs, ',')
+		}
+		subs = append(subs, n.name...)
 	}
+
+	sampling := "off"
+	if info.SamplingEnabled {
+		sampling = fmt.Sprintf("1/%d", info.SampleRate)
+	}
+
+	enabled := "disabled"
+	if info.Enabled {
+		enabled = "enabled"
+	}
+
+	return fmt.Sprintf("race detector %s (%s), %s, subsystems=[%s], sampling=%s, fastGoid=%v",
+		info.Version, info.Algorithm, enabled, subs, sampling, info.FastGoid)
 }
@@ -0,0 +1,54 @@
+package race_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kolkov/racedetector/race"
+)
+
+// TestGetInfo_ReportsCapabilities verifies GetInfo reflects the current
+// Configure/Init state rather than always returning static defaults
+// (synth-3638).
+func TestGetInfo_ReportsCapabilities(t *testing.T) {
+	defer race.Configure(race.Options{})
+
+	race.Configure(race.Options{SampleRate: 5})
+	race.Init()
+	defer race.Fini()
+
+	info := race.GetInfo()
+
+	if !info.Enabled {
+		t.Error("GetInfo().Enabled = false after Init(), want true")
+	}
+	if !info.Subsystems.Mutexes {
+		t.Error("GetInfo().Subsystems.Mutexes = false, want true (Lock/Unlock is instrumented)")
+	}
+	if info.Subsystems.Channels || info.Subsystems.WaitGroups || info.Subsystems.Atomics {
+		t.Errorf("GetInfo().Subsystems = %+v, want only Mutexes set (not yet wired into instrumentation)", info.Subsystems)
+	}
+	if !info.SamplingEnabled || info.SampleRate != 5 {
+		t.Errorf("GetInfo() sampling = (%v, %d), want (true, 5)", info.SamplingEnabled, info.SampleRate)
+	}
+}
+
+// TestInfo_String verifies String produces a readable, stable summary
+// containing the fields a bug report would need (synth-3638).
+func TestInfo_String(t *testing.T) {
+	info := race.Info{
+		Version:         race.Version,
+		Algorithm:       "FastTrack (PLDI 2009)",
+		Enabled:         true,
+		Subsystems:      race.Subsystems{Mutexes: true},
+		SamplingEnabled: false,
+		FastGoid:        true,
+	}
+
+	s := info.String()
+	for _, want := range []string{race.Version, "enabled", "mutexes", "sampling=off", "fastGoid=true"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Info.String() = %q, want substring %q", s, want)
+		}
+	}
+}